@@ -0,0 +1,40 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"sort"
+
+	"github.com/golang/dep/gps"
+)
+
+// StaleAliases returns the aliases of m whose targets are in l, but which l
+// does not hold at the same revision as their targets, sorted. Such an alias
+// was added after l was solved, or its target has since changed, and l must be
+// solved again to vendor it.
+func StaleAliases(m *Manifest, l gps.Lock) []gps.ProjectRoot {
+	if m == nil || l == nil || len(m.Aliases) == 0 {
+		return nil
+	}
+
+	locked := make(map[gps.ProjectRoot]gps.LockedProject)
+	for _, lp := range l.Projects() {
+		locked[lp.Ident().ProjectRoot] = lp
+	}
+
+	var stale []gps.ProjectRoot
+	for alias, target := range m.Aliases {
+		tlp, has := locked[target]
+		if !has {
+			continue
+		}
+		alp, has := locked[alias]
+		if !has || !alp.Version().Matches(tlp.Version()) {
+			stale = append(stale, alias)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i] < stale[j] })
+	return stale
+}
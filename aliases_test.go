@@ -0,0 +1,60 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestManifestIgnoresAliases(t *testing.T) {
+	m := NewManifest()
+	m.Ignored = []string{"github.com/foo/ignored"}
+	m.Aliases = map[gps.ProjectRoot]gps.ProjectRoot{"github.com/old/cli": "github.com/new/cli"}
+
+	ir := m.IgnoredPackages()
+	for path, want := range map[string]bool{
+		"github.com/foo/ignored":   true,
+		"github.com/old/cli":       true,
+		"github.com/old/cli/sub":   true,
+		"github.com/old/client":    false,
+		"github.com/new/cli":       false,
+		"github.com/new/cli/sub":   false,
+		"github.com/foo/unignored": false,
+	} {
+		if got := ir.IsIgnored(path); got != want {
+			t.Errorf("expected IsIgnored(%q) to be %v", path, want)
+		}
+	}
+}
+
+func TestStaleAliases(t *testing.T) {
+	locked := func(pr string, rev string) gps.LockedProject {
+		return gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(pr)}, gps.Revision(rev), nil)
+	}
+
+	m := NewManifest()
+	m.Aliases = map[gps.ProjectRoot]gps.ProjectRoot{
+		"github.com/old/current":  "github.com/new/current",
+		"github.com/old/behind":   "github.com/new/behind",
+		"github.com/old/added":    "github.com/new/added",
+		"github.com/old/unlocked": "github.com/new/unlocked",
+	}
+	l := &Lock{P: []gps.LockedProject{
+		locked("github.com/new/current", "abc"),
+		locked("github.com/old/current", "abc"),
+		locked("github.com/new/behind", "def"),
+		locked("github.com/old/behind", "abc"),
+		locked("github.com/new/added", "abc"),
+	}}
+
+	got := StaleAliases(m, l)
+	want := []gps.ProjectRoot{"github.com/old/added", "github.com/old/behind"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected stale aliases:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+}
@@ -5,15 +5,97 @@
 package dep
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
 )
 
+// AnalyzerNameDep is the name by which the analyzers in the manifest refer to
+// dep's own, which reads the Gopkg.toml of dependencies.
+const AnalyzerNameDep = "dep"
+
+// A MetadataAnalyzer derives the constraints of dependencies from metadata
+// other than dep's own, such as a go.mod, a glide.yaml or Bazel's WORKSPACE.
+type MetadataAnalyzer interface {
+	gps.ProjectAnalyzer
+
+	// HasDepMetadata reports whether the directory at path holds metadata
+	// that the analyzer reads.
+	HasDepMetadata(path string) bool
+}
+
+var (
+	analyzersMu sync.RWMutex
+	analyzers   = make(map[string]MetadataAnalyzer)
+)
+
+// RegisterAnalyzer makes a available under name, so that the analyzers in a
+// manifest may refer to it. It is meant to be called from the init function
+// of a package or a program that embeds dep.
+func RegisterAnalyzer(name string, a MetadataAnalyzer) error {
+	if name == "" || a == nil {
+		return errors.New("an analyzer must have a name and not be nil")
+	}
+	analyzersMu.Lock()
+	defer analyzersMu.Unlock()
+	if _, has := analyzers[name]; has || name == AnalyzerNameDep {
+		return errors.Errorf("an analyzer named %q is already registered", name)
+	}
+	analyzers[name] = a
+	return nil
+}
+
+// RegisteredAnalyzers returns the names of the analyzers that have been
+// registered, in lexical order, not including AnalyzerNameDep.
+func RegisteredAnalyzers() []string {
+	analyzersMu.RLock()
+	defer analyzersMu.RUnlock()
+	names := make([]string, 0, len(analyzers))
+	for name := range analyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func registeredAnalyzer(name string) (MetadataAnalyzer, bool) {
+	analyzersMu.RLock()
+	defer analyzersMu.RUnlock()
+	a, has := analyzers[name]
+	return a, has
+}
+
+// checkAnalyzers returns a warning for each name in order that is repeated,
+// or names no analyzer.
+func checkAnalyzers(order []string) (warns []error) {
+	seen := make(map[string]bool, len(order))
+	for _, name := range order {
+		if seen[name] {
+			warns = append(warns, errors.Errorf("analyzer %q is listed more than once", name))
+			continue
+		}
+		seen[name] = true
+		if _, has := registeredAnalyzer(name); !has && name != AnalyzerNameDep {
+			warns = append(warns, errors.Errorf("analyzer %q is not registered, and will be ignored", name))
+		}
+	}
+	return warns
+}
+
 // Analyzer implements gps.ProjectAnalyzer.
-type Analyzer struct{}
+type Analyzer struct {
+	// Order holds the names of the analyzers to consult for each
+	// dependency, in order of precedence, as set by the analyzers of the
+	// manifest. If empty, only dep's own is consulted.
+	Order []string
+}
 
 // HasDepMetadata determines if a dep manifest exists at the specified path.
 func (a Analyzer) HasDepMetadata(path string) bool {
@@ -22,13 +104,34 @@ func (a Analyzer) HasDepMetadata(path string) bool {
 	return err == nil && fileOK
 }
 
-// DeriveManifestAndLock reads and returns the manifest at path/ManifestName or nil if one is not found.
-// The Lock is always nil for now.
+// DeriveManifestAndLock returns the manifest and lock derived by the first
+// analyzer in the order of a that finds its metadata at path, or nil if none
+// does. dep's own reads the manifest at path/ManifestName, and returns a nil
+// Lock.
 func (a Analyzer) DeriveManifestAndLock(path string, n gps.ProjectRoot) (gps.Manifest, gps.Lock, error) {
-	if !a.HasDepMetadata(path) {
-		return nil, nil, nil
+	for _, name := range a.order() {
+		if name == AnalyzerNameDep {
+			if a.HasDepMetadata(path) {
+				return a.deriveDepManifest(path)
+			}
+			continue
+		}
+		if ma, has := registeredAnalyzer(name); has && ma.HasDepMetadata(path) {
+			return ma.DeriveManifestAndLock(path, n)
+		}
 	}
+	return nil, nil, nil
+}
 
+func (a Analyzer) order() []string {
+	if len(a.Order) == 0 {
+		return []string{AnalyzerNameDep}
+	}
+	return a.Order
+}
+
+// deriveDepManifest reads and returns the manifest at path/ManifestName.
+func (a Analyzer) deriveDepManifest(path string) (gps.Manifest, gps.Lock, error) {
 	f, err := os.Open(filepath.Join(path, ManifestName))
 	if err != nil {
 		return nil, nil, err
@@ -44,10 +147,29 @@ func (a Analyzer) DeriveManifestAndLock(path string, n gps.ProjectRoot) (gps.Man
 	return m, nil, nil
 }
 
-// Info returns Analyzer's name and version info.
+// Info returns Analyzer's name and version info. Unless only dep's own
+// analyzer is used, the name lists the analyzers in order, with their
+// versions, so that what they derived is cached apart.
 func (a Analyzer) Info() gps.ProjectAnalyzerInfo {
+	order := a.order()
+	if len(order) == 1 && order[0] == AnalyzerNameDep {
+		return gps.ProjectAnalyzerInfo{
+			Name:    "dep",
+			Version: 1,
+		}
+	}
+
+	parts := make([]string, 0, len(order))
+	for _, name := range order {
+		if name == AnalyzerNameDep {
+			parts = append(parts, "dep.v1")
+		} else if ma, has := registeredAnalyzer(name); has {
+			info := ma.Info()
+			parts = append(parts, fmt.Sprintf("%s.v%d", info.Name, info.Version))
+		}
+	}
 	return gps.ProjectAnalyzerInfo{
-		Name:    "dep",
+		Name:    "dep(" + strings.Join(parts, ",") + ")",
 		Version: 1,
 	}
 }
@@ -5,9 +5,11 @@
 package dep
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/golang/dep/gps"
 	"github.com/golang/dep/internal/test"
 )
 
@@ -111,3 +113,89 @@ func TestAnalyzerInfo(t *testing.T) {
 		t.Fatalf("expected name to be 'dep' and version to be 1: name -> %q vers -> %d", info.Name, info.Version)
 	}
 }
+
+// fileAnalyzer is a MetadataAnalyzer that derives an empty manifest, with
+// the given name, from any directory holding the file name.
+type fileAnalyzer struct {
+	name string
+	file string
+}
+
+func (a fileAnalyzer) HasDepMetadata(path string) bool {
+	_, err := os.Stat(filepath.Join(path, a.file))
+	return err == nil
+}
+
+func (a fileAnalyzer) DeriveManifestAndLock(path string, n gps.ProjectRoot) (gps.Manifest, gps.Lock, error) {
+	m := NewManifest()
+	m.Required = []string{a.name}
+	return m, nil, nil
+}
+
+func (a fileAnalyzer) Info() gps.ProjectAnalyzerInfo {
+	return gps.ProjectAnalyzerInfo{Name: a.name, Version: 2}
+}
+
+func TestAnalyzerOrder(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	if err := RegisterAnalyzer("test-mod", fileAnalyzer{name: "test-mod", file: "go.mod"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterAnalyzer("test-mod", fileAnalyzer{}); err == nil {
+		t.Error("expected registering an analyzer twice to fail")
+	}
+	if err := RegisterAnalyzer(AnalyzerNameDep, fileAnalyzer{}); err == nil {
+		t.Error("expected registering an analyzer named dep to fail")
+	}
+
+	h.TempFile(filepath.Join("both", ManifestName), "")
+	h.TempFile(filepath.Join("both", "go.mod"), "")
+	h.TempFile(filepath.Join("mod", "go.mod"), "")
+	h.TempDir("none")
+
+	cases := []struct {
+		name  string
+		order []string
+		dir   string
+		want  string
+	}{
+		{name: "dep by default", dir: "both", want: AnalyzerNameDep},
+		{name: "dep first", order: []string{"dep", "test-mod"}, dir: "both", want: AnalyzerNameDep},
+		{name: "registered first", order: []string{"test-mod", "dep"}, dir: "both", want: "test-mod"},
+		{name: "fallback", order: []string{"dep", "test-mod"}, dir: "mod", want: "test-mod"},
+		{name: "not listed", dir: "mod"},
+		{name: "not registered", order: []string{"unknown"}, dir: "mod"},
+		{name: "no metadata", order: []string{"dep", "test-mod"}, dir: "none"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m, _, err := Analyzer{Order: c.order}.DeriveManifestAndLock(h.Path(c.dir), "my/fake/project")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if m != nil {
+				got = AnalyzerNameDep
+				if req := m.(*Manifest).Required; len(req) > 0 {
+					got = req[0]
+				}
+			}
+			if got != c.want {
+				t.Errorf("expected the manifest to be derived by %q, got %q", c.want, got)
+			}
+		})
+	}
+
+	info := Analyzer{Order: []string{"test-mod", "dep"}}.Info()
+	if info.Name != "dep(test-mod.v2,dep.v1)" {
+		t.Errorf("expected the name to list the analyzers in order, got %q", info.Name)
+	}
+
+	warns := checkAnalyzers([]string{"dep", "test-mod", "unknown", "dep"})
+	if len(warns) != 2 {
+		t.Errorf("expected warnings about an unknown and a repeated analyzer, got %v", warns)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// The files that make up a metadata bundle.
+const (
+	// BundleVersionsFile lists the upstream versions of projects known when
+	// the bundle was made.
+	BundleVersionsFile = "versions.txt"
+	// BundleYankedFile is a yanked versions feed, in the format read by
+	// ReadYankedVersions.
+	BundleYankedFile = "yanked.txt"
+)
+
+// A Bundle is a snapshot of metadata about upstream projects, made on a
+// machine with network access and read from the local filesystem, so that
+// checks that would otherwise need the network work without it.
+type Bundle struct {
+	// Versions holds, for each project, the versions that were published
+	// upstream when the bundle was made.
+	Versions map[gps.ProjectRoot][]gps.PairedVersion
+	// Yanked holds the yanked versions feed included in the bundle, if any.
+	Yanked gps.YankedVersions
+}
+
+// ListVersions returns the versions of the project identified by id that are
+// recorded in the bundle. It returns an error if the bundle has no record of
+// the project, just as the network would be unable to answer.
+func (b *Bundle) ListVersions(id gps.ProjectIdentifier) ([]gps.PairedVersion, error) {
+	vl, has := b.Versions[id.ProjectRoot]
+	if !has {
+		return nil, errors.Errorf("no versions of %s recorded in the metadata bundle", id.ProjectRoot)
+	}
+	// Return a copy, as callers sort the list in place.
+	return append([]gps.PairedVersion(nil), vl...), nil
+}
+
+// ReadBundleVersions reads a list of upstream versions from r. Each line names
+// a project root, the kind of version ("version" or "branch"), its name and
+// the revision it refers to:
+//
+//	github.com/foo/bar version v1.2.3 2b9a0e4c49d442d94b7e3cdd1cd7ab4fce4ab2e5
+//	github.com/foo/bar branch master 8e2ad7f4c8f7a6ec2b2dc7f4bd3ee7e1c0d59ef2
+//
+// Blank lines, and lines beginning with #, are ignored.
+func ReadBundleVersions(r io.Reader) (map[gps.ProjectRoot][]gps.PairedVersion, error) {
+	versions := make(map[gps.ProjectRoot][]gps.PairedVersion)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, errors.Errorf("line %d: expected a project root, kind, name and revision, got %q", n, line)
+		}
+
+		var uv gps.UnpairedVersion
+		switch fields[1] {
+		case "version":
+			uv = gps.NewVersion(fields[2])
+		case "branch":
+			uv = gps.NewBranch(fields[2])
+		default:
+			return nil, errors.Errorf("line %d: unknown kind of version %q", n, fields[1])
+		}
+
+		pr := gps.ProjectRoot(fields[0])
+		versions[pr] = append(versions[pr], uv.Pair(gps.Revision(fields[3])))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// BundleVersions formats versions in the format read by ReadBundleVersions,
+// sorted by project root and then by version.
+func BundleVersions(versions map[gps.ProjectRoot][]gps.PairedVersion) []byte {
+	roots := make([]string, 0, len(versions))
+	for pr := range versions {
+		roots = append(roots, string(pr))
+	}
+	sort.Strings(roots)
+
+	var buf bytes.Buffer
+	for _, pr := range roots {
+		vl := append([]gps.PairedVersion(nil), versions[gps.ProjectRoot(pr)]...)
+		gps.SortPairedForUpgrade(vl)
+		for _, v := range vl {
+			kind := "version"
+			if v.Type() == gps.IsBranch {
+				kind = "branch"
+			}
+			fmt.Fprintf(&buf, "%s %s %s %s\n", pr, kind, v.String(), v.Revision())
+		}
+	}
+	return buf.Bytes()
+}
+
+// ReadBundle reads the metadata bundle in dir. Files missing from the bundle
+// are treated as empty.
+func ReadBundle(dir string) (*Bundle, error) {
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, errors.Wrap(err, "failed to read metadata bundle")
+	} else if !fi.IsDir() {
+		return nil, errors.Errorf("metadata bundle %s is not a directory", dir)
+	}
+
+	b := &Bundle{}
+
+	f, err := os.Open(filepath.Join(dir, BundleVersionsFile))
+	switch {
+	case err == nil:
+		b.Versions, err = ReadBundleVersions(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s from metadata bundle %s", BundleVersionsFile, dir)
+		}
+	case os.IsNotExist(err):
+	default:
+		return nil, errors.Wrapf(err, "failed to read metadata bundle %s", dir)
+	}
+
+	f, err = os.Open(filepath.Join(dir, BundleYankedFile))
+	switch {
+	case err == nil:
+		b.Yanked, err = ReadYankedVersions(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s from metadata bundle %s", BundleYankedFile, dir)
+		}
+	case os.IsNotExist(err):
+	default:
+		return nil, errors.Wrapf(err, "failed to read metadata bundle %s", dir)
+	}
+
+	return b, nil
+}
+
+// WriteBundle writes b into dir, which is created if it does not exist.
+func WriteBundle(dir string, b *Bundle) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return errors.Wrapf(err, "failed to create metadata bundle %s", dir)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, BundleVersionsFile), BundleVersions(b.Versions), 0666); err != nil {
+		return errors.Wrapf(err, "failed to write %s", BundleVersionsFile)
+	}
+
+	if len(b.Yanked) > 0 {
+		if err := ioutil.WriteFile(filepath.Join(dir, BundleYankedFile), yankedVersionsFeed(b.Yanked), 0666); err != nil {
+			return errors.Wrapf(err, "failed to write %s", BundleYankedFile)
+		}
+	}
+
+	return nil
+}
+
+// yankedVersionsFeed formats yanked in the format read by ReadYankedVersions,
+// sorted by project root.
+func yankedVersionsFeed(yanked gps.YankedVersions) []byte {
+	roots := make([]string, 0, len(yanked))
+	for pr := range yanked {
+		roots = append(roots, string(pr))
+	}
+	sort.Strings(roots)
+
+	var buf bytes.Buffer
+	for _, pr := range roots {
+		for _, yv := range yanked[gps.ProjectRoot(pr)] {
+			if yv.Reason == "" {
+				fmt.Fprintf(&buf, "%s %s\n", pr, yv.Version)
+			} else {
+				fmt.Fprintf(&buf, "%s %s %s\n", pr, yv.Version, yv.Reason)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// LoadBundle reads the metadata bundle named by c.Bundle. It returns nil if no
+// bundle is configured.
+func (c *Ctx) LoadBundle() (*Bundle, error) {
+	if c.Bundle == "" {
+		return nil, nil
+	}
+	return ReadBundle(c.Bundle)
+}
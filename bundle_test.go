@@ -0,0 +1,74 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestReadBundleVersions(t *testing.T) {
+	in := `# versions
+github.com/sdboyer/deptest version v1.0.0 ff2948a2ac8f538c4ecd55962e919d1e13e74baf
+
+github.com/sdboyer/deptest branch master 3f4c3bea144e112a69bbe5d8d01c1b09a544253f
+`
+	got, err := ReadBundleVersions(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[gps.ProjectRoot][]gps.PairedVersion{
+		"github.com/sdboyer/deptest": {
+			gps.NewVersion("v1.0.0").Pair("ff2948a2ac8f538c4ecd55962e919d1e13e74baf"),
+			gps.NewBranch("master").Pair("3f4c3bea144e112a69bbe5d8d01c1b09a544253f"),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected versions:\n\t(GOT): %#v\n\t(WNT): %#v", got, want)
+	}
+
+	if _, err := ReadBundleVersions(strings.NewReader("github.com/sdboyer/deptest tag v1.0.0 ff2948a2ac8f538c4ecd55962e919d1e13e74baf\n")); err == nil {
+		t.Error("expected an error for an unknown kind of version")
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("bundle")
+
+	want := &Bundle{
+		Versions: map[gps.ProjectRoot][]gps.PairedVersion{
+			"github.com/sdboyer/deptest": {
+				gps.NewVersion("v1.0.0").Pair("ff2948a2ac8f538c4ecd55962e919d1e13e74baf"),
+				gps.NewBranch("master").Pair("3f4c3bea144e112a69bbe5d8d01c1b09a544253f"),
+			},
+		},
+		Yanked: gps.YankedVersions{
+			"github.com/sdboyer/deptest": {{Version: "v0.8.0", Reason: "broken"}},
+		},
+	}
+	h.Must(WriteBundle(h.Path("bundle"), want))
+
+	got, err := ReadBundle(h.Path("bundle"))
+	h.Must(err)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected bundle:\n\t(GOT): %#v\n\t(WNT): %#v", got, want)
+	}
+
+	vl, err := got.ListVersions(gps.ProjectIdentifier{ProjectRoot: "github.com/sdboyer/deptest"})
+	h.Must(err)
+	if len(vl) != 2 {
+		t.Errorf("expected 2 versions, got %d", len(vl))
+	}
+	if _, err := got.ListVersions(gps.ProjectIdentifier{ProjectRoot: "github.com/sdboyer/deptestdos"}); err == nil {
+		t.Error("expected an error listing versions of a project missing from the bundle")
+	}
+}
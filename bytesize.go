@@ -0,0 +1,48 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// byteSizeUnits are the suffixes accepted by ParseByteSize, longest first.
+var byteSizeUnits = []struct {
+	suffix string
+	mult   uint64
+}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}}
+
+// ParseByteSize parses a size given in bytes, optionally with a KB, MB or GB
+// suffix (powers of 1024).
+func ParseByteSize(s string) (uint64, error) {
+	mult := uint64(1)
+	num := strings.ToUpper(strings.TrimSpace(s))
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(num, u.suffix) {
+			num, mult = strings.TrimSuffix(num, u.suffix), u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(strings.TrimSpace(num), 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+// FormatByteSize formats n in the largest unit accepted by ParseByteSize that
+// divides it exactly.
+func FormatByteSize(n uint64) string {
+	for _, u := range byteSizeUnits[:3] {
+		if n != 0 && n%u.mult == 0 {
+			return strconv.FormatUint(n/u.mult, 10) + u.suffix
+		}
+	}
+	return strconv.FormatUint(n, 10)
+}
@@ -0,0 +1,23 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import "testing"
+
+func TestFormatByteSize(t *testing.T) {
+	for _, n := range []uint64{0, 1, 1000, 1 << 10, 3 << 20, 1<<30 + 1, 5 << 30} {
+		s := FormatByteSize(n)
+		got, err := ParseByteSize(s)
+		if err != nil {
+			t.Errorf("could not parse %q, formatted from %d: %s", s, n, err)
+		} else if got != n {
+			t.Errorf("%d formatted as %q, which parses to %d", n, s, got)
+		}
+	}
+
+	if got := FormatByteSize(3 << 20); got != "3MB" {
+		t.Errorf("expected 3MB, got %q", got)
+	}
+}
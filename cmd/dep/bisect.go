@@ -0,0 +1,243 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
+)
+
+const bisectShortHelp = `Find the version of a dependency that broke the project`
+const bisectLongHelp = `
+Binary search the released versions of <project> between -good and -bad to
+find the first one with which the current project breaks.
+
+At each step, the version in the middle of the remaining range is written into
+vendor/ in place of the locked version of <project>, and the -cmd command is
+run in the project root; the version is good if the command succeeds, and bad
+otherwise. No other dependency is touched, and Gopkg.lock is never modified.
+When the search is done, vendor/ is put back as it was. bisect holds the lock
+of the project throughout, so no other dep command writes to it meanwhile.
+
+Versions are ordered as by dep ensure: semver versions in version order,
+followed by any other tags. Branches are not searched.
+
+Flags:
+
+  -good  A version of <project> with which the project works
+  -bad   A later version of <project> with which the project is broken
+  -cmd   The command to test each version with (default: go test ./...)
+
+Example:
+
+  dep bisect -good v1.2.0 -bad v1.6.0 -cmd "go build ./... && go test ./..." github.com/foo/bar
+`
+
+type bisectCommand struct {
+	good    string
+	bad     string
+	command string
+}
+
+func (cmd *bisectCommand) Name() string { return "bisect" }
+func (cmd *bisectCommand) Args() string {
+	return "-good <version> -bad <version> [-cmd <command>] <project>"
+}
+func (cmd *bisectCommand) ShortHelp() string { return bisectShortHelp }
+func (cmd *bisectCommand) LongHelp() string  { return bisectLongHelp }
+func (cmd *bisectCommand) Hidden() bool      { return false }
+
+func (cmd *bisectCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.good, "good", "", "a version of the dependency with which the project works")
+	fs.StringVar(&cmd.bad, "bad", "", "a later version of the dependency with which the project is broken")
+	fs.StringVar(&cmd.command, "cmd", "go test ./...", "the command to test each version with")
+}
+
+func (cmd *bisectCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) != 1 {
+		return errors.New("bisect requires exactly one project")
+	}
+	if cmd.good == "" || cmd.bad == "" {
+		return errors.New("bisect requires both -good and -bad versions")
+	}
+
+	// vendor is rewritten at each step, so no other dep command may write to
+	// the project until bisect has put it back.
+	lock, err := ctx.LockProject(false)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("no %s found in %s, run dep ensure first", dep.LockName, p.AbsRoot)
+	}
+
+	var lp gps.LockedProject
+	for _, candidate := range p.Lock.Projects() {
+		if string(candidate.Ident().ProjectRoot) == args[0] {
+			lp = candidate
+		}
+	}
+	if lp == nil {
+		return errors.Errorf("%s is not in %s", args[0], dep.LockName)
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	vl, err := sm.ListVersions(lp.Ident())
+	if err != nil {
+		return errors.Wrapf(err, "could not list versions of %s", lp.Ident())
+	}
+	candidates, err := bisectCandidates(vl, cmd.good, cmd.bad)
+	if err != nil {
+		return err
+	}
+
+	prune := p.Manifest.PruneOptions.PruneOptionsFor(lp.Ident().ProjectRoot)
+	if vp, ok := lp.(verify.VerifiableProject); ok {
+		prune = vp.PruneOpts
+	}
+
+	vdir := filepath.Join(p.AbsRoot, "vendor", string(lp.Ident().ProjectRoot))
+	restore, err := setAsideDir(vdir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := restore(); err != nil {
+			ctx.Err.Printf("Warning: %v\n", err)
+		}
+	}()
+
+	first, err := bisectVersions(candidates, func(v gps.PairedVersion, remaining int) (bool, error) {
+		ctx.Err.Printf("# Testing %s@%s (%d versions left to test)\n", lp.Ident().ProjectRoot, v, remaining)
+		if err := os.RemoveAll(vdir); err != nil {
+			return false, errors.Wrapf(err, "could not remove %s", vdir)
+		}
+		step := gps.NewLockedProject(lp.Ident(), v, lp.Packages())
+		if err := sm.ExportPrunedProject(context.TODO(), step, prune, vdir); err != nil {
+			return false, errors.Wrapf(err, "could not write %s@%s into vendor", lp.Ident(), v)
+		}
+
+		err := runShellCommand(ctx, p.AbsRoot, cmd.command)
+		if _, ok := err.(*exec.ExitError); ok {
+			ctx.Err.Printf("# %s is bad\n", v)
+			return false, nil
+		} else if err != nil {
+			return false, errors.Wrapf(err, "could not run %q", cmd.command)
+		}
+		ctx.Err.Printf("# %s is good\n", v)
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx.Out.Printf("%s is the first bad version of %s; the last good version is %s\n", candidates[first], lp.Ident().ProjectRoot, candidates[first-1])
+	return nil
+}
+
+// bisectCandidates returns the versions from good to bad, inclusive, in the
+// order in which dep would downgrade through them.
+func bisectCandidates(vl []gps.PairedVersion, good, bad string) ([]gps.PairedVersion, error) {
+	var tags []gps.PairedVersion
+	for _, v := range vl {
+		if t := v.Type(); t == gps.IsSemver || t == gps.IsVersion {
+			tags = append(tags, v)
+		}
+	}
+	gps.SortPairedForDowngrade(tags)
+
+	goodIdx, badIdx := -1, -1
+	for i, v := range tags {
+		switch v.String() {
+		case good:
+			goodIdx = i
+		case bad:
+			badIdx = i
+		}
+	}
+	switch {
+	case goodIdx == -1:
+		return nil, errors.Errorf("no version named %s", good)
+	case badIdx == -1:
+		return nil, errors.Errorf("no version named %s", bad)
+	case goodIdx >= badIdx:
+		return nil, errors.Errorf("the good version %s must come before the bad version %s", good, bad)
+	}
+	return tags[goodIdx : badIdx+1], nil
+}
+
+// bisectVersions binary searches versions, the first of which is assumed to
+// be good and the last bad, for the first version for which test reports
+// false. test is passed the number of untested versions in the range.
+func bisectVersions(versions []gps.PairedVersion, test func(v gps.PairedVersion, remaining int) (bool, error)) (int, error) {
+	good, bad := 0, len(versions)-1
+	for bad-good > 1 {
+		mid := good + (bad-good)/2
+		ok, err := test(versions[mid], bad-good-1)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			good = mid
+		} else {
+			bad = mid
+		}
+	}
+	return bad, nil
+}
+
+// setAsideDir moves dir, if it exists, into a temporary directory, and
+// returns a func that puts it back in place of whatever is then at dir.
+func setAsideDir(dir string) (func() error, error) {
+	td, err := ioutil.TempDir("", "dep-bisect")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temporary directory")
+	}
+	aside := filepath.Join(td, "orig")
+
+	existed := true
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		existed = false
+	} else if err := fs.RenameWithFallback(dir, aside); err != nil {
+		os.RemoveAll(td)
+		return nil, errors.Wrapf(err, "failed to move %s aside", dir)
+	}
+
+	return func() error {
+		defer os.RemoveAll(td)
+		if err := os.RemoveAll(dir); err != nil {
+			return errors.Wrapf(err, "failed to remove %s", dir)
+		}
+		if !existed {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dir), 0777); err != nil {
+			return errors.Wrapf(err, "failed to restore %s", dir)
+		}
+		return errors.Wrapf(fs.RenameWithFallback(aside, dir), "failed to restore %s", dir)
+	}, nil
+}
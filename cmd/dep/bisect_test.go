@@ -0,0 +1,94 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/test"
+)
+
+func pairedVersions(names ...string) []gps.PairedVersion {
+	var vl []gps.PairedVersion
+	for _, name := range names {
+		vl = append(vl, gps.NewVersion(name).Pair(gps.Revision("rev-"+name)))
+	}
+	return vl
+}
+
+func TestBisectCandidates(t *testing.T) {
+	vl := append(pairedVersions("v1.6.0", "v1.0.0", "v1.2.0", "v1.5.0", "v1.3.0-rc1", "v1.4.0", "v1.3.0", "v1.7.0"),
+		gps.NewBranch("master").Pair(gps.Revision("rev-master")))
+
+	got, err := bisectCandidates(vl, "v1.2.0", "v1.6.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range got {
+		names = append(names, v.String())
+	}
+	if want := []string{"v1.2.0", "v1.3.0", "v1.4.0", "v1.5.0", "v1.6.0"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected candidates:\n\t(GOT): %v\n\t(WNT): %v", names, want)
+	}
+
+	for _, c := range [][2]string{{"v1.6.0", "v1.2.0"}, {"v0.9.0", "v1.6.0"}, {"v1.2.0", "master"}} {
+		if _, err := bisectCandidates(vl, c[0], c[1]); err == nil {
+			t.Errorf("expected an error bisecting from %s to %s", c[0], c[1])
+		}
+	}
+}
+
+func TestBisectVersions(t *testing.T) {
+	versions := pairedVersions("v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0", "v1.5.0", "v1.6.0")
+
+	for firstBad := 1; firstBad < len(versions); firstBad++ {
+		var tested []string
+		got, err := bisectVersions(versions, func(v gps.PairedVersion, remaining int) (bool, error) {
+			tested = append(tested, v.String())
+			for i, cand := range versions {
+				if cand == v {
+					return i < firstBad, nil
+				}
+			}
+			t.Fatalf("tested unknown version %s", v)
+			return false, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != firstBad {
+			t.Errorf("expected %s to be found as the first bad version, got %s", versions[firstBad], versions[got])
+		}
+		if len(tested) > 3 {
+			t.Errorf("expected at most 3 steps to bisect 7 versions, took %d: %v", len(tested), tested)
+		}
+	}
+}
+
+func TestSetAsideDir(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("vendor/github.com/foo/bar/bar.go", "package bar")
+	dir := filepath.Join(h.Path("."), "vendor/github.com/foo/bar")
+
+	restore, err := setAsideDir(dir)
+	h.Must(err)
+	h.MustNotExist(dir)
+
+	h.TempFile("vendor/github.com/foo/bar/other.go", "package bar")
+	h.Must(restore())
+	h.MustNotExist(filepath.Join(dir, "other.go"))
+	got, err := ioutil.ReadFile(filepath.Join(dir, "bar.go"))
+	h.Must(err)
+	if string(got) != "package bar\n" {
+		t.Errorf("unexpected restored contents %q", got)
+	}
+}
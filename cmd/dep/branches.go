@@ -0,0 +1,197 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// branchReader is implemented by SourceManagers that can list the branches of
+// a source and report when their revisions were committed.
+type branchReader interface {
+	ListVersions(gps.ProjectIdentifier) ([]gps.PairedVersion, error)
+	RevisionTime(gps.ProjectIdentifier, gps.Version) (time.Time, error)
+}
+
+// branchLag describes how far the locked revision of a project constrained to
+// a branch has fallen behind the head of the branch.
+type branchLag struct {
+	ProjectRoot string
+	Branch      string
+	// Refresh is the refresh policy of the constraint.
+	Refresh string
+	// Locked is the locked revision, committed at LockedTime.
+	Locked     gps.Revision
+	LockedTime time.Time
+	// Head is the revision at the head of the branch, committed at HeadTime.
+	Head     gps.Revision
+	HeadTime time.Time
+	// Err is set when the head of the branch or the times could not be
+	// determined.
+	Err error
+}
+
+// behind returns how long before the head of the branch the locked revision
+// was committed, or zero if the lock is at the head.
+func (b branchLag) behind() time.Duration {
+	if b.Locked == b.Head || b.HeadTime.Before(b.LockedTime) {
+		return 0
+	}
+	return b.HeadTime.Sub(b.LockedTime)
+}
+
+// status summarizes b in a word or two, for the STATUS column.
+func (b branchLag) status() string {
+	switch {
+	case b.Err != nil:
+		return "unknown"
+	case b.Locked == b.Head:
+		return "up to date"
+	default:
+		return fmt.Sprintf("%d days behind", int(b.behind().Hours()/24))
+	}
+}
+
+type rawBranchLag struct {
+	ProjectRoot string `json:"projectRoot"`
+	Branch      string `json:"branch"`
+	Refresh     string `json:"refresh"`
+	Locked      string `json:"locked"`
+	LockedTime  string `json:"lockedTime,omitempty"`
+	Head        string `json:"head,omitempty"`
+	HeadTime    string `json:"headTime,omitempty"`
+	BehindDays  int    `json:"behindDays"`
+	Error       string `json:"error,omitempty"`
+}
+
+func (b branchLag) marshalJSON() rawBranchLag {
+	raw := rawBranchLag{
+		ProjectRoot: b.ProjectRoot,
+		Branch:      b.Branch,
+		Refresh:     b.Refresh,
+		Locked:      string(b.Locked),
+		Head:        string(b.Head),
+	}
+	if !b.LockedTime.IsZero() {
+		raw.LockedTime = b.LockedTime.Format(time.RFC3339)
+	}
+	if !b.HeadTime.IsZero() {
+		raw.HeadTime = b.HeadTime.Format(time.RFC3339)
+	}
+	if b.Err != nil {
+		raw.Error = b.Err.Error()
+	} else {
+		raw.BehindDays = int(b.behind().Hours() / 24)
+	}
+	return raw
+}
+
+// assessBranches compares the locked revision of each of lps that m
+// constrains to a branch with the head of that branch, as read from br. The
+// results are sorted by project root.
+func assessBranches(lps []gps.LockedProject, m *dep.Manifest, br branchReader) []branchLag {
+	var tracked []gps.LockedProject
+	for _, lp := range lps {
+		if _, ok := lockedBranch(m, lp); ok {
+			tracked = append(tracked, lp)
+		}
+	}
+	reports := make([]branchLag, len(tracked))
+
+	var wg sync.WaitGroup
+	for i, lp := range tracked {
+		wg.Add(1)
+		go func(i int, lp gps.LockedProject) {
+			defer wg.Done()
+			id := lp.Ident()
+			branch, _ := lockedBranch(m, lp)
+			b := branchLag{
+				ProjectRoot: string(id.ProjectRoot),
+				Branch:      branch,
+				Refresh:     m.RefreshPolicy(id.ProjectRoot),
+			}
+			defer func() { reports[i] = b }()
+
+			if pv, ok := lp.Version().(gps.PairedVersion); ok {
+				b.Locked = pv.Revision()
+			} else if r, ok := lp.Version().(gps.Revision); ok {
+				b.Locked = r
+			}
+
+			versions, err := br.ListVersions(id)
+			if err != nil {
+				b.Err = errors.Wrapf(err, "could not list the versions of %s", id)
+				return
+			}
+			for _, pv := range versions {
+				if pv.Type() == gps.IsBranch && pv.String() == branch {
+					b.Head = pv.Revision()
+				}
+			}
+			if b.Head == "" {
+				b.Err = errors.Errorf("branch %s no longer exists in %s", branch, id)
+				return
+			}
+
+			if b.LockedTime, err = br.RevisionTime(id, b.Locked); err != nil {
+				b.Err = errors.Wrapf(err, "could not read the time of the locked revision of %s", id)
+				return
+			}
+			if b.HeadTime, err = br.RevisionTime(id, b.Head); err != nil {
+				b.Err = errors.Wrapf(err, "could not read the time of the head of %s in %s", branch, id)
+			}
+		}(i, lp)
+	}
+	wg.Wait()
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ProjectRoot < reports[j].ProjectRoot })
+	return reports
+}
+
+// lockedBranch returns the branch to which m constrains lp, if any.
+func lockedBranch(m *dep.Manifest, lp gps.LockedProject) (string, bool) {
+	v, ok := m.Constraints[lp.Ident().ProjectRoot].Constraint.(gps.Version)
+	if !ok || v.Type() != gps.IsBranch {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// printBranches writes reports to w as a table, or as JSON.
+func printBranches(w io.Writer, reports []branchLag, asJSON bool) error {
+	if asJSON {
+		raw := make([]rawBranchLag, 0, len(reports))
+		for _, b := range reports {
+			raw = append(raw, b.marshalJSON())
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(raw)
+	}
+
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tBRANCH\tREFRESH\tLOCKED\tHEAD\tSTATUS")
+	for _, b := range reports {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", b.ProjectRoot, b.Branch, b.Refresh, date(b.LockedTime), date(b.HeadTime), b.status())
+	}
+	return tw.Flush()
+}
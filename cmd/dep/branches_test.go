@@ -0,0 +1,128 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// fakeBranchReader maps project roots to the revisions at the heads of their
+// master branches, and revisions to their commit times.
+type fakeBranchReader struct {
+	heads map[gps.ProjectRoot]gps.Revision
+	times map[gps.Revision]time.Time
+}
+
+func (f fakeBranchReader) ListVersions(id gps.ProjectIdentifier) ([]gps.PairedVersion, error) {
+	head, ok := f.heads[id.ProjectRoot]
+	if !ok {
+		return nil, errors.New("no such source")
+	}
+	return []gps.PairedVersion{gps.NewBranch("master").Pair(head)}, nil
+}
+
+func (f fakeBranchReader) RevisionTime(id gps.ProjectIdentifier, v gps.Version) (time.Time, error) {
+	return f.times[v.(gps.Revision)], nil
+}
+
+func TestAssessBranches(t *testing.T) {
+	locked := gps.Revision("d4a1a8e2a4f50f8b4e610b7ab3e8b3a4a4b6c0de")
+	newer := gps.Revision("5b1d5e0a2c8e6b4f1a1e8d0f9b2c3a4d5e6f7a8b")
+	at := time.Date(2018, time.June, 1, 0, 0, 0, 0, time.UTC)
+	br := fakeBranchReader{
+		heads: map[gps.ProjectRoot]gps.Revision{
+			"github.com/current/lib": locked,
+			"github.com/lagging/lib": newer,
+		},
+		times: map[gps.Revision]time.Time{
+			locked: at,
+			newer:  at.AddDate(0, 0, 10),
+		},
+	}
+
+	m := dep.NewManifest()
+	for _, root := range []gps.ProjectRoot{"github.com/current/lib", "github.com/lagging/lib", "github.com/missing/lib"} {
+		m.Constraints[root] = gps.ProjectProperties{Constraint: gps.NewBranch("master")}
+	}
+	m.Constraints["github.com/tagged/lib"] = gps.ProjectProperties{Constraint: gps.NewVersion("v1.0.0")}
+	m.Refresh = map[gps.ProjectRoot]string{"github.com/lagging/lib": dep.RefreshNever}
+	lps := lockedProjects("github.com/lagging/lib", "github.com/tagged/lib", "github.com/current/lib", "github.com/missing/lib")
+
+	reports := assessBranches(lps, m, br)
+	want := map[string]string{
+		"github.com/current/lib": "up to date",
+		"github.com/lagging/lib": "10 days behind",
+		"github.com/missing/lib": "unknown",
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("expected %d reports, got %d", len(want), len(reports))
+	}
+	for i, b := range reports {
+		if i > 0 && reports[i-1].ProjectRoot > b.ProjectRoot {
+			t.Errorf("reports are not sorted: %s before %s", reports[i-1].ProjectRoot, b.ProjectRoot)
+		}
+		if got := b.status(); got != want[b.ProjectRoot] {
+			t.Errorf("%s: expected status %q, got %q", b.ProjectRoot, want[b.ProjectRoot], got)
+		}
+	}
+	if reports[1].Refresh != dep.RefreshNever || reports[0].Refresh != dep.RefreshUpdate {
+		t.Errorf("unexpected refresh policies %q and %q", reports[0].Refresh, reports[1].Refresh)
+	}
+}
+
+func TestPrintBranches(t *testing.T) {
+	at := time.Date(2018, time.June, 1, 0, 0, 0, 0, time.UTC)
+	reports := []branchLag{
+		{
+			ProjectRoot: "github.com/lagging/lib",
+			Branch:      "master",
+			Refresh:     dep.RefreshWeekly,
+			Locked:      gps.Revision("d4a1a8e2a4f50f8b4e610b7ab3e8b3a4a4b6c0de"),
+			LockedTime:  at,
+			Head:        gps.Revision("5b1d5e0a2c8e6b4f1a1e8d0f9b2c3a4d5e6f7a8b"),
+			HeadTime:    at.AddDate(0, 0, 3),
+		},
+		{
+			ProjectRoot: "github.com/missing/lib",
+			Branch:      "develop",
+			Refresh:     dep.RefreshUpdate,
+			Err:         errors.New("no such source"),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := printBranches(&buf, reports, false); err != nil {
+		t.Fatal(err)
+	}
+	want := `PROJECT                 BRANCH   REFRESH  LOCKED      HEAD        STATUS
+github.com/lagging/lib  master   weekly   2018-06-01  2018-06-04  3 days behind
+github.com/missing/lib  develop  update   -           -           unknown
+`
+	if buf.String() != want {
+		t.Errorf("unexpected table:\n\t(GOT):\n%s\n\t(WNT):\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := printBranches(&buf, reports, true); err != nil {
+		t.Fatal(err)
+	}
+	var raw []rawBranchLag
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw[0].BehindDays != 3 || raw[0].HeadTime != "2018-06-04T00:00:00Z" {
+		t.Errorf("unexpected JSON for lagging project: %+v", raw[0])
+	}
+	if raw[1].Error != "no such source" || raw[1].LockedTime != "" {
+		t.Errorf("unexpected JSON for unknown project: %+v", raw[1])
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// projectGraph maps each project to the projects its used packages import.
+type projectGraph map[gps.ProjectRoot][]gps.ProjectRoot
+
+// buildProjectGraph builds the project-level import graph of the root project
+// and the projects in l, following only the packages the lock says are used.
+func buildProjectGraph(p *dep.Project, sm gps.SourceManager, l gps.Lock) (projectGraph, error) {
+	var roots []gps.ProjectRoot
+	for _, lp := range l.Projects() {
+		roots = append(roots, lp.Ident().ProjectRoot)
+	}
+	// Longest roots first, so that nested projects are matched before the
+	// projects containing them.
+	sort.Slice(roots, func(i, j int) bool { return len(roots[i]) > len(roots[j]) })
+	rootOf := func(ip string) (gps.ProjectRoot, bool) {
+		for _, pr := range roots {
+			if ip == string(pr) || strings.HasPrefix(ip, string(pr)+"/") {
+				return pr, true
+			}
+		}
+		return "", false
+	}
+
+	graph := make(projectGraph)
+	addImports := func(from gps.ProjectRoot, imps []string) {
+		seen := make(map[gps.ProjectRoot]bool)
+		for _, pr := range graph[from] {
+			seen[pr] = true
+		}
+		for _, imp := range imps {
+			if pr, has := rootOf(imp); has && pr != from && !seen[pr] {
+				seen[pr] = true
+				graph[from] = append(graph[from], pr)
+			}
+		}
+	}
+
+	rootPR := gps.ProjectRoot(p.ImportRoot)
+	for _, perr := range p.RootPackageTree.Packages {
+		if perr.Err == nil {
+			addImports(rootPR, perr.P.Imports)
+			addImports(rootPR, perr.P.TestImports)
+		}
+	}
+	if p.Manifest != nil {
+		addImports(rootPR, p.Manifest.Required)
+	}
+
+	for _, lp := range l.Projects() {
+		pr := lp.Ident().ProjectRoot
+		ptree, err := sm.ListPackages(lp.Ident(), lp.Version())
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not list packages in %s", lp.Ident())
+		}
+		for _, rel := range lp.Packages() {
+			if perr, has := ptree.Packages[path.Join(string(pr), rel)]; has && perr.Err == nil {
+				addImports(pr, perr.P.Imports)
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// shortestChains returns, for each project reachable from root in graph, the
+// shortest chain of projects by which it is reached, starting with root.
+func (graph projectGraph) shortestChains(root gps.ProjectRoot) map[gps.ProjectRoot][]gps.ProjectRoot {
+	chains := map[gps.ProjectRoot][]gps.ProjectRoot{root: {root}}
+	queue := []gps.ProjectRoot{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		next := append([]gps.ProjectRoot(nil), graph[cur]...)
+		sort.Slice(next, func(i, j int) bool { return next[i] < next[j] })
+		for _, pr := range next {
+			if _, has := chains[pr]; has {
+				continue
+			}
+			chain := append(append([]gps.ProjectRoot(nil), chains[cur]...), pr)
+			chains[pr] = chain
+			queue = append(queue, pr)
+		}
+	}
+	delete(chains, root)
+	return chains
+}
+
+// depthViolation describes the projects that lie deeper than maxDepth in
+// chains, or returns an empty string if there are none.
+func depthViolation(chains map[gps.ProjectRoot][]gps.ProjectRoot, maxDepth int) string {
+	var deep []gps.ProjectRoot
+	for pr, chain := range chains {
+		if len(chain)-1 > maxDepth {
+			deep = append(deep, pr)
+		}
+	}
+	if len(deep) == 0 {
+		return ""
+	}
+
+	// Report the deepest, breaking ties by name.
+	sort.Slice(deep, func(i, j int) bool {
+		di, dj := len(chains[deep[i]]), len(chains[deep[j]])
+		if di != dj {
+			return di > dj
+		}
+		return deep[i] < deep[j]
+	})
+	chain := chains[deep[0]]
+	names := make([]string, len(chain))
+	for i, pr := range chain {
+		names[i] = string(pr)
+	}
+	return fmt.Sprintf("%d projects are deeper than the max-depth of %d; the deepest, %s at depth %d, is reached by way of %s",
+		len(deep), maxDepth, deep[0], len(chain)-1, strings.Join(names, " -> "))
+}
+
+// checkLockBudget checks l against the project count and depth limits of
+// p's budget.
+func checkLockBudget(ctx *dep.Ctx, p *dep.Project, sm gps.SourceManager, l gps.Lock) error {
+	b := p.Manifest.Budget
+	var violations []string
+
+	if b.MaxProjects > 0 {
+		if n := len(l.Projects()); n > b.MaxProjects {
+			violations = append(violations, fmt.Sprintf("%s holds %d projects, more than the max-projects of %d", dep.LockName, n, b.MaxProjects))
+		}
+	}
+
+	if b.MaxDepth > 0 {
+		graph, err := buildProjectGraph(p, sm, l)
+		if err != nil {
+			return errors.Wrap(err, "could not determine the depth of dependencies")
+		}
+		if v := depthViolation(graph.shortestChains(gps.ProjectRoot(p.ImportRoot)), b.MaxDepth); v != "" {
+			violations = append(violations, v)
+		}
+	}
+
+	return reportBudget(ctx, b, violations)
+}
+
+// checkVendorBudget checks the size of p's vendor directory against the
+// max-vendor-size of its budget.
+func checkVendorBudget(ctx *dep.Ctx, p *dep.Project) error {
+	b := p.Manifest.Budget
+	if b.MaxVendorSize == 0 {
+		return nil
+	}
+
+	size, err := dirSize(filepath.Join(p.AbsRoot, "vendor"))
+	if err != nil {
+		return errors.Wrap(err, "could not determine the size of vendor")
+	}
+	if size <= b.MaxVendorSize {
+		return nil
+	}
+	return reportBudget(ctx, b, []string{
+		fmt.Sprintf("vendor is %s, larger than the max-vendor-size of %s", humanByteSize(size), humanByteSize(b.MaxVendorSize)),
+	})
+}
+
+// reportBudget returns an error listing violations if the budget is
+// enforced, and otherwise prints them as warnings.
+func reportBudget(ctx *dep.Ctx, b dep.BudgetOptions, violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	if b.Enforce {
+		return errors.Errorf("dependency budget exceeded:\n\t%s", strings.Join(violations, "\n\t"))
+	}
+	for _, v := range violations {
+		ctx.Err.Printf("Warning: dependency budget exceeded: %s\n", v)
+	}
+	return nil
+}
+
+// dirSize returns the total size of the regular files under dir, or zero if
+// dir does not exist.
+func dirSize(dir string) (uint64, error) {
+	size, _, err := dirUsage(dir)
+	return size, err
+}
+
+// dirUsage returns the total size and the number of the regular files under
+// dir, or zero for both if dir does not exist.
+func dirUsage(dir string) (size uint64, files int, err error) {
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += uint64(fi.Size())
+			files++
+		}
+		return nil
+	})
+	return size, files, err
+}
+
+// humanByteSize formats n to one decimal place in the largest unit that it
+// is at least one of.
+func humanByteSize(n uint64) string {
+	for _, u := range []struct {
+		suffix string
+		mult   uint64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if n >= u.mult {
+			return fmt.Sprintf("%.1f%s", float64(n)/float64(u.mult), u.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
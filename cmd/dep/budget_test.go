@@ -0,0 +1,77 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestShortestChains(t *testing.T) {
+	graph := projectGraph{
+		"root": {"a", "b"},
+		"a":    {"c"},
+		"b":    {"c", "d"},
+		"c":    {"e", "a"},
+		"d":    nil,
+	}
+
+	chains := graph.shortestChains("root")
+	want := map[gps.ProjectRoot][]gps.ProjectRoot{
+		"a": {"root", "a"},
+		"b": {"root", "b"},
+		"c": {"root", "a", "c"},
+		"d": {"root", "b", "d"},
+		"e": {"root", "a", "c", "e"},
+	}
+	if !reflect.DeepEqual(chains, want) {
+		t.Fatalf("unexpected chains:\n\t(GOT): %v\n\t(WNT): %v", chains, want)
+	}
+
+	if v := depthViolation(chains, 3); v != "" {
+		t.Errorf("expected no violation at max-depth 3, got %q", v)
+	}
+	v := depthViolation(chains, 1)
+	if !strings.HasPrefix(v, "3 projects are deeper than the max-depth of 1; the deepest, e at depth 3, is reached by way of root -> a -> c -> e") {
+		t.Errorf("unexpected violation: %q", v)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("vendor/a/a.go", "package a")
+	h.TempFile("vendor/b/b.go", "package b\n")
+
+	var want uint64
+	for _, name := range []string{"vendor/a/a.go", "vendor/b/b.go"} {
+		fi, err := os.Stat(h.Path(name))
+		h.Must(err)
+		want += uint64(fi.Size())
+	}
+
+	size, err := dirSize(h.Path("vendor"))
+	h.Must(err)
+	if size != want {
+		t.Errorf("expected a size of %d, got %d", want, size)
+	}
+
+	size, err = dirSize(filepath.Join(h.Path("."), "missing"))
+	h.Must(err)
+	if size != 0 {
+		t.Errorf("expected a missing directory to have size 0, got %d", size)
+	}
+
+	if got := humanByteSize(3 << 19); got != "1.5MB" {
+		t.Errorf("unexpected human size %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"sync"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const bundleShortHelp = `Write a metadata bundle for use without network access`
+const bundleLongHelp = `
+Write a snapshot of the metadata that dep fetches from upstream about the
+current project's dependencies into <dir>, so that it can be carried into a
+network with no internet access.
+
+The bundle records the versions currently published by each project in
+Gopkg.lock, in ` + dep.BundleVersionsFile + `, and a copy of the yanked versions feed named by
+$DEPYANKED, if any, in ` + dep.BundleYankedFile + `. Both are plain text, so bundles for
+several projects can be concatenated, or written by other tools.
+
+Setting $DEPBUNDLE to the path of a bundle makes dep use it in place of the
+network: dep status takes the LATEST column from the versions in the bundle,
+and the yanked versions in the bundle are used whenever $DEPYANKED is not set.
+`
+
+type bundleCommand struct{}
+
+func (cmd *bundleCommand) Name() string      { return "bundle" }
+func (cmd *bundleCommand) Args() string      { return "<dir>" }
+func (cmd *bundleCommand) ShortHelp() string { return bundleShortHelp }
+func (cmd *bundleCommand) LongHelp() string  { return bundleLongHelp }
+func (cmd *bundleCommand) Hidden() bool      { return false }
+
+func (cmd *bundleCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *bundleCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) != 1 {
+		return errors.New("bundle takes exactly one argument, the directory to write the bundle into")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("no Gopkg.lock found in %s, run dep ensure first", p.AbsRoot)
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	yanked, err := ctx.LoadYankedVersions()
+	if err != nil {
+		return err
+	}
+
+	b := &dep.Bundle{
+		Versions: bundleVersions(ctx, sm, p.Lock.Projects()),
+		Yanked:   yanked,
+	}
+	if err := dep.WriteBundle(args[0], b); err != nil {
+		return err
+	}
+
+	if len(b.Versions) < len(p.Lock.Projects()) {
+		return errors.Errorf("failed to list the versions of %d of %d projects", len(p.Lock.Projects())-len(b.Versions), len(p.Lock.Projects()))
+	}
+	return nil
+}
+
+// bundleVersions lists the upstream versions of each of lps. Projects whose
+// versions cannot be listed are reported on ctx.Err, and omitted.
+func bundleVersions(ctx *dep.Ctx, sm gps.SourceManager, lps []gps.LockedProject) map[gps.ProjectRoot][]gps.PairedVersion {
+	versions := make(map[gps.ProjectRoot][]gps.PairedVersion, len(lps))
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, lp := range lps {
+		wg.Add(1)
+		go func(id gps.ProjectIdentifier) {
+			defer wg.Done()
+
+			vl, err := sm.ListVersions(id)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				ctx.Err.Printf("Unable to list the versions of %s: %s\n", id.ProjectRoot, err)
+				return
+			}
+			versions[id.ProjectRoot] = vl
+		}(lp.Ident())
+	}
+	wg.Wait()
+
+	return versions
+}
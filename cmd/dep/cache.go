@@ -0,0 +1,962 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
+)
+
+const cacheShortHelp = `Maintain the local cache of sources`
+const cacheLongHelp = `
+Cache provides maintenance operations on the local cache of source
+repositories, in $DEPCACHEDIR (by default, $GOPATH/pkg/dep).
+
+  dep cache relocate [<old cache dir>]
+  dep cache export [-lock <lock file>] -o <bundle>
+  dep cache import <bundle>
+  dep cache register <lock file>...
+  dep cache gc [-dry-run] [<lock file>...]
+  dep cache stats [-by-project]
+
+Relocate fixes up a cache that has been moved or copied from elsewhere, such as
+one populated as root in a Docker image layer and then used as another user.
+dep itself only records paths relative to the cache, but repositories may have
+picked up absolute paths, such as in git alternates or gitdir files, or in
+mercurial shared paths. Those that point into <old cache dir> are rewritten to
+point into the cache; the work tree of git repositories is always recorded as
+where the repository is found. Repositories that refer to absolute paths that
+no longer exist are removed, to be fetched again on demand.
+
+Relocate also reports repositories that the current user cannot write to, which
+dep would fail to update. dep cannot change their owner; run, as root:
+
+  chown -R <user> $DEPCACHEDIR
+
+dep trusts the repositories in its cache regardless of who owns them, so git's
+safe.directory setting need not be changed to use a cache owned by another
+user.
+
+Export writes a bundle of the sources in the cache that are needed for the
+projects in a lock, Gopkg.lock in the current project unless -lock names
+another one, into a gzipped tarball. Each git repository in the bundle holds no
+more than the revisions in the lock, tagged or branched as in the lock;
+repositories of other kinds are included in full. Sources missing from the
+cache, or lacking a locked revision, are fetched first.
+
+Import unpacks such a bundle into the cache, to seed a CI image or a machine
+without network access. Sources that are already in the cache are left as they
+are. dep ensure -vendor-only can then populate vendor from the imported sources.
+
+Register records lock files in the registry of locks known to the cache, in
+$DEPCACHEDIR/` + dep.LockRegistryFile + `.
+
+GC reclaims the space taken by history that no known lock needs: every git
+repository in the cache is replaced by a shallow copy holding only the
+revisions referenced by the registered locks and by those given as arguments,
+and repositories holding none of them are removed altogether. Repositories of
+other kinds are left as they are. Registered locks that no longer exist are
+skipped. This is aggressive: whatever is needed later is fetched from upstream
+again. With -dry-run, gc only reports what it would do.
+
+Stats reports the number and size of the sources in the cache, and the size of
+its metadata database. With -by-project, the git sources are attributed to the
+registered projects whose locks they hold revisions for; a source shared by
+several projects counts towards each of them.
+`
+
+type cacheCommand struct{}
+
+func (cmd *cacheCommand) Name() string { return "cache" }
+func (cmd *cacheCommand) Args() string {
+	return "relocate [<old cache dir>] | export [-lock <lock file>] -o <bundle> | import <bundle> | register <lock file>... | gc [-dry-run] [<lock file>...] | stats [-by-project]"
+}
+func (cmd *cacheCommand) ShortHelp() string { return cacheShortHelp }
+func (cmd *cacheCommand) LongHelp() string  { return cacheLongHelp }
+func (cmd *cacheCommand) Hidden() bool      { return false }
+
+func (cmd *cacheCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *cacheCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) == 0 {
+		return errors.New("cache requires a subcommand: relocate, export, import, register, gc or stats")
+	}
+	switch args[0] {
+	case "relocate":
+		return cmd.runRelocate(ctx, args[1:])
+	case "export":
+		return cmd.runExport(ctx, args[1:])
+	case "import":
+		return cmd.runImport(ctx, args[1:])
+	case "register":
+		return cmd.runRegister(ctx, args[1:])
+	case "gc":
+		return cmd.runGC(ctx, args[1:])
+	case "stats":
+		return cmd.runStats(ctx, args[1:])
+	}
+	return errors.Errorf("unknown cache subcommand %q: must be relocate, export, import, register, gc or stats", args[0])
+}
+
+func (cmd *cacheCommand) runRelocate(ctx *dep.Ctx, args []string) error {
+	if len(args) > 1 {
+		return errors.New("cache relocate takes at most one argument, the old cache dir")
+	}
+	var old string
+	if len(args) == 1 {
+		var err error
+		if old, err = filepath.Abs(args[0]); err != nil {
+			return errors.Wrapf(err, "invalid cache dir %s", args[0])
+		}
+	}
+
+	// Hold the cache lock, so that no other dep process uses the sources
+	// while they are being fixed up.
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	defer sm.Release()
+
+	res, err := relocateCache(sm.Cachedir(), old)
+	if err != nil {
+		return err
+	}
+	for _, name := range res.removed {
+		ctx.Err.Printf("Removed %s, which refers to paths that no longer exist\n", name)
+	}
+	for _, name := range res.unwritable {
+		ctx.Err.Printf("Warning: %s is not writable by the current user\n", name)
+	}
+	ctx.Out.Printf("Checked %d sources: fixed %d, removed %d\n", res.checked, len(res.fixed), len(res.removed))
+	if len(res.unwritable) > 0 {
+		ctx.Err.Printf("dep cannot update the %d unwritable sources; change their owner with chown -R as root\n", len(res.unwritable))
+	}
+	return nil
+}
+
+func (cmd *cacheCommand) runExport(ctx *dep.Ctx, args []string) error {
+	flags := flag.NewFlagSet("cache export", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	lockFile := flags.String("lock", "", "lock file naming the sources to export")
+	out := flags.String("o", "", "path of the bundle to write")
+	if err := flags.Parse(args); err != nil {
+		return errors.Wrap(err, "cache export")
+	}
+	if *out == "" || flags.NArg() != 0 {
+		return errors.New("cache export requires -o <bundle>, and takes no arguments")
+	}
+
+	var l *dep.Lock
+	if *lockFile == "" {
+		p, err := ctx.LoadProject()
+		if err != nil {
+			return err
+		}
+		if p.Lock == nil {
+			return errors.Errorf("no Gopkg.lock found in %s, run dep ensure first", p.AbsRoot)
+		}
+		l = p.Lock
+	} else {
+		var err error
+		if l, err = readLockFile(*lockFile); err != nil {
+			return err
+		}
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	n, err := exportCache(sm, l.Projects(), *out)
+	if err != nil {
+		return err
+	}
+	ctx.Out.Printf("Exported %d sources for %d projects to %s\n", n, len(l.Projects()), *out)
+	return nil
+}
+
+func (cmd *cacheCommand) runImport(ctx *dep.Ctx, args []string) error {
+	if len(args) != 1 {
+		return errors.New("cache import takes exactly one argument, the bundle to import")
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	defer sm.Release()
+
+	imported, skipped, err := importCache(sm.Cachedir(), args[0])
+	if err != nil {
+		return err
+	}
+	if ctx.Verbose {
+		for _, name := range skipped {
+			ctx.Err.Printf("%s is already in the cache, leaving it as it is\n", name)
+		}
+	}
+	ctx.Out.Printf("Imported %d sources, %d already in the cache\n", len(imported), len(skipped))
+	return nil
+}
+
+func (cmd *cacheCommand) runRegister(ctx *dep.Ctx, args []string) error {
+	if len(args) == 0 {
+		return errors.New("cache register requires the lock files to register")
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	defer sm.Release()
+
+	return dep.RegisterLocks(sm.Cachedir(), args...)
+}
+
+func (cmd *cacheCommand) runGC(ctx *dep.Ctx, args []string) error {
+	flags := flag.NewFlagSet("cache gc", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	dryRun := flags.Bool("dry-run", false, "only report what would be done")
+	if err := flags.Parse(args); err != nil {
+		return errors.Wrap(err, "cache gc")
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	defer sm.Release()
+
+	locks, err := readRegisteredLocks(sm.Cachedir())
+	if err != nil {
+		return err
+	}
+	for _, rl := range locks {
+		if rl.lock == nil {
+			ctx.Err.Printf("Skipping %s, which no longer exists\n", rl.path)
+		}
+	}
+	for _, path := range flags.Args() {
+		l, err := readLockFile(path)
+		if err != nil {
+			return err
+		}
+		locks = append(locks, registeredLock{path: path, lock: l})
+	}
+	lps := registeredProjects(locks)
+	// Without any locks, gc would remove every source in the cache.
+	if len(lps) == 0 {
+		return errors.New("no locked projects to keep revisions for; register locks with dep cache register, or name them as arguments")
+	}
+
+	res, err := gcCache(sm.Cachedir(), lps, *dryRun)
+	if err != nil {
+		return err
+	}
+	if ctx.Verbose {
+		for _, name := range res.removed {
+			ctx.Err.Printf("%s holds no locked revisions\n", name)
+		}
+	}
+	verb := "Repacked"
+	if *dryRun {
+		verb = "Would repack"
+	}
+	ctx.Out.Printf("%s %d git sources and remove %d holding no locked revisions, reclaiming %s\n",
+		verb, len(res.repacked), len(res.removed), formatMB(res.reclaimed))
+	return nil
+}
+
+func (cmd *cacheCommand) runStats(ctx *dep.Ctx, args []string) error {
+	flags := flag.NewFlagSet("cache stats", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	byProject := flags.Bool("by-project", false, "attribute sources to registered projects")
+	if err := flags.Parse(args); err != nil {
+		return errors.Wrap(err, "cache stats")
+	}
+	if flags.NArg() != 0 {
+		return errors.New("cache stats takes no arguments")
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	defer sm.Release()
+
+	st, err := readCacheStats(sm.Cachedir())
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	st.write(&buf)
+
+	if *byProject {
+		locks, err := readRegisteredLocks(sm.Cachedir())
+		if err != nil {
+			return err
+		}
+		usage, err := attributeSources(sm.Cachedir(), st.sources, locks)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		writeProjectUsage(&buf, usage)
+	}
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// relocation describes what relocateCache did to each source in the cache.
+type relocation struct {
+	checked    int
+	fixed      []string
+	removed    []string
+	unwritable []string
+}
+
+// relocateCache fixes up the absolute paths recorded by the repositories in
+// the cache at cachedir, which was previously at old, if old is not empty.
+func relocateCache(cachedir, old string) (relocation, error) {
+	var res relocation
+	srcdir := filepath.Join(cachedir, "sources")
+	fis, err := ioutil.ReadDir(srcdir)
+	if os.IsNotExist(err) {
+		return res, nil
+	} else if err != nil {
+		return res, errors.Wrap(err, "failed to read the sources in the cache")
+	}
+
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		res.checked++
+		dir := filepath.Join(srcdir, fi.Name())
+		fixed, broken, err := relocateRepo(dir, cachedir, old)
+		switch {
+		case broken:
+			if err := os.RemoveAll(dir); err != nil {
+				return res, errors.Wrapf(err, "failed to remove %s", fi.Name())
+			}
+			res.removed = append(res.removed, fi.Name())
+			continue
+		case err != nil:
+			return res, errors.Wrapf(err, "failed to relocate %s", fi.Name())
+		case fixed:
+			res.fixed = append(res.fixed, fi.Name())
+		}
+		if !dirWritable(dir) {
+			res.unwritable = append(res.unwritable, fi.Name())
+		}
+	}
+	return res, nil
+}
+
+// relocateRepo rewrites the paths into old recorded by the repository at dir
+// to point into cachedir instead. It reports whether anything was changed, and
+// whether the repository refers to an absolute path that does not exist.
+func relocateRepo(dir, cachedir, old string) (fixed, broken bool, err error) {
+	rewrite := func(p string) (string, bool) {
+		if old == "" || !filepath.IsAbs(p) {
+			return p, false
+		}
+		rel, err := filepath.Rel(old, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return p, false
+		}
+		return filepath.Join(cachedir, rel), true
+	}
+	exists := func(p string) bool {
+		_, err := os.Stat(p)
+		return err == nil
+	}
+
+	// A .git file names the repository's git dir.
+	gitdir := filepath.Join(dir, ".git")
+	if b, err := ioutil.ReadFile(gitdir); err == nil {
+		p := strings.TrimSpace(strings.TrimPrefix(string(b), "gitdir:"))
+		np, changed := rewrite(p)
+		if changed {
+			if err := ioutil.WriteFile(gitdir, []byte("gitdir: "+np+"\n"), 0666); err != nil {
+				return false, false, err
+			}
+			fixed = true
+		}
+		if !filepath.IsAbs(np) {
+			np = filepath.Join(dir, np)
+		}
+		if !exists(np) {
+			return fixed, true, nil
+		}
+		gitdir = np
+	}
+
+	// Relative paths in these files are relative to the objects and .hg
+	// directories, respectively.
+	for _, f := range [][2]string{
+		{filepath.Join(gitdir, "objects", "info", "alternates"), filepath.Join(gitdir, "objects")},
+		{filepath.Join(dir, ".hg", "sharedpath"), filepath.Join(dir, ".hg")},
+	} {
+		changed, missing, err := relocatePathList(f[0], f[1], rewrite, exists)
+		if err != nil {
+			return fixed, false, err
+		}
+		if missing {
+			return fixed, true, nil
+		}
+		fixed = fixed || changed
+	}
+
+	// The work tree of dep's git repositories is always where they are; an
+	// absolute core.worktree setting could only point somewhere stale.
+	if config := filepath.Join(gitdir, "config"); exists(config) {
+		out, err := runGit("", "config", "-f", config, "--get", "core.worktree")
+		if err == nil && filepath.IsAbs(out) {
+			if _, err := runGit("", "config", "-f", config, "--unset", "core.worktree"); err != nil {
+				return fixed, false, errors.Wrap(err, "failed to unset core.worktree")
+			}
+			fixed = true
+		}
+	}
+	return fixed, false, nil
+}
+
+// relocatePathList rewrites the paths, one per line, in the file at path, if
+// it exists. Relative paths are taken to be relative to base. It reports
+// whether the file was changed, and whether any of the paths do not exist.
+func relocatePathList(path, base string, rewrite func(string) (string, bool), exists func(string) bool) (changed, missing bool, err error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, false, nil
+	} else if err != nil {
+		return false, false, err
+	}
+
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			out.WriteString(line + "\n")
+			continue
+		}
+		np, ok := rewrite(line)
+		changed = changed || ok
+		check := np
+		if !filepath.IsAbs(check) {
+			check = filepath.Join(base, check)
+		}
+		if !exists(check) {
+			missing = true
+		}
+		out.WriteString(np + "\n")
+	}
+	if err := sc.Err(); err != nil {
+		return false, false, err
+	}
+	if changed {
+		if err := ioutil.WriteFile(path, out.Bytes(), 0666); err != nil {
+			return false, false, err
+		}
+	}
+	return changed, missing, nil
+}
+
+// dirWritable reports whether the current user can create files in dir.
+func dirWritable(dir string) bool {
+	f, err := ioutil.TempFile(dir, ".dep-writable")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return true
+}
+
+// exportCache writes a bundle of the sources in the cache of sm needed for
+// lps, as a gzipped tarball laid out like the cache, to out. It returns the
+// number of sources in the bundle.
+func exportCache(sm *gps.SourceMgr, lps []gps.LockedProject, out string) (int, error) {
+	srcdir := filepath.Join(sm.Cachedir(), "sources")
+
+	// Projects sharing a source are exported together.
+	var names []string
+	bySource := make(map[string][]gps.LockedProject)
+	for _, lp := range lps {
+		rev := lockedRevision(lp.Version())
+		if rev == "" {
+			return 0, errors.Errorf("%s is not locked to a revision", lp.Ident())
+		}
+		dir, err := sm.SourceDir(lp.Ident(), rev)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to fetch %s", lp.Ident())
+		}
+		name, err := filepath.Rel(srcdir, dir)
+		if err != nil || strings.HasPrefix(name, "..") {
+			return 0, errors.Errorf("source of %s is outside of the cache", lp.Ident())
+		}
+		if _, ok := bySource[name]; !ok {
+			names = append(names, name)
+		}
+		bySource[name] = append(bySource[name], lp)
+	}
+
+	tmp, err := ioutil.TempDir("", "dep-cache-export")
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create a temporary directory")
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, name := range names {
+		src, dst := filepath.Join(srcdir, name), filepath.Join(tmp, "sources", name)
+		if fi, err := os.Stat(filepath.Join(src, ".git")); err == nil && fi.IsDir() {
+			err = shallowCopyGitRepo(src, dst, lockedGitRefs(bySource[name]))
+		} else {
+			err = fs.CopyDir(src, dst)
+		}
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to export %s", name)
+		}
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create bundle")
+	}
+	if err := fs.WriteTarGz(f, tmp); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return len(names), errors.Wrapf(f.Close(), "failed to write %s", out)
+}
+
+// gitRef is a ref to create in a copy of a git repository.
+type gitRef struct {
+	rev  gps.Revision
+	name string
+}
+
+// lockedGitRefs returns the refs naming the revisions locked by lps: a tag or
+// branch named as in the lock, or a ref under refs/dep where the lock names no
+// version, or names one that another revision already has.
+func lockedGitRefs(lps []gps.LockedProject) []gitRef {
+	var refs []gitRef
+	taken := make(map[string]gps.Revision)
+	for _, lp := range lps {
+		rev := lockedRevision(lp.Version())
+		name := "refs/dep/" + string(rev)
+		if pv, ok := lp.Version().(gps.PairedVersion); ok {
+			if pv.Type() == gps.IsBranch {
+				name = "refs/heads/" + pv.Unpair().String()
+			} else {
+				name = "refs/tags/" + pv.Unpair().String()
+			}
+			if r, ok := taken[name]; ok && r != rev {
+				name = "refs/dep/" + string(rev)
+			}
+		}
+		if _, ok := taken[name]; ok {
+			continue
+		}
+		taken[name] = rev
+		refs = append(refs, gitRef{rev: rev, name: name})
+	}
+	return refs
+}
+
+// shallowCopyGitRepo writes a shallow git repository into dst holding only
+// the revisions in refs, without their history, out of the repository at src.
+// dep can fetch more from upstream into the copy as usual.
+func shallowCopyGitRepo(src, dst string, refs []gitRef) error {
+	if len(refs) == 0 {
+		return errors.New("no revisions to copy")
+	}
+	if err := os.MkdirAll(dst, 0777); err != nil {
+		return err
+	}
+
+	if _, err := runGit(dst, "init", "-q"); err != nil {
+		return err
+	}
+	if u, err := runGit(src, "config", "--get", "remote.origin.url"); err == nil {
+		if _, err := runGit(dst, "remote", "add", "origin", u); err != nil {
+			return err
+		}
+	}
+
+	// Revisions are asked for directly, as they need not be the tip of any
+	// ref in src; the upload-pack serving them must allow that. The branch
+	// that the new repository's unborn HEAD names may be among the refs.
+	fetch := []string{"fetch", "-q", "--depth=1", "--no-tags", "--update-head-ok", "--upload-pack", "git -c uploadpack.allowAnySHA1InWant=true upload-pack", src}
+	for _, ref := range refs {
+		fetch = append(fetch, string(ref.rev)+":"+ref.name)
+	}
+	if _, err := runGit(dst, fetch...); err != nil {
+		return err
+	}
+
+	// dep expects a checked out work tree with an index.
+	_, err := runGit(dst, "checkout", "-q", "--detach", string(refs[0].rev))
+	return err
+}
+
+// runGit runs git with args in dir, and returns its trimmed output. It is run
+// as gps runs its own commands, so that it is audited alike.
+func runGit(dir string, args ...string) (string, error) {
+	out, err := gps.RunCommand(context.TODO(), dir, nil, "git", args...)
+	if err != nil {
+		return "", errors.Wrapf(err, "git %s: %s", args[0], bytes.TrimSpace(out))
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
+
+// importCache unpacks the bundle written by exportCache at bundle into the
+// cache at cachedir. It returns the names of the sources imported, and of
+// those skipped because they already were in the cache.
+func importCache(cachedir, bundle string) (imported, skipped []string, err error) {
+	f, err := os.Open(bundle)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to open bundle")
+	}
+	defer f.Close()
+
+	srcdir := filepath.Join(cachedir, "sources")
+	if err := os.MkdirAll(srcdir, 0777); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create the sources directory")
+	}
+	// Unpack next to the sources, so that they can be moved into place.
+	tmp, err := ioutil.TempDir(cachedir, ".dep-cache-import")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create a temporary directory")
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := fs.ExtractTarGz(f, tmp); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to unpack %s", bundle)
+	}
+	fis, err := ioutil.ReadDir(filepath.Join(tmp, "sources"))
+	if err != nil {
+		return nil, nil, errors.Errorf("%s is not a cache bundle", bundle)
+	}
+
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		dst := filepath.Join(srcdir, fi.Name())
+		if _, err := os.Stat(dst); err == nil {
+			skipped = append(skipped, fi.Name())
+			continue
+		}
+		if err := fs.RenameWithFallback(filepath.Join(tmp, "sources", fi.Name()), dst); err != nil {
+			return imported, skipped, errors.Wrapf(err, "failed to import %s", fi.Name())
+		}
+		imported = append(imported, fi.Name())
+	}
+	return imported, skipped, nil
+}
+
+// gcResult describes what gcCache did to the git sources in the cache.
+type gcResult struct {
+	repacked  []string
+	removed   []string
+	reclaimed uint64
+}
+
+// gcCache replaces each git repository in the cache at cachedir with a
+// shallow copy holding only the revisions locked by lps, and removes those
+// holding none of them. If dryRun is set, nothing is changed, and the space
+// that repacking would reclaim is not counted.
+func gcCache(cachedir string, lps []gps.LockedProject, dryRun bool) (gcResult, error) {
+	var res gcResult
+	srcdir := filepath.Join(cachedir, "sources")
+	fis, err := ioutil.ReadDir(srcdir)
+	if os.IsNotExist(err) {
+		return res, nil
+	} else if err != nil {
+		return res, errors.Wrap(err, "failed to read the sources in the cache")
+	}
+
+	var revs []gps.Revision
+	for _, lp := range lps {
+		if rev := lockedRevision(lp.Version()); rev != "" {
+			revs = append(revs, rev)
+		}
+	}
+
+	for _, fi := range fis {
+		dir := filepath.Join(srcdir, fi.Name())
+		if gfi, err := os.Stat(filepath.Join(dir, ".git")); err != nil || !gfi.IsDir() {
+			continue
+		}
+		present, err := presentRevisions(dir, revs)
+		if err != nil {
+			return res, errors.Wrapf(err, "failed to inspect %s", fi.Name())
+		}
+		var keep []gps.LockedProject
+		for _, lp := range lps {
+			if present[lockedRevision(lp.Version())] {
+				keep = append(keep, lp)
+			}
+		}
+
+		before, err := dirSize(dir)
+		if err != nil {
+			return res, err
+		}
+		if len(keep) == 0 {
+			res.removed = append(res.removed, fi.Name())
+			res.reclaimed += before
+			if !dryRun {
+				if err := os.RemoveAll(dir); err != nil {
+					return res, errors.Wrapf(err, "failed to remove %s", fi.Name())
+				}
+			}
+			continue
+		}
+		res.repacked = append(res.repacked, fi.Name())
+		if dryRun {
+			continue
+		}
+		if err := replaceWithShallowCopy(cachedir, dir, lockedGitRefs(keep)); err != nil {
+			return res, errors.Wrapf(err, "failed to repack %s", fi.Name())
+		}
+		if after, err := dirSize(dir); err == nil && after < before {
+			res.reclaimed += before - after
+		}
+	}
+	return res, nil
+}
+
+// replaceWithShallowCopy replaces the git repository at dir with a shallow
+// copy of itself holding only the revisions in refs. The copy is made in a
+// temporary directory in cachedir, so that it can be moved into place.
+func replaceWithShallowCopy(cachedir, dir string, refs []gitRef) error {
+	tmp, err := ioutil.TempDir(cachedir, ".dep-gc")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	repo := filepath.Join(tmp, "repo")
+	if err := shallowCopyGitRepo(dir, repo, refs); err != nil {
+		return err
+	}
+	old := filepath.Join(tmp, "old")
+	if err := os.Rename(dir, old); err != nil {
+		return err
+	}
+	if err := os.Rename(repo, dir); err != nil {
+		// Put the original back, rather than lose the source.
+		os.Rename(old, dir)
+		return err
+	}
+	return nil
+}
+
+// presentRevisions reports which of revs are commits in the git repository at
+// dir.
+func presentRevisions(dir string, revs []gps.Revision) (map[gps.Revision]bool, error) {
+	present := make(map[gps.Revision]bool)
+	if len(revs) == 0 {
+		return present, nil
+	}
+
+	var in bytes.Buffer
+	for _, rev := range revs {
+		in.WriteString(string(rev) + "^{commit}\n")
+	}
+	out, err := gps.RunCommand(context.TODO(), dir, in.Bytes(), "git", "cat-file", "--batch-check")
+	if err != nil {
+		return nil, errors.Wrapf(err, "git cat-file: %s", bytes.TrimSpace(out))
+	}
+
+	// cat-file answers each line of input in turn, with "<input> missing" for
+	// objects it does not have.
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != len(revs) {
+		return nil, errors.Errorf("git cat-file answered %d of %d revisions", len(lines), len(revs))
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, " missing") {
+			present[revs[i]] = true
+		}
+	}
+	return present, nil
+}
+
+// cacheSource is a source in the cache.
+type cacheSource struct {
+	name string
+	kind string
+	size uint64
+}
+
+// cacheStats describes the contents of a cache.
+type cacheStats struct {
+	sources  []cacheSource
+	metadata uint64
+}
+
+// readCacheStats collects the sources in the cache at cachedir, and the size
+// of its metadata databases.
+func readCacheStats(cachedir string) (cacheStats, error) {
+	var st cacheStats
+	dbs, err := filepath.Glob(filepath.Join(cachedir, "*.db"))
+	if err != nil {
+		return st, err
+	}
+	for _, db := range dbs {
+		if fi, err := os.Stat(db); err == nil {
+			st.metadata += uint64(fi.Size())
+		}
+	}
+
+	srcdir := filepath.Join(cachedir, "sources")
+	fis, err := ioutil.ReadDir(srcdir)
+	if os.IsNotExist(err) {
+		return st, nil
+	} else if err != nil {
+		return st, errors.Wrap(err, "failed to read the sources in the cache")
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		dir := filepath.Join(srcdir, fi.Name())
+		size, err := dirSize(dir)
+		if err != nil {
+			return st, err
+		}
+		kind := "other"
+		for _, k := range []string{"git", "hg", "bzr", "svn"} {
+			if ok, _ := fs.IsDir(filepath.Join(dir, "."+k)); ok {
+				kind = k
+				break
+			}
+		}
+		st.sources = append(st.sources, cacheSource{name: fi.Name(), kind: kind, size: size})
+	}
+	return st, nil
+}
+
+// write writes a summary of st to w.
+func (st cacheStats) write(w io.Writer) {
+	var total uint64
+	kinds := make(map[string]int)
+	for _, src := range st.sources {
+		total += src.size
+		kinds[src.kind]++
+	}
+	var counts []string
+	for _, k := range []string{"git", "hg", "bzr", "svn", "other"} {
+		if kinds[k] > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", kinds[k], k))
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(counts) > 0 {
+		fmt.Fprintf(tw, "Sources:\t%d (%s), %s\n", len(st.sources), strings.Join(counts, ", "), formatMB(total))
+	} else {
+		fmt.Fprintf(tw, "Sources:\t0\n")
+	}
+	fmt.Fprintf(tw, "Metadata:\t%s\n", formatMB(st.metadata))
+	tw.Flush()
+}
+
+// projectUsage is the share of the cache used by a registered project. An
+// empty path stands for the sources that no registered project uses.
+type projectUsage struct {
+	path    string
+	sources int
+	size    uint64
+}
+
+// attributeSources attributes each git source in sources to the projects in
+// locks that are locked to a revision it holds.
+func attributeSources(cachedir string, sources []cacheSource, locks []registeredLock) ([]projectUsage, error) {
+	var revs []gps.Revision
+	for _, lp := range registeredProjects(locks) {
+		if rev := lockedRevision(lp.Version()); rev != "" {
+			revs = append(revs, rev)
+		}
+	}
+
+	var usage []projectUsage
+	for _, rl := range locks {
+		if rl.lock != nil {
+			usage = append(usage, projectUsage{path: filepath.Dir(rl.path)})
+		}
+	}
+	var unused projectUsage
+	for _, src := range sources {
+		used := false
+		if src.kind == "git" {
+			present, err := presentRevisions(filepath.Join(cachedir, "sources", src.name), revs)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to inspect %s", src.name)
+			}
+			i := 0
+			for _, rl := range locks {
+				if rl.lock == nil {
+					continue
+				}
+				for _, lp := range rl.lock.Projects() {
+					if present[lockedRevision(lp.Version())] {
+						usage[i].sources++
+						usage[i].size += src.size
+						used = true
+						break
+					}
+				}
+				i++
+			}
+		}
+		if !used {
+			unused.sources++
+			unused.size += src.size
+		}
+	}
+	return append(usage, unused), nil
+}
+
+// writeProjectUsage writes a table of usage to w.
+func writeProjectUsage(w io.Writer, usage []projectUsage) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tSOURCES\tSIZE")
+	for _, u := range usage {
+		path := u.path
+		if path == "" {
+			path = "(no registered project)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", path, u.sources, formatMB(u.size))
+	}
+	tw.Flush()
+}
+
+// formatMB formats n bytes in megabytes.
+func formatMB(n uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
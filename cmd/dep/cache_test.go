@@ -0,0 +1,273 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/fs"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestRelocateCache(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	// The cache used to be at /old/cache, and has been copied to the temp dir.
+	h.TempFile("cache/sources/shared/.git/HEAD", "ref: refs/heads/master")
+	h.TempDir("cache/sources/shared/.git/objects/pack")
+	h.TempFile("cache/sources/alt/.git/objects/info/alternates", "/old/cache/sources/shared/.git/objects")
+	h.TempFile("cache/sources/gone/.git/objects/info/alternates", "/nowhere/objects")
+	h.TempFile("cache/sources/hg/.hg/sharedpath", "/old/cache/sources/shared/.git")
+	h.TempFile("cache/sources/plain/.git/HEAD", "ref: refs/heads/master")
+	cachedir := h.Path("cache")
+
+	res, err := relocateCache(cachedir, "/old/cache")
+	h.Must(err)
+
+	if res.checked != 5 {
+		t.Errorf("expected 5 sources to be checked, got %d", res.checked)
+	}
+	if want := []string{"alt", "hg"}; !reflect.DeepEqual(res.fixed, want) {
+		t.Errorf("expected %v to be fixed, got %v", want, res.fixed)
+	}
+	if want := []string{"gone"}; !reflect.DeepEqual(res.removed, want) {
+		t.Errorf("expected %v to be removed, got %v", want, res.removed)
+	}
+	h.MustNotExist(filepath.Join(cachedir, "sources", "gone"))
+
+	alt, err := ioutil.ReadFile(filepath.Join(cachedir, "sources/alt/.git/objects/info/alternates"))
+	h.Must(err)
+	if want := filepath.Join(cachedir, "sources/shared/.git/objects") + "\n"; string(alt) != want {
+		t.Errorf("expected alternates to be rewritten to %q, got %q", want, alt)
+	}
+
+	// Relocating again changes nothing.
+	res, err = relocateCache(cachedir, "/old/cache")
+	h.Must(err)
+	if len(res.fixed) != 0 || len(res.removed) != 0 {
+		t.Errorf("expected a relocated cache to be left alone, got %+v", res)
+	}
+}
+
+func TestRelocateCacheWithoutSources(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir("cache")
+	res, err := relocateCache(h.Path("cache"), "")
+	h.Must(err)
+	if res.checked != 0 {
+		t.Errorf("expected no sources to be checked, got %d", res.checked)
+	}
+}
+
+// testGit runs git with args in dir, failing t if it fails, and returns its
+// trimmed output.
+func testGit(t *testing.T, dir string, args ...string) string {
+	c := exec.Command("git", append([]string{"-c", "user.name=dep", "-c", "user.email=dep@example.com"}, args...)...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s", args, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestExportImportGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("bundle")
+	root := h.Path(".")
+
+	git := func(dir string, args ...string) string { return testGit(t, dir, args...) }
+
+	h.TempDir("cache/sources/https---github.com-foo-bar")
+	src := h.Path("cache/sources/https---github.com-foo-bar")
+	git(src, "init")
+	git(src, "remote", "add", "origin", "https://github.com/foo/bar")
+	h.TempFile("cache/sources/https---github.com-foo-bar/foo.go", "package foo")
+	git(src, "add", "foo.go")
+	git(src, "commit", "-m", "v1")
+	git(src, "tag", "v1.0.0")
+	rev1 := git(src, "rev-parse", "HEAD")
+	git(src, "commit", "--allow-empty", "-m", "v2")
+	rev2 := git(src, "rev-parse", "HEAD")
+
+	lps := []gps.LockedProject{
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}, gps.NewVersion("v1.0.0").Pair(gps.Revision(rev1)), nil),
+	}
+	dst := filepath.Join(root, "bundle/sources/https---github.com-foo-bar")
+	h.Must(shallowCopyGitRepo(src, dst, lockedGitRefs(lps)))
+
+	if got := git(dst, "rev-parse", "refs/tags/v1.0.0"); got != rev1 {
+		t.Errorf("expected v1.0.0 to be tagged at %s, got %s", rev1, got)
+	}
+	if got := git(dst, "config", "--get", "remote.origin.url"); got != "https://github.com/foo/bar" {
+		t.Errorf("expected the origin to be kept, got %s", got)
+	}
+	if err := exec.Command("git", "-C", dst, "cat-file", "-e", rev2).Run(); err == nil {
+		t.Errorf("expected %s, which is not locked, to be left out", rev2)
+	}
+	h.MustExist(filepath.Join(dst, "foo.go"))
+
+	f, err := os.Create(filepath.Join(root, "bundle.tar.gz"))
+	h.Must(err)
+	h.Must(fs.WriteTarGz(f, h.Path("bundle")))
+	h.Must(f.Close())
+
+	// One of the sources in the bundle is already in the cache.
+	h.TempDir("newcache/sources/https---github.com-foo-bar")
+	h.TempDir("bundle/sources/https---github.com-foo-baz")
+	f, err = os.Create(filepath.Join(root, "bundle2.tar.gz"))
+	h.Must(err)
+	h.Must(fs.WriteTarGz(f, h.Path("bundle")))
+	h.Must(f.Close())
+
+	imported, skipped, err := importCache(h.Path("newcache"), filepath.Join(root, "bundle2.tar.gz"))
+	h.Must(err)
+	if want := []string{"https---github.com-foo-baz"}; !reflect.DeepEqual(imported, want) {
+		t.Errorf("expected %v to be imported, got %v", want, imported)
+	}
+	if want := []string{"https---github.com-foo-bar"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("expected %v to be skipped, got %v", want, skipped)
+	}
+	h.MustNotExist(filepath.Join(root, "newcache/sources/https---github.com-foo-bar/.git"))
+
+	imported, _, err = importCache(filepath.Join(root, "othercache"), filepath.Join(root, "bundle.tar.gz"))
+	h.Must(err)
+	if len(imported) != 1 {
+		t.Fatalf("expected one source to be imported, got %v", imported)
+	}
+	if got := git(filepath.Join(root, "othercache/sources/https---github.com-foo-bar"), "rev-parse", "HEAD"); got != rev1 {
+		t.Errorf("expected the imported source to be at %s, got %s", rev1, got)
+	}
+}
+
+func TestGCCache(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	git := func(dir string, args ...string) string { return testGit(t, dir, args...) }
+
+	var revs []string
+	for _, name := range []string{"kept", "unused"} {
+		h.TempDir("cache/sources/" + name)
+		dir := h.Path("cache/sources/" + name)
+		git(dir, "init")
+		for i := 0; i < 3; i++ {
+			h.TempFile(filepath.Join("cache/sources", name, "foo.go"), "// "+name+"\n"+strings.Repeat("package foo\n", i+1))
+			git(dir, "add", "foo.go")
+			git(dir, "commit", "-m", "commit")
+			revs = append(revs, git(dir, "rev-parse", "HEAD"))
+		}
+	}
+	h.TempFile("cache/sources/hg/.hg/requires", "store")
+	cachedir := h.Path("cache")
+	kept := h.Path("cache/sources/kept")
+
+	lps := []gps.LockedProject{
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/foo/kept"}, gps.NewBranch("master").Pair(gps.Revision(revs[1])), nil),
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/foo/gone"}, gps.Revision("0123456789abcdef0123456789abcdef01234567"), nil),
+	}
+
+	res, err := gcCache(cachedir, lps, true)
+	h.Must(err)
+	if !reflect.DeepEqual(res.repacked, []string{"kept"}) || !reflect.DeepEqual(res.removed, []string{"unused"}) {
+		t.Fatalf("expected kept to be repacked and unused to be removed, got %+v", res)
+	}
+	h.MustExist(h.Path("cache/sources/unused"))
+	git(kept, "cat-file", "-e", revs[0])
+
+	res, err = gcCache(cachedir, lps, false)
+	h.Must(err)
+	if res.reclaimed == 0 {
+		t.Error("expected space to be reclaimed")
+	}
+	h.MustNotExist(filepath.Join(cachedir, "sources", "unused"))
+	h.MustExist(h.Path("cache/sources/hg/.hg/requires"))
+
+	if got := git(kept, "rev-parse", "refs/heads/master"); got != revs[1] {
+		t.Errorf("expected master to be kept at %s, got %s", revs[1], got)
+	}
+	if err := exec.Command("git", "-C", kept, "cat-file", "-e", revs[0]).Run(); err == nil {
+		t.Error("expected the history of the locked revision to be dropped")
+	}
+	if err := exec.Command("git", "-C", kept, "cat-file", "-e", revs[2]).Run(); err == nil {
+		t.Error("expected revisions that are not locked to be dropped")
+	}
+	if out := git(kept, "status", "--porcelain"); out != "" {
+		t.Errorf("expected the repacked source to be clean, got %s", out)
+	}
+}
+
+func TestCacheStatsByProject(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	revs := make(map[string]string)
+	for _, name := range []string{"a", "b", "c"} {
+		h.TempDir("cache/sources/" + name)
+		dir := h.Path("cache/sources/" + name)
+		testGit(t, dir, "init")
+		testGit(t, dir, "commit", "--allow-empty", "-m", name)
+		revs[name] = testGit(t, dir, "rev-parse", "HEAD")
+	}
+	h.TempFile("cache/sources/hg/.hg/requires", "store")
+	h.TempFile("cache/bolt-v1.db", "metadata")
+	cachedir := h.Path("cache")
+
+	lockFor := func(names ...string) string {
+		var buf strings.Builder
+		for _, name := range names {
+			buf.WriteString("[[projects]]\n  name = \"github.com/foo/" + name + "\"\n  revision = \"" + revs[name] + "\"\n\n")
+		}
+		return buf.String()
+	}
+	h.TempFile("one/Gopkg.lock", lockFor("a"))
+	h.TempFile("two/Gopkg.lock", lockFor("a", "b"))
+	h.Must(dep.RegisterLocks(cachedir, h.Path("one/Gopkg.lock"), h.Path("two/Gopkg.lock"), filepath.Join(h.Path("."), "gone", "Gopkg.lock")))
+
+	st, err := readCacheStats(cachedir)
+	h.Must(err)
+	if len(st.sources) != 4 || st.metadata != uint64(len("metadata")) {
+		t.Fatalf("expected 4 sources and the metadata database, got %+v", st)
+	}
+	var buf bytes.Buffer
+	st.write(&buf)
+	if !strings.Contains(buf.String(), "4 (3 git, 1 hg)") {
+		t.Errorf("expected the sources to be counted by kind, got:\n%s", buf.String())
+	}
+
+	locks, err := readRegisteredLocks(cachedir)
+	h.Must(err)
+	usage, err := attributeSources(cachedir, st.sources, locks)
+	h.Must(err)
+	got := make(map[string]int)
+	for _, u := range usage {
+		got[u.path] = u.sources
+	}
+	want := map[string]int{h.Path("one"): 1, h.Path("two"): 2, "": 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected sources to be attributed as %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,425 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const canUseShortHelp = `Check whether a constraint could be satisfied`
+const canUseLongHelp = `
+Check whether <project> could be constrained to <constraint> without
+conflicting with the other projects in Gopkg.lock, and report the first
+conflict if not.
+
+This is much faster than a trial dep ensure, as only manifests are read, and
+only those of the projects that the change reaches: <project> itself, then any
+project in Gopkg.lock whose locked version no longer satisfies the constraints
+on it, and so on. The other projects in Gopkg.lock are assumed to stay at their
+locked versions.
+
+Packages are not listed, so dependencies that a new version brings in are not
+followed. dep ensure remains the final word, but a constraint that dep can-use
+rejects cannot be satisfied without changing other constraints as well.
+
+Example:
+
+  dep can-use github.com/pkg/errors@^0.8.0
+`
+
+type canUseCommand struct{}
+
+func (cmd *canUseCommand) Name() string      { return "can-use" }
+func (cmd *canUseCommand) Args() string      { return "<project>[:alt source URL]@<constraint>" }
+func (cmd *canUseCommand) ShortHelp() string { return canUseShortHelp }
+func (cmd *canUseCommand) LongHelp() string  { return canUseLongHelp }
+func (cmd *canUseCommand) Hidden() bool      { return false }
+
+func (cmd *canUseCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *canUseCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) != 1 || !strings.Contains(args[0], "@") {
+		return errors.New("can-use takes exactly one argument, of the form <project>@<constraint>")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	pc, _, err := getProjectConstraint(args[0], sm)
+	if err != nil {
+		return err
+	}
+
+	var locked []gps.LockedProject
+	if p.Lock != nil {
+		locked = p.Lock.Projects()
+	}
+	chk := newCanUseChecker(sm, dep.Analyzer{Order: p.Manifest.Analyzers}, p.Manifest, locked)
+	if err := chk.prefetch(); err != nil {
+		return err
+	}
+	if err := chk.check(pc); err != nil {
+		return errors.Wrapf(err, "cannot use %s", args[0])
+	}
+
+	var buf bytes.Buffer
+	chk.printChanges(&buf, args[0])
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// manifestReader is the part of gps.SourceManager that a canUseChecker needs.
+type manifestReader interface {
+	ListVersions(gps.ProjectIdentifier) ([]gps.PairedVersion, error)
+	GetManifestAndLock(gps.ProjectIdentifier, gps.Version, gps.ProjectAnalyzer) (gps.Manifest, gps.Lock, error)
+}
+
+// sourcedConstraint is a constraint on a project, and where it comes from.
+type sourcedConstraint struct {
+	c    gps.Constraint
+	from string
+}
+
+func (sc sourcedConstraint) String() string {
+	return fmt.Sprintf("%s (from %s)", sc.c, sc.from)
+}
+
+// canUseChecker checks whether a single constraint can be satisfied against a
+// lock. It is a small solver, which starts from the locked versions and only
+// changes those that stop satisfying the constraints on them.
+type canUseChecker struct {
+	sm manifestReader
+	an gps.ProjectAnalyzer
+
+	// root and ovr are the constraints and overrides of the root manifest.
+	root map[gps.ProjectRoot]sourcedConstraint
+	ovr  map[gps.ProjectRoot]sourcedConstraint
+
+	ids    map[gps.ProjectRoot]gps.ProjectIdentifier
+	locked map[gps.ProjectRoot]gps.Version
+	// selected holds the version of each project, as locked or as changed.
+	selected map[gps.ProjectRoot]gps.Version
+	// changing holds the projects that are being changed, so that a change
+	// does not in turn try to change them again.
+	changing map[gps.ProjectRoot]bool
+
+	mu        sync.Mutex
+	manifests map[string]gps.Manifest
+}
+
+func newCanUseChecker(sm manifestReader, an gps.ProjectAnalyzer, m *dep.Manifest, locked []gps.LockedProject) *canUseChecker {
+	c := &canUseChecker{
+		sm:        sm,
+		an:        an,
+		root:      make(map[gps.ProjectRoot]sourcedConstraint),
+		ovr:       make(map[gps.ProjectRoot]sourcedConstraint),
+		ids:       make(map[gps.ProjectRoot]gps.ProjectIdentifier),
+		locked:    make(map[gps.ProjectRoot]gps.Version),
+		selected:  make(map[gps.ProjectRoot]gps.Version),
+		changing:  make(map[gps.ProjectRoot]bool),
+		manifests: make(map[string]gps.Manifest),
+	}
+	for pr, pp := range m.Constraints {
+		if pp.Constraint != nil {
+			c.root[pr] = sourcedConstraint{c: pp.Constraint, from: dep.ManifestName}
+		}
+	}
+	for pr, pp := range m.Ovr {
+		if pp.Constraint != nil {
+			c.ovr[pr] = sourcedConstraint{c: pp.Constraint, from: dep.ManifestName + " override"}
+		}
+	}
+	for _, lp := range locked {
+		pr := lp.Ident().ProjectRoot
+		c.ids[pr] = lp.Ident()
+		c.locked[pr] = lp.Version()
+		c.selected[pr] = lp.Version()
+	}
+	return c
+}
+
+// prefetch reads the manifests of all the locked projects concurrently, as
+// the constraints on any project may come from any of them.
+func (c *canUseChecker) prefetch() error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(c.selected))
+	for pr, v := range c.selected {
+		wg.Add(1)
+		go func(pr gps.ProjectRoot, v gps.Version) {
+			defer wg.Done()
+			if _, err := c.manifest(pr, v); err != nil {
+				errs <- err
+			}
+		}(pr, v)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// check reports whether pc can be satisfied. If it can, the versions that it
+// takes are left in c.selected.
+func (c *canUseChecker) check(pc gps.ProjectConstraint) error {
+	pr := pc.Ident.ProjectRoot
+	if pc.Ident.Source != "" || c.ids[pr].ProjectRoot == "" {
+		c.ids[pr] = pc.Ident
+	}
+	// The requested constraint replaces whatever Gopkg.toml says.
+	c.root[pr] = sourcedConstraint{c: pc.Constraint, from: "the request"}
+	delete(c.ovr, pr)
+
+	c.changing[pr] = true
+	defer delete(c.changing, pr)
+	return c.choose(pr)
+}
+
+// choose selects a version of pr that satisfies all the constraints on it, and
+// whose own constraints can be satisfied in turn. If there is none, it returns
+// the first conflict found.
+func (c *canUseChecker) choose(pr gps.ProjectRoot) error {
+	cs, err := c.constraintsOn(pr)
+	if err != nil {
+		return err
+	}
+	candidates, err := c.candidates(pr, cs)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return errors.Errorf("no version of %s satisfies %s", pr, joinConstraints(cs))
+	}
+
+	var firstErr error
+	for _, v := range candidates {
+		saved := make(map[gps.ProjectRoot]gps.Version, len(c.selected))
+		for k, sv := range c.selected {
+			saved[k] = sv
+		}
+
+		c.selected[pr] = v
+		err := c.propagate(pr, v)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		c.selected = saved
+	}
+	return firstErr
+}
+
+// propagate checks the projects that the manifest of pr at v constrains, and
+// chooses another version of those whose selected version no longer satisfies
+// all the constraints on them. Projects that are not in the lock are new, and
+// are assumed to be satisfiable.
+func (c *canUseChecker) propagate(pr gps.ProjectRoot, v gps.Version) error {
+	m, err := c.manifest(pr, v)
+	if err != nil {
+		return err
+	}
+	for _, dpr := range sortedDeps(m.DependencyConstraints()) {
+		sel, has := c.selected[dpr]
+		if !has || dpr == pr {
+			continue
+		}
+		cs, err := c.constraintsOn(dpr)
+		if err != nil {
+			return err
+		}
+		if satisfiesAll(sel, cs) {
+			continue
+		}
+		if c.changing[dpr] {
+			return errors.Errorf("%s@%s conflicts with %s: %s is not satisfied by %s", pr, v, dpr, joinConstraints(cs), sel)
+		}
+
+		c.changing[dpr] = true
+		err = c.choose(dpr)
+		delete(c.changing, dpr)
+		if err != nil {
+			return errors.Wrapf(err, "%s@%s requires changing %s", pr, v, dpr)
+		}
+	}
+	return nil
+}
+
+// constraintsOn returns the constraints on pr: an override, or else those of
+// the root manifest and of the manifests of every other selected project.
+func (c *canUseChecker) constraintsOn(pr gps.ProjectRoot) ([]sourcedConstraint, error) {
+	if sc, has := c.ovr[pr]; has {
+		return []sourcedConstraint{sc}, nil
+	}
+
+	var cs []sourcedConstraint
+	if sc, has := c.root[pr]; has {
+		cs = append(cs, sc)
+	}
+	for _, other := range sortedRoots(c.selected) {
+		if other == pr {
+			continue
+		}
+		v := c.selected[other]
+		m, err := c.manifest(other, v)
+		if err != nil {
+			return nil, err
+		}
+		if pp, has := m.DependencyConstraints()[pr]; has && pp.Constraint != nil {
+			cs = append(cs, sourcedConstraint{c: pp.Constraint, from: fmt.Sprintf("%s@%s", other, v)})
+		}
+	}
+	return cs, nil
+}
+
+// candidates returns the versions of pr that satisfy cs, in the order in which
+// the solver would try them, but with the locked version first.
+func (c *canUseChecker) candidates(pr gps.ProjectRoot, cs []sourcedConstraint) ([]gps.Version, error) {
+	var candidates []gps.Version
+	if lv, has := c.locked[pr]; has && satisfiesAll(lv, cs) {
+		candidates = append(candidates, lv)
+	}
+
+	vl, err := c.sm.ListVersions(c.id(pr))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not list the versions of %s", pr)
+	}
+	gps.SortPairedForUpgrade(vl)
+	for _, v := range vl {
+		if satisfiesAll(v, cs) && !isLockedVersion(c.locked[pr], v) {
+			candidates = append(candidates, v)
+		}
+	}
+
+	// A revision need not be listed to satisfy a constraint naming it.
+	if len(candidates) == 0 {
+		for _, sc := range cs {
+			if r, ok := sc.c.(gps.Revision); ok && satisfiesAll(r, cs) {
+				candidates = append(candidates, r)
+				break
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// manifest returns the manifest of pr at v, as the analyzer derives it.
+func (c *canUseChecker) manifest(pr gps.ProjectRoot, v gps.Version) (gps.Manifest, error) {
+	key := fmt.Sprintf("%s@%s", pr, v)
+	if pv, ok := v.(gps.PairedVersion); ok {
+		key += "#" + pv.Revision().String()
+	}
+
+	c.mu.Lock()
+	m, has := c.manifests[key]
+	id := c.id(pr)
+	c.mu.Unlock()
+	if has {
+		return m, nil
+	}
+
+	m, _, err := c.sm.GetManifestAndLock(id, v, c.an)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read the manifest of %s@%s", pr, v)
+	}
+	if m == nil {
+		m = gps.SimpleManifest{}
+	}
+
+	c.mu.Lock()
+	c.manifests[key] = m
+	c.mu.Unlock()
+	return m, nil
+}
+
+func (c *canUseChecker) id(pr gps.ProjectRoot) gps.ProjectIdentifier {
+	if id, has := c.ids[pr]; has {
+		return id
+	}
+	return gps.ProjectIdentifier{ProjectRoot: pr}
+}
+
+// printChanges writes the versions that the check selected, other than the
+// locked ones, to buf.
+func (c *canUseChecker) printChanges(buf *bytes.Buffer, arg string) {
+	var changed []gps.ProjectRoot
+	for _, pr := range sortedRoots(c.selected) {
+		lv, has := c.locked[pr]
+		if !has || lv != c.selected[pr] {
+			changed = append(changed, pr)
+		}
+	}
+	if len(changed) == 0 {
+		fmt.Fprintf(buf, "%s can be used without changing %s\n", arg, dep.LockName)
+		return
+	}
+
+	fmt.Fprintf(buf, "%s can be used, with:\n", arg)
+	tw := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	for _, pr := range changed {
+		from := "(new)"
+		if lv, has := c.locked[pr]; has {
+			from = lv.String()
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t->\t%s\n", pr, from, c.selected[pr])
+	}
+	tw.Flush()
+}
+
+func satisfiesAll(v gps.Version, cs []sourcedConstraint) bool {
+	for _, sc := range cs {
+		if !sc.c.Matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func joinConstraints(cs []sourcedConstraint) string {
+	s := make([]string, len(cs))
+	for i, sc := range cs {
+		s[i] = sc.String()
+	}
+	return strings.Join(s, " and ")
+}
+
+// sortedRoots returns the projects in m, sorted, so that the first conflict
+// reported does not depend on map order.
+func sortedRoots(m map[gps.ProjectRoot]gps.Version) []gps.ProjectRoot {
+	roots := make([]gps.ProjectRoot, 0, len(m))
+	for pr := range m {
+		roots = append(roots, pr)
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+	return roots
+}
+
+// sortedDeps is sortedRoots for the projects constrained by pc.
+func sortedDeps(pc gps.ProjectConstraints) []gps.ProjectRoot {
+	roots := make([]gps.ProjectRoot, 0, len(pc))
+	for pr := range pc {
+		roots = append(roots, pr)
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+	return roots
+}
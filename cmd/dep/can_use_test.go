@@ -0,0 +1,150 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+// fakeManifestReader serves versions and manifests from maps, keyed by
+// project root and by "<root>@<version>".
+type fakeManifestReader struct {
+	versions  map[gps.ProjectRoot][]gps.PairedVersion
+	manifests map[string]gps.ProjectConstraints
+}
+
+func (r fakeManifestReader) ListVersions(id gps.ProjectIdentifier) ([]gps.PairedVersion, error) {
+	return append([]gps.PairedVersion(nil), r.versions[id.ProjectRoot]...), nil
+}
+
+func (r fakeManifestReader) GetManifestAndLock(id gps.ProjectIdentifier, v gps.Version, _ gps.ProjectAnalyzer) (gps.Manifest, gps.Lock, error) {
+	return gps.SimpleManifest{Deps: r.manifests[string(id.ProjectRoot)+"@"+v.String()]}, nil, nil
+}
+
+func TestCanUseChecker(t *testing.T) {
+	semver := func(body string) gps.Constraint {
+		c, err := gps.NewSemverConstraint(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	pv := func(v, rev string) gps.PairedVersion {
+		return gps.NewVersion(v).Pair(gps.Revision(rev))
+	}
+
+	// foo, bar and baz are locked at v1.0.0. Each major version of foo
+	// requires a newer bar, and bar v2.0.0 requires a baz that does not exist.
+	sm := fakeManifestReader{
+		versions: map[gps.ProjectRoot][]gps.PairedVersion{
+			"example.com/foo": {pv("v1.0.0", "f1"), pv("v2.0.0", "f2"), pv("v3.0.0", "f3")},
+			"example.com/bar": {pv("v1.0.0", "b1"), pv("v1.1.0", "b11"), pv("v2.0.0", "b2")},
+			"example.com/baz": {pv("v1.0.0", "z1")},
+			"example.com/qux": {pv("v1.0.0", "q1")},
+		},
+		manifests: map[string]gps.ProjectConstraints{
+			"example.com/foo@v1.0.0": {"example.com/bar": {Constraint: semver("^1.0.0")}},
+			"example.com/foo@v2.0.0": {"example.com/bar": {Constraint: semver("^1.1.0")}},
+			"example.com/foo@v3.0.0": {"example.com/bar": {Constraint: semver("^2.0.0")}},
+			"example.com/bar@v2.0.0": {"example.com/baz": {Constraint: semver("^2.0.0")}},
+			"example.com/qux@v1.0.0": {"example.com/foo": {Constraint: semver("<3.0.0")}},
+		},
+	}
+	locked := []gps.LockedProject{
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "example.com/foo"}, pv("v1.0.0", "f1"), nil),
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "example.com/bar"}, pv("v1.0.0", "b1"), nil),
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "example.com/baz"}, pv("v1.0.0", "z1"), nil),
+	}
+	withQux := append(locked[:len(locked):len(locked)],
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "example.com/qux"}, pv("v1.0.0", "q1"), nil),
+	)
+
+	cases := []struct {
+		name       string
+		locked     []gps.LockedProject
+		manifest   *dep.Manifest
+		constraint gps.Constraint
+		wantErr    string
+		wantOut    []string
+		notOut     string
+	}{
+		{
+			name:       "locked version satisfies",
+			locked:     locked,
+			constraint: semver("^1.0.0"),
+			wantOut:    []string{"without changing"},
+		},
+		{
+			name:       "propagates to a dependency",
+			locked:     locked,
+			constraint: semver("^2.0.0"),
+			wantOut:    []string{"example.com/foo  v1.0.0  ->  v2.0.0", "example.com/bar  v1.0.0  ->  v1.1.0"},
+		},
+		{
+			name:       "conflict deeper in the graph",
+			locked:     locked,
+			constraint: semver("^3.0.0"),
+			wantErr:    "no version of example.com/baz satisfies ^2.0.0 (from example.com/bar@v2.0.0)",
+		},
+		{
+			name:       "conflict with the manifest of another project",
+			locked:     withQux,
+			constraint: semver("^3.0.0"),
+			wantErr:    "no version of example.com/foo satisfies ^3.0.0 (from the request) and <3.0.0 (from example.com/qux@v1.0.0)",
+		},
+		{
+			name:   "override supersedes a dependency",
+			locked: locked,
+			manifest: &dep.Manifest{Ovr: gps.ProjectConstraints{
+				"example.com/bar": {Constraint: semver("^1.0.0")},
+			}},
+			constraint: semver("^3.0.0"),
+			wantOut:    []string{"example.com/foo  v1.0.0  ->  v3.0.0"},
+			notOut:     "example.com/bar",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := c.manifest
+			if m == nil {
+				m = dep.NewManifest()
+			}
+			chk := newCanUseChecker(sm, nil, m, c.locked)
+			if err := chk.prefetch(); err != nil {
+				t.Fatal(err)
+			}
+			err := chk.check(gps.ProjectConstraint{
+				Ident:      gps.ProjectIdentifier{ProjectRoot: "example.com/foo"},
+				Constraint: c.constraint,
+			})
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			chk.printChanges(&buf, "example.com/foo")
+			for _, want := range c.wantOut {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, buf.String())
+				}
+			}
+			if c.notOut != "" && strings.Contains(buf.String(), c.notOut) {
+				t.Errorf("expected output not to contain %q, got:\n%s", c.notOut, buf.String())
+			}
+		})
+	}
+}
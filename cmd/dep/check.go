@@ -6,18 +6,25 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/dep"
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/gps/verify"
+	"github.com/golang/dep/internal/watch"
 	"github.com/pkg/errors"
 )
 
@@ -29,7 +36,51 @@ prints a description of each issue, then exits 1. Passing -q suppresses output.
 Flags control which specific checks will be run. By default, dep check verifies
 that Gopkg.lock is in sync with Gopkg.toml and the imports in your project's .go
 files, and that the vendor directory is in sync with Gopkg.lock. These checks
-can be disabled with -skip-lock and -skip-vendor, respectively.
+can be disabled with -skip-lock and -skip-vendor, respectively. -skip-digest
+keeps checking that vendor holds exactly the projects in Gopkg.lock, but no
+longer compares their contents with the digests in Gopkg.lock.
+
+Hashing every file in vendor is slow for large trees, so once a vendored
+project has matched its digest, an index of its files is kept in the cache,
+and later checks only compare the number and sizes of its files with the
+index, and hash a random sample of them. -thorough compares every file with
+the digests instead, as does -fix. -upstream
+additionally checks that the source of every project in Gopkg.lock can still
+be reached. -generated additionally checks that the protobuf definitions in
+vendor match the Go code generated from them: that each .proto file in a Go
+package is accompanied by its .pb.go file, and each .pb.go file beside .proto
+files by the .proto file it was generated from. Either going missing, as when
+upstream generates code at build time or pruning is too aggressive, breaks the
+build in vendor while it works upstream.
+
+If the [health] table of Gopkg.toml sets "enforce = true", check also fails on
+any project whose locked revision is older than max-age-years, or whose
+upstream has been inactive for longer than max-inactive-months (see dep status
+-health).
+
+If the [kind-policy] table of Gopkg.toml sets "enforce = true", check also fails
+on any [[constraint]] or [[override]] for a dependency of a kind that the
+policy does not permit it for.
+
+Check also fails on any import that a [[forbid]] rule in Gopkg.toml forbids,
+such as an import of a database driver from an API package:
+
+  [[forbid]]
+    packages = "./pkg/api"
+    imports = ["github.com/lib/pq"]
+    reason = "go through the storage package"
+
+With "transitive = true", the rule also forbids reaching the imports through
+other packages, following the imports of dependencies as vendored.
+
+These choices can be persisted for a project in a [check] table in Gopkg.toml,
+which is combined with the flags given on the command line:
+
+  [check]
+    skip-digest = true
+    upstream = true
+    generated = true
+    thorough = true
 
 (See https://golang.github.io/dep/docs/ensure-mechanics.html#staying-in-sync for
 more information on what it means to be "in sync.")
@@ -37,16 +88,59 @@ more information on what it means to be "in sync.")
 If your workflow necessitates that you modify the contents of vendor, you can
 force check to ignore hash mismatches on a per-project basis by naming
 project roots in Gopkg.toml's "noverify" list.
+
+Gopkg.toml is read strictly: anything that would only produce a warning in
+other commands, such as an unknown key or a version that looks like a
+malformed semver range, is reported as an error. Pass -lenient to report
+these as warnings and continue instead.
+
+Passing -fix makes check repair the problems it finds, rather than only
+reporting them: if Gopkg.lock is out of sync with Gopkg.toml and imports, the
+project is solved again and Gopkg.lock rewritten, and vendor is then brought in
+sync with Gopkg.lock, leaving noverify projects alone. Check exits 0 if
+everything could be fixed, which makes "dep check -fix" suitable for use in a
+pre-commit hook. Checks that are skipped are not fixed either. While Gopkg.lock
+is frozen by the [freeze] table of Gopkg.toml or $DEPFREEZE, -fix refuses to
+change it, unless -override-freeze is passed. If Gopkg.toml sets
+justify-major-updates, -fix refuses to update any project to a new major
+version, which takes dep ensure -reason.
+
+Passing -watch keeps check running: after the first check, the project is
+checked again whenever Gopkg.toml, Gopkg.lock or anything under vendor is
+modified, until check is interrupted. Modifications are noticed as they are
+made, rather than by hashing vendor, and an idle check costs nothing. Changes
+to the project's own imports are not noticed. -watch cannot be combined with
+-fix, and is only available where the platform can notify dep of
+modifications (currently Linux).
+
+Passing -json writes a report of every finding as a JSON object instead, with
+the type of each finding, the project or import path it concerns, the expected
+and actual digest or version where there is one, and a suggested remediation.
+
+Without -fix or an enforced [health] table, check neither writes to the project
+nor locks the cache, so it works where both are mounted read-only, as in
+hermetic CI sandboxes, and while dep ensure is running. The read-only cache
+only needs a writable temporary directory ($TMPDIR) besides. Indexes of
+vendored projects are only written to the cache where it is writable.
 `
 
 type checkCommand struct {
 	quiet                bool
 	skiplock, skipvendor bool
+	skipdigest           bool
+	upstream             bool
+	generated            bool
+	thorough             bool
+	lenient              bool
+	fix                  bool
+	overrideFreeze       bool
+	json                 bool
+	watch                bool
 }
 
 func (cmd *checkCommand) Name() string { return "check" }
 func (cmd *checkCommand) Args() string {
-	return "[-q] [-skip-lock] [-skip-vendor]"
+	return "[-q] [-skip-lock] [-skip-vendor] [-skip-digest] [-thorough] [-upstream] [-generated] [-lenient] [-fix [-override-freeze]] [-json] [-watch]"
 }
 func (cmd *checkCommand) ShortHelp() string { return checkShortHelp }
 func (cmd *checkCommand) LongHelp() string  { return checkLongHelp }
@@ -55,21 +149,107 @@ func (cmd *checkCommand) Hidden() bool      { return false }
 func (cmd *checkCommand) Register(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.skiplock, "skip-lock", false, "Skip checking that imports and Gopkg.toml are in sync with Gopkg.lock")
 	fs.BoolVar(&cmd.skipvendor, "skip-vendor", false, "Skip checking that vendor is in sync with Gopkg.lock")
+	fs.BoolVar(&cmd.skipdigest, "skip-digest", false, "Skip comparing vendored projects with the digests in Gopkg.lock")
+	fs.BoolVar(&cmd.upstream, "upstream", false, "Also check that the source of every project in Gopkg.lock can be reached")
+	fs.BoolVar(&cmd.generated, "generated", false, "Also check that vendored .proto files and the .pb.go files generated from them are both present")
+	fs.BoolVar(&cmd.thorough, "thorough", false, "Compare every vendored file with the digests in Gopkg.lock, rather than a sample")
 	fs.BoolVar(&cmd.quiet, "q", false, "Suppress non-error output")
+	fs.BoolVar(&cmd.lenient, "lenient", false, "Report problems in Gopkg.toml as warnings, rather than errors")
+	fs.BoolVar(&cmd.fix, "fix", false, "Re-solve and rewrite Gopkg.lock and vendor to fix any problems found")
+	fs.BoolVar(&cmd.overrideFreeze, "override-freeze", false, "With -fix, rewrite Gopkg.lock even while it is frozen")
+	fs.BoolVar(&cmd.json, "json", false, "Output a report of all findings in JSON format")
+	fs.BoolVar(&cmd.watch, "watch", false, "Check again whenever Gopkg.toml, Gopkg.lock or vendor is modified, until interrupted")
 }
 
 func (cmd *checkCommand) Run(ctx *dep.Ctx, args []string) error {
+	if cmd.watch {
+		if cmd.fix {
+			return errors.New("cannot pass both -watch and -fix, as fixing would set off the watch")
+		}
+		return cmd.runWatch(ctx)
+	}
+	return cmd.check(ctx)
+}
+
+// runWatch checks the project, then checks it again each time it is modified,
+// until interrupted.
+func (cmd *checkCommand) runWatch(ctx *dep.Ctx) error {
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	tr, err := watch.New(p.AbsRoot)
+	if err != nil {
+		return errors.Wrap(err, "cannot watch project")
+	}
+	defer tr.Close()
+
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt)
+	defer signal.Stop(sigch)
+
+	for {
+		// Let a burst of modifications, such as dep ensure rewriting
+		// vendor, settle before checking.
+		for tr.Reset() && tr.Err() == nil {
+			select {
+			case <-time.After(watchSettleTime):
+			case <-sigch:
+				return nil
+			}
+		}
+
+		err := cmd.check(ctx)
+		if _, ok := err.(silentfail); err != nil && !ok {
+			ctx.Err.Println(err)
+		} else if err == nil && !cmd.quiet && !cmd.json {
+			ctx.Out.Println("# in sync")
+		}
+		if err := tr.Err(); err != nil {
+			return err
+		}
+
+		select {
+		case <-tr.Changed():
+		case <-sigch:
+			return nil
+		}
+	}
+}
+
+// watchSettleTime is how long the project must go unmodified before check
+// -watch checks it again.
+const watchSettleTime = 200 * time.Millisecond
+
+// check runs each selected check once.
+func (cmd *checkCommand) check(ctx *dep.Ctx) error {
 	logger := ctx.Out
-	if cmd.quiet {
+	if cmd.quiet || cmd.json {
 		logger = log.New(ioutil.Discard, "", 0)
 	}
 
+	ctx.StrictManifest = !cmd.lenient
+	if cmd.fix {
+		lock, err := ctx.LockProject(false)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+	}
 	p, err := ctx.LoadProject()
 	if err != nil {
 		return err
 	}
 
-	sm, err := ctx.SourceManager()
+	// Unless it has to fix problems or read the sources to assess their
+	// health, check writes nothing, so it can run where the project and the
+	// cache are read-only.
+	var sm *gps.SourceMgr
+	if cmd.fix || p.Manifest.Health.Enforce {
+		sm, err = ctx.SourceManager()
+	} else {
+		sm, err = ctx.ReadOnlySourceManager()
+	}
 	if err != nil {
 		return err
 	}
@@ -77,32 +257,51 @@ func (cmd *checkCommand) Run(ctx *dep.Ctx, args []string) error {
 	sm.UseDefaultSignalHandling()
 	defer sm.Release()
 
-	var fail bool
-	if !cmd.skiplock {
+	opts := cmd.options(p.Manifest.Check)
+	// Fixing rewrites whatever differs, so it needs to know for sure.
+	if !opts.Thorough && !cmd.fix {
+		p.SampleVendor = &verify.SampleOptions{IndexDir: filepath.Join(ctx.Cachedir, "vendor-index")}
+	}
+	var report checkReport
+
+	// resolve records whether fixing requires solving again, rather than
+	// just writing out the lock as updated on load.
+	var fail, resolve bool
+	if !opts.SkipLock && p.Lock == nil && cmd.fix {
+		fail, resolve = true, true
+		logger.Println("# Gopkg.lock does not exist")
+		report.add(checkFinding{
+			Type:        findingNoLock,
+			Message:     "Gopkg.lock does not exist",
+			Remediation: remedyEnsure,
+		})
+	} else if !opts.SkipLock {
 		if p.Lock == nil {
 			return errors.New("Gopkg.lock does not exist, cannot check it against imports and Gopkg.toml")
 		}
 
 		lsat := verify.LockSatisfiesInputs(p.Lock, p.Manifest, p.RootPackageTree)
-		delta := verify.DiffLocks(p.Lock, p.ChangedLock)
-		sat, changed := lsat.Satisfied(), delta.Changed(verify.PruneOptsChanged|verify.HashVersionChanged)
+		changes := dep.DiffLocks(p.Lock, p.ChangedLock)
+		dims := verify.PruneOptsChanged | verify.HashVersionChanged
+		if opts.SkipDigest {
+			dims = verify.PruneOptsChanged
+		}
+		sat, changed := lsat.Satisfied(), changes.Changed(dims)
 
 		if changed || !sat {
 			fail = true
 			logger.Println("# Gopkg.lock is out of sync:")
 			if !sat {
+				resolve = true
 				logger.Printf("%s\n", sprintLockUnsat(lsat))
+				for _, f := range lockUnsatFindings(lsat) {
+					report.add(f)
+				}
 			}
 			if changed {
-				// Sort, for deterministic output.
-				var ordered []string
-				for pr := range delta.ProjectDeltas {
-					ordered = append(ordered, string(pr))
-				}
-				sort.Strings(ordered)
-
-				for _, pr := range ordered {
-					lpd := delta.ProjectDeltas[gps.ProjectRoot(pr)]
+				report.LockChanges = &changes
+				for _, pc := range changes.Projects {
+					pr, lpd := pc.Name, pc.Delta()
 					// Only two possible changes right now are prune opts
 					// changing or a missing hash digest (for old Gopkg.lock
 					// files)
@@ -113,16 +312,32 @@ func (cmd *checkCommand) Run(ctx *dep.Ctx, args []string) error {
 						old := lpd.PruneOptsBefore & ^gps.PruneNestedVendorDirs
 						new := lpd.PruneOptsAfter & ^gps.PruneNestedVendorDirs
 						logger.Printf("%s: prune options changed (%s -> %s)\n", pr, old, new)
+						report.add(checkFinding{
+							Type:        findingPruneOptsChanged,
+							Project:     pr,
+							Expected:    new.String(),
+							Actual:      old.String(),
+							Message:     "prune options changed",
+							Remediation: remedyEnsure,
+						})
 					}
-					if lpd.HashVersionWasZero() {
+					if lpd.HashVersionWasZero() && !opts.SkipDigest {
 						logger.Printf("%s: no hash digest in lock\n", pr)
+						report.add(checkFinding{
+							Type:        findingNoDigestInLock,
+							Project:     pr,
+							Message:     "no hash digest in lock",
+							Remediation: remedyEnsure,
+						})
 					}
 				}
 			}
 		}
 	}
 
-	if !cmd.skipvendor {
+	// Without a lock, there is nothing to check vendor against until it has
+	// been fixed.
+	if !opts.SkipVendor && !(p.Lock == nil && cmd.fix) {
 		if p.Lock == nil {
 			return errors.New("Gopkg.lock does not exist, cannot check vendor against it")
 		}
@@ -146,6 +361,9 @@ func (cmd *checkCommand) Run(ctx *dep.Ctx, args []string) error {
 		// create an array of names to sort for deterministic output.
 		var ordered []string
 		for path, status := range statuses {
+			if opts.SkipDigest && isDigestStatus(status) {
+				continue
+			}
 			ordered = append(ordered, path)
 
 			switch status {
@@ -176,6 +394,13 @@ func (cmd *checkCommand) Run(ctx *dep.Ctx, args []string) error {
 		fmt.Fprintf(&vfbuf, "# vendor is out of sync:\n")
 		fmt.Fprintf(&novbuf, "# out of sync, but ignored, due to noverify in Gopkg.toml:\n")
 
+		digests := make(map[string]verify.VersionedDigest)
+		for _, lp := range p.Lock.Projects() {
+			if vp, ok := lp.(verify.VerifiableProject); ok {
+				digests[string(vp.Ident().ProjectRoot)] = vp.Digest
+			}
+		}
+
 		for _, pr := range ordered {
 			if noverify[pr] {
 				bufptr = &novbuf
@@ -183,30 +408,49 @@ func (cmd *checkCommand) Run(ctx *dep.Ctx, args []string) error {
 				bufptr = &vfbuf
 			}
 
+			f := checkFinding{
+				Project:     pr,
+				Ignored:     noverify[pr],
+				Remediation: remedyVendorOnly,
+			}
 			status := statuses[pr]
 			switch status {
 			case verify.NotInTree:
-				fmt.Fprintf(bufptr, "%s: missing from vendor\n", pr)
+				f.Type, f.Message = findingMissingFromVendor, "missing from vendor"
 			case verify.NotInLock:
 				fi, err := os.Stat(filepath.Join(p.AbsRoot, "vendor", pr))
 				if err != nil {
 					return errors.Wrap(err, "could not stat file that VerifyVendor claimed existed")
 				}
 				if fi.IsDir() {
-					fmt.Fprintf(bufptr, "%s: unused project\n", pr)
+					f.Type, f.Message = findingUnusedProject, "unused project"
 				} else {
-					fmt.Fprintf(bufptr, "%s: orphaned file\n", pr)
+					f.Type, f.Message = findingOrphanedFile, "orphaned file"
 				}
+				f.Remediation = remedyEnsure
 			case verify.DigestMismatchInLock:
-				fmt.Fprintf(bufptr, "%s: hash of vendored tree not equal to digest in Gopkg.lock\n", pr)
+				f.Type, f.Message = findingDigestMismatch, "hash of vendored tree not equal to digest in Gopkg.lock"
+				f.Expected = digests[pr].String()
+				if cmd.json {
+					if vd, err := verify.DigestFromDirectory(filepath.Join(p.AbsRoot, "vendor", pr)); err == nil {
+						f.Actual = vd.String()
+					}
+				}
 			case verify.EmptyDigestInLock:
-				fmt.Fprintf(bufptr, "%s: no digest in Gopkg.lock to compare against hash of vendored tree\n", pr)
+				f.Type, f.Message = findingEmptyDigest, "no digest in Gopkg.lock to compare against hash of vendored tree"
 			case verify.HashVersionMismatch:
 				// This will double-print if the hash version is zero, but
 				// that's a rare case that really only occurs before the first
 				// run with a version of dep >=0.5.0, so it's fine.
-				fmt.Fprintf(bufptr, "%s: hash algorithm mismatch, want version %v\n", pr, verify.HashVersion)
+				f.Type = findingHashVersionMismatch
+				f.Message = fmt.Sprintf("hash algorithm mismatch, want version %v", verify.HashVersion)
+				f.Expected = strconv.Itoa(verify.HashVersion)
+				f.Actual = strconv.Itoa(digests[pr].HashVersion)
+			default:
+				continue
 			}
+			fmt.Fprintf(bufptr, "%s: %s\n", pr, f.Message)
+			report.add(f)
 		}
 
 		if vendorfail {
@@ -220,14 +464,272 @@ func (cmd *checkCommand) Run(ctx *dep.Ctx, args []string) error {
 		}
 	}
 
-	if fail {
+	unreachable := false
+	if opts.Upstream && p.Lock != nil {
+		findings := upstreamFindings(p.Lock, sm)
+		if len(findings) > 0 {
+			unreachable = true
+			if fail {
+				logger.Println()
+			}
+			logger.Println("# upstream sources are unreachable:")
+			for _, f := range findings {
+				logger.Printf("%s: %s\n", f.Project, f.Message)
+				report.add(f)
+			}
+		}
+	}
+
+	unhealthy := false
+	if p.Manifest.Health.Enforce && p.Lock != nil {
+		reports := assessHealth(p.Lock.Projects(), sm, p.Manifest.Health, time.Now())
+		findings := healthFindings(reports, p.Manifest.Health)
+		if len(findings) > 0 {
+			unhealthy = true
+			if fail || unreachable {
+				logger.Println()
+			}
+			logger.Println("# dependencies are unhealthy:")
+			for _, f := range findings {
+				logger.Printf("%s: %s (%s)\n", f.Project, f.Message, f.Actual)
+				report.add(f)
+			}
+		}
+		if ctx.Verbose {
+			for _, h := range reports {
+				if h.Err != nil {
+					ctx.Err.Println(h.Err)
+				}
+			}
+		}
+	}
+
+	disallowed := false
+	if p.Manifest.KindPolicy.Enforce {
+		findings := kindPolicyFindings(p.KindPolicyViolations())
+		if len(findings) > 0 {
+			disallowed = true
+			if fail || unreachable || unhealthy {
+				logger.Println()
+			}
+			logger.Println("# Gopkg.toml has rules that its kind policy does not permit:")
+			for _, f := range findings {
+				logger.Println(f.Message)
+				report.add(f)
+			}
+		}
+	}
+
+	forbidden := false
+	if len(p.Manifest.Forbidden) > 0 {
+		violations, err := p.ImportViolations()
+		if err != nil {
+			return err
+		}
+		if len(violations) > 0 {
+			forbidden = true
+			if fail || unreachable || unhealthy || disallowed {
+				logger.Println()
+			}
+			logger.Println("# imports are forbidden by Gopkg.toml:")
+			for _, f := range forbiddenImportFindings(violations) {
+				logger.Println(f.Message)
+				report.add(f)
+			}
+		}
+	}
+
+	ungenerated := false
+	if opts.Generated && p.Lock != nil {
+		findings, err := generatedFindings(filepath.Join(p.AbsRoot, "vendor"), p.Lock)
+		if err != nil {
+			return err
+		}
+		if len(findings) > 0 {
+			ungenerated = true
+			if fail || unreachable || unhealthy || disallowed || forbidden {
+				logger.Println()
+			}
+			logger.Println("# generated code in vendor is out of step with its protobuf definitions:")
+			for _, f := range findings {
+				logger.Printf("%s: %s\n", f.Project, f.Message)
+				report.add(f)
+			}
+		}
+	}
+
+	if fail && cmd.fix {
+		if err := cmd.runFix(ctx, p, sm, opts, resolve, logger); err != nil {
+			return err
+		}
+		fail, report.Fixed = false, true
+	}
+	report.OK = !fail && !unreachable && !unhealthy && !disallowed && !forbidden && !ungenerated
+
+	if cmd.json {
+		if report.Findings == nil {
+			report.Findings = []checkFinding{}
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return errors.Wrap(err, "failed to encode check report")
+		}
+		ctx.Out.Print(buf.String())
+	}
+
+	if !report.OK {
 		return silentfail{}
 	}
 	return nil
 }
 
+// options combines the checks selected on the command line with those set in
+// the manifest.
+func (cmd *checkCommand) options(mopts dep.CheckOptions) dep.CheckOptions {
+	return dep.CheckOptions{
+		SkipLock:   cmd.skiplock || mopts.SkipLock,
+		SkipVendor: cmd.skipvendor || mopts.SkipVendor,
+		SkipDigest: cmd.skipdigest || mopts.SkipDigest,
+		Upstream:   cmd.upstream || mopts.Upstream,
+		Generated:  cmd.generated || mopts.Generated,
+		Thorough:   cmd.thorough || mopts.Thorough,
+	}
+}
+
+// isDigestStatus reports whether status describes a difference between the
+// digest of a vendored project and its lock, rather than the project being
+// missing from either.
+func isDigestStatus(status verify.VendorStatus) bool {
+	switch status {
+	case verify.DigestMismatchInLock, verify.HashVersionMismatch, verify.EmptyDigestInLock:
+		return true
+	}
+	return false
+}
+
+// upstreamFindings returns a finding for each project in l whose source cannot
+// be reached.
+func upstreamFindings(l *dep.Lock, sm gps.SourceManager) []checkFinding {
+	lps := l.Projects()
+	errs := make([]error, len(lps))
+
+	var wg sync.WaitGroup
+	for i, lp := range lps {
+		wg.Add(1)
+		go func(i int, id gps.ProjectIdentifier) {
+			defer wg.Done()
+			exists, err := sm.SourceExists(id)
+			if err == nil && !exists {
+				err = errors.New("source does not exist")
+			}
+			errs[i] = err
+		}(i, lp.Ident())
+	}
+	wg.Wait()
+
+	var findings []checkFinding
+	for i, lp := range lps {
+		if errs[i] != nil {
+			findings = append(findings, checkFinding{
+				Type:        findingUpstreamUnreachable,
+				Project:     string(lp.Ident().ProjectRoot),
+				Message:     fmt.Sprintf("upstream source cannot be reached: %s", errors.Cause(errs[i])),
+				Remediation: remedyUpstream,
+			})
+		}
+	}
+	return findings
+}
+
+// runFix writes out the lock and vendor for p, after solving again if resolve
+// is set, so that subsequent checks pass.
+func (cmd *checkCommand) runFix(ctx *dep.Ctx, p *dep.Project, sm gps.SourceManager, opts dep.CheckOptions, resolve bool, logger *log.Logger) error {
+	lock := p.ChangedLock
+	if opts.SkipLock {
+		lock = p.Lock
+	}
+
+	if resolve {
+		params := p.MakeParams()
+		if ctx.Verbose {
+			params.TraceLogger = ctx.Err
+		}
+		applyImportPolicy(ctx, &params)
+		yanked, err := applyYanked(ctx, &params)
+		if err != nil {
+			return err
+		}
+		saveHints, err := applyHints(ctx, &params)
+		if err != nil {
+			return err
+		}
+		defer saveHints()
+		if err := ctx.ValidateParams(sm, params); err != nil {
+			return err
+		}
+
+		solver, err := gps.Prepare(params, sm)
+		if err != nil {
+			return errors.Wrap(err, "prepare solver")
+		}
+		solution, err := solver.Solve(context.TODO())
+		if err != nil {
+			return handleAllTheFailuresOfTheWorld(err)
+		}
+		lock = dep.LockFromSolution(solution, p.Manifest.PruneOptions)
+		if err := lock.RecordTestOnly(p, sm); err != nil {
+			return err
+		}
+		if ctx.YankedWarnOnly {
+			warnYanked(ctx.Err, yanked, lock)
+		}
+		if err := checkQuarantine(p.Manifest, p.Lock, lock); err != nil {
+			return err
+		}
+		if err := checkFreeze(ctx, p, lock, cmd.overrideFreeze); err != nil {
+			return err
+		}
+		if err := checkMajorUpdates(p.Manifest, p.Lock, lock, ""); err != nil {
+			return err
+		}
+	}
+
+	behavior := dep.VendorOnChanged
+	if opts.SkipVendor {
+		behavior = dep.VendorNever
+	}
+	dw, err := dep.NewDeltaWriter(p, lock, behavior)
+	if err != nil {
+		return err
+	}
+
+	var wlogger *log.Logger
+	if ctx.Verbose {
+		wlogger = ctx.Err
+	}
+	wctx, stop := interruptContext()
+	defer stop()
+	if err := dw.Write(wctx, p.AbsRoot, sm, true, wlogger); err != nil {
+		return errors.WithMessage(err, "grouped write of manifest, lock and vendor")
+	}
+
+	logger.Println()
+	if opts.SkipVendor {
+		logger.Println("# fixed: Gopkg.lock has been updated")
+	} else {
+		logger.Println("# fixed: Gopkg.lock and vendor have been updated")
+	}
+	return nil
+}
+
 func sprintLockUnsat(lsat verify.LockSatisfaction) string {
 	var buf bytes.Buffer
+	if lsat.InputsVersionMismatch {
+		fmt.Fprintf(&buf, "Gopkg.lock's input-imports were recorded by a newer version of dep, and cannot be compared\n")
+	}
+
 	sort.Strings(lsat.MissingImports)
 	for _, missing := range lsat.MissingImports {
 		fmt.Fprintf(&buf, "%s: imported or required, but missing from Gopkg.lock's input-imports\n", missing)
@@ -257,5 +759,15 @@ func sprintLockUnsat(lsat verify.LockSatisfaction) string {
 		unmatched := lsat.UnmetConstraints[gps.ProjectRoot(pr)]
 		fmt.Fprintf(&buf, "%s@%s: not allowed by constraint %s\n", pr, unmatched.V, unmatched.C)
 	}
+
+	ordered = ordered[:0]
+	for pr := range lsat.UnmetRootDirs {
+		ordered = append(ordered, string(pr))
+	}
+	sort.Strings(ordered)
+	for _, pr := range ordered {
+		mismatch := lsat.UnmetRootDirs[gps.ProjectRoot(pr)]
+		fmt.Fprintf(&buf, "%s: locked in root directory %q, but Gopkg.toml sets %q\n", pr, mismatch.Locked, mismatch.Want)
+	}
 	return strings.TrimSpace(buf.String())
 }
@@ -0,0 +1,188 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+)
+
+// The types of finding reported by dep check.
+const (
+	findingNoLock              = "no-lock"
+	findingMissingInputImport  = "missing-input-import"
+	findingExcessInputImport   = "excess-input-import"
+	findingUnmetOverride       = "unmet-override"
+	findingUnmetConstraint     = "unmet-constraint"
+	findingUnmetRootDir        = "unmet-root-dir"
+	findingInputsVersion       = "inputs-version-mismatch"
+	findingPruneOptsChanged    = "prune-options-changed"
+	findingNoDigestInLock      = "no-digest-in-lock"
+	findingMissingFromVendor   = "missing-from-vendor"
+	findingUnusedProject       = "unused-project"
+	findingOrphanedFile        = "orphaned-file"
+	findingDigestMismatch      = "digest-mismatch"
+	findingEmptyDigest         = "empty-digest"
+	findingHashVersionMismatch = "hash-version-mismatch"
+	findingUpstreamUnreachable = "upstream-unreachable"
+	findingStaleRevision       = "stale-revision"
+	findingInactiveProject     = "inactive-project"
+	findingKindPolicy          = "kind-policy-violation"
+	findingMissingGenerated    = "missing-generated-code"
+	findingMissingProto        = "missing-proto-source"
+	findingForbiddenImport     = "forbidden-import"
+)
+
+const (
+	remedyEnsure     = "run dep ensure to update Gopkg.lock and vendor"
+	remedyVendorOnly = "run dep ensure -vendor-only to regenerate vendor from Gopkg.lock"
+	remedyUpstream   = "check that the source is still available, or change the source for the project in Gopkg.toml"
+	remedyKindPolicy = "remove the rule from Gopkg.toml, or permit the kind of dependency in its [kind-policy] table"
+	remedyGenerated  = "use a version of the project that commits its generated code, or disable pruning for it in Gopkg.toml"
+	remedyForbidden  = "remove the import, or change the [[forbid]] rule in Gopkg.toml"
+)
+
+// checkFinding is a single problem found by dep check.
+type checkFinding struct {
+	Type string `json:"type"`
+	// Project is the project root the finding concerns, if any.
+	Project string `json:"project,omitempty"`
+	// Import is the import path the finding concerns, for findings about
+	// Gopkg.lock's input-imports and forbidden imports.
+	Import   string `json:"import,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	// Ignored is set when the project is listed in noverify, so the finding
+	// does not fail the check.
+	Ignored     bool   `json:"ignored,omitempty"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// checkReport is the result of dep check, as output by -json.
+type checkReport struct {
+	// OK is set when the check passed, after any fixes were applied.
+	OK bool `json:"ok"`
+	// Fixed is set when -fix rewrote Gopkg.lock or vendor.
+	Fixed    bool           `json:"fixed,omitempty"`
+	Findings []checkFinding `json:"findings"`
+	// LockChanges is what updating Gopkg.lock would change, when it is out
+	// of sync without needing a solve.
+	LockChanges *dep.LockChanges `json:"lockChanges,omitempty"`
+}
+
+func (r *checkReport) add(f checkFinding) {
+	r.Findings = append(r.Findings, f)
+}
+
+// kindPolicyFindings converts violations of the kind policy of the manifest
+// into findings.
+func kindPolicyFindings(violations []dep.KindPolicyViolation) []checkFinding {
+	var findings []checkFinding
+	for _, v := range violations {
+		findings = append(findings, checkFinding{
+			Type:        findingKindPolicy,
+			Project:     string(v.ProjectRoot),
+			Actual:      v.Kind,
+			Message:     v.String(),
+			Remediation: remedyKindPolicy,
+		})
+	}
+	return findings
+}
+
+// forbiddenImportFindings converts the import chains that the [[forbid]] rules
+// of the manifest forbid into findings.
+func forbiddenImportFindings(violations []dep.ImportViolation) []checkFinding {
+	var findings []checkFinding
+	for _, v := range violations {
+		findings = append(findings, checkFinding{
+			Type:        findingForbiddenImport,
+			Import:      v.Import(),
+			Actual:      strings.Join(v.Chain, " -> "),
+			Message:     v.String(),
+			Remediation: remedyForbidden,
+		})
+	}
+	return findings
+}
+
+// lockUnsatFindings converts the ways in which a lock fails to satisfy its
+// inputs into findings, in the same order as sprintLockUnsat.
+func lockUnsatFindings(lsat verify.LockSatisfaction) []checkFinding {
+	var findings []checkFinding
+
+	if lsat.InputsVersionMismatch {
+		findings = append(findings, checkFinding{
+			Type:        findingInputsVersion,
+			Message:     "input-imports recorded by a newer version of dep, and cannot be compared",
+			Remediation: remedyEnsure,
+		})
+	}
+
+	sort.Strings(lsat.MissingImports)
+	for _, missing := range lsat.MissingImports {
+		findings = append(findings, checkFinding{
+			Type:        findingMissingInputImport,
+			Import:      missing,
+			Message:     "imported or required, but missing from Gopkg.lock's input-imports",
+			Remediation: remedyEnsure,
+		})
+	}
+
+	sort.Strings(lsat.ExcessImports)
+	for _, excess := range lsat.ExcessImports {
+		findings = append(findings, checkFinding{
+			Type:        findingExcessInputImport,
+			Import:      excess,
+			Message:     "in Gopkg.lock's input-imports, but neither imported nor required",
+			Remediation: remedyEnsure,
+		})
+	}
+
+	unmet := func(typ, rule string, m map[gps.ProjectRoot]verify.ConstraintMismatch) {
+		var ordered []string
+		for pr := range m {
+			ordered = append(ordered, string(pr))
+		}
+		sort.Strings(ordered)
+		for _, pr := range ordered {
+			mismatch := m[gps.ProjectRoot(pr)]
+			findings = append(findings, checkFinding{
+				Type:        typ,
+				Project:     pr,
+				Expected:    mismatch.C.String(),
+				Actual:      mismatch.V.String(),
+				Message:     "locked version not allowed by " + rule,
+				Remediation: remedyEnsure,
+			})
+		}
+	}
+	unmet(findingUnmetOverride, "override", lsat.UnmetOverrides)
+	unmet(findingUnmetConstraint, "constraint", lsat.UnmetConstraints)
+
+	var ordered []string
+	for pr := range lsat.UnmetRootDirs {
+		ordered = append(ordered, string(pr))
+	}
+	sort.Strings(ordered)
+	for _, pr := range ordered {
+		mismatch := lsat.UnmetRootDirs[gps.ProjectRoot(pr)]
+		findings = append(findings, checkFinding{
+			Type:        findingUnmetRootDir,
+			Project:     pr,
+			Expected:    mismatch.Want,
+			Actual:      mismatch.Locked,
+			Message:     "locked in a root directory other than the one Gopkg.toml sets",
+			Remediation: remedyEnsure,
+		})
+	}
+
+	return findings
+}
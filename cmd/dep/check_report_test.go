@@ -0,0 +1,45 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+)
+
+func TestLockUnsatFindings(t *testing.T) {
+	c, _ := gps.NewSemverConstraint("^1.0.0")
+	lsat := verify.LockSatisfaction{
+		MissingImports: []string{"github.com/foo/b", "github.com/foo/a"},
+		ExcessImports:  []string{"github.com/bar"},
+		UnmetConstraints: map[gps.ProjectRoot]verify.ConstraintMismatch{
+			"github.com/baz": {C: c, V: gps.NewVersion("v2.0.0")},
+		},
+	}
+
+	want := []checkFinding{
+		{Type: findingMissingInputImport, Import: "github.com/foo/a", Message: "imported or required, but missing from Gopkg.lock's input-imports", Remediation: remedyEnsure},
+		{Type: findingMissingInputImport, Import: "github.com/foo/b", Message: "imported or required, but missing from Gopkg.lock's input-imports", Remediation: remedyEnsure},
+		{Type: findingExcessInputImport, Import: "github.com/bar", Message: "in Gopkg.lock's input-imports, but neither imported nor required", Remediation: remedyEnsure},
+		{Type: findingUnmetConstraint, Project: "github.com/baz", Expected: "^1.0.0", Actual: "v2.0.0", Message: "locked version not allowed by constraint", Remediation: remedyEnsure},
+	}
+	if got := lockUnsatFindings(lsat); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected findings:\n\t(GOT): %+v\n\t(WNT): %+v", got, want)
+	}
+}
+
+func TestLockUnsatFindingsInputsVersion(t *testing.T) {
+	lsat := verify.LockSatisfaction{LockExisted: true, InputsVersionMismatch: true}
+
+	want := []checkFinding{
+		{Type: findingInputsVersion, Message: "input-imports recorded by a newer version of dep, and cannot be compared", Remediation: remedyEnsure},
+	}
+	if got := lockUnsatFindings(lsat); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected findings:\n\t(GOT): %+v\n\t(WNT): %+v", got, want)
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const completionShortHelp = `Print a shell completion script`
+const completionLongHelp = `
+Print a script that enables tab completion of dep commands in the given shell,
+which must be one of bash, zsh, fish or powershell.
+
+In addition to commands and flags, the completions are aware of the current
+project: the arguments to 'dep ensure -update' complete to the projects in
+Gopkg.lock, and 'dep ensure -add <path>@' completes to the versions that are
+available for that project, as recorded in the local cache.
+
+To enable completions for the current shell session:
+
+  bash:        source <(dep completion bash)
+  zsh:         source <(dep completion zsh)
+  fish:        dep completion fish | source
+  powershell:  dep completion powershell | Out-String | Invoke-Expression
+
+To enable them permanently, add the same line to the shell's startup file.
+`
+
+// completeArg is the hidden first argument with which the completion scripts
+// call back into dep to retrieve candidates for the word under the cursor.
+const completeArg = "__complete"
+
+// completionTimeout bounds how long dynamic completion may take to retrieve
+// versions, so that an uncached project cannot stall the shell.
+const completionTimeout = 2 * time.Second
+
+// completionCacheAge is the cache age used to look up versions when
+// $DEPCACHEAGE is not set, so that completion prefers cached version lists,
+// however old, over going to the network.
+const completionCacheAge = 30 * 24 * time.Hour
+
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+var completionScripts = map[string]string{
+	"bash": `# bash completion for dep
+_dep_completion() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words
+    read -ra words <<< "$line"
+    if [[ "$line" == *" " ]]; then
+        words+=("")
+    fi
+    local IFS=$'\n'
+    COMPREPLY=($(dep completion __complete "${words[@]:1}" 2>/dev/null))
+    local cur="${words[${#words[@]}-1]}"
+    if [[ "$cur" == *@* && "$COMP_WORDBREAKS" == *@* ]]; then
+        COMPREPLY=("${COMPREPLY[@]#*@}")
+    fi
+}
+complete -o default -F _dep_completion dep
+`,
+	"zsh": `#compdef dep
+# zsh completion for dep
+_dep() {
+    local -a completions
+    completions=("${(@f)$(dep completion __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n "${completions[1]}" ]]; then
+        compadd -Q -- "${completions[@]}"
+    fi
+}
+compdef _dep dep
+`,
+	"fish": `# fish completion for dep
+function __dep_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    dep completion __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c dep -f -a '(__dep_complete)'
+`,
+	"powershell": `# powershell completion for dep
+Register-ArgumentCompleter -Native -CommandName dep -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') {
+        $words += ''
+    }
+    & dep completion __complete @words 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`,
+}
+
+type completionCommand struct{}
+
+func (cmd *completionCommand) Name() string { return "completion" }
+func (cmd *completionCommand) Args() string {
+	return strings.Join(completionShells, "|")
+}
+func (cmd *completionCommand) ShortHelp() string { return completionShortHelp }
+func (cmd *completionCommand) LongHelp() string  { return completionLongHelp }
+func (cmd *completionCommand) Hidden() bool      { return false }
+
+func (cmd *completionCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *completionCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 && args[0] == completeArg {
+		c := &completer{
+			commands: commandList(),
+			locked:   func() []string { return lockedProjectRoots(ctx) },
+			versions: func(path string) []string { return completionVersions(ctx, path) },
+		}
+		for _, s := range c.complete(args[1:]) {
+			ctx.Out.Println(s)
+		}
+		return nil
+	}
+
+	if len(args) != 1 {
+		return errors.Errorf("completion takes exactly one argument, one of %s", cmd.Args())
+	}
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		return errors.Errorf("unsupported shell %q, must be one of %s", args[0], cmd.Args())
+	}
+	ctx.Out.Print(script)
+	return nil
+}
+
+// completer computes the candidates for the last of a list of words typed
+// after "dep".
+type completer struct {
+	commands []command
+	// locked returns the roots of the projects in the current lock.
+	locked func() []string
+	// versions returns the versions available for the project containing
+	// the given import path.
+	versions func(path string) []string
+}
+
+func (c *completer) complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+
+	if len(words) == 1 {
+		var names []string
+		for _, cmd := range c.commands {
+			if !cmd.Hidden() {
+				names = append(names, cmd.Name())
+			}
+		}
+		return filterPrefix(names, cur)
+	}
+
+	var cmd command
+	for _, cand := range c.commands {
+		if cand.Name() == words[0] {
+			cmd = cand
+			break
+		}
+	}
+	if cmd == nil {
+		return nil
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		fs := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+		cmd.Register(fs)
+		var flags []string
+		fs.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, "-"+f.Name)
+		})
+		return filterPrefix(flags, cur)
+	}
+
+	prev := words[len(words)-2]
+	args := words[1 : len(words)-1]
+	switch cmd.Name() {
+	case "ensure":
+		if hasWord(args, "-update") {
+			return filterPrefix(excludeWords(c.locked(), args), cur)
+		}
+		if hasWord(args, "-add") {
+			at := strings.LastIndex(cur, "@")
+			if at == -1 {
+				return nil
+			}
+			var cands []string
+			for _, v := range c.versions(cur[:at]) {
+				cands = append(cands, cur[:at+1]+v)
+			}
+			return filterPrefix(cands, cur)
+		}
+	case "graph":
+		if prev == "-from" || prev == "-to" {
+			return filterPrefix(c.locked(), cur)
+		}
+	case "completion":
+		if len(args) == 0 {
+			return filterPrefix(completionShells, cur)
+		}
+	case "schema":
+		if len(args) == 0 {
+			return filterPrefix([]string{"manifest", "lock"}, cur)
+		}
+	}
+	return nil
+}
+
+// lockedProjectRoots returns the roots of the projects in the lock of the
+// project containing the working directory, if any.
+func lockedProjectRoots(ctx *dep.Ctx) []string {
+	p, err := ctx.LoadProject()
+	if err != nil || p.Lock == nil {
+		return nil
+	}
+
+	var roots []string
+	for _, lp := range p.Lock.Projects() {
+		roots = append(roots, string(lp.Ident().ProjectRoot))
+	}
+	return roots
+}
+
+// completionVersions returns the versions available for the project containing
+// path, preferring the version lists stored in the local cache. It gives up,
+// returning nothing, if they cannot be retrieved within completionTimeout.
+func completionVersions(ctx *dep.Ctx, path string) []string {
+	// Work on a copy of ctx, so that the source manager's logging does not
+	// end up among the candidates.
+	smctx := *ctx
+	smctx.Out = log.New(ioutil.Discard, "", 0)
+	if smctx.CacheAge == 0 {
+		smctx.CacheAge = completionCacheAge
+	}
+
+	done := make(chan []string, 1)
+	go func() {
+		sm, err := smctx.SourceManager()
+		if err != nil {
+			done <- nil
+			return
+		}
+		defer sm.Release()
+
+		root, err := sm.DeduceProjectRoot(path)
+		if err != nil {
+			done <- nil
+			return
+		}
+		pvs, err := sm.ListVersions(gps.ProjectIdentifier{ProjectRoot: root})
+		if err != nil {
+			done <- nil
+			return
+		}
+
+		gps.SortPairedForUpgrade(pvs)
+		vs := make([]string, 0, len(pvs))
+		for _, pv := range pvs {
+			vs = append(vs, pv.String())
+		}
+		done <- vs
+	}()
+
+	select {
+	case vs := <-done:
+		return vs
+	case <-time.After(completionTimeout):
+		return nil
+	}
+}
+
+// filterPrefix returns the elements of cands that begin with prefix, keeping
+// their order.
+func filterPrefix(cands []string, prefix string) []string {
+	var out []string
+	for _, c := range cands {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func hasWord(words []string, w string) bool {
+	for _, word := range words {
+		if word == w {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeWords returns the elements of cands that do not appear in words, in
+// sorted order.
+func excludeWords(cands, words []string) []string {
+	var out []string
+	for _, c := range cands {
+		if !hasWord(words, c) {
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,59 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompleterComplete(t *testing.T) {
+	var versionsFor []string
+	c := &completer{
+		commands: commandList(),
+		locked: func() []string {
+			return []string{"github.com/foo/baz", "github.com/foo/bar", "github.com/qux/quux"}
+		},
+		versions: func(path string) []string {
+			versionsFor = append(versionsFor, path)
+			return []string{"v1.1.0", "v1.0.0", "master"}
+		},
+	}
+
+	cases := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"ens"}, []string{"ensure"}},
+		{[]string{"ensure", "-upd"}, []string{"-update"}},
+		{[]string{"ensure", "-update", ""}, []string{"github.com/foo/bar", "github.com/foo/baz", "github.com/qux/quux"}},
+		{[]string{"ensure", "-update", "github.com/foo/bar", "github.com/foo/"}, []string{"github.com/foo/baz"}},
+		{[]string{"ensure", "-add", "github.com/foo/new"}, nil},
+		{[]string{"ensure", "-add", "github.com/foo/new/pkg@v1"}, []string{"github.com/foo/new/pkg@v1.1.0", "github.com/foo/new/pkg@v1.0.0"}},
+		{[]string{"graph", "-to", "github.com/q"}, []string{"github.com/qux/quux"}},
+		{[]string{"completion", "f"}, []string{"fish"}},
+		{[]string{"completion", "fish", ""}, nil},
+		{[]string{"nosuchcommand", ""}, nil},
+	}
+
+	for _, tc := range cases {
+		got := c.complete(tc.words)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("complete(%q):\n\t(GOT): %q\n\t(WNT): %q", tc.words, got, tc.want)
+		}
+	}
+
+	if want := []string{"github.com/foo/new/pkg"}; !reflect.DeepEqual(versionsFor, want) {
+		t.Errorf("expected versions to be looked up for %q, got %q", want, versionsFor)
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range completionShells {
+		if _, ok := completionScripts[shell]; !ok {
+			t.Errorf("no completion script for %s", shell)
+		}
+	}
+}
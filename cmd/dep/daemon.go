@@ -0,0 +1,607 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/watch"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+)
+
+const daemonShortHelp = `Serve information about the project's dependencies to editors`
+const daemonLongHelp = `
+Daemon starts an HTTP server that answers questions about the current
+project's dependencies, for use by editor plugins. Each request reads
+Gopkg.toml and Gopkg.lock from disk, so edits are picked up once saved.
+
+All responses are JSON. The following endpoints are served:
+
+  GET /constraint?name=<project root>
+      The constraint and source declared for the project in Gopkg.toml, and
+      the version recorded for it in Gopkg.lock.
+
+  GET /versions?name=<project root>
+      The versions available for the project, ordered as the solver prefers
+      them.
+
+  GET /hover?line=<n>
+      The same information as /constraint, for the [[constraint]] or
+      [[override]] table covering line n (1-based) of Gopkg.toml.
+
+  GET /diagnostics
+      Hints for each [[constraint]] and [[override]] in Gopkg.toml, such as
+      newer versions being available, with the line of the table they
+      concern.
+
+  GET /changes
+      The changes that dep ensure would make to Gopkg.lock without solving,
+      for imports and prune options changed since it was written, in the
+      same form as dep ensure -dry-run -json.
+
+  GET /changes?solve=true
+      The changes that dep ensure would make to Gopkg.lock, solving if the
+      lock no longer satisfies the project, as dep ensure -dry-run -json.
+
+  GET /dirty
+      Whether Gopkg.toml, Gopkg.lock or anything under vendor has been
+      modified since the daemon started, or since the last POST /dirty, and
+      whether modifications are being watched at all. Where they are not
+      (on systems other than Linux, macOS, the BSDs and Windows, or where
+      watching fails), the project is always dirty.
+
+  POST /dirty
+      Marks the project clean, answering as GET /dirty did before. Editors
+      can run dep check only when this reports the project dirty, rather than
+      on every save; post before checking, so as not to miss modifications
+      made during the check.
+
+  GET /metrics
+      Metrics for monitoring with Prometheus, in its text format rather than
+      JSON: the number and duration of requests by path, the duration of
+      solves by whether they succeeded, lookups in the cache of version lists
+      by whether they hit, failures to list versions by the host of the
+      source, and the time spent waiting for the lock on the cache, which
+      other dep processes may hold, and for requests being answered before.
+
+The source manager is only held while a request is being answered, so other
+dep commands can run alongside the daemon. Version lists are cached for
+-cache-ttl.
+`
+
+type daemonCommand struct {
+	addr     string
+	cacheTTL time.Duration
+}
+
+func (cmd *daemonCommand) Name() string { return "daemon" }
+func (cmd *daemonCommand) Args() string {
+	return "[-addr <host:port>] [-cache-ttl <duration>]"
+}
+func (cmd *daemonCommand) ShortHelp() string { return daemonShortHelp }
+func (cmd *daemonCommand) LongHelp() string  { return daemonLongHelp }
+func (cmd *daemonCommand) Hidden() bool      { return false }
+
+func (cmd *daemonCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.addr, "addr", "localhost:7370", "address to listen on")
+	fs.DurationVar(&cmd.cacheTTL, "cache-ttl", time.Minute, "how long to cache version lists")
+}
+
+func (cmd *daemonCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.Errorf("daemon takes no arguments, got %q", args)
+	}
+
+	// Fail early if we're not in a project.
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+
+	d := newDaemon(ctx, cmd.cacheTTL)
+	if tr, err := watch.New(p.AbsRoot); err == nil {
+		d.tracker = tr
+		defer tr.Close()
+	} else if ctx.Verbose {
+		ctx.Err.Printf("Not watching the project for modifications: %s\n", err)
+	}
+	d.newSM = func() (gps.SourceManager, func(), error) {
+		sm, err := ctx.SourceManager()
+		if err != nil {
+			return nil, nil, err
+		}
+		return sm, sm.Release, nil
+	}
+
+	srv := &http.Server{Addr: cmd.addr, Handler: d}
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt)
+	go func() {
+		<-sigch
+		srv.Close()
+	}()
+
+	ctx.Err.Printf("Serving dependency information on %s\n", cmd.addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+type cachedVersions struct {
+	at time.Time
+	vl []gps.PairedVersion
+}
+
+// daemon answers editor requests about the project found from ctx.
+type daemon struct {
+	ctx *dep.Ctx
+	mux *http.ServeMux
+	ttl time.Duration
+
+	// newSM returns a source manager, and a func to release it.
+	newSM func() (gps.SourceManager, func(), error)
+
+	// tracker tracks modifications to the project, if they can be watched.
+	tracker *watch.Tracker
+
+	// mu serializes use of source managers and guards versions.
+	mu       sync.Mutex
+	versions map[gps.ProjectIdentifier]cachedVersions
+
+	metrics *daemonMetrics
+}
+
+func newDaemon(ctx *dep.Ctx, ttl time.Duration) *daemon {
+	d := &daemon{
+		ctx:      ctx,
+		mux:      http.NewServeMux(),
+		ttl:      ttl,
+		versions: make(map[gps.ProjectIdentifier]cachedVersions),
+		metrics:  newDaemonMetrics(),
+	}
+
+	d.mux.HandleFunc("/constraint", d.handleConstraint)
+	d.mux.HandleFunc("/versions", d.handleVersions)
+	d.mux.HandleFunc("/hover", d.handleHover)
+	d.mux.HandleFunc("/diagnostics", d.handleDiagnostics)
+	d.mux.HandleFunc("/changes", d.handleChanges)
+	d.mux.HandleFunc("/dirty", d.handleDirty)
+	d.mux.HandleFunc("/metrics", d.handleMetrics)
+	return d
+}
+
+func (d *daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+	d.mux.ServeHTTP(rec, r)
+
+	// Requests for paths that are not served are counted together, so that
+	// they cannot add labels without bound.
+	path := "other"
+	if _, pattern := d.mux.Handler(r); pattern != "" {
+		path = pattern
+	}
+	d.metrics.observeRequest(path, rec.code, time.Since(start))
+}
+
+// lock takes the daemon's lock, recording the time spent waiting for it.
+func (d *daemon) lock() {
+	start := time.Now()
+	d.mu.Lock()
+	d.metrics.observeLockWait("daemon", time.Since(start), false)
+}
+
+// sourceManager returns a source manager and a func to release it, recording
+// the time spent waiting for the lock on the cache. d.mu must be held.
+func (d *daemon) sourceManager() (gps.SourceManager, func(), error) {
+	start := time.Now()
+	sm, release, err := d.newSM()
+	_, locked := errors.Cause(err).(gps.CouldNotCreateLockError)
+	d.metrics.observeLockWait("cache", time.Since(start), locked)
+	return sm, release, err
+}
+
+// listVersions returns the versions of the project, sorted for upgrade,
+// consulting the cache first.
+func (d *daemon) listVersions(id gps.ProjectIdentifier) ([]gps.PairedVersion, error) {
+	d.lock()
+	defer d.mu.Unlock()
+
+	if cv, has := d.versions[id]; has && time.Since(cv.at) < d.ttl {
+		d.metrics.observeVersionCache(true)
+		return cv.vl, nil
+	}
+	d.metrics.observeVersionCache(false)
+
+	sm, release, err := d.sourceManager()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	vl, err := sm.ListVersions(id)
+	if err != nil {
+		d.metrics.observeFetchError(fetchHost(sm, id))
+		return nil, err
+	}
+	gps.SortPairedForUpgrade(vl)
+
+	d.versions[id] = cachedVersions{at: time.Now(), vl: vl}
+	return vl, nil
+}
+
+// constraintInfo describes a project's declared constraint and locked version.
+type constraintInfo struct {
+	Name       string       `json:"name"`
+	Constraint string       `json:"constraint"`
+	Source     string       `json:"source,omitempty"`
+	Override   bool         `json:"override"`
+	Locked     *versionInfo `json:"locked,omitempty"`
+}
+
+type versionInfo struct {
+	Version  string `json:"version,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	Type     string `json:"type"`
+}
+
+func newVersionInfo(v gps.Version) *versionInfo {
+	rev, _, _ := gps.VersionComponentStrings(v)
+	vi := &versionInfo{Revision: rev}
+	switch v.Type() {
+	case gps.IsRevision:
+		vi.Type = "revision"
+	case gps.IsBranch:
+		vi.Type = "branch"
+	case gps.IsSemver:
+		vi.Type = "semver"
+	default:
+		vi.Type = "version"
+	}
+	if v.Type() != gps.IsRevision {
+		vi.Version = v.String()
+	}
+	return vi
+}
+
+func projectConstraintInfo(p *dep.Project, pr gps.ProjectRoot) (constraintInfo, bool) {
+	ci := constraintInfo{Name: string(pr)}
+
+	pp, has := p.Manifest.Ovr[pr]
+	if has {
+		ci.Override = true
+	} else if pp, has = p.Manifest.Constraints[pr]; !has {
+		return ci, false
+	}
+
+	ci.Constraint = pp.Constraint.String()
+	ci.Source = pp.Source
+	for _, lp := range p.Lock.Projects() {
+		if lp.Ident().ProjectRoot == pr {
+			ci.Locked = newVersionInfo(lp.Version())
+		}
+	}
+	return ci, true
+}
+
+func (d *daemon) handleConstraint(w http.ResponseWriter, r *http.Request) {
+	p, err := d.ctx.LoadProject()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	pr := gps.ProjectRoot(r.URL.Query().Get("name"))
+	ci, has := projectConstraintInfo(p, pr)
+	if !has {
+		writeJSONError(w, http.StatusNotFound, errors.Errorf("no constraint or override for %s in %s", pr, dep.ManifestName))
+		return
+	}
+	writeJSON(w, ci)
+}
+
+func (d *daemon) handleVersions(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("name must be specified"))
+		return
+	}
+
+	id := gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(name)}
+	if p, err := d.ctx.LoadProject(); err == nil {
+		// Respect any alternate source declared in the manifest.
+		if pp, has := p.Manifest.Ovr[id.ProjectRoot]; has {
+			id.Source = pp.Source
+		} else if pp, has := p.Manifest.Constraints[id.ProjectRoot]; has {
+			id.Source = pp.Source
+		}
+	}
+
+	vl, err := d.listVersions(id)
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	versions := make([]*versionInfo, 0, len(vl))
+	for _, v := range vl {
+		versions = append(versions, newVersionInfo(v))
+	}
+	writeJSON(w, struct {
+		Name     string         `json:"name"`
+		Versions []*versionInfo `json:"versions"`
+	}{name, versions})
+}
+
+func (d *daemon) handleHover(w http.ResponseWriter, r *http.Request) {
+	line, err := strconv.Atoi(r.URL.Query().Get("line"))
+	if err != nil || line < 1 {
+		writeJSONError(w, http.StatusBadRequest, errors.New("line must be a positive integer"))
+		return
+	}
+
+	p, err := d.ctx.LoadProject()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tables, err := readManifestTables(p)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	mt, has := tableAtLine(tables, line)
+	if !has || mt.name == "" {
+		writeJSONError(w, http.StatusNotFound, errors.Errorf("no constraint or override at line %d", line))
+		return
+	}
+
+	ci, _ := projectConstraintInfo(p, gps.ProjectRoot(mt.name))
+	writeJSON(w, ci)
+}
+
+func (d *daemon) handleChanges(w http.ResponseWriter, r *http.Request) {
+	p, err := d.ctx.LoadProject()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if p.Lock == nil {
+		writeJSONError(w, http.StatusNotFound, errors.Errorf("no %s found in %s", dep.LockName, p.AbsRoot))
+		return
+	}
+	if solve, _ := strconv.ParseBool(r.URL.Query().Get("solve")); !solve {
+		writeJSON(w, dep.DiffLocks(p.Lock, p.ChangedLock))
+		return
+	}
+
+	lock, err := d.solve(p)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, dep.DiffLocks(p.Lock, lock))
+}
+
+// solve returns the lock that dep ensure would write for p.
+func (d *daemon) solve(p *dep.Project) (*dep.Lock, error) {
+	d.lock()
+	defer d.mu.Unlock()
+
+	sm, release, err := d.sourceManager()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	lock, solved, err := execLock(d.ctx, p, sm)
+	if solved || err != nil {
+		d.metrics.observeSolve(time.Since(start), err == nil)
+	}
+	return lock, err
+}
+
+func (d *daemon) handleDirty(w http.ResponseWriter, r *http.Request) {
+	var dirty bool
+	switch {
+	case r.Method != http.MethodGet && r.Method != http.MethodPost:
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+		return
+	case d.tracker == nil:
+		dirty = true
+	case r.Method == http.MethodPost:
+		dirty = d.tracker.Reset()
+	default:
+		dirty = d.tracker.Dirty()
+	}
+	writeJSON(w, struct {
+		Dirty    bool `json:"dirty"`
+		Watching bool `json:"watching"`
+	}{dirty, d.tracker != nil && d.tracker.Err() == nil})
+}
+
+// diagnostic is a hint about a single table in the manifest.
+type diagnostic struct {
+	Line     int    `json:"line"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (d *daemon) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	p, err := d.ctx.LoadProject()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tables, err := readManifestTables(p)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	diags := []diagnostic{}
+	for _, mt := range tables {
+		if mt.name == "" {
+			continue
+		}
+		ci, has := projectConstraintInfo(p, gps.ProjectRoot(mt.name))
+		if !has {
+			continue
+		}
+
+		pp := p.Manifest.Constraints[gps.ProjectRoot(mt.name)]
+		if ci.Override {
+			pp = p.Manifest.Ovr[gps.ProjectRoot(mt.name)]
+		}
+		vl, err := d.listVersions(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(mt.name), Source: pp.Source})
+		if err != nil {
+			diags = append(diags, diagnostic{
+				Line:     mt.line,
+				Name:     mt.name,
+				Severity: "error",
+				Message:  err.Error(),
+			})
+			continue
+		}
+		diags = append(diags, constraintDiagnostics(mt, pp.Constraint, vl)...)
+	}
+	writeJSON(w, diags)
+}
+
+// constraintDiagnostics produces hints for a single constraint, given the
+// project's available versions sorted for upgrade.
+func constraintDiagnostics(mt manifestTable, c gps.Constraint, vl []gps.PairedVersion) []diagnostic {
+	var latest, latestAllowed gps.Version
+	for _, v := range vl {
+		if v.Type() != gps.IsSemver {
+			continue
+		}
+		if latest == nil {
+			latest = v
+		}
+		if latestAllowed == nil && c.Matches(v) {
+			latestAllowed = v
+		}
+	}
+
+	var diags []diagnostic
+	matchesAny := false
+	for _, v := range vl {
+		if c.Matches(v) {
+			matchesAny = true
+			break
+		}
+	}
+	if _, isRev := c.(gps.Revision); !matchesAny && !isRev {
+		diags = append(diags, diagnostic{
+			Line:     mt.line,
+			Name:     mt.name,
+			Severity: "warning",
+			Message:  "no available version satisfies " + c.String(),
+		})
+	}
+	if latest != nil && latestAllowed != nil && latest != latestAllowed {
+		diags = append(diags, diagnostic{
+			Line:     mt.line,
+			Name:     mt.name,
+			Severity: "info",
+			Message:  "newer version available outside of the constraint: " + latest.String(),
+		})
+	}
+	return diags
+}
+
+// manifestTable is a [[constraint]] or [[override]] table in a manifest, or
+// any other table, which has no name.
+type manifestTable struct {
+	line int
+	name string
+}
+
+// readManifestTables returns the tables in the project's manifest, in
+// document order.
+func readManifestTables(p *dep.Project) ([]manifestTable, error) {
+	b, err := ioutil.ReadFile(filepath.Join(p.AbsRoot, dep.ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	return manifestTables(b)
+}
+
+func manifestTables(b []byte) ([]manifestTable, error) {
+	tree, err := toml.LoadBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []manifestTable
+	var walk func(*toml.Tree)
+	walk = func(t *toml.Tree) {
+		for _, key := range t.Keys() {
+			switch tv := t.Get(key).(type) {
+			case *toml.Tree:
+				tables = append(tables, manifestTable{line: tv.Position().Line})
+				walk(tv)
+			case []*toml.Tree:
+				for _, st := range tv {
+					mt := manifestTable{line: st.Position().Line}
+					if t == tree && (key == "constraint" || key == "override") {
+						mt.name, _ = st.Get("name").(string)
+					}
+					tables = append(tables, mt)
+					walk(st)
+				}
+			}
+		}
+	}
+	walk(tree)
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].line < tables[j].line
+	})
+	return tables, nil
+}
+
+// tableAtLine returns the table covering the given line: the last one that
+// starts on or before it.
+func tableAtLine(tables []manifestTable, line int) (manifestTable, bool) {
+	i := sort.Search(len(tables), func(i int) bool {
+		return tables[i].line > line
+	})
+	if i == 0 {
+		return manifestTable{}, false
+	}
+	return tables[i-1], true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,277 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/dep/gps"
+)
+
+// metricBuckets are the upper bounds, in seconds, of the buckets of the
+// daemon's histograms.
+var metricBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// histogram counts observations of durations into metricBuckets.
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(metricBuckets))
+	}
+	s := d.Seconds()
+	for i, le := range metricBuckets {
+		if s <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += s
+	h.count++
+}
+
+// daemonMetrics records what the daemon does, for monitoring with Prometheus.
+type daemonMetrics struct {
+	mu sync.Mutex
+	// requests counts the requests answered, by path and status code.
+	requests map[[2]string]uint64
+	// durations are the durations of requests, by path.
+	durations map[string]*histogram
+	// solves are the durations of solves, by whether they succeeded.
+	solves map[string]*histogram
+	// versionCache counts lookups in the cache of version lists, by whether
+	// they hit.
+	versionCache map[string]uint64
+	// fetchErrors counts failures to list versions, by the host of the
+	// source, as returned by fetchHost.
+	fetchErrors map[string]uint64
+	// lockWaits are the time spent waiting for the daemon's own lock, and
+	// for the lock on the cache, which other dep processes can hold.
+	lockWaits map[string]*histogram
+	// lockFailures counts failures to take the lock on the cache.
+	lockFailures uint64
+}
+
+func newDaemonMetrics() *daemonMetrics {
+	return &daemonMetrics{
+		requests:     make(map[[2]string]uint64),
+		durations:    make(map[string]*histogram),
+		solves:       make(map[string]*histogram),
+		versionCache: make(map[string]uint64),
+		fetchErrors:  make(map[string]uint64),
+		lockWaits:    make(map[string]*histogram),
+	}
+}
+
+func (m *daemonMetrics) observeRequest(path string, code int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[[2]string{path, strconv.Itoa(code)}]++
+	if m.durations[path] == nil {
+		m.durations[path] = &histogram{}
+	}
+	m.durations[path].observe(d)
+}
+
+func (m *daemonMetrics) observeSolve(d time.Duration, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := "failure"
+	if ok {
+		result = "success"
+	}
+	if m.solves[result] == nil {
+		m.solves[result] = &histogram{}
+	}
+	m.solves[result].observe(d)
+}
+
+func (m *daemonMetrics) observeVersionCache(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.versionCache["hit"]++
+	} else {
+		m.versionCache["miss"]++
+	}
+}
+
+func (m *daemonMetrics) observeFetchError(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetchErrors[host]++
+}
+
+func (m *daemonMetrics) observeLockWait(lock string, d time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lockWaits[lock] == nil {
+		m.lockWaits[lock] = &histogram{}
+	}
+	m.lockWaits[lock].observe(d)
+	if failed {
+		m.lockFailures++
+	}
+}
+
+// fetchHosts are the hosts by which fetch errors are counted. Those for other
+// hosts are counted together, so that sources cannot add labels without bound.
+var fetchHosts = map[string]bool{
+	"bitbucket.org":       true,
+	"git.apache.org":      true,
+	"git.launchpad.net":   true,
+	"github.com":          true,
+	"gitlab.com":          true,
+	"go.googlesource.com": true,
+	"hub.jazz.net":        true,
+	"launchpad.net":       true,
+}
+
+// fetchHost returns the host that the source of id is fetched from, as
+// deduced by sm, if it is one of fetchHosts, or else "other".
+func fetchHost(sm gps.SourceManager, id gps.ProjectIdentifier) string {
+	s := id.Source
+	if s == "" {
+		s = string(id.ProjectRoot)
+	}
+	urls, err := sm.SourceURLsForPath(s)
+	if err != nil || len(urls) == 0 {
+		return "other"
+	}
+	if host := urls[0].Hostname(); fetchHosts[host] {
+		return host
+	}
+	return "other"
+}
+
+// write writes the metrics to w in the Prometheus text exposition format.
+func (m *daemonMetrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ew := &errWriter{w: w}
+	ew.header("dep_daemon_requests_total", "counter", "Requests answered by the daemon, by path and status code.")
+	keys := make([][2]string, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		ew.sample("dep_daemon_requests_total", labels("path", k[0], "code", k[1]), float64(m.requests[k]))
+	}
+
+	ew.header("dep_daemon_request_duration_seconds", "histogram", "Time taken to answer requests, by path.")
+	ew.histograms("dep_daemon_request_duration_seconds", "path", m.durations)
+
+	ew.header("dep_daemon_solve_duration_seconds", "histogram", "Time taken to solve, by result.")
+	ew.histograms("dep_daemon_solve_duration_seconds", "result", m.solves)
+
+	ew.header("dep_daemon_version_cache_requests_total", "counter", "Lookups in the cache of version lists, by result.")
+	for _, result := range []string{"hit", "miss"} {
+		ew.sample("dep_daemon_version_cache_requests_total", labels("result", result), float64(m.versionCache[result]))
+	}
+
+	ew.header("dep_daemon_fetch_errors_total", "counter", "Failures to list the versions of sources, by the host of the source.")
+	for _, host := range sortedKeys(m.fetchErrors) {
+		ew.sample("dep_daemon_fetch_errors_total", labels("host", host), float64(m.fetchErrors[host]))
+	}
+
+	ew.header("dep_daemon_lock_wait_seconds", "histogram", "Time spent waiting for the daemon's lock and for the lock on the cache.")
+	ew.histograms("dep_daemon_lock_wait_seconds", "lock", m.lockWaits)
+
+	ew.header("dep_daemon_lock_failures_total", "counter", "Failures to take the lock on the cache.")
+	ew.sample("dep_daemon_lock_failures_total", "", float64(m.lockFailures))
+	return ew.err
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labels formats pairs of label names and values.
+func labels(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], v))
+	}
+	return strings.Join(parts, ",")
+}
+
+// errWriter writes metrics, keeping the first error.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
+
+func (ew *errWriter) header(name, typ, help string) {
+	ew.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (ew *errWriter) sample(name, labels string, v float64) {
+	if labels != "" {
+		name += "{" + labels + "}"
+	}
+	ew.printf("%s %s\n", name, strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+func (ew *errWriter) histograms(name, label string, hs map[string]*histogram) {
+	keys := make([]string, 0, len(hs))
+	for k := range hs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h := hs[k]
+		var cum uint64
+		for i, le := range metricBuckets {
+			cum += h.counts[i]
+			ew.sample(name+"_bucket", labels(label, k, "le", strconv.FormatFloat(le, 'g', -1, 64)), float64(cum))
+		}
+		ew.sample(name+"_bucket", labels(label, k, "le", "+Inf"), float64(h.count))
+		ew.sample(name+"_sum", labels(label, k), h.sum)
+		ew.sample(name+"_count", labels(label, k), float64(h.count))
+	}
+}
+
+// statusRecorder records the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (d *daemon) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	d.metrics.write(w)
+}
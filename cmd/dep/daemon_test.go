@@ -0,0 +1,306 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/watch"
+)
+
+const daemonTestManifest = `required = ["github.com/foo/tool"]
+
+[[constraint]]
+  name = "github.com/foo/bar"
+  version = "1.0.0"
+
+[prune]
+  go-tests = true
+
+  [[prune.project]]
+    name = "github.com/foo/bar"
+    non-go = true
+
+[[override]]
+  name = "github.com/foo/baz"
+  branch = "master"
+`
+
+func TestManifestTables(t *testing.T) {
+	tables, err := manifestTables([]byte(daemonTestManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[int]string{
+		1:  "",
+		3:  "github.com/foo/bar",
+		5:  "github.com/foo/bar",
+		7:  "",
+		12: "",
+		15: "github.com/foo/baz",
+		17: "github.com/foo/baz",
+	}
+	for line, want := range cases {
+		mt, has := tableAtLine(tables, line)
+		if line == 1 {
+			if has {
+				t.Errorf("expected no table at line 1, got %v", mt)
+			}
+			continue
+		}
+		if !has || mt.name != want {
+			t.Errorf("table at line %d: got %q (found: %v), want %q", line, mt.name, has, want)
+		}
+	}
+}
+
+func TestConstraintDiagnostics(t *testing.T) {
+	vl := []gps.PairedVersion{
+		gps.NewVersion("v2.0.0").Pair("rev2"),
+		gps.NewVersion("v1.1.0").Pair("rev1"),
+		gps.NewBranch("master").Pair("rev3"),
+	}
+	gps.SortPairedForUpgrade(vl)
+	mt := manifestTable{line: 3, name: "github.com/foo/bar"}
+
+	c, _ := gps.NewSemverConstraintIC("^1.0.0")
+	got := constraintDiagnostics(mt, c, vl)
+	want := []diagnostic{{
+		Line:     3,
+		Name:     "github.com/foo/bar",
+		Severity: "info",
+		Message:  "newer version available outside of the constraint: v2.0.0",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected diagnostics:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+
+	c, _ = gps.NewSemverConstraintIC("^3.0.0")
+	got = constraintDiagnostics(mt, c, vl)
+	if len(got) != 1 || got[0].Severity != "warning" {
+		t.Errorf("expected a single warning for an unsatisfiable constraint, got %v", got)
+	}
+
+	if got = constraintDiagnostics(mt, gps.Any(), vl); len(got) != 0 {
+		t.Errorf("expected no diagnostics for an open constraint, got %v", got)
+	}
+}
+
+type countingLister struct {
+	gps.SourceManager
+	calls int
+	vl    []gps.PairedVersion
+}
+
+func (l *countingLister) ListVersions(gps.ProjectIdentifier) ([]gps.PairedVersion, error) {
+	l.calls++
+	return l.vl, nil
+}
+
+func TestDaemonVersions(t *testing.T) {
+	discard := log.New(ioutil.Discard, "", 0)
+	ctx := &dep.Ctx{WorkingDir: "/", Out: discard, Err: discard}
+
+	lister := &countingLister{vl: []gps.PairedVersion{
+		gps.NewVersion("v1.0.0").Pair("rev1"),
+		gps.NewVersion("v1.1.0").Pair("rev2"),
+	}}
+	d := newDaemon(ctx, time.Hour)
+	d.newSM = func() (gps.SourceManager, func(), error) {
+		return lister, func() {}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, httptest.NewRequest("GET", "/versions?name=github.com/foo/bar", nil))
+		if rec.Code != 200 {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+		}
+
+		var got struct {
+			Versions []versionInfo
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Versions) != 2 || got.Versions[0].Version != "v1.1.0" || got.Versions[0].Type != "semver" {
+			t.Errorf("unexpected versions: %+v", got.Versions)
+		}
+	}
+
+	if lister.calls != 1 {
+		t.Errorf("expected version list to be cached, but it was retrieved %d times", lister.calls)
+	}
+}
+
+func TestDaemonDirty(t *testing.T) {
+	discard := log.New(ioutil.Discard, "", 0)
+	ctx := &dep.Ctx{WorkingDir: "/", Out: discard, Err: discard}
+
+	dirty := func(d *daemon, method string) (dirty, watching bool) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, httptest.NewRequest(method, "/dirty", nil))
+		if rec.Code != 200 {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+		}
+		var got struct {
+			Dirty, Watching bool
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got.Dirty, got.Watching
+	}
+
+	// Without a tracker, nothing can be ruled out.
+	d := newDaemon(ctx, time.Hour)
+	if dirty, watching := dirty(d, "POST"); !dirty || watching {
+		t.Errorf("expected an unwatched project to be dirty, got dirty=%v watching=%v", dirty, watching)
+	}
+	if dirty, _ := dirty(d, "GET"); !dirty {
+		t.Error("expected an unwatched project to stay dirty")
+	}
+
+	root, err := ioutil.TempDir("", "dep-daemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	tr, err := watch.New(root)
+	if err == watch.ErrUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	d.tracker = tr
+	if dirty, watching := dirty(d, "POST"); !dirty || !watching {
+		t.Errorf("expected a new tracker to be dirty, got dirty=%v watching=%v", dirty, watching)
+	}
+	if dirty, _ := dirty(d, "GET"); dirty {
+		t.Error("expected the project to be clean after POST")
+	}
+}
+
+type failingLister struct {
+	gps.SourceManager
+}
+
+func (failingLister) ListVersions(gps.ProjectIdentifier) ([]gps.PairedVersion, error) {
+	return nil, errors.New("unreachable")
+}
+
+func (failingLister) SourceURLsForPath(ip string) ([]*url.URL, error) {
+	return []*url.URL{{Scheme: "https", Host: strings.SplitN(ip, "/", 2)[0]}}, nil
+}
+
+func TestDaemonMetrics(t *testing.T) {
+	discard := log.New(ioutil.Discard, "", 0)
+	ctx := &dep.Ctx{WorkingDir: "/", Out: discard, Err: discard}
+
+	d := newDaemon(ctx, time.Hour)
+	var lister gps.SourceManager = &countingLister{vl: []gps.PairedVersion{gps.NewVersion("v1.0.0").Pair("rev1")}}
+	d.newSM = func() (gps.SourceManager, func(), error) {
+		return lister, func() {}, nil
+	}
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		return rec
+	}
+	get("/versions?name=github.com/foo/bar")
+	get("/versions?name=github.com/foo/bar")
+	lister = failingLister{}
+	get("/versions?name=git.example.com/foo/baz")
+	get("/versions?name=github.com/foo/baz")
+	get("/versions")
+	get("/nowhere")
+	d.metrics.observeSolve(2*time.Second, true)
+
+	rec := get("/metrics")
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+	}
+	metrics := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE dep_daemon_requests_total counter\n",
+		`dep_daemon_requests_total{path="/versions",code="200"} 2` + "\n",
+		`dep_daemon_requests_total{path="/versions",code="400"} 1` + "\n",
+		`dep_daemon_requests_total{path="/versions",code="502"} 2` + "\n",
+		`dep_daemon_requests_total{path="other",code="404"} 1` + "\n",
+		`dep_daemon_request_duration_seconds_count{path="/versions"} 5` + "\n",
+		`dep_daemon_request_duration_seconds_bucket{path="/versions",le="+Inf"} 5` + "\n",
+		`dep_daemon_solve_duration_seconds_bucket{result="success",le="1"} 0` + "\n",
+		`dep_daemon_solve_duration_seconds_bucket{result="success",le="2.5"} 1` + "\n",
+		`dep_daemon_solve_duration_seconds_count{result="success"} 1` + "\n",
+		`dep_daemon_version_cache_requests_total{result="hit"} 1` + "\n",
+		`dep_daemon_version_cache_requests_total{result="miss"} 3` + "\n",
+		`dep_daemon_fetch_errors_total{host="github.com"} 1` + "\n",
+		`dep_daemon_fetch_errors_total{host="other"} 1` + "\n",
+		`dep_daemon_lock_wait_seconds_count{lock="cache"} 3` + "\n",
+		`dep_daemon_lock_wait_seconds_count{lock="daemon"} 4` + "\n",
+		"dep_daemon_lock_failures_total 0\n",
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("expected the metrics to contain %q, got:\n%s", want, metrics)
+		}
+	}
+}
+
+// urlLister deduces sources from a fixed map of paths to URLs.
+type urlLister struct {
+	gps.SourceManager
+	urls map[string]string
+}
+
+func (l urlLister) SourceURLsForPath(ip string) ([]*url.URL, error) {
+	s, has := l.urls[ip]
+	if !has {
+		return nil, errors.New("cannot deduce source")
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return []*url.URL{u}, nil
+}
+
+func TestFetchHost(t *testing.T) {
+	sm := urlLister{urls: map[string]string{
+		"github.com/foo/bar":                "https://github.com/foo/bar",
+		"gopkg.in/foo.v1":                   "https://github.com/go-foo/foo",
+		"https://git.example.com:8443/bar":  "https://git.example.com:8443/bar",
+		"git@gitlab.com:foo/bar.git":        "ssh://git@gitlab.com/foo/bar.git",
+		"example.com/vanity/does/not/exist": "https://example.com/vanity",
+	}}
+	for id, want := range map[gps.ProjectIdentifier]string{
+		{ProjectRoot: "github.com/foo/bar"}:                                             "github.com",
+		{ProjectRoot: "gopkg.in/foo.v1"}:                                                "github.com",
+		{ProjectRoot: "github.com/foo/bar", Source: "https://git.example.com:8443/bar"}: "other",
+		{ProjectRoot: "github.com/foo/bar", Source: "git@gitlab.com:foo/bar.git"}:       "gitlab.com",
+		{ProjectRoot: "example.com/vanity/does/not/exist"}:                              "other",
+		{ProjectRoot: "example.com/\"bad\"\nlabel"}:                                     "other",
+	} {
+		if got := fetchHost(sm, id); got != want {
+			t.Errorf("%v: expected %q, got %q", id, want, got)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// isDelimitedFormat reports whether format is one that delimitedWriter writes.
+func isDelimitedFormat(format string) bool {
+	return format == "csv" || format == "tsv"
+}
+
+// selectColumns returns the indexes in header of the columns named in list, a
+// comma-separated list of column names that match case-insensitively, in the
+// order they are named. If list is empty, all the columns are selected.
+func selectColumns(header []string, list string) ([]int, error) {
+	if list == "" {
+		cols := make([]int, len(header))
+		for i := range header {
+			cols[i] = i
+		}
+		return cols, nil
+	}
+
+	var cols []int
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		i := 0
+		for ; i < len(header); i++ {
+			if strings.EqualFold(header[i], name) {
+				break
+			}
+		}
+		if i == len(header) {
+			return nil, errors.Errorf("unknown column %q, the columns are %s", name, strings.Join(header, ", "))
+		}
+		cols = append(cols, i)
+	}
+	return cols, nil
+}
+
+// delimitedWriter writes a table as CSV or TSV for spreadsheets, with only
+// some of its columns.
+type delimitedWriter struct {
+	w    *csv.Writer
+	cols []int
+}
+
+// newDelimitedWriter returns a delimitedWriter that writes to w, in format,
+// the columns of the table with header that are named in list, as by
+// selectColumns. The header is written first.
+func newDelimitedWriter(w io.Writer, format string, header []string, list string) (*delimitedWriter, error) {
+	if !isDelimitedFormat(format) {
+		return nil, errors.Errorf("unsupported format %q, must be csv or tsv", format)
+	}
+	cols, err := selectColumns(header, list)
+	if err != nil {
+		return nil, err
+	}
+	dw := &delimitedWriter{w: csv.NewWriter(w), cols: cols}
+	if format == "tsv" {
+		dw.w.Comma = '\t'
+	}
+	return dw, dw.write(header)
+}
+
+// write writes the selected columns of row. Cells that a spreadsheet would
+// take for a formula are prefixed with a quote, so that they are shown as
+// they are.
+func (dw *delimitedWriter) write(row []string) error {
+	record := make([]string, len(dw.cols))
+	for i, c := range dw.cols {
+		cell := row[c]
+		if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+			cell = "'" + cell
+		}
+		record[i] = cell
+	}
+	return dw.w.Write(record)
+}
+
+// flush writes out any buffered rows.
+func (dw *delimitedWriter) flush() error {
+	dw.w.Flush()
+	return dw.w.Error()
+}
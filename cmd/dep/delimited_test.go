@@ -0,0 +1,52 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDelimitedWriter(t *testing.T) {
+	header := []string{"project", "version", "notes"}
+	rows := [][]string{
+		{"github.com/a/a", "v1.0.0", "plain"},
+		{"github.com/b/b", "=HYPERLINK(\"x\")", "with, comma"},
+	}
+	cases := []struct {
+		name, format, columns string
+		want                  string
+	}{
+		{"csv", "csv", "", "project,version,notes\ngithub.com/a/a,v1.0.0,plain\ngithub.com/b/b,\"'=HYPERLINK(\"\"x\"\")\",\"with, comma\"\n"},
+		{"tsv with columns", "tsv", "Notes, project", "notes\tproject\nplain\tgithub.com/a/a\nwith, comma\tgithub.com/b/b\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			dw, err := newDelimitedWriter(&buf, c.format, header, c.columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range rows {
+				if err := dw.write(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := dw.flush(); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != c.want {
+				t.Errorf("expected\n%q\ngot\n%q", c.want, buf.String())
+			}
+		})
+	}
+
+	if _, err := newDelimitedWriter(&bytes.Buffer{}, "csv", header, "project,license"); err == nil {
+		t.Error("expected an error selecting an unknown column")
+	}
+	if _, err := newDelimitedWriter(&bytes.Buffer{}, "xlsx", header, ""); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
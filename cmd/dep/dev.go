@@ -0,0 +1,250 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+)
+
+const devShortHelp = `Tools for working on dep itself`
+const devLongHelp = `
+Dev provides tools for working on dep itself, rather than on a project.
+
+  dep dev regen-fixtures [-n] [<dir>...]
+
+Regen-fixtures rewrites the manifests and locks in the testdata directories
+beneath each <dir>, the current directory by default, as dep would write them
+now: each is read and written again, keeping only the comments at its top, such
+as the one dep puts at the top of Gopkg.lock. Run it after changing how dep
+writes Gopkg.toml or Gopkg.lock, instead of updating every fixture by hand;
+the tests of a package can also update its own golden files when run with
+-update-golden (or -update).
+
+Files named Gopkg.toml and Gopkg.lock are rewritten, as are other .toml files
+that hold a manifest or a lock. Fixtures that dep cannot read, which are often
+invalid on purpose, and those with comments below their top, which would be
+lost, are left alone; -v lists them. Vendor directories are not searched.
+
+With -n, the fixtures that would be rewritten are listed, and none are written.
+`
+
+type devCommand struct {
+	dryRun bool
+}
+
+func (cmd *devCommand) Name() string      { return "dev" }
+func (cmd *devCommand) Args() string      { return "regen-fixtures [-n] [<dir>...]" }
+func (cmd *devCommand) ShortHelp() string { return devShortHelp }
+func (cmd *devCommand) LongHelp() string  { return devLongHelp }
+func (cmd *devCommand) Hidden() bool      { return true }
+
+func (cmd *devCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *devCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) == 0 {
+		return errors.New("dev requires a subcommand: regen-fixtures")
+	}
+	switch args[0] {
+	case "regen-fixtures":
+		return cmd.runRegenFixtures(ctx, args[1:])
+	}
+	return errors.Errorf("unknown dev subcommand %q: must be regen-fixtures", args[0])
+}
+
+func (cmd *devCommand) runRegenFixtures(ctx *dep.Ctx, args []string) error {
+	flags := flag.NewFlagSet("dev regen-fixtures", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.BoolVar(&cmd.dryRun, "n", false, "list the fixtures that would be rewritten, without writing them")
+	if err := flags.Parse(args); err != nil {
+		return errors.Wrap(err, "dev regen-fixtures")
+	}
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{ctx.WorkingDir}
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		found, err := findFixtures(dir)
+		if err != nil {
+			return err
+		}
+		files = append(files, found...)
+	}
+
+	var n int
+	for _, path := range files {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+		regen, err := regenFixture(path, b)
+		if err != nil {
+			if ctx.Verbose {
+				ctx.Err.Printf("Skipped %s: %v\n", path, err)
+			}
+			continue
+		}
+		if bytes.Equal(regen, b) {
+			continue
+		}
+		n++
+		if cmd.dryRun {
+			ctx.Out.Printf("Would rewrite %s\n", path)
+			continue
+		}
+		if err := ioutil.WriteFile(path, regen, 0666); err != nil {
+			return errors.Wrapf(err, "failed to write %s", path)
+		}
+		ctx.Out.Printf("Rewrote %s\n", path)
+	}
+	if cmd.dryRun {
+		ctx.Err.Printf("%d of %d fixtures would be rewritten\n", n, len(files))
+	} else {
+		ctx.Err.Printf("Rewrote %d of %d fixtures\n", n, len(files))
+	}
+	return nil
+}
+
+// findFixtures returns the paths, in lexical order, of the Gopkg.toml,
+// Gopkg.lock and other .toml files in the testdata directories beneath root,
+// skipping vendor directories.
+func findFixtures(root string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if path != root && (fi.Name() == "vendor" || strings.HasPrefix(fi.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !inTestdata(root, path) {
+			return nil
+		}
+		if name := fi.Name(); name == dep.LockName || filepath.Ext(name) == ".toml" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to search %s for fixtures", root)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// inTestdata reports whether path is beneath a testdata directory, relative
+// to root.
+func inTestdata(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/") {
+		if elem == "testdata" {
+			return true
+		}
+	}
+	return false
+}
+
+// regenFixture returns the contents b of the manifest or lock fixture at path
+// as dep writes them now, keeping the comments at the top of b. It returns an
+// error if b cannot be read, or has comments that would be lost.
+func regenFixture(path string, b []byte) ([]byte, error) {
+	header, body := splitFixtureHeader(b)
+	if hasComments(body) {
+		return nil, errors.New("comments below the top would be lost")
+	}
+
+	isLock, err := isLockFixture(path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var regen []byte
+	if isLock {
+		l, err := dep.ReadLock(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		regen, err = l.MarshalTOML()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		m, _, err := dep.ReadManifest(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		regen, err = m.MarshalTOML()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(header, regen...), nil
+}
+
+// splitFixtureHeader splits b into the comment lines at its top, along with
+// the blank line that follows them, if any, and the rest.
+func splitFixtureHeader(b []byte) (header, body []byte) {
+	var n int
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		if !strings.HasPrefix(sc.Text(), "#") {
+			if n > 0 && sc.Text() == "" {
+				n++
+			}
+			break
+		}
+		n += len(sc.Bytes()) + 1
+	}
+	if n > len(b) {
+		n = len(b)
+	}
+	return b[:n:n], b[n:]
+}
+
+// hasComments reports whether any line of b is a comment.
+func hasComments(b []byte) bool {
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		if strings.HasPrefix(strings.TrimSpace(sc.Text()), "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// isLockFixture reports whether the fixture at path, with the contents b,
+// is a lock rather than a manifest: it is named Gopkg.lock, or has the
+// projects or solve-meta of a lock.
+func isLockFixture(path string, b []byte) (bool, error) {
+	if filepath.Base(path) == dep.LockName {
+		return true, nil
+	}
+	if filepath.Base(path) == dep.ManifestName {
+		return false, nil
+	}
+	tree, err := toml.LoadBytes(b)
+	if err != nil {
+		return false, errors.Wrap(err, "not valid TOML")
+	}
+	return tree.Has("projects") || tree.Has("solve-meta"), nil
+}
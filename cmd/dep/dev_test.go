@@ -0,0 +1,114 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestRegenFixture(t *testing.T) {
+	cases := []struct {
+		name    string
+		path    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "manifest",
+			path: "Gopkg.toml",
+			in:   "[[constraint]]\nversion = \"1.0.0\"\nname = \"github.com/foo/bar\"\n",
+			want: "\n[[constraint]]\n  name = \"github.com/foo/bar\"\n  version = \"1.0.0\"\n",
+		},
+		{
+			name: "manifest with a header",
+			path: "Gopkg.toml",
+			in:   "# Header\n#\n# More header\n\n[[constraint]]\n  name = \"github.com/foo/bar\"\n  version = \"1.0.0\"\n",
+			want: "# Header\n#\n# More header\n\n\n[[constraint]]\n  name = \"github.com/foo/bar\"\n  version = \"1.0.0\"\n",
+		},
+		{
+			name:    "manifest with comments below the top",
+			path:    "Gopkg.toml",
+			in:      "[[constraint]]\n  # The only one.\n  name = \"github.com/foo/bar\"\n  version = \"1.0.0\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid manifest",
+			path:    "Gopkg.toml",
+			in:      "ignored = \"github.com/foo/bar\"\n",
+			wantErr: true,
+		},
+		{
+			name: "golden lock",
+			path: "golden.toml",
+			in:   "[[projects]]\n  revision = \"abc\"\n  packages = [\".\"]\n  name = \"github.com/foo/bar\"\n  digest = \"1:cafebabe\"\n",
+			want: "\n[[projects]]\n  digest = \"1:cafebabe\"\n  name = \"github.com/foo/bar\"\n  packages = [\".\"]\n  pruneopts = \"\"\n  revision = \"abc\"\n\n[solve-meta]\n  analyzer-name = \"\"\n  analyzer-version = 0\n  input-imports = []\n  solver-name = \"\"\n  solver-version = 0\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := regenFixture(c.path, []byte(c.in))
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != c.want {
+				t.Errorf("expected:\n%s\ngot:\n%s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestFindFixtures(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	for _, name := range []string{
+		"Gopkg.toml",
+		"Gopkg.lock",
+		"testdata/Gopkg.toml",
+		"testdata/case/initial/Gopkg.lock",
+		"testdata/lock/golden.toml",
+		"testdata/lock/golden.txt",
+		"testdata/case/initial/vendor/github.com/foo/bar/Gopkg.toml",
+		"pkg/testdata/Gopkg.toml",
+	} {
+		h.TempFile(name, "")
+	}
+
+	root := h.Path(".")
+	files, err := findFixtures(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, f := range files {
+		rel, err := filepath.Rel(root, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{
+		"pkg/testdata/Gopkg.toml",
+		"testdata/Gopkg.toml",
+		"testdata/case/initial/Gopkg.lock",
+		"testdata/lock/golden.toml",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected fixtures %v, got %v", want, got)
+	}
+}
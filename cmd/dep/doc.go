@@ -38,7 +38,8 @@
 // When configuration for another dependency management tool is detected, it is
 // imported into the initial manifest and lock. Use the -skip-tools flag to
 // disable this behavior. The following external tools are supported:
-// glide, godep, vndr, govend, gb, gvt, glock.
+// glide, godep, vndr, govend, gb, gvt, glock, and the vendor/modules.txt file
+// written by go modules.
 //
 // Any dependencies that are not constrained by external configuration use the
 // GOPATH analysis below.
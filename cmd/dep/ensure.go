@@ -5,16 +5,21 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/build"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/dep"
 	"github.com/golang/dep/gps"
@@ -44,6 +49,45 @@ may be passed to bypass these checks; -vendor-only will allow an out-of-date
 Gopkg.lock to populate vendor/, and -no-vendor will update Gopkg.lock (if
 needed), but never touch vendor/.
 
+If there is no Gopkg.lock, but vendor/ was populated by go modules, the
+versions recorded in vendor/modules.txt are preferred when solving, so that
+switching a project from go modules to dep keeps its dependencies where they
+are wherever Gopkg.toml allows.
+
+If Gopkg.toml has a [budget] table, ensure checks the number of projects in
+Gopkg.lock, the depth of the dependency graph and the size of vendor/ against
+it, warning or failing when a limit is exceeded.
+
+Dependencies constrained to a branch may set refresh in their [[constraint]]
+to govern when their locked revisions advance: "update", the default, only
+with -update; "weekly", also when ensure finds the locked revision more than
+a week old; and "never", only when the dependency is named to -update.
+
+Only one ensure, prune or check -fix writes to a project at a time, holding
+.dep.lock in its root while it does; any other fails, but ensure with -wait
+waits for the first to finish instead. If that was an ensure asked to make
+the same changes, and Gopkg.toml and Gopkg.lock have not changed since it
+finished, the one that waited has nothing left to do and exits at once.
+
+If Gopkg.toml has a [freeze] table, or $DEPFREEZE is set, and the lock is
+frozen, as during a release freeze, ensure refuses to -update, or to make any
+other change to Gopkg.lock, unless -override-freeze is passed.
+
+If Gopkg.toml sets justify-major-updates, ensure refuses to update any project
+to a new major version unless -reason gives the reason for it. The reason is
+recorded in Gopkg.lock against each project updated to a new major version.
+
+Ensure warns about imports of packages whose paths are known to have moved,
+such as those on code.google.com, or github.com/Sirupsen/logrus, which is now
+github.com/sirupsen/logrus; -migrate rewrites them to their new paths before
+solving. Further moves may be listed with [[migration]] in Gopkg.toml.
+
+With -preflight, ensure first resolves and connects to the host of every
+project in Gopkg.toml and Gopkg.lock, or to the proxy set for it, and fails at
+once if any cannot be reached, with a report of the unreachable hosts, the
+number of projects fetched from each, and the likely causes. Hosts are
+otherwise only found to be unreachable part way through solving.
+
 The effect of passing project spec arguments varies slightly depending on the
 combination of flags that are passed.
 
@@ -106,6 +150,37 @@ dep ensure -update
     changes. (NOTE: Not recommended. Updating one/some dependencies at a time is
     preferred.)
 
+dep ensure -update -except k8s.io/...,github.com/aws/aws-sdk-go
+
+    As above, but leave the listed dependencies at the versions recorded in
+    Gopkg.lock. Each entry is a project root, or a pattern in which "..."
+    matches any string, so that k8s.io/... holds back every project under
+    k8s.io. This is useful for dependencies whose updates need to be
+    coordinated by hand.
+
+dep ensure -update -group kubernetes
+
+    Update together, in a single solve, the dependencies in the group named
+    "kubernetes" in Gopkg.toml, leaving all others at the versions recorded
+    in Gopkg.lock. Groups are declared as [[group]] tables, listing project
+    roots or patterns using "...":
+
+      [[group]]
+        name = "kubernetes"
+        projects = ["k8s.io/...", "github.com/googleapis/gnostic"]
+
+    Several groups may be named, separated by commas, and groups may be
+    combined with named dependencies.
+
+dep ensure -update -smoke-test "go build ./... && go test ./..."
+
+    Update all dependencies, then run the given command in the project root.
+    If it fails, Gopkg.lock and vendor/ are rolled back to how they were
+    before the update. A smoke test to run after every update can instead be
+    set in Gopkg.toml:
+
+      smoke-test = "go build ./... && go test ./..."
+
 dep ensure -update -no-vendor
 
     As above, but only modify Gopkg.lock; leave vendor/ unchanged.
@@ -125,7 +200,7 @@ var (
 
 func (cmd *ensureCommand) Name() string { return "ensure" }
 func (cmd *ensureCommand) Args() string {
-	return "[-update | -add] [-no-vendor | -vendor-only] [-dry-run] [-v] [<spec>...]"
+	return "[-update [-except <project>,...] [-group <name>,...] [-smoke-test <command>] | -add] [-no-vendor | -vendor-only] [-dry-run [-json]] [-wait] [-memory-budget <size>] [-max-bandwidth <size>] [-vendor-jobs <n>] [-v] [<spec>...]"
 }
 func (cmd *ensureCommand) ShortHelp() string { return ensureShortHelp }
 func (cmd *ensureCommand) LongHelp() string  { return ensureLongHelp }
@@ -134,19 +209,48 @@ func (cmd *ensureCommand) Hidden() bool      { return false }
 func (cmd *ensureCommand) Register(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.examples, "examples", false, "print detailed usage examples")
 	fs.BoolVar(&cmd.update, "update", false, "update the named dependencies (or all, if none are named) in Gopkg.lock to the latest allowed by Gopkg.toml")
+	fs.StringVar(&cmd.except, "except", "", "with -update and no arguments, a comma-separated list of projects (or patterns using ...) to leave at their locked versions")
+	fs.StringVar(&cmd.group, "group", "", "with -update, a comma-separated list of update groups from Gopkg.toml to update together")
+	fs.StringVar(&cmd.smokeTestCmd, "smoke-test", "", "with -update, a command to run after writing; Gopkg.lock and vendor/ are rolled back if it fails")
 	fs.BoolVar(&cmd.add, "add", false, "add new dependencies, or populate Gopkg.toml with constraints for existing dependencies")
 	fs.BoolVar(&cmd.vendorOnly, "vendor-only", false, "populate vendor/ from Gopkg.lock without updating it first")
 	fs.BoolVar(&cmd.noVendor, "no-vendor", false, "update Gopkg.lock (if needed), but do not update vendor/")
 	fs.BoolVar(&cmd.dryRun, "dry-run", false, "only report the changes that would be made")
+	fs.BoolVar(&cmd.json, "json", false, "with -dry-run, output the changes to Gopkg.lock in JSON format")
+	fs.BoolVar(&cmd.wait, "wait", false, "if another dep command is writing to the project, wait for it to finish rather than failing")
+	fs.Var(&cmd.memoryBudget, "memory-budget", "abort solving if heap usage exceeds this size (e.g. 512MB, 2GB)")
+	fs.Var(&cmd.maxBandwidth, "max-bandwidth", "limit transfers from upstream sources to this many bytes per second (e.g. 512KB, 2MB); overrides $DEPMAXBANDWIDTH")
+	fs.IntVar(&cmd.vendorJobs, "vendor-jobs", 0, "write up to this many projects to vendor/ at the same time; overrides $DEPVENDORJOBS")
+	fs.BoolVar(&cmd.migrate, "migrate", false, "rewrite imports of packages whose paths are known to have moved to their new paths")
+	fs.BoolVar(&cmd.preflight, "preflight", false, "check that the hosts of all projects in Gopkg.toml and Gopkg.lock can be reached before solving")
+	fs.BoolVar(&cmd.overrideFreeze, "override-freeze", false, "update and change Gopkg.lock even while it is frozen")
+	fs.StringVar(&cmd.reason, "reason", "", "the reason for updating projects to a new major version, recorded in Gopkg.lock")
 }
 
 type ensureCommand struct {
-	examples   bool
-	update     bool
-	add        bool
-	noVendor   bool
-	vendorOnly bool
-	dryRun     bool
+	examples     bool
+	update       bool
+	except       string
+	group        string
+	smokeTestCmd string
+	add          bool
+	noVendor     bool
+	vendorOnly   bool
+	dryRun       bool
+	json         bool
+	wait         bool
+	memoryBudget byteSize
+	maxBandwidth byteSize
+	vendorJobs   int
+	preflight    bool
+	migrate      bool
+	// overrideFreeze allows changes to a frozen lock.
+	overrideFreeze bool
+	// reason is recorded for the projects updated to a new major version.
+	reason string
+
+	// Versions from the yanked versions feed, if one is configured.
+	yanked gps.YankedVersions
 }
 
 func (cmd *ensureCommand) Run(ctx *dep.Ctx, args []string) error {
@@ -159,11 +263,67 @@ func (cmd *ensureCommand) Run(ctx *dep.Ctx, args []string) error {
 		return err
 	}
 
+	// A dry run writes nothing, so it need not wait for anything that does.
+	request := cmd.request(args)
+	var lock *dep.ProjectLock
+	if !cmd.dryRun {
+		var err error
+		lock, err = ctx.LockProject(cmd.wait)
+		if e, ok := err.(dep.ProjectLockedError); ok {
+			return errors.Errorf("%v; pass -wait to run once it has finished", e)
+		} else if err != nil {
+			return err
+		}
+		defer lock.Release()
+		if lock.Coalesced(request) {
+			ctx.Err.Println("The dep ensure waited for made the same changes; there is nothing left to do.")
+			return nil
+		}
+	}
+
 	p, err := ctx.LoadProject()
 	if err != nil {
 		return err
 	}
+	// A dry run only reports the imports that -migrate would rewrite.
+	if rewrote, err := migrateImports(ctx, p.AbsRoot, p.Manifest, cmd.migrate && !cmd.dryRun, "dep ensure -migrate"); err != nil {
+		return err
+	} else if rewrote {
+		if p, err = ctx.LoadProject(); err != nil {
+			return err
+		}
+	}
+	if err := cmd.runProject(ctx, args, p); err != nil {
+		return err
+	}
+	if lock != nil {
+		if err := lock.Done(request); err != nil {
+			ctx.Err.Printf("Warning: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// request identifies what dep ensure was asked to do with args, so that a
+// dep ensure queued behind another can tell whether it was asked the same.
+func (cmd *ensureCommand) request(args []string) string {
+	return fmt.Sprintf("update=%t add=%t except=%q group=%q smoke-test=%q no-vendor=%t vendor-only=%t migrate=%t args=%q",
+		cmd.update, cmd.add, cmd.except, cmd.group, cmd.smokeTestCmd, cmd.noVendor, cmd.vendorOnly, cmd.migrate, args)
+}
 
+// runProject runs dep ensure on p.
+func (cmd *ensureCommand) runProject(ctx *dep.Ctx, args []string, p *dep.Project) error {
+	if cmd.maxBandwidth != 0 {
+		ctx.MaxBandwidth = uint64(cmd.maxBandwidth)
+	}
+	if cmd.vendorJobs != 0 {
+		gps.SetConcurrentWriters(cmd.vendorJobs)
+	}
+	if cmd.preflight {
+		if err := preflight(ctx, p, probeAddr); err != nil {
+			return err
+		}
+	}
 	sm, err := ctx.SourceManager()
 	if err != nil {
 		return err
@@ -179,9 +339,29 @@ func (cmd *ensureCommand) Run(ctx *dep.Ctx, args []string) error {
 	if ctx.Verbose {
 		params.TraceLogger = ctx.Err
 	}
+	params.MemoryBudget = uint64(cmd.memoryBudget)
+	applyImportPolicy(ctx, &params)
+	if cmd.yanked, err = applyYanked(ctx, &params); err != nil {
+		return err
+	}
+	saveHints, err := applyHints(ctx, &params)
+	if err != nil {
+		return err
+	}
+	defer saveHints()
 
 	if cmd.vendorOnly {
-		return cmd.runVendorOnly(ctx, args, p, sm, params)
+		if err := cmd.runVendorOnly(ctx, args, p, sm, params); err != nil {
+			return err
+		}
+		registerLock(ctx, sm, p)
+		return nil
+	}
+
+	if p.Lock == nil {
+		if l := vendoredModulesLock(ctx, p, sm); l != nil {
+			params.Lock = l
+		}
 	}
 
 	if fatal, err := checkErrors(params.RootPackageTree.Packages, p.Manifest.IgnoredPackages()); err != nil {
@@ -205,17 +385,35 @@ func (cmd *ensureCommand) Run(ctx *dep.Ctx, args []string) error {
 		ctx.Err.Printf("dependencies, or convert each [[constraint]] to an [[override]] to enforce rules\n")
 		ctx.Err.Printf("on these projects, if they happen to be transitive dependencies.\n\n")
 	}
+	for _, v := range p.KindPolicyViolations() {
+		ctx.Err.Printf("Warning: %s\n", v)
+	}
 
 	// Kick off vendor verification in the background. All of the remaining
 	// paths from here will need it, whether or not they end up solving.
 	go p.VerifyVendor()
 
-	if cmd.add {
-		return cmd.runAdd(ctx, args, p, sm, params)
-	} else if cmd.update {
-		return cmd.runUpdate(ctx, args, p, sm, params)
+	switch {
+	case cmd.add:
+		err = cmd.runAdd(ctx, args, p, sm, params)
+	case cmd.update:
+		err = cmd.runUpdate(ctx, args, p, sm, params)
+	default:
+		err = cmd.runDefault(ctx, args, p, sm, params)
+	}
+	if err != nil {
+		return err
+	}
+	registerLock(ctx, sm, p)
+	return nil
+}
+
+// warnYanked warns about any projects in l locked to yanked versions, when the
+// solver was only asked to warn about them rather than refuse them.
+func (cmd *ensureCommand) warnYanked(ctx *dep.Ctx, l gps.Lock) {
+	if ctx.YankedWarnOnly {
+		warnYanked(ctx.Err, cmd.yanked, l)
 	}
-	return cmd.runDefault(ctx, args, p, sm, params)
 }
 
 func (cmd *ensureCommand) validateFlags() error {
@@ -223,6 +421,22 @@ func (cmd *ensureCommand) validateFlags() error {
 		return errors.New("cannot pass both -add and -update")
 	}
 
+	if cmd.except != "" && !cmd.update {
+		return errors.New("-except can only be passed with -update")
+	}
+	if cmd.group != "" && !cmd.update {
+		return errors.New("-group can only be passed with -update")
+	}
+	if cmd.smokeTestCmd != "" && !cmd.update {
+		return errors.New("-smoke-test can only be passed with -update")
+	}
+	if cmd.json && !cmd.dryRun {
+		return errors.New("-json can only be passed with -dry-run")
+	}
+	if cmd.vendorJobs < 0 {
+		return errors.New("-vendor-jobs must be a positive number of projects")
+	}
+
 	if cmd.vendorOnly {
 		if cmd.update {
 			return errors.New("-vendor-only makes -update a no-op; cannot pass them together")
@@ -238,6 +452,67 @@ func (cmd *ensureCommand) validateFlags() error {
 	return nil
 }
 
+// printDryRun reports what dw would write, as JSON with -json.
+func (cmd *ensureCommand) printDryRun(ctx *dep.Ctx, dw dep.TreeWriter) error {
+	if !cmd.json {
+		return dw.PrintPreparedActions(ctx.Out, ctx.Verbose)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dw.Changes()); err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// byteSize is a flag.Value for sizes given in bytes, optionally with a
+// KB, MB or GB suffix (powers of 1024).
+type byteSize uint64
+
+func (b *byteSize) String() string {
+	return strconv.FormatUint(uint64(*b), 10)
+}
+
+func (b *byteSize) Set(s string) error {
+	n, err := dep.ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = byteSize(n)
+	return nil
+}
+
+// checkVendorBudget checks the size of vendor against the project's budget,
+// unless vendor was left alone.
+func (cmd *ensureCommand) checkVendorBudget(ctx *dep.Ctx, p *dep.Project) error {
+	if cmd.noVendor {
+		return nil
+	}
+	return checkVendorBudget(ctx, p)
+}
+
+// checkFreeze returns an error if newLock would change the lock of p while it
+// is frozen, unless ensure was passed -override-freeze, or is only a dry run.
+func (cmd *ensureCommand) checkFreeze(ctx *dep.Ctx, p *dep.Project, newLock gps.Lock) error {
+	if cmd.dryRun {
+		return nil
+	}
+	return checkFreeze(ctx, p, newLock, cmd.overrideFreeze)
+}
+
+// checkMajorUpdates returns an error if newLock would update projects in the
+// lock of p to a new major version without the reason that its manifest
+// requires, unless ensure is only a dry run.
+func (cmd *ensureCommand) checkMajorUpdates(p *dep.Project, newLock gps.Lock) error {
+	if cmd.dryRun {
+		return nil
+	}
+	return checkMajorUpdates(p.Manifest, p.Lock, newLock, cmd.reason)
+}
+
 func (cmd *ensureCommand) vendorBehavior() dep.VendorBehavior {
 	if cmd.noVendor {
 		return dep.VendorNever
@@ -258,12 +533,35 @@ func (cmd *ensureCommand) runDefault(ctx *dep.Ctx, args []string, p *dep.Project
 	var solve bool
 	lock := p.ChangedLock
 	if lock != nil {
+		// Projects on branches that refresh weekly may advance once their
+		// locked revisions are old enough, without an explicit -update.
+		due := dep.RefreshDue(p.Manifest, p.Lock, sm, time.Now())
+		params.ToChange = append(params.ToChange, due...)
+
 		lsat := verify.LockSatisfiesInputs(p.Lock, p.Manifest, params.RootPackageTree)
 		if !lsat.Satisfied() {
 			if ctx.Verbose {
 				ctx.Out.Printf("# Gopkg.lock is out of sync with Gopkg.toml and project imports:\n%s\n\n", sprintLockUnsat(lsat))
 			}
 			solve = true
+		} else if warnYanked(ctx.Err, params.Yanked, lock) {
+			// Versions in the lock have since been yanked, so they have to be
+			// replaced.
+			solve = true
+		} else if stale := dep.StaleAliases(p.Manifest, lock); len(stale) > 0 {
+			if ctx.Verbose {
+				for _, pr := range stale {
+					ctx.Err.Printf("Vendoring alias %s of %s\n", pr, p.Manifest.Aliases[pr])
+				}
+			}
+			solve = true
+		} else if len(due) > 0 {
+			if ctx.Verbose {
+				for _, pr := range due {
+					ctx.Err.Printf("Refreshing %s, whose locked revision is more than a week old\n", pr)
+				}
+			}
+			solve = true
 		} else if cmd.noVendor {
 			// The user said not to touch vendor/, so definitely nothing to do.
 			return nil
@@ -284,21 +582,45 @@ func (cmd *ensureCommand) runDefault(ctx *dep.Ctx, args []string, p *dep.Project
 		}
 		lock = dep.LockFromSolution(solution, p.Manifest.PruneOptions)
 	}
+	if solve || p.Manifest.ExcludeTestDeps {
+		if err := lock.RecordTestOnly(p, sm); err != nil {
+			return err
+		}
+	}
+	cmd.warnYanked(ctx, lock)
+	if err := checkQuarantine(p.Manifest, p.Lock, lock); err != nil {
+		return err
+	}
+	if err := cmd.checkFreeze(ctx, p, lock); err != nil {
+		return err
+	}
+	if err := cmd.checkMajorUpdates(p, lock); err != nil {
+		return err
+	}
+	if err := checkLockBudget(ctx, p, sm, lock); err != nil {
+		return err
+	}
 
+	lock.RecordUpdateReason(p.Lock, cmd.reason)
 	dw, err := dep.NewDeltaWriter(p, lock, cmd.vendorBehavior())
 	if err != nil {
 		return err
 	}
 
 	if cmd.dryRun {
-		return dw.PrintPreparedActions(ctx.Out, ctx.Verbose)
+		return cmd.printDryRun(ctx, dw)
 	}
 
 	var logger *log.Logger
 	if ctx.Verbose {
 		logger = ctx.Err
 	}
-	return errors.WithMessage(dw.Write(p.AbsRoot, sm, true, logger), "grouped write of manifest, lock and vendor")
+	wctx, stop := interruptContext()
+	defer stop()
+	if err := dw.Write(wctx, p.AbsRoot, sm, true, logger); err != nil {
+		return errors.WithMessage(err, "grouped write of manifest, lock and vendor")
+	}
+	return cmd.checkVendorBudget(ctx, p)
 }
 
 func (cmd *ensureCommand) runVendorOnly(ctx *dep.Ctx, args []string, p *dep.Project, sm gps.SourceManager, params gps.SolveParameters) error {
@@ -317,36 +639,75 @@ func (cmd *ensureCommand) runVendorOnly(ctx *dep.Ctx, args []string, p *dep.Proj
 	if err != nil {
 		return err
 	}
+	if p.Manifest.VendorLayout == dep.VendorLayoutModules {
+		dw.UseModulesLayout(string(p.ImportRoot))
+	}
+	if p.Manifest.ExcludeTestDeps {
+		dw.ExcludeTestOnly()
+	}
+	dw.KeepVendor(p.Manifest.KeepVendor)
 
 	if cmd.dryRun {
-		return dw.PrintPreparedActions(ctx.Out, ctx.Verbose)
+		return cmd.printDryRun(ctx, dw)
 	}
 
 	var logger *log.Logger
 	if ctx.Verbose {
 		logger = ctx.Err
 	}
-	return errors.WithMessage(dw.Write(p.AbsRoot, sm, true, logger), "grouped write of manifest, lock and vendor")
+	wctx, stop := interruptContext()
+	defer stop()
+	return errors.WithMessage(dw.Write(wctx, p.AbsRoot, sm, true, logger), "grouped write of manifest, lock and vendor")
 }
 
 func (cmd *ensureCommand) runUpdate(ctx *dep.Ctx, args []string, p *dep.Project, sm gps.SourceManager, params gps.SolveParameters) error {
 	if p.Lock == nil {
 		return errors.Errorf("-update works by updating the versions recorded in %s, but %s does not exist", dep.LockName, dep.LockName)
 	}
+	if !cmd.dryRun {
+		if err := checkFreezeUpdate(ctx, p, "dep ensure -update", cmd.overrideFreeze); err != nil {
+			return err
+		}
+	}
 
 	if err := ctx.ValidateParams(sm, params); err != nil {
 		return err
 	}
 
+	grouped, err := groupUpdates(p.Manifest, p.Lock, splitPrefixList(cmd.group))
+	if err != nil {
+		return err
+	}
+
 	// When -update is specified without args, allow every dependency to change
-	// versions, regardless of the lock file.
-	if len(args) == 0 {
-		params.ChangeAll = true
+	// versions, regardless of the lock file, save for those excluded by
+	// -except and those on branches that are never refreshed.
+	if len(args) == 0 && len(grouped) == 0 {
+		held := dep.RefreshHeld(p.Manifest, p.Lock)
+		if cmd.except == "" && len(held) == 0 {
+			params.ChangeAll = true
+		} else {
+			change, unmatched := exceptUpdates(p.Lock, splitPrefixList(cmd.except))
+			for _, pattern := range unmatched {
+				ctx.Err.Printf("Warning: -except %s matches no project in %s\n", pattern, dep.LockName)
+			}
+			change = withoutRoots(change, held)
+			if ctx.Verbose {
+				for _, pr := range held {
+					ctx.Err.Printf("Holding back %s, whose constraint sets refresh = %q\n", pr, dep.RefreshNever)
+				}
+				ctx.Err.Printf("Holding back %d of %d projects in %s\n", len(p.Lock.Projects())-len(change), len(p.Lock.Projects()), dep.LockName)
+			}
+			params.ToChange = change
+		}
+	} else if cmd.except != "" {
+		return errors.New("-except applies to updating all dependencies; cannot pass it with named dependencies or groups")
 	}
 
 	if err := validateUpdateArgs(ctx, args, p, sm, &params); err != nil {
 		return err
 	}
+	params.ToChange = append(params.ToChange, grouped...)
 
 	// Re-prepare a solver now that our params are complete.
 	solver, err := gps.Prepare(params, sm)
@@ -360,20 +721,63 @@ func (cmd *ensureCommand) runUpdate(ctx *dep.Ctx, args []string, p *dep.Project,
 		// were available.
 		return handleAllTheFailuresOfTheWorld(err)
 	}
+	cmd.warnYanked(ctx, solution)
+	if err := checkQuarantine(p.Manifest, p.Lock, solution); err != nil {
+		return err
+	}
+	if err := cmd.checkFreeze(ctx, p, solution); err != nil {
+		return err
+	}
+	if err := cmd.checkMajorUpdates(p, solution); err != nil {
+		return err
+	}
+	if err := checkLockBudget(ctx, p, sm, solution); err != nil {
+		return err
+	}
 
-	dw, err := dep.NewDeltaWriter(p, dep.LockFromSolution(solution, p.Manifest.PruneOptions), cmd.vendorBehavior())
+	lock := dep.LockFromSolution(solution, p.Manifest.PruneOptions)
+	if err := lock.RecordTestOnly(p, sm); err != nil {
+		return err
+	}
+	lock.RecordUpdateReason(p.Lock, cmd.reason)
+	dw, err := dep.NewDeltaWriter(p, lock, cmd.vendorBehavior())
 	if err != nil {
 		return err
 	}
 	if cmd.dryRun {
-		return dw.PrintPreparedActions(ctx.Out, ctx.Verbose)
+		return cmd.printDryRun(ctx, dw)
 	}
 
 	var logger *log.Logger
 	if ctx.Verbose {
 		logger = ctx.Err
 	}
-	return errors.Wrap(dw.Write(p.AbsRoot, sm, false, logger), "grouped write of manifest, lock and vendor")
+
+	smoke := cmd.smokeTest(p.Manifest)
+	var snap *dep.Snapshot
+	if smoke != "" {
+		if snap, err = dep.NewSnapshot(p.AbsRoot, !cmd.noVendor); err != nil {
+			return err
+		}
+		defer snap.Discard()
+	}
+
+	wctx, stop := interruptContext()
+	defer stop()
+	if err := dw.Write(wctx, p.AbsRoot, sm, false, logger); err != nil {
+		return errors.Wrap(err, "grouped write of manifest, lock and vendor")
+	}
+
+	if smoke != "" {
+		ctx.Err.Printf("Running smoke test: %s\n", smoke)
+		if err := runShellCommand(ctx, p.AbsRoot, smoke); err != nil {
+			if rerr := snap.Restore(); rerr != nil {
+				return errors.Wrapf(rerr, "smoke test failed (%v), and rolling back the update failed", err)
+			}
+			return errors.Wrapf(err, "smoke test failed; %s and vendor were rolled back", dep.LockName)
+		}
+	}
+	return cmd.checkVendorBudget(ctx, p)
 }
 
 func (cmd *ensureCommand) runAdd(ctx *dep.Ctx, args []string, p *dep.Project, sm gps.SourceManager, params gps.SolveParameters) error {
@@ -589,6 +993,19 @@ func (cmd *ensureCommand) runAdd(ctx *dep.Ctx, args []string, p *dep.Project, sm
 		// TODO(sdboyer) detect if the failure was specifically about some of the -add arguments
 		return handleAllTheFailuresOfTheWorld(err)
 	}
+	cmd.warnYanked(ctx, solution)
+	if err := checkQuarantine(p.Manifest, p.Lock, solution); err != nil {
+		return err
+	}
+	if err := cmd.checkFreeze(ctx, p, solution); err != nil {
+		return err
+	}
+	if err := cmd.checkMajorUpdates(p, solution); err != nil {
+		return err
+	}
+	if err := checkLockBudget(ctx, p, sm, solution); err != nil {
+		return err
+	}
 
 	// Prep post-actions and feedback from adds.
 	var reqlist []string
@@ -630,20 +1047,27 @@ func (cmd *ensureCommand) runAdd(ctx *dep.Ctx, args []string, p *dep.Project, sm
 	}
 	sort.Strings(reqlist)
 
-	dw, err := dep.NewDeltaWriter(p, dep.LockFromSolution(solution, p.Manifest.PruneOptions), cmd.vendorBehavior())
+	lock := dep.LockFromSolution(solution, p.Manifest.PruneOptions)
+	if err := lock.RecordTestOnly(p, sm); err != nil {
+		return err
+	}
+	lock.RecordUpdateReason(p.Lock, cmd.reason)
+	dw, err := dep.NewDeltaWriter(p, lock, cmd.vendorBehavior())
 	if err != nil {
 		return err
 	}
 
 	if cmd.dryRun {
-		return dw.PrintPreparedActions(ctx.Out, ctx.Verbose)
+		return cmd.printDryRun(ctx, dw)
 	}
 
 	var logger *log.Logger
 	if ctx.Verbose {
 		logger = ctx.Err
 	}
-	if err := errors.Wrap(dw.Write(p.AbsRoot, sm, true, logger), "grouped write of manifest, lock and vendor"); err != nil {
+	wctx, stop := interruptContext()
+	defer stop()
+	if err := errors.Wrap(dw.Write(wctx, p.AbsRoot, sm, true, logger), "grouped write of manifest, lock and vendor"); err != nil {
 		return err
 	}
 
@@ -681,7 +1105,10 @@ func (cmd *ensureCommand) runAdd(ctx *dep.Ctx, args []string, p *dep.Project, sm
 		}
 	}
 
-	return errors.Wrapf(f.Close(), "closing %s", dep.ManifestName)
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "closing %s", dep.ManifestName)
+	}
+	return cmd.checkVendorBudget(ctx, p)
 }
 
 func getProjectConstraint(arg string, sm gps.SourceManager) (gps.ProjectConstraint, string, error) {
@@ -779,6 +1206,100 @@ func (e pkgtreeErrs) Error() string {
 	return fmt.Sprintf("found %d errors in the package tree:\n%s", len(e), strings.Join(errs, "\n"))
 }
 
+// exceptUpdates returns the roots of the projects in l that match none of
+// patterns, and so may be updated, along with the patterns that matched no
+// project at all.
+func exceptUpdates(l gps.Lock, patterns []string) ([]gps.ProjectRoot, []string) {
+	matched := make([]bool, len(patterns))
+	var change []gps.ProjectRoot
+	for _, lp := range l.Projects() {
+		pr := lp.Ident().ProjectRoot
+		held := false
+		for i, pattern := range patterns {
+			if matchProjectPattern(pattern, string(pr)) {
+				matched[i], held = true, true
+			}
+		}
+		if !held {
+			change = append(change, pr)
+		}
+	}
+
+	var unmatched []string
+	for i, pattern := range patterns {
+		if !matched[i] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+	return change, unmatched
+}
+
+// withoutRoots returns the roots in roots that are not in exclude.
+func withoutRoots(roots, exclude []gps.ProjectRoot) []gps.ProjectRoot {
+	if len(exclude) == 0 {
+		return roots
+	}
+	excluded := make(map[gps.ProjectRoot]bool, len(exclude))
+	for _, pr := range exclude {
+		excluded[pr] = true
+	}
+	var kept []gps.ProjectRoot
+	for _, pr := range roots {
+		if !excluded[pr] {
+			kept = append(kept, pr)
+		}
+	}
+	return kept
+}
+
+// groupUpdates returns the roots of the projects in l that belong to the
+// named update groups of m.
+func groupUpdates(m *dep.Manifest, l gps.Lock, names []string) ([]gps.ProjectRoot, error) {
+	seen := make(map[gps.ProjectRoot]bool)
+	var roots []gps.ProjectRoot
+	for _, name := range names {
+		g, has := m.UpdateGroup(name)
+		if !has {
+			return nil, errors.Errorf("no group named %q in %s", name, dep.ManifestName)
+		}
+
+		matched := false
+		for _, lp := range l.Projects() {
+			pr := lp.Ident().ProjectRoot
+			for _, pattern := range g.Projects {
+				if matchProjectPattern(pattern, string(pr)) {
+					matched = true
+					if !seen[pr] {
+						seen[pr] = true
+						roots = append(roots, pr)
+					}
+					break
+				}
+			}
+		}
+		if !matched {
+			return nil, errors.Errorf("group %q matches no project in %s", name, dep.LockName)
+		}
+	}
+	return roots, nil
+}
+
+// matchProjectPattern reports whether the project root pr matches pattern,
+// in which "..." matches any string, as in the patterns of the go tool. As
+// there, a trailing "/..." also matches the path before it, so that k8s.io/...
+// matches k8s.io itself.
+func matchProjectPattern(pattern, pr string) bool {
+	if !strings.Contains(pattern, "...") {
+		return pattern == pr
+	}
+	if strings.HasSuffix(pattern, "/...") && pr == strings.TrimSuffix(pattern, "/...") {
+		return true
+	}
+	re := regexp.QuoteMeta(pattern)
+	re = strings.Replace(re, `\.\.\.`, `.*`, -1)
+	return regexp.MustCompile("^" + re + "$").MatchString(pr)
+}
+
 func validateUpdateArgs(ctx *dep.Ctx, args []string, p *dep.Project, sm gps.SourceManager, params *gps.SolveParameters) error {
 	// Channel for receiving all the valid arguments.
 	argsCh := make(chan string, len(args))
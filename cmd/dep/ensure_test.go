@@ -10,6 +10,7 @@ import (
 	"go/build"
 	"io/ioutil"
 	"log"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -45,6 +46,12 @@ func TestInvalidEnsureFlagCombinations(t *testing.T) {
 	}
 	ec.noVendor = false
 
+	ec.vendorOnly, ec.except = false, "k8s.io/..."
+	if err := ec.validateFlags(); err == nil {
+		t.Error("-except without -update should fail validation")
+	}
+	ec.vendorOnly, ec.except = true, ""
+
 	// Also verify that the plain ensure path takes no args. This is a shady
 	// test, as lots of other things COULD return errors, and we don't check
 	// anything other than the error being non-nil. For now, it works well
@@ -254,3 +261,88 @@ func TestValidateUpdateArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestByteSizeFlag(t *testing.T) {
+	cases := map[string]uint64{
+		"1024":   1024,
+		"512MB":  512 << 20,
+		"2gb":    2 << 30,
+		"4K":     4 << 10,
+		" 10 B ": 10,
+	}
+
+	for in, want := range cases {
+		var b byteSize
+		if err := b.Set(in); err != nil {
+			t.Errorf("unexpected error parsing %q: %v", in, err)
+			continue
+		}
+		if uint64(b) != want {
+			t.Errorf("parsing %q: got %d, want %d", in, b, want)
+		}
+	}
+
+	for _, in := range []string{"", "MB", "-1GB", "1.5GB", "ten"} {
+		var b byteSize
+		if err := b.Set(in); err == nil {
+			t.Errorf("expected error parsing %q, got %d", in, b)
+		}
+	}
+}
+
+func TestExceptUpdates(t *testing.T) {
+	l := &dep.Lock{}
+	for _, root := range []string{"github.com/aws/aws-sdk-go", "github.com/pkg/errors", "k8s.io/api", "k8s.io/client-go", "k8s.io"} {
+		l.P = append(l.P, gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(root)}, gps.Revision("abc"), nil))
+	}
+
+	change, unmatched := exceptUpdates(l, []string{"k8s.io/...", "github.com/aws/aws-sdk-go", "github.com/nope/...", "github.com/pkg"})
+	if want := []gps.ProjectRoot{"github.com/pkg/errors"}; !reflect.DeepEqual(change, want) {
+		t.Errorf("unexpected projects to change:\n\t(GOT): %v\n\t(WNT): %v", change, want)
+	}
+	if want := []string{"github.com/nope/...", "github.com/pkg"}; !reflect.DeepEqual(unmatched, want) {
+		t.Errorf("unexpected unmatched patterns:\n\t(GOT): %v\n\t(WNT): %v", unmatched, want)
+	}
+
+	for pattern, pr := range map[string]string{
+		"github.com/.../errors": "github.com/pkg/errors",
+		"...":                   "github.com/pkg/errors",
+	} {
+		if !matchProjectPattern(pattern, pr) {
+			t.Errorf("expected %s to match %s", pattern, pr)
+		}
+	}
+	if matchProjectPattern("k8s.io/...", "k8s.iox/api") {
+		t.Error("expected k8s.io/... not to match k8s.iox/api")
+	}
+}
+
+func TestGroupUpdates(t *testing.T) {
+	l := &dep.Lock{}
+	for _, root := range []string{"github.com/googleapis/gnostic", "github.com/pkg/errors", "k8s.io/api", "k8s.io/client-go", "google.golang.org/grpc"} {
+		l.P = append(l.P, gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(root)}, gps.Revision("abc"), nil))
+	}
+	m := &dep.Manifest{
+		Groups: []dep.UpdateGroup{
+			{Name: "kubernetes", Projects: []string{"k8s.io/...", "github.com/googleapis/gnostic"}},
+			{Name: "grpc", Projects: []string{"google.golang.org/grpc", "k8s.io/api"}},
+			{Name: "gone", Projects: []string{"github.com/nope/..."}},
+		},
+	}
+
+	roots, err := groupUpdates(m, l, []string{"kubernetes", "grpc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gps.ProjectRoot{"github.com/googleapis/gnostic", "k8s.io/api", "k8s.io/client-go", "google.golang.org/grpc"}
+	if !reflect.DeepEqual(roots, want) {
+		t.Errorf("unexpected projects to change:\n\t(GOT): %v\n\t(WNT): %v", roots, want)
+	}
+
+	if _, err := groupUpdates(m, l, []string{"missing"}); err == nil {
+		t.Error("expected an error for an undeclared group")
+	}
+	if _, err := groupUpdates(m, l, []string{"gone"}); err == nil {
+		t.Error("expected an error for a group matching no locked project")
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const envShortHelp = `Print the effective dep configuration`
+const envLongHelp = `
+Print the configuration that dep commands run from the current directory will
+use: the cache location and age, GOPATH, locking, proxies, concurrency, and the
+prune defaults of the current project, if any. Settings are taken from the
+environment ($DEPCACHEDIR, $DEPCACHEAGE, $DEPGLOBALCACHE, $DEPREMOTECACHE,
+$DEPNOLOCK, $DEPPROJECTROOT, $DEPYANKED, $DEPYANKEDWARN, $DEPBUNDLE, $DEPALLOW,
+$DEPDENY, $DEPHINTS, $DEPREGISTER, $DEPTOOLS, $DEPHERMETIC, $DEPPUREGIT,
+$DEPAUDITLOG, $DEPVCSALLOW, $DEPVCSTIMEOUT, $DEPVCSRETRIES, $DEPMAXBANDWIDTH,
+$DEPPROXY, $DEPVENDORJOBS, $DEPVENDORLINK, $DEPFREEZE, $GOPATH and the
+standard proxy variables) and from Gopkg.toml.
+
+Flags:
+
+  -json  Print the configuration as a JSON object
+`
+
+// proxyEnvVars are the environment variables consulted by dep's HTTP and VCS
+// operations to decide whether to use a proxy.
+var proxyEnvVars = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "ALL_PROXY"}
+
+type envCommand struct {
+	json bool
+}
+
+func (cmd *envCommand) Name() string      { return "env" }
+func (cmd *envCommand) Args() string      { return "[-json]" }
+func (cmd *envCommand) ShortHelp() string { return envShortHelp }
+func (cmd *envCommand) LongHelp() string  { return envLongHelp }
+func (cmd *envCommand) Hidden() bool      { return false }
+
+func (cmd *envCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.json, "json", false, "output in JSON format")
+}
+
+func (cmd *envCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.New("env takes no arguments")
+	}
+
+	env := newDepEnv(ctx, os.Getenv)
+
+	var buf bytes.Buffer
+	if cmd.json {
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(env); err != nil {
+			return errors.Wrap(err, "failed to encode configuration")
+		}
+	} else if err := env.write(&buf); err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// depEnv is the effective configuration of a dep invocation.
+type depEnv struct {
+	ProjectRoot    string            `json:"projectRoot,omitempty"`
+	ImportRoot     string            `json:"importRoot,omitempty"`
+	GOPATH         string            `json:"gopath"`
+	GOPATHs        []string          `json:"gopaths,omitempty"`
+	Cachedir       string            `json:"cacheDir"`
+	CacheAge       string            `json:"cacheAge"`
+	GlobalCache    string            `json:"globalCache,omitempty"`
+	RemoteCache    string            `json:"remoteCache,omitempty"`
+	Locking        bool              `json:"locking"`
+	StrictManifest bool              `json:"strictManifest"`
+	Proxies        map[string]string `json:"proxies,omitempty"`
+	YankedFeed     string            `json:"yankedFeed,omitempty"`
+	YankedWarnOnly bool              `json:"yankedWarnOnly,omitempty"`
+	Bundle         string            `json:"bundle,omitempty"`
+	ImportAllow    []string          `json:"importAllow,omitempty"`
+	ImportDeny     []string          `json:"importDeny,omitempty"`
+	HintsFile      string            `json:"hintsFile,omitempty"`
+	RegisterLocks  bool              `json:"registerLocks,omitempty"`
+	Tools          []string          `json:"tools,omitempty"`
+	HermeticTools  bool              `json:"hermeticTools,omitempty"`
+	PureGitHosts   []string          `json:"pureGitHosts,omitempty"`
+	CommandLog     string            `json:"commandLog,omitempty"`
+	AllowedVCS     []string          `json:"allowedVCS,omitempty"`
+	CommandLimits  []string          `json:"commandLimits,omitempty"`
+	MaxBandwidth   uint64            `json:"maxBandwidth,omitempty"`
+	ModuleProxy    string            `json:"moduleProxy,omitempty"`
+	ProxyFirst     bool              `json:"proxyFirst,omitempty"`
+	Freeze         string            `json:"freeze,omitempty"`
+	VendorLinks    string            `json:"vendorLinks"`
+	Concurrency    envConcurrency    `json:"concurrency"`
+	Prune          *envPrune         `json:"prune,omitempty"`
+}
+
+type envConcurrency struct {
+	VendorWriters int `json:"vendorWriters"`
+	InitSyncs     int `json:"initSyncs"`
+}
+
+type envPrune struct {
+	Defaults []string            `json:"defaults"`
+	Projects map[string][]string `json:"projects,omitempty"`
+}
+
+// newDepEnv collects the configuration in use by ctx. getenv is used to look
+// up settings that are not recorded in ctx.
+func newDepEnv(ctx *dep.Ctx, getenv func(string) string) depEnv {
+	// Loading the project first selects the GOPATH containing it, if any.
+	p, perr := ctx.LoadProject()
+
+	gopath := ctx.GOPATH
+	if gopath == "" && len(ctx.GOPATHs) > 0 {
+		gopath = ctx.GOPATHs[0]
+	}
+	env := depEnv{
+		GOPATH:         gopath,
+		Cachedir:       ctx.Cachedir,
+		CacheAge:       "disabled",
+		GlobalCache:    ctx.GlobalCache,
+		RemoteCache:    ctx.RemoteCache,
+		Locking:        !ctx.DisableLocking,
+		StrictManifest: ctx.StrictManifest,
+		YankedFeed:     ctx.YankedFeed,
+		YankedWarnOnly: ctx.YankedWarnOnly,
+		Bundle:         ctx.Bundle,
+		ImportAllow:    ctx.ImportAllow,
+		ImportDeny:     ctx.ImportDeny,
+		HintsFile:      ctx.HintsFile,
+		RegisterLocks:  ctx.RegisterLocks,
+		HermeticTools:  ctx.HermeticTools,
+		PureGitHosts:   ctx.PureGitHosts,
+		CommandLog:     ctx.CommandLog,
+		AllowedVCS:     ctx.AllowedVCS,
+		CommandLimits:  formatCommandLimits(ctx.CommandLimits),
+		MaxBandwidth:   ctx.MaxBandwidth,
+		ModuleProxy:    ctx.ModuleProxy,
+		ProxyFirst:     ctx.ProxyFirst,
+		Freeze:         ctx.Freeze,
+		VendorLinks:    gps.CurrentExportLinks().String(),
+		Concurrency: envConcurrency{
+			VendorWriters: gps.MaxConcurrentWriters(),
+			InitSyncs:     cacheDepsConcurrency,
+		},
+	}
+	if perr == nil {
+		env.ProjectRoot = p.AbsRoot
+		env.ImportRoot = string(p.ImportRoot)
+		env.Prune = newEnvPrune(p.Manifest.PruneOptions)
+	}
+
+	for _, t := range ctx.Tools {
+		env.Tools = append(env.Tools, formatTool(t))
+	}
+	for _, gp := range ctx.GOPATHs {
+		if gp != gopath {
+			env.GOPATHs = append(env.GOPATHs, gp)
+		}
+	}
+	if env.Cachedir == "" {
+		// Mirrors the default applied by Ctx.SourceManager.
+		env.Cachedir = filepath.Join(gopath, "pkg", "dep")
+	}
+	if ctx.CacheAge > 0 {
+		env.CacheAge = ctx.CacheAge.String()
+	}
+
+	for _, name := range proxyEnvVars {
+		v := getenv(name)
+		if v == "" {
+			v = getenv(strings.ToLower(name))
+		}
+		if v != "" {
+			if env.Proxies == nil {
+				env.Proxies = make(map[string]string)
+			}
+			env.Proxies[name] = v
+		}
+	}
+
+	return env
+}
+
+func newEnvPrune(co gps.CascadingPruneOptions) *envPrune {
+	ep := &envPrune{Defaults: pruneOptionNames(co.DefaultOptions)}
+	for pr := range co.PerProjectOptions {
+		if ep.Projects == nil {
+			ep.Projects = make(map[string][]string)
+		}
+		ep.Projects[string(pr)] = pruneOptionNames(co.PruneOptionsFor(pr))
+	}
+	return ep
+}
+
+// pruneOptionNames returns the Gopkg.toml names of the options set in po.
+func pruneOptionNames(po gps.PruneOptions) []string {
+	names := []string{}
+	if po&gps.PruneUnusedPackages != 0 {
+		names = append(names, "unused-packages")
+	}
+	if po&gps.PruneNonGoFiles != 0 {
+		names = append(names, "non-go")
+	}
+	if po&gps.PruneGoTestFiles != 0 {
+		names = append(names, "go-tests")
+	}
+	return names
+}
+
+func (env depEnv) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	row := func(k, v string) {
+		fmt.Fprintf(tw, "%s:\t%s\n", k, v)
+	}
+
+	if env.ProjectRoot != "" {
+		row("Project root", env.ProjectRoot)
+		row("Import root", env.ImportRoot)
+	} else {
+		row("Project root", "(not in a dep project)")
+	}
+	row("GOPATH", env.GOPATH)
+	if len(env.GOPATHs) > 0 {
+		row("Other GOPATHs", strings.Join(env.GOPATHs, string(filepath.ListSeparator)))
+	}
+	row("Cache dir", env.Cachedir)
+	row("Cache age", env.CacheAge)
+	if env.GlobalCache != "" {
+		row("Global cache", env.GlobalCache)
+	}
+	if env.RemoteCache != "" {
+		row("Remote cache", env.RemoteCache)
+	}
+	row("Locking", fmt.Sprint(env.Locking))
+	row("Strict manifest", fmt.Sprint(env.StrictManifest))
+	for _, name := range proxyEnvVars {
+		if v, has := env.Proxies[name]; has {
+			row(name, v)
+		}
+	}
+	if env.YankedFeed != "" {
+		row("Yanked feed", env.YankedFeed)
+		row("Yanked warn only", fmt.Sprint(env.YankedWarnOnly))
+	}
+	if env.Bundle != "" {
+		row("Metadata bundle", env.Bundle)
+	}
+	if len(env.ImportAllow) > 0 {
+		row("Allowed imports", strings.Join(env.ImportAllow, ","))
+	}
+	if len(env.ImportDeny) > 0 {
+		row("Denied imports", strings.Join(env.ImportDeny, ","))
+	}
+	if env.HintsFile != "" {
+		row("Solver hints", env.HintsFile)
+	}
+	if env.RegisterLocks {
+		row("Register locks", "true")
+	}
+	if len(env.Tools) > 0 {
+		row("Pinned tools", strings.Join(env.Tools, ","))
+	}
+	if env.HermeticTools {
+		row("Hermetic tools", "true")
+	}
+	if len(env.PureGitHosts) > 0 {
+		row("Pure Go git hosts", strings.Join(env.PureGitHosts, ","))
+	}
+	if env.CommandLog != "" {
+		row("Command log", env.CommandLog)
+	}
+	if len(env.AllowedVCS) > 0 {
+		row("Allowed commands", strings.Join(env.AllowedVCS, ","))
+	}
+	for _, l := range env.CommandLimits {
+		row("Command limits", l)
+	}
+	if env.MaxBandwidth != 0 {
+		row("Max bandwidth", dep.FormatByteSize(env.MaxBandwidth)+"/s")
+	}
+	if env.ModuleProxy != "" {
+		order := "after sources"
+		if env.ProxyFirst {
+			order = "before sources"
+		}
+		row("Module proxy", env.ModuleProxy+" ("+order+")")
+	}
+	if env.Freeze != "" {
+		row("Freeze", env.Freeze)
+	}
+	row("Vendor links", env.VendorLinks)
+	row("Vendor writers", fmt.Sprint(env.Concurrency.VendorWriters))
+	row("Init syncs", fmt.Sprint(env.Concurrency.InitSyncs))
+
+	if env.Prune != nil {
+		row("Prune defaults", pruneNamesString(env.Prune.Defaults))
+		var roots []string
+		for pr := range env.Prune.Projects {
+			roots = append(roots, pr)
+		}
+		sort.Strings(roots)
+		for _, pr := range roots {
+			row("Prune "+pr, pruneNamesString(env.Prune.Projects[pr]))
+		}
+	}
+
+	return tw.Flush()
+}
+
+func pruneNamesString(names []string) string {
+	if len(names) == 0 {
+		return "(none)"
+	}
+	return strings.Join(names, ", ")
+}
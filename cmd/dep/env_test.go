@@ -0,0 +1,78 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestNewDepEnv(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir("src/example.com/proj")
+	h.TempFile("src/example.com/proj/Gopkg.toml", `
+[prune]
+  go-tests = true
+
+  [[prune.project]]
+    name = "github.com/foo/bar"
+    unused-packages = true
+`)
+
+	discard := log.New(ioutil.Discard, "", 0)
+	ctx := &dep.Ctx{
+		Out:      discard,
+		Err:      discard,
+		CacheAge: time.Hour,
+	}
+	h.Must(ctx.SetPaths(h.Path("src/example.com/proj"), h.Path(".")))
+
+	env := newDepEnv(ctx, func(key string) string {
+		if key == "https_proxy" {
+			return "http://proxy:3128"
+		}
+		return ""
+	})
+
+	if env.ImportRoot != "example.com/proj" {
+		t.Errorf("unexpected import root %q", env.ImportRoot)
+	}
+	if want := filepath.Join(h.Path("."), "pkg", "dep"); env.Cachedir != want {
+		t.Errorf("expected default cache dir %q, got %q", want, env.Cachedir)
+	}
+	if env.CacheAge != "1h0m0s" || !env.Locking {
+		t.Errorf("unexpected cache age or locking: %q, %v", env.CacheAge, env.Locking)
+	}
+	if want := map[string]string{"HTTPS_PROXY": "http://proxy:3128"}; !reflect.DeepEqual(env.Proxies, want) {
+		t.Errorf("unexpected proxies %v", env.Proxies)
+	}
+
+	wantPrune := &envPrune{
+		Defaults: []string{"go-tests"},
+		Projects: map[string][]string{"github.com/foo/bar": {"unused-packages", "go-tests"}},
+	}
+	if !reflect.DeepEqual(env.Prune, wantPrune) {
+		t.Errorf("unexpected prune options:\n\t(GOT): %+v\n\t(WNT): %+v", env.Prune, wantPrune)
+	}
+
+	var buf bytes.Buffer
+	h.Must(env.write(&buf))
+	for _, want := range []string{"Import root:", "HTTPS_PROXY:", "Prune defaults:", "Prune github.com/foo/bar:"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected output to contain %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+	"github.com/pkg/errors"
+)
+
+const execShortHelp = `Run a command with dependencies that are guaranteed to be up to date`
+const execLongHelp = `
+Run <command> with Gopkg.lock and vendor/ in sync with Gopkg.toml and the
+project's imports, without writing to either.
+
+If Gopkg.lock is missing or out of sync, the project is solved again, in
+memory. If vendor/ is then out of sync with the lock, the projects that differ
+are written into a temporary directory, and an overlay is passed to the go
+command through $GOFLAGS (as -overlay, which requires Go 1.16 or later), so
+that go build, go test and the like see them in place of those in vendor/, as
+dep ensure would have written them. The temporary directory is removed when the
+command exits. If everything is already in sync, the command is run as is.
+
+This makes exec suitable for scripts that must never run against stale
+dependencies, but must not modify the project either. Run dep ensure to bring
+Gopkg.lock and vendor/ up to date for good.
+
+Example:
+
+  dep exec -- go test ./...
+`
+
+type execCommand struct{}
+
+func (cmd *execCommand) Name() string      { return "exec" }
+func (cmd *execCommand) Args() string      { return "-- <command>..." }
+func (cmd *execCommand) ShortHelp() string { return execShortHelp }
+func (cmd *execCommand) LongHelp() string  { return execLongHelp }
+func (cmd *execCommand) Hidden() bool      { return false }
+
+func (cmd *execCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *execCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) == 0 {
+		return errors.New("exec requires a command to run")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	lock, solved, err := execLock(ctx, p, sm)
+	if err != nil {
+		return err
+	}
+
+	// A new solution replaces vendor as a whole.
+	var stale map[string]bool
+	if !solved {
+		if stale, err = staleVendor(p); err != nil {
+			return err
+		}
+	}
+
+	env := os.Environ()
+	if solved || len(stale) > 0 {
+		td, err := ioutil.TempDir("", "dep-exec")
+		if err != nil {
+			return errors.Wrap(err, "failed to create temporary directory")
+		}
+		defer os.RemoveAll(td)
+
+		overlay, err := writeVendorOverlay(ctx, p, sm, lock, stale, td)
+		if err != nil {
+			return err
+		}
+		env = append(env, "GOFLAGS="+strings.TrimSpace(os.Getenv("GOFLAGS")+" -overlay="+overlay))
+		ctx.Err.Printf("# Running %s with vendor brought up to date\n", strings.Join(args, " "))
+	}
+
+	// SourceMgr holds a lock on the cache; release it so that the command may
+	// itself run dep.
+	sm.Release()
+
+	c := exec.Command(args[0], args[1:]...)
+	c.Dir = ctx.WorkingDir
+	c.Env = env
+	c.Stdin = os.Stdin
+	c.Stdout = ctx.Out.Writer()
+	c.Stderr = ctx.Err.Writer()
+	if err := c.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return silentfail{}
+		}
+		return errors.Wrapf(err, "could not run %s", args[0])
+	}
+	return nil
+}
+
+// execLock returns the lock that p should have: its lock, as updated on load,
+// if that satisfies the manifest and imports, or else a new solution. solved
+// reports whether it had to solve.
+func execLock(ctx *dep.Ctx, p *dep.Project, sm gps.SourceManager) (lock *dep.Lock, solved bool, err error) {
+	if p.Lock != nil && verify.LockSatisfiesInputs(p.Lock, p.Manifest, p.RootPackageTree).Satisfied() {
+		return p.ChangedLock, false, nil
+	}
+
+	params := p.MakeParams()
+	if ctx.Verbose {
+		params.TraceLogger = ctx.Err
+	}
+	applyImportPolicy(ctx, &params)
+	yanked, err := applyYanked(ctx, &params)
+	if err != nil {
+		return nil, false, err
+	}
+	saveHints, err := applyHints(ctx, &params)
+	if err != nil {
+		return nil, false, err
+	}
+	defer saveHints()
+	if err := ctx.ValidateParams(sm, params); err != nil {
+		return nil, false, err
+	}
+
+	solver, err := gps.Prepare(params, sm)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "prepare solver")
+	}
+	solution, err := solver.Solve(context.TODO())
+	if err != nil {
+		return nil, false, handleAllTheFailuresOfTheWorld(err)
+	}
+	lock = dep.LockFromSolution(solution, p.Manifest.PruneOptions)
+	if err := lock.RecordTestOnly(p, sm); err != nil {
+		return nil, false, err
+	}
+	if ctx.YankedWarnOnly {
+		warnYanked(ctx.Err, yanked, lock)
+	}
+	if err := checkQuarantine(p.Manifest, p.Lock, lock); err != nil {
+		return nil, false, err
+	}
+	return lock, true, nil
+}
+
+// staleVendor returns the roots of the projects in the vendor directory of p
+// that differ from its lock, along with the paths in vendor that the lock does
+// not account for. Projects in the noverify list of the manifest are left
+// alone unless they are missing.
+func staleVendor(p *dep.Project) (map[string]bool, error) {
+	stale := make(map[string]bool)
+	statuses, err := p.VerifyVendor()
+	if err != nil {
+		return nil, errors.Wrap(err, "error while verifying vendor")
+	}
+	noverify := make(map[string]bool)
+	for _, skip := range p.Manifest.NoVerify {
+		noverify[skip] = true
+	}
+	for path, status := range statuses {
+		switch {
+		case status == verify.NoMismatch:
+		case status == verify.NotInLock && noverify[path]:
+		case isDigestStatus(status) && noverify[path]:
+		default:
+			stale[path] = true
+		}
+	}
+	return stale, nil
+}
+
+// writeVendorOverlay writes the projects of lock that are stale, or all of
+// them if stale is nil, into a vendor directory under td, and an overlay that
+// makes the go command see it in place of the stale paths of the vendor
+// directory of p, or all of it. It returns the path of the overlay.
+func writeVendorOverlay(ctx *dep.Ctx, p *dep.Project, sm gps.SourceManager, lock *dep.Lock, stale map[string]bool, td string) (string, error) {
+	newVendor := filepath.Join(td, "vendor")
+	if err := os.MkdirAll(newVendor, 0777); err != nil {
+		return "", errors.Wrap(err, "failed to create temporary vendor")
+	}
+
+	for _, lp := range lock.Projects() {
+		root := lp.Ident().ProjectRoot
+		if stale != nil && !stale[string(root)] {
+			continue
+		}
+		if vp, ok := lp.(verify.VerifiableProject); ok && vp.TestOnly && p.Manifest.ExcludeTestDeps {
+			continue
+		}
+
+		prune := p.Manifest.PruneOptions.PruneOptionsFor(root)
+		if vp, ok := lp.(verify.VerifiableProject); ok {
+			prune = vp.PruneOpts
+		}
+		if ctx.Verbose {
+			ctx.Err.Printf("Writing %s@%s for the overlay\n", root, lp.Version())
+		}
+		to := filepath.Join(newVendor, filepath.FromSlash(string(root)))
+		if err := sm.ExportPrunedProject(context.TODO(), lp, prune, to); err != nil {
+			return "", errors.Wrapf(err, "could not write %s@%s", root, lp.Version())
+		}
+	}
+
+	vendorDir := filepath.Join(p.AbsRoot, "vendor")
+	replace, err := vendorOverlay(vendorDir, newVendor)
+	if err != nil {
+		return "", err
+	}
+	// Files of projects that are up to date are neither in the new vendor
+	// nor to be deleted.
+	for path, to := range replace {
+		if stale == nil || to != "" {
+			continue
+		}
+		rel, err := filepath.Rel(vendorDir, path)
+		if err != nil || !staleAt(stale, filepath.ToSlash(rel)) {
+			delete(replace, path)
+		}
+	}
+
+	overlay := filepath.Join(td, "overlay.json")
+	if err := writeOverlay(overlay, replace); err != nil {
+		return "", err
+	}
+	return overlay, nil
+}
+
+// staleAt reports whether the slash-separated path in vendor rel is, or is
+// beneath, one of stale.
+func staleAt(stale map[string]bool, rel string) bool {
+	for path := range stale {
+		if rel == path || strings.HasPrefix(rel, path+"/") {
+			return true
+		}
+	}
+	return false
+}
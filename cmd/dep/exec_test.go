@@ -0,0 +1,73 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestWriteVendorOverlay(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("project/vendor/github.com/current/lib/lib.go", "package lib")
+	h.TempFile("project/vendor/github.com/unused/lib/lib.go", "package lib")
+	h.TempFile("project/vendor/orphaned.go", "package orphaned")
+	h.TempDir("tmp")
+	vendorDir := h.Path("project/vendor")
+	p := &dep.Project{AbsRoot: h.Path("project"), Manifest: dep.NewManifest()}
+
+	testCases := []struct {
+		name  string
+		stale map[string]bool
+		want  map[string]string
+	}{
+		{
+			name:  "stale paths",
+			stale: map[string]bool{"github.com/unused/lib": true, "orphaned.go": true},
+			want: map[string]string{
+				filepath.Join(vendorDir, "github.com/unused/lib/lib.go"): "",
+				filepath.Join(vendorDir, "orphaned.go"):                  "",
+			},
+		},
+		{
+			name: "whole vendor",
+			want: map[string]string{
+				filepath.Join(vendorDir, "github.com/current/lib/lib.go"): "",
+				filepath.Join(vendorDir, "github.com/unused/lib/lib.go"):  "",
+				filepath.Join(vendorDir, "orphaned.go"):                   "",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			td := filepath.Join(h.Path("tmp"), tc.name)
+			overlay, err := writeVendorOverlay(&dep.Ctx{}, p, nil, &dep.Lock{}, tc.stale, td)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := ioutil.ReadFile(overlay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got struct{ Replace map[string]string }
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Replace, tc.want) {
+				t.Errorf("unexpected overlay:\n\t(GOT): %v\n\t(WNT): %v", got.Replace, tc.want)
+			}
+		})
+	}
+}
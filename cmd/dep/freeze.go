@@ -0,0 +1,47 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// activeFreeze returns why the lock of p is frozen at now, and whether it is:
+// because $DEPFREEZE is set, or because of the [freeze] table of its manifest.
+func activeFreeze(ctx *dep.Ctx, p *dep.Project, now time.Time) (string, bool) {
+	if ctx.Freeze != "" {
+		return ctx.Freeze + " ($DEPFREEZE)", true
+	}
+	return p.Manifest.ActiveFreeze(p.AbsRoot, now)
+}
+
+// checkFreezeUpdate returns an error if the lock of p is frozen, as what, an
+// operation that updates dependencies, is then refused outright. If override
+// is set, it returns nil; any change is warned about by checkFreeze.
+func checkFreezeUpdate(ctx *dep.Ctx, p *dep.Project, what string, override bool) error {
+	reason, frozen := activeFreeze(ctx, p, time.Now())
+	if !frozen || override {
+		return nil
+	}
+	return errors.Errorf("%s is refused while %s is frozen: %s\nPass -override-freeze to update it anyway.", what, dep.LockName, reason)
+}
+
+// checkFreeze returns an error if the lock of p is frozen and newLock would
+// change it. If override is set, it only warns.
+func checkFreeze(ctx *dep.Ctx, p *dep.Project, newLock gps.Lock, override bool) error {
+	reason, frozen := activeFreeze(ctx, p, time.Now())
+	if !frozen || !dep.LockChanged(p.Lock, newLock) {
+		return nil
+	}
+	if override {
+		ctx.Err.Printf("Warning: changing %s, although it is frozen: %s\n", dep.LockName, reason)
+		return nil
+	}
+	return errors.Errorf("%s is frozen, and would be changed: %s\nPass -override-freeze to change it anyway.", dep.LockName, reason)
+}
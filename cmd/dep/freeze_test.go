@@ -0,0 +1,56 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+func TestCheckFreeze(t *testing.T) {
+	lock := func(rev string) *dep.Lock {
+		return &dep.Lock{P: []gps.LockedProject{
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}, gps.Revision(rev), []string{"."}),
+		}}
+	}
+	p := &dep.Project{Manifest: dep.NewManifest(), Lock: lock("abc")}
+
+	var errBuf bytes.Buffer
+	ctx := &dep.Ctx{Err: log.New(&errBuf, "", 0)}
+	if err := checkFreeze(ctx, p, lock("def"), false); err != nil {
+		t.Errorf("expected changes to an unfrozen lock to be allowed, got %v", err)
+	}
+	if err := checkFreezeUpdate(ctx, p, "dep ensure -update", false); err != nil {
+		t.Errorf("expected updates of an unfrozen lock to be allowed, got %v", err)
+	}
+
+	ctx.Freeze = "release 2.4"
+	if err := checkFreeze(ctx, p, lock("abc"), false); err != nil {
+		t.Errorf("expected a frozen lock to be rewritten unchanged, got %v", err)
+	}
+	err := checkFreeze(ctx, p, lock("def"), false)
+	if err == nil || !strings.Contains(err.Error(), "release 2.4 ($DEPFREEZE)") {
+		t.Errorf("expected changes to a frozen lock to be refused, got %v", err)
+	}
+	err = checkFreezeUpdate(ctx, p, "dep ensure -update", false)
+	if err == nil || !strings.HasPrefix(err.Error(), "dep ensure -update is refused") {
+		t.Errorf("expected updates of a frozen lock to be refused, got %v", err)
+	}
+
+	if err := checkFreezeUpdate(ctx, p, "dep ensure -update", true); err != nil {
+		t.Errorf("expected -override-freeze to allow updates, got %v", err)
+	}
+	if err := checkFreeze(ctx, p, lock("def"), true); err != nil {
+		t.Errorf("expected -override-freeze to allow changes, got %v", err)
+	}
+	if !strings.Contains(errBuf.String(), "Warning: changing Gopkg.lock, although it is frozen") {
+		t.Errorf("expected a warning about overriding the freeze, got %q", errBuf.String())
+	}
+}
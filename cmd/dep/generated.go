@@ -0,0 +1,124 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/pkg/errors"
+)
+
+// generatedFindings returns a finding for each protobuf definition vendored
+// beneath vendorDir, for the projects in l, that is out of step with the Go
+// code generated from it: a .proto file in a Go package that lacks the
+// .pb.go file generated from it, or a .pb.go file beside .proto files that
+// lacks the one it was generated from.
+func generatedFindings(vendorDir string, l *dep.Lock) ([]checkFinding, error) {
+	var findings []checkFinding
+	for _, lp := range l.Projects() {
+		pr := lp.Ident().ProjectRoot
+		root := filepath.Join(vendorDir, filepath.FromSlash(string(pr)))
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					// Projects missing from vendor are reported elsewhere.
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			if path != root && info.Name() == "vendor" {
+				return filepath.SkipDir
+			}
+
+			missing, orphaned, err := unpairedProtos(path)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(vendorDir, path)
+			dir := filepath.ToSlash(rel)
+			for _, name := range missing {
+				findings = append(findings, checkFinding{
+					Type:        findingMissingGenerated,
+					Project:     string(pr),
+					Expected:    dir + "/" + strings.TrimSuffix(name, ".proto") + ".pb.go",
+					Message:     fmt.Sprintf("%s/%s has no generated Go code in vendor", dir, name),
+					Remediation: remedyGenerated,
+				})
+			}
+			for _, name := range orphaned {
+				findings = append(findings, checkFinding{
+					Type:        findingMissingProto,
+					Project:     string(pr),
+					Expected:    dir + "/" + strings.TrimSuffix(name, ".pb.go") + ".proto",
+					Message:     fmt.Sprintf("%s/%s was generated from a .proto file that is missing from vendor", dir, name),
+					Remediation: remedyGenerated,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check the generated code vendored for %s", pr)
+		}
+	}
+	return findings, nil
+}
+
+// unpairedProtos returns the .proto files in dir without a corresponding
+// .pb.go file, and the .pb.go files without a corresponding .proto file.
+//
+// Definitions are often kept apart from the Go code generated from them, and
+// pruning non-Go files removes them all, so a .proto file is only expected to
+// have been generated into dir if dir holds Go files, and a .pb.go file only
+// expected to have its .proto file beside it if dir holds .proto files.
+func unpairedProtos(dir string) (missing, orphaned []string, err error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	protos := make(map[string]bool)
+	generated := make(map[string]bool)
+	var gofiles bool
+	for _, fi := range fis {
+		name := fi.Name()
+		switch {
+		case fi.IsDir():
+		case strings.HasSuffix(name, ".proto"):
+			protos[strings.TrimSuffix(name, ".proto")] = true
+		case strings.HasSuffix(name, ".pb.go"):
+			generated[strings.TrimSuffix(name, ".pb.go")] = true
+		case strings.HasSuffix(name, ".go"):
+			gofiles = true
+		}
+	}
+
+	if gofiles || len(generated) > 0 {
+		for base := range protos {
+			if !generated[base] {
+				missing = append(missing, base+".proto")
+			}
+		}
+	}
+	if len(protos) > 0 {
+		for base := range generated {
+			if !protos[base] {
+				orphaned = append(orphaned, base+".pb.go")
+			}
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(orphaned)
+	return missing, orphaned, nil
+}
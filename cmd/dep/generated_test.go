@@ -0,0 +1,73 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestGeneratedFindings(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	for _, name := range []string{
+		// Complete: a Go package with its definition and generated code.
+		"vendor/github.com/complete/api/api.proto",
+		"vendor/github.com/complete/api/api.pb.go",
+		"vendor/github.com/complete/api/client.go",
+		// Definitions kept apart from the Go package, and pruned.
+		"vendor/github.com/apart/api/proto/api.proto",
+		"vendor/github.com/apart/api/gen/api.pb.go",
+		// Generated at build time upstream, so missing from vendor.
+		"vendor/github.com/ungenerated/api/api.proto",
+		"vendor/github.com/ungenerated/api/types.proto",
+		"vendor/github.com/ungenerated/api/types.pb.go",
+		"vendor/github.com/ungenerated/api/client.go",
+		// One definition lost beside another.
+		"vendor/github.com/partial/api/a.proto",
+		"vendor/github.com/partial/api/a.pb.go",
+		"vendor/github.com/partial/api/b.pb.go",
+	} {
+		h.TempFile(name, "")
+	}
+
+	l := &dep.Lock{P: lockedProjects(
+		"github.com/complete/api",
+		"github.com/apart/api",
+		"github.com/ungenerated/api",
+		"github.com/partial/api",
+		"github.com/missing/api",
+	)}
+
+	findings, err := generatedFindings(filepath.Join(h.Path("."), "vendor"), l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []checkFinding{
+		{
+			Type:     findingMissingGenerated,
+			Project:  "github.com/ungenerated/api",
+			Expected: "github.com/ungenerated/api/api.pb.go",
+		},
+		{
+			Type:     findingMissingProto,
+			Project:  "github.com/partial/api",
+			Expected: "github.com/partial/api/b.proto",
+		},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("expected %d findings, got %d: %+v", len(want), len(findings), findings)
+	}
+	for i, f := range findings {
+		if f.Type != want[i].Type || f.Project != want[i].Project || f.Expected != want[i].Expected {
+			t.Errorf("expected finding %+v, got %+v", want[i], f)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const gitHooksShortHelp = `Configure git to merge and diff Gopkg.lock using dep`
+const gitHooksLongHelp = `
+Configure the git repository containing the current project so that conflicts
+in Gopkg.lock are resolved by 'dep lock merge', and diffs of Gopkg.lock are
+shown as one line per project by 'dep lock textconv'.
+
+The drivers are registered in the repository's local git config, and
+Gopkg.lock is associated with them in the .gitattributes file at the project
+root, which is created if necessary and should be committed so that other
+clones pick up the association. Each clone still needs to run this command
+once, as git does not share config between clones.
+
+Only the project's own Gopkg.lock is configured; it is not committed, and no
+git hooks in .git/hooks are changed. The merge driver is given the path of
+the lock being merged, so that it solves the right project even when that is
+not at the top of the repository. This needs git 2.5 or later.
+`
+
+// gitDriverName is the name under which dep's merge driver and diff textconv
+// are registered in git config and .gitattributes.
+const gitDriverName = "dep-lock"
+
+type gitHooksCommand struct{}
+
+func (cmd *gitHooksCommand) Name() string      { return "git-install-hooks" }
+func (cmd *gitHooksCommand) Args() string      { return "" }
+func (cmd *gitHooksCommand) ShortHelp() string { return gitHooksShortHelp }
+func (cmd *gitHooksCommand) LongHelp() string  { return gitHooksLongHelp }
+func (cmd *gitHooksCommand) Hidden() bool      { return false }
+
+func (cmd *gitHooksCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *gitHooksCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.New("git-install-hooks takes no arguments")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range gitDriverConfig() {
+		out, err := gps.RunCommand(context.TODO(), p.AbsRoot, nil, "git", "config", kv[0], kv[1])
+		if err != nil {
+			return errors.Wrapf(err, "failed to set git config %s: %s", kv[0], bytes.TrimSpace(out))
+		}
+	}
+
+	changed, err := ensureGitAttributes(filepath.Join(p.AbsRoot, ".gitattributes"))
+	if err != nil {
+		return err
+	}
+	if changed {
+		ctx.Out.Printf("Added %s to .gitattributes; commit it to share the association.\n", dep.LockName)
+	}
+	ctx.Out.Printf("Configured git to merge and diff %s with dep.\n", dep.LockName)
+	return nil
+}
+
+// gitDriverConfig returns the git config keys, and their values, that register
+// dep's merge driver and diff textconv.
+func gitDriverConfig() [][2]string {
+	return [][2]string{
+		{"merge." + gitDriverName + ".name", "dep lock merge driver"},
+		{"merge." + gitDriverName + ".driver", "dep lock merge -base %O -ours %A -theirs %B -path %P"},
+		{"diff." + gitDriverName + ".textconv", "dep lock textconv"},
+	}
+}
+
+// ensureGitAttributes adds a line associating Gopkg.lock with dep's drivers to
+// the gitattributes file at path, unless one is already present. It reports
+// whether the file was changed.
+func ensureGitAttributes(path string) (bool, error) {
+	line := dep.LockName + " merge=" + gitDriverName + " diff=" + gitDriverName
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	for _, l := range strings.Split(string(b), "\n") {
+		if strings.TrimSpace(l) == line {
+			return false, nil
+		}
+	}
+
+	if len(b) > 0 && b[len(b)-1] != '\n' {
+		b = append(b, '\n')
+	}
+	b = append(b, line+"\n"...)
+	if err := ioutil.WriteFile(path, b, 0666); err != nil {
+		return false, errors.Wrapf(err, "failed to write %s", path)
+	}
+	return true, nil
+}
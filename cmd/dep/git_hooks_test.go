@@ -0,0 +1,35 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestEnsureGitAttributes(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile(".gitattributes", "*.go text")
+	path := filepath.Join(h.Path("."), ".gitattributes")
+
+	for i, wantChanged := range []bool{true, false} {
+		changed, err := ensureGitAttributes(path)
+		h.Must(err)
+		if changed != wantChanged {
+			t.Errorf("call %d: expected changed to be %v", i, wantChanged)
+		}
+	}
+
+	b, err := ioutil.ReadFile(path)
+	h.Must(err)
+	if want := "*.go text\nGopkg.lock merge=dep-lock diff=dep-lock\n"; string(b) != want {
+		t.Errorf("unexpected .gitattributes:\n\t(GOT): %q\n\t(WNT): %q", b, want)
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/pkg/errors"
+)
+
+const graphShortHelp = `Show how packages are reached through imports`
+const graphLongHelp = `
+Graph answers questions about the package-level import graph of the current
+project and its locked dependencies.
+
+With -from and -to, graph prints every import chain leading from a package in
+the current project to the packages in a dependency that it reaches, one chain
+per line, shortest first:
+
+  dep graph -from ./cmd/server -to github.com/foo/bar
+
+-from is either an import path or a path relative to the project root. -to
+matches the named package and every package beneath it, so passing a project
+root shows how each of its packages that are used is reached. A chain ends at
+the first package matching -to, and never passes through a package twice.
+
+As densely connected packages can be linked by a great many chains, at most
+-max chains are printed (100 by default); graph says so when there are more.
+
+With -packages, graph instead exports the whole import graph of the packages
+of the current project and of the packages it uses from its dependencies, as
+listed in Gopkg.lock, for external analyzers and architecture linters:
+
+  dep graph -packages -o graph.json
+
+The graph is written to the file named by -o, or to standard output, as a JSON
+object of the form:
+
+  {
+    "version": 1,
+    "root": "github.com/me/project",
+    "packages": [
+      {
+        "importPath": "github.com/me/project/cmd/server",
+        "project": "github.com/me/project",
+        "root": true,
+        "imports": ["fmt", "github.com/foo/bar"],
+        "testImports": ["testing"]
+      },
+      {
+        "importPath": "github.com/foo/bar",
+        "project": "github.com/foo/bar",
+        "version": "v1.2.0",
+        "revision": "5b1d5e0a2c8e6b4f1a1e8d0f9b2c3a4d5e6f7a8b",
+        "imports": ["strings"]
+      }
+    ]
+  }
+
+"version" is the version of the format, which only changes if it does in a way
+that is not backwards-compatible. Packages are sorted by import path, and
+their imports are sorted and include the standard library. "root" marks the
+packages of the current project, and "version" and "revision" give the
+version of a dependency recorded in Gopkg.lock. "testImports" is only set for
+the packages of the current project, and only if -tests is passed.
+
+Packages from dependencies are read at the revisions recorded in Gopkg.lock.
+Test imports of the current project's packages are followed only if -tests is
+passed; test imports of dependencies are never followed.
+`
+
+type graphCommand struct {
+	from, to string
+	max      int
+	tests    bool
+	packages bool
+	out      string
+}
+
+func (cmd *graphCommand) Name() string { return "graph" }
+func (cmd *graphCommand) Args() string {
+	return "-from <package> -to <package> [-max <n>] [-tests] | -packages [-o <file>] [-tests]"
+}
+func (cmd *graphCommand) ShortHelp() string { return graphShortHelp }
+func (cmd *graphCommand) LongHelp() string  { return graphLongHelp }
+func (cmd *graphCommand) Hidden() bool      { return false }
+
+func (cmd *graphCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.from, "from", "", "package at which import chains start")
+	fs.StringVar(&cmd.to, "to", "", "package, or prefix of packages, at which import chains end")
+	fs.IntVar(&cmd.max, "max", 100, "print at most this many import chains")
+	fs.BoolVar(&cmd.tests, "tests", false, "follow test imports of the current project's packages")
+	fs.BoolVar(&cmd.packages, "packages", false, "export the whole package-level import graph as JSON")
+	fs.StringVar(&cmd.out, "o", "", "file to which -packages writes the graph, instead of standard output")
+}
+
+func (cmd *graphCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.Errorf("graph takes no arguments, got %q", args)
+	}
+	if cmd.packages {
+		if cmd.from != "" || cmd.to != "" {
+			return errors.New("-packages exports the whole graph; cannot pass -from or -to")
+		}
+	} else if cmd.out != "" {
+		return errors.New("-o can only be used with -packages")
+	} else if cmd.from == "" || cmd.to == "" {
+		return errors.New("both -from and -to must be specified")
+	} else if cmd.max < 1 {
+		return errors.Errorf("-max must be at least 1, got %d", cmd.max)
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("no %s found, cannot read the import graph of dependencies", dep.LockName)
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	if cmd.packages {
+		export, err := exportGraph(p, sm, cmd.tests)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(export); err != nil {
+			return errors.Wrap(err, "failed to encode the import graph")
+		}
+		if cmd.out == "" {
+			ctx.Out.Print(buf.String())
+			return nil
+		}
+		return errors.Wrapf(ioutil.WriteFile(cmd.out, buf.Bytes(), 0666), "failed to write the import graph to %s", cmd.out)
+	}
+
+	graph := make(map[string][]string)
+	addPackages(graph, p.RootPackageTree, cmd.tests)
+	for _, lp := range p.Lock.Projects() {
+		ptree, err := sm.ListPackages(lp.Ident(), lp.Version())
+		if err != nil {
+			return errors.Wrapf(err, "could not list packages in %s", lp.Ident())
+		}
+		addPackages(graph, ptree, false)
+	}
+
+	from := cmd.from
+	if from == "." || strings.HasPrefix(from, "./") {
+		from = path.Join(string(p.ImportRoot), from)
+	}
+	if _, has := graph[from]; !has {
+		return errors.Errorf("%s is not a package in the current project or its dependencies", from)
+	}
+
+	to := strings.TrimSuffix(cmd.to, "/")
+	chains, more := findImportChains(graph, from, func(ip string) bool {
+		return ip == to || strings.HasPrefix(ip, to+"/")
+	}, cmd.max)
+	if len(chains) == 0 {
+		return errors.Errorf("%s does not import %s", from, to)
+	}
+
+	for _, chain := range chains {
+		ctx.Out.Println(strings.Join(chain, " -> "))
+	}
+	if more {
+		ctx.Err.Printf("There are more than %d import chains; pass a higher -max to see them all.\n", cmd.max)
+	}
+	return nil
+}
+
+// addPackages records the imports of each valid package in ptree in graph.
+func addPackages(graph map[string][]string, ptree pkgtree.PackageTree, tests bool) {
+	for ip, perr := range ptree.Packages {
+		if perr.Err != nil {
+			continue
+		}
+
+		imps := perr.P.Imports
+		if tests {
+			imps = append(append([]string(nil), imps...), perr.P.TestImports...)
+		}
+		graph[ip] = dedupeStrings(imps)
+	}
+}
+
+// graphExportVersion is the version of the format written by -packages.
+const graphExportVersion = 1
+
+// graphExport is the package-level import graph, as written by -packages.
+type graphExport struct {
+	Version  int            `json:"version"`
+	Root     string         `json:"root"`
+	Packages []graphPackage `json:"packages"`
+}
+
+type graphPackage struct {
+	ImportPath string `json:"importPath"`
+	Project    string `json:"project"`
+	// Root is set for the packages of the current project.
+	Root bool `json:"root,omitempty"`
+	// Version and Revision are those locked for the packages of dependencies.
+	Version     string   `json:"version,omitempty"`
+	Revision    string   `json:"revision,omitempty"`
+	Imports     []string `json:"imports"`
+	TestImports []string `json:"testImports,omitempty"`
+}
+
+// exportGraph builds the import graph of the valid packages of p, including
+// their test imports if tests is set, and of the packages p uses from each of
+// its locked dependencies, as listed by pl at their locked versions.
+func exportGraph(p *dep.Project, pl packageLister, tests bool) (graphExport, error) {
+	export := graphExport{
+		Version:  graphExportVersion,
+		Root:     string(p.ImportRoot),
+		Packages: []graphPackage{},
+	}
+
+	for ip, poe := range p.RootPackageTree.Packages {
+		if poe.Err != nil {
+			continue
+		}
+		gp := graphPackage{
+			ImportPath: ip,
+			Project:    string(p.ImportRoot),
+			Root:       true,
+			Imports:    dedupeStrings(poe.P.Imports),
+		}
+		if tests && len(poe.P.TestImports) > 0 {
+			gp.TestImports = dedupeStrings(poe.P.TestImports)
+		}
+		export.Packages = append(export.Packages, gp)
+	}
+
+	if p.Lock != nil {
+		for _, lp := range p.Lock.Projects() {
+			id := lp.Ident()
+			ptree, err := pl.ListPackages(id, lp.Version())
+			if err != nil {
+				return graphExport{}, errors.Wrapf(err, "could not list packages in %s", id)
+			}
+
+			rev, _, _ := gps.VersionComponentStrings(lp.Version())
+			var version string
+			if _, ok := lp.Version().(gps.Revision); !ok {
+				version = lp.Version().String()
+			}
+			for _, pkg := range lp.Packages() {
+				ip := string(id.ProjectRoot)
+				if pkg != "." {
+					ip = path.Join(ip, pkg)
+				}
+				poe, has := ptree.Packages[ip]
+				if !has || poe.Err != nil {
+					continue
+				}
+				export.Packages = append(export.Packages, graphPackage{
+					ImportPath: ip,
+					Project:    string(id.ProjectRoot),
+					Version:    version,
+					Revision:   rev,
+					Imports:    dedupeStrings(poe.P.Imports),
+				})
+			}
+		}
+	}
+
+	sort.Slice(export.Packages, func(i, j int) bool {
+		return export.Packages[i].ImportPath < export.Packages[j].ImportPath
+	})
+	return export, nil
+}
+
+// findImportChains returns the import chains through graph that start at from
+// and end at a package for which isTarget returns true, without passing
+// through another such package, or through any package twice. At most max
+// chains are returned, sorted by length and then lexically; more reports
+// whether there were more.
+//
+// Which chains are returned when there are more than max depends on the order
+// of the imports in graph, so they are not necessarily the shortest. The graph
+// is searched depth first, only through packages from which a target can be
+// reached, so that the time taken is bounded by max and the size of graph,
+// however many chains there are in all.
+//
+// Imports with no entry in graph (e.g. the standard library) are treated as
+// leaves, but can still terminate a chain.
+func findImportChains(graph map[string][]string, from string, isTarget func(string) bool, max int) (chains [][]string, more bool) {
+	// leads holds the packages through which a chain can continue: those from
+	// which a target can be reached without passing through another.
+	importers := make(map[string][]string)
+	for ip, imps := range graph {
+		for _, imp := range imps {
+			importers[imp] = append(importers[imp], ip)
+		}
+	}
+	leads := make(map[string]bool)
+	var queue []string
+	for imp, ips := range importers {
+		if isTarget(imp) {
+			queue = append(queue, ips...)
+		}
+	}
+	for len(queue) > 0 {
+		ip := queue[0]
+		queue = queue[1:]
+		if leads[ip] || (isTarget(ip) && ip != from) {
+			continue
+		}
+		leads[ip] = true
+		queue = append(queue, importers[ip]...)
+	}
+
+	path := []string{from}
+	onPath := map[string]bool{from: true}
+	// walk extends path with the imports of its last package, and returns
+	// false once more than max chains have been found.
+	var walk func() bool
+	walk = func() bool {
+		for _, imp := range graph[path[len(path)-1]] {
+			if onPath[imp] {
+				continue
+			}
+			if isTarget(imp) {
+				if len(chains) == max {
+					return false
+				}
+				chains = append(chains, append(append([]string(nil), path...), imp))
+				continue
+			}
+			if !leads[imp] {
+				continue
+			}
+
+			path = append(path, imp)
+			onPath[imp] = true
+			ok := walk()
+			onPath[imp] = false
+			path = path[:len(path)-1]
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+	if leads[from] {
+		more = !walk()
+	}
+
+	sort.Slice(chains, func(i, j int) bool {
+		if len(chains[i]) != len(chains[j]) {
+			return len(chains[i]) < len(chains[j])
+		}
+		return strings.Join(chains[i], "\x00") < strings.Join(chains[j], "\x00")
+	})
+	return chains, more
+}
+
+// dedupeStrings returns a sorted copy of s with duplicates removed.
+func dedupeStrings(s []string) []string {
+	seen := make(map[string]bool, len(s))
+	out := make([]string, 0, len(s))
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
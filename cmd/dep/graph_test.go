@@ -0,0 +1,193 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+)
+
+func TestFindImportChains(t *testing.T) {
+	graph := map[string][]string{
+		"root/cmd/server":        {"fmt", "root/internal/api", "root/internal/db"},
+		"root/internal/api":      {"github.com/foo/bar", "root/internal/db"},
+		"root/internal/db":       {"github.com/foo/bar/sub", "root/internal/api"},
+		"github.com/foo/bar":     {"github.com/foo/bar/sub"},
+		"github.com/foo/bar/sub": {"strings"},
+	}
+	under := func(prefix string) func(string) bool {
+		return func(ip string) bool {
+			return ip == prefix || strings.HasPrefix(ip, prefix+"/")
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		from, to string
+		want     [][]string
+	}{
+		{
+			name: "project prefix",
+			from: "root/cmd/server",
+			to:   "github.com/foo/bar",
+			want: [][]string{
+				{"root/cmd/server", "root/internal/api", "github.com/foo/bar"},
+				{"root/cmd/server", "root/internal/db", "github.com/foo/bar/sub"},
+				{"root/cmd/server", "root/internal/api", "root/internal/db", "github.com/foo/bar/sub"},
+				{"root/cmd/server", "root/internal/db", "root/internal/api", "github.com/foo/bar"},
+			},
+		},
+		{
+			name: "standard library leaf",
+			from: "root/cmd/server",
+			to:   "fmt",
+			want: [][]string{
+				{"root/cmd/server", "fmt"},
+			},
+		},
+		{
+			name: "through a dependency",
+			from: "root/internal/api",
+			to:   "strings",
+			want: [][]string{
+				{"root/internal/api", "github.com/foo/bar", "github.com/foo/bar/sub", "strings"},
+				{"root/internal/api", "root/internal/db", "github.com/foo/bar/sub", "strings"},
+			},
+		},
+		{
+			name: "unreachable",
+			from: "github.com/foo/bar/sub",
+			to:   "root",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, more := findImportChains(graph, tc.from, under(tc.to), 10)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("unexpected chains:\n\t(GOT): %v\n\t(WNT): %v", got, tc.want)
+			}
+			if more {
+				t.Error("expected every chain to be found")
+			}
+		})
+	}
+}
+
+func TestFindImportChainsDense(t *testing.T) {
+	// Every package of each layer imports every package of the next, so
+	// that there are 8^24 chains from the root to the leaf.
+	const layers, width = 24, 8
+	graph := map[string][]string{}
+	prev := []string{"root"}
+	for l := 0; l < layers; l++ {
+		var cur []string
+		for w := 0; w < width; w++ {
+			cur = append(cur, fmt.Sprintf("root/l%02d/p%d", l, w))
+		}
+		for _, ip := range prev {
+			graph[ip] = cur
+		}
+		prev = cur
+	}
+	for _, ip := range prev {
+		graph[ip] = []string{"github.com/foo/leaf"}
+	}
+
+	root := append([]string(nil), graph["root"]...)
+	for w := 0; w < width; w++ {
+		root = append(root, fmt.Sprintf("root/other%d", w))
+	}
+	graph["root"] = root
+
+	const max = 20
+	chains, more := findImportChains(graph, "root/l00/p0", func(ip string) bool { return ip == "github.com/foo/leaf" }, max)
+	if !more {
+		t.Error("expected more chains than the maximum")
+	}
+	if len(chains) != max {
+		t.Fatalf("expected %d chains, got %d", max, len(chains))
+	}
+	for _, chain := range chains {
+		if len(chain) != layers+1 {
+			t.Errorf("expected a chain through every layer, got %v", chain)
+		}
+	}
+
+	// None of the chains through the layers lead to the targets, so they must
+	// not be searched.
+	chains, more = findImportChains(graph, "root", func(ip string) bool { return strings.HasPrefix(ip, "root/other") }, max)
+	if more || len(chains) != width {
+		t.Errorf("expected a chain to each other package, got %v", chains)
+	}
+}
+
+func TestExportGraph(t *testing.T) {
+	pkg := func(ip string, imports, testImports []string) pkgtree.PackageOrErr {
+		return pkgtree.PackageOrErr{P: pkgtree.Package{ImportPath: ip, Imports: imports, TestImports: testImports}}
+	}
+	rev := gps.Revision("5b1d5e0a2c8e6b4f1a1e8d0f9b2c3a4d5e6f7a8b")
+	p := &dep.Project{
+		ImportRoot: "root",
+		RootPackageTree: pkgtree.PackageTree{
+			ImportRoot: "root",
+			Packages: map[string]pkgtree.PackageOrErr{
+				"root/cmd/server": pkg("root/cmd/server", []string{"fmt", "github.com/foo/bar", "fmt"}, []string{"testing"}),
+				"root/broken":     {Err: &pkgtree.LocalImportsError{}},
+			},
+		},
+		Lock: &dep.Lock{P: []gps.LockedProject{
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}, gps.NewVersion("v1.2.0").Pair(rev), []string{"."}),
+		}},
+	}
+	pl := fakePackageLister{
+		"github.com/foo/bar": {
+			ImportRoot: "github.com/foo/bar",
+			Packages: map[string]pkgtree.PackageOrErr{
+				"github.com/foo/bar":        pkg("github.com/foo/bar", []string{"strings"}, []string{"testing"}),
+				"github.com/foo/bar/unused": pkg("github.com/foo/bar/unused", nil, nil),
+			},
+		},
+	}
+
+	export, err := exportGraph(p, pl, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := graphExport{
+		Version: graphExportVersion,
+		Root:    "root",
+		Packages: []graphPackage{
+			{
+				ImportPath: "github.com/foo/bar",
+				Project:    "github.com/foo/bar",
+				Version:    "v1.2.0",
+				Revision:   string(rev),
+				Imports:    []string{"strings"},
+			},
+			{
+				ImportPath:  "root/cmd/server",
+				Project:     "root",
+				Root:        true,
+				Imports:     []string{"fmt", "github.com/foo/bar"},
+				TestImports: []string{"testing"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(export, want) {
+		t.Errorf("unexpected graph:\n\t(GOT): %+v\n\t(WNT): %+v", export, want)
+	}
+
+	delete(pl, "github.com/foo/bar")
+	if _, err := exportGraph(p, pl, false); err == nil {
+		t.Error("expected an error when the packages of a dependency cannot be listed")
+	}
+}
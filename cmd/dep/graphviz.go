@@ -25,6 +25,8 @@ type gvnode struct {
 	project  string
 	version  string
 	children []string
+	// style is the graphviz style of the node, if any.
+	style string
 }
 
 // Sort gvnode(s).
@@ -50,7 +52,7 @@ func (g *graphviz) output(project string) bytes.Buffer {
 
 		for _, gvp := range g.ps {
 			// Create node string
-			g.b.WriteString(fmt.Sprintf("\n\t%d [label=\"%s\"];", gvp.hash(), gvp.label()))
+			g.b.WriteString(fmt.Sprintf("\n\t%d [%s];", gvp.hash(), gvp.attributes()))
 		}
 
 		g.createProjectRelations()
@@ -174,6 +176,16 @@ func (g *graphviz) createNode(project, version string, children []string) {
 	g.ps = append(g.ps, pr)
 }
 
+// setNodeStyle sets the graphviz style, such as "dashed", with which the node
+// of project is drawn.
+func (g *graphviz) setNodeStyle(project, style string) {
+	for _, gvp := range g.ps {
+		if gvp.project == project {
+			gvp.style = style
+		}
+	}
+}
+
 func (dp gvnode) hash() uint32 {
 	h := fnv.New32a()
 	h.Write([]byte(dp.project))
@@ -190,6 +202,14 @@ func (dp gvnode) label() string {
 	return strings.Join(label, "\\n")
 }
 
+func (dp gvnode) attributes() string {
+	attrs := fmt.Sprintf("label=\"%s\"", dp.label())
+	if dp.style != "" {
+		attrs += ", style=" + dp.style
+	}
+	return attrs
+}
+
 // isPathPrefix ensures that the literal string prefix is a path tree match and
 // guards against possibilities like this:
 //
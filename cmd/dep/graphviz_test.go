@@ -44,6 +44,25 @@ func TestSimpleProject(t *testing.T) {
 	}
 }
 
+func TestStyledNode(t *testing.T) {
+	h := test.NewHelper(t)
+	h.Parallel()
+	defer h.Cleanup()
+
+	g := new(graphviz).New()
+
+	g.createNode("project", "", []string{"foo", "bar"})
+	g.createNode("foo", "master", []string{"bar"})
+	g.createNode("bar", "dev", []string{})
+	g.setNodeStyle("bar", "dashed")
+
+	b := g.output("")
+	want := h.GetTestFileString("graphviz/styled.dot")
+	if b.String() != want {
+		t.Fatalf("expected '%v', got '%v'", want, b.String())
+	}
+}
+
 func TestNoLinks(t *testing.T) {
 	h := test.NewHelper(t)
 	h.Parallel()
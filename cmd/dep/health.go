@@ -0,0 +1,198 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const (
+	remedyStaleRevision   = "update the project to a more recent revision, or raise max-age-years in the [health] table of Gopkg.toml"
+	remedyInactiveProject = "replace the project with a maintained alternative, or raise max-inactive-months in the [health] table of Gopkg.toml"
+)
+
+// activityReader is implemented by SourceManagers that can report when the
+// revisions of a source were committed.
+type activityReader interface {
+	RevisionTime(gps.ProjectIdentifier, gps.Version) (time.Time, error)
+	LatestActivity(gps.ProjectIdentifier) (time.Time, error)
+}
+
+// projectHealth describes the age of the locked revision of a project, and
+// how recently its upstream was last active.
+type projectHealth struct {
+	ProjectRoot string
+	Revision    gps.Revision
+	// RevisionTime is when the locked revision was committed.
+	RevisionTime time.Time
+	// LatestActivity is when the most recent commit to any branch or tag was
+	// made, as of the last time the cached source was fetched.
+	LatestActivity time.Time
+	// Stale is set when the locked revision is older than max-age-years.
+	Stale bool
+	// Inactive is set when there has been no activity for longer than
+	// max-inactive-months.
+	Inactive bool
+	// Err is set when the times could not be determined.
+	Err error
+}
+
+// status summarizes h in a word or two, for the STATUS column.
+func (h projectHealth) status() string {
+	if h.Err != nil {
+		return "unknown"
+	}
+	var problems []string
+	if h.Stale {
+		problems = append(problems, "stale")
+	}
+	if h.Inactive {
+		problems = append(problems, "inactive")
+	}
+	if len(problems) == 0 {
+		return "ok"
+	}
+	return strings.Join(problems, ", ")
+}
+
+type rawProjectHealth struct {
+	ProjectRoot    string `json:"projectRoot"`
+	Revision       string `json:"revision"`
+	RevisionTime   string `json:"revisionTime,omitempty"`
+	LatestActivity string `json:"latestActivity,omitempty"`
+	Stale          bool   `json:"stale"`
+	Inactive       bool   `json:"inactive"`
+	Error          string `json:"error,omitempty"`
+}
+
+func (h projectHealth) marshalJSON() rawProjectHealth {
+	raw := rawProjectHealth{
+		ProjectRoot: h.ProjectRoot,
+		Revision:    string(h.Revision),
+		Stale:       h.Stale,
+		Inactive:    h.Inactive,
+	}
+	if !h.RevisionTime.IsZero() {
+		raw.RevisionTime = h.RevisionTime.Format(time.RFC3339)
+	}
+	if !h.LatestActivity.IsZero() {
+		raw.LatestActivity = h.LatestActivity.Format(time.RFC3339)
+	}
+	if h.Err != nil {
+		raw.Error = h.Err.Error()
+	}
+	return raw
+}
+
+// assessHealth reads the revision and activity times of each of lps from ar,
+// and judges them against the limits in opts as of now. The results are sorted
+// by project root.
+func assessHealth(lps []gps.LockedProject, ar activityReader, opts dep.HealthOptions, now time.Time) []projectHealth {
+	reports := make([]projectHealth, len(lps))
+
+	var wg sync.WaitGroup
+	for i, lp := range lps {
+		wg.Add(1)
+		go func(i int, lp gps.LockedProject) {
+			defer wg.Done()
+			id := lp.Ident()
+			h := projectHealth{ProjectRoot: string(id.ProjectRoot)}
+			defer func() { reports[i] = h }()
+
+			v := lp.Version()
+			if pv, ok := v.(gps.PairedVersion); ok {
+				h.Revision = pv.Revision()
+			} else if r, ok := v.(gps.Revision); ok {
+				h.Revision = r
+			}
+
+			var err error
+			if h.RevisionTime, err = ar.RevisionTime(id, v); err != nil {
+				h.Err = errors.Wrapf(err, "could not read the time of the locked revision of %s", id)
+				return
+			}
+			if h.LatestActivity, err = ar.LatestActivity(id); err != nil {
+				h.Err = errors.Wrapf(err, "could not read the latest activity in %s", id)
+				return
+			}
+
+			if opts.MaxAgeYears > 0 {
+				h.Stale = h.RevisionTime.Before(now.AddDate(-opts.MaxAgeYears, 0, 0))
+			}
+			if opts.MaxInactiveMonths > 0 {
+				h.Inactive = h.LatestActivity.Before(now.AddDate(0, -opts.MaxInactiveMonths, 0))
+			}
+		}(i, lp)
+	}
+	wg.Wait()
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ProjectRoot < reports[j].ProjectRoot })
+	return reports
+}
+
+// healthFindings converts the stale and inactive projects among reports into
+// findings for dep check.
+func healthFindings(reports []projectHealth, opts dep.HealthOptions) []checkFinding {
+	var findings []checkFinding
+	for _, h := range reports {
+		if h.Stale {
+			findings = append(findings, checkFinding{
+				Type:        findingStaleRevision,
+				Project:     h.ProjectRoot,
+				Actual:      h.RevisionTime.Format("2006-01-02"),
+				Message:     fmt.Sprintf("locked revision is more than %d years old", opts.MaxAgeYears),
+				Remediation: remedyStaleRevision,
+			})
+		}
+		if h.Inactive {
+			findings = append(findings, checkFinding{
+				Type:        findingInactiveProject,
+				Project:     h.ProjectRoot,
+				Actual:      h.LatestActivity.Format("2006-01-02"),
+				Message:     fmt.Sprintf("no upstream activity in more than %d months", opts.MaxInactiveMonths),
+				Remediation: remedyInactiveProject,
+			})
+		}
+	}
+	return findings
+}
+
+// printHealth writes reports to w as a table, or as JSON.
+func printHealth(w io.Writer, reports []projectHealth, asJSON bool) error {
+	if asJSON {
+		raw := make([]rawProjectHealth, 0, len(reports))
+		for _, h := range reports {
+			raw = append(raw, h.marshalJSON())
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(raw)
+	}
+
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tREVISION\tCOMMITTED\tLAST ACTIVITY\tSTATUS")
+	for _, h := range reports {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", h.ProjectRoot, formatVersion(h.Revision), date(h.RevisionTime), date(h.LatestActivity), h.status())
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// fakeActivityReader maps project roots to their revision and latest
+// activity times.
+type fakeActivityReader map[gps.ProjectRoot][2]time.Time
+
+func (f fakeActivityReader) RevisionTime(id gps.ProjectIdentifier, v gps.Version) (time.Time, error) {
+	times, ok := f[id.ProjectRoot]
+	if !ok {
+		return time.Time{}, errors.New("no such source")
+	}
+	return times[0], nil
+}
+
+func (f fakeActivityReader) LatestActivity(id gps.ProjectIdentifier) (time.Time, error) {
+	return f[id.ProjectRoot][1], nil
+}
+
+func TestAssessHealth(t *testing.T) {
+	now := time.Date(2018, time.June, 1, 0, 0, 0, 0, time.UTC)
+	ar := fakeActivityReader{
+		"github.com/fresh/lib":     {now.AddDate(0, -2, 0), now.AddDate(0, 0, -3)},
+		"github.com/old/lib":       {now.AddDate(-4, 0, 0), now.AddDate(0, -1, 0)},
+		"github.com/abandoned/lib": {now.AddDate(-5, 0, 0), now.AddDate(-2, 0, 0)},
+	}
+	lps := lockedProjects("github.com/old/lib", "github.com/fresh/lib", "github.com/abandoned/lib", "github.com/missing/lib")
+	opts := dep.HealthOptions{MaxAgeYears: 3, MaxInactiveMonths: 12}
+
+	reports := assessHealth(lps, ar, opts, now)
+	want := map[string]string{
+		"github.com/abandoned/lib": "stale, inactive",
+		"github.com/fresh/lib":     "ok",
+		"github.com/missing/lib":   "unknown",
+		"github.com/old/lib":       "stale",
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("expected %d reports, got %d", len(want), len(reports))
+	}
+	for i, h := range reports {
+		if i > 0 && reports[i-1].ProjectRoot > h.ProjectRoot {
+			t.Errorf("reports are not sorted: %s before %s", reports[i-1].ProjectRoot, h.ProjectRoot)
+		}
+		if got := h.status(); got != want[h.ProjectRoot] {
+			t.Errorf("%s: expected status %q, got %q", h.ProjectRoot, want[h.ProjectRoot], got)
+		}
+	}
+
+	findings := healthFindings(reports, opts)
+	var types []string
+	for _, f := range findings {
+		types = append(types, f.Project+" "+f.Type)
+	}
+	wantTypes := "github.com/abandoned/lib stale-revision,github.com/abandoned/lib inactive-project,github.com/old/lib stale-revision"
+	if got := strings.Join(types, ","); got != wantTypes {
+		t.Errorf("unexpected findings:\n\t(GOT): %s\n\t(WNT): %s", got, wantTypes)
+	}
+
+	// Without limits, nothing is flagged.
+	for _, h := range assessHealth(lps, ar, dep.HealthOptions{}, now) {
+		if h.Stale || h.Inactive {
+			t.Errorf("%s: expected no problems without limits, got %q", h.ProjectRoot, h.status())
+		}
+	}
+}
+
+func TestPrintHealth(t *testing.T) {
+	at := time.Date(2015, time.March, 4, 0, 0, 0, 0, time.UTC)
+	reports := []projectHealth{
+		{
+			ProjectRoot:    "github.com/old/lib",
+			Revision:       gps.Revision("d4a1a8e2a4f50f8b4e610b7ab3e8b3a4a4b6c0de"),
+			RevisionTime:   at,
+			LatestActivity: at,
+			Stale:          true,
+		},
+		{
+			ProjectRoot: "github.com/missing/lib",
+			Err:         errors.New("no such source"),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := printHealth(&buf, reports, false); err != nil {
+		t.Fatal(err)
+	}
+	want := `PROJECT                 REVISION  COMMITTED   LAST ACTIVITY  STATUS
+github.com/old/lib      d4a1a8e   2015-03-04  2015-03-04     stale
+github.com/missing/lib            -           -              unknown
+`
+	if buf.String() != want {
+		t.Errorf("unexpected table:\n\t(GOT):\n%s\n\t(WNT):\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := printHealth(&buf, reports, true); err != nil {
+		t.Fatal(err)
+	}
+	var raw []rawProjectHealth
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw[0].RevisionTime != "2015-03-04T00:00:00Z" || !raw[0].Stale {
+		t.Errorf("unexpected JSON for stale project: %+v", raw[0])
+	}
+	if raw[1].Error != "no such source" || raw[1].RevisionTime != "" {
+		t.Errorf("unexpected JSON for unknown project: %+v", raw[1])
+	}
+}
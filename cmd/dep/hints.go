@@ -0,0 +1,27 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+// applyHints loads the solver hints file configured in ctx, if any, and sets
+// it on params, so that the solver skips candidates that earlier solves could
+// not use. It returns a func that saves the hints, along with any recorded by
+// the solve, back to the file; a failure to save is only warned about.
+func applyHints(ctx *dep.Ctx, params *gps.SolveParameters) (func(), error) {
+	hints, err := ctx.LoadSolveHints(params.ProjectAnalyzer.Info())
+	if err != nil {
+		return nil, err
+	}
+	params.Hints = hints
+	return func() {
+		if err := ctx.SaveSolveHints(hints); err != nil {
+			ctx.Err.Printf("Warning: %v\n", err)
+		}
+	}, nil
+}
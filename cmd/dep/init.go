@@ -27,7 +27,8 @@ specified, use the current directory.
 When configuration for another dependency management tool is detected, it is
 imported into the initial manifest and lock. Use the -skip-tools flag to
 disable this behavior. The following external tools are supported:
-glide, godep, vndr, govend, gb, gvt, govendor, glock.
+glide, godep, vndr, govend, gb, gvt, govendor, glock, and the
+vendor/modules.txt file written by go modules.
 
 Any dependencies that are not constrained by external configuration use the
 GOPATH analysis below.
@@ -45,6 +46,10 @@ of each dependency will reflect the current state of the GOPATH. If a dependency
 doesn't exist in the GOPATH, a version will be selected based on the above
 network version selection algorithm.
 
+Imports of packages whose paths are known to have moved, such as those on
+code.google.com, are reported, as they can no longer be fetched; -migrate
+rewrites them to their new paths first.
+
 A Gopkg.toml file will be written with inferred version constraints for all
 direct dependencies. Gopkg.lock will be written with precise versions, and
 vendor/ will be populated with the precise versions written to Gopkg.lock.
@@ -60,12 +65,14 @@ func (cmd *initCommand) Register(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.noExamples, "no-examples", false, "don't include example in Gopkg.toml")
 	fs.BoolVar(&cmd.skipTools, "skip-tools", false, "skip importing configuration from other dependency managers")
 	fs.BoolVar(&cmd.gopath, "gopath", false, "search in GOPATH for dependencies")
+	fs.BoolVar(&cmd.migrate, "migrate", false, "rewrite imports of packages whose paths are known to have moved to their new paths")
 }
 
 type initCommand struct {
 	noExamples bool
 	skipTools  bool
 	gopath     bool
+	migrate    bool
 }
 
 func (cmd *initCommand) Run(ctx *dep.Ctx, args []string) error {
@@ -90,6 +97,9 @@ func (cmd *initCommand) Run(ctx *dep.Ctx, args []string) error {
 	if err != nil {
 		return err
 	}
+	if _, err := migrateImports(ctx, root, nil, cmd.migrate, "dep init -migrate"); err != nil {
+		return errors.Wrap(err, "init failed: unable to look for imports of moved packages")
+	}
 
 	sm, err := ctx.SourceManager()
 	if err != nil {
@@ -142,6 +152,16 @@ func (cmd *initCommand) Run(ctx *dep.Ctx, args []string) error {
 	if ctx.Verbose {
 		params.TraceLogger = ctx.Err
 	}
+	applyImportPolicy(ctx, &params)
+	yanked, err := applyYanked(ctx, &params)
+	if err != nil {
+		return errors.Wrap(err, "init failed")
+	}
+	saveHints, err := applyHints(ctx, &params)
+	if err != nil {
+		return errors.Wrap(err, "init failed")
+	}
+	defer saveHints()
 
 	if err := ctx.ValidateParams(sm, params); err != nil {
 		return errors.Wrapf(err, "init failed: validation of solve parameters failed")
@@ -158,6 +178,9 @@ func (cmd *initCommand) Run(ctx *dep.Ctx, args []string) error {
 		return errors.Wrap(err, "init failed: unable to solve the dependency graph")
 	}
 	p.Lock = dep.LockFromSolution(soln, p.Manifest.PruneOptions)
+	if ctx.YankedWarnOnly {
+		warnYanked(ctx.Err, yanked, p.Lock)
+	}
 
 	rootAnalyzer.FinalizeRootManifestAndLock(p.Manifest, p.Lock, copyLock)
 
@@ -179,7 +202,9 @@ func (cmd *initCommand) Run(ctx *dep.Ctx, args []string) error {
 	if ctx.Verbose {
 		logger = ctx.Err
 	}
-	if err := sw.Write(root, sm, !cmd.noExamples, logger); err != nil {
+	wctx, stop := interruptContext()
+	defer stop()
+	if err := sw.Write(wctx, root, sm, !cmd.noExamples, logger); err != nil {
 		return errors.Wrap(err, "init failed: unable to write the manifest, lock and vendor directory to disk")
 	}
 
@@ -0,0 +1,311 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// lintKind identifies the class of problem a lintFinding describes.
+type lintKind string
+
+const (
+	// lintDuplicateFunctionality indicates that several locked projects
+	// appear to provide the same functionality.
+	lintDuplicateFunctionality lintKind = "duplicate-functionality"
+	// lintSharedAncestry indicates that several locked projects share VCS
+	// history, meaning that at least one is likely a fork of another.
+	lintSharedAncestry lintKind = "shared-ancestry"
+)
+
+// lintFinding is a single problem reported by dep status -lint.
+type lintFinding struct {
+	Kind     lintKind
+	Projects []string
+	Reason   string
+}
+
+// knownFunctionality groups well-known projects by the functionality they
+// provide. Depending on more than one project from the same group is rarely
+// intentional.
+var knownFunctionality = map[string][]gps.ProjectRoot{
+	"YAML parsing": {
+		"gopkg.in/yaml.v2",
+		"gopkg.in/yaml.v3",
+		"github.com/go-yaml/yaml",
+		"github.com/ghodss/yaml",
+		"sigs.k8s.io/yaml",
+	},
+	"TOML parsing": {
+		"github.com/BurntSushi/toml",
+		"github.com/pelletier/go-toml",
+	},
+	"structured logging": {
+		"github.com/sirupsen/logrus",
+		"github.com/Sirupsen/logrus",
+		"go.uber.org/zap",
+		"github.com/rs/zerolog",
+		"github.com/inconshreveable/log15",
+		"github.com/go-kit/kit",
+	},
+	"error wrapping": {
+		"github.com/pkg/errors",
+		"github.com/juju/errors",
+		"github.com/go-errors/errors",
+	},
+	"UUID generation": {
+		"github.com/satori/go.uuid",
+		"github.com/google/uuid",
+		"github.com/pborman/uuid",
+		"github.com/gofrs/uuid",
+	},
+	"HTTP routing": {
+		"github.com/gorilla/mux",
+		"github.com/julienschmidt/httprouter",
+		"github.com/go-chi/chi",
+		"github.com/labstack/echo",
+		"github.com/gin-gonic/gin",
+	},
+	"test assertions": {
+		"github.com/stretchr/testify",
+		"gopkg.in/check.v1",
+		"github.com/onsi/gomega",
+	},
+	"command line parsing": {
+		"github.com/spf13/cobra",
+		"github.com/urfave/cli",
+		"gopkg.in/alecthomas/kingpin.v2",
+		"github.com/alecthomas/kingpin",
+		"github.com/jessevdk/go-flags",
+	},
+}
+
+// findDuplicateFunctionality reports groups of locked projects that are likely
+// to provide overlapping functionality.
+//
+// Two heuristics are applied: membership in the same knownFunctionality group,
+// and projects that share a repository name under different owners (which is
+// typically a fork living alongside its upstream).
+func findDuplicateFunctionality(lps []gps.LockedProject) []lintFinding {
+	byRoot := make(map[gps.ProjectRoot]bool, len(lps))
+	for _, lp := range lps {
+		byRoot[lp.Ident().ProjectRoot] = true
+	}
+
+	var findings []lintFinding
+	claimed := make(map[gps.ProjectRoot]bool)
+
+	// Iterate over the groups in sorted order for deterministic output.
+	groups := make([]string, 0, len(knownFunctionality))
+	for g := range knownFunctionality {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	for _, g := range groups {
+		var present []string
+		for _, pr := range knownFunctionality[g] {
+			if byRoot[pr] {
+				present = append(present, string(pr))
+				claimed[pr] = true
+			}
+		}
+		if len(present) > 1 {
+			sort.Strings(present)
+			findings = append(findings, lintFinding{
+				Kind:     lintDuplicateFunctionality,
+				Projects: present,
+				Reason:   fmt.Sprintf("multiple projects providing %s", g),
+			})
+		}
+	}
+
+	byName := make(map[string][]string)
+	for pr := range byRoot {
+		if claimed[pr] {
+			continue
+		}
+		if name := repoBaseName(pr); name != "" {
+			byName[name] = append(byName[name], string(pr))
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if prs := byName[name]; len(prs) > 1 {
+			sort.Strings(prs)
+			findings = append(findings, lintFinding{
+				Kind:     lintDuplicateFunctionality,
+				Projects: prs,
+				Reason:   fmt.Sprintf("multiple projects named %q, possibly forks of one another", name),
+			})
+		}
+	}
+
+	return findings
+}
+
+// rootRevisionLister is implemented by SourceManagers that can report the
+// parentless revisions in a source's history.
+type rootRevisionLister interface {
+	RootRevisions(gps.ProjectIdentifier) ([]gps.Revision, error)
+}
+
+// findSharedAncestry reports groups of locked projects whose sources share a
+// root revision. Importing both a fork and its upstream is a common source of
+// duplicate type bugs.
+//
+// Groups are transitive: if a shares a root revision with b, and b with c, all
+// three are reported as one group, even if a and c share none.
+//
+// Projects whose root revisions cannot be determined are skipped; the errors
+// encountered are returned alongside any findings. As listing them may clone
+// the sources, at most gps.MaxConcurrentWriters projects are listed at the
+// same time.
+func findSharedAncestry(lps []gps.LockedProject, rl rootRevisionLister) ([]lintFinding, []error) {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	// group maps each project to another project of its group, and each
+	// group's representative to itself.
+	group := make(map[string]string)
+	find := func(pr string) string {
+		for group[pr] != pr {
+			group[pr] = group[group[pr]]
+			pr = group[pr]
+		}
+		return pr
+	}
+	// byRoot maps each root revision to the first project found with it.
+	byRoot := make(map[gps.Revision]string)
+	sem := make(chan struct{}, gps.MaxConcurrentWriters())
+
+	for _, lp := range lps {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id gps.ProjectIdentifier) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			roots, err := rl.RootRevisions(id)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "could not determine ancestry of %s", id))
+				return
+			}
+			pr := string(id.ProjectRoot)
+			if _, has := group[pr]; !has {
+				group[pr] = pr
+			}
+			for _, r := range roots {
+				other, has := byRoot[r]
+				if !has {
+					byRoot[r] = pr
+					continue
+				}
+				group[find(pr)] = find(other)
+			}
+		}(lp.Ident())
+	}
+	wg.Wait()
+
+	members := make(map[string][]string)
+	for pr := range group {
+		rep := find(pr)
+		members[rep] = append(members[rep], pr)
+	}
+
+	var findings []lintFinding
+	for _, prs := range members {
+		if len(prs) < 2 {
+			continue
+		}
+		sort.Strings(prs)
+		findings = append(findings, lintFinding{
+			Kind:     lintSharedAncestry,
+			Projects: prs,
+			Reason:   "projects share VCS history; one is likely a fork of another",
+		})
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		return strings.Join(findings[i].Projects, ",") < strings.Join(findings[j].Projects, ",")
+	})
+	return findings, errs
+}
+
+// repoBaseName extracts a normalized repository name from a project root, for
+// comparison against other project roots. Common decorations like "go-"
+// prefixes, ".go" suffixes and gopkg.in-style ".vN" suffixes are dropped.
+//
+// An empty string is returned for roots with too few path elements to have a
+// meaningful owner/name split.
+func repoBaseName(pr gps.ProjectRoot) string {
+	parts := strings.Split(string(pr), "/")
+	if len(parts) < 3 {
+		return ""
+	}
+
+	name := strings.ToLower(parts[len(parts)-1])
+	if i := strings.LastIndex(name, ".v"); i > 0 && isDigits(name[i+2:]) {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimPrefix(name, "go.")
+	name = strings.TrimSuffix(name, ".go")
+	name = strings.TrimSuffix(name, "-go")
+	return name
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// printLintFindings writes findings to w, either as a table or JSON.
+func printLintFindings(w io.Writer, findings []lintFinding, asJSON bool) error {
+	if asJSON {
+		if findings == nil {
+			findings = []lintFinding{}
+		}
+		return json.NewEncoder(w).Encode(findings)
+	}
+
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "No problems found.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tPROJECTS\tREASON")
+	for _, f := range findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Kind, strings.Join(f.Projects, ", "), f.Reason)
+	}
+	return tw.Flush()
+}
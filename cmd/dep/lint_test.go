@@ -0,0 +1,219 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+func lockedProjects(roots ...string) []gps.LockedProject {
+	lps := make([]gps.LockedProject, 0, len(roots))
+	for _, r := range roots {
+		lps = append(lps, gps.NewLockedProject(
+			gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(r)},
+			gps.Revision("d4a1a8e2a4f50f8b4e610b7ab3e8b3a4a4b6c0de"),
+			nil,
+		))
+	}
+	return lps
+}
+
+func TestFindDuplicateFunctionality(t *testing.T) {
+	testCases := []struct {
+		name  string
+		roots []string
+		want  []lintFinding
+	}{
+		{
+			name:  "no duplicates",
+			roots: []string{"github.com/pkg/errors", "gopkg.in/yaml.v2"},
+		},
+		{
+			name:  "known group",
+			roots: []string{"github.com/ghodss/yaml", "gopkg.in/yaml.v2", "github.com/pkg/errors"},
+			want: []lintFinding{
+				{
+					Kind:     lintDuplicateFunctionality,
+					Projects: []string{"github.com/ghodss/yaml", "gopkg.in/yaml.v2"},
+					Reason:   "multiple projects providing YAML parsing",
+				},
+			},
+		},
+		{
+			name:  "same repository name",
+			roots: []string{"github.com/foo/go-bar", "github.com/baz/bar", "github.com/foo/qux"},
+			want: []lintFinding{
+				{
+					Kind:     lintDuplicateFunctionality,
+					Projects: []string{"github.com/baz/bar", "github.com/foo/go-bar"},
+					Reason:   `multiple projects named "bar", possibly forks of one another`,
+				},
+			},
+		},
+		{
+			name:  "known group members are not double-reported",
+			roots: []string{"github.com/sirupsen/logrus", "github.com/Sirupsen/logrus"},
+			want: []lintFinding{
+				{
+					Kind:     lintDuplicateFunctionality,
+					Projects: []string{"github.com/Sirupsen/logrus", "github.com/sirupsen/logrus"},
+					Reason:   "multiple projects providing structured logging",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := findDuplicateFunctionality(lockedProjects(tc.roots...))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("unexpected findings:\n\t(GOT): %#v\n\t(WNT): %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+type fakeRootRevisionLister map[gps.ProjectRoot][]gps.Revision
+
+func (f fakeRootRevisionLister) RootRevisions(id gps.ProjectIdentifier) ([]gps.Revision, error) {
+	roots, ok := f[id.ProjectRoot]
+	if !ok {
+		return nil, errors.New("no such source")
+	}
+	return roots, nil
+}
+
+func TestFindSharedAncestry(t *testing.T) {
+	rl := fakeRootRevisionLister{
+		"github.com/upstream/lib": {"r1", "r2"},
+		"github.com/fork/lib":     {"r2", "r1"},
+		"github.com/other/fork":   {"r1", "r3"},
+		"github.com/unrelated/x":  {"r4"},
+		"github.com/third/lib":    {"r3", "r5"},
+		"github.com/other/x":      {"r4"},
+	}
+	lps := lockedProjects(
+		"github.com/upstream/lib",
+		"github.com/fork/lib",
+		"github.com/other/fork",
+		"github.com/unrelated/x",
+		"github.com/third/lib",
+		"github.com/other/x",
+		"github.com/missing/y",
+	)
+
+	got, errs := findSharedAncestry(lps, rl)
+	want := []lintFinding{
+		{
+			Kind:     lintSharedAncestry,
+			Projects: []string{"github.com/fork/lib", "github.com/other/fork", "github.com/third/lib", "github.com/upstream/lib"},
+			Reason:   "projects share VCS history; one is likely a fork of another",
+		},
+		{
+			Kind:     lintSharedAncestry,
+			Projects: []string{"github.com/other/x", "github.com/unrelated/x"},
+			Reason:   "projects share VCS history; one is likely a fork of another",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected findings:\n\t(GOT): %#v\n\t(WNT): %#v", got, want)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected 1 error for the missing source, got %v", errs)
+	}
+}
+
+// countingRootRevisionLister records the most calls to RootRevisions made at
+// the same time.
+type countingRootRevisionLister struct {
+	mu        sync.Mutex
+	cur, peak int
+}
+
+func (c *countingRootRevisionLister) RootRevisions(id gps.ProjectIdentifier) ([]gps.Revision, error) {
+	c.mu.Lock()
+	c.cur++
+	if c.cur > c.peak {
+		c.peak = c.cur
+	}
+	c.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	c.mu.Lock()
+	c.cur--
+	c.mu.Unlock()
+	return []gps.Revision{gps.Revision(id.ProjectRoot)}, nil
+}
+
+func TestFindSharedAncestryBounded(t *testing.T) {
+	gps.SetConcurrentWriters(2)
+	defer gps.SetConcurrentWriters(0)
+
+	var names []string
+	for i := 0; i < 20; i++ {
+		names = append(names, fmt.Sprintf("github.com/foo/bar%d", i))
+	}
+	rl := &countingRootRevisionLister{}
+	if _, errs := findSharedAncestry(lockedProjects(names...), rl); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if rl.peak > 2 {
+		t.Errorf("expected at most 2 sources to be listed at the same time, got %d", rl.peak)
+	}
+}
+
+func TestRepoBaseName(t *testing.T) {
+	testCases := map[gps.ProjectRoot]string{
+		"github.com/foo/bar":        "bar",
+		"github.com/foo/go-bar":     "bar",
+		"github.com/satori/go.uuid": "uuid",
+		"gopkg.in/foo/bar.v2":       "bar",
+		"github.com/foo/Bar-go":     "bar",
+		"gopkg.in/yaml.v2":          "",
+		"k8s.io/client-go":          "",
+	}
+
+	for pr, want := range testCases {
+		if got := repoBaseName(pr); got != want {
+			t.Errorf("repoBaseName(%q) = %q, want %q", pr, got, want)
+		}
+	}
+}
+
+func TestPrintLintFindings(t *testing.T) {
+	findings := []lintFinding{
+		{
+			Kind:     lintDuplicateFunctionality,
+			Projects: []string{"github.com/a/b", "github.com/c/b"},
+			Reason:   "because",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := printLintFindings(&buf, findings, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "github.com/a/b, github.com/c/b") {
+		t.Errorf("unexpected table output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := printLintFindings(&buf, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Errorf("unexpected JSON output for no findings: %s", got)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const listProjectsShortHelp = `List the projects registered with the cache`
+const listProjectsLongHelp = `
+List the projects whose locks are registered with the local cache, with the
+number of dependencies in each lock and when it was last written. Projects are
+registered by dep ensure when $DEPREGISTER is set, or with dep cache register.
+Projects whose lock no longer exists are listed as missing.
+`
+
+type listProjectsCommand struct{}
+
+func (cmd *listProjectsCommand) Name() string      { return "list-projects" }
+func (cmd *listProjectsCommand) Args() string      { return "" }
+func (cmd *listProjectsCommand) ShortHelp() string { return listProjectsShortHelp }
+func (cmd *listProjectsCommand) LongHelp() string  { return listProjectsLongHelp }
+func (cmd *listProjectsCommand) Hidden() bool      { return false }
+
+func (cmd *listProjectsCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *listProjectsCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.New("list-projects takes no arguments")
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	defer sm.Release()
+
+	locks, err := readRegisteredLocks(sm.Cachedir())
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeProjectList(&buf, locks)
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// registeredLock is a lock file registered with the cache. lock is nil if the
+// file no longer exists.
+type registeredLock struct {
+	path    string
+	lock    *dep.Lock
+	modTime time.Time
+}
+
+// readRegisteredLocks reads the locks registered with the cache in cachedir.
+func readRegisteredLocks(cachedir string) ([]registeredLock, error) {
+	paths, err := dep.ReadLockRegistry(cachedir)
+	if err != nil {
+		return nil, err
+	}
+
+	locks := make([]registeredLock, 0, len(paths))
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			locks = append(locks, registeredLock{path: path})
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "unable to stat %s", path)
+		}
+		l, err := readLockFile(path)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, registeredLock{path: path, lock: l, modTime: fi.ModTime()})
+	}
+	return locks, nil
+}
+
+// registeredProjects returns the projects in all of the locks that exist.
+func registeredProjects(locks []registeredLock) []gps.LockedProject {
+	var lps []gps.LockedProject
+	for _, rl := range locks {
+		if rl.lock != nil {
+			lps = append(lps, rl.lock.Projects()...)
+		}
+	}
+	return lps
+}
+
+// writeProjectList writes a table of the projects with locks in locks to w.
+func writeProjectList(w io.Writer, locks []registeredLock) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tDEPENDENCIES\tLOCK WRITTEN")
+	for _, rl := range locks {
+		if rl.lock == nil {
+			fmt.Fprintf(tw, "%s\t-\tmissing\n", filepath.Dir(rl.path))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", filepath.Dir(rl.path), len(rl.lock.Projects()), rl.modTime.Format(time.RFC3339))
+	}
+	tw.Flush()
+}
+
+// registerLock records the lock of p in the lock registry of the cache, if
+// ctx asks for that. A failure to do so is only warned about.
+func registerLock(ctx *dep.Ctx, sm *gps.SourceMgr, p *dep.Project) {
+	if !ctx.RegisterLocks {
+		return
+	}
+	if err := dep.RegisterLocks(sm.Cachedir(), filepath.Join(p.AbsRoot, dep.LockName)); err != nil {
+		ctx.Err.Printf("Warning: failed to register %s with the cache: %v\n", dep.LockName, err)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestWriteProjectList(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("cache/.keep", "")
+	h.TempFile("proj/Gopkg.lock", "[[projects]]\n  name = \"github.com/foo/bar\"\n  revision = \"abc123\"\n")
+	cachedir := h.Path("cache")
+	gone := filepath.Join(h.Path("."), "gone", "Gopkg.lock")
+	h.Must(dep.RegisterLocks(cachedir, h.Path("proj/Gopkg.lock"), gone))
+
+	locks, err := readRegisteredLocks(cachedir)
+	h.Must(err)
+	var buf bytes.Buffer
+	writeProjectList(&buf, locks)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and two projects, got:\n%s", buf.String())
+	}
+	if f := strings.Fields(lines[1]); f[0] != filepath.Dir(gone) || f[2] != "missing" {
+		t.Errorf("expected the missing project to be listed as such, got %q", lines[1])
+	}
+	if f := strings.Fields(lines[2]); f[0] != h.Path("proj") || f[1] != "1" {
+		t.Errorf("expected the project to be listed with one dependency, got %q", lines[2])
+	}
+}
+
+func TestRegisterLock(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir("cache")
+	h.TempDir("proj")
+	discard := log.New(ioutil.Discard, "", 0)
+	sm, err := (&dep.Ctx{Cachedir: h.Path("cache"), Out: discard, Err: discard}).SourceManager()
+	h.Must(err)
+	defer sm.Release()
+	p := &dep.Project{AbsRoot: h.Path("proj")}
+
+	registerLock(&dep.Ctx{}, sm, p)
+	if paths, err := dep.ReadLockRegistry(h.Path("cache")); err != nil || len(paths) != 0 {
+		t.Fatalf("expected nothing to be registered unless asked for, got %v, %v", paths, err)
+	}
+
+	registerLock(&dep.Ctx{RegisterLocks: true, Err: discard}, sm, p)
+	paths, err := dep.ReadLockRegistry(h.Path("cache"))
+	h.Must(err)
+	if len(paths) != 1 || paths[0] != filepath.Join(h.Path("proj"), dep.LockName) {
+		t.Errorf("expected the project's lock to be registered, got %v", paths)
+	}
+}
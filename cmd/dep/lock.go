@@ -0,0 +1,407 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+	"github.com/pkg/errors"
+)
+
+const lockShortHelp = `Operate on Gopkg.lock`
+const lockLongHelp = `
+Lock provides operations on Gopkg.lock that are not part of the normal ensure
+workflow.
+
+  dep lock merge -ours <lock> -theirs <lock> [-base <lock>] [-path <lock>] [-o <file>] [-override-freeze] [-reason <text>]
+  dep lock textconv <lock>
+  dep lock diff [-json] <old lock> [<new lock>]
+
+Merge resolves a conflict between two versions of Gopkg.lock, such as after
+merging two branches that each changed dependencies. Rather than merging the
+files line by line, it solves the current project again against Gopkg.toml
+(which must already be merged), preferring the versions recorded in either
+lock. Where both locks hold a project at different versions, the one that
+sorts first for upgrade is preferred (the newer semver release), falling back
+to ours. The result is written to -o, which defaults to the -ours file.
+
+The project is the one containing the current directory, or, if -path is
+given, the one containing that path. As git runs merge drivers at the top of
+the work tree, rather than in the directory of the file being merged, -path
+is needed there when Gopkg.toml is not at the top.
+
+While Gopkg.lock is frozen (see dep ensure), merge fails if the merged lock
+differs from ours, unless -override-freeze is given. Likewise, if Gopkg.toml
+sets justify-major-updates, merge fails if the merged lock updates a project of
+ours to a new major version, unless -reason gives the reason for it, which is
+recorded in the merged lock.
+
+Projects whose resulting revision is not in either lock will have no digest;
+run 'dep ensure' afterwards to update vendor/ and fill them in.
+
+Merge can be used directly as a git merge driver for Gopkg.lock:
+
+  git config merge.dep-lock.driver 'dep lock merge -base %O -ours %A -theirs %B -path %P'
+  echo 'Gopkg.lock merge=dep-lock' >> .gitattributes
+
+Textconv prints a lock as one line per project, giving its version and
+revision, so that diffs of Gopkg.lock show a line per changed project. It is
+meant for use as a git diff textconv. 'dep git-install-hooks' configures both
+the merge driver and the textconv for the current repository.
+
+Diff prints what changes from the old lock to the new one, which defaults to
+the Gopkg.lock of the current project: the projects added (+), removed (-) and
+modified (~), and the input imports added and removed. With -json, the changes
+are output in the same JSON form as dep ensure -dry-run -json.
+`
+
+type lockCommand struct {
+	ours, theirs, base string
+	path               string
+	output             string
+	overrideFreeze     bool
+	reason             string
+}
+
+func (cmd *lockCommand) Name() string { return "lock" }
+func (cmd *lockCommand) Args() string {
+	return "merge -ours <lock> -theirs <lock> [-base <lock>] [-path <lock>] [-o <file>] [-override-freeze] [-reason <text>] | textconv <lock> | diff [-json] <old lock> [<new lock>]"
+}
+func (cmd *lockCommand) ShortHelp() string { return lockShortHelp }
+func (cmd *lockCommand) LongHelp() string  { return lockLongHelp }
+func (cmd *lockCommand) Hidden() bool      { return false }
+
+func (cmd *lockCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.ours, "ours", "", "our version of the lock")
+	fs.StringVar(&cmd.theirs, "theirs", "", "their version of the lock")
+	fs.StringVar(&cmd.base, "base", "", "common ancestor of the locks; accepted for use as a merge driver, but not needed")
+	fs.StringVar(&cmd.path, "path", "", "path of the lock being merged, whose project is solved; git passes it as %P")
+	fs.StringVar(&cmd.output, "o", "", "file to write the merged lock to (defaults to the -ours file)")
+	fs.BoolVar(&cmd.overrideFreeze, "override-freeze", false, "change the merged lock even while Gopkg.lock is frozen")
+	fs.StringVar(&cmd.reason, "reason", "", "the reason for updating projects to a new major version, recorded in the merged lock")
+}
+
+func (cmd *lockCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) == 2 && args[0] == "textconv" {
+		l, err := readLockFile(args[1])
+		if err != nil {
+			return err
+		}
+		ctx.Out.Print(lockTextconv(l))
+		return nil
+	}
+
+	if len(args) > 0 && args[0] == "diff" {
+		return cmd.runDiff(ctx, args[1:])
+	}
+
+	if len(args) != 1 || args[0] != "merge" {
+		return errors.New("lock requires a subcommand, one of merge, textconv <lock> or diff <old lock> [<new lock>]")
+	}
+	if cmd.ours == "" || cmd.theirs == "" {
+		return errors.New("lock merge requires both -ours and -theirs")
+	}
+	return cmd.runMerge(ctx)
+}
+
+func (cmd *lockCommand) runMerge(ctx *dep.Ctx) error {
+	ours, err := readLockFile(cmd.ours)
+	if err != nil {
+		return err
+	}
+	theirs, err := readLockFile(cmd.theirs)
+	if err != nil {
+		return err
+	}
+
+	if cmd.path != "" {
+		ctx.WorkingDir = mergeProjectDir(ctx.WorkingDir, cmd.path)
+	}
+
+	// The lock in the project may well contain conflict markers; it is being
+	// replaced in any case.
+	ctx.IgnoreLock = true
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	// Ours is the lock being replaced, against which the merged lock is
+	// checked.
+	p.Lock = ours
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	merged := mergeLocks(ours, theirs)
+	params := p.MakeParams()
+	params.Lock = merged
+	if ctx.Verbose {
+		params.TraceLogger = ctx.Err
+	}
+	applyImportPolicy(ctx, &params)
+	yanked, err := applyYanked(ctx, &params)
+	if err != nil {
+		return err
+	}
+	saveHints, err := applyHints(ctx, &params)
+	if err != nil {
+		return err
+	}
+	defer saveHints()
+
+	solver, err := gps.Prepare(params, sm)
+	if err != nil {
+		return errors.Wrap(err, "prepare solver")
+	}
+	solution, err := solver.Solve(context.TODO())
+	if err != nil {
+		return handleAllTheFailuresOfTheWorld(err)
+	}
+
+	l := dep.LockFromSolution(solution, p.Manifest.PruneOptions)
+	carryDigests(l, ours, theirs)
+	if err := l.RecordTestOnly(p, sm); err != nil {
+		return err
+	}
+	if ctx.YankedWarnOnly {
+		warnYanked(ctx.Err, yanked, l)
+	}
+	if err := checkQuarantine(p.Manifest, merged, l); err != nil {
+		return err
+	}
+	if err := checkFreeze(ctx, p, l, cmd.overrideFreeze); err != nil {
+		return err
+	}
+	if err := checkMajorUpdates(p.Manifest, ours, l, cmd.reason); err != nil {
+		return err
+	}
+	l.RecordUpdateReason(ours, cmd.reason)
+
+	b, err := l.MarshalTOML()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal merged lock")
+	}
+
+	out := cmd.output
+	if out == "" {
+		out = cmd.ours
+	}
+	if err := ioutil.WriteFile(out, b, 0666); err != nil {
+		return errors.Wrapf(err, "failed to write merged lock to %s", out)
+	}
+	return nil
+}
+
+func (cmd *lockCommand) runDiff(ctx *dep.Ctx, args []string) error {
+	flags := flag.NewFlagSet("lock diff", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	asJSON := flags.Bool("json", false, "output in JSON format")
+	if err := flags.Parse(args); err != nil {
+		return errors.Wrap(err, "lock diff")
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		return errors.New("lock diff takes an old lock, and optionally a new one")
+	}
+
+	l1, err := readLockFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var l2 *dep.Lock
+	if flags.NArg() == 2 {
+		if l2, err = readLockFile(flags.Arg(1)); err != nil {
+			return err
+		}
+	} else {
+		p, err := ctx.LoadProject()
+		if err != nil {
+			return err
+		}
+		if p.Lock == nil {
+			return errors.Errorf("no %s found in %s", dep.LockName, p.AbsRoot)
+		}
+		l2 = p.Lock
+	}
+
+	changes := dep.DiffLocks(l1, l2)
+	if !*asJSON {
+		ctx.Out.Print(changes.String())
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(changes); err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// mergeProjectDir returns the directory from which to find the project of the
+// lock at path, which is relative to wd unless it is absolute.
+func mergeProjectDir(wd, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(wd, path)
+	}
+	return filepath.Dir(path)
+}
+
+func readLockFile(path string) (*dep.Lock, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer f.Close()
+
+	l, err := dep.ReadLock(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	return l, nil
+}
+
+// mergeLocks returns a lock holding every project in either ours or theirs,
+// for use as the solver's preferred versions. Where the locks disagree on a
+// project, the version that sorts first for upgrade is taken, or ours if the
+// versions are not comparable.
+func mergeLocks(ours, theirs *dep.Lock) *dep.Lock {
+	merged := &dep.Lock{
+		SolveMeta: ours.SolveMeta,
+	}
+
+	theirsByRoot := make(map[gps.ProjectRoot]gps.LockedProject, len(theirs.P))
+	for _, lp := range theirs.P {
+		theirsByRoot[lp.Ident().ProjectRoot] = lp
+	}
+
+	for _, olp := range ours.P {
+		pr := olp.Ident().ProjectRoot
+		tlp, has := theirsByRoot[pr]
+		delete(theirsByRoot, pr)
+		if has && preferTheirs(olp.Version(), tlp.Version()) {
+			merged.P = append(merged.P, tlp)
+		} else {
+			merged.P = append(merged.P, olp)
+		}
+	}
+	for _, lp := range theirs.P {
+		if _, has := theirsByRoot[lp.Ident().ProjectRoot]; has {
+			merged.P = append(merged.P, lp)
+		}
+	}
+
+	return merged
+}
+
+// preferTheirs reports whether theirs sorts strictly before ours for upgrade.
+func preferTheirs(ours, theirs gps.Version) bool {
+	opv, ok := ours.(gps.PairedVersion)
+	if !ok {
+		return false
+	}
+	tpv, ok := theirs.(gps.PairedVersion)
+	if !ok || opv.Revision() == tpv.Revision() {
+		return false
+	}
+
+	vl := []gps.PairedVersion{opv, tpv}
+	gps.SortPairedForUpgrade(vl)
+	return vl[0] == tpv && !opv.Unpair().Matches(tpv.Unpair())
+}
+
+// carryDigests copies the digests from the input locks to each project in l
+// that is at the same revision, and with the same prune options, as in one of
+// them.
+func carryDigests(l *dep.Lock, inputs ...*dep.Lock) {
+	for i, lp := range l.P {
+		vp, ok := lp.(verify.VerifiableProject)
+		if !ok || !vp.Digest.IsEmpty() {
+			continue
+		}
+		for _, in := range inputs {
+			if ivp, ok := findVerifiable(in, vp); ok {
+				vp.Digest = ivp.Digest
+				l.P[i] = vp
+				break
+			}
+		}
+	}
+}
+
+// findVerifiable returns the project in l that matches vp in project root,
+// revision and prune options.
+func findVerifiable(l *dep.Lock, vp verify.VerifiableProject) (verify.VerifiableProject, bool) {
+	for _, lp := range l.P {
+		ivp, ok := lp.(verify.VerifiableProject)
+		if !ok || ivp.Ident().ProjectRoot != vp.Ident().ProjectRoot || ivp.PruneOpts != vp.PruneOpts {
+			continue
+		}
+		if lockedRevision(ivp.Version()) == lockedRevision(vp.Version()) {
+			return ivp, true
+		}
+	}
+	return verify.VerifiableProject{}, false
+}
+
+// lockTextconv renders l with a line per project, sorted by project root, and
+// a final line listing the input imports.
+func lockTextconv(l *dep.Lock) string {
+	lps := make([]gps.LockedProject, len(l.P))
+	copy(lps, l.P)
+	sort.Slice(lps, func(i, j int) bool {
+		return lps[i].Ident().Less(lps[j].Ident())
+	})
+
+	var buf bytes.Buffer
+	for _, lp := range lps {
+		id := lp.Ident()
+		fmt.Fprintf(&buf, "%s", id.ProjectRoot)
+		if id.Source != "" {
+			fmt.Fprintf(&buf, " (from %s)", id.Source)
+		}
+		if id.RootDir != "" {
+			fmt.Fprintf(&buf, " (in %s)", id.RootDir)
+		}
+
+		v, r := lp.Version(), lockedRevision(lp.Version())
+		if pv, ok := v.(gps.PairedVersion); ok {
+			fmt.Fprintf(&buf, " %s", formatVersion(pv.Unpair()))
+		}
+		fmt.Fprintf(&buf, " %s", r)
+		if len(lp.Packages()) > 0 {
+			fmt.Fprintf(&buf, " [%s]", strings.Join(lp.Packages(), ", "))
+		}
+		buf.WriteByte('\n')
+	}
+	fmt.Fprintf(&buf, "input imports: %s\n", strings.Join(l.SolveMeta.InputImports, ", "))
+	return buf.String()
+}
+
+func lockedRevision(v gps.Version) gps.Revision {
+	switch tv := v.(type) {
+	case gps.PairedVersion:
+		return tv.Revision()
+	case gps.Revision:
+		return tv
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+)
+
+func TestMergeLocks(t *testing.T) {
+	pi := func(root string) gps.ProjectIdentifier {
+		return gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(root)}
+	}
+	ours := &dep.Lock{P: []gps.LockedProject{
+		gps.NewLockedProject(pi("github.com/a/newer-theirs"), gps.NewVersion("v1.0.0").Pair("a1"), nil),
+		gps.NewLockedProject(pi("github.com/b/newer-ours"), gps.NewVersion("v2.0.0").Pair("b2"), nil),
+		gps.NewLockedProject(pi("github.com/c/branch"), gps.NewBranch("master").Pair("c1"), nil),
+		gps.NewLockedProject(pi("github.com/d/only-ours"), gps.Revision("d1"), nil),
+	}}
+	theirs := &dep.Lock{P: []gps.LockedProject{
+		gps.NewLockedProject(pi("github.com/a/newer-theirs"), gps.NewVersion("v1.1.0").Pair("a2"), nil),
+		gps.NewLockedProject(pi("github.com/b/newer-ours"), gps.NewVersion("v1.9.0").Pair("b1"), nil),
+		gps.NewLockedProject(pi("github.com/c/branch"), gps.NewBranch("master").Pair("c2"), nil),
+		gps.NewLockedProject(pi("github.com/e/only-theirs"), gps.Revision("e1"), nil),
+	}}
+
+	want := map[gps.ProjectRoot]gps.Revision{
+		"github.com/a/newer-theirs": "a2",
+		"github.com/b/newer-ours":   "b2",
+		"github.com/c/branch":       "c1",
+		"github.com/d/only-ours":    "d1",
+		"github.com/e/only-theirs":  "e1",
+	}
+
+	merged := mergeLocks(ours, theirs)
+	if len(merged.P) != len(want) {
+		t.Fatalf("expected %d projects in the merged lock, got %d", len(want), len(merged.P))
+	}
+	for _, lp := range merged.P {
+		pr := lp.Ident().ProjectRoot
+		if got := lockedRevision(lp.Version()); got != want[pr] {
+			t.Errorf("%s: expected revision %s, got %s", pr, want[pr], got)
+		}
+	}
+}
+
+func TestCarryDigests(t *testing.T) {
+	pi := gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}
+	digest := verify.VersionedDigest{HashVersion: verify.HashVersion, Digest: []byte("digest")}
+	in := &dep.Lock{P: []gps.LockedProject{verify.VerifiableProject{
+		LockedProject: gps.NewLockedProject(pi, gps.NewVersion("v1.0.0").Pair("rev1"), nil),
+		PruneOpts:     gps.PruneNestedVendorDirs,
+		Digest:        digest,
+	}}}
+
+	l := &dep.Lock{P: []gps.LockedProject{
+		verify.VerifiableProject{
+			LockedProject: gps.NewLockedProject(pi, gps.NewVersion("v1.0.0").Pair("rev1"), nil),
+			PruneOpts:     gps.PruneNestedVendorDirs,
+		},
+	}}
+	carryDigests(l, in)
+	if got := l.P[0].(verify.VerifiableProject).Digest; got.String() != digest.String() {
+		t.Errorf("expected digest to be carried over, got %s", got)
+	}
+
+	l.P[0] = verify.VerifiableProject{
+		LockedProject: gps.NewLockedProject(pi, gps.NewVersion("v1.1.0").Pair("rev2"), nil),
+		PruneOpts:     gps.PruneNestedVendorDirs,
+	}
+	carryDigests(l, in)
+	if got := l.P[0].(verify.VerifiableProject).Digest; !got.IsEmpty() {
+		t.Errorf("expected no digest for a new revision, got %s", got)
+	}
+}
+
+func TestLockTextconv(t *testing.T) {
+	l := &dep.Lock{
+		SolveMeta: dep.SolveMeta{InputImports: []string{"github.com/a/a", "github.com/b/b/pkg"}},
+		P: []gps.LockedProject{
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/b/b"}, gps.NewBranch("master").Pair("0123456789"), []string{"pkg"}),
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/a/a", Source: "github.com/fork/a"}, gps.NewVersion("v1.0.0").Pair("abcdef"), []string{"."}),
+		},
+	}
+
+	want := `github.com/a/a (from github.com/fork/a) v1.0.0 abcdef [.]
+github.com/b/b branch master 0123456789 [pkg]
+input imports: github.com/a/a, github.com/b/b/pkg
+`
+	if got := lockTextconv(l); got != want {
+		t.Errorf("unexpected textconv output:\n\t(GOT): %q\n\t(WNT): %q", got, want)
+	}
+}
+
+func TestMergeProjectDir(t *testing.T) {
+	wd, err := filepath.Abs("repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		dep.LockName:                                  wd,
+		filepath.Join("go", dep.LockName):             filepath.Join(wd, "go"),
+		filepath.Join(wd, "svc", "api", dep.LockName): filepath.Join(wd, "svc", "api"),
+	}
+	for path, want := range cases {
+		if got := mergeProjectDir(wd, path); got != want {
+			t.Errorf("mergeProjectDir(%q, %q) = %q, want %q", wd, path, got, want)
+		}
+	}
+}
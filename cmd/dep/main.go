@@ -8,19 +8,23 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
 	"github.com/golang/dep/internal/fs"
 )
 
@@ -195,6 +199,50 @@ func (c *Config) Run() int {
 				}
 			}
 
+			tools, err := parseTools(getEnv(c.Env, "DEPTOOLS"))
+			if err != nil {
+				errLogger.Printf("dep: failed to parse $DEPTOOLS: %v\n", err)
+				return errorExitCode
+			}
+
+			limits, err := parseCommandLimits(getEnv(c.Env, "DEPVCSTIMEOUT"), getEnv(c.Env, "DEPVCSRETRIES"))
+			if err != nil {
+				errLogger.Printf("dep: %v\n", err)
+				return errorExitCode
+			}
+
+			var maxBandwidth uint64
+			if env := getEnv(c.Env, "DEPMAXBANDWIDTH"); env != "" {
+				if maxBandwidth, err = dep.ParseByteSize(env); err != nil {
+					errLogger.Printf("dep: failed to parse $DEPMAXBANDWIDTH: %v\n", err)
+					return errorExitCode
+				}
+			}
+
+			vendorJobs := 0
+			if env := getEnv(c.Env, "DEPVENDORJOBS"); env != "" {
+				if vendorJobs, err = strconv.Atoi(env); err != nil || vendorJobs < 1 {
+					errLogger.Printf("dep: $DEPVENDORJOBS must be a positive number of projects, got %q\n", env)
+					return errorExitCode
+				}
+			}
+			gps.SetConcurrentWriters(vendorJobs)
+
+			if env := getEnv(c.Env, "DEPVENDORLINK"); env != "" {
+				links, err := gps.ParseExportLinks(env)
+				if err != nil {
+					errLogger.Printf("dep: failed to parse $DEPVENDORLINK: %v\n", err)
+					return errorExitCode
+				}
+				gps.SetExportLinks(links)
+			}
+
+			moduleProxy, proxyFirst, err := parseModuleProxy(getEnv(c.Env, "DEPPROXY"))
+			if err != nil {
+				errLogger.Printf("dep: failed to parse $DEPPROXY: %v\n", err)
+				return errorExitCode
+			}
+
 			// Set up dep context.
 			ctx := &dep.Ctx{
 				Out:            outLogger,
@@ -203,6 +251,50 @@ func (c *Config) Run() int {
 				DisableLocking: getEnv(c.Env, "DEPNOLOCK") != "",
 				Cachedir:       cachedir,
 				CacheAge:       cacheAge,
+				RemoteCache:    getEnv(c.Env, "DEPREMOTECACHE"),
+				GlobalCache:    getEnv(c.Env, "DEPGLOBALCACHE"),
+				YankedFeed:     getEnv(c.Env, "DEPYANKED"),
+				YankedWarnOnly: getEnv(c.Env, "DEPYANKEDWARN") != "",
+				Bundle:         getEnv(c.Env, "DEPBUNDLE"),
+				ImportAllow:    splitPrefixList(getEnv(c.Env, "DEPALLOW")),
+				ImportDeny:     splitPrefixList(getEnv(c.Env, "DEPDENY")),
+				HintsFile:      getEnv(c.Env, "DEPHINTS"),
+				RegisterLocks:  getEnv(c.Env, "DEPREGISTER") != "",
+				Tools:          tools,
+				HermeticTools:  getEnv(c.Env, "DEPHERMETIC") != "",
+				PureGitHosts:   splitPrefixList(getEnv(c.Env, "DEPPUREGIT")),
+				CommandLog:     getEnv(c.Env, "DEPAUDITLOG"),
+				AllowedVCS:     splitPrefixList(getEnv(c.Env, "DEPVCSALLOW")),
+				CommandLimits:  limits,
+				MaxBandwidth:   maxBandwidth,
+				ModuleProxy:    moduleProxy,
+				ProxyFirst:     proxyFirst,
+				Freeze:         getEnv(c.Env, "DEPFREEZE"),
+			}
+			if len(ctx.Tools) > 0 || ctx.HermeticTools {
+				if err := gps.ConfigureTools(ctx.Tools, ctx.HermeticTools); err != nil {
+					errLogger.Printf("dep: unable to use the tools in $DEPTOOLS: %v\n", err)
+					return errorExitCode
+				}
+			}
+
+			gps.UsePureGit(ctx.PureGitHosts)
+			if err := gps.AllowCommands(ctx.AllowedVCS); err != nil {
+				errLogger.Printf("dep: failed to parse $DEPVCSALLOW: %v\n", err)
+				return errorExitCode
+			}
+			if err := gps.SetCommandLimits(ctx.CommandLimits); err != nil {
+				errLogger.Printf("dep: invalid $DEPVCSTIMEOUT or $DEPVCSRETRIES: %v\n", err)
+				return errorExitCode
+			}
+			if ctx.CommandLog != "" {
+				f, err := os.OpenFile(ctx.CommandLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+				if err != nil {
+					errLogger.Printf("dep: unable to open $DEPAUDITLOG: %v\n", err)
+					return errorExitCode
+				}
+				defer f.Close()
+				gps.LogCommands(f)
 			}
 
 			GOPATHS := filepath.SplitList(getEnv(c.Env, "GOPATH"))
@@ -211,7 +303,7 @@ func (c *Config) Run() int {
 			// Run the command with the post-flag-processing args.
 			if err := cmd.Run(ctx, flags.Args()); err != nil {
 				if _, ok := err.(silentfail); !ok {
-					errLogger.Printf("%v\n", err)
+					printError(errLogger, err, verbose, flags.Lookup("v") != nil, c.Env)
 				}
 				return errorExitCode
 			}
@@ -226,6 +318,45 @@ func (c *Config) Run() int {
 	return errorExitCode
 }
 
+// printError prints err to logger. If it holds failures to reach hosts, they
+// are summarized by host, with their likely causes, in place of the errors
+// themselves, which are only printed in full if verbose. canVerbose is whether
+// the command has a -v flag to suggest.
+func printError(logger *log.Logger, err error, verbose, canVerbose bool, env []string) {
+	msg := err.Error()
+	diag := diagnoseNetworkFailures(msg)
+	if diag == nil {
+		logger.Printf("%v\n", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if verbose {
+		fmt.Fprintf(&buf, "%v\n\n", err)
+	} else {
+		fmt.Fprintf(&buf, "%s\n\n", firstLine(msg))
+	}
+	diag.write(&buf, proxyEnvVar(env))
+	if !verbose && canVerbose {
+		fmt.Fprintln(&buf, "\nRun again with -v to see each error in full.")
+	}
+	logger.Print(buf.String())
+}
+
+// proxyEnvVar returns the first of proxyEnvVars, as $NAME, that sets a proxy
+// in env, or "" if none does.
+func proxyEnvVar(env []string) string {
+	for _, name := range proxyEnvVars {
+		if name == "NO_PROXY" {
+			continue
+		}
+		if getEnv(env, name) != "" || getEnv(env, strings.ToLower(name)) != "" {
+			return "$" + name
+		}
+	}
+	return ""
+}
+
 // Build the list of available commands.
 //
 // Note that these commands are mutable, but parts of this file
@@ -238,6 +369,30 @@ func commandList() []command {
 		&pruneCommand{},
 		&versionCommand{},
 		&checkCommand{},
+		&verifyCommand{},
+		&graphCommand{},
+		&schemaCommand{},
+		&daemonCommand{},
+		&completionCommand{},
+		&envCommand{},
+		&skewCommand{},
+		&lockCommand{},
+		&gitHooksCommand{},
+		&bundleCommand{},
+		&vendorZipCommand{},
+		&approveCommand{},
+		&noteCommand{},
+		&bisectCommand{},
+		&tryCommand{},
+		&execCommand{},
+		&cacheCommand{},
+		&listProjectsCommand{},
+		&versionsCommand{},
+		&canUseCommand{},
+		&outdatedCommand{},
+		&tidyCommand{},
+		&devCommand{},
+		&reportCommand{},
 	}
 }
 
@@ -358,6 +513,26 @@ func getEnv(env []string, key string) string {
 	return ""
 }
 
+// interruptContext returns a context that is cancelled when dep is
+// interrupted, so that a write under way can be abandoned and rolled back. The
+// returned function stops listening for interrupts.
+func interruptContext() (context.Context, context.CancelFunc) {
+	c, cancel := context.WithCancel(context.Background())
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt)
+	go func() {
+		select {
+		case <-sigch:
+			cancel()
+		case <-c.Done():
+		}
+	}()
+	return c, func() {
+		signal.Stop(sigch)
+		cancel()
+	}
+}
+
 // commentWriter writes a Go comment to the underlying io.Writer,
 // using line comment form (//).
 //
@@ -0,0 +1,35 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// checkMajorUpdates returns an error naming the projects that newLock would
+// update to a new major version from oldLock, if the manifest requires a
+// reason for such updates and none was given.
+func checkMajorUpdates(m *dep.Manifest, oldLock, newLock gps.Lock, reason string) error {
+	if !m.JustifyMajor || reason != "" {
+		return nil
+	}
+	majors := dep.MajorUpdates(oldLock, newLock)
+	if len(majors) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "justify-major-updates: the following projects would be updated to a new major version in %s, but no reason was given:\n", dep.LockName)
+	for _, pr := range majors {
+		fmt.Fprintf(&buf, "\t%s\n", pr)
+	}
+	buf.WriteString("Run dep ensure with -reason to record why they are updated.")
+	return errors.New(buf.String())
+}
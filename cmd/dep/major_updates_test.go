@@ -0,0 +1,38 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+func TestCheckMajorUpdates(t *testing.T) {
+	lock := func(v string) *dep.Lock {
+		return &dep.Lock{P: []gps.LockedProject{
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}, gps.NewVersion(v).Pair("abc"), []string{"."}),
+		}}
+	}
+	m := dep.NewManifest()
+
+	if err := checkMajorUpdates(m, lock("v1.0.0"), lock("v2.0.0"), ""); err != nil {
+		t.Errorf("expected major updates to be allowed without justify-major-updates, got %v", err)
+	}
+
+	m.JustifyMajor = true
+	if err := checkMajorUpdates(m, lock("v1.0.0"), lock("v1.1.0"), ""); err != nil {
+		t.Errorf("expected minor updates to be allowed without a reason, got %v", err)
+	}
+	err := checkMajorUpdates(m, lock("v1.0.0"), lock("v2.0.0"), "")
+	if err == nil || !strings.Contains(err.Error(), "\tgithub.com/foo/bar\n") {
+		t.Errorf("expected a major update without a reason to be refused, got %v", err)
+	}
+	if err := checkMajorUpdates(m, lock("v1.0.0"), lock("v2.0.0"), "v2 is faster"); err != nil {
+		t.Errorf("expected a major update with a reason to be allowed, got %v", err)
+	}
+}
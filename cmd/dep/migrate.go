@@ -0,0 +1,65 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+// migrateImports finds the imports, in the project at root, of paths that have
+// moved according to the migrations of m, which may be nil, and the rules of m
+// that name such paths. If rewrite is set, the imports are rewritten to their
+// new paths; otherwise, they are only reported, along with rerun, the command
+// that rewrites them. Rules are always left to be renamed by hand. It reports
+// whether any imports were rewritten.
+func migrateImports(ctx *dep.Ctx, root string, m *dep.Manifest, rewrite bool, rerun string) (bool, error) {
+	migrations := m.ImportMigrations()
+	moved, err := dep.FindMovedImports(root, migrations)
+	if err != nil {
+		return false, err
+	}
+
+	if len(moved) > 0 {
+		if rewrite {
+			if err := dep.RewriteMovedImports(root, moved); err != nil {
+				return false, err
+			}
+			for _, mi := range moved {
+				ctx.Err.Printf("Rewrote imports of %s to %s in %d file(s)\n", mi.Path, mi.NewPath, len(mi.Files))
+			}
+		} else {
+			ctx.Err.Println("Warning: the project imports packages whose paths have moved:")
+			ctx.Err.Println()
+			for _, mi := range moved {
+				ctx.Err.Printf("  %s -> %s (%s; imported in %d file(s))\n", mi.Path, mi.NewPath, mi.Migration.Reason, len(mi.Files))
+			}
+			ctx.Err.Printf("\nRun %q to rewrite these imports.\n\n", rerun)
+		}
+	}
+
+	if m != nil {
+		for _, rule := range []struct {
+			kind string
+			pcs  gps.ProjectConstraints
+		}{{"[[constraint]]", m.Constraints}, {"[[override]]", m.Ovr}} {
+			var names []string
+			for pr := range rule.pcs {
+				names = append(names, string(pr))
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if mg, ok := dep.FindImportMigration(migrations, name); ok {
+					np, _ := mg.Migrate(name)
+					ctx.Err.Printf("Warning: %s has a %s for %s, which has moved to %s; rename it by hand.\n", dep.ManifestName, rule.kind, name, np)
+				}
+			}
+		}
+	}
+
+	return rewrite && len(moved) > 0, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/pkg/errors"
+)
+
+// packageLister is implemented by SourceManagers that can list the packages
+// of a project at a version.
+type packageLister interface {
+	ListPackages(gps.ProjectIdentifier, gps.Version) (pkgtree.PackageTree, error)
+}
+
+// nativePackage describes a package of a dependency that is built with
+// non-Go source: cgo, SWIG or assembly.
+type nativePackage struct {
+	ProjectRoot string
+	// Package is the import path of the package, empty if the packages of
+	// the project could not be listed.
+	Package string
+	// Native is the kinds of non-Go source the package is built with, as
+	// named by pkgtree.NativeKinds.
+	Native []string
+	Err    error
+}
+
+type rawNativePackage struct {
+	ProjectRoot string   `json:"projectRoot"`
+	Package     string   `json:"package,omitempty"`
+	Native      []string `json:"native,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+func (n nativePackage) marshalJSON() rawNativePackage {
+	raw := rawNativePackage{
+		ProjectRoot: n.ProjectRoot,
+		Package:     n.Package,
+		Native:      n.Native,
+	}
+	if n.Err != nil {
+		raw.Error = n.Err.Error()
+	}
+	return raw
+}
+
+// assessNative finds the packages used from each of lps that are built with
+// non-Go source, as listed by pl at their locked versions. Projects whose
+// packages cannot be listed are reported with an error. The results are
+// sorted by import path.
+func assessNative(lps []gps.LockedProject, pl packageLister) []nativePackage {
+	found := make([][]nativePackage, len(lps))
+
+	var wg sync.WaitGroup
+	for i, lp := range lps {
+		wg.Add(1)
+		go func(i int, lp gps.LockedProject) {
+			defer wg.Done()
+			id := lp.Ident()
+			root := string(id.ProjectRoot)
+
+			ptree, err := pl.ListPackages(id, lp.Version())
+			if err != nil {
+				found[i] = []nativePackage{{
+					ProjectRoot: root,
+					Err:         errors.Wrapf(err, "could not list the packages of %s", id),
+				}}
+				return
+			}
+
+			for _, pkg := range lp.Packages() {
+				ip := root
+				if pkg != "." {
+					ip = path.Join(root, pkg)
+				}
+				poe, has := ptree.Packages[ip]
+				if !has || poe.Err != nil || len(poe.P.Native) == 0 {
+					continue
+				}
+				found[i] = append(found[i], nativePackage{
+					ProjectRoot: root,
+					Package:     ip,
+					Native:      poe.P.Native,
+				})
+			}
+		}(i, lp)
+	}
+	wg.Wait()
+
+	var reports []nativePackage
+	for _, f := range found {
+		reports = append(reports, f...)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].ProjectRoot != reports[j].ProjectRoot {
+			return reports[i].ProjectRoot < reports[j].ProjectRoot
+		}
+		return reports[i].Package < reports[j].Package
+	})
+	return reports
+}
+
+// printNative writes reports to w as a table, or as JSON.
+func printNative(w io.Writer, reports []nativePackage, asJSON bool) error {
+	if asJSON {
+		raw := make([]rawNativePackage, 0, len(reports))
+		for _, n := range reports {
+			raw = append(raw, n.marshalJSON())
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(raw)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tPACKAGE\tNATIVE")
+	for _, n := range reports {
+		if n.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\tunknown\n", n.ProjectRoot)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", n.ProjectRoot, n.Package, strings.Join(n.Native, ","))
+	}
+	return tw.Flush()
+}
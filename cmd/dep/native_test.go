@@ -0,0 +1,73 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/pkg/errors"
+)
+
+// fakePackageLister lists the packages of projects from fixed package trees.
+type fakePackageLister map[gps.ProjectRoot]pkgtree.PackageTree
+
+func (f fakePackageLister) ListPackages(id gps.ProjectIdentifier, v gps.Version) (pkgtree.PackageTree, error) {
+	ptree, ok := f[id.ProjectRoot]
+	if !ok {
+		return pkgtree.PackageTree{}, errors.New("no such source")
+	}
+	return ptree, nil
+}
+
+func TestAssessNative(t *testing.T) {
+	pkg := func(ip string, native ...string) pkgtree.PackageOrErr {
+		return pkgtree.PackageOrErr{P: pkgtree.Package{ImportPath: ip, Native: native}}
+	}
+	pl := fakePackageLister{
+		"github.com/sqlite/lib": {
+			ImportRoot: "github.com/sqlite/lib",
+			Packages: map[string]pkgtree.PackageOrErr{
+				"github.com/sqlite/lib":          pkg("github.com/sqlite/lib", pkgtree.NativeCgo),
+				"github.com/sqlite/lib/internal": pkg("github.com/sqlite/lib/internal", pkgtree.NativeCgo, pkgtree.NativeAsm),
+				"github.com/sqlite/lib/unused":   pkg("github.com/sqlite/lib/unused", pkgtree.NativeSwig),
+			},
+		},
+		"github.com/pure/lib": {
+			ImportRoot: "github.com/pure/lib",
+			Packages: map[string]pkgtree.PackageOrErr{
+				"github.com/pure/lib": pkg("github.com/pure/lib"),
+			},
+		},
+	}
+	rev := gps.Revision("d4a1a8e2a4f50f8b4e610b7ab3e8b3a4a4b6c0de")
+	lps := []gps.LockedProject{
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/sqlite/lib"}, rev, []string{".", "internal"}),
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/pure/lib"}, rev, []string{"."}),
+		gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/missing/lib"}, rev, []string{"."}),
+	}
+
+	reports := assessNative(lps, pl)
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 reports, got %d", len(reports))
+	}
+	if reports[0].ProjectRoot != "github.com/missing/lib" || reports[0].Err == nil {
+		t.Errorf("expected an error for the missing project, got %+v", reports[0])
+	}
+	if reports[1].Package != "github.com/sqlite/lib" || reports[2].Package != "github.com/sqlite/lib/internal" {
+		t.Errorf("unexpected packages %q and %q", reports[1].Package, reports[2].Package)
+	}
+
+	var buf bytes.Buffer
+	if err := printNative(&buf, reports, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "github.com/sqlite/lib/internal  cgo,asm") {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// netFailureKind is a kind of failure to reach a host.
+type netFailureKind int
+
+const (
+	netDNS netFailureKind = iota
+	netTLS
+	netAuth
+	netTimeout
+	netConnection
+	numNetFailureKinds
+)
+
+// netFailureKinds names the kinds of failure, and lists the phrases of git, hg,
+// bzr, svn, ssh and Go's net/http by which each is told, in lower case. A
+// failure is of the first kind that it has a phrase of.
+var netFailureKinds = [numNetFailureKinds]struct {
+	name    string
+	phrases []string
+}{
+	netDNS: {"DNS", []string{
+		"could not resolve host", "unable to look up", "no such host", "name or service not known",
+		"temporary failure in name resolution", "nodename nor servname",
+	}},
+	netTLS: {"TLS", []string{
+		"ssl certificate problem", "certificate verify failed", "server certificate verification failed",
+		"x509:", "tls:", "ssl_error", "gnutls_handshake",
+	}},
+	netAuth: {"AUTH", []string{
+		"authentication failed", "permission denied (publickey", "could not read username",
+		"could not read password", "terminal prompts disabled", "host key verification failed",
+		"401 unauthorized", "403 forbidden", "access denied",
+	}},
+	netTimeout: {"TIMEOUT", []string{"timed out", "timeout", "deadline exceeded"}},
+	netConnection: {"CONNECTION", []string{
+		"connection refused", "connection reset", "network is unreachable", "no route to host",
+	}},
+}
+
+// sourceHostPattern matches the sources of projects in error messages, as URLs
+// or scp-like ssh addresses, capturing their hosts.
+var sourceHostPattern = regexp.MustCompile(`[a-z][a-z0-9+.-]*://(?:[^\s/@'"]+@)?([^\s/:'"]+)|\b[\w.-]+@([\w-]+(?:\.[\w-]+)+):`)
+
+// netDiagnosis counts the failures to reach each host.
+type netDiagnosis struct {
+	counts map[string]*[numNetFailureKinds]int
+}
+
+// diagnoseNetworkFailures finds the failures to reach hosts in msg, the
+// message of an error. Each error listed in msg, on a line of its own that
+// starts with a tab, is taken to be a failure to reach the first host it
+// names. It returns nil if there are no such failures.
+func diagnoseNetworkFailures(msg string) *netDiagnosis {
+	d := &netDiagnosis{counts: make(map[string]*[numNetFailureKinds]int)}
+	for _, item := range strings.Split(msg, "\n\t") {
+		m := sourceHostPattern.FindStringSubmatch(item)
+		if m == nil {
+			continue
+		}
+		host := m[1]
+		if host == "" {
+			host = m[2]
+		}
+		host = strings.ToLower(host)
+		if kind, ok := classifyNetFailure(item); ok {
+			if d.counts[host] == nil {
+				d.counts[host] = new([numNetFailureKinds]int)
+			}
+			d.counts[host][kind]++
+		}
+	}
+	if len(d.counts) == 0 {
+		return nil
+	}
+	return d
+}
+
+// classifyNetFailure returns the kind of failure that s describes, if any.
+func classifyNetFailure(s string) (netFailureKind, bool) {
+	s = strings.ToLower(s)
+	for kind, k := range netFailureKinds {
+		for _, phrase := range k.phrases {
+			if strings.Contains(s, phrase) {
+				return netFailureKind(kind), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// hosts returns the hosts with failures of kind, in order.
+func (d *netDiagnosis) hosts(kind netFailureKind) []string {
+	var hosts []string
+	for host, counts := range d.counts {
+		if counts[kind] > 0 {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// write writes a table of the failures by host and kind to w, followed by
+// their likely causes. proxy names the variable in which a proxy is set, if
+// any.
+func (d *netDiagnosis) write(w io.Writer, proxy string) error {
+	var all []string
+	for host := range d.counts {
+		all = append(all, host)
+	}
+	sort.Strings(all)
+
+	fmt.Fprintln(w, "Network failures, by host:")
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, "  HOST")
+	for _, k := range netFailureKinds {
+		fmt.Fprintf(tw, "\t%s", k.name)
+	}
+	fmt.Fprintln(tw)
+	for _, host := range all {
+		fmt.Fprintf(tw, "  %s", host)
+		for _, n := range d.counts[host] {
+			fmt.Fprintf(tw, "\t%d", n)
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Likely causes:")
+	fmt.Fprintln(w)
+	for kind := netFailureKind(0); kind < numNetFailureKinds; kind++ {
+		hosts := d.hosts(kind)
+		if len(hosts) == 0 {
+			continue
+		}
+		list := strings.Join(hosts, ", ")
+		var cause string
+		switch kind {
+		case netDNS:
+			if proxy == "" {
+				cause = fmt.Sprintf("%s could not be resolved. If this network only reaches the internet through a proxy, set HTTPS_PROXY and HTTP_PROXY to it.", list)
+			} else {
+				cause = fmt.Sprintf("%s could not be resolved, even though %s is set. Check the host names, and that the proxy can be reached.", list, proxy)
+			}
+		case netTLS:
+			cause = fmt.Sprintf("TLS with %s failed. A proxy or firewall that intercepts TLS needs its CA certificate installed on this machine; otherwise, the certificates of the hosts are not valid.", list)
+		case netAuth:
+			cause = fmt.Sprintf("%s refused access: credentials are likely missing. Configure a git credential helper or ~/.netrc for https sources, or load an ssh key with ssh-add for ssh ones.", list)
+		case netTimeout:
+			cause = fmt.Sprintf("Requests to %s timed out. The hosts may be blocked by a firewall, or slow; a proxy may be needed, or a longer $DEPVCSTIMEOUT.", list)
+		case netConnection:
+			cause = fmt.Sprintf("Connections to %s failed. The hosts are likely blocked by a firewall, and a proxy may be needed.", list)
+		}
+		fmt.Fprintf(w, "  * %s\n", cause)
+	}
+	return nil
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimRight(s[:i], ": \t")
+	}
+	return s
+}
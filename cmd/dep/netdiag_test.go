@@ -0,0 +1,65 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiagnoseNetworkFailures(t *testing.T) {
+	msg := `Solving failure: No versions of github.com/sdboyer/deptest met constraints:
+	master: unable to update checked out version: failed to list versions for:
+	(1) failed to list versions for https://github.com/sdboyer/deptest: fatal: unable to access 'https://github.com/sdboyer/deptest/': Could not resolve host: github.com
+: exit status 128
+	(2) failed to list versions for ssh://git@github.com/sdboyer/deptest: ssh: Could not resolve hostname github.com: Name or service not known
+fatal: Could not read from remote repository.
+: exit status 128
+	(3) failed to list versions for https://git.example.com/team/private: fatal: could not read Username for 'https://git.example.com': terminal prompts disabled
+: exit status 128
+	(4) unable to deduce repository and source type for "golang.org/x/net": unable to read metadata: Get "https://golang.org/x/net?go-get=1": net/http: TLS handshake timeout
+	(5) failed to list versions for git@bitbucket.org:team/repo.git: ssh: connect to host bitbucket.org port 22: Connection refused
+	(6) failed to list versions for https://github.com/sdboyer/other: fatal: invalid reference
+`
+	d := diagnoseNetworkFailures(msg)
+	if d == nil {
+		t.Fatal("expected network failures to be found")
+	}
+	want := map[string][numNetFailureKinds]int{
+		"github.com":      {netDNS: 2},
+		"git.example.com": {netAuth: 1},
+		"golang.org":      {netTimeout: 1},
+		"bitbucket.org":   {netConnection: 1},
+	}
+	if len(d.counts) != len(want) {
+		t.Errorf("expected failures on %d hosts, got %v", len(want), d.counts)
+	}
+	for host, counts := range want {
+		if got := d.counts[host]; got == nil || *got != counts {
+			t.Errorf("%s: expected %v, got %v", host, counts, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := d.write(&buf, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"  HOST             DNS  TLS  AUTH  TIMEOUT  CONNECTION\n  bitbucket.org    0    0    0     0        1\n",
+		"github.com could not be resolved. If this network only reaches the internet through a proxy",
+		"git.example.com refused access",
+		"Requests to golang.org timed out",
+		"Connections to bitbucket.org failed",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected the diagnosis to contain %q, got:\n%s", s, buf.String())
+		}
+	}
+
+	if d := diagnoseNetworkFailures("no versions of github.com/a/b met constraints: v1.0.0: not allowed"); d != nil {
+		t.Errorf("expected no network failures, got %v", d.counts)
+	}
+}
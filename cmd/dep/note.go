@@ -0,0 +1,144 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const noteShortHelp = `Record or list notes about a dependency`
+const noteLongHelp = `
+Record a note about <project>, a dependency in Gopkg.lock, or with no text and
+no flags, list the notes recorded about it.
+
+Notes keep the review trail of each dependency next to the code: remarks from
+its review, links to an issue or a design document, and records of its
+approval. They are recorded as [[note]] tables in Gopkg.notes.toml, beside
+Gopkg.toml and Gopkg.lock, together with the day, who recorded them and the
+version the project was locked to at the time. dep status -detail shows them.
+
+Flags:
+
+  -link      A link to record with the note
+  -approve   Record the note as an approval of the locked version
+  -by        Who records the note (default: the current user)
+`
+
+type noteCommand struct {
+	link    string
+	approve bool
+	by      string
+}
+
+func (cmd *noteCommand) Name() string      { return "note" }
+func (cmd *noteCommand) Args() string      { return "[-link url] [-approve] [-by name] <project> [text]" }
+func (cmd *noteCommand) ShortHelp() string { return noteShortHelp }
+func (cmd *noteCommand) LongHelp() string  { return noteLongHelp }
+func (cmd *noteCommand) Hidden() bool      { return false }
+
+func (cmd *noteCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.link, "link", "", "a link to record with the note")
+	fs.BoolVar(&cmd.approve, "approve", false, "record the note as an approval of the locked version")
+	fs.StringVar(&cmd.by, "by", "", "who records the note (default: the current user)")
+}
+
+func (cmd *noteCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) == 0 {
+		return errors.New("note requires a project")
+	}
+
+	text := strings.Join(args[1:], " ")
+	list := text == "" && cmd.link == "" && !cmd.approve
+	if !list {
+		lock, err := ctx.LockProject(false)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("%s does not exist, cannot record notes about its projects", dep.LockName)
+	}
+
+	pr := gps.ProjectRoot(args[0])
+	var version string
+	for _, lp := range p.Lock.Projects() {
+		if lp.Ident().ProjectRoot == pr {
+			version = lp.Version().String()
+		}
+	}
+	if version == "" {
+		return errors.Errorf("%s is not in %s", pr, dep.LockName)
+	}
+
+	notes, err := dep.ReadNotes(p.AbsRoot)
+	if err != nil {
+		return err
+	}
+
+	if list {
+		var buf bytes.Buffer
+		writeNotes(&buf, notes.For(pr))
+		ctx.Out.Print(buf.String())
+		return nil
+	}
+
+	by := cmd.by
+	if by == "" {
+		if u, err := user.Current(); err == nil {
+			by = u.Username
+		}
+	}
+	notes.N = append(notes.N, dep.Note{
+		Project:  pr,
+		Date:     time.Now().Format("2006-01-02"),
+		By:       by,
+		Version:  version,
+		Text:     text,
+		Link:     cmd.link,
+		Approval: cmd.approve,
+	})
+	return dep.WriteNotes(p.AbsRoot, notes)
+}
+
+// writeNotes writes notes one per line, indented, as dep note and dep status
+// -detail list them.
+func writeNotes(w io.Writer, notes []dep.Note) {
+	for _, n := range notes {
+		fields := []string{n.Date}
+		if n.By != "" {
+			fields = append(fields, n.By)
+		}
+		if n.Version != "" {
+			fields = append(fields, "at "+n.Version)
+		}
+		line := strings.Join(fields, " ")
+		if n.Approval {
+			line += ": approved"
+		}
+		if n.Text != "" {
+			line += ": " + n.Text
+		}
+		if n.Link != "" {
+			line += " <" + n.Link + ">"
+		}
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
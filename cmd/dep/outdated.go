@@ -0,0 +1,378 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const outdatedShortHelp = `List the dependencies that have newer versions`
+const outdatedLongHelp = `
+List the projects in Gopkg.lock that have newer versions available: the newest
+version that satisfies the constraint in Gopkg.toml, and the newest of all,
+where that is different.
+
+Projects locked to a semver release are compared against newer semver
+releases. Projects locked to a branch are outdated if the branch has moved.
+Projects locked to other tags or to a bare revision are not listed.
+
+With -check-compat, a trial solve is run for each update, with only that
+project allowed to change and pinned to the new version as if by an
+[[override]]. Updates that solve are marked as such, so that it is known which
+updates are safe before trying them. Trial solves are run in parallel, -j at a
+time; they do not change Gopkg.lock or vendor/. With -v, the reason that each
+failing update does not solve is printed.
+
+With -o csv or -o tsv, the updates are written as a spreadsheet. -columns
+selects and orders the columns, which are project, locked, update, satisfies
+and, with -check-compat, solves.
+`
+
+type outdatedCommand struct {
+	json        bool
+	checkCompat bool
+	jobs        int
+	format      string
+	columns     string
+}
+
+func (cmd *outdatedCommand) Name() string { return "outdated" }
+func (cmd *outdatedCommand) Args() string {
+	return "[-json | -o csv|tsv [-columns <list>]] [-check-compat [-j <n>]]"
+}
+func (cmd *outdatedCommand) ShortHelp() string { return outdatedShortHelp }
+func (cmd *outdatedCommand) LongHelp() string  { return outdatedLongHelp }
+func (cmd *outdatedCommand) Hidden() bool      { return false }
+
+func (cmd *outdatedCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.json, "json", false, "output in JSON format")
+	fs.BoolVar(&cmd.checkCompat, "check-compat", false, "run a trial solve for each update to check that it solves")
+	fs.IntVar(&cmd.jobs, "j", runtime.NumCPU(), "number of trial solves to run at once")
+	fs.StringVar(&cmd.format, "o", "", "output as a spreadsheet, in csv or tsv format")
+	fs.StringVar(&cmd.columns, "columns", "", "comma-separated list of the columns to output with -o")
+}
+
+func (cmd *outdatedCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.New("outdated takes no arguments")
+	}
+	if cmd.jobs < 1 {
+		return errors.New("-j must be at least 1")
+	}
+	if cmd.format != "" {
+		if cmd.json {
+			return errors.New("cannot pass both -json and -o")
+		}
+		if _, err := selectColumns(outdatedHeader(cmd.checkCompat), cmd.columns); err != nil {
+			return err
+		}
+	} else if cmd.columns != "" {
+		return errors.New("-columns can only be used with -o")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("no %s found in %s, run dep ensure first", dep.LockName, p.AbsRoot)
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	var updates []outdatedUpdate
+	for _, lp := range p.Lock.Projects() {
+		pr := lp.Ident().ProjectRoot
+		avl, err := gps.ListAvailableVersions(sm, lp.Ident(), manifestConstraint(p.Manifest, pr))
+		if err != nil {
+			ctx.Err.Printf("Warning: could not list the versions of %s: %s\n", pr, err)
+			continue
+		}
+		updates = append(updates, updateCandidates(lp, avl)...)
+	}
+
+	if cmd.checkCompat && len(updates) > 0 {
+		checkUpdates(updates, cmd.jobs, func(u outdatedUpdate) error {
+			return trialUpdate(p, sm, u)
+		})
+		if ctx.Verbose {
+			for _, u := range updates {
+				if u.err != nil {
+					ctx.Err.Printf("%s@%s does not solve: %s\n", u.ProjectRoot, u.Version, u.err)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if cmd.format != "" {
+		err = printOutdatedDelimited(&buf, updates, cmd.checkCompat, cmd.format, cmd.columns)
+	} else {
+		err = printOutdated(&buf, updates, cmd.checkCompat, cmd.json)
+	}
+	if err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// manifestConstraint returns the constraint that m places on pr, or gps.Any()
+// if there is none.
+func manifestConstraint(m *dep.Manifest, pr gps.ProjectRoot) gps.Constraint {
+	if pp, has := m.Ovr[pr]; has && pp.Constraint != nil {
+		return pp.Constraint
+	}
+	if pp, has := m.Constraints[pr]; has && pp.Constraint != nil {
+		return pp.Constraint
+	}
+	return gps.Any()
+}
+
+// outdatedUpdate is a version that a locked project could be updated to.
+type outdatedUpdate struct {
+	ProjectRoot gps.ProjectRoot
+	Locked      gps.Version
+	Version     gps.PairedVersion
+	// Satisfies is true if Version satisfies the constraint on the project.
+	Satisfies bool
+
+	// checked and err are set by checkUpdates.
+	checked bool
+	err     error
+}
+
+// updateCandidates returns the updates available for lp, given the versions
+// of its project as listed by gps.ListAvailableVersions: the newest version
+// that satisfies the constraint, and the newest of all if that is different.
+func updateCandidates(lp gps.LockedProject, avl []gps.AvailableVersion) []outdatedUpdate {
+	lv := lp.Version()
+	update := func(av gps.AvailableVersion) outdatedUpdate {
+		return outdatedUpdate{
+			ProjectRoot: lp.Ident().ProjectRoot,
+			Locked:      lv,
+			Version:     av.Version,
+			Satisfies:   av.Satisfies,
+		}
+	}
+
+	pv, ok := lv.(gps.PairedVersion)
+	if !ok {
+		return nil
+	}
+
+	switch pv.Type() {
+	case gps.IsBranch:
+		for _, av := range avl {
+			if isLockedVersion(lv, av.Version) && av.Version.Revision() != pv.Revision() {
+				return []outdatedUpdate{update(av)}
+			}
+		}
+	case gps.IsSemver:
+		// The list is in upgrade order, so the newer releases are those
+		// before the locked one.
+		var updates []outdatedUpdate
+		for _, av := range avl {
+			if isLockedVersion(lv, av.Version) {
+				break
+			}
+			if av.Version.Type() != gps.IsSemver {
+				continue
+			}
+			if len(updates) == 0 || (av.Satisfies && !updates[0].Satisfies) {
+				updates = append(updates, update(av))
+			}
+			if av.Satisfies {
+				break
+			}
+		}
+		// List the newest that satisfies the constraint first.
+		if len(updates) == 2 {
+			updates[0], updates[1] = updates[1], updates[0]
+		}
+		return updates
+	}
+	return nil
+}
+
+// checkUpdates runs solve for each of updates, at most jobs at a time, and
+// records the results in updates.
+func checkUpdates(updates []outdatedUpdate, jobs int, solve func(outdatedUpdate) error) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, jobs)
+	for i := range updates {
+		wg.Add(1)
+		go func(u *outdatedUpdate) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			u.err = solve(*u)
+			u.checked = true
+		}(&updates[i])
+	}
+	wg.Wait()
+}
+
+// trialUpdate solves p with only the project of u allowed to change, and
+// pinned to u.Version.
+func trialUpdate(p *dep.Project, sm gps.SourceManager, u outdatedUpdate) error {
+	params := p.MakeParams()
+	params.Manifest = pinnedManifest{
+		RootManifest: p.Manifest,
+		root:         u.ProjectRoot,
+		pp: gps.ProjectProperties{
+			Source:     sourceOf(p, u.ProjectRoot),
+			Constraint: u.Version.Unpair(),
+		},
+	}
+	params.ToChange = []gps.ProjectRoot{u.ProjectRoot}
+
+	solver, err := gps.Prepare(params, sm)
+	if err != nil {
+		return errors.Wrap(err, "prepare")
+	}
+	_, err = solver.Solve(context.TODO())
+	return err
+}
+
+// sourceOf returns the source of pr in the lock of p.
+func sourceOf(p *dep.Project, pr gps.ProjectRoot) string {
+	for _, lp := range p.Lock.Projects() {
+		if lp.Ident().ProjectRoot == pr {
+			return lp.Ident().Source
+		}
+	}
+	return ""
+}
+
+// pinnedManifest is a root manifest with an additional override, that pins
+// one project to a version.
+type pinnedManifest struct {
+	gps.RootManifest
+	root gps.ProjectRoot
+	pp   gps.ProjectProperties
+}
+
+func (m pinnedManifest) Overrides() gps.ProjectConstraints {
+	ovr := make(gps.ProjectConstraints)
+	for pr, pp := range m.RootManifest.Overrides() {
+		ovr[pr] = pp
+	}
+	ovr[m.root] = m.pp
+	return ovr
+}
+
+type rawOutdatedUpdate struct {
+	Name      string `json:"name"`
+	Locked    string `json:"locked"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Satisfies bool   `json:"satisfies"`
+	Solves    *bool  `json:"solves,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (u outdatedUpdate) marshalJSON() rawOutdatedUpdate {
+	raw := rawOutdatedUpdate{
+		Name:      string(u.ProjectRoot),
+		Locked:    u.Locked.String(),
+		Version:   u.Version.String(),
+		Revision:  u.Version.Revision().String(),
+		Satisfies: u.Satisfies,
+	}
+	if u.checked {
+		solves := u.err == nil
+		raw.Solves = &solves
+		if u.err != nil {
+			raw.Error = u.err.Error()
+		}
+	}
+	return raw
+}
+
+// printOutdated writes updates to w, as a table or as JSON. The SOLVES column
+// is only written if checked is true.
+func printOutdated(w io.Writer, updates []outdatedUpdate, checked, asJSON bool) error {
+	if asJSON {
+		raw := make([]rawOutdatedUpdate, 0, len(updates))
+		for _, u := range updates {
+			raw = append(raw, u.marshalJSON())
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(raw)
+	}
+
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := []string{"PROJECT", "LOCKED", "UPDATE", "SATISFIES"}
+	if checked {
+		header = append(header, "SOLVES")
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, u := range updates {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s", u.ProjectRoot, formatVersion(u.Locked), formatVersion(u.Version), yesNo(u.Satisfies))
+		if checked {
+			fmt.Fprintf(tw, "\t%s", yesNo(u.err == nil))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// outdatedHeader returns the columns of the updates written as a spreadsheet.
+// solves is only included if checked is true.
+func outdatedHeader(checked bool) []string {
+	header := []string{"project", "locked", "update", "satisfies"}
+	if checked {
+		header = append(header, "solves")
+	}
+	return header
+}
+
+// printOutdatedDelimited writes the columns of updates named in columns to w,
+// in format, as by newDelimitedWriter.
+func printOutdatedDelimited(w io.Writer, updates []outdatedUpdate, checked bool, format, columns string) error {
+	dw, err := newDelimitedWriter(w, format, outdatedHeader(checked), columns)
+	if err != nil {
+		return err
+	}
+	for _, u := range updates {
+		row := []string{string(u.ProjectRoot), formatVersion(u.Locked), formatVersion(u.Version), strconv.FormatBool(u.Satisfies)}
+		if checked {
+			row = append(row, strconv.FormatBool(u.err == nil))
+		}
+		if err := dw.write(row); err != nil {
+			return err
+		}
+	}
+	return dw.flush()
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+func TestUpdateCandidates(t *testing.T) {
+	id := gps.ProjectIdentifier{ProjectRoot: "example.com/foo"}
+	avl := []gps.AvailableVersion{
+		{Version: gps.NewVersion("v2.0.0").Pair("rev4")},
+		{Version: gps.NewVersion("v1.2.0").Pair("rev3"), Satisfies: true},
+		{Version: gps.NewVersion("v1.1.0").Pair("rev2"), Satisfies: true},
+		{Version: gps.NewVersion("v1.0.0").Pair("rev1"), Satisfies: true},
+		{Version: gps.NewBranch("master").Pair("rev5"), DefaultBranch: true},
+	}
+	versions := func(updates []outdatedUpdate) string {
+		var s []string
+		for _, u := range updates {
+			s = append(s, u.Version.String())
+		}
+		return strings.Join(s, ",")
+	}
+
+	cases := []struct {
+		name   string
+		locked gps.Version
+		want   string
+	}{
+		{"older release", gps.NewVersion("v1.0.0").Pair("rev1"), "v1.2.0,v2.0.0"},
+		{"newest allowed release", gps.NewVersion("v1.2.0").Pair("rev3"), "v2.0.0"},
+		{"newest release", gps.NewVersion("v2.0.0").Pair("rev4"), ""},
+		{"moved branch", gps.NewBranch("master").Pair("rev0"), "master"},
+		{"branch at head", gps.NewBranch("master").Pair("rev5"), ""},
+		{"revision", gps.Revision("rev1"), ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := versions(updateCandidates(gps.NewLockedProject(id, c.locked, nil), avl))
+			if got != c.want {
+				t.Errorf("expected updates %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckUpdates(t *testing.T) {
+	updates := []outdatedUpdate{
+		{ProjectRoot: "example.com/foo", Version: gps.NewVersion("v1.1.0").Pair("rev1")},
+		{ProjectRoot: "example.com/bar", Version: gps.NewVersion("v2.0.0").Pair("rev2")},
+		{ProjectRoot: "example.com/baz", Version: gps.NewVersion("v1.0.0").Pair("rev3")},
+	}
+
+	var mu sync.Mutex
+	var running, most int
+	checkUpdates(updates, 2, func(u outdatedUpdate) error {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+
+		if u.ProjectRoot == "example.com/bar" {
+			return errors.New("no versions of example.com/bar met constraints")
+		}
+		return nil
+	})
+
+	if most > 2 {
+		t.Errorf("expected at most 2 trial solves at once, got %d", most)
+	}
+	for _, u := range updates {
+		if !u.checked {
+			t.Errorf("expected %s to be checked", u.ProjectRoot)
+		}
+		if solves := u.err == nil; solves != (u.ProjectRoot != "example.com/bar") {
+			t.Errorf("unexpected result for %s: %v", u.ProjectRoot, u.err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := printOutdated(&buf, updates, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "SOLVES") || !strings.Contains(buf.String(), "example.com/bar  ") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestPinnedManifest(t *testing.T) {
+	m := dep.NewManifest()
+	m.Ovr["example.com/bar"] = gps.ProjectProperties{Constraint: gps.NewBranch("master")}
+
+	pm := pinnedManifest{
+		RootManifest: m,
+		root:         "example.com/foo",
+		pp:           gps.ProjectProperties{Constraint: gps.NewVersion("v1.1.0")},
+	}
+	ovr := pm.Overrides()
+	if len(ovr) != 2 || ovr["example.com/foo"].Constraint.String() != "v1.1.0" {
+		t.Errorf("unexpected overrides: %v", ovr)
+	}
+	if len(m.Ovr) != 1 {
+		t.Errorf("expected the overrides of the manifest to be left alone, got %v", m.Ovr)
+	}
+}
+
+func TestPrintOutdatedDelimited(t *testing.T) {
+	updates := []outdatedUpdate{
+		{ProjectRoot: "example.com/foo", Locked: gps.NewVersion("v1.0.0").Pair("rev1"), Version: gps.NewVersion("v1.2.0").Pair("rev3"), Satisfies: true},
+		{ProjectRoot: "example.com/foo", Locked: gps.NewVersion("v1.0.0").Pair("rev1"), Version: gps.NewVersion("v2.0.0").Pair("rev4"), err: errors.New("conflict")},
+	}
+	var buf bytes.Buffer
+	if err := printOutdatedDelimited(&buf, updates, true, "csv", "project,update,solves"); err != nil {
+		t.Fatal(err)
+	}
+	want := "project,update,solves\nexample.com/foo,v1.2.0,true\nexample.com/foo,v2.0.0,false\n"
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+	if err := printOutdatedDelimited(&buf, updates, false, "csv", "solves"); err == nil {
+		t.Error("expected solves to be unknown without -check-compat")
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+// envImportPolicy names the import policy taken from the environment in
+// solve failures.
+const envImportPolicy = "$DEPALLOW and $DEPDENY"
+
+// splitPrefixList splits a comma-separated list of import path prefixes, as
+// given in $DEPALLOW and $DEPDENY.
+func splitPrefixList(s string) []string {
+	var prefixes []string
+	for _, prefix := range strings.Split(s, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// applyImportPolicy adds the import policy configured in the environment, if
+// any, to params, alongside any policy from the manifest. Organization-wide
+// lists in the environment therefore cannot be widened by a project.
+func applyImportPolicy(ctx *dep.Ctx, params *gps.SolveParameters) {
+	if len(ctx.ImportAllow) == 0 && len(ctx.ImportDeny) == 0 {
+		return
+	}
+	params.ImportPolicies = append(params.ImportPolicies, gps.ImportPolicy{
+		Source: envImportPolicy,
+		Allow:  ctx.ImportAllow,
+		Deny:   ctx.ImportDeny,
+	})
+}
@@ -0,0 +1,23 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPrefixList(t *testing.T) {
+	cases := map[string][]string{
+		"":                   nil,
+		"github.com/our-org": {"github.com/our-org"},
+		" github.com/our-org , gitlab.example.com,": {"github.com/our-org", "gitlab.example.com"},
+	}
+	for in, want := range cases {
+		if got := splitPrefixList(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitPrefixList(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// preflightTimeout bounds each probe of a host by dep ensure -preflight.
+var preflightTimeout = 10 * time.Second
+
+// defaultPorts are the ports of the schemes that sources are fetched by.
+var defaultPorts = map[string]string{
+	"https":   "443",
+	"http":    "80",
+	"ssh":     "22",
+	"git+ssh": "22",
+	"git":     "9418",
+	"bzr+ssh": "22",
+	"svn":     "3690",
+}
+
+// preflightAddr returns the address, as host:port, that the source of id is
+// fetched from, and whether it is fetched over the network at all. Sources
+// reached through a proxy, as proxy says, are fetched from the proxy.
+func preflightAddr(id gps.ProjectIdentifier, proxy func(*http.Request) (*url.URL, error)) (string, bool) {
+	s := id.Source
+	if s == "" {
+		s = string(id.ProjectRoot)
+	}
+
+	var u *url.URL
+	if pu, err := url.Parse(s); err == nil && pu.Scheme != "" && !strings.Contains(pu.Scheme, ".") {
+		if pu.Scheme == "file" || pu.Host == "" {
+			return "", false
+		}
+		u = pu
+	} else if i, j := strings.Index(s, "@"), strings.Index(s, ":"); i >= 0 && j > i {
+		// scp-like sources, as in git@github.com:foo/bar.git.
+		u = &url.URL{Scheme: "ssh", Host: s[i+1 : j]}
+	} else {
+		// An import path, whose host is asked for its source over https.
+		host := s
+		if i := strings.Index(host, "/"); i >= 0 {
+			host = host[:i]
+		}
+		u = &url.URL{Scheme: "https", Host: host}
+	}
+
+	if u.Scheme == "https" || u.Scheme == "http" {
+		if pu, err := proxy(&http.Request{URL: u}); err == nil && pu != nil {
+			u = pu
+		}
+	}
+	if u.Port() != "" {
+		return u.Host, true
+	}
+	port, has := defaultPorts[u.Scheme]
+	if !has {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port), true
+}
+
+// preflightAddrs returns the addresses that the sources of the projects in the
+// manifest and lock of p are fetched from, with the projects fetched from
+// each.
+func preflightAddrs(p *dep.Project, proxy func(*http.Request) (*url.URL, error)) map[string][]string {
+	addrs := make(map[string][]string)
+	seen := make(map[gps.ProjectIdentifier]bool)
+	add := func(id gps.ProjectIdentifier) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		if addr, ok := preflightAddr(id, proxy); ok {
+			addrs[addr] = append(addrs[addr], string(id.ProjectRoot))
+		}
+	}
+
+	if p.Lock != nil {
+		for _, lp := range p.Lock.Projects() {
+			add(lp.Ident())
+		}
+	}
+	for _, pcs := range []gps.ProjectConstraints{p.Manifest.Constraints, p.Manifest.Ovr} {
+		for pr, pp := range pcs {
+			add(gps.ProjectIdentifier{ProjectRoot: pr, Source: pp.Source})
+		}
+	}
+	for _, projects := range addrs {
+		sort.Strings(projects)
+	}
+	return addrs
+}
+
+// probeAddr resolves the host of addr and connects to it.
+func probeAddr(ctx context.Context, addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		return err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeFailureKind returns the kind of failure that err, returned by probeAddr,
+// is.
+func probeFailureKind(err error) netFailureKind {
+	if _, ok := err.(*net.DNSError); ok {
+		return netDNS
+	}
+	if err == context.DeadlineExceeded {
+		return netTimeout
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return netTimeout
+	}
+	return netConnection
+}
+
+// preflight probes every host that the projects in the manifest and lock of p
+// are fetched from, so that dep ensure can fail at once, with a report of all
+// the hosts that cannot be reached, rather than part way through solving.
+func preflight(ctx *dep.Ctx, p *dep.Project, probe func(context.Context, string) error) error {
+	addrs := preflightAddrs(p, http.ProxyFromEnvironment)
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		diag = &netDiagnosis{counts: make(map[string]*[numNetFailureKinds]int)}
+	)
+	for addr, projects := range addrs {
+		wg.Add(1)
+		go func(addr string, projects []string) {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+			defer cancel()
+			err := probe(pctx, addr)
+			if err == nil {
+				return
+			}
+			if ctx.Verbose {
+				ctx.Err.Printf("Preflight: %s, needed by %s: %v\n", addr, strings.Join(projects, ", "), err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			counts := new([numNetFailureKinds]int)
+			counts[probeFailureKind(err)] = len(projects)
+			diag.counts[addr] = counts
+		}(addr, projects)
+	}
+	wg.Wait()
+
+	if len(diag.counts) == 0 {
+		if ctx.Verbose {
+			ctx.Err.Printf("Preflight: all %d hosts can be reached\n", len(addrs))
+		}
+		return nil
+	}
+	if err := diag.write(ctx.Err.Writer(), proxyEnvVar(os.Environ())); err != nil {
+		return err
+	}
+	ctx.Err.Println()
+	return errors.Errorf("preflight: %d of %d hosts could not be reached", len(diag.counts), len(addrs))
+}
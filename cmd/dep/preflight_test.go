@@ -0,0 +1,104 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+func TestPreflightAddrs(t *testing.T) {
+	proxy := func(r *http.Request) (*url.URL, error) {
+		if r.URL.Hostname() == "corp.example" {
+			return url.Parse("http://proxy.corp.example:3128")
+		}
+		return nil, nil
+	}
+	p := &dep.Project{
+		Manifest: &dep.Manifest{
+			Constraints: gps.ProjectConstraints{
+				"github.com/foo/bar":  {Source: "git@github.com:foo/bar.git"},
+				"corp.example/tool":   {},
+				"example.com/vanity":  {Source: "https://git.example.com:8443/vanity"},
+				"example.com/onlocal": {Source: "file:///srv/repos/onlocal"},
+			},
+			Ovr: gps.ProjectConstraints{
+				"golang.org/x/net": {},
+			},
+		},
+		Lock: &dep.Lock{P: []gps.LockedProject{
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/foo/baz"}, gps.Revision("abc"), nil),
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "golang.org/x/net"}, gps.Revision("def"), nil),
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "example.com/svn", Source: "svn://svn.example.com/repo"}, gps.Revision("1"), nil),
+		}},
+	}
+
+	got := preflightAddrs(p, proxy)
+	want := map[string][]string{
+		"github.com:22":           {"github.com/foo/bar"},
+		"github.com:443":          {"github.com/foo/baz"},
+		"golang.org:443":          {"golang.org/x/net"},
+		"proxy.corp.example:3128": {"corp.example/tool"},
+		"git.example.com:8443":    {"example.com/vanity"},
+		"svn.example.com:3690":    {"example.com/svn"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected addresses:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	p := &dep.Project{
+		Manifest: dep.NewManifest(),
+		Lock: &dep.Lock{P: []gps.LockedProject{
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "good.example/a", Source: "git@good.example:a.git"}, gps.Revision("a"), nil),
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "bad.example/b", Source: "ssh://bad.example/b"}, gps.Revision("b"), nil),
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "bad.example/c", Source: "ssh://bad.example/c"}, gps.Revision("c"), nil),
+		}},
+	}
+	var (
+		mu     sync.Mutex
+		probed []string
+	)
+	probe := func(ctx context.Context, addr string) error {
+		mu.Lock()
+		probed = append(probed, addr)
+		mu.Unlock()
+		if strings.HasPrefix(addr, "bad.example") {
+			return &net.DNSError{Err: "no such host", Name: "bad.example"}
+		}
+		return nil
+	}
+
+	var errBuf bytes.Buffer
+	ctx := &dep.Ctx{Err: log.New(&errBuf, "", 0)}
+	err := preflight(ctx, p, probe)
+	if err == nil || err.Error() != "preflight: 1 of 2 hosts could not be reached" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(probed) != 2 {
+		t.Errorf("expected each host to be probed once, got %v", probed)
+	}
+	out := errBuf.String()
+	for _, want := range []string{"bad.example:22", "could not be resolved"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the report to mention %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "good.example") {
+		t.Errorf("expected the report not to mention reachable hosts, got:\n%s", out)
+	}
+}
@@ -47,6 +47,12 @@ func (cmd *pruneCommand) Run(ctx *dep.Ctx, args []string) error {
 	ctx.Err.Printf("\nNow is the time to update your Gopkg.toml and remove `dep prune` from any scripts.\n")
 	ctx.Err.Printf("\nFor more information, see: https://golang.github.io/dep/docs/Gopkg.toml.html#prune\n")
 
+	lock, err := ctx.LockProject(false)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	p, err := ctx.LoadProject()
 	if err != nil {
 		return err
@@ -61,7 +67,7 @@ func (cmd *pruneCommand) Run(ctx *dep.Ctx, args []string) error {
 
 	// While the network churns on ListVersions() requests, statically analyze
 	// code from the current project.
-	ptree, err := pkgtree.ListPackages(p.ResolvedAbsRoot, string(p.ImportRoot))
+	ptree, err := pkgtree.ListPackagesForPlatforms(p.ResolvedAbsRoot, string(p.ImportRoot), p.Manifest.Platforms)
 	if err != nil {
 		return errors.Wrap(err, "analysis of local packages failed: %v")
 	}
@@ -94,7 +100,9 @@ func pruneProject(p *dep.Project, sm gps.SourceManager, logger *log.Logger) erro
 	defer os.RemoveAll(td)
 
 	onWrite := func(progress gps.WriteProgress) {
-		logger.Println(progress)
+		if progress.Stage == gps.WriteExported {
+			logger.Println(progress)
+		}
 	}
 	if err := gps.WriteDepTree(td, p.Lock, sm, gps.CascadingPruneOptions{DefaultOptions: gps.PruneNestedVendorDirs}, onWrite); err != nil {
 		return err
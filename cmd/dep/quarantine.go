@@ -0,0 +1,150 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const approveShortHelp = `Approve projects for addition to Gopkg.lock under quarantine`
+const approveLongHelp = `
+Record in Gopkg.toml that each <project> has been reviewed, and may be added to
+Gopkg.lock.
+
+When Gopkg.toml sets "quarantine = true", dep refuses to add any project to
+Gopkg.lock that is not already there, whether it is a direct or a transitive
+dependency, until it has been approved. This prevents new dependencies from
+entering the project without review. dep ensure, dep check -fix and dep lock
+merge all fail, listing the projects awaiting approval, rather than write a
+lock containing them.
+
+Approvals are recorded as [[approved]] tables in Gopkg.toml. Once a project is
+in Gopkg.lock it is no longer quarantined, and its approval may be removed.
+`
+
+type approveCommand struct{}
+
+func (cmd *approveCommand) Name() string      { return "approve" }
+func (cmd *approveCommand) Args() string      { return "<project>..." }
+func (cmd *approveCommand) ShortHelp() string { return approveShortHelp }
+func (cmd *approveCommand) LongHelp() string  { return approveLongHelp }
+func (cmd *approveCommand) Hidden() bool      { return false }
+
+func (cmd *approveCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *approveCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) == 0 {
+		return errors.New("approve requires at least one project")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	approved := make(map[gps.ProjectRoot]bool, len(p.Manifest.Approved))
+	for _, name := range p.Manifest.Approved {
+		approved[gps.ProjectRoot(name)] = true
+	}
+
+	appender := dep.NewManifest()
+	for _, arg := range args {
+		pr, err := sm.DeduceProjectRoot(arg)
+		if err != nil {
+			return errors.Wrapf(err, "could not infer project root from %s", arg)
+		}
+		if string(pr) != arg {
+			return errors.Errorf("%s is not a project root, try %s instead", arg, pr)
+		}
+		if approved[pr] {
+			continue
+		}
+		approved[pr] = true
+		appender.Approved = append(appender.Approved, string(pr))
+	}
+
+	if len(appender.Approved) == 0 {
+		return nil
+	}
+	if !p.Manifest.Quarantine {
+		ctx.Err.Printf("Warning: quarantine is not enabled in %s, so approvals have no effect\n", dep.ManifestName)
+	}
+
+	extra, err := appender.MarshalTOML()
+	if err != nil {
+		return errors.Wrap(err, "could not marshal manifest into TOML")
+	}
+
+	f, err := os.OpenFile(filepath.Join(p.AbsRoot, dep.ManifestName), os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "opening %s failed", dep.ManifestName)
+	}
+	if _, err := f.Write(extra); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "writing to %s failed", dep.ManifestName)
+	}
+	return errors.Wrapf(f.Close(), "closing %s", dep.ManifestName)
+}
+
+// quarantined returns the projects in newLock that are in neither oldLock nor
+// the manifest's list of approved projects, sorted by project root. It
+// returns nil if the manifest does not enable quarantine.
+func quarantined(m *dep.Manifest, oldLock, newLock gps.Lock) []gps.ProjectRoot {
+	if !m.Quarantine || newLock == nil {
+		return nil
+	}
+
+	allowed := make(map[gps.ProjectRoot]bool)
+	if oldLock != nil {
+		for _, lp := range oldLock.Projects() {
+			allowed[lp.Ident().ProjectRoot] = true
+		}
+	}
+	for _, name := range m.Approved {
+		allowed[gps.ProjectRoot(name)] = true
+	}
+
+	var held []gps.ProjectRoot
+	for _, lp := range newLock.Projects() {
+		if pr := lp.Ident().ProjectRoot; !allowed[pr] {
+			held = append(held, pr)
+		}
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i] < held[j] })
+	return held
+}
+
+// checkQuarantine returns an error naming the projects that newLock would add
+// to oldLock without approval, if there are any.
+func checkQuarantine(m *dep.Manifest, oldLock, newLock gps.Lock) error {
+	held := quarantined(m, oldLock, newLock)
+	if len(held) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "quarantine: the following projects would be added to %s, but have not been approved:\n", dep.LockName)
+	for _, pr := range held {
+		fmt.Fprintf(&buf, "\t%s\n", pr)
+	}
+	buf.WriteString("Review them, and run \"dep approve <project>\" for each to allow them.")
+	return errors.New(buf.String())
+}
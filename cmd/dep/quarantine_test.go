@@ -0,0 +1,52 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+func TestQuarantined(t *testing.T) {
+	mkLock := func(roots ...string) *dep.Lock {
+		l := &dep.Lock{}
+		for _, pr := range roots {
+			l.P = append(l.P, gps.NewLockedProject(
+				gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(pr)},
+				gps.NewVersion("v1.0.0").Pair("ff2948a2ac8f538c4ecd55962e919d1e13e74baf"),
+				[]string{"."},
+			))
+		}
+		return l
+	}
+
+	m := dep.NewManifest()
+	m.Approved = []string{"github.com/approved/proj"}
+	oldLock := mkLock("github.com/locked/proj")
+	newLock := mkLock("github.com/locked/proj", "github.com/new/b", "github.com/approved/proj", "github.com/new/a")
+
+	if held := quarantined(m, oldLock, newLock); held != nil {
+		t.Errorf("expected nothing to be held without quarantine, got %v", held)
+	}
+
+	m.Quarantine = true
+	want := []gps.ProjectRoot{"github.com/new/a", "github.com/new/b"}
+	if held := quarantined(m, oldLock, newLock); !reflect.DeepEqual(held, want) {
+		t.Errorf("unexpected quarantined projects:\n\t(GOT): %v\n\t(WNT): %v", held, want)
+	}
+
+	var noLock *dep.Lock
+	want = []gps.ProjectRoot{"github.com/locked/proj"}
+	if held := quarantined(m, noLock, mkLock("github.com/locked/proj", "github.com/approved/proj")); !reflect.DeepEqual(held, want) {
+		t.Errorf("unexpected quarantined projects without a lock:\n\t(GOT): %v\n\t(WNT): %v", held, want)
+	}
+
+	if err := checkQuarantine(m, oldLock, oldLock); err != nil {
+		t.Errorf("unexpected error for an unchanged lock: %s", err)
+	}
+}
@@ -0,0 +1,630 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const reportShortHelp = `Write a dashboard of the dependencies of the project`
+const reportLongHelp = `
+Report writes a dashboard of the projects in Gopkg.lock to standard output, as
+a single HTML file with no external resources, suitable for attaching to
+release artifacts or publishing from CI:
+
+  dep report -o html > dependencies.html
+
+The dashboard holds:
+
+  * a table of the dependencies, with their locked versions and revisions,
+    their sources, and the updates available for them, as listed by
+    dep outdated;
+  * a summary of the licenses of the dependencies, recognized from the
+    LICENSE, COPYING and similar files at their roots;
+  * a treemap of the size of each dependency in vendor/;
+  * a graph of which projects import which.
+
+Licenses and sizes are read from vendor/. Dependencies missing from vendor/ are
+exported from their sources, at their locked revisions, and pruned as
+Gopkg.toml prescribes. Licenses are recognized from their text; those that
+are not are reported as "Other", and should be checked by hand.
+
+With -o csv or -o tsv, the table of dependencies, with the license and the
+size in bytes of each, is written as a spreadsheet instead, for compliance
+reviews. -columns selects and orders the columns, which are project, version,
+revision, source, update, latest, license, size and files.
+`
+
+type reportCommand struct {
+	format  string
+	columns string
+}
+
+func (cmd *reportCommand) Name() string      { return "report" }
+func (cmd *reportCommand) Args() string      { return "[-o html | -o csv|tsv [-columns <list>]]" }
+func (cmd *reportCommand) ShortHelp() string { return reportShortHelp }
+func (cmd *reportCommand) LongHelp() string  { return reportLongHelp }
+func (cmd *reportCommand) Hidden() bool      { return false }
+
+func (cmd *reportCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.format, "o", "html", "format of the report: html, csv or tsv")
+	fs.StringVar(&cmd.columns, "columns", "", "comma-separated list of the columns to output with -o csv or tsv")
+}
+
+func (cmd *reportCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.New("report takes no arguments")
+	}
+	switch {
+	case isDelimitedFormat(cmd.format):
+		if _, err := selectColumns(reportHeader, cmd.columns); err != nil {
+			return err
+		}
+	case cmd.format != "html":
+		return errors.Errorf("unsupported report format %q, must be html, csv or tsv", cmd.format)
+	case cmd.columns != "":
+		return errors.New("-columns can only be used with -o csv or tsv")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("no %s found in %s, run dep ensure first", dep.LockName, p.AbsRoot)
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	r, err := collectReport(ctx, p, sm)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if cmd.format == "html" {
+		err = writeHTMLReport(&buf, r)
+	} else {
+		err = writeDelimitedReport(&buf, r, cmd.format, cmd.columns)
+	}
+	if err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// report is the content of a dashboard.
+type report struct {
+	Root     string
+	Projects []reportProject
+	Licenses []reportLicense
+	Treemap  []treemapTile
+	Graph    reportGraph
+}
+
+// reportProject describes a locked project in a report.
+type reportProject struct {
+	ProjectRoot string
+	Version     string
+	Revision    string
+	Source      string
+	// Update is the newest version that satisfies the constraint on the
+	// project, and Latest the newest of all, if they are newer than Version.
+	Update, Latest string
+	// UpdatesUnknown is true if the versions of the project could not be
+	// listed.
+	UpdatesUnknown bool
+	License        string
+	Size           uint64
+	Files          int
+}
+
+// HumanSize returns the size of rp, made human-readable.
+func (rp reportProject) HumanSize() string { return humanByteSize(rp.Size) }
+
+// reportLicense is a license and the projects under it.
+type reportLicense struct {
+	License  string
+	Projects []string
+}
+
+// Outdated returns the number of projects in r with an update available.
+func (r report) Outdated() int {
+	var n int
+	for _, rp := range r.Projects {
+		if rp.Update != "" || rp.Latest != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// TotalSize returns the size of all the projects in r, made human-readable.
+func (r report) TotalSize() string {
+	var total uint64
+	for _, rp := range r.Projects {
+		total += rp.Size
+	}
+	return humanByteSize(total)
+}
+
+// collectReport gathers the report for p, listing the versions of its
+// dependencies with sm and exporting those missing from vendor.
+func collectReport(ctx *dep.Ctx, p *dep.Project, sm gps.SourceManager) (report, error) {
+	r := report{Root: string(p.ImportRoot)}
+
+	tmp, err := ioutil.TempDir("", "dep-report")
+	if err != nil {
+		return report{}, err
+	}
+	defer os.RemoveAll(tmp)
+
+	vendor := filepath.Join(p.AbsRoot, "vendor")
+	for _, lp := range p.Lock.Projects() {
+		id := lp.Ident()
+		rev, _, _ := gps.VersionComponentStrings(lp.Version())
+		rp := reportProject{
+			ProjectRoot: string(id.ProjectRoot),
+			Version:     formatVersion(lp.Version()),
+			Revision:    rev,
+			Source:      id.Source,
+		}
+
+		avl, err := gps.ListAvailableVersions(sm, id, manifestConstraint(p.Manifest, id.ProjectRoot))
+		if err != nil {
+			ctx.Err.Printf("Warning: could not list the versions of %s: %s\n", id.ProjectRoot, err)
+			rp.UpdatesUnknown = true
+		} else {
+			for _, u := range updateCandidates(lp, avl) {
+				if u.Satisfies {
+					rp.Update = formatVersion(u.Version)
+				} else {
+					rp.Latest = formatVersion(u.Version)
+				}
+			}
+		}
+
+		dir := filepath.Join(vendor, filepath.FromSlash(string(id.ProjectRoot)))
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			dir = filepath.Join(tmp, filepath.FromSlash(string(id.ProjectRoot)))
+			prune := p.Manifest.PruneOptions.PruneOptionsFor(id.ProjectRoot)
+			if err := sm.ExportPrunedProject(context.TODO(), lp, prune, dir); err != nil {
+				return report{}, errors.Wrapf(err, "could not export %s", id)
+			}
+		}
+		if rp.License, err = detectLicense(dir); err != nil {
+			return report{}, err
+		}
+		if rp.Size, rp.Files, err = dirUsage(dir); err != nil {
+			return report{}, errors.Wrapf(err, "could not measure %s", id)
+		}
+		r.Projects = append(r.Projects, rp)
+	}
+
+	export, err := exportGraph(p, sm, false)
+	if err != nil {
+		return report{}, err
+	}
+	r.Licenses = summarizeLicenses(r.Projects)
+	r.Treemap = layoutTreemap(r.Projects, treemapWidth, treemapHeight)
+	r.Graph = layoutProjectGraph(export, p.Lock.Projects())
+	return r, nil
+}
+
+// licensePatterns recognizes licenses by phrases from their text, lower-cased
+// and with runs of whitespace collapsed. They are tried in order, so that the
+// licenses that quote others come first.
+var licensePatterns = []struct {
+	license string
+	all     []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "may be used to endorse or promote products"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+}
+
+// isLicenseFile reports whether name is that of a file that holds a license.
+func isLicenseFile(name string) bool {
+	name = strings.ToLower(name)
+	for _, prefix := range []string{"license", "licence", "copying", "unlicense"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectLicense recognizes the licenses in the license files at the root of
+// dir. It returns "None" if there are none, and "Other" for those that are not
+// recognized; several licenses are joined by " AND ".
+func detectLicense(dir string) (string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var licenses []string
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || !isLicenseFile(fi.Name()) {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return "", err
+		}
+		text := strings.Join(strings.Fields(strings.ToLower(string(b))), " ")
+		license := "Other"
+		for _, lp := range licensePatterns {
+			matched := true
+			for _, phrase := range lp.all {
+				if !strings.Contains(text, phrase) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				license = lp.license
+				break
+			}
+		}
+		licenses = append(licenses, license)
+	}
+	if len(licenses) == 0 {
+		return "None", nil
+	}
+	return strings.Join(dedupeStrings(licenses), " AND "), nil
+}
+
+// summarizeLicenses groups projects by license, most common first.
+func summarizeLicenses(projects []reportProject) []reportLicense {
+	byLicense := make(map[string][]string)
+	for _, rp := range projects {
+		byLicense[rp.License] = append(byLicense[rp.License], rp.ProjectRoot)
+	}
+	summary := make([]reportLicense, 0, len(byLicense))
+	for license, roots := range byLicense {
+		sort.Strings(roots)
+		summary = append(summary, reportLicense{License: license, Projects: roots})
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if len(summary[i].Projects) != len(summary[j].Projects) {
+			return len(summary[i].Projects) > len(summary[j].Projects)
+		}
+		return summary[i].License < summary[j].License
+	})
+	return summary
+}
+
+// The treemap and graph are drawn at fixed sizes, in pixels.
+const (
+	treemapWidth, treemapHeight = 960, 400
+	graphNodeWidth              = 240
+	graphNodeHeight             = 24
+	graphColumnGap              = 80
+	graphRowGap                 = 12
+)
+
+// treemapTile is the rectangle of a project in a treemap.
+type treemapTile struct {
+	ProjectRoot string
+	Size        string
+	X, Y, W, H  float64
+	// Hue picks the color of the tile.
+	Hue int
+}
+
+// layoutTreemap lays the projects out in a w by h treemap, with the area of
+// each in proportion to its size, using the squarified algorithm of Bruls,
+// Huizing and van Wijk so that the tiles are as square as they can be.
+// Projects of no size are left out.
+func layoutTreemap(projects []reportProject, w, h float64) []treemapTile {
+	var sized []reportProject
+	var total float64
+	for _, rp := range projects {
+		if rp.Size > 0 {
+			sized = append(sized, rp)
+			total += float64(rp.Size)
+		}
+	}
+	sort.SliceStable(sized, func(i, j int) bool { return sized[i].Size > sized[j].Size })
+
+	areas := make([]float64, len(sized))
+	for i, rp := range sized {
+		areas[i] = float64(rp.Size) / total * w * h
+	}
+
+	tiles := make([]treemapTile, 0, len(sized))
+	x, y := 0.0, 0.0
+	for len(areas) > 0 {
+		// Rows are laid along the shorter side of the space left, and grow
+		// for as long as that makes their worst aspect ratio better.
+		side := math.Min(w, h)
+		n := 1
+		for n < len(areas) && worstAspect(areas[:n+1], side) <= worstAspect(areas[:n], side) {
+			n++
+		}
+		var sum float64
+		for _, a := range areas[:n] {
+			sum += a
+		}
+		thick := sum / side
+		var off float64
+		for i, a := range areas[:n] {
+			t := treemapTile{ProjectRoot: sized[i].ProjectRoot, Size: humanByteSize(sized[i].Size), Hue: len(tiles) * 47 % 360}
+			if w >= h {
+				t.X, t.Y, t.W, t.H = x, y+off, thick, a/thick
+			} else {
+				t.X, t.Y, t.W, t.H = x+off, y, a/thick, thick
+			}
+			off += a / thick
+			tiles = append(tiles, t)
+		}
+		if w >= h {
+			x, w = x+thick, w-thick
+		} else {
+			y, h = y+thick, h-thick
+		}
+		areas, sized = areas[n:], sized[n:]
+	}
+	return tiles
+}
+
+// worstAspect returns the worst aspect ratio among the tiles of a row of
+// areas laid along side.
+func worstAspect(areas []float64, side float64) float64 {
+	var sum, max float64
+	min := math.Inf(1)
+	for _, a := range areas {
+		sum += a
+		max = math.Max(max, a)
+		min = math.Min(min, a)
+	}
+	s2, sum2 := side*side, sum*sum
+	return math.Max(s2*max/sum2, sum2/(s2*min))
+}
+
+// reportGraph is the project-level import graph, laid out for drawing.
+type reportGraph struct {
+	Width, Height int
+	Nodes         []graphNode
+	Edges         []graphEdge
+}
+
+type graphNode struct {
+	Label string
+	X, Y  int
+	Root  bool
+}
+
+type graphEdge struct {
+	X1, Y1, X2, Y2 int
+}
+
+// layoutProjectGraph reduces the package import graph of export to which
+// projects import which, and lays it out in columns, by the length of the
+// shortest chain of imports from the current project.
+func layoutProjectGraph(export graphExport, lps []gps.LockedProject) reportGraph {
+	roots := make([]string, 0, len(lps))
+	for _, lp := range lps {
+		roots = append(roots, string(lp.Ident().ProjectRoot))
+	}
+	// The longest roots come first, so that the project of an import is the
+	// deepest one that holds it.
+	sort.Slice(roots, func(i, j int) bool { return len(roots[i]) > len(roots[j]) })
+	projectOf := func(ip string) string {
+		for _, r := range roots {
+			if isPathPrefix(ip, r) {
+				return r
+			}
+		}
+		return ""
+	}
+
+	edges := make(map[string][]string)
+	for _, gp := range export.Packages {
+		for _, imp := range gp.Imports {
+			if to := projectOf(imp); to != "" && to != gp.Project {
+				edges[gp.Project] = append(edges[gp.Project], to)
+			}
+		}
+	}
+	for from := range edges {
+		edges[from] = dedupeStrings(edges[from])
+	}
+
+	depth := map[string]int{export.Root: 0}
+	queue := []string{export.Root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, to := range edges[cur] {
+			if _, seen := depth[to]; !seen {
+				depth[to] = depth[cur] + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+	// Projects that are not reached, as when only their tests are, are put
+	// beside the direct dependencies.
+	for _, r := range roots {
+		if _, seen := depth[r]; !seen {
+			depth[r] = 1
+		}
+	}
+
+	var columns [][]string
+	for project, d := range depth {
+		for len(columns) <= d {
+			columns = append(columns, nil)
+		}
+		columns[d] = append(columns[d], project)
+	}
+
+	var g reportGraph
+	pos := make(map[string]graphNode)
+	for c, column := range columns {
+		sort.Strings(column)
+		for row, project := range column {
+			n := graphNode{
+				Label: project,
+				X:     c * (graphNodeWidth + graphColumnGap),
+				Y:     row * (graphNodeHeight + graphRowGap),
+				Root:  project == export.Root,
+			}
+			pos[project] = n
+			g.Nodes = append(g.Nodes, n)
+			if h := n.Y + graphNodeHeight; h > g.Height {
+				g.Height = h
+			}
+		}
+		g.Width = c*(graphNodeWidth+graphColumnGap) + graphNodeWidth
+	}
+
+	froms := make([]string, 0, len(edges))
+	for from := range edges {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		f, ok := pos[from]
+		if !ok {
+			continue
+		}
+		for _, to := range edges[from] {
+			t := pos[to]
+			g.Edges = append(g.Edges, graphEdge{
+				X1: f.X + graphNodeWidth, Y1: f.Y + graphNodeHeight/2,
+				X2: t.X, Y2: t.Y + graphNodeHeight/2,
+			})
+		}
+	}
+	return g
+}
+
+// writeHTMLReport writes r to w as a self-contained HTML page.
+func writeHTMLReport(w io.Writer, r report) error {
+	return reportTemplate.Execute(w, r)
+}
+
+// reportHeader is the columns of a report written as a spreadsheet.
+var reportHeader = []string{"project", "version", "revision", "source", "update", "latest", "license", "size", "files"}
+
+// writeDelimitedReport writes the columns of the projects in r named in
+// columns to w, in format, as by newDelimitedWriter.
+func writeDelimitedReport(w io.Writer, r report, format, columns string) error {
+	dw, err := newDelimitedWriter(w, format, reportHeader, columns)
+	if err != nil {
+		return err
+	}
+	for _, rp := range r.Projects {
+		update, latest := rp.Update, rp.Latest
+		if rp.UpdatesUnknown {
+			update, latest = "unknown", "unknown"
+		}
+		row := []string{rp.ProjectRoot, rp.Version, rp.Revision, rp.Source, update, latest, rp.License,
+			strconv.FormatUint(rp.Size, 10), strconv.Itoa(rp.Files)}
+		if err := dw.write(row); err != nil {
+			return err
+		}
+	}
+	return dw.flush()
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Dependencies of {{.Root}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.3em 0.8em; border-bottom: 1px solid #ddd; }
+code { font-size: 0.9em; }
+.update { color: #b35900; font-weight: bold; }
+.unknown { color: #888; }
+.treemap { position: relative; border: 1px solid #ccc; }
+.tile { position: absolute; box-sizing: border-box; border: 1px solid #fff; overflow: hidden; font-size: 0.75em; padding: 2px; }
+svg text { font-family: sans-serif; font-size: 11px; }
+</style>
+</head>
+<body>
+<h1>Dependencies of {{.Root}}</h1>
+<p>{{len .Projects}} dependencies, {{.Outdated}} with updates available, {{len .Licenses}} licenses, {{.TotalSize}} in vendor.</p>
+
+<h2>Dependencies</h2>
+<table>
+<tr><th>Project</th><th>Version</th><th>Revision</th><th>Update</th><th>License</th><th>Size</th></tr>
+{{- range .Projects}}
+<tr>
+<td>{{.ProjectRoot}}{{if .Source}}<br><small>from {{.Source}}</small>{{end}}</td>
+<td>{{.Version}}</td>
+<td><code>{{.Revision}}</code></td>
+<td>{{if .UpdatesUnknown}}<span class="unknown">unknown</span>{{else}}{{if .Update}}<span class="update">{{.Update}}</span>{{end}}{{if .Latest}}{{if .Update}}<br>{{end}}{{.Latest}} <small>(outside constraint)</small>{{end}}{{end}}</td>
+<td>{{.License}}</td>
+<td>{{.HumanSize}}, {{.Files}} files</td>
+</tr>
+{{- end}}
+</table>
+
+<h2>Licenses</h2>
+<table>
+<tr><th>License</th><th>Projects</th></tr>
+{{- range .Licenses}}
+<tr><td>{{.License}}</td><td>{{range $i, $p := .Projects}}{{if $i}}, {{end}}{{$p}}{{end}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Sizes</h2>
+<div class="treemap" style="width: 960px; height: 400px">
+{{- range .Treemap}}
+<div class="tile" title="{{.ProjectRoot}}: {{.Size}}" style="left: {{printf "%.1f" .X}}px; top: {{printf "%.1f" .Y}}px; width: {{printf "%.1f" .W}}px; height: {{printf "%.1f" .H}}px; background: hsl({{.Hue}}, 60%, 75%)">{{.ProjectRoot}}<br>{{.Size}}</div>
+{{- end}}
+</div>
+
+<h2>Graph</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Graph.Width}}" height="{{.Graph.Height}}">
+{{- range .Graph.Edges}}
+<line x1="{{.X1}}" y1="{{.Y1}}" x2="{{.X2}}" y2="{{.Y2}}" stroke="#999"/>
+{{- end}}
+{{- range .Graph.Nodes}}
+<rect x="{{.X}}" y="{{.Y}}" width="240" height="24" rx="4" fill="{{if .Root}}#cde{{else}}#eee{{end}}" stroke="#666"/>
+<text x="{{.X}}" y="{{.Y}}" dx="6" dy="16">{{.Label}}</text>
+{{- end}}
+</svg>
+</body>
+</html>
+`))
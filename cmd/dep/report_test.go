@@ -0,0 +1,150 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestDetectLicense(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"mit", map[string]string{"LICENSE": "MIT License\n\nPermission is hereby granted, free\nof charge, to any person"}, "MIT"},
+		{"apache", map[string]string{"LICENSE.txt": "Apache License\n  Version 2.0, January 2004"}, "Apache-2.0"},
+		{"bsd3", map[string]string{"LICENSE": "Redistribution and use in source and binary forms ... Neither the name of Google Inc. nor the names of its contributors may be used to endorse or promote products"}, "BSD-3-Clause"},
+		{"bsd2", map[string]string{"LICENSE": "Redistribution and use in source and binary forms, with or without modification"}, "BSD-2-Clause"},
+		{"lgpl", map[string]string{"COPYING.LESSER": "GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007\n... the GNU General Public License"}, "LGPL-3.0"},
+		{"dual", map[string]string{"LICENSE-MIT": "Permission is hereby granted, free of charge", "UNLICENSE": "This is free and unencumbered software released into the public domain."}, "MIT AND Unlicense"},
+		{"other", map[string]string{"LICENSE": "All rights reserved."}, "Other"},
+		{"none", map[string]string{"README.md": "Permission is hereby granted, free of charge"}, "None"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for name, contents := range c.files {
+				h.TempFile(filepath.Join(c.name, name), contents)
+			}
+			got, err := detectLicense(h.Path(c.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("expected %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestLayoutTreemap(t *testing.T) {
+	projects := []reportProject{
+		{ProjectRoot: "github.com/a/a", Size: 600},
+		{ProjectRoot: "github.com/b/b", Size: 0},
+		{ProjectRoot: "github.com/c/c", Size: 100},
+		{ProjectRoot: "github.com/d/d", Size: 300},
+	}
+	tiles := layoutTreemap(projects, 100, 50)
+	if len(tiles) != 3 {
+		t.Fatalf("expected projects of no size to be left out, got %v", tiles)
+	}
+
+	want := map[string]float64{"github.com/a/a": 3000, "github.com/d/d": 1500, "github.com/c/c": 500}
+	for _, tile := range tiles {
+		if a := tile.W * tile.H; math.Abs(a-want[tile.ProjectRoot]) > 1e-6 {
+			t.Errorf("%s: expected an area of %v, got %v", tile.ProjectRoot, want[tile.ProjectRoot], a)
+		}
+		if tile.X < 0 || tile.Y < 0 || tile.X+tile.W > 100+1e-6 || tile.Y+tile.H > 50+1e-6 {
+			t.Errorf("%s: tile %v is outside the treemap", tile.ProjectRoot, tile)
+		}
+	}
+	if tiles[0].ProjectRoot != "github.com/a/a" {
+		t.Errorf("expected the largest project to be laid out first, got %s", tiles[0].ProjectRoot)
+	}
+}
+
+func TestLayoutProjectGraph(t *testing.T) {
+	export := graphExport{
+		Root: "example.com/me",
+		Packages: []graphPackage{
+			{ImportPath: "example.com/me", Project: "example.com/me", Imports: []string{"fmt", "github.com/a/a", "github.com/a/a/sub"}},
+			{ImportPath: "github.com/a/a", Project: "github.com/a/a", Imports: []string{"github.com/a/a/sub", "github.com/b/b"}},
+			{ImportPath: "github.com/a/a/sub", Project: "github.com/a/a", Imports: []string{"github.com/b/b/c"}},
+			{ImportPath: "github.com/b/b/c", Project: "github.com/b/b"},
+		},
+	}
+	g := layoutProjectGraph(export, lockedProjects("github.com/a/a", "github.com/b/b", "github.com/unused/u"))
+
+	columns := make(map[string]int)
+	for _, n := range g.Nodes {
+		columns[n.Label] = n.X / (graphNodeWidth + graphColumnGap)
+	}
+	want := map[string]int{"example.com/me": 0, "github.com/a/a": 1, "github.com/unused/u": 1, "github.com/b/b": 2}
+	for label, c := range want {
+		if got, ok := columns[label]; !ok || got != c {
+			t.Errorf("expected %s in column %d, got %d (%v)", label, c, got, ok)
+		}
+	}
+	if len(g.Edges) != 2 {
+		t.Errorf("expected an edge from the project to a and from a to b, got %v", g.Edges)
+	}
+	if g.Width != 3*graphNodeWidth+2*graphColumnGap {
+		t.Errorf("expected the graph to be three columns wide, got %d", g.Width)
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	projects := []reportProject{
+		{ProjectRoot: "github.com/a/a", Version: "v1.0.0", Revision: "abc", Update: "v1.1.0", Latest: "v2.0.0", License: "MIT", Size: 2048, Files: 3},
+		{ProjectRoot: "github.com/b/<b>", Version: "branch master", License: "Other", UpdatesUnknown: true},
+	}
+	r := report{
+		Root:     "example.com/me",
+		Projects: projects,
+		Licenses: summarizeLicenses(projects),
+		Treemap:  layoutTreemap(projects, treemapWidth, treemapHeight),
+	}
+	var buf bytes.Buffer
+	if err := writeHTMLReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<title>Dependencies of example.com/me</title>",
+		"2 dependencies, 1 with updates available, 2 licenses, 2.0KB in vendor.",
+		`<span class="update">v1.1.0</span><br>v2.0.0 <small>(outside constraint)</small>`,
+		`<span class="unknown">unknown</span>`,
+		"github.com/b/&lt;b&gt;",
+		"width: 960.0px; height: 400.0px",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteDelimitedReport(t *testing.T) {
+	r := report{Projects: []reportProject{
+		{ProjectRoot: "github.com/a/a", Version: "v1.0.0", Update: "v1.1.0", License: "MIT AND Unlicense", Size: 2048, Files: 3},
+		{ProjectRoot: "github.com/b/b", Version: "branch master", License: "None", UpdatesUnknown: true},
+	}}
+	var buf bytes.Buffer
+	if err := writeDelimitedReport(&buf, r, "tsv", "project,license,update,size"); err != nil {
+		t.Fatal(err)
+	}
+	want := "project\tlicense\tupdate\tsize\ngithub.com/a/a\tMIT AND Unlicense\tv1.1.0\t2048\ngithub.com/b/b\tNone\tunknown\t0\n"
+	if buf.String() != want {
+		t.Errorf("expected\n%q\ngot\n%q", want, buf.String())
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/golang/dep/gps"
 	fb "github.com/golang/dep/internal/feedback"
 	"github.com/golang/dep/internal/importers"
+	"github.com/golang/dep/internal/importers/gomod"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -59,10 +60,13 @@ func (a *rootAnalyzer) InitializeRootManifestAndLock(dir string, pr gps.ProjectR
 	return
 }
 
+// cacheDepsConcurrency is the number of sources cacheDeps syncs at the same
+// time.
+const cacheDepsConcurrency = 4
+
 func (a *rootAnalyzer) cacheDeps(pr gps.ProjectRoot) error {
 	logger := a.ctx.Err
 	g, _ := errgroup.WithContext(context.TODO())
-	concurrency := 4
 
 	syncDep := func(pr gps.ProjectRoot, sm gps.SourceManager) error {
 		if err := sm.SyncSourceFor(gps.ProjectIdentifier{ProjectRoot: pr}); err != nil {
@@ -74,7 +78,7 @@ func (a *rootAnalyzer) cacheDeps(pr gps.ProjectRoot) error {
 
 	deps := make(chan gps.ProjectRoot)
 
-	for i := 0; i < concurrency; i++ {
+	for i := 0; i < cacheDepsConcurrency; i++ {
 		g.Go(func() error {
 			for d := range deps {
 				err := syncDep(gps.ProjectRoot(d), a.sm)
@@ -129,6 +133,30 @@ func (a *rootAnalyzer) importManifestAndLock(dir string, pr gps.ProjectRoot, sup
 	return emptyManifest, nil
 }
 
+// vendoredModulesLock returns the versions recorded in the go modules
+// vendor/modules.txt file of p, for use as the solver's preferred versions when
+// p has no lock of its own. It returns nil if there is no such file, or it
+// cannot be read.
+func vendoredModulesLock(ctx *dep.Ctx, p *dep.Project, sm gps.SourceManager) *dep.Lock {
+	logger := log.New(ioutil.Discard, "", 0)
+	if ctx.Verbose {
+		logger = ctx.Err
+	}
+
+	i := gomod.NewImporter(logger, ctx.Verbose, sm)
+	if !i.HasDepMetadata(p.AbsRoot) {
+		return nil
+	}
+
+	_, l, err := i.Import(p.AbsRoot, p.ImportRoot)
+	if err != nil {
+		ctx.Err.Printf("Warning: Unable to read the versions in vendor/modules.txt: %s", err)
+		return nil
+	}
+	ctx.Err.Printf("Preferring the versions in vendor/modules.txt, as there is no %s.", dep.LockName)
+	return l
+}
+
 func (a *rootAnalyzer) removeTransitiveDependencies(m *dep.Manifest) {
 	for pr := range m.Constraints {
 		if _, isDirect := a.directDeps[pr]; !isDirect {
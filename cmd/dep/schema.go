@@ -0,0 +1,56 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+
+	"github.com/golang/dep"
+	"github.com/pkg/errors"
+)
+
+const schemaShortHelp = `Print JSON Schemas for Gopkg.toml and Gopkg.lock`
+const schemaLongHelp = `
+Print a JSON Schema describing the format of Gopkg.toml (manifest) or
+Gopkg.lock (lock), for use by editors and other tools that validate or
+complete these files.
+
+The schemas are generated from the same types dep uses to read and write the
+files, so they always match the running version of dep.
+`
+
+type schemaCommand struct{}
+
+func (cmd *schemaCommand) Name() string      { return "schema" }
+func (cmd *schemaCommand) Args() string      { return "manifest|lock" }
+func (cmd *schemaCommand) ShortHelp() string { return schemaShortHelp }
+func (cmd *schemaCommand) LongHelp() string  { return schemaLongHelp }
+func (cmd *schemaCommand) Hidden() bool      { return false }
+
+func (cmd *schemaCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *schemaCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) != 1 {
+		return errors.New("schema takes exactly one argument, either manifest or lock")
+	}
+
+	var schema map[string]interface{}
+	switch args[0] {
+	case "manifest":
+		schema = dep.ManifestSchema()
+	case "lock":
+		schema = dep.LockSchema()
+	default:
+		return errors.Errorf("unknown schema %q, must be either manifest or lock", args[0])
+	}
+
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal schema")
+	}
+	ctx.Out.Println(string(b))
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// sizeReader is implemented by SourceManagers that can measure the local
+// copies of sources and export their trees.
+type sizeReader interface {
+	SourceUsage(gps.ProjectIdentifier) (gps.SourceUsage, error)
+	ExportProject(context.Context, gps.ProjectIdentifier, gps.Version, string) error
+}
+
+// projectSize describes how much a project weighs, in the cache and in
+// vendor.
+type projectSize struct {
+	ProjectRoot string
+	// CloneSize and CloneFiles measure the local copy of the source in the
+	// cache, including its VCS metadata.
+	CloneSize  uint64
+	CloneFiles int
+	// Fetched is the number of bytes recorded as fetched into the local copy.
+	Fetched uint64
+	// VendorSize and VendorFiles measure the locked revision as exported into
+	// vendor, before pruning.
+	VendorSize  uint64
+	VendorFiles int
+	// PrunedSize and PrunedFiles measure it after pruning according to the
+	// prune options of the manifest.
+	PrunedSize  uint64
+	PrunedFiles int
+	// Err is set when the sizes could not be determined.
+	Err error
+}
+
+type rawProjectSize struct {
+	ProjectRoot string `json:"projectRoot"`
+	CloneSize   uint64 `json:"cloneSize"`
+	CloneFiles  int    `json:"cloneFiles"`
+	Fetched     uint64 `json:"fetched"`
+	VendorSize  uint64 `json:"vendorSize"`
+	VendorFiles int    `json:"vendorFiles"`
+	PrunedSize  uint64 `json:"prunedSize"`
+	PrunedFiles int    `json:"prunedFiles"`
+	Error       string `json:"error,omitempty"`
+}
+
+func (s projectSize) marshalJSON() rawProjectSize {
+	raw := rawProjectSize{
+		ProjectRoot: s.ProjectRoot,
+		CloneSize:   s.CloneSize,
+		CloneFiles:  s.CloneFiles,
+		Fetched:     s.Fetched,
+		VendorSize:  s.VendorSize,
+		VendorFiles: s.VendorFiles,
+		PrunedSize:  s.PrunedSize,
+		PrunedFiles: s.PrunedFiles,
+	}
+	if s.Err != nil {
+		raw.Error = s.Err.Error()
+	}
+	return raw
+}
+
+// measureSizes measures the local copy of the source of each of lps, and its
+// locked revision as exported to a temporary directory before and after
+// pruning with prune. The results are sorted from the heaviest project in
+// vendor to the lightest.
+func measureSizes(lps []gps.LockedProject, sr sizeReader, prune gps.CascadingPruneOptions) []projectSize {
+	reports := make([]projectSize, len(lps))
+
+	var wg sync.WaitGroup
+	for i, lp := range lps {
+		wg.Add(1)
+		go func(i int, lp gps.LockedProject) {
+			defer wg.Done()
+			id := lp.Ident()
+			s := projectSize{ProjectRoot: string(id.ProjectRoot)}
+			defer func() { reports[i] = s }()
+
+			u, err := sr.SourceUsage(id)
+			if err != nil {
+				s.Err = errors.Wrapf(err, "could not measure the source of %s", id)
+				return
+			}
+			s.CloneSize, s.CloneFiles, s.Fetched = u.Size, u.Files, u.Fetched
+
+			tmp, err := ioutil.TempDir("", "dep-sizes")
+			if err != nil {
+				s.Err = err
+				return
+			}
+			defer os.RemoveAll(tmp)
+
+			to := filepath.Join(tmp, string(id.ProjectRoot))
+			if err := sr.ExportProject(context.TODO(), id, lp.Version(), to); err != nil {
+				s.Err = errors.Wrapf(err, "could not export %s", id)
+				return
+			}
+			if s.VendorSize, s.VendorFiles, err = dirUsage(to); err != nil {
+				s.Err = errors.Wrapf(err, "could not measure %s", id)
+				return
+			}
+			if err := gps.PruneProject(to, lp, prune.PruneOptionsFor(id.ProjectRoot)); err != nil {
+				s.Err = errors.Wrapf(err, "could not prune %s", id)
+				return
+			}
+			if s.PrunedSize, s.PrunedFiles, err = dirUsage(to); err != nil {
+				s.Err = errors.Wrapf(err, "could not measure %s", id)
+			}
+		}(i, lp)
+	}
+	wg.Wait()
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].PrunedSize != reports[j].PrunedSize {
+			return reports[i].PrunedSize > reports[j].PrunedSize
+		}
+		return reports[i].ProjectRoot < reports[j].ProjectRoot
+	})
+	return reports
+}
+
+// printSizes writes reports to w as a table with a line of totals, or as JSON.
+func printSizes(w io.Writer, reports []projectSize, asJSON bool) error {
+	if asJSON {
+		raw := make([]rawProjectSize, 0, len(reports))
+		for _, s := range reports {
+			raw = append(raw, s.marshalJSON())
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(raw)
+	}
+
+	var total projectSize
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tCLONE\tFETCHED\tVENDOR\tPRUNED\tFILES")
+	for _, s := range reports {
+		if s.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\n", s.ProjectRoot)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d/%d\n", s.ProjectRoot, humanByteSize(s.CloneSize), humanByteSize(s.Fetched),
+			humanByteSize(s.VendorSize), humanByteSize(s.PrunedSize), s.PrunedFiles, s.VendorFiles)
+		total.CloneSize += s.CloneSize
+		total.Fetched += s.Fetched
+		total.VendorSize += s.VendorSize
+		total.PrunedSize += s.PrunedSize
+		total.VendorFiles += s.VendorFiles
+		total.PrunedFiles += s.PrunedFiles
+	}
+	fmt.Fprintf(tw, "TOTAL\t%s\t%s\t%s\t%s\t%d/%d\n", humanByteSize(total.CloneSize), humanByteSize(total.Fetched),
+		humanByteSize(total.VendorSize), humanByteSize(total.PrunedSize), total.PrunedFiles, total.VendorFiles)
+	return tw.Flush()
+}
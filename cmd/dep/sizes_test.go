@@ -0,0 +1,109 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// fakeSizeReader maps project roots to the files, by path and size, of their
+// exported trees.
+type fakeSizeReader map[gps.ProjectRoot]map[string]int
+
+func (f fakeSizeReader) SourceUsage(id gps.ProjectIdentifier) (gps.SourceUsage, error) {
+	files, ok := f[id.ProjectRoot]
+	if !ok {
+		return gps.SourceUsage{}, errors.New("no such source")
+	}
+	return gps.SourceUsage{Size: 1 << 20, Files: len(files) + 10, Fetched: 2 << 20}, nil
+}
+
+func (f fakeSizeReader) ExportProject(ctx context.Context, id gps.ProjectIdentifier, v gps.Version, to string) error {
+	for name, size := range f[id.ProjectRoot] {
+		path := filepath.Join(to, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, make([]byte, size), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMeasureSizes(t *testing.T) {
+	sr := fakeSizeReader{
+		"github.com/small/lib": {"lib.go": 100},
+		"github.com/heavy/lib": {
+			"lib.go":                 2000,
+			"vendor/other/lib.go":    5000,
+			"vendor/other/README.md": 300,
+		},
+	}
+	lps := lockedProjects("github.com/small/lib", "github.com/heavy/lib", "github.com/missing/lib")
+	prune := gps.CascadingPruneOptions{DefaultOptions: gps.PruneNestedVendorDirs}
+
+	reports := measureSizes(lps, sr, prune)
+	var order []string
+	for _, s := range reports {
+		order = append(order, s.ProjectRoot)
+	}
+	if got, want := strings.Join(order, ","), "github.com/heavy/lib,github.com/small/lib,github.com/missing/lib"; got != want {
+		t.Errorf("unexpected order:\n\t(GOT): %s\n\t(WNT): %s", got, want)
+	}
+
+	heavy := reports[0]
+	if heavy.Err != nil {
+		t.Fatal(heavy.Err)
+	}
+	if heavy.VendorSize != 7300 || heavy.VendorFiles != 3 {
+		t.Errorf("expected 7300 bytes in 3 files before pruning, got %d in %d", heavy.VendorSize, heavy.VendorFiles)
+	}
+	if heavy.PrunedSize != 2000 || heavy.PrunedFiles != 1 {
+		t.Errorf("expected 2000 bytes in 1 file after pruning, got %d in %d", heavy.PrunedSize, heavy.PrunedFiles)
+	}
+	if heavy.CloneSize != 1<<20 || heavy.Fetched != 2<<20 {
+		t.Errorf("unexpected clone size %d and fetched bytes %d", heavy.CloneSize, heavy.Fetched)
+	}
+	if reports[2].Err == nil {
+		t.Error("expected an error for a missing source")
+	}
+
+	var buf bytes.Buffer
+	if err := printSizes(&buf, reports, false); err != nil {
+		t.Fatal(err)
+	}
+	want := `PROJECT                 CLONE  FETCHED  VENDOR  PRUNED  FILES
+github.com/heavy/lib    1.0MB  2.0MB    7.1KB   2.0KB   1/3
+github.com/small/lib    1.0MB  2.0MB    100B    100B    1/1
+github.com/missing/lib  -      -        -       -       -
+TOTAL                   2.0MB  4.0MB    7.2KB   2.1KB   2/4
+`
+	if buf.String() != want {
+		t.Errorf("unexpected table:\n\t(GOT):\n%s\n\t(WNT):\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := printSizes(&buf, reports, true); err != nil {
+		t.Fatal(err)
+	}
+	var raw []rawProjectSize
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 3 || raw[0].PrunedSize != 2000 || raw[2].Error == "" {
+		t.Errorf("unexpected JSON output: %s", buf.String())
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// namedLock is a lock, along with the name of the project it belongs to.
+type namedLock struct {
+	name string
+	lock gps.Lock
+}
+
+// lockedAt records the version at which one lock holds a dependency.
+type lockedAt struct {
+	Lock     string `json:"lock"`
+	Version  string `json:"version,omitempty"`
+	Revision string `json:"revision"`
+}
+
+// depSkew collects the versions at which a dependency is held by each of a
+// set of locks.
+type depSkew struct {
+	ProjectRoot string     `json:"projectRoot"`
+	Locks       []lockedAt `json:"locks"`
+	// Skewed is set when the locks do not all agree on a revision.
+	Skewed bool `json:"skewed"`
+}
+
+// computeSkew cross-tabulates the dependencies of locks, returning an entry
+// for each dependency that appears in any of them, sorted by project root.
+// Locks that do not contain a dependency are omitted from its entry.
+func computeSkew(locks []namedLock) []depSkew {
+	byRoot := make(map[gps.ProjectRoot]*depSkew)
+	for _, nl := range locks {
+		for _, lp := range nl.lock.Projects() {
+			pr := lp.Ident().ProjectRoot
+			ds, has := byRoot[pr]
+			if !has {
+				ds = &depSkew{ProjectRoot: string(pr)}
+				byRoot[pr] = ds
+			}
+
+			la := lockedAt{Lock: nl.name}
+			switch v := lp.Version().(type) {
+			case gps.PairedVersion:
+				la.Version = v.Unpair().String()
+				la.Revision = v.Revision().String()
+			case gps.Revision:
+				la.Revision = v.String()
+			}
+			ds.Locks = append(ds.Locks, la)
+		}
+	}
+
+	skews := make([]depSkew, 0, len(byRoot))
+	for _, ds := range byRoot {
+		for _, la := range ds.Locks[1:] {
+			if la.Revision != ds.Locks[0].Revision {
+				ds.Skewed = true
+				break
+			}
+		}
+		skews = append(skews, *ds)
+	}
+	sort.Slice(skews, func(i, j int) bool {
+		return skews[i].ProjectRoot < skews[j].ProjectRoot
+	})
+	return skews
+}
+
+// countSkewed returns the number of skewed dependencies in skews.
+func countSkewed(skews []depSkew) int {
+	var n int
+	for _, ds := range skews {
+		if ds.Skewed {
+			n++
+		}
+	}
+	return n
+}
+
+// writeSkewTable writes skews as a table with a row per lock holding each
+// dependency. Unless all is set, only skewed dependencies are written.
+func writeSkewTable(w io.Writer, lockHeader string, skews []depSkew, all bool) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PROJECT\t%s\tVERSION\tREVISION\tSKEW\n", lockHeader)
+	for _, ds := range skews {
+		if !ds.Skewed && !all {
+			continue
+		}
+
+		mark := ""
+		if ds.Skewed {
+			mark = "*"
+		}
+		for _, la := range ds.Locks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				ds.ProjectRoot, la.Lock, la.Version, formatVersion(gps.Revision(la.Revision)), mark)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d of %d dependencies are locked at different revisions\n", countSkewed(skews), len(skews))
+	return err
+}
+
+// writeSkewJSON writes the names of the locks that were compared, and skews,
+// as a JSON object.
+func writeSkewJSON(w io.Writer, locksKey string, locks []namedLock, skews []depSkew) error {
+	names := make([]string, 0, len(locks))
+	for _, nl := range locks {
+		names = append(names, nl.name)
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		locksKey:   names,
+		"projects": skews,
+	})
+}
+
+const skewShortHelp = `Compare the versions locked by several projects`
+const skewLongHelp = `
+Skew cross-tabulates the dependencies locked by a number of projects, and
+reports those that the projects lock at different revisions. This helps when
+aligning the versions used across many projects, such as all the services
+maintained by a team.
+
+Each argument is either the path of a Gopkg.lock, the path of a directory
+containing one, or the URL of a git repository with a Gopkg.lock at its root.
+The lock of a repository is read from the head of its default branch, which is
+fetched into the source cache, as for any dependency.
+
+  dep skew ../svc-a ../svc-b/Gopkg.lock https://github.com/example/svc-c.git
+
+The exit status is non-zero if any dependency is skewed.
+`
+
+type skewCommand struct {
+	all  bool
+	json bool
+}
+
+func (cmd *skewCommand) Name() string      { return "skew" }
+func (cmd *skewCommand) Args() string      { return "[-all] [-json] <lock> <lock> [lock...]" }
+func (cmd *skewCommand) ShortHelp() string { return skewShortHelp }
+func (cmd *skewCommand) LongHelp() string  { return skewLongHelp }
+func (cmd *skewCommand) Hidden() bool      { return false }
+
+func (cmd *skewCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.all, "all", false, "also show dependencies locked at the same revision everywhere")
+	fs.BoolVar(&cmd.json, "json", false, "output in JSON format")
+}
+
+func (cmd *skewCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) < 2 {
+		return errors.New("skew requires at least two locks to compare")
+	}
+
+	// Repositories are read through the source manager, which is only needed,
+	// and only locks the cache, if any are given.
+	var sm gps.SourceManager
+	for _, arg := range args {
+		if isGitURL(arg) {
+			smgr, err := ctx.SourceManager()
+			if err != nil {
+				return err
+			}
+			smgr.UseDefaultSignalHandling()
+			defer smgr.Release()
+			sm = smgr
+			break
+		}
+	}
+
+	locks := make([]namedLock, 0, len(args))
+	for _, arg := range args {
+		l, err := loadSkewLock(sm, arg)
+		if err != nil {
+			return err
+		}
+		locks = append(locks, namedLock{name: arg, lock: l})
+	}
+
+	var buf bytes.Buffer
+	skews := computeSkew(locks)
+	var err error
+	if cmd.json {
+		err = writeSkewJSON(&buf, "locks", locks, skews)
+	} else {
+		err = writeSkewTable(&buf, "LOCK", skews, cmd.all)
+	}
+	if err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+
+	if countSkewed(skews) > 0 {
+		return silentfail{}
+	}
+	return nil
+}
+
+// isGitURL reports whether arg names a remote git repository, rather than a
+// local path.
+func isGitURL(arg string) bool {
+	if strings.Contains(arg, "://") {
+		return true
+	}
+	// scp-like syntax, e.g. git@github.com:foo/bar.git. A colon in the first
+	// path element of a local path is only possible on Windows, where it is
+	// preceded by a single drive letter.
+	if i := strings.Index(arg, ":"); i > 1 && !strings.ContainsAny(arg[:i], `/\`) {
+		return true
+	}
+	return false
+}
+
+// skewProjectRoot names the repository at url as a project root, such as
+// github.com/example/svc-c for https://github.com/example/svc-c.git.
+func skewProjectRoot(url string) gps.ProjectRoot {
+	p := url
+	if i := strings.Index(p, "://"); i >= 0 {
+		p = p[i+3:]
+	} else {
+		// scp-like syntax
+		p = strings.Replace(p, ":", "/", 1)
+	}
+	if i := strings.Index(p, "@"); i >= 0 && i < strings.Index(p+"/", "/") {
+		p = p[i+1:]
+	}
+	return gps.ProjectRoot(strings.TrimSuffix(strings.TrimSuffix(p, "/"), ".git"))
+}
+
+// loadSkewLock reads the lock named by arg, which is a lock file, a directory
+// containing one, or a git URL, in which case it is read through sm.
+func loadSkewLock(sm gps.SourceManager, arg string) (gps.Lock, error) {
+	path := arg
+	if isGitURL(arg) {
+		dir, err := ioutil.TempDir("", "dep-skew")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create temporary directory")
+		}
+		defer os.RemoveAll(dir)
+
+		if err := exportDefaultBranch(sm, arg, dir); err != nil {
+			return nil, err
+		}
+		path = dir
+	}
+
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		path = filepath.Join(path, dep.LockName)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read lock for %s", arg)
+	}
+	defer f.Close()
+
+	l, err := dep.ReadLock(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read lock for %s", arg)
+	}
+	return l, nil
+}
+
+// exportDefaultBranch exports the head of the default branch of the
+// repository at url to dir.
+func exportDefaultBranch(sm gps.SourceManager, url, dir string) error {
+	id := gps.ProjectIdentifier{ProjectRoot: skewProjectRoot(url), Source: url}
+	avl, err := gps.ListAvailableVersions(sm, id, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the versions of %s", url)
+	}
+
+	for _, av := range avl {
+		if av.DefaultBranch {
+			return errors.Wrapf(sm.ExportProject(context.TODO(), id, av.Version, dir), "failed to export %s", url)
+		}
+	}
+	return errors.Errorf("%s has no default branch", url)
+}
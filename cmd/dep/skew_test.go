@@ -0,0 +1,129 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/test"
+)
+
+func skewTestLock(projects ...gps.LockedProject) *dep.Lock {
+	return &dep.Lock{P: projects}
+}
+
+func TestComputeSkew(t *testing.T) {
+	bar := gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}
+	baz := gps.ProjectIdentifier{ProjectRoot: "github.com/foo/baz"}
+	locks := []namedLock{
+		{"a", skewTestLock(
+			gps.NewLockedProject(bar, gps.NewVersion("v1.0.0").Pair("rev1"), nil),
+			gps.NewLockedProject(baz, gps.Revision("rev3"), nil),
+		)},
+		{"b", skewTestLock(
+			gps.NewLockedProject(bar, gps.NewVersion("v1.1.0").Pair("rev2"), nil),
+			gps.NewLockedProject(baz, gps.Revision("rev3"), nil),
+		)},
+		{"c", skewTestLock()},
+	}
+
+	got := computeSkew(locks)
+	want := []depSkew{
+		{
+			ProjectRoot: "github.com/foo/bar",
+			Locks: []lockedAt{
+				{Lock: "a", Version: "v1.0.0", Revision: "rev1"},
+				{Lock: "b", Version: "v1.1.0", Revision: "rev2"},
+			},
+			Skewed: true,
+		},
+		{
+			ProjectRoot: "github.com/foo/baz",
+			Locks: []lockedAt{
+				{Lock: "a", Revision: "rev3"},
+				{Lock: "b", Revision: "rev3"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected skew:\n\t(GOT): %+v\n\t(WNT): %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSkewTable(&buf, "MEMBER", got, false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "github.com/foo/baz") {
+		t.Errorf("expected aligned dependencies to be omitted:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "1 of 2 dependencies") {
+		t.Errorf("expected a summary of the skew:\n%s", buf.String())
+	}
+}
+
+func TestIsGitURL(t *testing.T) {
+	cases := map[string]bool{
+		"https://github.com/foo/bar.git": true,
+		"ssh://git@github.com/foo/bar":   true,
+		"git@github.com:foo/bar.git":     true,
+		"../svc-a":                       false,
+		"svc-a/Gopkg.lock":               false,
+		`C:\src\svc-a`:                   false,
+		"./foo:bar":                      false,
+	}
+	for arg, want := range cases {
+		if got := isGitURL(arg); got != want {
+			t.Errorf("isGitURL(%q) = %v, want %v", arg, got, want)
+		}
+	}
+}
+
+func TestSkewProjectRoot(t *testing.T) {
+	cases := map[string]gps.ProjectRoot{
+		"https://github.com/foo/bar.git":  "github.com/foo/bar",
+		"ssh://git@github.com/foo/bar":    "github.com/foo/bar",
+		"git@github.com:foo/bar.git":      "github.com/foo/bar",
+		"https://git.example.com/svc-a/":  "git.example.com/svc-a",
+		"https://git.example.com/a@b.git": "git.example.com/a@b",
+	}
+	for url, want := range cases {
+		if got := skewProjectRoot(url); got != want {
+			t.Errorf("skewProjectRoot(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestLoadSkewLock(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir("svc")
+	h.TempFile(filepath.Join("svc", dep.LockName), `[[projects]]
+  name = "github.com/foo/bar"
+  packages = ["."]
+  revision = "rev1"
+  version = "v1.0.0"
+`)
+
+	for _, arg := range []string{h.Path("svc"), h.Path(filepath.Join("svc", dep.LockName))} {
+		l, err := loadSkewLock(nil, arg)
+		if err != nil {
+			t.Fatalf("failed to load lock from %s: %v", arg, err)
+		}
+		if len(l.Projects()) == 0 {
+			t.Errorf("expected projects in the lock loaded from %s", arg)
+		}
+	}
+
+	if _, err := loadSkewLock(nil, filepath.Join(h.Path("svc"), "nonexistent")); err == nil {
+		t.Error("expected an error for a missing lock")
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os/exec"
+	"runtime"
+
+	"github.com/golang/dep"
+)
+
+// runShellCommand runs command with the shell in the directory root, passing
+// its output through.
+func runShellCommand(ctx *dep.Ctx, root, command string) error {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+	c.Dir = root
+	c.Stdout = ctx.Out.Writer()
+	c.Stderr = ctx.Err.Writer()
+	return c.Run()
+}
+
+// smokeTest returns the smoke test command to run after an update: the one
+// passed with -smoke-test, or else the one set in the manifest.
+func (cmd *ensureCommand) smokeTest(m *dep.Manifest) string {
+	if cmd.smokeTestCmd != "" {
+		return cmd.smokeTestCmd
+	}
+	return m.SmokeTest
+}
@@ -0,0 +1,33 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"runtime"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestRunShellCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("smoke test commands below are for sh")
+	}
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir(".")
+
+	discard := log.New(ioutil.Discard, "", 0)
+	ctx := &dep.Ctx{Out: discard, Err: discard}
+	if err := runShellCommand(ctx, h.Path("."), "true && test -d ."); err != nil {
+		t.Errorf("unexpected error from passing smoke test: %v", err)
+	}
+	if err := runShellCommand(ctx, h.Path("."), "true && false"); err == nil {
+		t.Error("expected an error from failing smoke test")
+	}
+}
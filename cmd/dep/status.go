@@ -15,10 +15,12 @@ import (
 	"log"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"text/tabwriter"
 	"text/template"
+	"time"
 
 	"github.com/golang/dep"
 	"github.com/golang/dep/gps"
@@ -27,17 +29,24 @@ import (
 	"github.com/pkg/errors"
 )
 
-const availableTemplateVariables = "ProjectRoot, Constraint, Version, Revision, Latest, and PackageCount."
+const availableTemplateVariables = "ProjectRoot, Constraint, Version, Revision, PseudoVersion, Latest, PackageCount, and Kind."
 const availableDefaultTemplateVariables = `.Projects[]{
-	    .ProjectRoot,.Source,.Constraint,.PackageCount,.Packages[],
-		.PruneOpts,.Digest,.Locked{.Branch,.Revision,.Version},
-		.Latest{.Revision,.Version}
+	    .ProjectRoot,.Source,.Constraint,.PackageCount,.Kind,.Packages[],
+		.PruneOpts,.Digest,.Locked{.Branch,.Revision,.PseudoVersion,.Version},
+		.Latest{.Revision,.Version},
+		.Notes[]{.Date,.By,.Version,.Text,.Link,.Approval}
 	},
 	.Metadata{
 	    .AnalyzerName,.AnalyzerVersion,.InputImports,.SolverName,
 	    .SolverVersion
 	}`
 
+const (
+	statusColumns       = "project, constraint, version, revision, pseudo-version, latest, pkgs-used and kind"
+	statusDetailColumns = "source, packages and digest"
+	statusOldColumns    = "project, constraint, revision and latest"
+)
+
 const statusShortHelp = `Report the status of the project's dependencies`
 const statusLongHelp = `
 With no arguments, print the status of each dependency of the project.
@@ -49,6 +58,22 @@ With no arguments, print the status of each dependency of the project.
   LATEST      Latest VCS revision available
   PKGS USED   Number of packages from this project that are actually used
 
+If $DEPBUNDLE names a metadata bundle (see dep bundle), LATEST is taken from
+the versions recorded in the bundle, rather than from upstream.
+
+Except with -old, -lint, -health, -sizes, -branches, -native or -dot, which
+need to read the sources, dep status neither locks nor writes to the cache, so
+it can run while dep ensure does, or in a read-only checkout. It then reports
+from the lock and the persistent cache alone (see $DEPCACHEAGE): the
+constraints that dependencies place on each other are only shown if they are
+in the persistent cache.
+
+With -o csv or -o tsv, the status is written as a spreadsheet, one row per
+project, with full revisions. -columns selects and orders the columns, as in
+-columns project,version,latest; the columns are ` + statusColumns + `,
+and, with -detail, ` + statusDetailColumns + `. With -old they are
+` + statusOldColumns + `.
+
 You may use the -f flag to create a custom format for the output of the
 dep status command. The available fields you can utilize are as follows:
 ` + availableTemplateVariables + `
@@ -68,7 +93,11 @@ dep status -detail
 	Displays a detailed table of the dependencies in the project including
 	the value of any source rules used and full list of packages used from
 	each project (instead of simply a count). Text wrapping may make this
-	output hard to read.
+	output hard to read. KIND tells whether each project is imported by the
+	project's packages (direct), only listed in the required list of
+	Gopkg.toml (required), only depended on by other dependencies
+	(transitive), or only reached through _test.go files (test). The notes
+	recorded about each project with dep note are listed after the table.
 
 dep status -f='{{if eq .Constraint "master"}}{{.ProjectRoot}} {{end}}'
 
@@ -87,6 +116,74 @@ dep status -detail -f='{{range $i, $p := .Projects}}{{if ne .Source "" -}}
 	to the full output document, instead of to packages one at a time.
 	Available flags are as follows: ` + availableDefaultTemplateVariables + `
 
+dep status -lint
+
+	Reports likely problems with the set of locked dependencies, such as
+	multiple projects that appear to provide the same functionality (two
+	YAML parsers), or projects whose repositories share history (a fork
+	alongside its upstream). Combine with -json for machine-readable
+	output.
+
+dep status -health
+
+	Displays when the locked revision of each dependency was committed,
+	and when its upstream was last active, as recorded in the local
+	cache of its source. Dependencies are marked stale or inactive
+	according to the limits in the [health] table of Gopkg.toml:
+
+	  [health]
+	    max-age-years = 3
+	    max-inactive-months = 18
+
+	Combine with -json for machine-readable output.
+
+dep status -sizes
+
+	Displays, for each dependency, the size of its source in the local
+	cache (CLONE), the bytes recorded as fetched into it (FETCHED), the
+	size of its locked revision in vendor before (VENDOR) and after
+	(PRUNED) pruning, and its number of files after and before pruning.
+	The heaviest dependencies are listed first. Combine with -json for
+	machine-readable output.
+
+dep status -branches
+
+	Displays, for each dependency constrained to a branch, its refresh
+	policy (see dep ensure -help), when its locked revision was committed
+	(LOCKED), when the head of the branch was committed (HEAD), and how
+	many days the lock has fallen behind the branch. Combine with -json
+	for machine-readable output.
+
+dep status -native
+
+	Displays each package used from the dependencies that is built with
+	cgo, SWIG or assembly, and so needs a C toolchain or particular
+	architectures to build. Pruning never removes the non-Go sources
+	these packages are built from. Combine with -json for
+	machine-readable output.
+
+dep status -suggest-constraints
+
+	Proposes a constraint for each direct dependency that Gopkg.toml does
+	not constrain, admitting the version recorded in Gopkg.lock: a caret
+	range for a semver version, and the branch or tag otherwise. Run dep
+	tidy -constrain-direct to add them to Gopkg.toml. Combine with -json
+	for machine-readable output.
+
+dep status -workspace
+
+	Treats the current directory as a workspace containing several
+	projects, such as the services in a monorepo, and displays the
+	dependencies locked by each of them. Dependencies that members lock
+	at different revisions are marked in the SKEW column. Combine with
+	-json for machine-readable output.
+
+dep status -detail -o csv -columns project,version,source -out deps.csv
+
+	Writes the projects, their locked versions and their sources to
+	deps.csv, to be opened in a spreadsheet. Cells that a spreadsheet
+	would take for a formula are prefixed with a quote.
+
 dep status -json
 
 	Displays the dependency information in JSON format as a list of
@@ -98,8 +195,9 @@ MacOS:   dep status -dot | dot -T png | open -f -a /Applications/Preview.app
 Windows: dep status -dot | dot -T png -o status.png; start status.png
 
 	Generates a visual representation of the dependency tree using GraphViz.
-	(Note: in order for this example to work you must first have graphviz
-	installed on your system)
+	Transitive dependencies are drawn dashed, and those only in the
+	required list of Gopkg.toml dotted. (Note: in order for this example to
+	work you must first have graphviz installed on your system)
 
 `
 
@@ -129,8 +227,17 @@ func (cmd *statusCommand) Register(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.dot, "dot", false, "output the dependency graph in GraphViz format")
 	fs.BoolVar(&cmd.old, "old", false, "only show out-of-date dependencies")
 	fs.BoolVar(&cmd.missing, "missing", false, "only show missing dependencies")
+	fs.BoolVar(&cmd.lint, "lint", false, "report likely problems with the set of locked dependencies")
+	fs.BoolVar(&cmd.health, "health", false, "report the age of locked revisions and the latest upstream activity of each dependency")
+	fs.BoolVar(&cmd.sizes, "sizes", false, "report the size in the cache and in vendor of each dependency, heaviest first")
+	fs.BoolVar(&cmd.branches, "branches", false, "report how far the locked revisions of branch-tracked dependencies have fallen behind their branches")
+	fs.BoolVar(&cmd.native, "native", false, "report the packages used from dependencies that are built with cgo, SWIG or assembly")
+	fs.BoolVar(&cmd.suggestConstraints, "suggest-constraints", false, "propose constraints for direct dependencies that Gopkg.toml leaves unconstrained")
+	fs.BoolVar(&cmd.workspace, "workspace", false, "aggregate the locks of all projects beneath the current directory")
 	fs.StringVar(&cmd.outFilePath, "out", "", "path to a file to which to write the output. Blank value will be ignored")
 	fs.BoolVar(&cmd.detail, "detail", false, "include more detail in the chosen format")
+	fs.StringVar(&cmd.format, "o", "", "output as a spreadsheet, in csv or tsv format")
+	fs.StringVar(&cmd.columns, "columns", "", "comma-separated list of the columns to output with -o")
 }
 
 type statusCommand struct {
@@ -142,8 +249,18 @@ type statusCommand struct {
 	dot         bool
 	old         bool
 	missing     bool
+	lint        bool
+	health      bool
+	sizes       bool
+	branches    bool
+	workspace   bool
 	outFilePath string
 	detail      bool
+	format      string
+	columns     string
+
+	suggestConstraints bool
+	native             bool
 }
 
 type outputter interface {
@@ -165,7 +282,12 @@ type oldOutputter interface {
 	OldFooter() error
 }
 
-type tableOutput struct{ w *tabwriter.Writer }
+type tableOutput struct {
+	w *tabwriter.Writer
+	// notes are the projects with notes, in the order they were listed by
+	// DetailLine.
+	notes []*DetailStatus
+}
 
 func (out *tableOutput) BasicHeader() error {
 	_, err := fmt.Fprintf(out.w, "PROJECT\tCONSTRAINT\tVERSION\tREVISION\tLATEST\tPKGS USED\n")
@@ -190,18 +312,34 @@ func (out *tableOutput) BasicLine(bs *BasicStatus) error {
 }
 
 func (out *tableOutput) DetailHeader(metadata *dep.SolveMeta) error {
-	_, err := fmt.Fprintf(out.w, "PROJECT\tSOURCE\tCONSTRAINT\tVERSION\tREVISION\tLATEST\tPKGS USED\n")
+	_, err := fmt.Fprintf(out.w, "PROJECT\tKIND\tSOURCE\tCONSTRAINT\tVERSION\tREVISION\tLATEST\tPKGS USED\n")
 	return err
 }
 
 func (out *tableOutput) DetailFooter(metadata *dep.SolveMeta) error {
-	return out.BasicFooter()
+	if err := out.BasicFooter(); err != nil {
+		return err
+	}
+	if len(out.notes) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(out.w, "\nNOTES\n")
+	for _, ds := range out.notes {
+		fmt.Fprintf(out.w, "%s\n", ds.ProjectRoot)
+		writeNotes(out.w, ds.Notes)
+	}
+	return out.w.Flush()
 }
 
 func (out *tableOutput) DetailLine(ds *DetailStatus) error {
+	if len(ds.Notes) > 0 {
+		out.notes = append(out.notes, ds)
+	}
 	_, err := fmt.Fprintf(out.w,
-		"%s\t%s\t%s\t%s\t%s\t%s\t[%s]\t\n",
+		"%s\t%s\t%s\t%s\t%s\t%s\t%s\t[%s]\t\n",
 		ds.ProjectRoot,
+		ds.Kind,
 		ds.Source,
 		ds.getConsolidatedConstraint(),
 		formatVersion(ds.Version),
@@ -319,6 +457,54 @@ func (out *jsonOutput) OldFooter() error {
 	return json.NewEncoder(out.w).Encode(out.old)
 }
 
+// The columns written by delimitedOutput, as named in statusColumns,
+// statusDetailColumns and statusOldColumns.
+var (
+	statusBasicHeader  = []string{"project", "constraint", "version", "revision", "pseudo-version", "latest", "pkgs-used", "kind"}
+	statusDetailHeader = append(append([]string(nil), statusBasicHeader...), "source", "packages", "digest")
+	statusOldHeader    = []string{"project", "constraint", "revision", "latest"}
+)
+
+type delimitedOutput struct {
+	w       io.Writer
+	format  string
+	columns string
+	dw      *delimitedWriter
+}
+
+func (out *delimitedOutput) header(header []string) (err error) {
+	out.dw, err = newDelimitedWriter(out.w, out.format, header, out.columns)
+	return err
+}
+
+func (out *delimitedOutput) BasicHeader() error { return out.header(statusBasicHeader) }
+func (out *delimitedOutput) BasicFooter() error { return out.dw.flush() }
+
+func (out *delimitedOutput) BasicLine(bs *BasicStatus) error {
+	return out.dw.write(bs.delimitedRow())
+}
+
+func (out *delimitedOutput) DetailHeader(metadata *dep.SolveMeta) error {
+	return out.header(statusDetailHeader)
+}
+
+func (out *delimitedOutput) DetailFooter(metadata *dep.SolveMeta) error { return out.dw.flush() }
+
+func (out *delimitedOutput) DetailLine(ds *DetailStatus) error {
+	return out.dw.write(append(ds.BasicStatus.delimitedRow(), ds.Source, strings.Join(ds.Packages, " "), ds.Digest.String()))
+}
+
+func (out *delimitedOutput) MissingHeader() error                { return nil }
+func (out *delimitedOutput) MissingLine(ms *MissingStatus) error { return nil }
+func (out *delimitedOutput) MissingFooter() error                { return nil }
+
+func (out *delimitedOutput) OldHeader() error { return out.header(statusOldHeader) }
+func (out *delimitedOutput) OldFooter() error { return out.dw.flush() }
+
+func (out *delimitedOutput) OldLine(os *OldStatus) error {
+	return out.dw.write([]string{os.ProjectRoot, os.getConsolidatedConstraint(), string(os.Revision), os.getConsolidatedLatest(longRev)})
+}
+
 type dotOutput struct {
 	w io.Writer
 	o string
@@ -346,6 +532,14 @@ func (out *dotOutput) BasicFooter() error {
 
 func (out *dotOutput) BasicLine(bs *BasicStatus) error {
 	out.g.createNode(bs.ProjectRoot, bs.getConsolidatedVersion(), bs.Children)
+	// Dependencies that are not imported directly are drawn with broken
+	// lines.
+	switch bs.Kind {
+	case dep.DependencyRequired:
+		out.g.setNodeStyle(bs.ProjectRoot, "dotted")
+	case dep.DependencyTransitive, dep.DependencyTest:
+		out.g.setNodeStyle(bs.ProjectRoot, "dashed")
+	}
 	return nil
 }
 
@@ -375,12 +569,14 @@ func (out *templateOutput) BasicHeader() error { return nil }
 func (out *templateOutput) BasicFooter() error { return nil }
 func (out *templateOutput) BasicLine(bs *BasicStatus) error {
 	data := rawStatus{
-		ProjectRoot:  bs.ProjectRoot,
-		Constraint:   bs.getConsolidatedConstraint(),
-		Version:      bs.getConsolidatedVersion(),
-		Revision:     bs.Revision.String(),
-		Latest:       bs.getConsolidatedLatest(shortRev),
-		PackageCount: bs.PackageCount,
+		ProjectRoot:   bs.ProjectRoot,
+		Constraint:    bs.getConsolidatedConstraint(),
+		Version:       bs.getConsolidatedVersion(),
+		Revision:      bs.Revision.String(),
+		PseudoVersion: bs.PseudoVersion,
+		Latest:        bs.getConsolidatedLatest(shortRev),
+		PackageCount:  bs.PackageCount,
+		Kind:          bs.Kind,
 	}
 	return out.tmpl.Execute(out.w, data)
 }
@@ -404,13 +600,15 @@ func (out *templateOutput) DetailLine(ds *DetailStatus) error {
 	data := rawDetailProject{
 		ProjectRoot:  ds.ProjectRoot,
 		Constraint:   ds.getConsolidatedConstraint(),
-		Locked:       formatDetailVersion(ds.Version, ds.Revision),
+		Locked:       formatDetailVersion(ds.Version, ds.Revision, ds.PseudoVersion),
 		Latest:       formatDetailLatestVersion(ds.Latest, ds.hasError),
 		PruneOpts:    ds.getPruneOpts(),
 		Digest:       ds.Digest.String(),
 		PackageCount: ds.PackageCount,
+		Kind:         ds.Kind,
 		Source:       ds.Source,
 		Packages:     ds.Packages,
+		Notes:        newRawNotes(ds.Notes),
 	}
 
 	out.detail = append(out.detail, data)
@@ -440,12 +638,24 @@ func (cmd *statusCommand) Run(ctx *dep.Ctx, args []string) error {
 		return err
 	}
 
+	if cmd.workspace {
+		return cmd.runWorkspace(ctx)
+	}
+
 	p, err := ctx.LoadProject()
 	if err != nil {
 		return err
 	}
 
-	sm, err := ctx.SourceManager()
+	// Unless it has to read the sources, status reports on what the lock and
+	// the persistent cache record, without taking the cache, so it can run
+	// while dep ensure does, or in a read-only checkout.
+	var sm *gps.SourceMgr
+	if cmd.old || cmd.lint || cmd.health || cmd.sizes || cmd.branches || cmd.native || cmd.dot {
+		sm, err = ctx.SourceManager()
+	} else {
+		sm, err = ctx.ReadOnlySourceManager()
+	}
 	if err != nil {
 		return err
 	}
@@ -465,6 +675,12 @@ func (cmd *statusCommand) Run(ctx *dep.Ctx, args []string) error {
 		out = &jsonOutput{
 			w: &buf,
 		}
+	case cmd.format != "":
+		out = &delimitedOutput{
+			w:       &buf,
+			format:  cmd.format,
+			columns: cmd.columns,
+		}
 	case cmd.dot:
 		out = &dotOutput{
 			p: p,
@@ -500,6 +716,111 @@ func (cmd *statusCommand) Run(ctx *dep.Ctx, args []string) error {
 		return errors.Errorf("no Gopkg.lock found. Run `dep ensure` to generate lock file")
 	}
 
+	if cmd.lint {
+		if cmd.template != "" {
+			return errors.Errorf("invalid output format used")
+		}
+		if err := printLintFindings(&buf, cmd.runLint(ctx, p, sm), cmd.json); err != nil {
+			return err
+		}
+		ctx.Out.Print(buf.String())
+		return nil
+	}
+
+	if cmd.health {
+		if cmd.template != "" {
+			return errors.Errorf("invalid output format used")
+		}
+		reports := assessHealth(p.Lock.Projects(), sm, p.Manifest.Health, time.Now())
+		if ctx.Verbose {
+			for _, h := range reports {
+				if h.Err != nil {
+					ctx.Err.Println(h.Err)
+				}
+			}
+		}
+		if err := printHealth(&buf, reports, cmd.json); err != nil {
+			return err
+		}
+		ctx.Out.Print(buf.String())
+		return nil
+	}
+
+	if cmd.sizes {
+		if cmd.template != "" {
+			return errors.Errorf("invalid output format used")
+		}
+		reports := measureSizes(p.Lock.Projects(), sm, p.Manifest.PruneOptions)
+		if ctx.Verbose {
+			for _, s := range reports {
+				if s.Err != nil {
+					ctx.Err.Println(s.Err)
+				}
+			}
+		}
+		if err := printSizes(&buf, reports, cmd.json); err != nil {
+			return err
+		}
+		ctx.Out.Print(buf.String())
+		return nil
+	}
+
+	if cmd.branches {
+		if cmd.template != "" {
+			return errors.Errorf("invalid output format used")
+		}
+		reports := assessBranches(p.Lock.Projects(), p.Manifest, sm)
+		if ctx.Verbose {
+			for _, b := range reports {
+				if b.Err != nil {
+					ctx.Err.Println(b.Err)
+				}
+			}
+		}
+		if err := printBranches(&buf, reports, cmd.json); err != nil {
+			return err
+		}
+		ctx.Out.Print(buf.String())
+		return nil
+	}
+
+	if cmd.native {
+		if cmd.template != "" {
+			return errors.Errorf("invalid output format used")
+		}
+		reports := assessNative(p.Lock.Projects(), sm)
+		if ctx.Verbose {
+			for _, n := range reports {
+				if n.Err != nil {
+					ctx.Err.Println(n.Err)
+				}
+			}
+		}
+		if err := printNative(&buf, reports, cmd.json); err != nil {
+			return err
+		}
+		ctx.Out.Print(buf.String())
+		return nil
+	}
+
+	if cmd.suggestConstraints {
+		if cmd.template != "" {
+			return errors.Errorf("invalid output format used")
+		}
+		if p.Lock == nil {
+			return errors.Errorf("constraints are proposed from the versions in %s, but %s does not exist", dep.LockName, dep.LockName)
+		}
+		direct, err := p.GetDirectDependencyNames(sm)
+		if err != nil {
+			return err
+		}
+		if err := printConstraintSuggestions(&buf, suggestConstraints(p.Manifest, p.Lock, direct), cmd.json); err != nil {
+			return err
+		}
+		ctx.Out.Print(buf.String())
+		return nil
+	}
+
 	if cmd.old {
 		if _, ok := out.(oldOutputter); !ok {
 			return errors.Errorf("invalid output format used")
@@ -542,6 +863,39 @@ func (cmd *statusCommand) Run(ctx *dep.Ctx, args []string) error {
 	return runerr
 }
 
+// runWorkspace prints the dependencies locked by each project in the workspace
+// rooted at the working directory, marking those that members lock at
+// different revisions.
+func (cmd *statusCommand) runWorkspace(ctx *dep.Ctx) error {
+	w, err := ctx.LoadWorkspace()
+	if err != nil {
+		return err
+	}
+
+	var locks []namedLock
+	for _, p := range w.Members {
+		name := w.MemberName(p)
+		if p.Lock == nil {
+			ctx.Err.Printf("Warning: workspace member %s has no %s, skipping it\n", name, dep.LockName)
+			continue
+		}
+		locks = append(locks, namedLock{name: name, lock: p.Lock})
+	}
+
+	var buf bytes.Buffer
+	skews := computeSkew(locks)
+	if cmd.json {
+		err = writeSkewJSON(&buf, "members", locks, skews)
+	} else {
+		err = writeSkewTable(&buf, "MEMBER", skews, true)
+	}
+	if err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
 func (cmd *statusCommand) validateFlags() error {
 	// Operating mode flags.
 	var opModes []string
@@ -558,6 +912,64 @@ func (cmd *statusCommand) validateFlags() error {
 		opModes = append(opModes, "-detail")
 	}
 
+	if cmd.lint {
+		opModes = append(opModes, "-lint")
+	}
+
+	if cmd.health {
+		opModes = append(opModes, "-health")
+	}
+
+	if cmd.sizes {
+		opModes = append(opModes, "-sizes")
+	}
+
+	if cmd.branches {
+		opModes = append(opModes, "-branches")
+	}
+
+	if cmd.suggestConstraints {
+		opModes = append(opModes, "-suggest-constraints")
+	}
+
+	if cmd.native {
+		opModes = append(opModes, "-native")
+	}
+
+	if cmd.workspace {
+		opModes = append(opModes, "-workspace")
+
+		if cmd.template != "" || cmd.dot || cmd.lock {
+			return errors.New("-workspace only supports the default and -json output formats")
+		}
+	}
+
+	if cmd.format != "" {
+		if !isDelimitedFormat(cmd.format) {
+			return errors.Errorf("unsupported format %q for -o, must be csv or tsv", cmd.format)
+		}
+		if cmd.json || cmd.dot || cmd.template != "" || cmd.lock {
+			return errors.New("cannot pass multiple output format flags")
+		}
+		for _, mode := range opModes {
+			if mode != "-old" && mode != "-detail" {
+				return errors.Errorf("-o cannot be used with %s", mode)
+			}
+		}
+		header := statusBasicHeader
+		switch {
+		case cmd.old:
+			header = statusOldHeader
+		case cmd.detail:
+			header = statusDetailHeader
+		}
+		if _, err := selectColumns(header, cmd.columns); err != nil {
+			return err
+		}
+	} else if cmd.columns != "" {
+		return errors.New("-columns can only be used with -o")
+	}
+
 	// Check if any other flags are passed with -dot.
 	if cmd.dot {
 		if cmd.template != "" {
@@ -638,6 +1050,33 @@ func (os OldStatus) marshalJSON() *rawOldStatus {
 	}
 }
 
+// runLint collects all lint findings for the project's lock.
+func (cmd *statusCommand) runLint(ctx *dep.Ctx, p *dep.Project, sm *gps.SourceMgr) []lintFinding {
+	lps := p.Lock.Projects()
+
+	forks, errs := findSharedAncestry(lps, sm)
+	if ctx.Verbose {
+		for _, err := range errs {
+			ctx.Err.Println(err)
+		}
+	}
+
+	// Shared ancestry is a stronger signal than a shared repository name, so
+	// drop name-based findings that the ancestry check has already confirmed.
+	confirmed := make(map[string]bool, len(forks))
+	for _, f := range forks {
+		confirmed[strings.Join(f.Projects, ",")] = true
+	}
+
+	var findings []lintFinding
+	for _, f := range findDuplicateFunctionality(lps) {
+		if !confirmed[strings.Join(f.Projects, ",")] {
+			findings = append(findings, f)
+		}
+	}
+	return append(findings, forks...)
+}
+
 func (cmd *statusCommand) runOld(ctx *dep.Ctx, out oldOutputter, p *dep.Project, sm gps.SourceManager) error {
 	// While the network churns on ListVersions() requests, statically analyze
 	// code from the current project.
@@ -724,12 +1163,14 @@ func (cmd *statusCommand) runOld(ctx *dep.Ctx, out oldOutputter, p *dep.Project,
 }
 
 type rawStatus struct {
-	ProjectRoot  string
-	Constraint   string
-	Version      string
-	Revision     string
-	Latest       string
-	PackageCount int
+	ProjectRoot   string
+	Constraint    string
+	Version       string
+	Revision      string
+	PseudoVersion string `json:",omitempty"`
+	Latest        string
+	PackageCount  int
+	Kind          string `json:",omitempty"`
 }
 
 // rawDetail is is additional information used for the status when the
@@ -740,9 +1181,10 @@ type rawDetail struct {
 }
 
 type rawDetailVersion struct {
-	Revision string `json:"Revision,omitempty"`
-	Version  string `json:"Version,omitempty"`
-	Branch   string `json:"Branch,omitempty"`
+	Revision      string `json:"Revision,omitempty"`
+	PseudoVersion string `json:"PseudoVersion,omitempty"`
+	Version       string `json:"Version,omitempty"`
+	Branch        string `json:"Branch,omitempty"`
 }
 
 type rawDetailProject struct {
@@ -755,6 +1197,32 @@ type rawDetailProject struct {
 	Source       string `json:"Source,omitempty"`
 	Constraint   string
 	PackageCount int
+	Kind         string          `json:",omitempty"`
+	Notes        []rawDetailNote `json:",omitempty"`
+}
+
+type rawDetailNote struct {
+	Date     string
+	By       string `json:",omitempty"`
+	Version  string `json:",omitempty"`
+	Text     string `json:",omitempty"`
+	Link     string `json:",omitempty"`
+	Approval bool   `json:",omitempty"`
+}
+
+func newRawNotes(notes []dep.Note) []rawDetailNote {
+	var raw []rawDetailNote
+	for _, n := range notes {
+		raw = append(raw, rawDetailNote{
+			Date:     n.Date,
+			By:       n.By,
+			Version:  n.Version,
+			Text:     n.Text,
+			Link:     n.Link,
+			Approval: n.Approval,
+		})
+	}
+	return raw
 }
 
 type rawDetailMetadata struct {
@@ -792,6 +1260,14 @@ type BasicStatus struct {
 	PackageCount int
 	hasOverride  bool
 	hasError     bool
+
+	// PseudoVersion is the pseudo-version of Revision recorded in the lock
+	// for projects locked to a revision or a branch, if any.
+	PseudoVersion string
+	// Kind is the kind of dependency that the project is, one of
+	// dep.DependencyDirect, dep.DependencyRequired, dep.DependencyTransitive
+	// and dep.DependencyTest.
+	Kind string
 }
 
 // DetailStatus contains all information reported about a single dependency
@@ -803,6 +1279,8 @@ type DetailStatus struct {
 	Source    string
 	PruneOpts gps.PruneOptions
 	Digest    verify.VersionedDigest
+	// Notes are the notes recorded about the project with dep note.
+	Notes []dep.Note
 }
 
 func (bs *BasicStatus) getConsolidatedConstraint() string {
@@ -826,6 +1304,8 @@ func (bs *BasicStatus) getConsolidatedVersion() string {
 	version := formatVersion(bs.Revision)
 	if bs.Version != nil {
 		version = formatVersion(bs.Version)
+	} else if bs.PseudoVersion != "" {
+		version = bs.PseudoVersion
 	}
 	return version
 }
@@ -848,18 +1328,34 @@ func (bs *BasicStatus) getConsolidatedLatest(revSize uint8) string {
 	return latest
 }
 
+// delimitedRow returns the cells of bs in the columns of statusBasicHeader.
+func (bs *BasicStatus) delimitedRow() []string {
+	return []string{
+		bs.ProjectRoot,
+		bs.getConsolidatedConstraint(),
+		formatVersion(bs.Version),
+		string(bs.Revision),
+		bs.PseudoVersion,
+		bs.getConsolidatedLatest(longRev),
+		strconv.Itoa(bs.PackageCount),
+		bs.Kind,
+	}
+}
+
 func (ds *DetailStatus) getPruneOpts() string {
 	return (ds.PruneOpts & ^gps.PruneNestedVendorDirs).String()
 }
 
 func (bs *BasicStatus) marshalJSON() *rawStatus {
 	return &rawStatus{
-		ProjectRoot:  bs.ProjectRoot,
-		Constraint:   bs.getConsolidatedConstraint(),
-		Version:      formatVersion(bs.Version),
-		Revision:     string(bs.Revision),
-		Latest:       bs.getConsolidatedLatest(longRev),
-		PackageCount: bs.PackageCount,
+		ProjectRoot:   bs.ProjectRoot,
+		Constraint:    bs.getConsolidatedConstraint(),
+		Version:       formatVersion(bs.Version),
+		Revision:      string(bs.Revision),
+		PseudoVersion: bs.PseudoVersion,
+		Latest:        bs.getConsolidatedLatest(longRev),
+		PackageCount:  bs.PackageCount,
+		Kind:          bs.Kind,
 	}
 }
 
@@ -869,13 +1365,15 @@ func (ds *DetailStatus) marshalJSON() *rawDetailProject {
 	return &rawDetailProject{
 		ProjectRoot:  rawStatus.ProjectRoot,
 		Constraint:   rawStatus.Constraint,
-		Locked:       formatDetailVersion(ds.Version, ds.Revision),
+		Locked:       formatDetailVersion(ds.Version, ds.Revision, ds.PseudoVersion),
 		Latest:       formatDetailLatestVersion(ds.Latest, ds.hasError),
 		PruneOpts:    ds.getPruneOpts(),
 		Digest:       ds.Digest.String(),
 		Source:       ds.Source,
 		Packages:     ds.Packages,
 		PackageCount: ds.PackageCount,
+		Kind:         rawStatus.Kind,
+		Notes:        newRawNotes(ds.Notes),
 	}
 }
 
@@ -886,6 +1384,17 @@ type MissingStatus struct {
 }
 
 func (cmd *statusCommand) runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Project, sm gps.SourceManager) (hasMissingPkgs bool, errCount int, err error) {
+	// Versions are taken from the metadata bundle, if there is one, rather
+	// than from upstream.
+	listVersions := sm.ListVersions
+	bundle, err := ctx.LoadBundle()
+	if err != nil {
+		return false, 0, err
+	}
+	if bundle != nil {
+		listVersions = bundle.ListVersions
+	}
+
 	// While the network churns on ListVersions() requests, statically analyze
 	// code from the current project.
 	ptree := p.RootPackageTree
@@ -945,6 +1454,14 @@ func (cmd *statusCommand) runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Proje
 		errListPkgCh := make(chan error, len(slp))
 		errListVerCh := make(chan error, len(slp))
 
+		kinds := p.DependencyKinds()
+		notes := &dep.Notes{}
+		if cmd.detail {
+			if notes, err = dep.ReadNotes(p.AbsRoot); err != nil {
+				return false, 0, err
+			}
+		}
+
 		var wg sync.WaitGroup
 
 		for i, proj := range slp {
@@ -955,6 +1472,7 @@ func (cmd *statusCommand) runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Proje
 				bs := BasicStatus{
 					ProjectRoot:  string(proj.Ident().ProjectRoot),
 					PackageCount: len(proj.Packages()),
+					Kind:         kinds[proj.Ident().ProjectRoot],
 				}
 
 				// Get children only for specific outputers
@@ -1016,7 +1534,7 @@ func (cmd *statusCommand) runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Proje
 					// transitive project deps will always show "any" here.
 					bs.Constraint = c.Constraint
 
-					vl, err := sm.ListVersions(proj.Ident())
+					vl, err := listVersions(proj.Ident())
 					if err == nil {
 						gps.SortPairedForUpgrade(vl)
 
@@ -1042,6 +1560,8 @@ func (cmd *statusCommand) runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Proje
 					}
 				}
 
+				bs.PseudoVersion = proj.PseudoVersion
+
 				ds := DetailStatus{
 					BasicStatus: bs,
 				}
@@ -1051,6 +1571,7 @@ func (cmd *statusCommand) runStatusAll(ctx *dep.Ctx, out outputter, p *dep.Proje
 					ds.Packages = proj.Packages()
 					ds.PruneOpts = proj.PruneOpts
 					ds.Digest = proj.Digest
+					ds.Notes = notes.For(proj.Ident().ProjectRoot)
 				}
 
 				dsCh <- &ds
@@ -1242,21 +1763,24 @@ func formatVersion(v gps.Version) string {
 	return v.String()
 }
 
-func formatDetailVersion(v gps.Version, r gps.Revision) rawDetailVersion {
+func formatDetailVersion(v gps.Version, r gps.Revision, pseudo string) rawDetailVersion {
 	if v == nil {
 		return rawDetailVersion{
-			Revision: r.String(),
+			Revision:      r.String(),
+			PseudoVersion: pseudo,
 		}
 	}
 	switch v.Type() {
 	case gps.IsBranch:
 		return rawDetailVersion{
-			Branch:   v.String(),
-			Revision: r.String(),
+			Branch:        v.String(),
+			Revision:      r.String(),
+			PseudoVersion: pseudo,
 		}
 	case gps.IsRevision:
 		return rawDetailVersion{
-			Revision: v.String(),
+			Revision:      v.String(),
+			PseudoVersion: pseudo,
 		}
 	}
 
@@ -1273,7 +1797,7 @@ func formatDetailLatestVersion(v gps.Version, hasError bool) rawDetailVersion {
 		}
 	}
 
-	return formatDetailVersion(v, "")
+	return formatDetailVersion(v, "", "")
 }
 
 // projectConstraint stores ProjectRoot and Constraint for that project.
@@ -1328,6 +1852,12 @@ func collectConstraints(ctx *dep.Ctx, p *dep.Project, sm gps.SourceManager) (con
 			defer wg.Done()
 
 			manifest, _, err := sm.GetManifestAndLock(proj.Ident(), proj.Version(), rootAnalyzer)
+			if errors.Cause(err) == gps.ErrSourceManagerIsReadOnly {
+				// Without a local copy of the dependency, only the constraints
+				// of the root project are known, which is not an error.
+				logger.Println(err)
+				return
+			}
 			if err != nil {
 				errCh <- errors.Wrap(err, "error getting manifest and lock")
 				return
@@ -1465,6 +1995,9 @@ const statusLockTemplate = `# This file is autogenerated, do not edit; changes m
   name = "{{$p.ProjectRoot}}"
   packages = {{(tomlStrSplit $p.Packages)}}
   pruneopts = "{{$p.PruneOpts}}"
+  {{- if $p.Locked.PseudoVersion}}
+  pseudo-version = "{{$p.Locked.PseudoVersion}}"
+  {{- end}}
   revision = "{{$p.Locked.Revision}}"
   {{- if $p.Source}}
   source = "{{$p.Source}}"
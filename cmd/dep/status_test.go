@@ -225,7 +225,7 @@ func TestDetailLine(t *testing.T) {
 			},
 			wantDotStatus:        []string{`[label="github.com/foo/bar"];`},
 			wantJSONStatus:       []string{`"Locked":{}`},
-			wantTableStatus:      []string{`github.com/foo/bar                                                 []`},
+			wantTableStatus:      []string{`github.com/foo/bar                                                       []`},
 			wantTemplateStatus:   []string{`PR:github.com/foo/bar, Src:, Const:, Ver:, Rev:, Lat:, PkgCt:0, Pkgs:[]`},
 			wantEqTemplateStatus: []string{`||`},
 		},
@@ -240,7 +240,7 @@ func TestDetailLine(t *testing.T) {
 			},
 			wantDotStatus:        []string{`[label="github.com/foo/bar\nflooboo"];`},
 			wantJSONStatus:       []string{`"Locked":{"Revision":"flooboofoobooo"}`, `"Constraint":""`},
-			wantTableStatus:      []string{`github.com/foo/bar                               flooboo           []`},
+			wantTableStatus:      []string{`github.com/foo/bar                                     flooboo           []`},
 			wantTemplateStatus:   []string{`PR:github.com/foo/bar, Src:, Const:, Ver:, Rev:flooboofoobooo, Lat:, PkgCt:0, Pkgs:[]`},
 			wantEqTemplateStatus: []string{`|Revision is flooboofoobooo|`},
 		},
@@ -255,7 +255,7 @@ func TestDetailLine(t *testing.T) {
 			},
 			wantDotStatus:        []string{`[label="github.com/foo/bar"];`},
 			wantJSONStatus:       []string{`"Locked":{}`, `"Source":"github.com/baz/bar"`, `"Constraint":""`},
-			wantTableStatus:      []string{`github.com/foo/bar  github.com/baz/bar                                         []`},
+			wantTableStatus:      []string{`github.com/foo/bar        github.com/baz/bar                                         []`},
 			wantTemplateStatus:   []string{`PR:github.com/foo/bar, Src:github.com/baz/bar, Const:, Ver:, Rev:, Lat:, PkgCt:0, Pkgs:[]`},
 			wantEqTemplateStatus: []string{`||`},
 		},
@@ -271,7 +271,7 @@ func TestDetailLine(t *testing.T) {
 			},
 			wantDotStatus:        []string{`[label="github.com/foo/bar\n1.0.0"];`},
 			wantJSONStatus:       []string{`"Version":"1.0.0"`, `"Revision":"flooboofoobooo"`, `"Constraint":""`},
-			wantTableStatus:      []string{`github.com/foo/bar                      1.0.0    flooboo           []`},
+			wantTableStatus:      []string{`github.com/foo/bar                            1.0.0    flooboo           []`},
 			wantTemplateStatus:   []string{`PR:github.com/foo/bar, Src:, Const:, Ver:1.0.0, Rev:flooboofoobooo, Lat:, PkgCt:0, Pkgs:[]`},
 			wantEqTemplateStatus: []string{`||`},
 		},
@@ -288,7 +288,7 @@ func TestDetailLine(t *testing.T) {
 			},
 			wantDotStatus:        []string{`[label="github.com/foo/bar\n1.0.0"];`},
 			wantJSONStatus:       []string{`"Revision":"revxyz"`, `"Constraint":"1.2.3"`, `"Version":"1.0.0"`},
-			wantTableStatus:      []string{`github.com/foo/bar          1.2.3       1.0.0    revxyz            []`},
+			wantTableStatus:      []string{`github.com/foo/bar                1.2.3       1.0.0    revxyz            []`},
 			wantTemplateStatus:   []string{`PR:github.com/foo/bar, Src:, Const:1.2.3, Ver:1.0.0, Rev:revxyz, Lat:, PkgCt:0, Pkgs:[]`},
 			wantEqTemplateStatus: []string{`Constraint is 1.2.3||`},
 		},
@@ -306,7 +306,7 @@ func TestDetailLine(t *testing.T) {
 			},
 			wantDotStatus:        []string{`[label="github.com/foo/bar\n1.0.0"];`},
 			wantJSONStatus:       []string{`"Revision":"revxyz"`, `"Constraint":"1.2.3"`, `"Version":"1.0.0"`, `"PackageCount":1`, `"Packages":["."]`},
-			wantTableStatus:      []string{`github.com/foo/bar          1.2.3       1.0.0    revxyz            [.]`},
+			wantTableStatus:      []string{`github.com/foo/bar                1.2.3       1.0.0    revxyz            [.]`},
 			wantTemplateStatus:   []string{`PR:github.com/foo/bar, Src:, Const:1.2.3, Ver:1.0.0, Rev:revxyz, Lat:, PkgCt:1, Pkgs:[.]`},
 			wantEqTemplateStatus: []string{`Constraint is 1.2.3||`},
 		},
@@ -324,7 +324,7 @@ func TestDetailLine(t *testing.T) {
 			},
 			wantDotStatus:        []string{`[label="github.com/foo/bar\n1.0.0"];`},
 			wantJSONStatus:       []string{`"Revision":"revxyz"`, `"Constraint":"1.2.3"`, `"Version":"1.0.0"`, `"PackageCount":3`, `"Packages":[".","foo","bar"]`},
-			wantTableStatus:      []string{`github.com/foo/bar          1.2.3       1.0.0    revxyz            [., foo, bar]`},
+			wantTableStatus:      []string{`github.com/foo/bar                1.2.3       1.0.0    revxyz            [., foo, bar]`},
 			wantTemplateStatus:   []string{`PR:github.com/foo/bar, Src:, Const:1.2.3, Ver:1.0.0, Rev:revxyz, Lat:, PkgCt:3, Pkgs:[. foo bar]`},
 			wantEqTemplateStatus: []string{`Constraint is 1.2.3||`},
 		},
@@ -339,7 +339,7 @@ func TestDetailLine(t *testing.T) {
 			},
 			wantDotStatus:        []string{`[label="github.com/foo/bar"];`},
 			wantJSONStatus:       []string{`"Locked":{}`, `"Latest":{"Revision":"unknown"}`},
-			wantTableStatus:      []string{`github.com/foo/bar                                         unknown  []`},
+			wantTableStatus:      []string{`github.com/foo/bar                                               unknown  []`},
 			wantTemplateStatus:   []string{`PR:github.com/foo/bar, Src:, Const:, Ver:, Rev:, Lat:unknown, PkgCt:0, Pkgs:[]`},
 			wantEqTemplateStatus: []string{`||Latest is unknown`},
 		},
@@ -830,6 +830,16 @@ func TestValidateFlags(t *testing.T) {
 			cmd:     statusCommand{missing: true, old: true},
 			wantErr: errors.Wrapf(errors.New("cannot pass multiple operating mode flags"), "[-old -missing]"),
 		},
+		{
+			name:    "lint with -old",
+			cmd:     statusCommand{lint: true, old: true},
+			wantErr: errors.Wrapf(errors.New("cannot pass multiple operating mode flags"), "[-old -lint]"),
+		},
+		{
+			name:    "health with -lint",
+			cmd:     statusCommand{lint: true, health: true},
+			wantErr: errors.Wrapf(errors.New("cannot pass multiple operating mode flags"), "[-lint -health]"),
+		},
 		{
 			name:    "old with -dot",
 			cmd:     statusCommand{dot: true, old: true},
@@ -840,6 +850,36 @@ func TestValidateFlags(t *testing.T) {
 			cmd:     statusCommand{old: true, template: "foo"},
 			wantErr: nil,
 		},
+		{
+			name:    "csv with -detail and columns",
+			cmd:     statusCommand{format: "csv", detail: true, columns: "project,Source"},
+			wantErr: nil,
+		},
+		{
+			name:    "csv with detail column",
+			cmd:     statusCommand{format: "csv", columns: "project,source"},
+			wantErr: errors.New(`unknown column "source", the columns are project, constraint, version, revision, pseudo-version, latest, pkgs-used, kind`),
+		},
+		{
+			name:    "unsupported format",
+			cmd:     statusCommand{format: "xlsx"},
+			wantErr: errors.New(`unsupported format "xlsx" for -o, must be csv or tsv`),
+		},
+		{
+			name:    "tsv with -json",
+			cmd:     statusCommand{format: "tsv", json: true},
+			wantErr: errors.New("cannot pass multiple output format flags"),
+		},
+		{
+			name:    "csv with -lint",
+			cmd:     statusCommand{format: "csv", lint: true},
+			wantErr: errors.New("-o cannot be used with -lint"),
+		},
+		{
+			name:    "columns without -o",
+			cmd:     statusCommand{columns: "project"},
+			wantErr: errors.New("-columns can only be used with -o"),
+		},
 	}
 
 	for _, tc := range testCases {
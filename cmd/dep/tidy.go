@@ -0,0 +1,224 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const tidyShortHelp = `Tighten up Gopkg.toml to match the project`
+const tidyLongHelp = `
+Propose changes to Gopkg.toml that make it describe the project more
+faithfully, and write them once confirmed.
+
+With -constrain-direct, each direct dependency that Gopkg.toml does not
+constrain is given a constraint admitting its version in Gopkg.lock: a caret
+range (^1.2.0) for a semver version, and the branch or tag itself otherwise.
+Without a constraint, dep ensure -update may move a dependency to any version
+at all, including across major versions. Dependencies locked only to a
+revision are listed, but no constraint is proposed for them.
+
+The proposed constraints are printed, and appended to Gopkg.toml as
+[[constraint]] tables if the answer to the prompt that follows is yes. Pass
+-yes to skip the prompt, or -dry-run to only print them. dep status
+-suggest-constraints prints the same proposals without offering to write them.
+`
+
+type tidyCommand struct {
+	constrainDirect bool
+	yes             bool
+	dryRun          bool
+}
+
+func (cmd *tidyCommand) Name() string      { return "tidy" }
+func (cmd *tidyCommand) Args() string      { return "-constrain-direct [-yes | -dry-run]" }
+func (cmd *tidyCommand) ShortHelp() string { return tidyShortHelp }
+func (cmd *tidyCommand) LongHelp() string  { return tidyLongHelp }
+func (cmd *tidyCommand) Hidden() bool      { return false }
+
+func (cmd *tidyCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.constrainDirect, "constrain-direct", false, "constrain each unconstrained direct dependency to its locked version")
+	fs.BoolVar(&cmd.yes, "yes", false, "write the proposed changes without asking")
+	fs.BoolVar(&cmd.dryRun, "dry-run", false, "only print the proposed changes")
+}
+
+func (cmd *tidyCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) != 0 {
+		return errors.New("tidy takes no arguments")
+	}
+	if !cmd.constrainDirect {
+		return errors.New("nothing to tidy; pass -constrain-direct")
+	}
+	if cmd.yes && cmd.dryRun {
+		return errors.New("cannot pass both -yes and -dry-run")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("constraints are proposed from the versions in %s, but %s does not exist", dep.LockName, dep.LockName)
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	direct, err := p.GetDirectDependencyNames(sm)
+	if err != nil {
+		return err
+	}
+	suggestions := suggestConstraints(p.Manifest, p.Lock, direct)
+	if len(suggestions) == 0 {
+		ctx.Out.Println("Every direct dependency is already constrained.")
+		return nil
+	}
+	if err := printConstraintSuggestions(ctx.Out.Writer(), suggestions, false); err != nil {
+		return err
+	}
+
+	appender := dep.NewManifest()
+	for _, s := range suggestions {
+		if s.Constraint != nil {
+			appender.Constraints[s.ProjectRoot] = gps.ProjectProperties{Source: s.Source, Constraint: s.Constraint}
+		}
+	}
+	if len(appender.Constraints) == 0 || cmd.dryRun {
+		return nil
+	}
+	if !cmd.yes {
+		ok, err := confirm(ctx.Out.Writer(), os.Stdin, fmt.Sprintf("Add %d constraints to %s?", len(appender.Constraints), dep.ManifestName))
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	extra, err := appender.MarshalTOML()
+	if err != nil {
+		return errors.Wrap(err, "could not marshal manifest into TOML")
+	}
+	f, err := os.OpenFile(filepath.Join(p.AbsRoot, dep.ManifestName), os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "opening %s failed", dep.ManifestName)
+	}
+	if _, err := f.Write(extra); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "writing to %s failed", dep.ManifestName)
+	}
+	return errors.Wrapf(f.Close(), "closing %s", dep.ManifestName)
+}
+
+// confirm writes question to w and reads the answer from r, reporting whether
+// it was yes. Anything else, including no answer at all, is taken as no.
+func confirm(w io.Writer, r io.Reader, question string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// constraintSuggestion proposes a constraint for a direct dependency that the
+// manifest leaves unconstrained.
+type constraintSuggestion struct {
+	ProjectRoot gps.ProjectRoot
+	Source      string
+	// Locked is the version of the project in the lock.
+	Locked gps.Version
+	// Constraint is the proposed constraint, or nil if the project is locked
+	// only to a revision.
+	Constraint gps.Constraint
+}
+
+type rawConstraintSuggestion struct {
+	ProjectRoot string `json:"projectRoot"`
+	Locked      string `json:"locked"`
+	Constraint  string `json:"constraint,omitempty"`
+}
+
+func (s constraintSuggestion) marshalJSON() rawConstraintSuggestion {
+	raw := rawConstraintSuggestion{
+		ProjectRoot: string(s.ProjectRoot),
+		Locked:      formatVersion(s.Locked),
+	}
+	if s.Constraint != nil {
+		raw.Constraint = s.Constraint.String()
+	}
+	return raw
+}
+
+// suggestConstraints proposes constraints for the projects in direct that m
+// neither constrains nor overrides, admitting their versions in l. The
+// results are sorted by project root.
+func suggestConstraints(m *dep.Manifest, l *dep.Lock, direct map[gps.ProjectRoot]bool) []constraintSuggestion {
+	var suggestions []constraintSuggestion
+	for _, lp := range l.Projects() {
+		id := lp.Ident()
+		if !direct[id.ProjectRoot] {
+			continue
+		}
+		if _, has := m.Constraints[id.ProjectRoot]; has {
+			continue
+		}
+		if _, has := m.Ovr[id.ProjectRoot]; has {
+			continue
+		}
+		suggestions = append(suggestions, constraintSuggestion{
+			ProjectRoot: id.ProjectRoot,
+			Source:      id.Source,
+			Locked:      lp.Version(),
+			Constraint:  getProjectPropertiesFromVersion(lp.Version()).Constraint,
+		})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool { return suggestions[i].ProjectRoot < suggestions[j].ProjectRoot })
+	return suggestions
+}
+
+// printConstraintSuggestions writes suggestions to w as a table, or as JSON.
+func printConstraintSuggestions(w io.Writer, suggestions []constraintSuggestion, asJSON bool) error {
+	if asJSON {
+		raw := make([]rawConstraintSuggestion, 0, len(suggestions))
+		for _, s := range suggestions {
+			raw = append(raw, s.marshalJSON())
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(raw)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tLOCKED\tSUGGESTED")
+	for _, s := range suggestions {
+		suggested := "- (locked to a revision)"
+		if s.Constraint != nil {
+			suggested = s.Constraint.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.ProjectRoot, formatVersion(s.Locked), suggested)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+func TestSuggestConstraints(t *testing.T) {
+	rev := gps.Revision("d4a1a8e2a4f50f8b4e610b7ab3e8b3a4a4b6c0de")
+	lp := func(root string, v gps.Version) gps.LockedProject {
+		return gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(root)}, v, nil)
+	}
+	l := &dep.Lock{P: []gps.LockedProject{
+		lp("github.com/semver/lib", gps.NewVersion("v1.2.0").Pair(rev)),
+		lp("github.com/branch/lib", gps.NewBranch("master").Pair(rev)),
+		lp("github.com/revision/lib", rev),
+		lp("github.com/constrained/lib", gps.NewVersion("v2.0.0").Pair(rev)),
+		lp("github.com/overridden/lib", gps.NewVersion("v3.0.0").Pair(rev)),
+		lp("github.com/transitive/lib", gps.NewVersion("v1.0.0").Pair(rev)),
+	}}
+	m := dep.NewManifest()
+	m.Constraints["github.com/constrained/lib"] = gps.ProjectProperties{Constraint: gps.Any()}
+	m.Ovr["github.com/overridden/lib"] = gps.ProjectProperties{Constraint: gps.Any()}
+	direct := map[gps.ProjectRoot]bool{
+		"github.com/semver/lib":      true,
+		"github.com/branch/lib":      true,
+		"github.com/revision/lib":    true,
+		"github.com/constrained/lib": true,
+		"github.com/overridden/lib":  true,
+	}
+
+	var buf bytes.Buffer
+	if err := printConstraintSuggestions(&buf, suggestConstraints(m, l, direct), false); err != nil {
+		t.Fatal(err)
+	}
+	want := `PROJECT                  LOCKED         SUGGESTED
+github.com/branch/lib    branch master  master
+github.com/revision/lib  d4a1a8e        - (locked to a revision)
+github.com/semver/lib    v1.2.0         ^1.2.0
+`
+	if buf.String() != want {
+		t.Errorf("unexpected table:\n\t(GOT):\n%s\n\t(WNT):\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := printConstraintSuggestions(&buf, suggestConstraints(m, l, direct), true); err != nil {
+		t.Fatal(err)
+	}
+	var raw []rawConstraintSuggestion
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw[1].Constraint != "" || raw[2].Constraint != "^1.2.0" {
+		t.Errorf("unexpected JSON: %+v", raw)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	cases := map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		" y ":   true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+		"yeah":  false,
+	}
+	for answer, want := range cases {
+		var out bytes.Buffer
+		got, err := confirm(&out, strings.NewReader(answer), "Proceed?")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("answer %q: expected %v, got %v", answer, want, got)
+		}
+		if out.String() != "Proceed? [y/N] " {
+			t.Errorf("unexpected prompt %q", out.String())
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// parseTools parses the VCS binaries pinned in $DEPTOOLS: a comma-separated
+// list of name=path entries, where path may be followed by @ and a semver
+// constraint on the version of the binary, as in git=/usr/bin/git@>=2.17.
+func parseTools(s string) ([]gps.VCSTool, error) {
+	var tools []gps.VCSTool
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		eq := strings.Index(entry, "=")
+		if eq < 1 {
+			return nil, errors.Errorf("tool %q must be given as name=path", entry)
+		}
+		t := gps.VCSTool{Name: entry[:eq], Path: entry[eq+1:]}
+		if at := strings.LastIndex(t.Path, "@"); at >= 0 {
+			c, err := gps.NewSemverConstraint(t.Path[at+1:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid version constraint for %s", t.Name)
+			}
+			t.Path, t.Constraint = t.Path[:at], c
+		}
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
+
+// formatTool formats t as an entry of $DEPTOOLS.
+func formatTool(t gps.VCSTool) string {
+	if t.Constraint == nil {
+		return t.Name + "=" + t.Path
+	}
+	return t.Name + "=" + t.Path + "@" + t.Constraint.String()
+}
+
+// parseCommandLimits parses the limits of VCS commands set in $DEPVCSTIMEOUT
+// and $DEPVCSRETRIES. Each is either a single value, applying to all
+// operations, or a comma-separated list of op=value entries, as in
+// clone=10m,ls-remote=30s.
+func parseCommandLimits(timeouts, retries string) (map[string]gps.CommandLimits, error) {
+	limits := make(map[string]gps.CommandLimits)
+	err := parseOpValues(timeouts, func(op, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		l := limits[op]
+		l.Timeout = d
+		limits[op] = l
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid $DEPVCSTIMEOUT")
+	}
+	err = parseOpValues(retries, func(op, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		l := limits[op]
+		l.Retries = n
+		limits[op] = l
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid $DEPVCSRETRIES")
+	}
+	return limits, nil
+}
+
+// parseOpValues calls set with each operation and value in s, which is either
+// a single value for all of gps.CommandOps, or a list of op=value entries.
+func parseOpValues(s string, set func(op, v string) error) error {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	if !strings.Contains(s, "=") {
+		for _, op := range gps.CommandOps {
+			if err := set(op, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, entry := range splitPrefixList(s) {
+		eq := strings.Index(entry, "=")
+		if eq < 1 {
+			return errors.Errorf("%q must be given as op=value", entry)
+		}
+		if err := set(strings.TrimSpace(entry[:eq]), strings.TrimSpace(entry[eq+1:])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatCommandLimits formats the limits of each operation, in the order of
+// gps.CommandOps.
+func formatCommandLimits(limits map[string]gps.CommandLimits) []string {
+	var s []string
+	for _, op := range gps.CommandOps {
+		l, has := limits[op]
+		if !has {
+			continue
+		}
+		var parts []string
+		if l.Timeout > 0 {
+			parts = append(parts, "timeout "+l.Timeout.String())
+		}
+		if l.Retries > 0 {
+			parts = append(parts, fmt.Sprintf("%d retries", l.Retries))
+		}
+		if len(parts) > 0 {
+			s = append(s, op+": "+strings.Join(parts, ", "))
+		}
+	}
+	return s
+}
+
+// parseModuleProxy parses $DEPPROXY: the URL of a Go module proxy, optionally
+// listed with direct, for the sources of projects, in the order in which they
+// are to be tried, as in GOPROXY. A URL alone is tried after direct.
+func parseModuleProxy(s string) (proxy string, first bool, err error) {
+	entries := splitPrefixList(s)
+	for i, entry := range entries {
+		if entry == "direct" {
+			continue
+		}
+		if proxy != "" {
+			return "", false, errors.New("only one module proxy may be given")
+		}
+		proxy, first = entry, i == 0 && len(entries) > 1
+	}
+	if proxy == "" && len(entries) > 0 {
+		return "", false, errors.New("no module proxy is given")
+	}
+	return proxy, first, nil
+}
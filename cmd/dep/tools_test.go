@@ -0,0 +1,92 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestParseTools(t *testing.T) {
+	tools, err := parseTools(" git=/usr/bin/git@>=2.17, hg=/opt/hg/bin/hg ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 2 {
+		t.Fatalf("expected two tools, got %v", tools)
+	}
+	if tools[0].Name != "git" || tools[0].Path != "/usr/bin/git" || tools[0].Constraint == nil || tools[0].Constraint.String() != ">=2.17.0" {
+		t.Errorf("unexpected git pin %+v", tools[0])
+	}
+	if tools[1].Name != "hg" || tools[1].Path != "/opt/hg/bin/hg" || tools[1].Constraint != nil {
+		t.Errorf("unexpected hg pin %+v", tools[1])
+	}
+
+	for _, in := range []string{"/usr/bin/git", "=/usr/bin/git", "git=/usr/bin/git@not a version"} {
+		if _, err := parseTools(in); err == nil {
+			t.Errorf("expected an error for %q", in)
+		}
+	}
+}
+
+func TestParseCommandLimits(t *testing.T) {
+	limits, err := parseCommandLimits("5m", "clone=2, fetch=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range gps.CommandOps {
+		if limits[op].Timeout != 5*time.Minute {
+			t.Errorf("expected a timeout of 5m for %s, got %s", op, limits[op].Timeout)
+		}
+	}
+	if limits[gps.OpClone].Retries != 2 || limits[gps.OpFetch].Retries != 1 || limits[gps.OpLsRemote].Retries != 0 {
+		t.Errorf("unexpected retries %+v", limits)
+	}
+
+	limits, err = parseCommandLimits("ls-remote=30s", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]gps.CommandLimits{gps.OpLsRemote: {Timeout: 30 * time.Second}}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("expected limits %v, got %v", want, limits)
+	}
+	if got := formatCommandLimits(limits); !reflect.DeepEqual(got, []string{"ls-remote: timeout 30s"}) {
+		t.Errorf("unexpected formatted limits %q", got)
+	}
+
+	for _, in := range [][2]string{{"forever", ""}, {"", "clone=often"}, {"=5m", ""}} {
+		if _, err := parseCommandLimits(in[0], in[1]); err == nil {
+			t.Errorf("expected an error for %q", in)
+		}
+	}
+}
+
+func TestParseModuleProxy(t *testing.T) {
+	for in, want := range map[string]struct {
+		proxy string
+		first bool
+	}{
+		"":                                 {},
+		"https://proxy.golang.org":         {"https://proxy.golang.org", false},
+		"direct, https://proxy.golang.org": {"https://proxy.golang.org", false},
+		"https://proxy.golang.org,direct":  {"https://proxy.golang.org", true},
+		" https://proxy.example.com/go/ ,": {"https://proxy.example.com/go/", false},
+	} {
+		proxy, first, err := parseModuleProxy(in)
+		if err != nil || proxy != want.proxy || first != want.first {
+			t.Errorf("%q: expected %q, %v, got %q, %v, %v", in, want.proxy, want.first, proxy, first, err)
+		}
+	}
+
+	for _, in := range []string{"direct", "https://a.example.com,https://b.example.com"} {
+		if _, _, err := parseModuleProxy(in); err == nil {
+			t.Errorf("expected an error for %q", in)
+		}
+	}
+}
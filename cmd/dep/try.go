@@ -0,0 +1,206 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+	"github.com/pkg/errors"
+)
+
+const tryShortHelp = `Run a command against another version of a dependency`
+const tryLongHelp = `
+Run <command> as if vendor/ held <version> of <project>, without touching
+Gopkg.lock or vendor/.
+
+The requested version is written into a temporary directory, and an overlay is
+passed to the go command through $GOFLAGS (as -overlay, which requires Go 1.16
+or later), so that go build, go test and the like see the files of that version
+in place of those in vendor/. Commands other than the go command are run with
+the same $GOFLAGS, but see vendor/ unchanged. The temporary directory is
+removed when the command exits.
+
+<project> must be in Gopkg.lock. <version> may be a tag, a branch or a
+revision.
+
+Example:
+
+  dep try github.com/foo/bar@v1.5.0 -- go test ./...
+`
+
+type tryCommand struct{}
+
+func (cmd *tryCommand) Name() string      { return "try" }
+func (cmd *tryCommand) Args() string      { return "<project>@<version> -- <command>..." }
+func (cmd *tryCommand) ShortHelp() string { return tryShortHelp }
+func (cmd *tryCommand) LongHelp() string  { return tryLongHelp }
+func (cmd *tryCommand) Hidden() bool      { return false }
+
+func (cmd *tryCommand) Register(fs *flag.FlagSet) {}
+
+func (cmd *tryCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) < 2 {
+		return errors.New("try requires a <project>@<version> and a command to run")
+	}
+	at := strings.LastIndex(args[0], "@")
+	if at <= 0 || at == len(args[0])-1 {
+		return errors.Errorf("%s must be of the form <project>@<version>", args[0])
+	}
+	root, version := gps.ProjectRoot(args[0][:at]), args[0][at+1:]
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("no %s found in %s, run dep ensure first", dep.LockName, p.AbsRoot)
+	}
+	var lp gps.LockedProject
+	for _, candidate := range p.Lock.Projects() {
+		if candidate.Ident().ProjectRoot == root {
+			lp = candidate
+		}
+	}
+	if lp == nil {
+		return errors.Errorf("%s is not in %s", root, dep.LockName)
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	v, err := findVersion(sm, lp.Ident(), version)
+	if err != nil {
+		return err
+	}
+
+	td, err := ioutil.TempDir("", "dep-try")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(td)
+
+	prune := p.Manifest.PruneOptions.PruneOptionsFor(root)
+	if vp, ok := lp.(verify.VerifiableProject); ok {
+		prune = vp.PruneOpts
+	}
+	newDir := filepath.Join(td, "src")
+	if err := sm.ExportPrunedProject(context.TODO(), gps.NewLockedProject(lp.Ident(), v, lp.Packages()), prune, newDir); err != nil {
+		return errors.Wrapf(err, "could not write %s@%s", root, v)
+	}
+
+	replace, err := vendorOverlay(filepath.Join(p.AbsRoot, "vendor", string(root)), newDir)
+	if err != nil {
+		return err
+	}
+	overlay := filepath.Join(td, "overlay.json")
+	if err := writeOverlay(overlay, replace); err != nil {
+		return err
+	}
+
+	ctx.Err.Printf("# Running %s with %s@%s\n", strings.Join(args[1:], " "), root, v)
+	// SourceMgr holds a lock on the cache; release it so that the command may
+	// itself run dep.
+	sm.Release()
+
+	c := exec.Command(args[1], args[2:]...)
+	c.Dir = ctx.WorkingDir
+	c.Env = append(os.Environ(), "GOFLAGS="+strings.TrimSpace(os.Getenv("GOFLAGS")+" -overlay="+overlay))
+	c.Stdin = os.Stdin
+	c.Stdout = ctx.Out.Writer()
+	c.Stderr = ctx.Err.Writer()
+	if err := c.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return silentfail{}
+		}
+		return errors.Wrapf(err, "could not run %s", args[1])
+	}
+	return nil
+}
+
+// findVersion returns the version of the source for id named by name, which
+// is taken to be a revision if no branch or tag has that name.
+func findVersion(sm gps.SourceManager, id gps.ProjectIdentifier, name string) (gps.Version, error) {
+	vl, err := sm.ListVersions(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not list versions of %s", id)
+	}
+	for _, v := range vl {
+		if v.String() == name {
+			return v, nil
+		}
+	}
+
+	r := gps.Revision(name)
+	present, err := sm.RevisionPresentIn(id, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not look for revision %s in %s", name, id)
+	}
+	if !present {
+		return nil, errors.Errorf("%s has no version or revision named %s", id, name)
+	}
+	return r, nil
+}
+
+// vendorOverlay returns the replacements that make the go command see the
+// tree at newDir in place of that at vendorDir: every file under newDir
+// replaces the file at the same relative path under vendorDir, and every file
+// under vendorDir that newDir lacks is deleted.
+func vendorOverlay(vendorDir, newDir string) (map[string]string, error) {
+	replace := make(map[string]string)
+	err := filepath.Walk(newDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(newDir, path)
+		if err != nil {
+			return err
+		}
+		replace[filepath.Join(vendorDir, rel)] = path
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to walk the new version")
+	}
+
+	err = filepath.Walk(vendorDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if path == vendorDir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if _, has := replace[path]; !has && !fi.IsDir() {
+			replace[path] = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to walk vendor")
+	}
+	return replace, nil
+}
+
+// writeOverlay writes replace to path as an overlay file for the go command.
+func writeOverlay(path string, replace map[string]string) error {
+	b, err := json.Marshal(struct{ Replace map[string]string }{replace})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode overlay")
+	}
+	return errors.Wrap(ioutil.WriteFile(path, b, 0666), "failed to write overlay")
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestVendorOverlay(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("vendor/github.com/foo/bar/bar.go", "package bar")
+	h.TempFile("vendor/github.com/foo/bar/old.go", "package bar")
+	h.TempFile("new/bar.go", "package bar // new")
+	h.TempFile("new/sub/sub.go", "package sub")
+	root := h.Path(".")
+	vendorDir := filepath.Join(root, "vendor/github.com/foo/bar")
+	newDir := filepath.Join(root, "new")
+
+	replace, err := vendorOverlay(vendorDir, newDir)
+	h.Must(err)
+	want := map[string]string{
+		filepath.Join(vendorDir, "bar.go"):     filepath.Join(newDir, "bar.go"),
+		filepath.Join(vendorDir, "sub/sub.go"): filepath.Join(newDir, "sub/sub.go"),
+		filepath.Join(vendorDir, "old.go"):     "",
+	}
+	if !reflect.DeepEqual(replace, want) {
+		t.Errorf("unexpected overlay:\n\t(GOT): %v\n\t(WNT): %v", replace, want)
+	}
+
+	// A project missing from vendor is only added.
+	replace, err = vendorOverlay(filepath.Join(root, "vendor/github.com/baz/qux"), newDir)
+	h.Must(err)
+	if len(replace) != 2 {
+		t.Errorf("expected only the two new files in the overlay, got %v", replace)
+	}
+
+	overlay := filepath.Join(root, "overlay.json")
+	h.Must(writeOverlay(overlay, want))
+	b, err := ioutil.ReadFile(overlay)
+	h.Must(err)
+	var got struct{ Replace map[string]string }
+	h.Must(json.Unmarshal(b, &got))
+	if !reflect.DeepEqual(got.Replace, want) {
+		t.Errorf("unexpected overlay file:\n\t(GOT): %v\n\t(WNT): %v", got.Replace, want)
+	}
+}
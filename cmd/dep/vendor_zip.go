@@ -0,0 +1,375 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps/verify"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+)
+
+const vendorZipShortHelp = `Write a reproducible zip of vendor, with an attestation`
+const vendorZipLongHelp = `
+Write the vendor directory of the current project into a zip archive that is
+byte-for-byte identical whenever the vendor directory is: entries are written
+in lexical order, with fixed timestamps and normalized permissions. The
+vendor directory must match the digests in Gopkg.lock; run dep check first if
+in doubt.
+
+Alongside the archive, an attestation is written into <output>.attestation.json.
+It is an in-toto statement, wrapped in a DSSE envelope, naming the archive and
+its SHA-256 digest, the SHA-256 digest of Gopkg.lock, the version of dep that
+wrote it and a timestamp. The timestamp is taken from $SOURCE_DATE_EPOCH if it
+is set, so that the attestation can be reproduced too. If -key is given, the
+envelope is signed with the Ed25519 private key in that file, which must be
+PEM-encoded in PKCS #8 form, as written by:
+
+  openssl genpkey -algorithm ed25519 -out key.pem
+
+Flags:
+
+  -o    Path of the archive to write (default: vendor.zip in the project root)
+  -key  File holding the private key to sign the attestation with
+`
+
+const (
+	// vendorZipStatementType is the type of the in-toto statement in the
+	// attestation.
+	vendorZipStatementType = "https://in-toto.io/Statement/v0.1"
+	// vendorZipPredicateType identifies the contents of the predicate.
+	vendorZipPredicateType = "https://github.com/golang/dep/vendor-zip/v1"
+	// vendorZipPayloadType is the DSSE payload type of the statement.
+	vendorZipPayloadType = "application/vnd.in-toto+json"
+)
+
+// vendorZipModTime is the modification time given every entry in the
+// archive; it is the earliest time representable in a zip file.
+var vendorZipModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type vendorZipCommand struct {
+	output string
+	key    string
+}
+
+func (cmd *vendorZipCommand) Name() string      { return "vendor-zip" }
+func (cmd *vendorZipCommand) Args() string      { return "[-o output] [-key file]" }
+func (cmd *vendorZipCommand) ShortHelp() string { return vendorZipShortHelp }
+func (cmd *vendorZipCommand) LongHelp() string  { return vendorZipLongHelp }
+func (cmd *vendorZipCommand) Hidden() bool      { return false }
+
+func (cmd *vendorZipCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.output, "o", "", "path of the archive to write")
+	fs.StringVar(&cmd.key, "key", "", "file holding the Ed25519 private key to sign the attestation with")
+}
+
+func (cmd *vendorZipCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.New("vendor-zip takes no arguments")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("no Gopkg.lock found in %s, run dep ensure first", p.AbsRoot)
+	}
+
+	var key ed25519.PrivateKey
+	if cmd.key != "" {
+		if key, err = readSigningKey(cmd.key); err != nil {
+			return err
+		}
+	}
+
+	statuses, err := p.VerifyVendor()
+	if err != nil {
+		return errors.Wrap(err, "error while verifying vendor")
+	}
+	for pr, status := range statuses {
+		if status != verify.NoMismatch {
+			return errors.Errorf("vendor is out of sync with Gopkg.lock at %s, run dep ensure", pr)
+		}
+	}
+
+	lockBytes, err := ioutil.ReadFile(filepath.Join(p.AbsRoot, dep.LockName))
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", dep.LockName)
+	}
+
+	output := cmd.output
+	if output == "" {
+		output = filepath.Join(p.AbsRoot, "vendor.zip")
+	}
+
+	var buf bytes.Buffer
+	if err := writeVendorZip(&buf, filepath.Join(p.AbsRoot, "vendor")); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(output, buf.Bytes(), 0666); err != nil {
+		return errors.Wrapf(err, "failed to write %s", output)
+	}
+
+	timestamp, err := sourceDateEpoch(os.Getenv("SOURCE_DATE_EPOCH"))
+	if err != nil {
+		return err
+	}
+	st := newVendorZipStatement(filepath.Base(output), buf.Bytes(), lockBytes, timestamp)
+	env, err := signStatement(st, key)
+	if err != nil {
+		return err
+	}
+	att, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode attestation")
+	}
+	if err := ioutil.WriteFile(output+".attestation.json", append(att, '\n'), 0666); err != nil {
+		return errors.Wrap(err, "failed to write attestation")
+	}
+
+	ctx.Out.Printf("%s sha256:%s\n", output, st.Subject[0].Digest["sha256"])
+	return nil
+}
+
+// writeVendorZip writes the tree rooted at vendorDir into w as a zip archive,
+// with entry names relative to the parent of vendorDir. The archive depends
+// only upon the names, contents, executable bits and symlink targets of the
+// files in the tree.
+func writeVendorZip(w io.Writer, vendorDir string) error {
+	base := filepath.Dir(vendorDir)
+
+	var paths []string
+	err := filepath.Walk(vendorDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to walk vendor")
+	}
+	sort.Strings(paths)
+
+	zw := zip.NewWriter(w)
+	for _, path := range paths {
+		if err := addVendorZipEntry(zw, base, path); err != nil {
+			return err
+		}
+	}
+	return errors.Wrap(zw.Close(), "failed to write archive")
+}
+
+func addVendorZipEntry(zw *zip.Writer, base, path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return err
+	}
+
+	fh := &zip.FileHeader{
+		Name:     filepath.ToSlash(rel),
+		Method:   zip.Deflate,
+		Modified: vendorZipModTime,
+	}
+
+	var content []byte
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		fh.SetMode(os.ModeSymlink | 0777)
+		content = []byte(filepath.ToSlash(target))
+	case fi.Mode().IsRegular():
+		if fi.Mode()&0111 != 0 {
+			fh.SetMode(0755)
+		} else {
+			fh.SetMode(0644)
+		}
+		if content, err = ioutil.ReadFile(path); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("cannot archive %s: not a regular file or symlink", path)
+	}
+
+	fw, err := zw.CreateHeader(fh)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add %s to archive", fh.Name)
+	}
+	_, err = fw.Write(content)
+	return errors.Wrapf(err, "failed to add %s to archive", fh.Name)
+}
+
+// sourceDateEpoch returns the time given by a $SOURCE_DATE_EPOCH value, or
+// the current time if it is empty.
+func sourceDateEpoch(v string) (time.Time, error) {
+	if v == "" {
+		return time.Now().UTC(), nil
+	}
+	secs, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid $SOURCE_DATE_EPOCH %q: must be a number of seconds", v)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
+
+// vendorZipStatement is an in-toto statement about a vendor archive.
+type vendorZipStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []statementSubject   `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     vendorZipAttestation `json:"predicate"`
+}
+
+type statementSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// vendorZipAttestation records how a vendor archive was produced.
+type vendorZipAttestation struct {
+	LockDigest  map[string]string `json:"lockDigest"`
+	Tool        string            `json:"tool"`
+	ToolVersion string            `json:"toolVersion"`
+	Timestamp   string            `json:"timestamp"`
+}
+
+func newVendorZipStatement(name string, archive, lock []byte, timestamp time.Time) vendorZipStatement {
+	return vendorZipStatement{
+		Type: vendorZipStatementType,
+		Subject: []statementSubject{{
+			Name:   name,
+			Digest: map[string]string{"sha256": sha256Hex(archive)},
+		}},
+		PredicateType: vendorZipPredicateType,
+		Predicate: vendorZipAttestation{
+			LockDigest:  map[string]string{"sha256": sha256Hex(lock)},
+			Tool:        "dep",
+			ToolVersion: version,
+			Timestamp:   timestamp.Format(time.RFC3339),
+		},
+	}
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// dsseEnvelope is a DSSE envelope, as used by in-toto and SLSA.
+type dsseEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+type dsseSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// dssePAE returns the DSSE pre-authentication encoding of payload, which is
+// what is signed.
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// signStatement wraps st in a DSSE envelope, signed with key unless it is
+// nil.
+func signStatement(st vendorZipStatement, key ed25519.PrivateKey) (dsseEnvelope, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return dsseEnvelope{}, errors.Wrap(err, "failed to encode attestation statement")
+	}
+
+	env := dsseEnvelope{
+		PayloadType: vendorZipPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []dsseSignature{},
+	}
+	if key != nil {
+		pub := key.Public().(ed25519.PublicKey)
+		env.Signatures = append(env.Signatures, dsseSignature{
+			KeyID: sha256Hex(pub),
+			Sig:   base64.StdEncoding.EncodeToString(ed25519.Sign(key, dssePAE(env.PayloadType, payload))),
+		})
+	}
+	return env, nil
+}
+
+// readSigningKey reads a PEM-encoded PKCS #8 Ed25519 private key from the
+// file at path.
+func readSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read signing key")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.Errorf("%s does not hold a PEM-encoded PKCS #8 private key", path)
+	}
+	key, err := parseEd25519PKCS8(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse signing key %s", path)
+	}
+	return key, nil
+}
+
+// oidEd25519 is the algorithm identifier of Ed25519 keys, from RFC 8410.
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+// parseEd25519PKCS8 parses a DER-encoded PKCS #8 Ed25519 private key. It is
+// done here, rather than with crypto/x509, which only knows Ed25519 keys from
+// Go 1.13 on.
+func parseEd25519PKCS8(der []byte) (ed25519.PrivateKey, error) {
+	var pkcs8 struct {
+		Version    int
+		Algo       struct{ Algorithm asn1.ObjectIdentifier }
+		PrivateKey []byte
+	}
+	if rest, err := asn1.Unmarshal(der, &pkcs8); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, errors.New("trailing data after the key")
+	}
+	if !pkcs8.Algo.Algorithm.Equal(oidEd25519) {
+		return nil, errors.New("not an Ed25519 key")
+	}
+	var seed []byte
+	if _, err := asn1.Unmarshal(pkcs8.PrivateKey, &seed); err != nil {
+		return nil, err
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.Errorf("bad Ed25519 seed length %d", len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
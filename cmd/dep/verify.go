@@ -0,0 +1,417 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps/verify"
+	"github.com/pkg/errors"
+)
+
+const verifyShortHelp = `Verify that vendor matches the digests in Gopkg.lock`
+const verifyLongHelp = `
+Verify hashes every project in vendor and compares it with its digest in
+Gopkg.lock, reporting projects whose contents have been tampered with, projects
+in Gopkg.lock that are missing from vendor, and directories or files in vendor
+that belong to no project in Gopkg.lock. Projects in Gopkg.lock without a
+digest of the current hash version cannot be verified, and are reported too.
+Verify exits 1 if it reports anything, which makes "dep verify" suitable as a
+CI gate.
+
+Unlike dep check, verify never samples: every file is hashed, every time.
+Without -reproduce, it neither solves nor reaches the network, nor writes
+anything, and so works on read-only checkouts. Projects named in the "noverify" list of Gopkg.toml are
+not reported as tampered with, but are still reported if missing.
+
+With -reproduce, verify also exports every project in Gopkg.lock afresh into a
+temporary directory, from the source cache or the network, exactly as
+"dep ensure -vendor-only" would, and compares the result byte for byte with
+vendor. Every file that differs, that vendor lacks, or that vendor has in
+excess is reported. Unlike the digests, which were computed by whoever last
+wrote Gopkg.lock, this checks vendor against the upstream sources themselves.
+
+Flags:
+
+  -q          Print nothing; only set the exit status
+  -json       Print the result as a JSON object
+  -reproduce  Also compare vendor with a fresh export of every project
+`
+
+type verifyCommand struct {
+	quiet     bool
+	json      bool
+	reproduce bool
+}
+
+func (cmd *verifyCommand) Name() string      { return "verify" }
+func (cmd *verifyCommand) Args() string      { return "[-q] [-json] [-reproduce]" }
+func (cmd *verifyCommand) ShortHelp() string { return verifyShortHelp }
+func (cmd *verifyCommand) LongHelp() string  { return verifyLongHelp }
+func (cmd *verifyCommand) Hidden() bool      { return false }
+
+func (cmd *verifyCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.quiet, "q", false, "print nothing; only set the exit status")
+	fs.BoolVar(&cmd.json, "json", false, "output in JSON format")
+	fs.BoolVar(&cmd.reproduce, "reproduce", false, "also compare vendor with a fresh export of every project")
+}
+
+func (cmd *verifyCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) > 0 {
+		return errors.New("verify takes no arguments")
+	}
+	if cmd.quiet && cmd.json {
+		return errors.New("cannot pass both -q and -json")
+	}
+
+	p, err := ctx.LoadProject()
+	if err != nil {
+		return err
+	}
+	if p.Lock == nil {
+		return errors.Errorf("%s does not exist, cannot verify vendor against it", dep.LockName)
+	}
+
+	v, err := verifyVendor(p)
+	if err != nil {
+		return err
+	}
+	if cmd.reproduce {
+		if err := cmd.reproduceVendor(ctx, p, &v); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case cmd.json:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return errors.Wrap(err, "failed to encode verification")
+		}
+	case !cmd.quiet:
+		if err := v.write(&buf); err != nil {
+			return err
+		}
+	}
+	ctx.Out.Print(buf.String())
+
+	if !v.OK {
+		return silentfail{}
+	}
+	return nil
+}
+
+// vendorVerification is the result of dep verify, as output by -json.
+type vendorVerification struct {
+	OK bool `json:"ok"`
+	// Projects is the number of projects in Gopkg.lock that were expected
+	// in vendor.
+	Projects int               `json:"projects"`
+	Tampered []tamperedProject `json:"tampered"`
+	Missing  []string          `json:"missing"`
+	Extra    []string          `json:"extra"`
+	// Unverifiable are the projects without a digest of the current hash
+	// version in Gopkg.lock.
+	Unverifiable []string `json:"unverifiable"`
+	// Ignored are the projects in noverify whose contents do not match
+	// their digests.
+	Ignored []string `json:"ignored,omitempty"`
+	// Unreproduced are the files in which vendor differs from a fresh
+	// export, with -reproduce.
+	Unreproduced []unreproducedFile `json:"unreproduced,omitempty"`
+	reproduced   bool
+}
+
+// unreproducedFile is a file in which vendor differs from a fresh export of
+// the project it belongs to.
+type unreproducedFile struct {
+	Project string `json:"project"`
+	// Path is the slash-separated path of the file in vendor.
+	Path string `json:"path"`
+	// Problem is "differs" if the file's contents differ, "missing" if
+	// vendor lacks it, and "extra" if only vendor has it.
+	Problem string `json:"problem"`
+}
+
+// tamperedProject is a project in vendor whose contents do not match its
+// digest in Gopkg.lock.
+type tamperedProject struct {
+	Project  string `json:"project"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// verifyVendor hashes every project in the vendor directory of p, and compares
+// them with the digests in its lock.
+func verifyVendor(p *dep.Project) (vendorVerification, error) {
+	p.SampleVendor = nil
+	statuses, err := p.VerifyVendor()
+	if err != nil {
+		return vendorVerification{}, errors.Wrap(err, "error while verifying vendor")
+	}
+
+	noverify := make(map[string]bool)
+	for _, pr := range p.Manifest.NoVerify {
+		noverify[pr] = true
+	}
+	digests := make(map[string]verify.VersionedDigest)
+	for _, lp := range p.Lock.Projects() {
+		if vp, ok := lp.(verify.VerifiableProject); ok {
+			digests[string(vp.Ident().ProjectRoot)] = vp.Digest
+		}
+	}
+
+	v := vendorVerification{
+		Tampered:     []tamperedProject{},
+		Missing:      []string{},
+		Extra:        []string{},
+		Unverifiable: []string{},
+	}
+	for pr, status := range statuses {
+		if status != verify.NotInLock {
+			v.Projects++
+		}
+		switch status {
+		case verify.NotInTree:
+			v.Missing = append(v.Missing, pr)
+		case verify.NotInLock:
+			v.Extra = append(v.Extra, pr)
+		case verify.EmptyDigestInLock, verify.HashVersionMismatch:
+			if noverify[pr] {
+				v.Ignored = append(v.Ignored, pr)
+			} else {
+				v.Unverifiable = append(v.Unverifiable, pr)
+			}
+		case verify.DigestMismatchInLock:
+			if noverify[pr] {
+				v.Ignored = append(v.Ignored, pr)
+				continue
+			}
+			tp := tamperedProject{Project: pr, Expected: digests[pr].String()}
+			if vd, err := verify.DigestFromDirectory(filepath.Join(p.AbsRoot, "vendor", pr)); err == nil {
+				tp.Actual = vd.String()
+			}
+			v.Tampered = append(v.Tampered, tp)
+		}
+	}
+
+	sort.Slice(v.Tampered, func(i, j int) bool { return v.Tampered[i].Project < v.Tampered[j].Project })
+	sort.Strings(v.Missing)
+	sort.Strings(v.Extra)
+	sort.Strings(v.Unverifiable)
+	sort.Strings(v.Ignored)
+	v.OK = len(v.Tampered)+len(v.Missing)+len(v.Extra)+len(v.Unverifiable) == 0
+	return v, nil
+}
+
+// write writes v as a table of the projects that failed verification,
+// followed by a summary.
+func (v vendorVerification) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, tp := range v.Tampered {
+		fmt.Fprintf(tw, "tampered\t%s\tGopkg.lock %s, vendor %s\n", tp.Project, tp.Expected, tp.Actual)
+	}
+	for _, pr := range v.Missing {
+		fmt.Fprintf(tw, "missing\t%s\t\n", pr)
+	}
+	for _, pr := range v.Extra {
+		fmt.Fprintf(tw, "extra\t%s\t\n", pr)
+	}
+	for _, pr := range v.Unverifiable {
+		fmt.Fprintf(tw, "unverifiable\t%s\tno digest of hash version %d in Gopkg.lock\n", pr, verify.HashVersion)
+	}
+	for _, pr := range v.Ignored {
+		fmt.Fprintf(tw, "ignored\t%s\tlisted in noverify\n", pr)
+	}
+	for _, uf := range v.Unreproduced {
+		fmt.Fprintf(tw, "unreproduced\t%s\t%s vendor/%s\n", uf.Project, uf.Problem, uf.Path)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if v.OK {
+		fmt.Fprintf(w, "vendor matches Gopkg.lock (%d projects verified", v.Projects-len(v.Ignored))
+		if v.reproduced {
+			fmt.Fprintf(w, ", and reproduced")
+		}
+		fmt.Fprintf(w, ")\n")
+		return nil
+	}
+	fmt.Fprintf(w, "\nvendor does not match Gopkg.lock: %d tampered, %d missing, %d extra, %d unverifiable",
+		len(v.Tampered), len(v.Missing), len(v.Extra), len(v.Unverifiable))
+	if v.reproduced {
+		fmt.Fprintf(w, ", %d files unreproduced", len(v.Unreproduced))
+	}
+	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "Run dep ensure -vendor-only to regenerate vendor from Gopkg.lock.\n")
+	return nil
+}
+
+// reproduceVendor exports every project in the lock of p into a temporary
+// directory and records in v the files in which vendor differs from it.
+func (cmd *verifyCommand) reproduceVendor(ctx *dep.Ctx, p *dep.Project, v *vendorVerification) error {
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	td, err := ioutil.TempDir("", "dep-verify")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(td)
+
+	if !cmd.quiet && !cmd.json {
+		ctx.Err.Println("# Exporting every project in Gopkg.lock to reproduce vendor")
+	}
+	if err := p.ReproduceVendor(context.TODO(), sm, td); err != nil {
+		return errors.Wrap(err, "failed to reproduce vendor")
+	}
+
+	diffs, err := compareTrees(td, filepath.Join(p.AbsRoot, "vendor"))
+	if err != nil {
+		return errors.Wrap(err, "failed to compare vendor with its reproduction")
+	}
+
+	// Projects missing from vendor, and files that belong to no project in
+	// the lock, are already reported.
+	skip := make(map[string]bool)
+	for _, pr := range v.Missing {
+		skip[pr] = true
+	}
+	var roots []string
+	for _, lp := range p.Lock.Projects() {
+		roots = append(roots, string(lp.Ident().ProjectRoot))
+	}
+	v.Unreproduced = []unreproducedFile{}
+	for _, d := range diffs {
+		if pr := projectOfPath(roots, d.Path); pr != "" && !skip[pr] {
+			d.Project = pr
+			v.Unreproduced = append(v.Unreproduced, d)
+		}
+	}
+	v.reproduced = true
+	if len(v.Unreproduced) > 0 {
+		v.OK = false
+	}
+	return nil
+}
+
+// projectOfPath returns the longest of roots that contains the
+// slash-separated path p, or "" if none does.
+func projectOfPath(roots []string, p string) string {
+	var pr string
+	for _, root := range roots {
+		if strings.HasPrefix(p, root+"/") && len(root) > len(pr) {
+			pr = root
+		}
+	}
+	return pr
+}
+
+// compareTrees compares the regular files and symlinks under want with those
+// under got, byte for byte, and returns those that differ, those missing from
+// got and those got has in excess, sorted by path. The returned files have
+// no Project.
+func compareTrees(want, got string) ([]unreproducedFile, error) {
+	list := func(root string) (map[string]os.FileInfo, error) {
+		files := make(map[string]os.FileInfo)
+		err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() && fi.Mode()&os.ModeSymlink == 0 {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(rel)] = fi
+			return nil
+		})
+		return files, err
+	}
+	wantFiles, err := list(want)
+	if err != nil {
+		return nil, err
+	}
+	gotFiles, err := list(got)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []unreproducedFile
+	for rel, wfi := range wantFiles {
+		gfi, has := gotFiles[rel]
+		if !has {
+			diffs = append(diffs, unreproducedFile{Path: rel, Problem: "missing"})
+			continue
+		}
+		same, err := sameFile(filepath.Join(want, rel), wfi, filepath.Join(got, rel), gfi)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			diffs = append(diffs, unreproducedFile{Path: rel, Problem: "differs"})
+		}
+	}
+	for rel := range gotFiles {
+		if _, has := wantFiles[rel]; !has {
+			diffs = append(diffs, unreproducedFile{Path: rel, Problem: "extra"})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// sameFile reports whether the files at a and b, described by afi and bfi,
+// are both symlinks to the same target, or both regular files with the same
+// contents.
+func sameFile(a string, afi os.FileInfo, b string, bfi os.FileInfo) (bool, error) {
+	if afi.Mode()&os.ModeSymlink != bfi.Mode()&os.ModeSymlink {
+		return false, nil
+	}
+	if afi.Mode()&os.ModeSymlink != 0 {
+		at, err := os.Readlink(a)
+		if err != nil {
+			return false, err
+		}
+		bt, err := os.Readlink(b)
+		if err != nil {
+			return false, err
+		}
+		return at == bt, nil
+	}
+	if afi.Size() != bfi.Size() {
+		return false, nil
+	}
+	ab, err := ioutil.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	bb, err := ioutil.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ab, bb), nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestVerifyVendor(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("vendor/github.com/foo/good/good.go", "package good\n")
+	h.TempFile("vendor/github.com/foo/bad/bad.go", "package bad\n")
+	h.TempFile("vendor/github.com/foo/skipped/skipped.go", "package skipped\n")
+	h.TempFile("vendor/github.com/foo/extra/extra.go", "package extra\n")
+
+	digestOf := func(pr string) verify.VersionedDigest {
+		vd, err := verify.DigestFromDirectory(h.Path("vendor/" + pr))
+		h.Must(err)
+		return vd
+	}
+	project := func(pr string, digest verify.VersionedDigest) gps.LockedProject {
+		return verify.VerifiableProject{
+			LockedProject: gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(pr)}, gps.Revision("rev"), nil),
+			Digest:        digest,
+		}
+	}
+	good, bad, skipped := digestOf("github.com/foo/good"), digestOf("github.com/foo/bad"), digestOf("github.com/foo/skipped")
+	h.TempFile("vendor/github.com/foo/bad/bad.go", "package bad // edited\n")
+	h.TempFile("vendor/github.com/foo/skipped/skipped.go", "package skipped // edited\n")
+
+	m := dep.NewManifest()
+	m.NoVerify = []string{"github.com/foo/skipped"}
+	p := &dep.Project{
+		AbsRoot:  h.Path("."),
+		Manifest: m,
+		Lock: &dep.Lock{P: []gps.LockedProject{
+			project("github.com/foo/good", good),
+			project("github.com/foo/bad", bad),
+			project("github.com/foo/skipped", skipped),
+			project("github.com/foo/missing", good),
+			project("github.com/foo/nodigest", verify.VersionedDigest{}),
+		}},
+	}
+	h.TempFile("vendor/github.com/foo/nodigest/nodigest.go", "package nodigest\n")
+
+	v, err := verifyVendor(p)
+	h.Must(err)
+	if v.OK {
+		t.Fatal("expected verification to fail")
+	}
+	if len(v.Tampered) != 1 || v.Tampered[0].Project != "github.com/foo/bad" || v.Tampered[0].Expected != bad.String() || v.Tampered[0].Actual == bad.String() {
+		t.Errorf("unexpected tampered projects: %+v", v.Tampered)
+	}
+	for name, c := range map[string]struct{ got, want []string }{
+		"missing":      {v.Missing, []string{"github.com/foo/missing"}},
+		"extra":        {v.Extra, []string{"github.com/foo/extra"}},
+		"unverifiable": {v.Unverifiable, []string{"github.com/foo/nodigest"}},
+		"ignored":      {v.Ignored, []string{"github.com/foo/skipped"}},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("unexpected %s projects:\n\t(GOT): %v\n\t(WNT): %v", name, c.got, c.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	h.Must(v.write(&buf))
+	if !strings.Contains(buf.String(), "1 tampered, 1 missing, 1 extra, 1 unverifiable") {
+		t.Errorf("unexpected summary:\n%s", buf.String())
+	}
+}
+
+func TestCompareTrees(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("want/github.com/foo/bar/same.txt", "same")
+	h.TempFile("got/github.com/foo/bar/same.txt", "same")
+	h.TempFile("want/github.com/foo/bar/edited.txt", "upstream")
+	h.TempFile("got/github.com/foo/bar/edited.txt", "patched!")
+	h.TempFile("want/github.com/foo/bar/deleted.txt", "deleted")
+	h.TempFile("got/github.com/foo/bar/added.txt", "added")
+
+	diffs, err := compareTrees(h.Path("want"), h.Path("got"))
+	h.Must(err)
+	want := []unreproducedFile{
+		{Path: "github.com/foo/bar/added.txt", Problem: "extra"},
+		{Path: "github.com/foo/bar/deleted.txt", Problem: "missing"},
+		{Path: "github.com/foo/bar/edited.txt", Problem: "differs"},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("unexpected differences:\n\t(GOT): %+v\n\t(WNT): %+v", diffs, want)
+	}
+
+	roots := []string{"github.com/foo/bar", "github.com/foo/bar/baz", "github.com/foo/qux"}
+	for p, want := range map[string]string{
+		"github.com/foo/bar/a.go":     "github.com/foo/bar",
+		"github.com/foo/bar/baz/b.go": "github.com/foo/bar/baz",
+		"github.com/foo/barn/c.go":    "",
+		"modules.txt":                 "",
+	} {
+		if got := projectOfPath(roots, p); got != want {
+			t.Errorf("expected %s to belong to %q, got %q", p, want, got)
+		}
+	}
+}
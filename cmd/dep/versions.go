@@ -0,0 +1,201 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+const versionsShortHelp = `List the versions a project could be upgraded to`
+const versionsLongHelp = `
+List the tags and branches of <project>, with the revision that each points to,
+in the order in which the solver tries them: semver releases, newest first,
+then the default branch, other branches, and other tags.
+
+<project> may name a source and a constraint, as for dep ensure -add:
+
+  dep versions github.com/pkg/errors@^0.8.0
+  dep versions github.com/pkg/errors:git.example.com/mirror/errors
+
+Each version is marked with whether it satisfies the constraint: the one
+given, or else, when run in a project, the one that Gopkg.toml declares for
+<project>. In a project, the source that Gopkg.toml declares is listed unless
+another is given, and the version in Gopkg.lock is marked as locked.
+`
+
+type versionsCommand struct {
+	json     bool
+	matching bool
+}
+
+func (cmd *versionsCommand) Name() string { return "versions" }
+func (cmd *versionsCommand) Args() string {
+	return "[-json] [-matching] <project>[:alt source URL][@<constraint>]"
+}
+func (cmd *versionsCommand) ShortHelp() string { return versionsShortHelp }
+func (cmd *versionsCommand) LongHelp() string  { return versionsLongHelp }
+func (cmd *versionsCommand) Hidden() bool      { return false }
+
+func (cmd *versionsCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.json, "json", false, "output in JSON format")
+	fs.BoolVar(&cmd.matching, "matching", false, "list only the versions that satisfy the constraint")
+}
+
+func (cmd *versionsCommand) Run(ctx *dep.Ctx, args []string) error {
+	if len(args) != 1 {
+		return errors.New("versions takes exactly one argument, the project")
+	}
+
+	sm, err := ctx.SourceManager()
+	if err != nil {
+		return err
+	}
+	sm.UseDefaultSignalHandling()
+	defer sm.Release()
+
+	pc, _, err := getProjectConstraint(args[0], sm)
+	if err != nil {
+		return err
+	}
+	id, c := pc.Ident, pc.Constraint
+	hasConstraint := strings.Contains(args[0], "@")
+
+	var locked gps.Version
+	if p, err := ctx.LoadProject(); err == nil {
+		pp, has := p.Manifest.Ovr[id.ProjectRoot]
+		if !has {
+			pp, has = p.Manifest.Constraints[id.ProjectRoot]
+		}
+		if has {
+			if id.Source == "" {
+				id.Source = pp.Source
+			}
+			if !hasConstraint && pp.Constraint != nil {
+				c = pp.Constraint
+			}
+		}
+		if p.Lock != nil {
+			for _, lp := range p.Lock.Projects() {
+				if lp.Ident().ProjectRoot == id.ProjectRoot {
+					locked = lp.Version()
+				}
+			}
+		}
+	}
+
+	avl, err := gps.ListAvailableVersions(sm, id, c)
+	if err != nil {
+		return errors.Wrapf(err, "could not list the versions of %s", id)
+	}
+
+	reports := make([]projectVersion, 0, len(avl))
+	for _, av := range avl {
+		if cmd.matching && !av.Satisfies {
+			continue
+		}
+		reports = append(reports, projectVersion{
+			AvailableVersion: av,
+			Locked:           isLockedVersion(locked, av.Version),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := printVersions(&buf, id, c, reports, cmd.json); err != nil {
+		return err
+	}
+	ctx.Out.Print(buf.String())
+	return nil
+}
+
+// projectVersion is an available version of a project, and whether it is the
+// locked one.
+type projectVersion struct {
+	gps.AvailableVersion
+	Locked bool
+}
+
+// notes lists what is notable about v, for the NOTES column.
+func (v projectVersion) notes() string {
+	var notes []string
+	if v.Locked {
+		notes = append(notes, "locked")
+	}
+	if v.DefaultBranch {
+		notes = append(notes, "default branch")
+	}
+	return strings.Join(notes, ", ")
+}
+
+type rawProjectVersion struct {
+	*versionInfo
+	DefaultBranch bool `json:"defaultBranch"`
+	Satisfies     bool `json:"satisfies"`
+	Locked        bool `json:"locked"`
+}
+
+func (v projectVersion) marshalJSON() rawProjectVersion {
+	return rawProjectVersion{
+		versionInfo:   newVersionInfo(v.Version),
+		DefaultBranch: v.DefaultBranch,
+		Satisfies:     v.Satisfies,
+		Locked:        v.Locked,
+	}
+}
+
+// isLockedVersion reports whether the tag or branch of v is that of the
+// locked version. A locked branch is matched even if it has since moved.
+func isLockedVersion(locked gps.Version, v gps.PairedVersion) bool {
+	lv, ok := locked.(gps.PairedVersion)
+	return ok && lv.Type() == v.Type() && lv.String() == v.String()
+}
+
+// printVersions writes the versions of the project id, marked with whether
+// they satisfy c, to w, as a table or as JSON.
+func printVersions(w io.Writer, id gps.ProjectIdentifier, c gps.Constraint, reports []projectVersion, asJSON bool) error {
+	if asJSON {
+		raw := struct {
+			Name       string              `json:"name"`
+			Source     string              `json:"source,omitempty"`
+			Constraint string              `json:"constraint"`
+			Versions   []rawProjectVersion `json:"versions"`
+		}{
+			Name:       string(id.ProjectRoot),
+			Source:     id.Source,
+			Constraint: c.String(),
+			Versions:   make([]rawProjectVersion, 0, len(reports)),
+		}
+		for _, v := range reports {
+			raw.Versions = append(raw.Versions, v.marshalJSON())
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(raw)
+	}
+
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tTYPE\tREVISION\tSATISFIES\tNOTES")
+	for _, v := range reports {
+		vi := newVersionInfo(v.Version)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", vi.Version, vi.Type, vi.Revision, yesNo(v.Satisfies), v.notes())
+	}
+	return tw.Flush()
+}
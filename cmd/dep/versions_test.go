@@ -0,0 +1,76 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestPrintVersions(t *testing.T) {
+	id := gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}
+	c, err := gps.NewSemverConstraintIC("1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := gps.NewBranch("master").Pair("rev1")
+
+	var reports []projectVersion
+	for _, av := range []gps.AvailableVersion{
+		{Version: gps.NewVersion("v2.0.0").Pair("rev3")},
+		{Version: gps.NewVersion("v1.1.0").Pair("rev2"), Satisfies: true},
+		{Version: gps.NewBranch("master").Pair("rev3"), DefaultBranch: true},
+	} {
+		reports = append(reports, projectVersion{
+			AvailableVersion: av,
+			Locked:           isLockedVersion(locked, av.Version),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := printVersions(&buf, id, c, reports, false); err != nil {
+		t.Fatal(err)
+	}
+	want := `VERSION  TYPE    REVISION  SATISFIES  NOTES
+v2.0.0   semver  rev3      no         
+v1.1.0   semver  rev2      yes        
+master   branch  rev3      no         locked, default branch
+`
+	if buf.String() != want {
+		t.Errorf("unexpected table:\n\t(GOT):\n%s\n\t(WNT):\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := printVersions(&buf, id, c, reports, true); err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Name       string
+		Constraint string
+		Versions   []struct {
+			Version       string
+			Revision      string
+			Type          string
+			DefaultBranch bool
+			Satisfies     bool
+			Locked        bool
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw.Name != "github.com/foo/bar" || raw.Constraint != "^1.0.0" || len(raw.Versions) != 3 {
+		t.Fatalf("unexpected JSON: %s", buf.String())
+	}
+	if v := raw.Versions[1]; v.Version != "v1.1.0" || v.Revision != "rev2" || !v.Satisfies || v.Locked {
+		t.Errorf("unexpected JSON for v1.1.0: %+v", v)
+	}
+	if v := raw.Versions[2]; v.Type != "branch" || !v.DefaultBranch || !v.Locked {
+		t.Errorf("unexpected JSON for master: %+v", v)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+)
+
+// applyYanked loads the yanked versions feed configured in ctx, if any, and
+// unless ctx only asks for warnings, sets it on params so that the solver
+// refuses to select the versions in it.
+func applyYanked(ctx *dep.Ctx, params *gps.SolveParameters) (gps.YankedVersions, error) {
+	yanked, err := ctx.LoadYankedVersions()
+	if err != nil {
+		return nil, err
+	}
+	if !ctx.YankedWarnOnly {
+		params.Yanked = yanked
+	}
+	return yanked, nil
+}
+
+// warnYanked logs a warning for each project in l that is locked to a yanked
+// version, and reports whether there were any.
+func warnYanked(logger *log.Logger, yanked gps.YankedVersions, l gps.Lock) bool {
+	if len(yanked) == 0 || l == nil {
+		return false
+	}
+
+	var found bool
+	for _, lp := range l.Projects() {
+		yv, has := yanked.Yanked(lp.Ident(), lp.Version())
+		if !has {
+			continue
+		}
+		found = true
+		if yv.Reason == "" {
+			logger.Printf("Warning: %s is locked to %s, which has been yanked\n", lp.Ident().ProjectRoot, lp.Version())
+		} else {
+			logger.Printf("Warning: %s is locked to %s, which has been yanked: %s\n", lp.Ident().ProjectRoot, lp.Version(), yv.Reason)
+		}
+	}
+	return found
+}
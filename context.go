@@ -47,7 +47,39 @@ type Ctx struct {
 	Verbose        bool          // Enables more verbose logging.
 	DisableLocking bool          // When set, no lock file will be created to protect against simultaneous dep processes.
 	Cachedir       string        // Cache directory loaded from environment.
+	RemoteCache    string        // Directory or URL of a shared cache to copy missing sources from.
+	GlobalCache    string        // Read-only cache directory, shared by all users, that Cachedir is layered over.
 	CacheAge       time.Duration // Maximum valid age of cached source data. <=0: Don't cache.
+	StrictManifest bool          // Treat problems found while reading the manifest as errors, rather than warnings.
+	IgnoreLock     bool          // Don't read the lock when loading a project, such as when it is being replaced.
+	YankedFeed     string        // File or URL listing versions the solver must not select.
+	YankedWarnOnly bool          // Only warn about selecting yanked versions, rather than refusing to.
+	Bundle         string        // Directory holding a metadata bundle to use in place of the network.
+	ImportAllow    []string      // Import path prefixes of the only projects that may be selected.
+	ImportDeny     []string      // Import path prefixes of projects that may not be selected.
+	HintsFile      string        // File in which solver hints are kept between runs.
+	RegisterLocks  bool          // Record the lock of each project ensured in the lock registry of the cache.
+	Tools          []gps.VCSTool // VCS binaries to run, rather than those found in PATH.
+	HermeticTools  bool          // Never look VCS binaries up in PATH, so that only Tools are run.
+	PureGitHosts   []string      // Hosts whose git repositories are listed without running git; "*" for all.
+	CommandLog     string        // File to which a record of each VCS command run is appended.
+	AllowedVCS     []string      // VCS tools, or tools and subcommands, that may be run; all if empty.
+	MaxBandwidth   uint64        // Bytes per second to limit transfers from upstream sources to; 0 for no limit.
+	ModuleProxy    string        // URL of a Go module proxy that exports fail over to, if any.
+	ProxyFirst     bool          // Export from ModuleProxy first, failing over to sources.
+	Freeze         string        // Why changes to the lock of every project are refused; none if empty.
+
+	// CommandLimits are the timeouts and retry counts of VCS commands, by
+	// operation.
+	CommandLimits map[string]gps.CommandLimits
+
+	// Platforms are those for which the packages of dependencies are listed;
+	// every platform if empty. LoadProject sets them from the manifest.
+	Platforms []pkgtree.Platform
+
+	// FallbackSources are the sources tried, in order, for projects whose own
+	// source cannot be reached. LoadProject sets them from the manifest.
+	FallbackSources map[gps.ProjectIdentifier][]string
 }
 
 // SetPaths sets the WorkingDir and GOPATHs fields. If GOPATHs is empty, then
@@ -107,14 +139,74 @@ func (c *Ctx) SourceManager() (*gps.SourceMgr, error) {
 		}
 	}
 
+	if err := c.limitBandwidth(); err != nil {
+		return nil, err
+	}
+
+	var backend gps.CacheBackend
+	if c.RemoteCache != "" {
+		var err error
+		if backend, err = gps.NewCacheBackend(c.RemoteCache); err != nil {
+			return nil, err
+		}
+	}
+
 	return gps.NewSourceManager(gps.SourceManagerConfig{
 		CacheAge:       c.CacheAge,
 		Cachedir:       cachedir,
 		Logger:         c.Out,
 		DisableLocking: c.DisableLocking,
+		CacheBackend:   backend,
+		GlobalCachedir: c.GlobalCache,
+		Platforms:      c.Platforms,
+
+		FallbackSources:  c.FallbackSources,
+		ModuleProxy:      c.ModuleProxy,
+		ModuleProxyFirst: c.ProxyFirst,
 	})
 }
 
+// ReadOnlySourceManager produces a read-only instance of gps's built-in
+// SourceManager, which neither locks nor modifies the cache. It serves data
+// from the persistent cache, and lists versions from upstream where that needs
+// no local copy of a source. It can be used while another dep process holds
+// the cache, or where the cache cannot be written.
+func (c *Ctx) ReadOnlySourceManager() (*gps.SourceMgr, error) {
+	cachedir := c.Cachedir
+	if cachedir == "" {
+		cachedir = filepath.Join(c.GOPATH, "pkg", "dep")
+	}
+
+	if err := c.limitBandwidth(); err != nil {
+		return nil, err
+	}
+
+	// Problems reading the cache are reported to Err, as the output of
+	// commands that only read, like dep status, may be parsed. Platforms are
+	// not passed on, as only package trees listed for every platform are kept
+	// in the cache.
+	return gps.NewSourceManager(gps.SourceManagerConfig{
+		CacheAge:       c.CacheAge,
+		Cachedir:       cachedir,
+		Logger:         c.Err,
+		GlobalCachedir: c.GlobalCache,
+		ReadOnly:       true,
+
+		FallbackSources:  c.FallbackSources,
+		ModuleProxy:      c.ModuleProxy,
+		ModuleProxyFirst: c.ProxyFirst,
+	})
+}
+
+// limitBandwidth limits the bandwidth used by source managers to
+// MaxBandwidth, if it is set.
+func (c *Ctx) limitBandwidth() error {
+	if c.MaxBandwidth == 0 {
+		return nil
+	}
+	return gps.LimitBandwidth(int64(c.MaxBandwidth))
+}
+
 // LoadProject starts from the current working directory and searches up the
 // directory tree for a project root.  The search stops when a file with the name
 // ManifestName (Gopkg.toml, by default) is located.
@@ -168,12 +260,20 @@ func (c *Ctx) LoadProject() (*Project, error) {
 
 	var warns []error
 	p.Manifest, warns, err = readManifest(mf)
+	if c.StrictManifest && err == nil && len(warns) > 0 {
+		for _, warn := range warns {
+			c.Err.Printf("dep: ERROR: %v\n", warn)
+		}
+		return nil, errors.Errorf("found %d problem(s) in %s", len(warns), mp)
+	}
 	for _, warn := range warns {
 		c.Err.Printf("dep: WARNING: %v\n", warn)
 	}
 	if err != nil {
 		return nil, errors.Wrapf(err, "error while parsing %s", mp)
 	}
+	c.Platforms = p.Manifest.Platforms
+	c.FallbackSources = p.Manifest.fallbackSourcesByIdentifier()
 
 	// Parse in the root package tree.
 	ptree, err := p.parseRootPackageTree()
@@ -181,6 +281,10 @@ func (c *Ctx) LoadProject() (*Project, error) {
 		return nil, err
 	}
 
+	if c.IgnoreLock {
+		return p, nil
+	}
+
 	lp := filepath.Join(p.AbsRoot, LockName)
 	lf, err := os.Open(lp)
 	if err == nil {
@@ -196,6 +300,7 @@ func (c *Ctx) LoadProject() (*Project, error) {
 		if p.Lock != nil {
 			p.ChangedLock = p.Lock.dup()
 			p.ChangedLock.SolveMeta.InputImports = externalImportList(ptree, p.Manifest)
+			p.ChangedLock.SolveMeta.InputsVersion = verify.InputsVersion
 
 			for k, lp := range p.ChangedLock.Projects() {
 				vp := lp.(verify.VerifiableProject)
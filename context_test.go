@@ -305,6 +305,32 @@ func TestLoadProjectLockParseError(t *testing.T) {
 	}
 }
 
+func TestLoadProjectStrictManifest(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir(filepath.Join("src", "test1"))
+	h.TempFile(filepath.Join("src", "test1", ManifestName), "[[constraint]]\n  name = \"github.com/foo/bar\"\n  verion = \"1.0.0\"\n")
+
+	ctx := &Ctx{
+		Out: discardLogger(),
+		Err: discardLogger(),
+	}
+	err := ctx.SetPaths(h.Path(filepath.Join("src", "test1")), h.Path("."))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if _, err = ctx.LoadProject(); err != nil {
+		t.Fatalf("lenient load should only have warned, but returned error: %s", err)
+	}
+
+	ctx.StrictManifest = true
+	if _, err = ctx.LoadProject(); err == nil {
+		t.Fatal("strict load should have returned an error for the unknown key")
+	}
+}
+
 func TestLoadProjectNoSrcDir(t *testing.T) {
 	tg := test.NewHelper(t)
 	defer tg.Cleanup()
@@ -596,3 +622,25 @@ func TestDepCachedir(t *testing.T) {
 		}
 	}
 }
+
+func TestReadOnlySourceManagerLeavesCacheAlone(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir("go")
+	gopath := h.Path("go")
+	ctx := &Ctx{
+		GOPATH: gopath,
+		Out:    discardLogger(),
+		Err:    discardLogger(),
+	}
+	sm, err := ctx.ReadOnlySourceManager()
+	h.Must(err)
+	sm.Release()
+
+	want := filepath.Join(gopath, "pkg", "dep")
+	if sm.Cachedir() != want {
+		t.Errorf("expected cachedir to be %s, got %s", want, sm.Cachedir())
+	}
+	h.MustNotExist(want)
+}
@@ -0,0 +1,148 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/paths"
+)
+
+// The kinds of dependency that a project in the lock may be.
+const (
+	// DependencyDirect is a project whose packages are imported by the
+	// packages of the current project.
+	DependencyDirect = "direct"
+	// DependencyRequired is a project that is only depended on through the
+	// required list of the manifest.
+	DependencyRequired = "required"
+	// DependencyTransitive is a project that is only depended on by other
+	// dependencies.
+	DependencyTransitive = "transitive"
+	// DependencyTest is a project that is only depended on through the
+	// _test.go files of the current project, as marked in the lock.
+	DependencyTest = "test"
+)
+
+func isDependencyKind(s string) bool {
+	return s == DependencyDirect || s == DependencyRequired || s == DependencyTransitive || s == DependencyTest
+}
+
+// KindPolicy restricts the kinds of dependency to which the rules of the
+// manifest may apply, as set in the [kind-policy] table of the manifest. An
+// empty list permits rules for every kind.
+type KindPolicy struct {
+	// Constraints are the kinds of dependency that may have a [[constraint]].
+	Constraints []string
+	// Overrides are the kinds of dependency that may have an [[override]].
+	Overrides []string
+	// Enforce makes dep check fail on rules that the policy does not permit.
+	Enforce bool
+}
+
+// A KindPolicyViolation is a rule in the manifest for a project in the lock
+// whose kind of dependency the kind policy does not permit to have one.
+type KindPolicyViolation struct {
+	ProjectRoot gps.ProjectRoot
+	// Rule is "constraint" or "override".
+	Rule string
+	// Kind is the kind of dependency that the project is.
+	Kind string
+}
+
+func (v KindPolicyViolation) String() string {
+	return fmt.Sprintf("[[%s]] for %s, a %s dependency, is not permitted by [kind-policy]", v.Rule, v.ProjectRoot, v.Kind)
+}
+
+// DependencyKinds returns the kind of dependency that each project in the
+// lock of p is, keyed by project root. It returns nil if there is no lock.
+func (p *Project) DependencyKinds() map[gps.ProjectRoot]string {
+	if p.Lock == nil {
+		return nil
+	}
+	rm, _ := p.RootPackageTree.ToReachMap(true, true, false, p.Manifest.IgnoredPackages())
+	kinds := classifyDependencies(p.Lock.Projects(), rm.FlattenFn(paths.IsStandardImportPath), p.Manifest.Required)
+	for _, lp := range p.Lock.Projects() {
+		if isTestOnly(lp) {
+			kinds[lp.Ident().ProjectRoot] = DependencyTest
+		}
+	}
+	return kinds
+}
+
+// classifyDependencies returns the kind of dependency that each of lps is,
+// given the imports of the packages of the current project and the packages
+// it requires.
+func classifyDependencies(lps []gps.LockedProject, imports, required []string) map[gps.ProjectRoot]string {
+	kinds := make(map[gps.ProjectRoot]string, len(lps))
+	for _, lp := range lps {
+		kinds[lp.Ident().ProjectRoot] = DependencyTransitive
+	}
+
+	// Each package is provided by the project with the longest matching root.
+	mark := func(pkgs []string, kind string) {
+		for _, pkg := range pkgs {
+			var provider gps.ProjectRoot
+			for pr := range kinds {
+				if (pkg == string(pr) || strings.HasPrefix(pkg, string(pr)+"/")) && len(pr) > len(provider) {
+					provider = pr
+				}
+			}
+			if provider != "" {
+				kinds[provider] = kind
+			}
+		}
+	}
+	mark(required, DependencyRequired)
+	mark(imports, DependencyDirect)
+	return kinds
+}
+
+// KindPolicyViolations returns the rules in the manifest of p, for projects in
+// its lock, that the kind policy of the manifest does not permit. They are
+// sorted by project root.
+func (p *Project) KindPolicyViolations() []KindPolicyViolation {
+	policy := p.Manifest.KindPolicy
+	if len(policy.Constraints) == 0 && len(policy.Overrides) == 0 {
+		return nil
+	}
+
+	kinds := p.DependencyKinds()
+	var violations []KindPolicyViolation
+	check := func(rule string, rules gps.ProjectConstraints, permitted []string) {
+		if len(permitted) == 0 {
+			return
+		}
+		for pr := range rules {
+			kind, has := kinds[pr]
+			if !has || containsString(permitted, kind) {
+				continue
+			}
+			violations = append(violations, KindPolicyViolation{ProjectRoot: pr, Rule: rule, Kind: kind})
+		}
+	}
+	check("constraint", p.Manifest.Constraints, policy.Constraints)
+	check("override", p.Manifest.Ovr, policy.Overrides)
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].ProjectRoot != violations[j].ProjectRoot {
+			return violations[i].ProjectRoot < violations[j].ProjectRoot
+		}
+		return violations[i].Rule < violations[j].Rule
+	})
+	return violations
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
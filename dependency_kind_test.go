@@ -0,0 +1,68 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+)
+
+func TestDependencyKinds(t *testing.T) {
+	rev := gps.Revision("d05d5aca9f895d19e9265839bffeadd74a2d2ecb")
+	l := &Lock{}
+	for _, root := range []string{"github.com/direct/lib", "github.com/direct/lib/nested", "github.com/required/tool", "github.com/transitive/lib", "github.com/both/lib"} {
+		l.P = append(l.P, gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(root)}, rev, []string{"."}))
+	}
+
+	m := NewManifest()
+	m.Required = []string{"github.com/required/tool/cmd/gen", "github.com/both/lib"}
+	m.Constraints["github.com/direct/lib"] = gps.ProjectProperties{Constraint: gps.Any()}
+	m.Constraints["github.com/transitive/lib"] = gps.ProjectProperties{Constraint: gps.Any()}
+	m.Ovr["github.com/transitive/lib"] = gps.ProjectProperties{Constraint: gps.Any()}
+	m.Ovr["github.com/required/tool"] = gps.ProjectProperties{Constraint: gps.Any()}
+
+	p := &Project{
+		ImportRoot: "example.com/root",
+		Manifest:   m,
+		Lock:       l,
+		RootPackageTree: pkgtree.PackageTree{
+			ImportRoot: "example.com/root",
+			Packages: map[string]pkgtree.PackageOrErr{
+				"example.com/root": {P: pkgtree.Package{
+					ImportPath: "example.com/root",
+					Name:       "root",
+					Imports:    []string{"fmt", "github.com/direct/lib/sub", "github.com/both/lib"},
+				}},
+			},
+		},
+	}
+
+	want := map[gps.ProjectRoot]string{
+		"github.com/direct/lib":        DependencyDirect,
+		"github.com/direct/lib/nested": DependencyTransitive,
+		"github.com/required/tool":     DependencyRequired,
+		"github.com/transitive/lib":    DependencyTransitive,
+		"github.com/both/lib":          DependencyDirect,
+	}
+	if got := p.DependencyKinds(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected kinds:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+
+	if got := p.KindPolicyViolations(); len(got) != 0 {
+		t.Errorf("expected no violations without a policy, got %v", got)
+	}
+
+	m.KindPolicy = KindPolicy{Constraints: []string{DependencyDirect}, Overrides: []string{DependencyTransitive}}
+	wantViolations := []KindPolicyViolation{
+		{ProjectRoot: "github.com/required/tool", Rule: "override", Kind: DependencyRequired},
+		{ProjectRoot: "github.com/transitive/lib", Rule: "constraint", Kind: DependencyTransitive},
+	}
+	if got := p.KindPolicyViolations(); !reflect.DeepEqual(got, wantViolations) {
+		t.Errorf("unexpected violations:\n\t(GOT): %v\n\t(WNT): %v", got, wantViolations)
+	}
+}
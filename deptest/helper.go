@@ -0,0 +1,58 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package deptest provides helpers for testing dep, and tools that embed it,
+// against realistic project trees: a Helper that manages temporary
+// directories, environment variables and fixtures copied from testdata, and a
+// ProjectContext that sets up a project for dep to work on and compares the
+// Gopkg.toml and Gopkg.lock it writes with golden files.
+//
+// Golden files are rewritten, rather than compared, when UpdateGolden is set.
+// deptest registers no flags of its own, so that it does not clash with those
+// of the test binaries that import it; they can bind UpdateGolden to one of
+// theirs, as in
+//
+//	func init() {
+//		flag.BoolVar(deptest.UpdateGolden, "update", false, "update golden files")
+//	}
+package deptest
+
+import (
+	"testing"
+
+	"github.com/golang/dep/internal/testhelper"
+)
+
+// Helper manages the temporary directories, environment and working
+// directory of a test, and restores them on Cleanup.
+type Helper = testhelper.Helper
+
+// Writer adapts a testing.TB to the io.Writer interface.
+type Writer = testhelper.Writer
+
+var (
+	// ExeSuffix is the suffix of executable files; ".exe" on Windows.
+	ExeSuffix = testhelper.ExeSuffix
+	// PrintLogs controls logging of test commands. It is not bound to a flag.
+	PrintLogs = testhelper.PrintLogs
+	// UpdateGolden controls updating test fixtures. It is not bound to a flag.
+	UpdateGolden = testhelper.UpdateGolden
+)
+
+// NewHelper initializes a new helper for testing.
+func NewHelper(t *testing.T) *Helper {
+	return testhelper.NewHelper(t)
+}
+
+// NeedsExternalNetwork makes sure the tests needing external network will not
+// be run when executing tests in short mode.
+func NeedsExternalNetwork(t *testing.T) {
+	testhelper.NeedsExternalNetwork(t)
+}
+
+// NeedsGit will make sure the tests that require git will be skipped if the
+// git binary is not available.
+func NeedsGit(t *testing.T) {
+	testhelper.NeedsGit(t)
+}
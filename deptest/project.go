@@ -0,0 +1,190 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deptest
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// ProjectContext is a project in a temporary GOPATH, set up for dep to work on
+// in tests, along with the Ctx and SourceManager with which to do so.
+type ProjectContext struct {
+	h              *Helper
+	tempDir        string // Full path to the temp directory
+	tempProjectDir string // Relative path of the project under the temp directory
+
+	Context       *dep.Ctx
+	Project       *dep.Project
+	SourceManager gps.SourceManager
+}
+
+// NewProjectContext creates the directory of the project with the import path
+// projectName in a temporary GOPATH, and changes into it. The SourceManager
+// of the context uses a cache in the temporary GOPATH, so that tests do not
+// share state.
+func NewProjectContext(h *Helper, projectName string) *ProjectContext {
+	pc := &ProjectContext{h: h}
+
+	pc.tempProjectDir = filepath.Join("src", projectName)
+	h.TempDir(pc.tempProjectDir)
+	pc.tempDir = h.Path(".")
+	pc.Project = &dep.Project{AbsRoot: filepath.Join(pc.tempDir, pc.tempProjectDir)}
+	h.Cd(pc.Project.AbsRoot)
+	h.Setenv("GOPATH", pc.tempDir)
+
+	var err error
+	pc.Context = &dep.Ctx{
+		WorkingDir: pc.Project.AbsRoot,
+		GOPATH:     pc.tempDir,
+		GOPATHs:    []string{pc.tempDir},
+		Out:        log.New(ioutil.Discard, "", 0),
+		Err:        log.New(ioutil.Discard, "", 0),
+	}
+	pc.SourceManager, err = pc.Context.SourceManager()
+	h.Must(errors.Wrap(err, "Unable to create a SourceManager"))
+
+	return pc
+}
+
+// CopyFile copies a file from the testdata directory into the project.
+// projectPath is the destination file path, relative to the project directory
+// testdataPath is the source path, relative to the testdata directory
+func (pc *ProjectContext) CopyFile(projectPath string, testdataPath string) string {
+	path := filepath.Join(pc.tempProjectDir, projectPath)
+	pc.h.TempCopy(path, testdataPath)
+	return path
+}
+
+// CopyTree copies a directory from the testdata directory into the project,
+// such as a whole fixture project.
+// projectPath is the destination directory, relative to the project directory
+// testdataPath is the source directory, relative to the testdata directory
+func (pc *ProjectContext) CopyTree(projectPath string, testdataPath string) {
+	src := pc.h.TestdataPath(testdataPath)
+	err := filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		pc.CopyFile(filepath.Join(projectPath, rel), filepath.Join(testdataPath, rel))
+		return nil
+	})
+	pc.h.Must(errors.Wrapf(err, "Unable to copy %s into the project", testdataPath))
+}
+
+// Load reads the project as dep would, if it has a manifest, or else only
+// reads its lock, if it has one.
+func (pc *ProjectContext) Load() {
+	if pc.h.Exist(pc.getManifestPath()) {
+		p, err := pc.Context.LoadProject()
+		pc.h.Must(errors.Wrap(err, "Unable to load project"))
+		pc.Project = p
+		return
+	}
+
+	pc.Project.Manifest = nil
+	pc.Project.Lock = nil
+	lp := pc.getLockPath()
+	if pc.h.Exist(lp) {
+		lf := pc.h.GetFile(lp)
+		defer lf.Close()
+		l, err := dep.ReadLock(lf)
+		pc.h.Must(errors.Wrapf(err, "Unable to read lock at %s", lp))
+		pc.Project.Lock = l
+	}
+}
+
+// getLockPath returns the full path to the lock
+func (pc *ProjectContext) getLockPath() string {
+	return filepath.Join(pc.Project.AbsRoot, dep.LockName)
+}
+
+// getManifestPath returns the full path to the manifest
+func (pc *ProjectContext) getManifestPath() string {
+	return filepath.Join(pc.Project.AbsRoot, dep.ManifestName)
+}
+
+// getVendorPath returns the full path to the vendor directory
+func (pc *ProjectContext) getVendorPath() string {
+	return filepath.Join(pc.Project.AbsRoot, "vendor")
+}
+
+// LockShouldMatchGolden returns an error when the lock does not match the golden lock.
+// goldenLockPath is the path to the golden lock file relative to the testdata directory
+// Updates the golden file when UpdateGolden is set.
+func (pc *ProjectContext) LockShouldMatchGolden(goldenLockPath string) error {
+	got := pc.h.ReadLock()
+	return pc.ShouldMatchGolden(goldenLockPath, got)
+}
+
+// LockShouldNotExist returns an error when the lock exists.
+func (pc *ProjectContext) LockShouldNotExist() error {
+	return pc.h.ShouldNotExist(pc.getLockPath())
+}
+
+// ManifestShouldMatchGolden returns an error when the manifest does not match the golden manifest.
+// goldenManifestPath is the path to the golden manifest file, relative to the testdata directory
+// Updates the golden file when UpdateGolden is set.
+func (pc *ProjectContext) ManifestShouldMatchGolden(goldenManifestPath string) error {
+	got := pc.h.ReadManifest()
+	return pc.ShouldMatchGolden(goldenManifestPath, got)
+}
+
+// ManifestShouldNotExist returns an error when the manifest exists.
+func (pc *ProjectContext) ManifestShouldNotExist() error {
+	return pc.h.ShouldNotExist(pc.getManifestPath())
+}
+
+// ShouldMatchGolden returns an error when a file does not match the golden file.
+// goldenFile is the path to the golden file, relative to the testdata directory
+// Updates the golden file when UpdateGolden is set.
+func (pc *ProjectContext) ShouldMatchGolden(goldenFile string, got string) error {
+	want := pc.h.GetTestFileString(goldenFile)
+	if want != got {
+		if *UpdateGolden {
+			if err := pc.h.WriteTestFile(goldenFile, got); err != nil {
+				return errors.Wrapf(err, "Unable to write updated golden file %s", goldenFile)
+			}
+		} else {
+			return errors.Errorf("expected %s, got %s", want, got)
+		}
+	}
+
+	return nil
+}
+
+// VendorShouldExist returns an error when the vendor directory does not exist.
+func (pc *ProjectContext) VendorShouldExist() error {
+	return pc.h.ShouldExist(pc.getVendorPath())
+}
+
+// VendorFileShouldExist returns an error when the specified file does not exist in vendor.
+// filePath is the relative path to the file within vendor
+func (pc *ProjectContext) VendorFileShouldExist(filePath string) error {
+	fullPath := filepath.Join(pc.getVendorPath(), filePath)
+	return pc.h.ShouldExist(fullPath)
+}
+
+// VendorShouldNotExist returns an error when the vendor directory exists.
+func (pc *ProjectContext) VendorShouldNotExist() error {
+	return pc.h.ShouldNotExist(pc.getVendorPath())
+}
+
+// Release cleans up after test objects created by this instance
+func (pc *ProjectContext) Release() {
+	if pc.SourceManager != nil {
+		pc.SourceManager.Release()
+	}
+}
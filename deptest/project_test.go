@@ -0,0 +1,43 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deptest
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestRegistersNoFlags(t *testing.T) {
+	// Test binaries importing deptest are free to define these themselves.
+	for _, name := range []string{"logs", "update", "update-golden"} {
+		if flag.Lookup(name) != nil {
+			t.Errorf("expected deptest not to register the -%s flag", name)
+		}
+	}
+}
+
+func TestProjectContext(t *testing.T) {
+	h := NewHelper(t)
+	defer h.Cleanup()
+
+	pc := NewProjectContext(h, "github.com/golang/notexist")
+	defer pc.Release()
+
+	pc.CopyTree(".", "project")
+	pc.Load()
+
+	if pc.Project.ImportRoot != "github.com/golang/notexist" {
+		t.Errorf("expected the project to be at github.com/golang/notexist, got %s", pc.Project.ImportRoot)
+	}
+	if _, has := pc.Project.Manifest.Constraints["github.com/sdboyer/deptest"]; !has {
+		t.Error("expected the manifest to have been read")
+	}
+	if _, has := pc.Project.RootPackageTree.Packages["github.com/golang/notexist"]; !has {
+		t.Error("expected the packages of the project to have been listed")
+	}
+	h.Must(pc.ManifestShouldMatchGolden("project/Gopkg.toml"))
+	h.Must(pc.LockShouldNotExist())
+	h.Must(pc.VendorShouldNotExist())
+}
@@ -0,0 +1,5 @@
+package main
+
+import _ "github.com/sdboyer/deptest"
+
+func main() {}
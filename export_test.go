@@ -0,0 +1,18 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+// Unexported identifiers used by the tests of package dep_test.
+var (
+	DefaultCascadingPruneOptions = defaultCascadingPruneOptions
+	TxnDirPrefix                 = txnDirPrefix
+	ErrVendorBackupFailed        = errVendorBackupFailed
+)
+
+// WritesLock reports whether sw is to write the lock.
+func (sw *SafeWriter) WritesLock() bool { return sw.writeLock }
+
+// WritesVendor reports whether sw is to write vendor.
+func (sw *SafeWriter) WritesVendor() bool { return sw.writeVendor }
@@ -0,0 +1,179 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/pkg/errors"
+)
+
+// ImportRule forbids the packages of the current project beneath Packages to
+// import any package beneath Imports, as set by a [[forbid]] in the manifest.
+type ImportRule struct {
+	// Packages is the import path of a package of the current project, or
+	// its path relative to the project root, starting with ".".
+	Packages string
+	// Imports are the import paths of the forbidden packages.
+	Imports []string
+	// Transitive also forbids the packages to reach the imports through
+	// other packages, whether in the current project or in vendor.
+	Transitive bool
+	// Reason is shown alongside violations of the rule.
+	Reason string
+}
+
+// An ImportViolation is a chain of imports that an ImportRule forbids.
+type ImportViolation struct {
+	Rule ImportRule
+	// Chain starts at a package of the current project and ends at the
+	// forbidden import. It has two elements, unless the rule is transitive.
+	Chain []string
+}
+
+// Import returns the forbidden import at the end of the chain.
+func (v ImportViolation) Import() string {
+	return v.Chain[len(v.Chain)-1]
+}
+
+func (v ImportViolation) String() string {
+	s := fmt.Sprintf("%s is forbidden by [[forbid]] for %s", strings.Join(v.Chain, " -> "), v.Rule.Packages)
+	if v.Rule.Reason != "" {
+		s += ": " + v.Rule.Reason
+	}
+	return s
+}
+
+// ImportViolations returns the import chains that the [[forbid]] rules of
+// the manifest of p forbid, sorted by their packages of the current project.
+// Only the imports of the non-test files of packages are followed; those of
+// dependencies are read from vendor.
+//
+// For each package of the current project and each rule, only the shortest
+// chain to each forbidden import is returned.
+func (p *Project) ImportViolations() ([]ImportViolation, error) {
+	rules := p.Manifest.Forbidden
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	graph := make(map[string][]string)
+	addImports := func(ptree pkgtree.PackageTree) {
+		for ip, poe := range ptree.Packages {
+			if poe.Err == nil {
+				graph[ip] = poe.P.Imports
+			}
+		}
+	}
+	addImports(p.RootPackageTree)
+
+	var transitive bool
+	for _, rule := range rules {
+		transitive = transitive || rule.Transitive
+	}
+	if transitive && p.Lock != nil {
+		vendor := filepath.Join(p.AbsRoot, "vendor")
+		for _, lp := range p.Lock.Projects() {
+			pr := string(lp.Ident().ProjectRoot)
+			dir := filepath.Join(vendor, filepath.FromSlash(pr))
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				continue
+			}
+			ptree, err := pkgtree.ListPackagesForPlatforms(dir, pr, p.Manifest.Platforms)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list the packages of %s in vendor", pr)
+			}
+			addImports(ptree)
+		}
+	}
+
+	var roots []string
+	for ip, poe := range p.RootPackageTree.Packages {
+		if poe.Err == nil {
+			roots = append(roots, ip)
+		}
+	}
+	sort.Strings(roots)
+
+	var violations []ImportViolation
+	for _, rule := range rules {
+		from := rule.Packages
+		if from == "." || strings.HasPrefix(from, "./") {
+			from = path.Join(string(p.ImportRoot), from)
+		}
+		for _, root := range roots {
+			if !hasPathPrefix(root, from) {
+				continue
+			}
+			for _, chain := range forbiddenChains(graph, root, rule) {
+				violations = append(violations, ImportViolation{Rule: rule, Chain: chain})
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Chain[0] < violations[j].Chain[0]
+	})
+	return violations, nil
+}
+
+// forbiddenChains returns the shortest chain through graph from root to each
+// import that rule forbids, following only the imports of root itself unless
+// the rule is transitive. Chains are in the order of their forbidden imports.
+func forbiddenChains(graph map[string][]string, root string, rule ImportRule) [][]string {
+	forbidden := func(ip string) bool {
+		for _, imp := range rule.Imports {
+			if hasPathPrefix(ip, imp) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Search breadth first, so that the first chain found to each import is
+	// the shortest.
+	prev := map[string]string{root: ""}
+	queue := []string{root}
+	var found []string
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, imp := range graph[cur] {
+			if _, seen := prev[imp]; seen {
+				continue
+			}
+			prev[imp] = cur
+			if forbidden(imp) {
+				found = append(found, imp)
+				continue
+			}
+			if rule.Transitive {
+				queue = append(queue, imp)
+			}
+		}
+	}
+	sort.Strings(found)
+
+	chains := make([][]string, 0, len(found))
+	for _, imp := range found {
+		chain := []string{imp}
+		for cur := prev[imp]; cur != ""; cur = prev[cur] {
+			chain = append([]string{cur}, chain...)
+		}
+		chains = append(chains, chain)
+	}
+	return chains
+}
+
+// hasPathPrefix reports whether the import path ip is prefix, or beneath it.
+func hasPathPrefix(ip, prefix string) bool {
+	return ip == prefix || strings.HasPrefix(ip, prefix+"/")
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestImportViolations(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	// github.com/foo/db imports github.com/lib/pq, as vendored.
+	h.TempFile("vendor/github.com/foo/db/db.go", "package db\n\nimport _ \"github.com/lib/pq\"\n")
+	h.TempFile("vendor/github.com/lib/pq/pq.go", "package pq\n")
+
+	pkg := func(ip string, imports ...string) pkgtree.PackageOrErr {
+		return pkgtree.PackageOrErr{P: pkgtree.Package{ImportPath: ip, Imports: imports, TestImports: []string{"github.com/lib/pq"}}}
+	}
+	rev := gps.Revision("d05d5aca9f895d19e9265839bffeadd74a2d2ecb")
+	p := &Project{
+		AbsRoot:    h.Path("."),
+		ImportRoot: "root",
+		RootPackageTree: pkgtree.PackageTree{
+			ImportRoot: "root",
+			Packages: map[string]pkgtree.PackageOrErr{
+				"root/pkg/api":       pkg("root/pkg/api", "fmt", "root/pkg/store"),
+				"root/pkg/api/admin": pkg("root/pkg/api/admin", "github.com/lib/pq"),
+				"root/pkg/store":     pkg("root/pkg/store", "github.com/foo/db"),
+				"root/cmd/server":    pkg("root/cmd/server", "github.com/lib/pq/oid", "root/pkg/api"),
+			},
+		},
+		Lock: &Lock{P: []gps.LockedProject{
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/foo/db"}, rev, []string{"."}),
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/lib/pq"}, rev, []string{"."}),
+			gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/missing/lib"}, rev, []string{"."}),
+		}},
+	}
+	direct := ImportRule{Packages: "./pkg/api", Imports: []string{"github.com/lib/pq"}, Reason: "go through the store"}
+	transitive := ImportRule{Packages: "root/pkg", Imports: []string{"github.com/lib/pq"}, Transitive: true}
+
+	testCases := []struct {
+		name  string
+		rules []ImportRule
+		want  [][]string
+	}{
+		{
+			name: "no rules",
+		},
+		{
+			name:  "direct",
+			rules: []ImportRule{direct},
+			want: [][]string{
+				{"root/pkg/api/admin", "github.com/lib/pq"},
+			},
+		},
+		{
+			name:  "transitive through vendor",
+			rules: []ImportRule{transitive},
+			want: [][]string{
+				{"root/pkg/api", "root/pkg/store", "github.com/foo/db", "github.com/lib/pq"},
+				{"root/pkg/api/admin", "github.com/lib/pq"},
+				{"root/pkg/store", "github.com/foo/db", "github.com/lib/pq"},
+			},
+		},
+		{
+			name:  "whole project",
+			rules: []ImportRule{{Packages: ".", Imports: []string{"github.com/lib/pq"}}},
+			want: [][]string{
+				{"root/cmd/server", "github.com/lib/pq/oid"},
+				{"root/pkg/api/admin", "github.com/lib/pq"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p.Manifest = &Manifest{Forbidden: tc.rules}
+			violations, err := p.ImportViolations()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]string
+			for _, v := range violations {
+				got = append(got, v.Chain)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("unexpected chains:\n\t(GOT): %v\n\t(WNT): %v", got, tc.want)
+			}
+		})
+	}
+
+	p.Manifest = &Manifest{Forbidden: []ImportRule{direct}}
+	violations, _ := p.ImportViolations()
+	want := "root/pkg/api/admin -> github.com/lib/pq is forbidden by [[forbid]] for ./pkg/api: go through the store"
+	if got := violations[0].String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
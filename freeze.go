@@ -0,0 +1,91 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+)
+
+// freezeDate is the layout of the dates of freeze windows.
+const freezeDate = "2006-01-02"
+
+// FreezeOptions declare when changes to the lock are refused, as during a
+// release freeze.
+type FreezeOptions struct {
+	// Windows are the periods during which the lock is frozen.
+	Windows []FreezeWindow
+	// File is the path, relative to the project root, of a file whose
+	// existence freezes the lock.
+	File string
+}
+
+// FreezeWindow is a period during which the lock is frozen, from the start of
+// the day From to the end of the day Until, both given as YYYY-MM-DD in local
+// time.
+type FreezeWindow struct {
+	From   string
+	Until  string
+	Reason string
+}
+
+// Contains reports whether the day of t, in its location, is in w.
+func (w FreezeWindow) Contains(t time.Time) bool {
+	day := t.Format(freezeDate)
+	return w.From <= day && day <= w.Until
+}
+
+// ActiveFreeze returns why the lock of the project at root is frozen at now,
+// and whether it is: because now falls in one of the windows of m, or because
+// the file of m exists. The reason for the latter is the first line of the
+// file, if it has one.
+func (m *Manifest) ActiveFreeze(root string, now time.Time) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+
+	for _, w := range m.Freeze.Windows {
+		if w.Contains(now) {
+			reason := fmt.Sprintf("frozen from %s until %s", w.From, w.Until)
+			if w.Reason != "" {
+				reason += " for " + w.Reason
+			}
+			return reason, true
+		}
+	}
+
+	if m.Freeze.File == "" {
+		return "", false
+	}
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(m.Freeze.File)))
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	if s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			return line, true
+		}
+	}
+	return fmt.Sprintf("%s exists", m.Freeze.File), true
+}
+
+// LockChanged reports whether newLock differs from oldLock in anything other
+// than digests and input imports, which change as vendor and the project's own
+// imports do, rather than as dependencies are updated.
+func LockChanged(oldLock, newLock gps.Lock) bool {
+	if newLock == nil {
+		return false
+	}
+	return DiffLocks(oldLock, newLock).Changed(anyExceptHash & ^verify.InputImportsChanged)
+}
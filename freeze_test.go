@@ -0,0 +1,86 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestActiveFreeze(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("proj")
+	root := h.Path("proj")
+
+	m := NewManifest()
+	m.Freeze = FreezeOptions{
+		Windows: []FreezeWindow{{From: "2018-12-17", Until: "2019-01-04", Reason: "the release"}},
+		File:    "RELEASE_FREEZE",
+	}
+
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02 15:04", s)
+		h.Must(err)
+		return d
+	}
+	for _, c := range []struct {
+		now    string
+		frozen bool
+	}{
+		{"2018-12-16 23:59", false},
+		{"2018-12-17 00:00", true},
+		{"2018-12-25 12:00", true},
+		{"2019-01-04 23:59", true},
+		{"2019-01-05 00:00", false},
+	} {
+		reason, frozen := m.ActiveFreeze(root, day(c.now))
+		if frozen != c.frozen {
+			t.Errorf("expected the lock to be frozen at %s to be %v", c.now, c.frozen)
+		}
+		if frozen && reason != "frozen from 2018-12-17 until 2019-01-04 for the release" {
+			t.Errorf("unexpected reason for the freeze at %s: %q", c.now, reason)
+		}
+	}
+
+	now := day("2019-03-01 12:00")
+	h.TempFile("proj/RELEASE_FREEZE", "")
+	if reason, frozen := m.ActiveFreeze(root, now); !frozen || reason != "RELEASE_FREEZE exists" {
+		t.Errorf("expected the sentinel file to freeze the lock, got %v, %q", frozen, reason)
+	}
+	h.TempFile("proj/RELEASE_FREEZE", "  2.4 is being released  \nby the release team\n")
+	if reason, frozen := m.ActiveFreeze(root, now); !frozen || reason != "2.4 is being released" {
+		t.Errorf("expected the first line of the sentinel file as the reason, got %v, %q", frozen, reason)
+	}
+}
+
+func TestLockChanged(t *testing.T) {
+	lock := func(rev string, digest []byte, imports ...string) *Lock {
+		return &Lock{
+			SolveMeta: SolveMeta{InputImports: imports},
+			P: []gps.LockedProject{
+				verify.VerifiableProject{
+					LockedProject: gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: "github.com/foo/bar"}, gps.Revision(rev), []string{"."}),
+					Digest:        verify.VersionedDigest{HashVersion: verify.HashVersion, Digest: digest},
+				},
+			},
+		}
+	}
+
+	old := lock("abc", []byte{1}, "github.com/foo/bar")
+	if LockChanged(old, lock("abc", []byte{2}, "github.com/foo/bar", "github.com/foo/bar/sub")) {
+		t.Error("expected changes of digests and input imports alone not to change the lock")
+	}
+	if !LockChanged(old, lock("def", []byte{1}, "github.com/foo/bar")) {
+		t.Error("expected a change of revision to change the lock")
+	}
+	if !LockChanged(nil, old) {
+		t.Error("expected writing a new lock to change it")
+	}
+}
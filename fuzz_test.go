@@ -0,0 +1,63 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package dep
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// Run the fuzz targets with, for example:
+//
+//   go test -run XXX -fuzz FuzzReadManifest .
+
+// addFuzzSeeds adds every file in the named testdata directory to the corpus.
+func addFuzzSeeds(f *testing.F, dir string) {
+	files, err := filepath.Glob(filepath.Join("testdata", dir, "*.toml"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+}
+
+func FuzzReadManifest(f *testing.F) {
+	addFuzzSeeds(f, "manifest")
+	// Inputs that have previously caused panics.
+	f.Add([]byte("constraint = []"))
+	f.Add([]byte("override = []"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		m, _, err := readManifest(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+		if _, err := m.MarshalTOML(); err != nil {
+			t.Fatalf("manifest that was read could not be written: %s", err)
+		}
+	})
+}
+
+func FuzzReadLock(f *testing.F) {
+	addFuzzSeeds(f, "lock")
+	f.Fuzz(func(t *testing.T, b []byte) {
+		l, err := readLock(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+		if _, err := l.MarshalTOML(); err != nil {
+			t.Fatalf("lock that was read could not be written: %s", err)
+		}
+	})
+}
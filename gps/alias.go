@@ -0,0 +1,85 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/pkg/errors"
+)
+
+// aliasIgnores validates aliases against the root manifest m, and returns ir
+// with the import paths beneath each alias added to it.
+func aliasIgnores(aliases map[ProjectRoot]ProjectRoot, ir *pkgtree.IgnoredRuleset, m RootManifest) (*pkgtree.IgnoredRuleset, error) {
+	roots := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		roots = append(roots, string(alias))
+	}
+	sort.Strings(roots)
+
+	ovr, constraints := m.Overrides(), m.DependencyConstraints()
+	ig := ir.ToSlice()
+	for _, root := range roots {
+		alias := ProjectRoot(root)
+		target := aliases[alias]
+		switch {
+		case alias == "" || target == "":
+			return nil, badOptsFailure(fmt.Sprintf("an alias of %q to %q must name both import paths", alias, target))
+		case alias == target:
+			return nil, badOptsFailure(fmt.Sprintf("%s was given as an alias of itself", alias))
+		case strings.HasPrefix(root+"/", string(target)+"/") || strings.HasPrefix(string(target)+"/", root+"/"):
+			return nil, badOptsFailure(fmt.Sprintf("%s cannot be an alias of %s, as one contains the other", alias, target))
+		}
+		if _, has := aliases[target]; has {
+			return nil, badOptsFailure(fmt.Sprintf("%s is an alias of %s, which is itself an alias", alias, target))
+		}
+		if _, has := ovr[alias]; has {
+			return nil, badOptsFailure(fmt.Sprintf("%s is an alias of %s, and cannot also have an override", alias, target))
+		}
+		if _, has := constraints[alias]; has {
+			return nil, badOptsFailure(fmt.Sprintf("%s is an alias of %s, and cannot also have a constraint", alias, target))
+		}
+		ig = append(ig, root, root+"/*")
+	}
+
+	return pkgtree.NewIgnoredRuleset(ig), nil
+}
+
+// aliasProjects returns the LockedProjects of the aliases of the projects in
+// all, a solution as returned by solve. Each holds the version, source and
+// packages of the project it is an alias of, under the alias.
+func (s *solver) aliasProjects(all map[atom]map[string]struct{}) ([]LockedProject, error) {
+	var lps []LockedProject
+	for pa := range all {
+		for alias, target := range s.aliases {
+			if pa.id.ProjectRoot != target {
+				continue
+			}
+
+			ptree, err := s.b.ListPackages(pa.id, pa.v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not list the packages of %s for its alias %s", target, alias)
+			}
+			pkgs := make(map[string]struct{}, len(ptree.Packages))
+			for ip, perr := range ptree.Packages {
+				if perr.Err != nil {
+					continue
+				}
+				pkgs[string(alias)+strings.TrimPrefix(ip, string(target))] = struct{}{}
+			}
+
+			id := pa.id
+			id.ProjectRoot = alias
+			if id.Source == "" {
+				id.Source = string(target)
+			}
+			lps = append(lps, pa2lp(atom{id: id, v: pa.v}, pkgs))
+		}
+	}
+	return lps, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSolveWithAlias(t *testing.T) {
+	ds := []depspec{
+		mkDepspec("root 0.0.0", "foo 1.0.0"),
+		mkDepspec("foo 1.0.0"),
+	}
+	fix := basicFixture{ds: ds}
+
+	params := SolveParameters{
+		RootDir:         string(ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		ProjectAnalyzer: naiveAnalyzer{},
+		Aliases:         map[ProjectRoot]ProjectRoot{"oldfoo": "foo"},
+	}
+
+	soln, err := fixSolve(params, newdepspecSM(ds, nil), t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lps := soln.Projects()
+	if len(lps) != 2 {
+		t.Fatalf("expected the solution to hold foo and its alias, got %v", lps)
+	}
+	foo, oldfoo := lps[0], lps[1]
+	if foo.Ident().ProjectRoot != "foo" || oldfoo.Ident().ProjectRoot != "oldfoo" {
+		t.Fatalf("unexpected projects in solution: %v", lps)
+	}
+	if oldfoo.Ident().Source != "foo" {
+		t.Errorf("expected the alias to come from the source of foo, got %q", oldfoo.Ident().Source)
+	}
+	if oldfoo.Version() != foo.Version() {
+		t.Errorf("expected the alias to be at %s, as foo is, got %s", foo.Version(), oldfoo.Version())
+	}
+	if !reflect.DeepEqual(oldfoo.Packages(), foo.Packages()) {
+		t.Errorf("expected the alias to hold the packages of foo %v, got %v", foo.Packages(), oldfoo.Packages())
+	}
+}
+
+func TestBadAliases(t *testing.T) {
+	ds := []depspec{
+		mkDepspec("root 0.0.0", "foo 1.0.0"),
+		mkDepspec("foo 1.0.0"),
+	}
+	fix := basicFixture{ds: ds}
+
+	cases := []struct {
+		aliases map[ProjectRoot]ProjectRoot
+		err     string
+	}{
+		{map[ProjectRoot]ProjectRoot{"foo": "foo"}, "alias of itself"},
+		{map[ProjectRoot]ProjectRoot{"foo/old": "foo"}, "one contains the other"},
+		{map[ProjectRoot]ProjectRoot{"a": "b", "b": "foo"}, "itself an alias"},
+		{map[ProjectRoot]ProjectRoot{"foo": "bar"}, "cannot also have a constraint"},
+	}
+	for _, c := range cases {
+		params := SolveParameters{
+			RootDir:         string(ds[0].n),
+			RootPackageTree: fix.rootTree(),
+			Manifest:        fix.rootmanifest(),
+			ProjectAnalyzer: naiveAnalyzer{},
+			Aliases:         c.aliases,
+		}
+		_, err := Prepare(params, newdepspecSM(ds, nil))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected aliases %v to fail with %q, got %v", c.aliases, c.err, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+// AvailableVersion is a version of a project, as listed by
+// ListAvailableVersions.
+type AvailableVersion struct {
+	// Version is a tag or branch, paired with the revision it points to.
+	Version PairedVersion
+	// DefaultBranch is true if Version is the default branch of the source.
+	DefaultBranch bool
+	// Satisfies is true if Version satisfies the constraint passed to
+	// ListAvailableVersions.
+	Satisfies bool
+}
+
+// ListAvailableVersions returns every version of the project id that sm can
+// find, that is, its tags and branches, sorted by SortPairedForUpgrade. Each
+// is marked with whether it satisfies c; a nil c is satisfied by any version.
+//
+// This is the same list of versions that the solver works through, so that it
+// answers which versions a project could be upgraded to.
+func ListAvailableVersions(sm SourceManager, id ProjectIdentifier, c Constraint) ([]AvailableVersion, error) {
+	vl, err := sm.ListVersions(id)
+	if err != nil {
+		return nil, err
+	}
+	SortPairedForUpgrade(vl)
+
+	if c == nil {
+		c = Any()
+	}
+	avl := make([]AvailableVersion, 0, len(vl))
+	for _, v := range vl {
+		bv, isBranch := v.Unpair().(branchVersion)
+		avl = append(avl, AvailableVersion{
+			Version:       v,
+			DefaultBranch: isBranch && bv.isDefault,
+			Satisfies:     c.Matches(v),
+		})
+	}
+	return avl, nil
+}
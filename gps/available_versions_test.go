@@ -0,0 +1,56 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"reflect"
+	"testing"
+)
+
+// versionListSM is a SourceManager that only lists versions.
+type versionListSM struct {
+	SourceManager
+	vl []PairedVersion
+}
+
+func (sm versionListSM) ListVersions(ProjectIdentifier) ([]PairedVersion, error) {
+	return append([]PairedVersion(nil), sm.vl...), nil
+}
+
+func TestListAvailableVersions(t *testing.T) {
+	sm := versionListSM{vl: []PairedVersion{
+		NewBranch("develop").Pair("rev4"),
+		NewVersion("v1.0.0").Pair("rev1"),
+		newDefaultBranch("master").Pair("rev3"),
+		NewVersion("v2.0.0").Pair("rev3"),
+		NewVersion("v1.1.0").Pair("rev2"),
+	}}
+
+	avl, err := ListAvailableVersions(sm, mkPI("example.com/foo"), mkSVC("^1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AvailableVersion{
+		{Version: NewVersion("v2.0.0").Pair("rev3")},
+		{Version: NewVersion("v1.1.0").Pair("rev2"), Satisfies: true},
+		{Version: NewVersion("v1.0.0").Pair("rev1"), Satisfies: true},
+		{Version: newDefaultBranch("master").Pair("rev3"), DefaultBranch: true},
+		{Version: NewBranch("develop").Pair("rev4")},
+	}
+	if !reflect.DeepEqual(avl, want) {
+		t.Errorf("expected versions:\n\t%v\ngot:\n\t%v", want, avl)
+	}
+
+	avl, err = ListAvailableVersions(sm, mkPI("example.com/foo"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, av := range avl {
+		if !av.Satisfies {
+			t.Errorf("expected %s to satisfy no constraint", av.Version)
+		}
+	}
+}
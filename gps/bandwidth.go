@@ -0,0 +1,247 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// httpTransport is the transport of the HTTP clients of gps, or nil for
+	// http.DefaultTransport.
+	httpTransport http.RoundTripper
+	// bandwidthProxy is the URL of the local proxy through which VCS tools
+	// reach http and https remotes when bandwidth is limited.
+	bandwidthProxy string
+	// bandwidth paces all transfers when bandwidth is limited.
+	bandwidth *bandwidthLimiter
+)
+
+// LimitBandwidth limits the combined rate at which gps transfers data to and
+// from remotes to bytesPerSecond. It applies to the requests gps makes itself,
+// and to the http and https transfers of git, hg and bzr, which are routed
+// through a throttling proxy that gps runs on the loopback interface. Any
+// proxy configured in the environment is used by that proxy in turn. Transfers
+// over ssh, and all transfers of svn, are not limited.
+//
+// LimitBandwidth must be called before any SourceManager is created. Calling
+// it again changes the limit.
+func LimitBandwidth(bytesPerSecond int64) error {
+	if bytesPerSecond <= 0 {
+		return errors.Errorf("bandwidth must be positive, got %d", bytesPerSecond)
+	}
+	if bandwidth != nil {
+		bandwidth.mu.Lock()
+		bandwidth.rate = bytesPerSecond
+		bandwidth.mu.Unlock()
+		return nil
+	}
+
+	l := &bandwidthLimiter{rate: bytesPerSecond}
+	d := &throttledDialer{limiter: l}
+	t := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         d.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return errors.Wrap(err, "unable to start bandwidth limiting proxy")
+	}
+	go http.Serve(ln, &throttlingProxy{
+		dialer: d,
+		forward: &httputil.ReverseProxy{
+			Director:  func(*http.Request) {},
+			Transport: t,
+		},
+	})
+
+	httpTransport, bandwidthProxy, bandwidth = t, "http://"+ln.Addr().String(), l
+	gitHTTPClient.Transport = t
+	return nil
+}
+
+// bandwidthLimiter paces transfers so that they proceed at no more than rate
+// bytes per second in total.
+type bandwidthLimiter struct {
+	mu   sync.Mutex
+	rate int64
+	// next is when the transfer of the next byte is due.
+	next time.Time
+}
+
+// throttleChunk bounds the bytes read or written at once on a throttled
+// connection, so that the transfer is smooth rather than bursty.
+const throttleChunk = 16 << 10
+
+// wait blocks until the transfer of n more bytes is due.
+func (l *bandwidthLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	due := l.next
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	l.mu.Unlock()
+
+	time.Sleep(due.Sub(now))
+}
+
+// throttledConn is a net.Conn whose reads and writes are paced by a limiter.
+type throttledConn struct {
+	net.Conn
+	limiter *bandwidthLimiter
+}
+
+func (c throttledConn) Read(p []byte) (int, error) {
+	if len(p) > throttleChunk {
+		p = p[:throttleChunk]
+	}
+	n, err := c.Conn.Read(p)
+	c.limiter.wait(n)
+	return n, err
+}
+
+func (c throttledConn) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > throttleChunk {
+			chunk = chunk[:throttleChunk]
+		}
+		c.limiter.wait(len(chunk))
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// throttledDialer dials connections paced by a limiter.
+type throttledDialer struct {
+	net.Dialer
+	limiter *bandwidthLimiter
+}
+
+func (d *throttledDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	c, err := d.Dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return throttledConn{Conn: c, limiter: d.limiter}, nil
+}
+
+// throttlingProxy is an HTTP proxy whose connections to remotes are paced by
+// the limiter of its dialer.
+type throttlingProxy struct {
+	dialer  *throttledDialer
+	forward http.Handler
+}
+
+func (p *throttlingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "CONNECT" {
+		p.forward.ServeHTTP(w, r)
+		return
+	}
+
+	upstream, err := p.dialTunnel(r.Context(), r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "unable to tunnel", http.StatusInternalServerError)
+		return
+	}
+	client, brw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	if _, err := io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		return
+	}
+
+	// Once either side is done, so is the tunnel.
+	go func() {
+		io.Copy(upstream, brw)
+		client.Close()
+		upstream.Close()
+	}()
+	io.Copy(client, upstream)
+}
+
+// dialTunnel opens a connection to host, through the proxy configured in the
+// environment for https, if any.
+func (p *throttlingProxy) dialTunnel(ctx context.Context, host string) (net.Conn, error) {
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: host}})
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil {
+		return p.dialer.DialContext(ctx, "tcp", host)
+	}
+
+	c, err := p.dialer.DialContext(ctx, "tcp", proxy.Host)
+	if err != nil {
+		return nil, err
+	}
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: host},
+		Host:   host,
+		Header: make(http.Header),
+	}
+	if u := proxy.User; u != nil {
+		pw, _ := u.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.Username()+":"+pw)))
+	}
+	if err := req.Write(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.Close()
+		return nil, errors.Errorf("proxy %s refused to connect to %s: %s", proxy.Host, host, resp.Status)
+	}
+	return bufferedConn{Conn: c, r: br}, nil
+}
+
+// bufferedConn is a net.Conn whose reads are served from r, which buffers
+// reads from the connection itself.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
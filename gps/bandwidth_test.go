@@ -0,0 +1,87 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBandwidthLimiter(t *testing.T) {
+	l := &bandwidthLimiter{rate: 100 << 10}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		l.wait(10 << 10)
+	}
+	// The first 10KB are due at once, and the rest after 400ms.
+	if d := time.Since(start); d < 350*time.Millisecond || d > 2*time.Second {
+		t.Errorf("expected 50KB at 100KB/s to take about 400ms, took %s", d)
+	}
+}
+
+func TestLimitBandwidth(t *testing.T) {
+	if err := LimitBandwidth(0); err == nil {
+		t.Error("expected an error for no bandwidth")
+	}
+
+	body := bytes.Repeat([]byte("x"), 48<<10)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	defer func() {
+		httpTransport, bandwidthProxy, bandwidth = nil, "", nil
+		gitHTTPClient.Transport = nil
+	}()
+	if err := LimitBandwidth(64 << 10); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(gitEnv(), "\n"), "GIT_CONFIG_VALUE_1="+bandwidthProxy) {
+		t.Errorf("expected git to be configured to use %s", bandwidthProxy)
+	}
+	if err := LimitBandwidth(128 << 10); err != nil || bandwidth.rate != 128<<10 {
+		t.Errorf("expected the limit to be changed, got %d (%v)", bandwidth.rate, err)
+	}
+
+	proxy, err := url.Parse(bandwidthProxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A client of the proxy, like git: http is forwarded by it, and https
+	// tunnelled through it.
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxy),
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	for _, u := range []string{plain.URL, secure.URL} {
+		start := time.Now()
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, body) {
+			t.Errorf("%s: unexpected body of %d bytes", u, len(got))
+		}
+		if d := time.Since(start); d < 250*time.Millisecond {
+			t.Errorf("%s: expected 48KB at 128KB/s to take about 375ms, took %s", u, d)
+		}
+	}
+}
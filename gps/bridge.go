@@ -26,9 +26,11 @@ type sourceBridge interface {
 	DeduceProjectRoot(ip string) (ProjectRoot, error)
 
 	listVersions(ProjectIdentifier) ([]Version, error)
+	pairVersion(ProjectIdentifier, UnpairedVersion) (Version, error)
 	verifyRootDir(path string) error
 	vendorCodeExists(ProjectIdentifier) (bool, error)
 	breakLock()
+	dropCaches()
 }
 
 // bridge is an adapter around a proper SourceManager. It provides localized
@@ -112,6 +114,39 @@ func (b *bridge) listVersions(id ProjectIdentifier) ([]Version, error) {
 	return vl, nil
 }
 
+// pairVersion returns the version in the project's version list that uv
+// matches, paired with its revision, or nil if there is none. Unlike
+// listVersions, it does not sort the list.
+func (b *bridge) pairVersion(id ProjectIdentifier, uv UnpairedVersion) (Version, error) {
+	if vl, exists := b.vlists[id]; exists {
+		return findUnpaired(vl, uv), nil
+	}
+
+	b.s.mtr.push("b-pair-version")
+	pvl, err := b.sm.ListVersions(id)
+	b.s.mtr.pop()
+	if err != nil {
+		return nil, err
+	}
+	return findUnpaired(hidePair(pvl), uv), nil
+}
+
+// findUnpaired returns the first version in vl that uv matches, or nil if
+// there is none.
+func findUnpaired(vl []Version, uv UnpairedVersion) Version {
+	for _, v := range vl {
+		if uv.Matches(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+// dropCaches discards the bridge's local caches. They are rebuilt on demand.
+func (b *bridge) dropCaches() {
+	b.vlists = make(map[ProjectIdentifier][]Version)
+}
+
 func (b *bridge) RevisionPresentIn(id ProjectIdentifier, r Revision) (bool, error) {
 	b.s.mtr.push("b-rev-present-in")
 	i, e := b.sm.RevisionPresentIn(id, r)
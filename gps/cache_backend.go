@@ -0,0 +1,135 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
+)
+
+// A CacheBackend is a shared store of source repositories, laid out as in the
+// sources directory of a local cache. Whenever the local cache lacks a source,
+// the SourceManager first asks the CacheBackend for a copy, and only clones
+// the source from upstream if the backend has none. A copy fetched from the
+// backend need not be up to date: it is brought up to date from upstream if
+// that is needed, which is generally much cheaper than a full clone.
+type CacheBackend interface {
+	// FetchSource copies the repository stored under name, the name of its
+	// directory in the sources directory of the cache, to dir, which does not
+	// exist. It reports false, without error, if the backend does not hold
+	// the repository.
+	FetchSource(ctx context.Context, name, dir string) (bool, error)
+}
+
+// cacheBackendTimeout bounds the time taken to fetch a single source from an
+// HTTP cache backend.
+const cacheBackendTimeout = 10 * time.Minute
+
+// NewCacheBackend returns a read-only CacheBackend for location. If location
+// is an http or https URL, the repository stored under name is fetched from
+// <location>/sources/<name>.tar.gz, a gzipped tarball of its directory.
+// Otherwise, location is taken to be a directory, such as a shared network
+// mount, holding a sources directory just like that of a local cache; a fully
+// warmed cache directory from another machine can thus be used as is.
+func NewCacheBackend(location string) (CacheBackend, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		if _, err := url.Parse(location); err != nil {
+			return nil, errors.Wrapf(err, "invalid cache backend URL %s", location)
+		}
+		return httpCacheBackend{
+			base:   strings.TrimSuffix(location, "/"),
+			client: &http.Client{Timeout: cacheBackendTimeout, Transport: httpTransport},
+		}, nil
+	}
+
+	fi, err := os.Stat(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to use cache backend %s", location)
+	}
+	if !fi.IsDir() {
+		return nil, errors.Errorf("cache backend %s is not a directory", location)
+	}
+	return dirCacheBackend{root: location}, nil
+}
+
+// dirCacheBackend is a CacheBackend that copies sources from a directory.
+type dirCacheBackend struct {
+	root string
+}
+
+func (b dirCacheBackend) FetchSource(ctx context.Context, name, dir string) (bool, error) {
+	src := filepath.Join(b.root, "sources", name)
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrapf(err, "unable to read %s", src)
+	}
+	if err := fs.CopyDir(src, dir); err != nil {
+		return false, errors.Wrapf(err, "failed to copy %s", src)
+	}
+	return true, nil
+}
+
+// cacheBackends is a CacheBackend that tries each of its backends in turn,
+// until one of them holds the source.
+type cacheBackends []CacheBackend
+
+func (bs cacheBackends) FetchSource(ctx context.Context, name, dir string) (bool, error) {
+	var err error
+	for _, b := range bs {
+		var ok bool
+		if ok, err = b.FetchSource(ctx, name, dir); ok {
+			return true, nil
+		}
+		// Clear out whatever a failed attempt may have left behind, before
+		// the next backend is tried.
+		os.RemoveAll(dir)
+	}
+	return false, err
+}
+
+// httpCacheBackend is a CacheBackend that downloads sources as tarballs from
+// an HTTP server, such as an object store.
+type httpCacheBackend struct {
+	base   string
+	client *http.Client
+}
+
+func (b httpCacheBackend) FetchSource(ctx context.Context, name, dir string) (bool, error) {
+	u := b.base + "/sources/" + url.PathEscape(name) + ".tar.gz"
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to build request for %s", u)
+	}
+	resp, err := b.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to fetch %s", u)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		// Object stores commonly answer 403 for missing objects when listing
+		// is not permitted.
+		return false, nil
+	default:
+		return false, errors.Errorf("failed to fetch %s: %s", u, resp.Status)
+	}
+
+	if err := fs.ExtractTarGz(resp.Body, dir); err != nil {
+		os.RemoveAll(dir)
+		return false, errors.Wrapf(err, "failed to unpack %s", u)
+	}
+	return true, nil
+}
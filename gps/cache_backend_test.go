@@ -0,0 +1,221 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// tarGz returns a gzipped tarball of files, which map names to contents. Names
+// ending in a slash are directories, and contents beginning with "->" are
+// symlink targets.
+func tarGz(t *testing.T, files [][2]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		name, content := f[0], f[1]
+		hdr := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(content))}
+		switch {
+		case name[len(name)-1] == '/':
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0755, 0
+		case len(content) > 2 && content[:2] == "->":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, content[2:], 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDirCacheBackend(t *testing.T) {
+	root, err := ioutil.TempDir("", "cachebackend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	src := filepath.Join(root, "remote", "sources", "https---github.com-foo-bar")
+	if err := os.MkdirAll(filepath.Join(src, ".git"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, ".git", "HEAD"), []byte("ref: refs/heads/master\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewCacheBackend(filepath.Join(root, "remote"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(root, "local", "https---github.com-foo-bar")
+	if ok, err := b.FetchSource(context.Background(), "https---github.com-foo-bar", dst); err != nil || !ok {
+		t.Fatalf("expected the source to be fetched, got %v, %v", ok, err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dst, ".git", "HEAD")); err != nil || string(b) != "ref: refs/heads/master\n" {
+		t.Errorf("expected the source to be copied, got %q, %v", b, err)
+	}
+
+	if ok, err := b.FetchSource(context.Background(), "https---github.com-foo-baz", filepath.Join(root, "local", "baz")); err != nil || ok {
+		t.Errorf("expected a missing source not to be fetched, got %v, %v", ok, err)
+	}
+
+	if _, err := NewCacheBackend(filepath.Join(root, "missing")); err == nil {
+		t.Error("expected an error for a missing cache backend directory")
+	}
+}
+
+func TestCacheBackendsFallThrough(t *testing.T) {
+	root, err := ioutil.TempDir("", "cachebackend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.MkdirAll(filepath.Join(root, "second", "sources", "foo"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	bs := cacheBackends{dirCacheBackend{root: filepath.Join(root, "first")}, dirCacheBackend{root: filepath.Join(root, "second")}}
+	if ok, err := bs.FetchSource(context.Background(), "foo", filepath.Join(root, "dst")); err != nil || !ok {
+		t.Fatalf("expected the source to be fetched from the second backend, got %v, %v", ok, err)
+	}
+	if ok, err := bs.FetchSource(context.Background(), "bar", filepath.Join(root, "bar")); err != nil || ok {
+		t.Errorf("expected a source held by no backend not to be fetched, got %v, %v", ok, err)
+	}
+}
+
+func TestHTTPCacheBackend(t *testing.T) {
+	archive := tarGz(t, [][2]string{
+		{".git/", ""},
+		{".git/HEAD", "ref: refs/heads/master\n"},
+		{"link", "->.git/HEAD"},
+	})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache/sources/https---github.com-foo-bar.tar.gz":
+			w.Write(archive)
+		case "/cache/sources/https---github.com-foo-broken.tar.gz":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	root, err := ioutil.TempDir("", "cachebackend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	b, err := NewCacheBackend(ts.URL + "/cache/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(root, "bar")
+	if ok, err := b.FetchSource(context.Background(), "https---github.com-foo-bar", dst); err != nil || !ok {
+		t.Fatalf("expected the source to be fetched, got %v, %v", ok, err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dst, "link")); err != nil || string(b) != "ref: refs/heads/master\n" {
+		t.Errorf("expected the source to be unpacked, got %q, %v", b, err)
+	}
+
+	if ok, err := b.FetchSource(context.Background(), "https---github.com-foo-baz", filepath.Join(root, "baz")); err != nil || ok {
+		t.Errorf("expected a missing source not to be fetched, got %v, %v", ok, err)
+	}
+	if _, err := b.FetchSource(context.Background(), "https---github.com-foo-broken", filepath.Join(root, "broken")); err == nil {
+		t.Error("expected an error for a server error")
+	}
+}
+
+func TestSourceGatewaySeedsFromBackend(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	root, err := ioutil.TempDir("", "cachebackend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=dep", "-c", "user.email=dep@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	up := filepath.Join(root, "upstream")
+	if err := os.MkdirAll(up, 0777); err != nil {
+		t.Fatal(err)
+	}
+	run(up, "init")
+	if err := ioutil.WriteFile(filepath.Join(up, "foo.go"), []byte("package foo\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	run(up, "add", "foo.go")
+	run(up, "commit", "-m", "foo")
+	run(up, "tag", "v1.0.0")
+
+	u := "file://" + filepath.ToSlash(up)
+	backend := filepath.Join(root, "backend")
+	seeded := sourceCachePath(backend, u)
+	run(root, "clone", u, seeded)
+	// Mark the copy in the backend, so that it can be told apart from a clone.
+	if err := ioutil.WriteFile(filepath.Join(seeded, ".git", "seeded"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	cachedir := filepath.Join(root, "cache")
+	if err := os.MkdirAll(filepath.Join(cachedir, "sources"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	src, err := maybeGitSource{url: mkurl(u)}.try(ctx, cachedir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg, err := newSourceGateway(ctx, src, newSupervisor(ctx), cachedir, newMemoryCache(), dirCacheBackend{root: backend}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sg.require(ctx, sourceExistsLocally); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(sourceCachePath(cachedir, u), ".git", "seeded")); err != nil {
+		t.Fatalf("expected the source to be copied from the backend: %v", err)
+	}
+
+	vl, err := sg.listVersions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vl) != 2 {
+		t.Errorf("expected the seeded source to list both master and v1.0.0, got %v", vl)
+	}
+}
@@ -5,7 +5,10 @@
 package gps
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 func (c cmd) Args() []string {
@@ -31,3 +34,50 @@ func init() {
 		os.Unsetenv(e)
 	}
 }
+
+// gitEnv returns the environment in which to run git on the repositories in
+// the cache. The cache may well have been populated by another user, such as
+// root in a container image, so git is told to trust repositories regardless
+// of their owner; otherwise, recent versions of git refuse to operate on them.
+// This is only ever passed to git commands that dep runs on its own cache.
+func gitEnv() []string {
+	env := withGitConfig(os.Environ(), "safe.directory", "*")
+	if bandwidthProxy != "" {
+		env = withGitConfig(env, "http.proxy", bandwidthProxy)
+	}
+	return env
+}
+
+// toolEnv returns the environment in which to run the tool called name, or nil
+// to run it in that of dep itself.
+func toolEnv(name string) []string {
+	switch {
+	case name == "git":
+		return gitEnv()
+	case bandwidthProxy != "" && (name == "hg" || name == "bzr"):
+		return append(os.Environ(),
+			"http_proxy="+bandwidthProxy, "https_proxy="+bandwidthProxy,
+			"HTTP_PROXY="+bandwidthProxy, "HTTPS_PROXY="+bandwidthProxy,
+		)
+	}
+	return nil
+}
+
+// withGitConfig returns env with the git config key set to value, by way of
+// the GIT_CONFIG_COUNT variables, after any config already set that way.
+func withGitConfig(env []string, key, value string) []string {
+	var n int
+	out := make([]string, 0, len(env)+3)
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "GIT_CONFIG_COUNT=") {
+			n, _ = strconv.Atoi(strings.TrimPrefix(kv, "GIT_CONFIG_COUNT="))
+			continue
+		}
+		out = append(out, kv)
+	}
+	return append(out,
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", n, key),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", n, value),
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+1),
+	)
+}
@@ -0,0 +1,227 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A CommandRecord describes an external VCS command that gps ran, or refused
+// to run, on a source.
+type CommandRecord struct {
+	Args     []string      `json:"args"`
+	Dir      string        `json:"dir,omitempty"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	// ExitCode is the exit code of the command, or -1 if it did not run to
+	// completion.
+	ExitCode int `json:"exitCode"`
+	// CombinedOutputBytes is the number of bytes the command wrote to its
+	// standard output and error. It is not the number of bytes the command
+	// transferred over the network, which gps cannot observe.
+	CombinedOutputBytes int    `json:"combinedOutputBytes"`
+	Denied              bool   `json:"denied,omitempty"`
+	Error               string `json:"error,omitempty"`
+}
+
+var (
+	// commandLog receives a JSON record of each command, if not nil.
+	commandLog   io.Writer
+	commandLogMu sync.Mutex
+	// allowedCommands maps the names of tools to the subcommands of each that
+	// may be run. A nil set permits every subcommand; a nil map, every tool.
+	allowedCommands map[string]map[string]bool
+)
+
+// LogCommands makes gps write a CommandRecord, as a line of JSON, to w for
+// each external VCS command it runs on a source, or refuses to run because
+// AllowCommands does not permit it. Passing nil stops logging.
+//
+// LogCommands must be called before any SourceManager is created.
+func LogCommands(w io.Writer) {
+	commandLog = w
+}
+
+// AllowCommands restricts the external VCS commands gps runs to those in
+// allowed. Each entry is either the name of a tool, such as "git", to permit
+// all its subcommands, or a tool followed by a subcommand, such as "git
+// fetch". Commands that are not permitted fail without being run. Passing no
+// entries lifts the restriction.
+//
+// AllowCommands must be called before any SourceManager is created.
+func AllowCommands(allowed []string) error {
+	if len(allowed) == 0 {
+		allowedCommands = nil
+		return nil
+	}
+
+	m := make(map[string]map[string]bool)
+	for _, entry := range allowed {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 || len(fields) > 2 {
+			return errors.Errorf("%q is neither a tool nor a tool and subcommand", entry)
+		}
+		if !isVCSTool(fields[0]) {
+			return errors.Errorf("cannot allow commands of unknown tool %q, expected one of %s", fields[0], strings.Join(vcsToolNames, ", "))
+		}
+
+		subs, has := m[fields[0]]
+		switch {
+		case len(fields) == 1:
+			m[fields[0]] = nil
+		case has && subs == nil:
+			// All subcommands are already permitted.
+		case subs == nil:
+			m[fields[0]] = map[string]bool{fields[1]: true}
+		default:
+			subs[fields[1]] = true
+		}
+	}
+	allowedCommands = m
+	return nil
+}
+
+// checkCommand returns an error if AllowCommands does not permit args to be
+// run.
+func checkCommand(args []string) error {
+	if allowedCommands == nil {
+		return nil
+	}
+
+	tool := toolName(args[0])
+	subs, has := allowedCommands[tool]
+	if !has {
+		return errors.Errorf("%s commands are not allowed", tool)
+	}
+	if subs == nil {
+		return nil
+	}
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if !subs[arg] {
+			return errors.Errorf("%s %s is not allowed", tool, arg)
+		}
+		return nil
+	}
+	return errors.Errorf("%s without a subcommand is not allowed", tool)
+}
+
+// toolName returns the name of the tool run as path.
+func toolName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".exe")
+}
+
+// CombinedOutput runs the command, if AllowCommands permits it, and returns
+// its combined standard output and error. The command is interrupted and
+// retried as set with SetCommandLimits for its operation, and each attempt is
+// recorded in the log set with LogCommands, if any.
+func (c cmd) CombinedOutput() ([]byte, error) {
+	if err := checkCommand(c.Args()); err != nil {
+		logCommand(CommandRecord{
+			Args:     c.Args(),
+			Dir:      c.Cmd.Dir,
+			Start:    time.Now(),
+			ExitCode: -1,
+			Denied:   true,
+			Error:    err.Error(),
+		})
+		return nil, err
+	}
+
+	op := c.op
+	if op == "" {
+		op = commandOp(c.Args())
+	}
+	limits := commandLimits[op]
+	for attempt := 0; ; attempt++ {
+		out, err := c.attempt(limits.Timeout)
+		if err == nil || attempt >= limits.Retries || c.ctx.Err() != nil {
+			return out, err
+		}
+	}
+}
+
+// attempt runs a copy of the command, interrupting it after timeout if that is
+// positive, and records it in the command log.
+func (c cmd) attempt(timeout time.Duration) ([]byte, error) {
+	ctx, cancel := c.ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(c.ctx, timeout)
+	}
+	defer cancel()
+
+	ac := c.withContext(ctx)
+	if ac.stdin != nil {
+		ac.Cmd.Stdin = bytes.NewReader(ac.stdin)
+	}
+	rec := CommandRecord{
+		Args:     ac.Args(),
+		Dir:      ac.Cmd.Dir,
+		Start:    time.Now(),
+		ExitCode: -1,
+	}
+	out, err := ac.combinedOutput()
+	if err != nil && ctx.Err() == context.DeadlineExceeded && c.ctx.Err() == nil {
+		err = &commandTimeoutError{args: ac.Args(), timeout: timeout}
+	}
+
+	rec.Duration = time.Since(rec.Start)
+	rec.CombinedOutputBytes = len(out)
+	if ac.Cmd.ProcessState != nil {
+		if ws, ok := ac.Cmd.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Exited() {
+			rec.ExitCode = ws.ExitStatus()
+		}
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		rec.Error = err.Error()
+	}
+	logCommand(rec)
+	return out, err
+}
+
+// RunCommand runs the VCS tool name, such as "git", with args in dir, as gps
+// runs the commands of its sources: only if AllowCommands permits it, within
+// the limits set with SetCommandLimits, in the environment gps gives the tool,
+// and recorded in the log set with LogCommands. If stdin is not nil, it is the
+// command's standard input. It returns the command's combined standard output
+// and error.
+//
+// Programs built on gps use it to run their own VCS commands, such as on the
+// repositories in the source cache, so that they are audited alike.
+func RunCommand(ctx context.Context, dir string, stdin []byte, name string, args ...string) ([]byte, error) {
+	c := commandContext(ctx, name, args...)
+	c.Cmd.Dir = dir
+	c.stdin = stdin
+	return c.CombinedOutput()
+}
+
+// logCommand writes rec to the command log, if there is one. Failures to do
+// so are ignored, so that they do not fail the command itself.
+func logCommand(rec CommandRecord) {
+	if commandLog == nil {
+		return
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+
+	commandLogMu.Lock()
+	defer commandLogMu.Unlock()
+	commandLog.Write(append(b, '\n'))
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestAllowCommands(t *testing.T) {
+	defer AllowCommands(nil)
+
+	for _, bad := range [][]string{{"cvs"}, {"git fetch origin"}, {" "}} {
+		if err := AllowCommands(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+
+	if err := AllowCommands([]string{"git ls-remote", "git fetch", "hg", "hg pull"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		args    []string
+		allowed bool
+	}{
+		{[]string{"git", "ls-remote", "https://example.com/foo"}, true},
+		{[]string{"/opt/git/bin/git", "fetch", "--tags", "--prune", "origin"}, true},
+		{[]string{"hg", "clone", "https://example.com/foo"}, true},
+		{[]string{"git", "clone", "https://example.com/foo"}, false},
+		{[]string{"git", "--version"}, false},
+		{[]string{"bzr", "pull"}, false},
+	} {
+		if err := checkCommand(c.args); c.allowed && err != nil {
+			t.Errorf("%q: unexpected error: %v", c.args, err)
+		} else if !c.allowed && err == nil {
+			t.Errorf("%q: expected an error", c.args)
+		}
+	}
+
+	if err := AllowCommands(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkCommand([]string{"bzr", "pull"}); err != nil {
+		t.Errorf("expected every command to be allowed, got %v", err)
+	}
+}
+
+func TestLogCommands(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	var buf bytes.Buffer
+	LogCommands(&buf)
+	defer LogCommands(nil)
+	defer AllowCommands(nil)
+
+	if _, err := commandContext(context.Background(), "git", "version").CombinedOutput(); err != nil {
+		t.Fatal(err)
+	}
+	if err := AllowCommands([]string{"git ls-remote"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := commandContext(context.Background(), "git", "version").CombinedOutput(); err == nil {
+		t.Fatal("expected git version not to be allowed")
+	}
+
+	dec := json.NewDecoder(&buf)
+	var ran, denied CommandRecord
+	if err := dec.Decode(&ran); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&denied); err != nil {
+		t.Fatal(err)
+	}
+	if ran.Args[1] != "version" || ran.ExitCode != 0 || ran.CombinedOutputBytes == 0 || ran.Denied || ran.Error != "" {
+		t.Errorf("unexpected record of a command that ran: %+v", ran)
+	}
+	if !denied.Denied || denied.ExitCode != -1 || denied.Error == "" {
+		t.Errorf("unexpected record of a denied command: %+v", denied)
+	}
+	if dec.More() {
+		t.Error("expected two records")
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	var buf bytes.Buffer
+	LogCommands(&buf)
+	defer LogCommands(nil)
+
+	dir, err := ioutil.TempDir("", "gps-runcommand")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out, err := RunCommand(context.Background(), dir, []byte("dep\n"), "git", "hash-object", "--stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The blob hash of "dep\n".
+	if got := string(bytes.TrimSpace(out)); got != "7b5d7e4bc87ed4ca9e71891dc040b5993d628776" {
+		t.Errorf("unexpected output from git hash-object: %q", got)
+	}
+	if _, err := RunCommand(context.Background(), dir, nil, "git", "rev-parse", "HEAD"); err == nil {
+		t.Fatal("expected git rev-parse to fail outside a repository")
+	}
+
+	dec := json.NewDecoder(&buf)
+	var hashed, failed CommandRecord
+	if err := dec.Decode(&hashed); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&failed); err != nil {
+		t.Fatal(err)
+	}
+	if hashed.Dir != dir || hashed.ExitCode != 0 {
+		t.Errorf("unexpected record of git hash-object: %+v", hashed)
+	}
+	if failed.ExitCode <= 0 || failed.Error != "" {
+		t.Errorf("expected the exit code of the failed command to be recorded: %+v", failed)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The operations for which limits can be set with SetCommandLimits.
+const (
+	// OpClone is getting a repository for the first time: hg clone, bzr
+	// branch, svn checkout and every step of cloning a git repository.
+	OpClone = "clone"
+	// OpFetch is updating a repository from upstream: git fetch, hg pull, bzr
+	// pull and svn update.
+	OpFetch = "fetch"
+	// OpLsRemote is listing the refs of a remote git repository.
+	OpLsRemote = "ls-remote"
+	// OpCheckout is checking out or exporting a revision of a repository: git
+	// checkout, git read-tree, git checkout-index, hg update, bzr update and
+	// svn update -r.
+	OpCheckout = "checkout"
+)
+
+// CommandOps are the operations for which limits can be set.
+var CommandOps = []string{OpClone, OpFetch, OpLsRemote, OpCheckout}
+
+// CommandLimits bounds the VCS commands run for an operation.
+type CommandLimits struct {
+	// Timeout, if positive, is the time after which a command is interrupted,
+	// and killed if it does not then exit.
+	Timeout time.Duration
+	// Retries is the number of times a command that fails, or times out, is
+	// run again.
+	Retries int
+}
+
+// commandLimits maps operations to their limits.
+var commandLimits map[string]CommandLimits
+
+// SetCommandLimits sets the limits of the VCS commands run for the operations
+// in limits, which must be among CommandOps. Commands run for operations
+// without limits are neither interrupted nor retried.
+//
+// SetCommandLimits must be called before any SourceManager is created.
+func SetCommandLimits(limits map[string]CommandLimits) error {
+	for op, l := range limits {
+		var known bool
+		for _, o := range CommandOps {
+			known = known || o == op
+		}
+		if !known {
+			return errors.Errorf("unknown operation %q, expected one of %s", op, strings.Join(CommandOps, ", "))
+		}
+		if l.Retries < 0 {
+			return errors.Errorf("negative retry count %d for %s", l.Retries, op)
+		}
+	}
+	commandLimits = limits
+	return nil
+}
+
+// commandOp returns the operation that the VCS command args is run for, or ""
+// if it is none of CommandOps.
+func commandOp(args []string) string {
+	var sub string
+	var rest []string
+	for i, arg := range args[1:] {
+		if !strings.HasPrefix(arg, "-") {
+			sub, rest = arg, args[i+2:]
+			break
+		}
+	}
+
+	switch toolName(args[0]) + " " + sub {
+	case "git clone", "hg clone", "bzr branch", "svn checkout":
+		return OpClone
+	case "git fetch", "hg pull", "bzr pull":
+		return OpFetch
+	case "git ls-remote":
+		return OpLsRemote
+	case "git checkout", "git read-tree", "git checkout-index", "hg update", "bzr update":
+		return OpCheckout
+	case "svn update":
+		for _, arg := range rest {
+			if arg == "-r" {
+				return OpCheckout
+			}
+		}
+		return OpFetch
+	}
+	return ""
+}
+
+// commandTimeoutError is returned for a command that was interrupted because
+// it ran for longer than the timeout of its operation.
+type commandTimeoutError struct {
+	args    []string
+	timeout time.Duration
+}
+
+func (e *commandTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s, and was killed", strings.Join(e.args, " "), e.timeout)
+}
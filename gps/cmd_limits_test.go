@@ -0,0 +1,109 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandOp(t *testing.T) {
+	for want, cmds := range map[string][][]string{
+		OpClone:    {{"git", "clone", "--recursive", "-v", "u", "p"}, {"/usr/bin/hg", "clone", "u", "p"}, {"bzr", "branch", "u", "p"}, {"svn", "checkout", "u", "p"}},
+		OpFetch:    {{"git", "fetch", "--tags", "--prune", "origin"}, {"hg", "pull"}, {"bzr", "pull"}, {"svn", "update"}},
+		OpLsRemote: {{"git", "ls-remote", "u"}},
+		OpCheckout: {{"git", "checkout", "v"}, {"git", "read-tree", "v"}, {"git", "checkout-index", "-a"}, {"hg", "update", "v"}, {"bzr", "update", "-r", "v"}, {"svn", "update", "-r", "v"}},
+		"":         {{"git", "log", "-1"}, {"git", "--version"}, {"svn", "info", "-r", "v"}},
+	} {
+		for _, args := range cmds {
+			if got := commandOp(args); got != want {
+				t.Errorf("commandOp(%q) = %q, want %q", args, got, want)
+			}
+		}
+	}
+}
+
+func TestSetCommandLimits(t *testing.T) {
+	defer SetCommandLimits(nil)
+
+	if err := SetCommandLimits(map[string]CommandLimits{"push": {Retries: 1}}); err == nil {
+		t.Error("expected an error for an unknown operation")
+	}
+	if err := SetCommandLimits(map[string]CommandLimits{OpFetch: {Retries: -1}}); err == nil {
+		t.Error("expected an error for a negative retry count")
+	}
+}
+
+func TestCommandLimits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "cmdlimits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The fake git hangs on ls-remote, and fails the first fetch it is asked
+	// for, counting fetches in a file.
+	git := filepath.Join(dir, "git")
+	script := `#!/bin/sh
+case "$1" in
+ls-remote) exec sleep 10 ;;
+fetch) echo x >> fetches; [ $(wc -l < fetches) -gt 1 ] ;;
+esac
+`
+	if err := ioutil.WriteFile(git, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(path string) {
+		os.Setenv("PATH", path)
+		pinnedTools, hermeticTools = nil, false
+	}(os.Getenv("PATH"))
+	if err := ConfigureTools([]VCSTool{{Name: "git", Path: git}}, false); err != nil {
+		t.Fatal(err)
+	}
+	defer SetCommandLimits(nil)
+
+	ctx := context.Background()
+	fetch := func() error {
+		c := commandContext(ctx, "git", "fetch")
+		c.SetDir(dir)
+		_, err := c.CombinedOutput()
+		return err
+	}
+
+	if err := fetch(); err == nil {
+		t.Fatal("expected the first fetch to fail")
+	}
+	if err := SetCommandLimits(map[string]CommandLimits{
+		OpFetch:    {Retries: 1},
+		OpLsRemote: {Timeout: 100 * time.Millisecond, Retries: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, "fetches"))
+	if err := fetch(); err != nil {
+		t.Errorf("expected the fetch to succeed when retried: %v", err)
+	}
+
+	start := time.Now()
+	_, err = commandContext(ctx, "git", "ls-remote", "https://example.com/foo").CombinedOutput()
+	if _, ok := err.(*commandTimeoutError); !ok {
+		t.Fatalf("expected ls-remote to time out, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "ls-remote https://example.com/foo timed out after 100ms") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected ls-remote to be interrupted twice within 5s, took %s", d)
+	}
+}
@@ -21,10 +21,17 @@ type cmd struct {
 	// ctx is provided by the caller; SIGINT is sent when it is cancelled.
 	ctx context.Context
 	Cmd *exec.Cmd
+	// op, if set, is the operation the command is run for, in place of the
+	// one commandOp would find.
+	op string
+	// stdin, if not nil, is given to each attempt at the command as its
+	// standard input.
+	stdin []byte
 }
 
 func commandContext(ctx context.Context, name string, arg ...string) cmd {
-	c := exec.Command(name, arg...)
+	c := exec.Command(toolPath(name), arg...)
+	c.Env = toolEnv(name)
 
 	// Force subprocesses into their own process group, rather than being in the
 	// same process group as the dep process. Because Ctrl-C sent from a
@@ -39,10 +46,18 @@ func commandContext(ctx context.Context, name string, arg ...string) cmd {
 	return cmd{ctx: ctx, Cmd: c}
 }
 
-// CombinedOutput is like (*os/exec.Cmd).CombinedOutput except that it
+// withContext returns a copy of c, which has not been started, that is
+// interrupted when ctx is done.
+func (c cmd) withContext(ctx context.Context) cmd {
+	nc := exec.Command(c.Cmd.Args[0], c.Cmd.Args[1:]...)
+	nc.Dir, nc.Env, nc.SysProcAttr = c.Cmd.Dir, c.Cmd.Env, c.Cmd.SysProcAttr
+	return cmd{ctx: ctx, Cmd: nc, op: c.op, stdin: c.stdin}
+}
+
+// combinedOutput is like (*os/exec.Cmd).CombinedOutput except that it
 // terminates subprocesses gently (via os.Interrupt), but resorts to Kill if
 // the subprocess fails to exit after 1 minute.
-func (c cmd) CombinedOutput() ([]byte, error) {
+func (c cmd) combinedOutput() ([]byte, error) {
 	// Adapted from (*os/exec.Cmd).CombinedOutput
 	if c.Cmd.Stdout != nil {
 		return nil, errors.New("exec: Stdout already set")
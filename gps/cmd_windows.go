@@ -10,9 +10,26 @@ import (
 )
 
 type cmd struct {
+	ctx context.Context
 	*exec.Cmd
+	op    string
+	stdin []byte
 }
 
 func commandContext(ctx context.Context, name string, arg ...string) cmd {
-	return cmd{Cmd: exec.CommandContext(ctx, name, arg...)}
+	c := exec.CommandContext(ctx, toolPath(name), arg...)
+	c.Env = toolEnv(name)
+	return cmd{ctx: ctx, Cmd: c}
+}
+
+// withContext returns a copy of c, which has not been started, that is killed
+// when ctx is done.
+func (c cmd) withContext(ctx context.Context) cmd {
+	nc := exec.CommandContext(ctx, c.Cmd.Args[0], c.Cmd.Args[1:]...)
+	nc.Dir, nc.Env = c.Cmd.Dir, c.Cmd.Env
+	return cmd{ctx: ctx, Cmd: nc, op: c.op, stdin: c.stdin}
+}
+
+func (c cmd) combinedOutput() ([]byte, error) {
+	return c.Cmd.CombinedOutput()
 }
@@ -338,6 +338,7 @@ func pcSliceToMap(l []ProjectConstraint, r ...[]ProjectConstraint) ProjectConstr
 	for _, pc := range l {
 		final[pc.Ident.ProjectRoot] = ProjectProperties{
 			Source:     pc.Ident.Source,
+			RootDir:    pc.Ident.RootDir,
 			Constraint: pc.Constraint,
 		}
 	}
@@ -353,6 +354,7 @@ func pcSliceToMap(l []ProjectConstraint, r ...[]ProjectConstraint) ProjectConstr
 			} else {
 				final[pc.Ident.ProjectRoot] = ProjectProperties{
 					Source:     pc.Ident.Source,
+					RootDir:    pc.Ident.RootDir,
 					Constraint: pc.Constraint,
 				}
 			}
@@ -389,6 +391,7 @@ func (m ProjectConstraints) override(pr ProjectRoot, pp ProjectProperties) worki
 		Ident: ProjectIdentifier{
 			ProjectRoot: pr,
 			Source:      pp.Source,
+			RootDir:     pp.RootDir,
 		},
 		Constraint: pp.Constraint,
 	}
@@ -414,6 +417,10 @@ func (m ProjectConstraints) override(pr ProjectRoot, pp ProjectProperties) worki
 			wc.Ident.Source = opp.Source
 			wc.overrNet = true
 		}
+		if opp.RootDir != "" {
+			wc.Ident.RootDir = opp.RootDir
+			wc.overrNet = true
+		}
 	}
 
 	return wc
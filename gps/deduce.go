@@ -848,7 +848,7 @@ func doFetchMetadata(ctx context.Context, scheme, path string) (io.ReadCloser, e
 			return nil, errors.Wrapf(err, "unable to build HTTP request for URL %q", url)
 		}
 
-		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		resp, err := (&http.Client{Transport: httpTransport}).Do(req.WithContext(ctx))
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed HTTP request to URL %q", url)
 		}
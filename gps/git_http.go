@@ -0,0 +1,222 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/vcs"
+	"github.com/pkg/errors"
+)
+
+// pureGitHosts are the hosts whose git repositories are listed without running
+// git. "*" stands for every host.
+var pureGitHosts []string
+
+// UsePureGit makes gps list the refs of git repositories served over http or
+// https from any of hosts by speaking the git smart HTTP protocol itself,
+// rather than running git ls-remote. The host "*" matches every host.
+//
+// Only listing versions is done without git. gps has no implementation of the
+// git fetch protocol or of git objects, so cloning, fetching and exporting a
+// revision still run the git binary, and fail with errNoGit if there is none;
+// solving and writing vendor thus need git. As version lists are all a
+// read-only SourceManager needs of upstream, it can serve such sources in
+// containers without a git binary.
+//
+// If a server does not speak the smart protocol, git is run after all.
+//
+// UsePureGit must be called before any SourceManager is created.
+func UsePureGit(hosts []string) {
+	pureGitHosts = hosts
+}
+
+// usePureGit reports whether the refs of the git repository at u are to be
+// listed without running git.
+func usePureGit(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	for _, h := range pureGitHosts {
+		if h == "*" || strings.EqualFold(h, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitHTTPTimeout bounds the time taken to list the refs of a repository over
+// http.
+const gitHTTPTimeout = 2 * time.Minute
+
+var gitHTTPClient = &http.Client{Timeout: gitHTTPTimeout}
+
+// errNotSmartHTTP is returned by lsRemoteHTTP when the server does not speak
+// the git smart HTTP protocol.
+var errNotSmartHTTP = errors.New("server does not speak the git smart HTTP protocol")
+
+// lsRemoteHTTP lists the refs advertised by the git repository at u, as git
+// ls-remote would: one "<hash>\t<ref>" line per ref, with HEAD first if the
+// server advertises it.
+func lsRemoteHTTP(ctx context.Context, u *url.URL) ([]byte, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(u.String(), "/")+"/info/refs?service=git-upload-pack", nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", "git/dep")
+	req.Header.Set("Git-Protocol", "version=0")
+
+	resp, err := gitHTTPClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list refs of %s", ufmt(u))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to list refs of %s: %s", ufmt(u), resp.Status)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-git-upload-pack-advertisement") {
+		return nil, errNotSmartHTTP
+	}
+
+	return parseRefAdvertisement(bufio.NewReader(resp.Body))
+}
+
+// parseRefAdvertisement parses the refs advertised by a smart HTTP server in
+// reply to a request for info/refs, formatting them as git ls-remote does.
+func parseRefAdvertisement(r io.Reader) ([]byte, error) {
+	line, err := readPktLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if string(bytes.TrimSuffix(line, []byte("\n"))) != "# service=git-upload-pack" {
+		return nil, errNotSmartHTTP
+	}
+	if line, err = readPktLine(r); err != nil {
+		return nil, err
+	} else if line != nil {
+		return nil, errors.New("expected a flush packet after the service announcement")
+	}
+
+	var buf bytes.Buffer
+	for first := true; ; first = false {
+		line, err := readPktLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line == nil {
+			return buf.Bytes(), nil
+		}
+
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		if first {
+			// Capabilities follow the first ref, after a NUL.
+			if nul := bytes.IndexByte(line, 0); nul >= 0 {
+				line = line[:nul]
+			}
+		}
+		sp := bytes.IndexByte(line, ' ')
+		if sp < 0 {
+			return nil, errors.Errorf("malformed ref advertisement %q", line)
+		}
+		if string(line[sp+1:]) == "capabilities^{}" {
+			// An empty repository, which has no refs.
+			continue
+		}
+		buf.Write(line[:sp])
+		buf.WriteByte('\t')
+		buf.Write(line[sp+1:])
+		buf.WriteByte('\n')
+	}
+}
+
+// readPktLine reads a pkt-line from r, returning nil for a flush packet.
+func readPktLine(r io.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, errors.Wrap(err, "failed to read pkt-line")
+	}
+	n, err := strconv.ParseUint(string(hdr[:]), 16, 16)
+	if err != nil {
+		return nil, errors.Errorf("malformed pkt-line length %q", hdr)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if n < 4 {
+		return nil, errors.Errorf("unexpected pkt-line length %d", n)
+	}
+	line := make([]byte, n-4)
+	if _, err := io.ReadFull(r, line); err != nil {
+		return nil, errors.Wrap(err, "failed to read pkt-line")
+	}
+	return line, nil
+}
+
+// errNoGit is returned by the operations of a refsOnlyRepo.
+var errNoGit = errors.New("git is not installed, so only the versions of the repository can be listed; fetching or exporting it requires git")
+
+// refsOnlyRepo is the ctxRepo of a git source used where no git binary can be
+// run. Its refs are listed with lsRemoteHTTP by gitSource, and it never has a
+// local copy: nothing is fetched without git, so every operation that would
+// need a local copy fails with errNoGit.
+type refsOnlyRepo struct {
+	remote, local string
+}
+
+func (r *refsOnlyRepo) Vcs() vcs.Type               { return vcs.Git }
+func (r *refsOnlyRepo) Remote() string              { return r.remote }
+func (r *refsOnlyRepo) LocalPath() string           { return r.local }
+func (r *refsOnlyRepo) CheckLocal() bool            { return false }
+func (r *refsOnlyRepo) IsReference(string) bool     { return false }
+func (r *refsOnlyRepo) IsDirty() bool               { return false }
+func (r *refsOnlyRepo) Get() error                  { return errNoGit }
+func (r *refsOnlyRepo) Init() error                 { return errNoGit }
+func (r *refsOnlyRepo) Update() error               { return errNoGit }
+func (r *refsOnlyRepo) UpdateVersion(string) error  { return errNoGit }
+func (r *refsOnlyRepo) Version() (string, error)    { return "", errNoGit }
+func (r *refsOnlyRepo) Current() (string, error)    { return "", errNoGit }
+func (r *refsOnlyRepo) Date() (time.Time, error)    { return time.Time{}, errNoGit }
+func (r *refsOnlyRepo) Branches() ([]string, error) { return nil, errNoGit }
+func (r *refsOnlyRepo) Tags() ([]string, error)     { return nil, errNoGit }
+func (r *refsOnlyRepo) TagsFromCommit(string) ([]string, error) {
+	return nil, errNoGit
+}
+func (r *refsOnlyRepo) CommitInfo(string) (*vcs.CommitInfo, error) {
+	return nil, errNoGit
+}
+func (r *refsOnlyRepo) RunFromDir(string, ...string) ([]byte, error) {
+	return nil, errNoGit
+}
+func (r *refsOnlyRepo) ExportDir(string) error { return errNoGit }
+
+// CmdFromDir returns the command, which cannot be run without a git binary.
+func (r *refsOnlyRepo) CmdFromDir(cmd string, args ...string) *exec.Cmd {
+	c := exec.Command(cmd, args...)
+	c.Dir = r.local
+	return c
+}
+
+func (r *refsOnlyRepo) Ping() bool {
+	u, err := url.Parse(r.remote)
+	if err != nil {
+		return false
+	}
+	_, err = lsRemoteHTTP(context.TODO(), u)
+	return err == nil
+}
+
+func (r *refsOnlyRepo) get(context.Context) error                   { return errNoGit }
+func (r *refsOnlyRepo) fetch(context.Context) error                 { return errNoGit }
+func (r *refsOnlyRepo) updateVersion(context.Context, string) error { return errNoGit }
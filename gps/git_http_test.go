@@ -0,0 +1,160 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pktLines formats lines as pkt-lines, with an empty line standing for a
+// flush packet.
+func pktLines(lines ...string) string {
+	var b strings.Builder
+	for _, l := range lines {
+		if l == "" {
+			b.WriteString("0000")
+		} else {
+			fmt.Fprintf(&b, "%04x%s", len(l)+4, l)
+		}
+	}
+	return b.String()
+}
+
+func TestParseRefAdvertisement(t *testing.T) {
+	const (
+		head = "30605f6ac35fcb075ad0bfa9296f90a7d891523e"
+		v1   = "9c1d8ef3f3fbcbca57ccaa8ee8a4c0fab23a1cd6"
+		v1c  = "c0a3b3b2e0ef5d1c2d0cd3f0c2f4f1df4cc1e0a9"
+	)
+	adv := pktLines(
+		"# service=git-upload-pack\n",
+		"",
+		head+" HEAD\x00multi_ack symref=HEAD:refs/heads/master agent=git/2.17.1\n",
+		head+" refs/heads/master\n",
+		v1+" refs/tags/v1.0.0\n",
+		v1c+" refs/tags/v1.0.0^{}\n",
+		"",
+	)
+	out, err := parseRefAdvertisement(strings.NewReader(adv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := head + "\tHEAD\n" + head + "\trefs/heads/master\n" + v1 + "\trefs/tags/v1.0.0\n" + v1c + "\trefs/tags/v1.0.0^{}\n"
+	if string(out) != want {
+		t.Errorf("unexpected refs:\n%s\nwant:\n%s", out, want)
+	}
+
+	empty := pktLines("# service=git-upload-pack\n", "", strings.Repeat("0", 40)+" capabilities^{}\x00agent=git/2.17.1\n", "")
+	if out, err := parseRefAdvertisement(strings.NewReader(empty)); err != nil || len(out) != 0 {
+		t.Errorf("expected no refs for an empty repository, got %q (%v)", out, err)
+	}
+
+	if _, err := parseRefAdvertisement(strings.NewReader(head + "\trefs/heads/master\n")); err == nil {
+		t.Error("expected an error for a dumb server's info/refs")
+	}
+	if _, err := parseRefAdvertisement(strings.NewReader(adv[:len(adv)-10])); err == nil {
+		t.Error("expected an error for a truncated advertisement")
+	}
+}
+
+func TestUsePureGit(t *testing.T) {
+	defer UsePureGit(nil)
+
+	UsePureGit([]string{"github.com"})
+	for in, want := range map[string]bool{
+		"https://github.com/golang/dep":      true,
+		"http://GitHub.com/golang/dep":       true,
+		"ssh://git@github.com/golang/dep":    false,
+		"https://bitbucket.org/golang/dep":   false,
+		"https://github.com:8443/golang/dep": true,
+	} {
+		if got := usePureGit(mkurl(in)); got != want {
+			t.Errorf("usePureGit(%s) = %v, want %v", in, got, want)
+		}
+	}
+
+	UsePureGit([]string{"*"})
+	if !usePureGit(mkurl("https://example.com/foo")) {
+		t.Error("expected * to match every host")
+	}
+}
+
+func TestGitlessSourceListVersions(t *testing.T) {
+	const rev = "30605f6ac35fcb075ad0bfa9296f90a7d891523e"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foo/bar/info/refs" || r.URL.Query().Get("service") != "git-upload-pack" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		fmt.Fprint(w, pktLines(
+			"# service=git-upload-pack\n",
+			"",
+			rev+" HEAD\x00symref=HEAD:refs/heads/master\n",
+			rev+" refs/heads/master\n",
+			rev+" refs/tags/v1.0.0\n",
+			"",
+		))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "gitless")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(path string) {
+		os.Setenv("PATH", path)
+	}(os.Getenv("PATH"))
+	// Make sure no git binary can be found.
+	os.Setenv("PATH", dir)
+	UsePureGit([]string{"127.0.0.1"})
+	defer UsePureGit(nil)
+
+	src, err := maybeGitSource{url: mkurl(ts.URL + "/foo/bar")}.try(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := src.(*gitSource)
+	if _, ok := gs.repo.(*refsOnlyRepo); !ok {
+		t.Fatalf("expected a refsOnlyRepo without a git binary, got %T", gs.repo)
+	}
+	if !gs.repo.Ping() {
+		t.Error("expected the repository to be reachable")
+	}
+	if err := gs.initLocal(context.Background()); err == nil {
+		t.Error("expected cloning to fail without a git binary")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sources")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written to the cache, got %v", err)
+	}
+
+	vlist, err := gs.listVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	SortPairedForUpgrade(vlist)
+	want := []PairedVersion{
+		NewVersion("v1.0.0").Pair(Revision(rev)),
+		newDefaultBranch("master").Pair(Revision(rev)),
+	}
+	if len(vlist) != len(want) {
+		t.Fatalf("expected versions %s, got %s", want, vlist)
+	}
+	for i := range want {
+		if !vlist[i].identical(want[i]) {
+			t.Errorf("expected version %s, got %s", want[i], vlist[i])
+		}
+	}
+}
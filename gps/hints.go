@@ -0,0 +1,154 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SolveHints records candidate versions that the solver has rejected because
+// their manifest or packages could not be analyzed, as opposed to rejections
+// arising from constraints. Such rejections depend only on the immutable
+// revision and the analyzer, so a later solve may skip the candidates without
+// fetching and analyzing them again. Failures to retrieve a revision, such as
+// network errors, may not recur, and are never recorded.
+type SolveHints struct {
+	// AnalyzerName and AnalyzerVersion identify the analyzer the rejections
+	// were made with. Hints are only applied by the solver when they match
+	// the analyzer it is given.
+	AnalyzerName    string
+	AnalyzerVersion int
+	Rejected        []HintedRejection
+}
+
+// HintedRejection is a single candidate version rejected by the solver.
+type HintedRejection struct {
+	ProjectRoot ProjectRoot
+	Source      string
+	Version     string
+	Revision    Revision
+	Reason      string
+}
+
+// NewSolveHints returns empty SolveHints for the given analyzer.
+func NewSolveHints(an ProjectAnalyzerInfo) *SolveHints {
+	return &SolveHints{AnalyzerName: an.Name, AnalyzerVersion: an.Version}
+}
+
+// Matches reports whether h was recorded with the given analyzer.
+func (h *SolveHints) Matches(an ProjectAnalyzerInfo) bool {
+	return h.AnalyzerName == an.Name && h.AnalyzerVersion == an.Version
+}
+
+// hintKey returns the version and revision by which a rejection of v is
+// recorded, or false if v is not paired with a revision.
+func hintKey(v Version) (string, Revision, bool) {
+	switch tv := v.(type) {
+	case PairedVersion:
+		return tv.Unpair().String(), tv.Revision(), true
+	case Revision:
+		return string(tv), tv, true
+	}
+	return "", "", false
+}
+
+// rejected returns the recorded rejection of version v of the project
+// identified by id, if there is one.
+func (h *SolveHints) rejected(id ProjectIdentifier, v Version) (HintedRejection, bool) {
+	if h == nil {
+		return HintedRejection{}, false
+	}
+	ver, rev, ok := hintKey(v)
+	if !ok {
+		return HintedRejection{}, false
+	}
+	for _, r := range h.Rejected {
+		if r.ProjectRoot == id.ProjectRoot && r.Source == id.Source && r.Version == ver && r.Revision == rev {
+			return r, true
+		}
+	}
+	return HintedRejection{}, false
+}
+
+// reject records that version v of the project identified by id was rejected
+// because of err.
+func (h *SolveHints) reject(id ProjectIdentifier, v Version, err error) {
+	if h == nil {
+		return
+	}
+	if _, has := h.rejected(id, v); has {
+		return
+	}
+	ver, rev, ok := hintKey(v)
+	if !ok {
+		return
+	}
+	h.Rejected = append(h.Rejected, HintedRejection{
+		ProjectRoot: id.ProjectRoot,
+		Source:      id.Source,
+		Version:     ver,
+		Revision:    rev,
+		Reason:      err.Error(),
+	})
+	sort.SliceStable(h.Rejected, func(i, j int) bool {
+		return h.Rejected[i].ProjectRoot < h.Rejected[j].ProjectRoot
+	})
+}
+
+// hintUnreadable records in the solver's hints that atom a was rejected
+// because its manifest or packages could not be analyzed. Any other error,
+// such as a failure to fetch the source, is not recorded.
+func (s *solver) hintUnreadable(a atom, err error) {
+	if isAnalysisError(err) {
+		s.hints.reject(a.id, a.v, err)
+	}
+}
+
+// analysisError indicates that the manifest or packages of a revision could
+// not be analyzed once it was retrieved, which does not change as long as the
+// revision and the analyzer do not.
+type analysisError struct {
+	err error
+}
+
+func (e analysisError) Error() string {
+	return e.err.Error()
+}
+
+func (e analysisError) Cause() error {
+	return e.err
+}
+
+// isAnalysisError reports whether err, or any error it wraps, is an
+// analysisError.
+func isAnalysisError(err error) bool {
+	for err != nil {
+		if _, ok := err.(analysisError); ok {
+			return true
+		}
+		c, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = c.Cause()
+	}
+	return false
+}
+
+// hintedRejectionFailure indicates that an atom was not considered because a
+// previous solve rejected it.
+type hintedRejectionFailure struct {
+	goal atom
+	r    HintedRejection
+}
+
+func (e *hintedRejectionFailure) Error() string {
+	return fmt.Sprintf("Could not introduce %s, as a previous solve could not use it: %s", a2vs(e.goal), e.r.Reason)
+}
+
+func (e *hintedRejectionFailure) traceString() string {
+	return fmt.Sprintf("%s previously rejected: %s", a2vs(e.goal), e.r.Reason)
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/dep/gps/pkgtree"
+)
+
+func TestSolveHintsRejected(t *testing.T) {
+	h := NewSolveHints(naiveAnalyzer{}.Info())
+	id := mkPI("github.com/foo/bar")
+	h.reject(id, NewVersion("v1.0.1").Pair("abc123"), errors.New("no Go files"))
+	h.reject(id, NewVersion("v1.0.1").Pair("abc123"), errors.New("duplicate"))
+	h.reject(id, NewBranch("master"), errors.New("unpaired"))
+
+	if len(h.Rejected) != 1 {
+		t.Fatalf("expected one recorded rejection, got %v", h.Rejected)
+	}
+	if r, ok := h.rejected(id, NewVersion("v1.0.1").Pair("abc123")); !ok || r.Reason != "no Go files" {
+		t.Errorf("expected v1.0.1 to be rejected for having no Go files, got %v, %v", r, ok)
+	}
+
+	cases := []Version{
+		NewVersion("v1.0.1").Pair("def456"),
+		NewVersion("v1.0.0").Pair("abc123"),
+		NewVersion("v1.0.1"),
+		Revision("def456"),
+	}
+	for _, v := range cases {
+		if _, ok := h.rejected(id, v); ok {
+			t.Errorf("expected %v not to be rejected", v)
+		}
+	}
+	if _, ok := h.rejected(mkPI("github.com/other"), NewVersion("v1.0.1").Pair("abc123")); ok {
+		t.Error("expected versions of other projects not to be rejected")
+	}
+
+	var nilHints *SolveHints
+	nilHints.reject(id, Revision("abc123"), errors.New("ignored"))
+	if _, ok := nilHints.rejected(id, Revision("abc123")); ok {
+		t.Error("expected nil hints to reject nothing")
+	}
+}
+
+// failingPackagesSM fails to list the packages of one version of a project,
+// with err.
+type failingPackagesSM struct {
+	*depspecSourceManager
+	root ProjectRoot
+	v    string
+	err  error
+}
+
+func (sm *failingPackagesSM) ListPackages(id ProjectIdentifier, v Version) (pkgtree.PackageTree, error) {
+	if id.ProjectRoot == sm.root && v.String() == sm.v {
+		return pkgtree.PackageTree{}, sm.err
+	}
+	return sm.depspecSourceManager.ListPackages(id, v)
+}
+
+func TestSolveRecordsAndSkipsHintedVersions(t *testing.T) {
+	fix := basicFixture{
+		ds: []depspec{
+			mkDepspec("root 0.0.0", "foo *"),
+			mkDepspec("foo 1.0.0 foorev0"),
+			mkDepspec("foo 1.0.1 foorev1"),
+		},
+		r: mksolution(
+			"foo 1.0.0 foorev0",
+		),
+	}
+	params := SolveParameters{
+		RootDir:         string(fix.ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		ProjectAnalyzer: naiveAnalyzer{},
+		Hints:           NewSolveHints(naiveAnalyzer{}.Info()),
+	}
+
+	sm := &failingPackagesSM{
+		depspecSourceManager: newdepspecSM(fix.ds, nil),
+		root:                 "foo",
+		v:                    "1.0.1",
+		err:                  analysisError{err: errors.New("could not parse packages")},
+	}
+	res, err := fixSolve(params, sm, t)
+	fixtureSolveSimpleChecks(fix, res, err, t)
+	if len(params.Hints.Rejected) != 1 || params.Hints.Rejected[0].Revision != "foorev1" {
+		t.Fatalf("expected the solve to record foo 1.0.1 as rejected, got %v", params.Hints.Rejected)
+	}
+
+	// With the hints from the first solve, a second one must not try 1.0.1,
+	// even though it would now succeed.
+	res, err = fixSolve(params, newdepspecSM(fix.ds, nil), t)
+	fixtureSolveSimpleChecks(fix, res, err, t)
+}
+
+func TestSolveDoesNotHintFetchFailures(t *testing.T) {
+	fix := basicFixture{
+		ds: []depspec{
+			mkDepspec("root 0.0.0", "foo *"),
+			mkDepspec("foo 1.0.0 foorev0"),
+			mkDepspec("foo 1.0.1 foorev1"),
+		},
+		r: mksolution(
+			"foo 1.0.0 foorev0",
+		),
+	}
+	params := SolveParameters{
+		RootDir:         string(fix.ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		ProjectAnalyzer: naiveAnalyzer{},
+		Hints:           NewSolveHints(naiveAnalyzer{}.Info()),
+	}
+
+	// A failure to fetch the revision may not recur, so the version is not
+	// rejected for later solves.
+	sm := &failingPackagesSM{
+		depspecSourceManager: newdepspecSM(fix.ds, nil),
+		root:                 "foo",
+		v:                    "1.0.1",
+		err:                  errors.New("connection reset by peer"),
+	}
+	res, err := fixSolve(params, sm, t)
+	fixtureSolveSimpleChecks(fix, res, err, t)
+	if len(params.Hints.Rejected) != 0 {
+		t.Fatalf("expected no rejection to be recorded for a fetch failure, got %v", params.Hints.Rejected)
+	}
+}
+
+func TestSolveDiscardsHintsFromOtherAnalyzers(t *testing.T) {
+	fix := basicFixture{
+		ds: []depspec{
+			mkDepspec("root 0.0.0", "foo *"),
+			mkDepspec("foo 1.0.0 foorev0"),
+			mkDepspec("foo 1.0.1 foorev1"),
+		},
+		r: mksolution(
+			"foo 1.0.1 foorev1",
+		),
+	}
+	hints := &SolveHints{
+		AnalyzerName:    "other",
+		AnalyzerVersion: 1,
+		Rejected:        []HintedRejection{{ProjectRoot: "foo", Version: "1.0.1", Revision: "foorev1"}},
+	}
+	params := SolveParameters{
+		RootDir:         string(fix.ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		ProjectAnalyzer: naiveAnalyzer{},
+		Hints:           hints,
+	}
+
+	res, err := fixSolve(params, newdepspecSM(fix.ds, nil), t)
+	fixtureSolveSimpleChecks(fix, res, err, t)
+	if !hints.Matches(naiveAnalyzer{}.Info()) || len(hints.Rejected) != 0 {
+		t.Errorf("expected hints from another analyzer to be discarded, got %+v", hints)
+	}
+}
@@ -75,12 +75,28 @@ type ProjectRoot string
 //
 // If Source is not explicitly set, gps will derive the network address from
 // the ImportRoot using a similar algorithm to that utilized by `go get`.
+//
+// Finally, ProjectIdentifiers can carry a RootDir, the slash-separated path of
+// the directory in the repository that holds the ProjectRoot, for upstreams
+// that keep their Go code in a subdirectory. The packages of the project are
+// then listed, analyzed and exported from that directory; nothing outside it
+// is part of the project, save for the legal files at the root of the
+// repository, which are exported along with it. RootDir takes part in
+// comparisons as Source does: identifiers that differ only in RootDir name
+// different projects, to == as well as to the solver. It must be clean, as
+// CleanRootDir returns it.
+//
+// RootDir was added to ProjectIdentifier and ProjectProperties after they were
+// first published. Code that builds them with unkeyed fields, or that relies
+// on identifiers comparing equal whatever directory they name, has to be
+// updated.
 type ProjectIdentifier struct {
 	ProjectRoot ProjectRoot
 	Source      string
+	RootDir     string
 }
 
-// Less compares by ProjectRoot then normalized Source.
+// Less compares by ProjectRoot, then normalized Source, then RootDir.
 func (i ProjectIdentifier) Less(j ProjectIdentifier) bool {
 	if i.ProjectRoot < j.ProjectRoot {
 		return true
@@ -88,11 +104,14 @@ func (i ProjectIdentifier) Less(j ProjectIdentifier) bool {
 	if j.ProjectRoot < i.ProjectRoot {
 		return false
 	}
-	return i.normalizedSource() < j.normalizedSource()
+	if is, js := i.normalizedSource(), j.normalizedSource(); is != js {
+		return is < js
+	}
+	return i.RootDir < j.RootDir
 }
 
 func (i ProjectIdentifier) eq(j ProjectIdentifier) bool {
-	if i.ProjectRoot != j.ProjectRoot {
+	if i.ProjectRoot != j.ProjectRoot || i.RootDir != j.RootDir {
 		return false
 	}
 	if i.Source == j.Source {
@@ -116,6 +135,8 @@ func (i ProjectIdentifier) eq(j ProjectIdentifier) bool {
 // 2. The LEFT (the receiver) Source is non-empty, and the right
 // Source is empty.
 //
+// and the same holds for their RootDirs.
+//
 // *This is asymmetry in this binary relation is intentional.* It facilitates
 // the case where we allow for a ProjectIdentifier with an explicit Source
 // to match one without.
@@ -123,6 +144,9 @@ func (i ProjectIdentifier) equiv(j ProjectIdentifier) bool {
 	if i.ProjectRoot != j.ProjectRoot {
 		return false
 	}
+	if i.RootDir != j.RootDir && j.RootDir != "" {
+		return false
+	}
 	if i.Source == j.Source {
 		return true
 	}
@@ -142,6 +166,9 @@ func (i ProjectIdentifier) normalizedSource() string {
 }
 
 func (i ProjectIdentifier) String() string {
+	if i.RootDir != "" {
+		return fmt.Sprintf("%s (from %s, in %s)", i.ProjectRoot, i.normalizedSource(), i.RootDir)
+	}
 	if i.Source == "" || i.Source == string(i.ProjectRoot) {
 		return string(i.ProjectRoot)
 	}
@@ -165,6 +192,9 @@ func (i ProjectIdentifier) normalize() ProjectIdentifier {
 type ProjectProperties struct {
 	Source     string
 	Constraint Constraint
+	// RootDir is the RootDir of the ProjectIdentifier of the project; see
+	// there.
+	RootDir string
 }
 
 // bimodalIdentifiers are used to track work to be done in the unselected queue.
@@ -89,6 +89,7 @@ type ProjectProperties struct {
 	Root       string      `protobuf:"bytes,1,opt,name=root" json:"root,omitempty"`
 	Source     string      `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
 	Constraint *Constraint `protobuf:"bytes,3,opt,name=constraint" json:"constraint,omitempty"`
+	RootDir    string      `protobuf:"bytes,4,opt,name=rootDir" json:"rootDir,omitempty"`
 }
 
 func (m *ProjectProperties) Reset()                    { *m = ProjectProperties{} }
@@ -117,6 +118,13 @@ func (m *ProjectProperties) GetConstraint() *Constraint {
 	return nil
 }
 
+func (m *ProjectProperties) GetRootDir() string {
+	if m != nil {
+		return m.RootDir
+	}
+	return ""
+}
+
 // LockedProject is a serializable representation of gps.LockedProject.
 type LockedProject struct {
 	Root            string      `protobuf:"bytes,1,opt,name=root" json:"root,omitempty"`
@@ -124,6 +132,7 @@ type LockedProject struct {
 	UnpairedVersion *Constraint `protobuf:"bytes,3,opt,name=unpairedVersion" json:"unpairedVersion,omitempty"`
 	Revision        string      `protobuf:"bytes,4,opt,name=revision" json:"revision,omitempty"`
 	Packages        []string    `protobuf:"bytes,5,rep,name=packages" json:"packages,omitempty"`
+	RootDir         string      `protobuf:"bytes,6,opt,name=rootDir" json:"rootDir,omitempty"`
 }
 
 func (m *LockedProject) Reset()                    { *m = LockedProject{} }
@@ -166,6 +175,13 @@ func (m *LockedProject) GetPackages() []string {
 	return nil
 }
 
+func (m *LockedProject) GetRootDir() string {
+	if m != nil {
+		return m.RootDir
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Constraint)(nil), "pb.Constraint")
 	proto.RegisterType((*ProjectProperties)(nil), "pb.ProjectProperties")
@@ -176,24 +192,25 @@ func init() {
 func init() { proto.RegisterFile("source_cache.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 294 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x91, 0x4f, 0x4f, 0xc2, 0x40,
-	0x14, 0xc4, 0x5d, 0x28, 0x08, 0x0f, 0x41, 0x78, 0x1a, 0xd3, 0x78, 0x6a, 0x7a, 0x91, 0x53, 0x0f,
-	0x78, 0xf1, 0xac, 0x1e, 0x39, 0x90, 0x6a, 0xbc, 0x9a, 0xed, 0xf2, 0x94, 0x0a, 0x76, 0x37, 0xaf,
-	0xdb, 0x26, 0x7c, 0x14, 0x3f, 0x84, 0xdf, 0xd1, 0x74, 0x59, 0xf1, 0x4f, 0xe2, 0xc1, 0x5b, 0xa7,
-	0xf3, 0xcb, 0xce, 0xcc, 0x2e, 0x60, 0xa9, 0x2b, 0x56, 0xf4, 0xa8, 0xa4, 0x5a, 0x51, 0x62, 0x58,
-	0x5b, 0x8d, 0x2d, 0x93, 0xc5, 0x6f, 0x02, 0xe0, 0x46, 0x17, 0xa5, 0x65, 0x99, 0x17, 0x16, 0x2f,
-	0x20, 0xb0, 0x5b, 0x43, 0xa1, 0x88, 0xc4, 0x74, 0x34, 0x3b, 0x49, 0x4c, 0x96, 0x7c, 0xb9, 0xc9,
-	0xfd, 0xd6, 0x50, 0xea, 0x00, 0x3c, 0x85, 0x4e, 0x2d, 0x37, 0x15, 0x85, 0xad, 0x48, 0x4c, 0xfb,
-	0xe9, 0x4e, 0xc4, 0x73, 0x08, 0x1a, 0x06, 0x8f, 0xa0, 0x97, 0x52, 0x9d, 0x97, 0xb9, 0x2e, 0xc6,
-	0x07, 0x08, 0xd0, 0xbd, 0x66, 0x59, 0xa8, 0xd5, 0x58, 0xe0, 0x04, 0x86, 0xb7, 0xf4, 0x24, 0xab,
-	0x8d, 0xf5, 0xbf, 0x5a, 0x38, 0x80, 0xc3, 0x07, 0x62, 0xc7, 0xb6, 0x1b, 0xf6, 0x8e, 0x5e, 0x6b,
-	0xe2, 0x71, 0x10, 0x6b, 0x98, 0x2c, 0x58, 0xbf, 0x90, 0xb2, 0x0b, 0xd6, 0x86, 0xd8, 0xe6, 0x54,
-	0x22, 0x42, 0xc0, 0x5a, 0x5b, 0xd7, 0xb0, 0x9f, 0xba, 0x6f, 0x3c, 0x83, 0xee, 0x6e, 0x9e, 0x6f,
-	0xe3, 0x15, 0x26, 0x00, 0x6a, 0xdf, 0x3e, 0x6c, 0x47, 0x62, 0x3a, 0x98, 0x8d, 0x7e, 0x6e, 0x4a,
-	0xbf, 0x11, 0xf1, 0xbb, 0x80, 0xe1, 0x5c, 0xab, 0x35, 0x2d, 0x7d, 0xee, 0xbf, 0xd2, 0xae, 0xe0,
-	0xb8, 0x2a, 0x8c, 0xcc, 0x99, 0x96, 0x7e, 0xcf, 0x1f, 0x91, 0xbf, 0x31, 0x3c, 0x87, 0x1e, 0xfb,
-	0xeb, 0x0a, 0x03, 0x77, 0xe6, 0x5e, 0x37, 0x9e, 0x91, 0x6a, 0x2d, 0x9f, 0xa9, 0x0c, 0x3b, 0x51,
-	0xbb, 0xf1, 0x3e, 0x75, 0xd6, 0x75, 0xef, 0x78, 0xf9, 0x11, 0x00, 0x00, 0xff, 0xff, 0xbd, 0x52,
-	0x77, 0xb3, 0xdd, 0x01, 0x00, 0x00,
+	// 308 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x91, 0xc1, 0x4e, 0xc2, 0x40,
+	0x10, 0x86, 0x5d, 0x28, 0x05, 0x06, 0x41, 0x18, 0x8d, 0x69, 0x3c, 0x35, 0xbd, 0xc8, 0xa9, 0x07,
+	0xbc, 0x78, 0x56, 0x8e, 0x1c, 0x48, 0x35, 0x5e, 0xcd, 0xb2, 0x8c, 0x52, 0xc1, 0xee, 0x66, 0xba,
+	0x25, 0xe1, 0x11, 0x7c, 0x04, 0x9f, 0xc8, 0xd7, 0x32, 0x2d, 0x0b, 0x82, 0x89, 0x07, 0x6f, 0xfb,
+	0xef, 0x7c, 0x99, 0x7c, 0xff, 0x2e, 0x60, 0xae, 0x0b, 0x56, 0xf4, 0xac, 0xa4, 0x5a, 0x50, 0x6c,
+	0x58, 0x5b, 0x8d, 0x35, 0x33, 0x8b, 0x3e, 0x05, 0xc0, 0xbd, 0xce, 0x72, 0xcb, 0x32, 0xcd, 0x2c,
+	0x5e, 0x83, 0x67, 0x37, 0x86, 0x02, 0x11, 0x8a, 0x61, 0x6f, 0x74, 0x1e, 0x9b, 0x59, 0xfc, 0x33,
+	0x8d, 0x1f, 0x37, 0x86, 0x92, 0x0a, 0xc0, 0x0b, 0x68, 0xac, 0xe5, 0xaa, 0xa0, 0xa0, 0x16, 0x8a,
+	0x61, 0x3b, 0xd9, 0x86, 0x68, 0x02, 0x5e, 0xc9, 0xe0, 0x29, 0xb4, 0x12, 0x5a, 0xa7, 0x79, 0xaa,
+	0xb3, 0xfe, 0x09, 0x02, 0xf8, 0x77, 0x2c, 0x33, 0xb5, 0xe8, 0x0b, 0x1c, 0x40, 0x77, 0x4c, 0x2f,
+	0xb2, 0x58, 0x59, 0x77, 0x55, 0xc3, 0x0e, 0x34, 0x9f, 0x88, 0x2b, 0xb6, 0x5e, 0xb2, 0x0f, 0xf4,
+	0xbe, 0x26, 0xee, 0x7b, 0xd1, 0x87, 0x80, 0xc1, 0x94, 0xf5, 0x1b, 0x29, 0x3b, 0x65, 0x6d, 0x88,
+	0x6d, 0x4a, 0x39, 0x22, 0x78, 0xac, 0xb5, 0xad, 0x14, 0xdb, 0x49, 0x75, 0xc6, 0x4b, 0xf0, 0xb7,
+	0xfd, 0x9c, 0x8e, 0x4b, 0x18, 0x03, 0xa8, 0xbd, 0x7e, 0x50, 0x0f, 0xc5, 0xb0, 0x33, 0xea, 0x1d,
+	0x97, 0x4a, 0x0e, 0x08, 0x0c, 0xa0, 0x59, 0xee, 0x1b, 0xa7, 0x1c, 0x78, 0xd5, 0xa2, 0x5d, 0x8c,
+	0xbe, 0x04, 0x74, 0x27, 0x5a, 0x2d, 0x69, 0xee, 0x8c, 0xfe, 0xe5, 0x71, 0x0b, 0x67, 0x45, 0x66,
+	0x64, 0xca, 0x34, 0x77, 0x55, 0xff, 0x90, 0xf9, 0x8d, 0xe1, 0x15, 0xb4, 0xd8, 0xbd, 0xa4, 0x53,
+	0xda, 0xe7, 0x72, 0x66, 0xa4, 0x5a, 0xca, 0x57, 0xca, 0x83, 0x46, 0x58, 0x2f, 0x67, 0xbb, 0x7c,
+	0xd8, 0xc4, 0x3f, 0x6a, 0x32, 0xf3, 0xab, 0xcf, 0xbf, 0xf9, 0x1e, 0x00, 0x03, 0xa4, 0x78, 0x0e,
+	0x12, 0x02, 0x00, 0x00,
 }
@@ -120,7 +120,7 @@ func prepManifest(m Manifest) SimpleManifest {
 		// normalize between these two by omitting such instances entirely, as
 		// it negates some possibility for false mismatches in input hashing.
 		if d.Constraint == nil {
-			if d.Source == "" {
+			if d.Source == "" && d.RootDir == "" {
 				continue
 			}
 			d.Constraint = anyConstraint{}
@@ -51,6 +51,17 @@ func (m maybeGitSource) try(ctx context.Context, cachedir string) (source, error
 	ustr := m.url.String()
 	path := sourceCachePath(cachedir, ustr)
 
+	if usePureGit(m.url) && !toolInstalled("git") {
+		return &gitSource{
+			baseVCSSource: baseVCSSource{
+				repo: &refsOnlyRepo{remote: ustr, local: path},
+			},
+		}, nil
+	}
+	if err := useTool("git"); err != nil {
+		return nil, err
+	}
+
 	r, err := vcs.NewGitRepo(ustr, path)
 	if err != nil {
 		os.RemoveAll(path)
@@ -97,19 +108,28 @@ func (m maybeGopkginSource) try(ctx context.Context, cachedir string) (source, e
 	path := sourceCachePath(cachedir, aliasURL)
 	ustr := m.url.String()
 
-	r, err := vcs.NewGitRepo(ustr, path)
-	if err != nil {
-		os.RemoveAll(path)
-		r, err = vcs.NewGitRepo(ustr, path)
+	var repo ctxRepo
+	if usePureGit(m.url) && !toolInstalled("git") {
+		repo = &refsOnlyRepo{remote: ustr, local: path}
+	} else {
+		if err := useTool("git"); err != nil {
+			return nil, err
+		}
+		r, err := vcs.NewGitRepo(ustr, path)
 		if err != nil {
-			return nil, unwrapVcsErr(err)
+			os.RemoveAll(path)
+			r, err = vcs.NewGitRepo(ustr, path)
+			if err != nil {
+				return nil, unwrapVcsErr(err)
+			}
 		}
+		repo = &gitRepo{r}
 	}
 
 	return &gopkginSource{
 		gitSource: gitSource{
 			baseVCSSource: baseVCSSource{
-				repo: &gitRepo{r},
+				repo: repo,
 			},
 		},
 		major:    m.major,
@@ -134,6 +154,10 @@ type maybeBzrSource struct {
 }
 
 func (m maybeBzrSource) try(ctx context.Context, cachedir string) (source, error) {
+	if err := useTool("bzr"); err != nil {
+		return nil, err
+	}
+
 	ustr := m.url.String()
 	path := sourceCachePath(cachedir, ustr)
 
@@ -166,6 +190,10 @@ type maybeHgSource struct {
 }
 
 func (m maybeHgSource) try(ctx context.Context, cachedir string) (source, error) {
+	if err := useTool("hg"); err != nil {
+		return nil, err
+	}
+
 	ustr := m.url.String()
 	path := sourceCachePath(cachedir, ustr)
 
@@ -0,0 +1,242 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// moduleProxy exports the trees of projects from a Go module proxy, serving
+// the GOPROXY protocol, as the zips of the modules at their import roots.
+type moduleProxy struct {
+	base   string
+	client *http.Client
+}
+
+// newModuleProxy returns a moduleProxy for the proxy at the http or https URL
+// base.
+func newModuleProxy(base string) (*moduleProxy, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid module proxy URL %s", base)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("module proxy URL %s is not an http or https URL", base)
+	}
+	return &moduleProxy{
+		base:   strings.TrimSuffix(base, "/"),
+		client: &http.Client{Timeout: cacheBackendTimeout, Transport: httpTransport},
+	}, nil
+}
+
+// exportVersionTo writes out the tree of the module at pr, at v, to to. The
+// revision of v is asked for, if it has one, so that what is exported is what
+// was locked, whatever the proxy calls its version.
+func (p *moduleProxy) exportVersionTo(ctx context.Context, pr ProjectRoot, v Version, to string) error {
+	query := v.String()
+	switch tv := v.(type) {
+	case Revision:
+		query = string(tv)
+	case PairedVersion:
+		query = string(tv.Revision())
+	}
+
+	mod, err := escapeModulePath(string(pr))
+	if err != nil {
+		return err
+	}
+	q, err := escapeModulePath(query)
+	if err != nil {
+		return err
+	}
+
+	var info struct {
+		Version string
+	}
+	body, err := p.get(ctx, mod+"/@v/"+q+".info")
+	if err != nil {
+		return err
+	}
+	err = json.NewDecoder(body).Decode(&info)
+	body.Close()
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse the version of %s at %s", pr, query)
+	}
+
+	ver, err := escapeModulePath(info.Version)
+	if err != nil {
+		return err
+	}
+	body, err = p.get(ctx, mod+"/@v/"+ver+".zip")
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// Zips can only be read with random access, so the module is spooled
+	// to a temporary file first.
+	tmp, err := ioutil.TempFile("", "dep-module")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	size, err := io.Copy(tmp, body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to download %s@%s", pr, info.Version)
+	}
+
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the zip of %s@%s", pr, info.Version)
+	}
+	return extractModuleZip(zr, string(pr)+"@"+info.Version+"/", to)
+}
+
+// get fetches the file at name in the proxy.
+func (p *moduleProxy) get(ctx context.Context, name string) (io.ReadCloser, error) {
+	u := p.base + "/" + name
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to build request for %s", u)
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch %s", u)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("failed to fetch %s: %s", u, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// extractModuleZip writes the files in zr, all of whose names must begin with
+// prefix, to dir, without the prefix.
+func extractModuleZip(zr *zip.Reader, prefix, dir string) error {
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, prefix) {
+			return errors.Errorf("%s is not within %s in the module zip", f.Name, prefix)
+		}
+		name := strings.TrimPrefix(f.Name, prefix)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if path.Clean(name) != name || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return errors.Errorf("%s is outside of the module", f.Name)
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+			return err
+		}
+		if err := extractZipFile(f, dst); err != nil {
+			return errors.Wrapf(err, "failed to extract %s", f.Name)
+		}
+	}
+	return nil
+}
+
+func extractZipFile(f *zip.File, dst string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// escapeModulePath escapes s, a module path or version, as the GOPROXY
+// protocol requires: each upper-case letter is replaced by an exclamation mark
+// followed by the letter's lower-case equivalent.
+func escapeModulePath(s string) (string, error) {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '!' || r == utf8.RuneError || r >= utf8.RuneSelf:
+			return "", errors.Errorf("%q cannot be fetched from a module proxy", s)
+		case 'A' <= r && r <= 'Z':
+			b.WriteByte('!')
+			b.WriteRune(r + 'a' - 'A')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+// exportWithProxy writes out the tree of id at v to to with export, which
+// exports it from its source. If the SourceMgr has a module proxy, the tree is
+// exported from that instead should export fail, and then prepared with
+// finish, if it is not nil; if the proxy is to be tried first, it is the other
+// way round. Each failover is logged.
+//
+// The proxy serves the module at the project root, so it is not used for
+// identifiers with a Source or RootDir: what it serves would not be the tree
+// that was locked.
+func (sm *SourceMgr) exportWithProxy(ctx context.Context, id ProjectIdentifier, v Version, to string, export func() error, finish func() error) error {
+	if sm.proxy == nil || id.Source != "" || id.RootDir != "" {
+		return export()
+	}
+
+	viaProxy := func() error {
+		if err := sm.proxy.exportVersionTo(ctx, id.ProjectRoot, v, to); err != nil {
+			return err
+		}
+		if finish != nil {
+			return finish()
+		}
+		return nil
+	}
+	attempts := []struct {
+		from   string
+		export func() error
+	}{
+		{"its source", export},
+		{"module proxy " + sm.proxy.base, viaProxy},
+	}
+	if sm.proxyFirst {
+		attempts[0], attempts[1] = attempts[1], attempts[0]
+	}
+
+	// Whatever a failed attempt leaves behind is cleared out before the next.
+	var errs errorSlice
+	for i, a := range attempts {
+		err := removeOnError(to, a.export)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, errors.Wrapf(err, "failed to export %s from %s", id, a.from))
+		if i+1 < len(attempts) {
+			sm.srcCoord.logger.Printf("failed to export %s from %s, retrying from %s: %v\n", id, a.from, attempts[i+1].from, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestEscapeModulePath(t *testing.T) {
+	for in, want := range map[string]string{
+		"github.com/foo/bar":       "github.com/foo/bar",
+		"github.com/Azure/go-Auth": "github.com/!azure/go-!auth",
+		"v1.0.0-RC1":               "v1.0.0-!r!c1",
+	} {
+		if got, err := escapeModulePath(in); err != nil || got != want {
+			t.Errorf("%q: expected %q, got %q, %v", in, want, got, err)
+		}
+	}
+	for _, in := range []string{"github.com/foo/bar!", "github.com/f\u00f6\u00f6/bar"} {
+		if _, err := escapeModulePath(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestSourceMgrExportFailsOverToModuleProxy(t *testing.T) {
+	rev := Revision("d05d5aca9f895d19e9265839bffeadd74a2d2ecb")
+	var zb bytes.Buffer
+	zw := zip.NewWriter(&zb)
+	for name, contents := range map[string]string{
+		"example.com/Foo@v0.0.0-20180613153352-d05d5aca9f89/foo.go":      "package foo\n",
+		"example.com/Foo@v0.0.0-20180613153352-d05d5aca9f89/foo_test.go": "package foo\n",
+		"example.com/Foo@v0.0.0-20180613153352-d05d5aca9f89/sub/sub.go":  "package sub\n",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(contents))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var served []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, r.URL.Path)
+		switch r.URL.Path {
+		case "/example.com/!foo/@v/" + string(rev) + ".info":
+			w.Write([]byte(`{"Version":"v0.0.0-20180613153352-d05d5aca9f89"}`))
+		case "/example.com/!foo/@v/v0.0.0-20180613153352-d05d5aca9f89.zip":
+			w.Write(zb.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("cache/sources")
+	h.TempDir("export")
+
+	ctx := context.Background()
+	var logged bytes.Buffer
+	superv := newSupervisor(ctx)
+	deducer := fileDeducer{"example.com/Foo": filepath.Join(h.Path("cache"), "missing")}
+	proxy, err := newModuleProxy(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := &SourceMgr{
+		suprvsr:  superv,
+		srcCoord: newSourceCoordinator(superv, deducer, h.Path("cache"), nil, nil, log.New(&logged, "", 0)),
+		proxy:    proxy,
+	}
+	defer sm.srcCoord.close()
+
+	id := ProjectIdentifier{ProjectRoot: "example.com/Foo"}
+	lp := NewLockedProject(id, NewBranch("master").Pair(rev), []string{".", "sub"})
+	to := filepath.Join(h.Path("export"), "foo")
+	if err := sm.ExportPrunedProject(ctx, lp, PruneNestedVendorDirs|PruneGoTestFiles, to); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	err = filepath.Walk(to, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(to, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	if want := []string{"foo.go", "sub/sub.go"}; strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Errorf("expected the pruned export to hold %v, got %v", want, files)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(to, "sub", "sub.go")); string(b) != "package sub\n" {
+		t.Errorf("expected sub/sub.go to be extracted from the zip, got %q", b)
+	}
+	if !strings.Contains(logged.String(), "retrying from module proxy "+srv.URL) {
+		t.Errorf("expected the failover to be logged, got %q", logged.String())
+	}
+
+	// Tried first, a proxy that fails falls back on the source; both errors
+	// are reported when that fails too.
+	sm.proxyFirst = true
+	served = nil
+	to = filepath.Join(h.Path("export"), "missing")
+	err = sm.ExportProject(ctx, ProjectIdentifier{ProjectRoot: "example.com/Foo"}, NewVersion("v1.0.0"), to)
+	if err == nil {
+		t.Fatal("expected an error when neither the proxy nor the source can be reached")
+	}
+	if len(served) != 1 || !strings.Contains(err.Error(), "from module proxy") || !strings.Contains(err.Error(), "from its source") {
+		t.Errorf("expected the proxy, then the source, to be tried, got %v: %v", served, err)
+	}
+	if _, err := os.Stat(to); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be left of the failed export, got %v", err)
+	}
+
+	// The proxy cannot serve a project from another source, or from a
+	// directory beneath its root.
+	for _, id := range []ProjectIdentifier{
+		{ProjectRoot: "example.com/Foo", Source: "example.com/fork/foo"},
+		{ProjectRoot: "example.com/Foo", RootDir: "sub"},
+	} {
+		served = nil
+		err = sm.ExportProject(ctx, id, rev, filepath.Join(h.Path("export"), "other"))
+		if err == nil {
+			t.Fatalf("expected %s not to be exported from the proxy", id)
+		}
+		if len(served) != 0 {
+			t.Errorf("expected the proxy not to be asked for %s, got %v", id, served)
+		}
+	}
+}
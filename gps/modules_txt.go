@@ -0,0 +1,205 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModulesFile is the name of the file, at the root of a vendor tree, in which
+// the go command looks up the modules that provide vendored packages when it
+// builds with -mod=vendor.
+const ModulesFile = "modules.txt"
+
+// A VendoredModule is a module whose packages are vendored, as inferred from a
+// locked project.
+type VendoredModule struct {
+	// Path is the module path, which is the import path of the directory in
+	// vendor that holds the module.
+	Path string
+	// Version is the module version: the locked semver version, or a
+	// pseudo-version naming the locked revision.
+	Version string
+	// GoVersion is the version in the go directive of the go.mod file of the
+	// module, if it has one.
+	GoVersion string
+	// Packages are the import paths of the vendored packages of the module.
+	Packages []string
+}
+
+// VendoredModules infers the modules provided by the vendor tree at basedir,
+// which was populated from l. Each locked project is a module, unless it
+// contains go.mod files below its root, each of which marks the root of a
+// nested module that provides the packages beneath it.
+//
+// revisionTime returns when the locked revision of a project was committed,
+// for the pseudo-versions of those not locked to a semver version.
+func VendoredModules(basedir string, l Lock, revisionTime func(LockedProject) time.Time) ([]VendoredModule, error) {
+	var mods []VendoredModule
+	for _, lp := range sortLockedProjects(l.Projects()) {
+		pr := string(lp.Ident().ProjectRoot)
+		projectDir := filepath.Join(basedir, filepath.FromSlash(pr))
+
+		// Find the roots of the modules in the project, keyed by their
+		// directory relative to the project root.
+		goVersions := make(map[string]string)
+		err := filepath.Walk(projectDir, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				if p == projectDir && os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if fi.IsDir() {
+				if p != projectDir && (fi.Name() == "vendor" || fi.Name() == "testdata") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if fi.Name() != "go.mod" {
+				return nil
+			}
+			rel, err := filepath.Rel(projectDir, filepath.Dir(p))
+			if err != nil {
+				return err
+			}
+			goVersions[filepath.ToSlash(rel)], err = goModGoVersion(p)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, has := goVersions["."]; !has {
+			goVersions["."] = ""
+		}
+
+		// Assign each package to the innermost module that contains it.
+		pkgs := make(map[string][]string)
+		for _, pkg := range lp.Packages() {
+			pkg = path.Clean(pkg)
+			dir := pkg
+			for {
+				if _, has := goVersions[dir]; has {
+					break
+				}
+				dir = path.Dir(dir)
+			}
+			pkgs[dir] = append(pkgs[dir], path.Join(pr, pkg))
+		}
+
+		dirs := make([]string, 0, len(goVersions))
+		for dir := range goVersions {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			if dir != "." && len(pkgs[dir]) == 0 {
+				continue
+			}
+			mod := VendoredModule{
+				Path:      path.Join(pr, dir),
+				GoVersion: goVersions[dir],
+				Packages:  pkgs[dir],
+			}
+			sort.Strings(mod.Packages)
+			// Tags name versions of the module at the root of the project;
+			// nested modules have tags of their own, which are not locked.
+			mod.Version = moduleVersion(mod.Path, lp.Version(), dir == ".", revisionTime(lp))
+			mods = append(mods, mod)
+		}
+	}
+	return mods, nil
+}
+
+// moduleVersion returns the version of the module at modPath in a project
+// locked to v, whose revision was committed at t. Only the module at the root
+// of the project takes its version from a semver tag.
+func moduleVersion(modPath string, v Version, root bool, t time.Time) string {
+	var rev Revision
+	switch tv := v.(type) {
+	case versionPair:
+		if sv, ok := tv.v.(semVersion); ok && root {
+			mv := fmt.Sprintf("v%d.%d.%d", sv.sv.Major(), sv.sv.Minor(), sv.sv.Patch())
+			if pre := sv.sv.Prerelease(); pre != "" {
+				mv += "-" + pre
+			}
+			if sv.sv.Major() >= 2 && !strings.HasSuffix(modPath, fmt.Sprintf("/v%d", sv.sv.Major())) {
+				// The module path lacks the major version suffix, so the go
+				// command accepts such a version only as incompatible.
+				mv += "+incompatible"
+			}
+			return mv
+		}
+		rev = tv.r
+	case Revision:
+		rev = tv
+	}
+	return PseudoVersion(rev, t)
+}
+
+// PseudoVersion returns the go modules pseudo-version of rev, committed at t,
+// such as v0.0.0-20180613153352-e1c0ee2d9a6c.
+func PseudoVersion(rev Revision, t time.Time) string {
+	abbrev := string(rev)
+	if len(abbrev) > 12 {
+		abbrev = abbrev[:12]
+	}
+	return fmt.Sprintf("v0.0.0-%s-%s", t.UTC().Format("20060102150405"), abbrev)
+}
+
+// goModGoVersion returns the version in the go directive of the go.mod file at
+// p, or "" if it has none.
+func goModGoVersion(p string) (string, error) {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && fields[0] == "go" {
+			return fields[1], nil
+		}
+	}
+	return "", s.Err()
+}
+
+// ModulesTxt renders the ModulesFile for a vendor tree holding mods, each of
+// which is marked as explicitly required by the main module:
+//
+//	# github.com/foo/bar v1.0.0
+//	## explicit; go 1.12
+//	github.com/foo/bar
+//	github.com/foo/bar/subpkg
+func ModulesTxt(mods []VendoredModule) []byte {
+	var buf bytes.Buffer
+	for _, mod := range mods {
+		fmt.Fprintf(&buf, "# %s %s\n", mod.Path, mod.Version)
+		if mod.GoVersion != "" {
+			fmt.Fprintf(&buf, "## explicit; go %s\n", mod.GoVersion)
+		} else {
+			buf.WriteString("## explicit\n")
+		}
+		for _, pkg := range mod.Packages {
+			fmt.Fprintln(&buf, pkg)
+		}
+	}
+	return buf.Bytes()
+}
+
+// WriteModulesTxt writes the ModulesFile for mods into the vendor tree at
+// basedir.
+func WriteModulesTxt(basedir string, mods []VendoredModule) error {
+	return ioutil.WriteFile(filepath.Join(basedir, ModulesFile), ModulesTxt(mods), 0666)
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVendoredModules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vendoredmodules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"github.com/foo/bar/go.mod":          "module github.com/foo/bar/v2\n\ngo 1.12\n",
+		"github.com/foo/bar/bar.go":          "package bar\n",
+		"github.com/foo/bar/sub/go.mod":      "module github.com/foo/bar/sub\n",
+		"github.com/foo/bar/sub/pkg/pkg.go":  "package pkg\n",
+		"github.com/foo/bar/testdata/go.mod": "module example.com/testdata\n",
+		"github.com/qux/quux/quux.go":        "package quux\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := fixLock{
+		NewLockedProject(mkPI("github.com/qux/quux"), Revision("0123456789abcdef0123456789abcdef01234567"), []string{"."}),
+		NewLockedProject(mkPI("github.com/foo/bar"), NewVersion("v2.1.0").Pair("abcdef0123456789abcdef0123456789abcdef01"), []string{".", "sub/pkg"}),
+	}
+	committed := time.Date(2018, time.June, 13, 15, 33, 52, 0, time.UTC)
+	mods, err := VendoredModules(dir, l, func(LockedProject) time.Time { return committed })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# github.com/foo/bar v2.1.0+incompatible
+## explicit; go 1.12
+github.com/foo/bar
+# github.com/foo/bar/sub v0.0.0-20180613153352-abcdef012345
+## explicit
+github.com/foo/bar/sub/pkg
+# github.com/qux/quux v0.0.0-20180613153352-0123456789ab
+## explicit
+github.com/qux/quux
+`
+	if got := string(ModulesTxt(mods)); got != want {
+		t.Errorf("unexpected modules.txt:\n\t(GOT):\n%s\n\t(WNT):\n%s", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkgtree
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The kinds of non-Go source that a package may be built with.
+const (
+	// NativeCgo is a package with Go files that import "C".
+	NativeCgo = "cgo"
+	// NativeSwig is a package with SWIG interface files.
+	NativeSwig = "swig"
+	// NativeAsm is a package with assembly files.
+	NativeAsm = "asm"
+)
+
+// NativeKinds returns the kinds of non-Go source, in the order NativeCgo,
+// NativeSwig and NativeAsm, that the package in dir is built with on any of
+// platforms, or on any platform at all if platforms is empty.
+func NativeKinds(dir string, platforms []Platform) ([]string, error) {
+	gofiles, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var cgo bool
+	for _, file := range gofiles {
+		name := filepath.Base(file)
+		if name[0] == '_' || name[0] == '.' || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if len(platforms) > 0 {
+			if match, err := matchAnyPlatform(platforms, dir, name); err == nil && !match {
+				continue
+			}
+		}
+		pf, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+		if err != nil {
+			// Files that cannot be parsed are not built at all.
+			continue
+		}
+		for _, is := range pf.Imports {
+			if path, _ := strconv.Unquote(is.Path.Value); path == "C" {
+				cgo = true
+			}
+		}
+	}
+
+	return nativeKinds(dir, cgo, platforms)
+}
+
+// nativeKinds returns the kinds of non-Go source that the package in dir is
+// built with, given whether its Go files use cgo.
+func nativeKinds(dir string, cgo bool, platforms []Platform) ([]string, error) {
+	var kinds []string
+	if cgo {
+		kinds = append(kinds, NativeCgo)
+	}
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return kinds, nil
+		}
+		return nil, err
+	}
+
+	var swig, asm bool
+	for _, fi := range fis {
+		name := fi.Name()
+		if fi.IsDir() || name[0] == '_' || name[0] == '.' {
+			continue
+		}
+		var kind *bool
+		switch filepath.Ext(name) {
+		case ".swig", ".swigcxx":
+			kind = &swig
+		case ".s", ".S":
+			kind = &asm
+		default:
+			continue
+		}
+		if len(platforms) > 0 {
+			if match, err := matchAnyPlatform(platforms, dir, name); err == nil && !match {
+				continue
+			}
+		}
+		*kind = true
+	}
+
+	if swig {
+		kinds = append(kinds, NativeSwig)
+	}
+	if asm {
+		kinds = append(kinds, NativeAsm)
+	}
+	return kinds, nil
+}
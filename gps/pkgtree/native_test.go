@@ -0,0 +1,82 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkgtree
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNativeKinds(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "nativekinds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	files := map[string]string{
+		"pure/pure.go":          "package pure\n",
+		"cgo/cgo.go":            "package cgo\n\nimport \"C\"\n",
+		"cgo/cgo_test.go":       "package cgo\n",
+		"asm/asm.go":            "package asm\n",
+		"asm/sum_amd64.s":       "TEXT ·sum(SB),$0\n",
+		"swig/swig.go":          "package swig\n",
+		"swig/lib.swigcxx":      "%module lib\n",
+		"wincgo/cgo_windows.go": "package wincgo\n\nimport \"C\"\n",
+		"testcgo/testcgo.go":    "package testcgo\n",
+		"testcgo/cgo_test.go":   "package testcgo\n\nimport \"C\"\n",
+		"winasm/winasm.go":      "package winasm\n",
+		"winasm/sum_windows.s":  "TEXT ·sum(SB),$0\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	linux := []Platform{{GOOS: "linux", GOARCH: "amd64"}}
+	cases := []struct {
+		dir       string
+		platforms []Platform
+		want      []string
+	}{
+		{dir: "pure"},
+		{dir: "cgo", want: []string{NativeCgo}},
+		{dir: "asm", want: []string{NativeAsm}},
+		{dir: "swig", want: []string{NativeSwig}},
+		{dir: "testcgo"},
+		{dir: "wincgo", want: []string{NativeCgo}},
+		{dir: "wincgo", platforms: linux},
+		{dir: "winasm", want: []string{NativeAsm}},
+		{dir: "winasm", platforms: linux},
+	}
+	for _, c := range cases {
+		got, err := NativeKinds(filepath.Join(tmp, c.dir), c.platforms)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s on %v: expected %v, got %v", c.dir, c.platforms, c.want, got)
+		}
+	}
+
+	ptree, err := ListPackages(tmp, "example.com/native")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ptree.Packages["example.com/native/cgo"].P.Native; !reflect.DeepEqual(got, []string{NativeCgo}) {
+		t.Errorf("expected ListPackages to find cgo, got %v", got)
+	}
+	if got := ptree.Packages["example.com/native/asm"].P.Native; !reflect.DeepEqual(got, []string{NativeAsm}) {
+		t.Errorf("expected ListPackages to find assembly, got %v", got)
+	}
+}
@@ -28,6 +28,7 @@ type Package struct {
 	CommentPath string   // Import path given in the comment on the package statement
 	Imports     []string // Imports from all go and cgo files
 	TestImports []string // Imports from all go test files (in go/build parlance: both TestImports and XTestImports)
+	Native      []string // Kinds of non-Go source the package is built with: NativeCgo, NativeSwig and NativeAsm
 }
 
 // vcsRoots is a set of directories we should not descend into in ListPackages when
@@ -58,10 +59,20 @@ var vcsRoots = map[string]struct{}{
 // "github.com/foo/bar", and the package at
 // "/home/user/workspace/path/to/repo/baz" will be "github.com/foo/bar/baz".
 //
+// The imports of every file are reported, whatever platforms it is built on.
+//
 // A PackageTree is returned, which contains the ImportRoot and map of import path
 // to PackageOrErr - each path under the root that exists will have either a
 // Package, or an error describing why the directory is not a valid package.
 func ListPackages(fileRoot, importRoot string) (PackageTree, error) {
+	return ListPackagesForPlatforms(fileRoot, importRoot, nil)
+}
+
+// ListPackagesForPlatforms is like ListPackages, but if platforms is not
+// empty, it reports only the files, and thus the imports, that are built on at
+// least one of them. Packages that none of platforms builds are reported as
+// having no Go files.
+func ListPackagesForPlatforms(fileRoot, importRoot string, platforms []Platform) (PackageTree, error) {
 	ptree := PackageTree{
 		ImportRoot: importRoot,
 		Packages:   make(map[string]PackageOrErr),
@@ -131,12 +142,13 @@ func ListPackages(fileRoot, importRoot string) (PackageTree, error) {
 		// import paths.
 		ip := filepath.ToSlash(filepath.Join(importRoot, strings.TrimPrefix(wp, fileRoot)))
 
-		// Find all the imports, across all os/arch combos
+		// Find all the imports, across all os/arch combos, or those of the
+		// platforms requested.
 		p := &build.Package{
 			Dir:        wp,
 			ImportPath: ip,
 		}
-		err = fillPackage(p)
+		err = fillPackage(p, platforms)
 
 		if err != nil {
 			switch err.(type) {
@@ -159,6 +171,9 @@ func ListPackages(fileRoot, importRoot string) (PackageTree, error) {
 			Imports:     p.Imports,
 			TestImports: dedupeStrings(p.TestImports, p.XTestImports),
 		}
+		if pkg.Native, err = nativeKinds(wp, len(p.CgoFiles) > 0, platforms); err != nil {
+			return err
+		}
 
 		if pkg.CommentPath != "" && !strings.HasPrefix(pkg.CommentPath, importRoot) {
 			ptree.Packages[ip] = PackageOrErr{
@@ -208,8 +223,9 @@ func ListPackages(fileRoot, importRoot string) (PackageTree, error) {
 	return ptree, nil
 }
 
-// fillPackage full of info. Assumes p.Dir is set at a minimum
-func fillPackage(p *build.Package) error {
+// fillPackage full of info. Assumes p.Dir is set at a minimum. If platforms is
+// not empty, files built on none of them are skipped.
+func fillPackage(p *build.Package, platforms []Platform) error {
 	var buildPrefix = "// +build "
 	var buildFieldSplit = func(r rune) bool {
 		return unicode.IsSpace(r) || r == ','
@@ -227,6 +243,7 @@ func fillPackage(p *build.Package) error {
 	var testImports []string
 	var imports []string
 	var importComments []string
+	var matched bool
 	for _, file := range gofiles {
 		// Skip underscore-led or dot-led files, in keeping with the rest of the toolchain.
 		bPrefix := filepath.Base(file)[0]
@@ -239,6 +256,15 @@ func fillPackage(p *build.Package) error {
 			continue
 		}
 
+		// Files whose build constraints cannot be read are left for the
+		// parser to report on.
+		if len(platforms) > 0 {
+			if match, err := matchAnyPlatform(platforms, p.Dir, filepath.Base(file)); err == nil && !match {
+				continue
+			}
+		}
+		matched = true
+
 		pf, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly|parser.ParseComments)
 		if err != nil {
 			if os.IsPermission(err) {
@@ -300,9 +326,16 @@ func fillPackage(p *build.Package) error {
 				testImports = append(testImports, name)
 			} else {
 				imports = append(imports, name)
+				if name == "C" && !ignored {
+					p.CgoFiles = append(p.CgoFiles, fname)
+				}
 			}
 		}
 	}
+	if !matched && len(platforms) > 0 {
+		return &build.NoGoError{Dir: p.Dir}
+	}
+
 	importComments = uniq(importComments)
 	if len(importComments) > 1 {
 		return &ConflictingImportComments{
@@ -619,7 +652,7 @@ func CopyPackages(p map[string]PackageOrErr, fn func(string, PackageOrErr) (stri
 	// need, then allocate them all at once.
 	strcount := 0
 	for _, poe := range p {
-		strcount = strcount + len(poe.P.Imports) + len(poe.P.TestImports)
+		strcount = strcount + len(poe.P.Imports) + len(poe.P.TestImports) + len(poe.P.Native)
 	}
 	pool := make([]string, strcount)
 
@@ -639,6 +672,10 @@ func CopyPackages(p map[string]PackageOrErr, fn func(string, PackageOrErr) (stri
 				poe2.P.TestImports, pool = pool[:til], pool[til:]
 				copy(poe2.P.TestImports, poe.P.TestImports)
 			}
+			if nl := len(poe.P.Native); nl > 0 {
+				poe2.P.Native, pool = pool[:nl], pool[nl:]
+				copy(poe2.P.Native, poe.P.Native)
+			}
 		}
 		if fn != nil {
 			path, poe2 = fn(path, poe2)
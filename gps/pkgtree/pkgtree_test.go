@@ -2347,6 +2347,7 @@ func TestCanaryPackageTreeCopy(t *testing.T) {
 		"CommentPath",
 		"Imports",
 		"TestImports",
+		"Native",
 	}
 
 	fieldNames := func(typ reflect.Type) []string {
@@ -0,0 +1,50 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkgtree
+
+import (
+	"go/build"
+	"strings"
+)
+
+// Platform is a combination of GOOS, GOARCH and build tags for which packages
+// may be built.
+type Platform struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+func (p Platform) String() string {
+	s := p.GOOS + "/" + p.GOARCH
+	if len(p.Tags) > 0 {
+		s += " (" + strings.Join(p.Tags, ",") + ")"
+	}
+	return s
+}
+
+// matchFile reports whether the file name in dir is built on p, as judged by
+// its name and its build constraints. Files that use cgo are assumed to be
+// built with it enabled.
+func (p Platform) matchFile(dir, name string) (bool, error) {
+	ctxt := build.Default
+	ctxt.GOOS = p.GOOS
+	ctxt.GOARCH = p.GOARCH
+	ctxt.BuildTags = p.Tags
+	ctxt.CgoEnabled = true
+	return ctxt.MatchFile(dir, name)
+}
+
+// matchAnyPlatform reports whether the file name in dir is built on any of
+// platforms.
+func matchAnyPlatform(platforms []Platform, dir, name string) (bool, error) {
+	for _, p := range platforms {
+		match, err := p.matchFile(dir, name)
+		if err != nil || match {
+			return match, err
+		}
+	}
+	return false, nil
+}
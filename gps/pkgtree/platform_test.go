@@ -0,0 +1,92 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkgtree
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListPackagesForPlatforms(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "listpkgsplatforms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	files := map[string]string{
+		"common.go":              "package foo\n\nimport _ \"example.com/common\"\n",
+		"sys_linux.go":           "package foo\n\nimport _ \"example.com/linux\"\n",
+		"sys_windows.go":         "package foo\n\nimport _ \"example.com/windows\"\n",
+		"extra.go":               "// +build extra\n\npackage foo\n\nimport _ \"example.com/extra\"\n",
+		"winonly/win_windows.go": "package winonly\n\nimport _ \"example.com/windows\"\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name        string
+		platforms   []Platform
+		imports     []string
+		winonlyNoGo bool
+	}{
+		{
+			name:    "every platform",
+			imports: []string{"example.com/common", "example.com/extra", "example.com/linux", "example.com/windows"},
+		},
+		{
+			name:        "linux",
+			platforms:   []Platform{{GOOS: "linux", GOARCH: "amd64"}},
+			imports:     []string{"example.com/common", "example.com/linux"},
+			winonlyNoGo: true,
+		},
+		{
+			name: "linux and windows with a tag",
+			platforms: []Platform{
+				{GOOS: "linux", GOARCH: "amd64"},
+				{GOOS: "windows", GOARCH: "386", Tags: []string{"extra"}},
+			},
+			imports: []string{"example.com/common", "example.com/extra", "example.com/linux", "example.com/windows"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ptree, err := ListPackagesForPlatforms(tmp, "example.com/foo", c.platforms)
+			if err != nil {
+				t.Fatal(err)
+			}
+			poe := ptree.Packages["example.com/foo"]
+			if poe.Err != nil {
+				t.Fatal(poe.Err)
+			}
+			if !reflect.DeepEqual(poe.P.Imports, c.imports) {
+				t.Errorf("expected imports %v, got %v", c.imports, poe.P.Imports)
+			}
+			_, noGo := ptree.Packages["example.com/foo/winonly"].Err.(*build.NoGoError)
+			if noGo != c.winonlyNoGo {
+				t.Errorf("expected the windows-only package to have no Go files to be %v, got %v", c.winonlyNoGo, noGo)
+			}
+		})
+	}
+}
+
+func TestPlatformString(t *testing.T) {
+	p := Platform{GOOS: "linux", GOARCH: "arm64", Tags: []string{"netgo", "osusergo"}}
+	if got, want := p.String(), "linux/arm64 (netgo,osusergo)"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
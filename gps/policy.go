@@ -0,0 +1,103 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ImportPolicy restricts the projects that the solver may select, by the
+// prefixes of their import paths and sources. A prefix matches a path if it is
+// equal to the path, or to a leading sequence of its elements, so that a host
+// name alone, like "gitlab.example.com", matches every project on that host.
+type ImportPolicy struct {
+	// Source names where the policy came from, for use in failure messages.
+	Source string
+	// Allow, if non-empty, lists the prefixes of the only projects that may
+	// be selected.
+	Allow []string
+	// Deny lists the prefixes of projects that may not be selected, even if
+	// they are allowed.
+	Deny []string
+}
+
+// Permits reports whether the project identified by id may be selected under
+// the policy. If it may not, the reason is returned.
+//
+// Both the project root and, if set, the source are checked, so that a
+// project cannot be fetched from a denied host by way of a source override.
+func (p ImportPolicy) Permits(id ProjectIdentifier) (string, bool) {
+	paths := []string{string(id.ProjectRoot)}
+	if id.Source != "" {
+		paths = append(paths, sourceImportPath(id.Source))
+	}
+
+	for _, path := range paths {
+		if prefix, has := matchImportPrefix(p.Deny, path); has {
+			return fmt.Sprintf("%s is denied by %q", path, prefix), false
+		}
+		if len(p.Allow) == 0 {
+			continue
+		}
+		if _, has := matchImportPrefix(p.Allow, path); !has {
+			return fmt.Sprintf("%s is not allowed", path), false
+		}
+	}
+	return "", true
+}
+
+// matchImportPrefix returns the first of prefixes that matches path.
+func matchImportPrefix(prefixes []string, path string) (string, bool) {
+	for _, prefix := range prefixes {
+		trimmed := strings.TrimSuffix(prefix, "/")
+		if trimmed == "" {
+			continue
+		}
+		if path == trimmed || strings.HasPrefix(path, trimmed+"/") {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// sourceImportPath converts a source URL into the form of an import path, by
+// removing its scheme and user, so that it can be matched against prefixes.
+func sourceImportPath(source string) string {
+	path := source
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+	} else if i := strings.Index(path, ":"); i >= 0 && !strings.Contains(path[:i], "/") {
+		// scp-like syntax, as in git@github.com:foo/bar.git
+		path = path[:i] + "/" + path[i+1:]
+	}
+	if i := strings.Index(path, "@"); i >= 0 && !strings.Contains(path[:i], "/") {
+		path = path[i+1:]
+	}
+	return strings.TrimSuffix(path, ".git")
+}
+
+// importPolicyFailure indicates that an atom could not be selected because an
+// import policy does not permit its project.
+type importPolicyFailure struct {
+	goal   atom
+	policy string
+	reason string
+	// chain is the sequence of imports by which the project was reached,
+	// starting with the root project.
+	chain []string
+}
+
+func (e *importPolicyFailure) Error() string {
+	msg := fmt.Sprintf("Could not introduce %s, as %s by the import policy in %s", a2vs(e.goal), e.reason, e.policy)
+	if len(e.chain) > 1 {
+		msg += fmt.Sprintf("; it is imported by way of %s", strings.Join(e.chain, " -> "))
+	}
+	return msg
+}
+
+func (e *importPolicyFailure) traceString() string {
+	return fmt.Sprintf("%s not permitted: %s", a2vs(e.goal), e.reason)
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportPolicyPermits(t *testing.T) {
+	p := ImportPolicy{
+		Source: "test",
+		Allow:  []string{"github.com/our-org/", "gitlab.example.com"},
+		Deny:   []string{"github.com/our-org/legacy"},
+	}
+
+	cases := []struct {
+		id     ProjectIdentifier
+		permit bool
+	}{
+		{mkPI("github.com/our-org/lib"), true},
+		{mkPI("gitlab.example.com/team/lib"), true},
+		{mkPI("github.com/our-org/legacy"), false},
+		{mkPI("github.com/our-org/legacyish"), true},
+		{mkPI("github.com/other/lib"), false},
+		{mkPI("gitlab.example.community/lib"), false},
+		{mkPI("github.com/our-org/lib").normalize(), true},
+		{ProjectIdentifier{ProjectRoot: "github.com/our-org/lib", Source: "https://github.com/other/lib"}, false},
+		{ProjectIdentifier{ProjectRoot: "github.com/our-org/lib", Source: "git@gitlab.example.com:team/lib.git"}, true},
+	}
+	for _, c := range cases {
+		if reason, permit := p.Permits(c.id); permit != c.permit {
+			t.Errorf("expected Permits(%s) to be %v, reason: %q", c.id, c.permit, reason)
+		}
+	}
+
+	if _, permit := (ImportPolicy{}).Permits(mkPI("github.com/other/lib")); !permit {
+		t.Error("expected an empty policy to permit every project")
+	}
+}
+
+func TestSolveFailsWithDeniedProject(t *testing.T) {
+	ds := []depspec{
+		mkDepspec("root 0.0.0", "foo 1.0.0"),
+		mkDepspec("foo 1.0.0", "bar 1.0.0"),
+		mkDepspec("bar 1.0.0"),
+	}
+	fix := basicFixture{ds: ds}
+
+	params := SolveParameters{
+		RootDir:         string(ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		ProjectAnalyzer: naiveAnalyzer{},
+		ImportPolicies: []ImportPolicy{
+			{Source: "test", Deny: []string{"bar"}},
+		},
+	}
+
+	_, err := fixSolve(params, newdepspecSM(ds, nil), t)
+	if err == nil {
+		t.Fatal("expected solving to fail when a transitive dependency is denied")
+	}
+	for _, want := range []string{`bar is denied by "bar"`, "root -> foo -> bar"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got:\n%s", want, err)
+		}
+	}
+}
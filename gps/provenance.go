@@ -0,0 +1,84 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// ProvenanceFile is the name of the file, at the root of a vendor tree, that
+// records which project, version and revision each vendored package was taken
+// from.
+const ProvenanceFile = "provenance.txt"
+
+const provenanceHeader = `## This file is autogenerated by dep, do not edit. For each vendored project,
+## it records the version and revision it was taken from, followed by the
+## import paths of the packages it provides.
+`
+
+// Provenance renders the provenance file for a vendor tree populated from l.
+//
+// After a header of lines beginning with "##", each project is introduced by a
+// line beginning with "# ", followed by the import path of each of its
+// packages, one per line:
+//
+//	# github.com/foo/bar version=v1.0.0 revision=<rev> [source=<source>] [root-dir=<dir>]
+//	github.com/foo/bar
+//	github.com/foo/bar/subpkg
+//
+// Projects locked to a branch have a branch= field rather than version=, and
+// those locked to a bare revision have neither.
+func Provenance(l Lock) []byte {
+	lps := make([]LockedProject, len(l.Projects()))
+	copy(lps, l.Projects())
+	sort.Slice(lps, func(i, j int) bool {
+		return lps[i].Ident().Less(lps[j].Ident())
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString(provenanceHeader)
+	for _, lp := range lps {
+		id := lp.Ident()
+		fmt.Fprintf(&buf, "# %s", id.ProjectRoot)
+
+		switch v := lp.Version().(type) {
+		case PairedVersion:
+			if v.Type() == IsBranch {
+				fmt.Fprintf(&buf, " branch=%s", v.Unpair())
+			} else {
+				fmt.Fprintf(&buf, " version=%s", v.Unpair())
+			}
+			fmt.Fprintf(&buf, " revision=%s", v.Revision())
+		case Revision:
+			fmt.Fprintf(&buf, " revision=%s", v)
+		}
+		if id.Source != "" {
+			fmt.Fprintf(&buf, " source=%s", id.Source)
+		}
+		if id.RootDir != "" {
+			fmt.Fprintf(&buf, " root-dir=%s", id.RootDir)
+		}
+		buf.WriteByte('\n')
+
+		pkgs := make([]string, len(lp.Packages()))
+		copy(pkgs, lp.Packages())
+		sort.Strings(pkgs)
+		for _, pkg := range pkgs {
+			fmt.Fprintln(&buf, path.Join(string(id.ProjectRoot), pkg))
+		}
+	}
+	return buf.Bytes()
+}
+
+// WriteProvenance writes the provenance file for l into the vendor tree at
+// basedir.
+func WriteProvenance(basedir string, l Lock) error {
+	return ioutil.WriteFile(filepath.Join(basedir, ProvenanceFile), Provenance(l), 0666)
+}
@@ -0,0 +1,27 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import "testing"
+
+func TestProvenance(t *testing.T) {
+	l := SimpleLock{
+		NewLockedProject(mkPI("github.com/foo/bar"), NewVersion("v1.0.0").Pair("rev1"), []string{"sub", "."}),
+		NewLockedProject(mkPI("github.com/baz/qux"), NewBranch("master").Pair("rev2"), []string{"."}),
+		NewLockedProject(ProjectIdentifier{ProjectRoot: "github.com/a/rev", Source: "github.com/fork/rev"}, Revision("rev3"), []string{"pkg"}),
+	}
+
+	want := provenanceHeader + `# github.com/a/rev revision=rev3 source=github.com/fork/rev
+github.com/a/rev/pkg
+# github.com/baz/qux branch=master revision=rev2
+github.com/baz/qux
+# github.com/foo/bar version=v1.0.0 revision=rev1
+github.com/foo/bar
+github.com/foo/bar/sub
+`
+	if got := string(Provenance(l)); got != want {
+		t.Errorf("unexpected provenance:\n\t(GOT): %s\n\t(WNT): %s", got, want)
+	}
+}
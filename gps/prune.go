@@ -12,8 +12,10 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/golang/dep/gps/pkgtree"
 	"github.com/golang/dep/internal/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
 )
 
 // PruneOptions represents the pruning options used to write the dependecy tree.
@@ -243,9 +245,17 @@ func pruneVendorDirs(fsState filesystemState) error {
 
 // pruneUnusedPackages deletes unimported packages found in fsState.
 // Determining whether packages are imported or not is based on the passed LockedProject.
+//
+// If any imported package is built with cgo, SWIG or assembly, the non-Go
+// source files of the project are all kept, wherever they are, as they may be
+// included from outside the package that holds them.
 func pruneUnusedPackages(lp LockedProject, fsState filesystemState) (map[string]interface{}, error) {
 	unusedPackages := calculateUnusedPackages(lp, fsState)
-	toDelete := collectUnusedPackagesFiles(fsState, unusedPackages)
+	native, err := usesNativeCode(lp, fsState)
+	if err != nil {
+		return nil, err
+	}
+	toDelete := collectUnusedPackagesFiles(fsState, unusedPackages, native)
 
 	for _, path := range toDelete {
 		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
@@ -257,12 +267,15 @@ func pruneUnusedPackages(lp LockedProject, fsState filesystemState) (map[string]
 }
 
 // calculateUnusedPackages generates a list of unused packages in lp.
+//
+// Packages are compared by their paths in Unicode Normalization Form C, as the
+// filesystem may store them otherwise than the lock records them.
 func calculateUnusedPackages(lp LockedProject, fsState filesystemState) map[string]interface{} {
 	unused := make(map[string]interface{})
 	imported := make(map[string]interface{})
 
 	for _, pkg := range lp.Packages() {
-		imported[pkg] = nil
+		imported[norm.NFC.String(pkg)] = nil
 	}
 
 	// Add the root package if it's not imported.
@@ -273,7 +286,7 @@ func calculateUnusedPackages(lp LockedProject, fsState filesystemState) map[stri
 	for _, dirPath := range fsState.dirs {
 		pkg := filepath.ToSlash(dirPath)
 
-		if _, ok := imported[pkg]; !ok {
+		if _, ok := imported[norm.NFC.String(pkg)]; !ok {
 			unused[pkg] = nil
 		}
 	}
@@ -281,9 +294,34 @@ func calculateUnusedPackages(lp LockedProject, fsState filesystemState) map[stri
 	return unused
 }
 
+// usesNativeCode reports whether any package of lp that is imported is built
+// with non-Go source.
+func usesNativeCode(lp LockedProject, fsState filesystemState) (bool, error) {
+	dirs := make(map[string]string, len(fsState.dirs))
+	for _, dir := range fsState.dirs {
+		dirs[norm.NFC.String(filepath.ToSlash(dir))] = dir
+	}
+
+	for _, pkg := range lp.Packages() {
+		dir, has := dirs[norm.NFC.String(pkg)]
+		if !has {
+			dir = filepath.FromSlash(pkg)
+		}
+		kinds, err := pkgtree.NativeKinds(filepath.Join(fsState.root, dir), nil)
+		if err != nil {
+			return false, err
+		}
+		if len(kinds) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // collectUnusedPackagesFiles returns a slice of all files in the unused
-// packages based on fsState.
-func collectUnusedPackagesFiles(fsState filesystemState, unusedPackages map[string]interface{}) []string {
+// packages based on fsState. If keepNative is set, non-Go source files are
+// not included.
+func collectUnusedPackagesFiles(fsState filesystemState, unusedPackages map[string]interface{}, keepNative bool) []string {
 	// TODO(ibrasho): is this useful?
 	files := make([]string, 0, len(unusedPackages))
 
@@ -293,6 +331,10 @@ func collectUnusedPackagesFiles(fsState filesystemState, unusedPackages map[stri
 			continue
 		}
 
+		if keepNative && isSourceFile(path) && fileExt(path) != ".go" {
+			continue
+		}
+
 		pkg := filepath.ToSlash(filepath.Dir(path))
 
 		if _, ok := unusedPackages[pkg]; ok {
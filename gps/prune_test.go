@@ -7,6 +7,7 @@ package gps
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang/dep/internal/test"
@@ -192,6 +193,37 @@ func TestPruneUnusedPackages(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"decomposed-package",
+			lockedProject{
+				pi: pi,
+				pkgs: []string{
+					"café",
+				},
+			},
+			fsTestCase{
+				before: filesystemState{
+					dirs: []string{
+						"café",
+						"other",
+					},
+					files: []string{
+						"café/main.go",
+						"other/main.go",
+					},
+				},
+				after: filesystemState{
+					dirs: []string{
+						"café",
+						"other",
+					},
+					files: []string{
+						"café/main.go",
+					},
+				},
+			},
+			false,
+		},
 		{
 			"complex-project",
 			lockedProject{
@@ -234,12 +266,52 @@ func TestPruneUnusedPackages(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"native-project",
+			lockedProject{
+				pi: pi,
+				pkgs: []string{
+					"pkg",
+				},
+			},
+			fsTestCase{
+				before: filesystemState{
+					dirs: []string{
+						"include",
+						"pkg",
+						"unused",
+					},
+					files: []string{
+						"main.go",
+						"include/sum.h",
+						"pkg/main.go",
+						"pkg/sum_amd64.s",
+						"unused/main.go",
+						"unused/sum.c",
+					},
+				},
+				after: filesystemState{
+					dirs: []string{
+						"include",
+						"pkg",
+						"unused",
+					},
+					files: []string{
+						"include/sum.h",
+						"pkg/main.go",
+						"pkg/sum_amd64.s",
+						"unused/sum.c",
+					},
+				},
+			},
+			false,
+		},
 	}
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			h.TempDir(pr)
-			baseDir := h.Path(pr)
+			h.TempDir(filepath.Join(tc.name, pr))
+			baseDir := h.Path(filepath.Join(tc.name, pr))
 			tc.fs.before.root = baseDir
 			tc.fs.after.root = baseDir
 			tc.fs.setup(t)
@@ -0,0 +1,123 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"math/bits"
+	"sort"
+
+	"github.com/golang/dep/gps/pkgtree"
+)
+
+// bitset is a set of small non-negative integers.
+type bitset []uint64
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << uint(i%64)
+}
+
+func (b bitset) has(i int) bool {
+	return b[i/64]&(1<<uint(i%64)) != 0
+}
+
+// union adds the members of o, which must be of the same length, to b.
+func (b bitset) union(o bitset) {
+	for k := range b {
+		b[k] |= o[k]
+	}
+}
+
+func (b bitset) count() int {
+	var n int
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// packageReach is the package reachability of a project at a version, as the
+// solver uses it: the packages of the project that each of its packages
+// reaches, and the packages outside it that each of them imports, directly
+// or through those it reaches.
+//
+// Packages are numbered by their place in sorted order, and the packages that
+// each reaches are kept as a bitset over those numbers, which takes far less
+// memory on large graphs than the slices of a pkgtree.ReachMap. It is
+// computed once per atom, rather than each time the atom is checked.
+type packageReach struct {
+	// pkgs are the valid packages of the project, sorted.
+	pkgs []string
+	// internal holds, for each of pkgs, the set of pkgs that it reaches,
+	// including itself.
+	internal []bitset
+	// external holds, for each of pkgs, the sorted packages outside the
+	// project that it reaches.
+	external [][]string
+	// errs are the errors of the packages that are not valid.
+	errs map[string]*pkgtree.ProblemImportError
+}
+
+// newPackageReach computes the packageReach of ptree, leaving out the
+// packages ignored by ir. Strings are interned with in.
+func newPackageReach(ptree pkgtree.PackageTree, ir *pkgtree.IgnoredRuleset, in interner) *packageReach {
+	rm, em := ptree.ToReachMap(true, false, true, ir)
+
+	pr := &packageReach{
+		pkgs:     make([]string, 0, len(rm)),
+		internal: make([]bitset, len(rm)),
+		external: make([][]string, len(rm)),
+	}
+	if len(em) > 0 {
+		pr.errs = em
+	}
+	for pkg := range rm {
+		pr.pkgs = append(pr.pkgs, in.intern(pkg))
+	}
+	sort.Strings(pr.pkgs)
+
+	// Carve all the bitsets out of one allocation.
+	words := (len(pr.pkgs) + 63) / 64
+	backing := make(bitset, len(pr.pkgs)*words)
+	for i, pkg := range pr.pkgs {
+		b := backing[i*words : (i+1)*words : (i+1)*words]
+		b.set(i)
+		ie := rm[pkg]
+		for _, ipkg := range ie.Internal {
+			if j, has := pr.index(ipkg); has {
+				b.set(j)
+			}
+		}
+		pr.internal[i] = b
+
+		if len(ie.External) > 0 {
+			ext := make([]string, len(ie.External))
+			for k, ex := range ie.External {
+				ext[k] = in.intern(ex)
+			}
+			pr.external[i] = ext
+		}
+	}
+	return pr
+}
+
+// index returns the number of pkg, and whether it is a valid package of the
+// project.
+func (pr *packageReach) index(pkg string) (int, bool) {
+	i := sort.SearchStrings(pr.pkgs, pkg)
+	return i, i < len(pr.pkgs) && pr.pkgs[i] == pkg
+}
+
+// interner dedupes strings, so that the equal strings the solver reads from
+// the package trees and manifests of the many versions of a project share
+// their memory.
+type interner map[string]string
+
+func (in interner) intern(s string) string {
+	if is, has := in[s]; has {
+		return is
+	}
+	in[s] = s
+	return s
+}
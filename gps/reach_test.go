@@ -0,0 +1,85 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps/pkgtree"
+)
+
+func TestBitset(t *testing.T) {
+	a, b := make(bitset, 3), make(bitset, 3)
+	for _, i := range []int{0, 63, 64, 130} {
+		a.set(i)
+	}
+	b.set(1)
+	b.set(130)
+
+	a.union(b)
+	for i := 0; i < 192; i++ {
+		want := i == 0 || i == 1 || i == 63 || i == 64 || i == 130
+		if a.has(i) != want {
+			t.Errorf("has(%d) = %v, want %v", i, a.has(i), want)
+		}
+	}
+	if a.count() != 5 {
+		t.Errorf("count() = %d, want 5", a.count())
+	}
+}
+
+func TestPackageReach(t *testing.T) {
+	pkg := func(path string, imports ...string) pkgtree.PackageOrErr {
+		return pkgtree.PackageOrErr{P: pkgtree.Package{Name: "p", ImportPath: path, Imports: imports}}
+	}
+	ptree := pkgtree.PackageTree{
+		ImportRoot: "example.com/a",
+		Packages: map[string]pkgtree.PackageOrErr{
+			"example.com/a":     pkg("example.com/a", "example.com/a/b", "example.com/x"),
+			"example.com/a/b":   pkg("example.com/a/b", "example.com/a/c", "example.com/y"),
+			"example.com/a/c":   pkg("example.com/a/c", "example.com/x/sub"),
+			"example.com/a/d":   pkg("example.com/a/d", "example.com/z"),
+			"example.com/a/bad": {Err: fmt.Errorf("no Go files")},
+		},
+	}
+	ir := pkgtree.NewIgnoredRuleset([]string{"example.com/a/d"})
+
+	in := make(interner)
+	pr := newPackageReach(ptree, ir, in)
+
+	wantPkgs := []string{"example.com/a", "example.com/a/b", "example.com/a/c"}
+	if !reflect.DeepEqual(pr.pkgs, wantPkgs) {
+		t.Fatalf("pkgs = %v, want %v", pr.pkgs, wantPkgs)
+	}
+
+	// The root package reaches all others, and c only itself.
+	for j := range wantPkgs {
+		if !pr.internal[0].has(j) {
+			t.Errorf("expected %s to reach %s", wantPkgs[0], wantPkgs[j])
+		}
+	}
+	if c := pr.internal[2]; c.count() != 1 || !c.has(2) {
+		t.Errorf("expected %s to reach only itself", wantPkgs[2])
+	}
+
+	wantExt := []string{"example.com/x", "example.com/x/sub", "example.com/y"}
+	if !reflect.DeepEqual(pr.external[0], wantExt) {
+		t.Errorf("external of %s = %v, want %v", wantPkgs[0], pr.external[0], wantExt)
+	}
+
+	if _, has := pr.index("example.com/a/d"); has {
+		t.Error("expected the ignored package to be left out")
+	}
+	if _, has := pr.errs["example.com/a/bad"]; !has {
+		t.Error("expected an error for the package without Go files")
+	}
+
+	// Equal strings from another tree share their memory.
+	if is := in.intern("example.com/x/sub"); is != "example.com/x/sub" || len(in) != 6 {
+		t.Errorf("unexpected interned strings: %v", in)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
+)
+
+// CleanRootDir returns dir in the form a ProjectIdentifier holds as its
+// RootDir: a clean, slash-separated path relative to the root of the
+// repository, without a trailing slash, so that "go/" and "./go" become "go".
+// A dir naming the root of the repository itself becomes "". It is an error
+// for dir to be absolute, separated with backslashes, or to leave the
+// repository.
+func CleanRootDir(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	switch {
+	case strings.Contains(dir, `\`):
+		return "", errors.Errorf("root directory %q must be slash-separated", dir)
+	case path.IsAbs(dir):
+		return "", errors.Errorf("root directory %q must be relative to the root of the repository", dir)
+	}
+	clean := path.Clean(dir)
+	switch {
+	case clean == ".":
+		return "", nil
+	case clean == ".." || strings.HasPrefix(clean, "../"):
+		return "", errors.Errorf("root directory %q is outside the repository", dir)
+	}
+	return clean, nil
+}
+
+// exportRootDir writes the directory dir of a tree to to, with export, which
+// writes out the whole tree. The legal files at the root of the tree are
+// written to to as well, unless dir has its own by the same name.
+func exportRootDir(dir, to string, export func(string) error) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(to), ".dep-root-dir-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	tree := filepath.Join(tmp, "tree")
+	if err := export(tree); err != nil {
+		return err
+	}
+
+	src := filepath.Join(tree, filepath.FromSlash(dir))
+	if fi, err := os.Stat(src); err != nil || !fi.IsDir() {
+		return errors.Errorf("root directory %s does not exist in the project", dir)
+	}
+	if err := fs.RenameWithFallback(src, to); err != nil {
+		return err
+	}
+
+	fis, err := ioutil.ReadDir(tree)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || !isPreservedFile(fi.Name()) {
+			continue
+		}
+		dst := filepath.Join(to, fi.Name())
+		if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+			continue
+		}
+		if err := fs.RenameWithFallback(filepath.Join(tree, fi.Name()), dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportPrunedRootDir writes the root directory of lp to to, pruned.
+func exportPrunedRootDir(ctx context.Context, sg *sourceGateway, lp LockedProject, prune PruneOptions, to string) error {
+	// Pruning the whole tree, with the packages of the project placed in its
+	// root directory, prunes that directory as pruning it alone would, and
+	// keeps the legal files at the root for exportRootDir.
+	dir := lp.Ident().RootDir
+	pkgs := make([]string, len(lp.Packages()))
+	for i, pkg := range lp.Packages() {
+		pkgs[i] = path.Join(dir, pkg)
+	}
+	inTree := NewLockedProject(lp.Ident(), lp.Version(), pkgs)
+	return exportRootDir(dir, to, func(tree string) error {
+		return sg.exportPrunedVersionTo(ctx, inTree, prune, tree)
+	})
+}
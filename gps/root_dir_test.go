@@ -0,0 +1,184 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestCleanRootDir(t *testing.T) {
+	for dir, want := range map[string]string{
+		"":            "",
+		".":           "",
+		"./":          "",
+		"go":          "go",
+		"go/":         "go",
+		"./go":        "go",
+		"clients//go": "clients/go",
+		"clients/go/": "clients/go",
+		"go/../java":  "java",
+	} {
+		got, err := CleanRootDir(dir)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", dir, err)
+		} else if got != want {
+			t.Errorf("%q: expected %q, got %q", dir, want, got)
+		}
+	}
+
+	for _, dir := range []string{"/go", "..", "../go", "go/../..", `clients\go`} {
+		if got, err := CleanRootDir(dir); err == nil {
+			t.Errorf("%q: expected an error, got %q", dir, got)
+		}
+	}
+}
+
+func TestProjectIdentifierRootDir(t *testing.T) {
+	plain := ProjectIdentifier{ProjectRoot: "example.com/client"}
+	sourced := ProjectIdentifier{ProjectRoot: "example.com/client", Source: "example.com/client"}
+	inGo := ProjectIdentifier{ProjectRoot: "example.com/client", RootDir: "go"}
+	inJava := ProjectIdentifier{ProjectRoot: "example.com/client", RootDir: "java"}
+
+	// Identifiers that differ only in RootDir are different projects.
+	if plain.eq(inGo) || inGo.eq(plain) || inGo.eq(inJava) {
+		t.Error("expected identifiers with different RootDirs not to be equal")
+	}
+	if !inGo.eq(inGo) || !plain.eq(sourced) {
+		t.Error("expected identifiers with the same RootDir to be equal")
+	}
+
+	// They are ordered by RootDir after ProjectRoot and Source.
+	if !plain.Less(inGo) || !inGo.Less(inJava) || inJava.Less(inGo) || inGo.Less(inGo) {
+		t.Error("expected identifiers to be ordered by RootDir")
+	}
+	other := ProjectIdentifier{ProjectRoot: "example.com/another", RootDir: "z"}
+	if !other.Less(plain) {
+		t.Error("expected ProjectRoot to be compared before RootDir")
+	}
+
+	// An identifier without a RootDir is matched by one with, as one without
+	// a Source is by one with.
+	if !inGo.equiv(plain) || plain.equiv(inGo) || inGo.equiv(inJava) {
+		t.Error("expected equiv to let only an empty RootDir on the right match any")
+	}
+}
+
+func TestRootDir(t *testing.T) {
+	requiresBins(t, "git")
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("upstream")
+	h.TempDir("scratch/sources")
+	h.TempDir("export")
+	up := h.Path("upstream")
+	h.RunGit(up, "init")
+	for name, contents := range map[string]string{
+		"LICENSE":             "license\n",
+		"NOTICE":              "root notice\n",
+		"README.md":           "readme\n",
+		"mono.go":             "package mono\n",
+		"go/client.go":        "package client\n",
+		"go/NOTICE":           "client notice\n",
+		"go/sub/sub.go":       "package sub\n\nimport _ \"example.com/client\"\n",
+		"go/sub/sub_test.go":  "package sub\n",
+		"go/unused/unused.go": "package unused\n",
+	} {
+		h.TempFile(filepath.Join("upstream", filepath.FromSlash(name)), contents)
+	}
+	h.RunGit(up, "add", ".")
+	h.RunGit(up, "-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "-m", "mono")
+	h.RunGit(up, "tag", "v1.0.0")
+
+	ctx := context.Background()
+	scratch := h.Path("scratch")
+	src, err := maybeGitSource{url: mkurl("file://" + filepath.ToSlash(up))}.try(ctx, scratch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg, err := newSourceGateway(ctx, src, newSupervisor(ctx), scratch, newMemoryCache(), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVersion("v1.0.0")
+
+	importPaths := func(dir string, pr ProjectRoot) []string {
+		t.Helper()
+		ptree, err := sg.listPackages(ctx, pr, dir, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ips []string
+		for ip := range ptree.Packages {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+		return ips
+	}
+	// The same source lists the packages of each directory separately.
+	want := []string{"example.com/client", "example.com/client/sub", "example.com/client/unused"}
+	if got := importPaths("go", "example.com/client"); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected the packages in the root directory to be\n\t%v\ngot\n\t%v", want, got)
+	}
+	want = []string{"example.com/mono", "example.com/mono/go", "example.com/mono/go/sub", "example.com/mono/go/unused"}
+	if got := importPaths("", "example.com/mono"); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected the packages of the whole source to be\n\t%v\ngot\n\t%v", want, got)
+	}
+
+	expect := func(dir string, want map[string]string) {
+		t.Helper()
+		got := make(map[string]string)
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			got[filepath.ToSlash(rel)] = string(b)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("expected the export to hold\n\t%v\ngot\n\t%v", want, got)
+		}
+	}
+
+	id := ProjectIdentifier{ProjectRoot: "example.com/client", RootDir: "go"}
+	lp := NewLockedProject(id, v, []string{".", "sub"})
+	to := filepath.Join(h.Path("export"), "pruned")
+	if err := exportPrunedRootDir(ctx, sg, lp, PruneUnusedPackages|PruneGoTestFiles|PruneNestedVendorDirs, to); err != nil {
+		t.Fatal(err)
+	}
+	expect(to, map[string]string{
+		"LICENSE":    "license\n",
+		"NOTICE":     "client notice\n",
+		"client.go":  "package client\n",
+		"sub/sub.go": "package sub\n\nimport _ \"example.com/client\"\n",
+	})
+
+	to = filepath.Join(h.Path("export"), "missing")
+	lp = NewLockedProject(ProjectIdentifier{ProjectRoot: "example.com/client", RootDir: "java"}, v, []string{"."})
+	if err := exportPrunedRootDir(ctx, sg, lp, PruneNestedVendorDirs, to); err == nil {
+		t.Error("expected an error exporting a root directory that does not exist")
+	}
+	if dirs, _ := filepath.Glob(filepath.Join(h.Path("export"), ".dep-root-dir-*")); len(dirs) != 0 {
+		t.Errorf("expected no temporary directories to be left, got %v", dirs)
+	}
+}
@@ -89,6 +89,7 @@ func (rd rootdata) getApplicableConstraints(stdLibFn func(string) bool) []workin
 			cpp := ProjectProperties{
 				Constraint: pp.Constraint,
 				Source:     pp.Source,
+				RootDir:    pp.RootDir,
 			}
 			if cpp.Constraint == nil {
 				cpp.Constraint = anyConstraint{}
@@ -33,6 +33,15 @@ func (s *solver) check(a atomWithPackages, pkgonly bool) error {
 		if err = s.checkAtomAllowable(pa); err != nil {
 			return err
 		}
+		if err = s.checkAtomNotYanked(pa); err != nil {
+			return err
+		}
+		if err = s.checkAtomPermitted(pa); err != nil {
+			return err
+		}
+		if err = s.checkAtomNotHinted(pa); err != nil {
+			return err
+		}
 	}
 
 	if err = s.checkRequiredPackagesExist(a); err != nil {
@@ -103,11 +112,76 @@ func (s *solver) checkAtomAllowable(pa atom) error {
 	return err
 }
 
+// checkAtomNotYanked ensures that the version of an atom has not been yanked.
+func (s *solver) checkAtomNotYanked(pa atom) error {
+	if yv, yanked := s.yanked.Yanked(pa.id, pa.v); yanked {
+		return &yankedVersionFailure{goal: pa, yv: yv}
+	}
+	return nil
+}
+
+// checkAtomPermitted ensures that the atom's project is permitted by every
+// import policy.
+func (s *solver) checkAtomPermitted(pa atom) error {
+	for _, p := range s.policies {
+		if reason, ok := p.Permits(pa.id); !ok {
+			return &importPolicyFailure{
+				goal:   pa,
+				policy: p.Source,
+				reason: reason,
+				chain:  s.importChain(pa.id),
+			}
+		}
+	}
+	return nil
+}
+
+// checkAtomNotHinted ensures that the atom was not rejected by an earlier
+// solve because its contents could not be read.
+func (s *solver) checkAtomNotHinted(pa atom) error {
+	if r, rejected := s.hints.rejected(pa.id, pa.v); rejected {
+		return &hintedRejectionFailure{goal: pa, r: r}
+	}
+	return nil
+}
+
+// importChain returns a sequence of imports by which the project identified
+// by id came to be required, starting with the root project and ending with a
+// package from id. The earliest selected depender is followed at each step.
+func (s *solver) importChain(id ProjectIdentifier) []string {
+	var chain []string
+	seen := make(map[ProjectRoot]bool)
+	for cur := id.ProjectRoot; !seen[cur]; {
+		seen[cur] = true
+		deps := s.sel.getDependenciesOn(ProjectIdentifier{ProjectRoot: cur})
+		if len(deps) == 0 {
+			break
+		}
+		d := deps[0]
+		pkg := string(cur)
+		if len(d.dep.pl) > 0 {
+			pkg = d.dep.pl[0]
+		}
+		chain = append(chain, pkg)
+		cur = d.depender.id.ProjectRoot
+		if s.rd.isRoot(cur) {
+			chain = append(chain, string(cur))
+			break
+		}
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
 // checkRequiredPackagesExist ensures that all required packages enumerated by
 // existing dependencies on this atom are actually present in the atom.
 func (s *solver) checkRequiredPackagesExist(a atomWithPackages) error {
 	ptree, err := s.b.ListPackages(a.a.id, a.a.v)
 	if err != nil {
+		s.hintUnreadable(a.a, err)
 		// TODO(sdboyer) handle this more gracefully
 		return err
 	}
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/golang/dep/internal/fs"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 )
@@ -47,23 +48,131 @@ type solution struct {
 	solv Solver
 }
 
+// WriteStage is the stage that the writing of a project has reached.
+type WriteStage int
+
+const (
+	// WriteExported is reported once a project has been exported and pruned,
+	// or has failed to be.
+	WriteExported WriteStage = iota
+	// WriteStarted is reported as a project starts to be exported.
+	WriteStarted
+	// WriteHashed is reported once the digest of a written project has been
+	// computed, by writers that record digests.
+	WriteHashed
+)
+
 // WriteProgress informs about the progress of WriteDepTree.
 type WriteProgress struct {
-	Count   int
-	Total   int
-	LP      LockedProject
-	Failure bool
+	// Count is the number of projects that have reached Stage, including LP,
+	// and Total the number of projects being written.
+	Count int
+	Total int
+	LP    LockedProject
+	Stage WriteStage
+	// BytesWritten is the size of the files of LP once it has been exported
+	// and pruned. It is only set for WriteExported.
+	BytesWritten uint64
+	Failure      bool
 }
 
 func (p WriteProgress) String() string {
 	msg := "Wrote"
-	if p.Failure {
+	switch {
+	case p.Failure:
 		msg = "Failed to write"
+	case p.Stage == WriteStarted:
+		msg = "Writing"
+	case p.Stage == WriteHashed:
+		msg = "Hashed"
 	}
 	return fmt.Sprintf("(%d/%d) %s %s@%s", p.Count, p.Total, msg, p.LP.Ident(), p.LP.Version())
 }
 
-const concurrentWriters = 16
+// ConcurrentWriters is the default maximum number of projects that
+// WriteDepTree exports at the same time.
+const ConcurrentWriters = 16
+
+// concurrentWriters is the maximum set by SetConcurrentWriters.
+var concurrentWriters = ConcurrentWriters
+
+// SetConcurrentWriters sets the maximum number of projects that WriteDepTree
+// exports at the same time; if n is less than 1, the maximum is
+// ConcurrentWriters.
+func SetConcurrentWriters(n int) {
+	if n < 1 {
+		n = ConcurrentWriters
+	}
+	concurrentWriters = n
+}
+
+// MaxConcurrentWriters returns the maximum number of projects that
+// WriteDepTree exports at the same time, for others that write projects to
+// disk to keep to.
+func MaxConcurrentWriters() int {
+	return concurrentWriters
+}
+
+// ExportLinks selects how exports from the source cache write out the files
+// of projects.
+type ExportLinks int
+
+const (
+	// ExportCopies writes out the files of projects anew. It is the default.
+	ExportCopies ExportLinks = iota
+	// ExportReflinks clones the files of projects from the source cache
+	// copy-on-write, where the filesystem supports it, as btrfs and XFS do,
+	// and copies them where it does not. Files are never hard linked, so
+	// that those written can be changed in place.
+	ExportReflinks
+	// ExportHardlinks also hard links the files of projects in git sources to
+	// the source cache, where they cannot be cloned but are on the same
+	// device. git replaces the files of its working tree rather than changing
+	// them in place, but the files written must not be changed in place
+	// either, as that would change the cache too; it must be asked for
+	// explicitly.
+	ExportHardlinks
+)
+
+// exportLinksNames are the names of ExportLinks, as ParseExportLinks takes
+// them.
+var exportLinksNames = []string{
+	ExportCopies:    "copy",
+	ExportReflinks:  "reflink",
+	ExportHardlinks: "hardlink",
+}
+
+func (l ExportLinks) String() string {
+	if l < 0 || int(l) >= len(exportLinksNames) {
+		return fmt.Sprintf("ExportLinks(%d)", int(l))
+	}
+	return exportLinksNames[l]
+}
+
+// ParseExportLinks returns the ExportLinks named s: "copy", "reflink" or
+// "hardlink".
+func ParseExportLinks(s string) (ExportLinks, error) {
+	for l, name := range exportLinksNames {
+		if s == name {
+			return ExportLinks(l), nil
+		}
+	}
+	return ExportCopies, errors.Errorf("%q is not one of copy, reflink or hardlink", s)
+}
+
+// exportLinks is the mode set by SetExportLinks.
+var exportLinks = ExportCopies
+
+// SetExportLinks sets how exports from the source cache write out the files of
+// projects, in WriteDepTree as elsewhere.
+func SetExportLinks(l ExportLinks) {
+	exportLinks = l
+}
+
+// CurrentExportLinks returns the mode set by SetExportLinks.
+func CurrentExportLinks() ExportLinks {
+	return exportLinks
+}
 
 // WriteDepTree takes a basedir, a Lock and a RootPruneOptions and exports all
 // the projects listed in the lock to the appropriate target location within basedir.
@@ -74,8 +183,17 @@ const concurrentWriters = 16
 // It requires a SourceManager to do the work. Prune options are read from the
 // passed manifest.
 //
-// If onWrite is not nil, it will be called after each project write. Calls are ordered and atomic.
+// If onWrite is not nil, it will be called as each project starts to be
+// written, and after each project write. Calls are ordered and atomic.
+//
+// WriteDepTree cannot be cancelled; see WriteDepTreeContext.
 func WriteDepTree(basedir string, l Lock, sm SourceManager, co CascadingPruneOptions, onWrite func(WriteProgress)) error {
+	return WriteDepTreeContext(context.Background(), basedir, l, sm, co, onWrite)
+}
+
+// WriteDepTreeContext is like WriteDepTree, but if ctx is cancelled, the
+// exports under way are abandoned, and basedir is removed.
+func WriteDepTreeContext(ctx context.Context, basedir string, l Lock, sm SourceManager, co CascadingPruneOptions, onWrite func(WriteProgress)) error {
 	if l == nil {
 		return fmt.Errorf("must provide non-nil Lock to WriteDepTree")
 	}
@@ -84,18 +202,19 @@ func WriteDepTree(basedir string, l Lock, sm SourceManager, co CascadingPruneOpt
 		return err
 	}
 
-	g, ctx := errgroup.WithContext(context.TODO())
+	g, ctx := errgroup.WithContext(ctx)
 	lps := l.Projects()
 	sem := make(chan struct{}, concurrentWriters)
 	var cnt struct {
 		sync.Mutex
-		i int
+		started, i int
 	}
 
 	for i := range lps {
 		p := lps[i] // per-iteration copy
 
 		g.Go(func() error {
+			var size uint64
 			err := func() error {
 				select {
 				case sem <- struct{}{}:
@@ -104,6 +223,18 @@ func WriteDepTree(basedir string, l Lock, sm SourceManager, co CascadingPruneOpt
 					return ctx.Err()
 				}
 
+				if onWrite != nil {
+					cnt.Lock()
+					cnt.started++
+					onWrite(WriteProgress{
+						Count: cnt.started,
+						Total: len(lps),
+						LP:    p,
+						Stage: WriteStarted,
+					})
+					cnt.Unlock()
+				}
+
 				ident := p.Ident()
 				projectRoot := string(ident.ProjectRoot)
 				to := filepath.FromSlash(filepath.Join(basedir, projectRoot))
@@ -117,6 +248,11 @@ func WriteDepTree(basedir string, l Lock, sm SourceManager, co CascadingPruneOpt
 					return errors.Wrapf(err, "failed to prune %s", projectRoot)
 				}
 
+				if onWrite != nil {
+					if size, err = fs.DirSize(to); err != nil {
+						return errors.Wrapf(err, "failed to measure %s", projectRoot)
+					}
+				}
 				return ctx.Err()
 			}()
 
@@ -129,10 +265,12 @@ func WriteDepTree(basedir string, l Lock, sm SourceManager, co CascadingPruneOpt
 					cnt.Lock()
 					cnt.i++
 					onWrite(WriteProgress{
-						Count:   cnt.i,
-						Total:   len(lps),
-						LP:      p,
-						Failure: err != nil,
+						Count:        cnt.i,
+						Total:        len(lps),
+						LP:           p,
+						Stage:        WriteExported,
+						BytesWritten: size,
+						Failure:      err != nil,
 					})
 					cnt.Unlock()
 				}
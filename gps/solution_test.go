@@ -5,6 +5,7 @@
 package gps
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"os"
@@ -117,6 +118,25 @@ func testWriteDepTree(t *testing.T) {
 	}
 }
 
+func TestWriteDepTreeContextCancelled(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "writetree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	basedir := filepath.Join(tmp, "vendor")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sm := newdepspecSM(nil, nil)
+	if err := WriteDepTreeContext(ctx, basedir, basicResult, sm, defaultCascadingPruneOptions(), nil); err == nil {
+		t.Fatal("expected an error from a cancelled write")
+	}
+	if _, err := os.Stat(basedir); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", basedir, err)
+	}
+}
+
 func BenchmarkCreateVendorTree(b *testing.B) {
 	// We're fs-bound here, so restrict to single parallelism
 	b.SetParallelism(1)
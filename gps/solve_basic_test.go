@@ -972,6 +972,13 @@ var basicFixtures = map[string]basicFixture{
 		fail: &noVersionError{
 			pn: mkPI("b"),
 			fails: []failedVersion{
+				{
+					v: NewVersion("1.0.0"),
+					f: &constraintNotAllowedFailure{
+						goal: mkDep("b 1.0.0", "a 2.0.0", "a"),
+						v:    NewVersion("1.0.0"),
+					},
+				},
 				{
 					v: NewVersion("2.0.0"),
 					f: &versionNotAllowedFailure{
@@ -980,13 +987,6 @@ var basicFixtures = map[string]basicFixture{
 						c:          mkSVC("1.0.0"),
 					},
 				},
-				{
-					v: NewVersion("1.0.0"),
-					f: &constraintNotAllowedFailure{
-						goal: mkDep("b 1.0.0", "a 2.0.0", "a"),
-						v:    NewVersion("1.0.0"),
-					},
-				},
 			},
 		},
 	},
@@ -1529,6 +1529,24 @@ func (b *depspecBridge) listVersions(id ProjectIdentifier) ([]Version, error) {
 	return vl, nil
 }
 
+func (b *depspecBridge) pairVersion(id ProjectIdentifier, uv UnpairedVersion) (Version, error) {
+	if vl, exists := b.vlists[id]; exists {
+		return findUnpaired(vl, uv), nil
+	}
+
+	pvl, err := b.sm.ListVersions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// As in listVersions, leave off the fake rev.
+	v := findUnpaired(hidePair(pvl), uv)
+	if pv, ok := v.(PairedVersion); ok && pv.Revision() == "FAKEREV" {
+		return pv.Unpair(), nil
+	}
+	return v, nil
+}
+
 // override verifyRoot() on bridge to prevent any filesystem interaction
 func (b *depspecBridge) verifyRootDir(path string) error {
 	root := b.sm.(fixSM).rootSpec()
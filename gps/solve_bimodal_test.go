@@ -1176,14 +1176,6 @@ var bimodalFixtures = map[string]bimodalFixture{
 		fail: &noVersionError{
 			pn: mkPI("baz"),
 			fails: []failedVersion{
-				{
-					v: NewVersion("2.0.0"),
-					f: &versionNotAllowedFailure{
-						goal:       mkAtom("baz 2.0.0"),
-						failparent: []dependency{mkDep("root", "baz 1.0.0", "baz/qux")},
-						c:          NewVersion("1.0.0"),
-					},
-				},
 				{
 					v: NewVersion("1.0.0"),
 					f: &checkeeHasProblemPackagesFailure{
@@ -1198,6 +1190,14 @@ var bimodalFixtures = map[string]bimodalFixture{
 						},
 					},
 				},
+				{
+					v: NewVersion("2.0.0"),
+					f: &versionNotAllowedFailure{
+						goal:       mkAtom("baz 2.0.0"),
+						failparent: []dependency{mkDep("root", "baz 1.0.0", "baz/qux")},
+						c:          NewVersion("1.0.0"),
+					},
+				},
 			},
 		},
 	},
@@ -1217,14 +1217,6 @@ var bimodalFixtures = map[string]bimodalFixture{
 		fail: &noVersionError{
 			pn: mkPI("baz"),
 			fails: []failedVersion{
-				{
-					v: NewVersion("2.0.0"),
-					f: &versionNotAllowedFailure{
-						goal:       mkAtom("baz 2.0.0"),
-						failparent: []dependency{mkDep("foo 1.0.0", "baz 1.0.0", "baz")},
-						c:          NewVersion("1.0.0"),
-					},
-				},
 				{
 					v: NewVersion("1.0.0"),
 					f: &checkeeHasProblemPackagesFailure{
@@ -1239,6 +1231,14 @@ var bimodalFixtures = map[string]bimodalFixture{
 						},
 					},
 				},
+				{
+					v: NewVersion("2.0.0"),
+					f: &versionNotAllowedFailure{
+						goal:       mkAtom("baz 2.0.0"),
+						failparent: []dependency{mkDep("foo 1.0.0", "baz 1.0.0", "baz")},
+						c:          NewVersion("1.0.0"),
+					},
+				},
 			},
 		},
 	},
@@ -1258,14 +1258,6 @@ var bimodalFixtures = map[string]bimodalFixture{
 		fail: &noVersionError{
 			pn: mkPI("baz"),
 			fails: []failedVersion{
-				{
-					v: NewVersion("2.0.0"),
-					f: &versionNotAllowedFailure{
-						goal:       mkAtom("baz 2.0.0"),
-						failparent: []dependency{mkDep("foo 1.0.0", "baz 1.0.0", "baz")},
-						c:          NewVersion("1.0.0"),
-					},
-				},
 				{
 					v: NewVersion("1.0.0"),
 					f: &checkeeHasProblemPackagesFailure{
@@ -1280,6 +1272,14 @@ var bimodalFixtures = map[string]bimodalFixture{
 						},
 					},
 				},
+				{
+					v: NewVersion("2.0.0"),
+					f: &versionNotAllowedFailure{
+						goal:       mkAtom("baz 2.0.0"),
+						failparent: []dependency{mkDep("foo 1.0.0", "baz 1.0.0", "baz")},
+						c:          NewVersion("1.0.0"),
+					},
+				},
 			},
 		},
 	},
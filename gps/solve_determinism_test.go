@@ -0,0 +1,100 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// determinismRuns is the number of times each fixture is solved, with its
+// inputs shuffled, when checking for determinism.
+const determinismRuns = 10
+
+// TestSolveDeterminism checks that solving is insensitive to the order in
+// which a SourceManager reports projects and versions. On real systems, that
+// order varies by platform, filesystem and VCS - e.g. ls-remote output versus
+// directory listings. Every fixture that solves successfully must yield exactly
+// the same solution on each run.
+func TestSolveDeterminism(t *testing.T) {
+	names := make([]string, 0, len(basicFixtures))
+	for n := range basicFixtures {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		fix := basicFixtures[n]
+		if fix.fail != nil || fix.broken != "" {
+			continue
+		}
+
+		n := n
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+			rnd := rand.New(rand.NewSource(int64(len(n))))
+
+			var want string
+			for i := 0; i < determinismRuns; i++ {
+				got, err := solveShuffled(fix, rnd)
+				if err != nil {
+					t.Fatalf("run %d: unexpected solve error: %s", i, err)
+				}
+
+				if i == 0 {
+					want = got
+				} else if got != want {
+					t.Fatalf("run %d produced a different solution:\n(GOT):\n%s\n(WNT):\n%s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// solveShuffled solves the fixture after shuffling the order of its non-root
+// depspecs, and returns a canonical rendering of the resulting solution.
+func solveShuffled(fix basicFixture, rnd *rand.Rand) (string, error) {
+	ds := make([]depspec, len(fix.ds))
+	copy(ds, fix.ds)
+	rnd.Shuffle(len(ds)-1, func(i, j int) {
+		ds[i+1], ds[j+1] = ds[j+1], ds[i+1]
+	})
+
+	params := SolveParameters{
+		RootDir:         string(ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		Lock:            dummyLock{},
+		Downgrade:       fix.downgrade,
+		ChangeAll:       fix.changeall,
+		ToChange:        fix.changelist,
+		ProjectAnalyzer: naiveAnalyzer{},
+		stdLibFn:        func(string) bool { return false },
+		mkBridgeFn:      overrideMkBridge,
+	}
+	if fix.l != nil {
+		params.Lock = fix.l
+	}
+
+	s, err := Prepare(params, newdepspecSM(ds, nil))
+	if err != nil {
+		return "", err
+	}
+	soln, err := s.Solve(context.Background())
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	for _, lp := range soln.Projects() {
+		rev, branch, version := VersionComponentStrings(lp.Version())
+		fmt.Fprintf(&buf, "%s %s %s %s %v\n", lp.Ident(), rev, branch, version, lp.Packages())
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// memCheckInterval is the number of solving loop iterations between checks of
+// heap usage against the memory budget. Reading memory statistics briefly stops
+// the world, so it is not done on every iteration.
+const memCheckInterval = 256
+
+// MemoryBudgetError is returned from a solve run when heap usage exceeds the
+// MemoryBudget given in SolveParameters, even after the solver has discarded
+// everything it can.
+type MemoryBudgetError struct {
+	// Budget is the budget that was exceeded, in bytes.
+	Budget uint64
+	// InUse is the heap usage observed when solving was aborted, in bytes.
+	InUse uint64
+	// Attempts is the number of solving attempts made before aborting.
+	Attempts int
+}
+
+func (e *MemoryBudgetError) Error() string {
+	return fmt.Sprintf("solving aborted after %d attempts: heap usage of %d bytes exceeds memory budget of %d bytes", e.Attempts, e.InUse, e.Budget)
+}
+
+// checkMemory periodically compares heap usage against the solver's memory
+// budget. When over budget, local caches are shed and memory is returned to the
+// runtime before giving up.
+func (s *solver) checkMemory() error {
+	if s.memBudget == 0 {
+		return nil
+	}
+
+	s.sinceMemCheck++
+	if s.sinceMemCheck < memCheckInterval {
+		return nil
+	}
+	s.sinceMemCheck = 0
+
+	if inuse := heapInUse(); inuse <= s.memBudget {
+		return nil
+	}
+
+	s.traceInfo("heap usage over memory budget, dropping caches")
+	s.b.dropCaches()
+	s.reaches = make(map[atom]*packageReach)
+	s.strs = make(interner)
+	debug.FreeOSMemory()
+
+	if inuse := heapInUse(); inuse > s.memBudget {
+		return &MemoryBudgetError{
+			Budget:   s.memBudget,
+			InUse:    inuse,
+			Attempts: s.attempts,
+		}
+	}
+	return nil
+}
+
+func heapInUse() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapInuse
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCheckMemory(t *testing.T) {
+	s := &solver{}
+	s.b = mkBridge(s, nil, false)
+	s.b.(*bridge).vlists[mkPI("foo")] = []Version{NewVersion("v1.0.0")}
+
+	// No budget never errors.
+	for i := 0; i < memCheckInterval*2; i++ {
+		if err := s.checkMemory(); err != nil {
+			t.Fatalf("unexpected error with no memory budget: %v", err)
+		}
+	}
+
+	// An unreasonably small budget is only checked every memCheckInterval
+	// iterations, and sheds caches before failing.
+	s.memBudget = 1
+	for i := 0; i < memCheckInterval-1; i++ {
+		if err := s.checkMemory(); err != nil {
+			t.Fatalf("memory checked before interval elapsed, on iteration %d", i)
+		}
+	}
+
+	err := s.checkMemory()
+	if _, ok := err.(*MemoryBudgetError); !ok {
+		t.Fatalf("expected a *MemoryBudgetError, got %#v", err)
+	}
+	if len(s.b.(*bridge).vlists) != 0 {
+		t.Error("expected bridge caches to be dropped when over budget")
+	}
+}
+
+// largeGraphFixture returns a bimodal fixture of n projects, each with the
+// given number of versions and of packages beside its root package. The
+// packages of each project import those of the next few projects, so that
+// solving it reaches every project through many packages.
+func largeGraphFixture(n, versions, pkgs int) bimodalFixture {
+	name := func(i int) string { return fmt.Sprintf("github.com/large/p%03d", i) }
+
+	root := dsp(mkDepspec("root 0.0.0"), pkg("root", name(0), name(1), name(2)))
+	fix := bimodalFixture{ds: []depspec{root}, r: map[ProjectIdentifier]LockedProject{}}
+	for i := 0; i < n; i++ {
+		var imports []string
+		for j := i + 1; j < n && j <= i+3; j++ {
+			imports = append(imports, fmt.Sprintf("%s/s%d", name(j), (i+j)%pkgs))
+		}
+
+		var tpkgs []tpkg
+		var rootImports []string
+		for k := 0; k < pkgs; k++ {
+			sub := fmt.Sprintf("%s/s%d", name(i), k)
+			rootImports = append(rootImports, sub)
+			tpkgs = append(tpkgs, pkg(sub, imports...))
+		}
+		tpkgs = append(tpkgs, pkg(name(i), rootImports...))
+
+		for v := 0; v < versions; v++ {
+			fix.ds = append(fix.ds, dsp(mkDepspec(fmt.Sprintf("%s 1.%d.0", name(i), v)), tpkgs...))
+		}
+	}
+	return fix
+}
+
+func BenchmarkSolveLargeGraph(b *testing.B) {
+	fix := largeGraphFixture(600, 4, 6)
+	sm := newbmSM(fix)
+	params := SolveParameters{
+		RootDir:         string(fix.ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		Lock:            dummyLock{},
+		ProjectAnalyzer: naiveAnalyzer{},
+		stdLibFn:        func(string) bool { return false },
+		mkBridgeFn:      overrideMkBridge,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s, err := Prepare(params, sm)
+		if err != nil {
+			b.Fatal(err)
+		}
+		soln, err := s.Solve(context.Background())
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(soln.Projects()) != 600 {
+			b.Fatalf("expected 600 projects in the solution, got %d", len(soln.Projects()))
+		}
+	}
+}
@@ -260,3 +260,83 @@ func TestRootLockNoVersionPairMatching(t *testing.T) {
 
 	fixtureSolveSimpleChecks(fix, res, err, t)
 }
+
+func TestExactConstraintSkipsVersionList(t *testing.T) {
+	fix := basicFixtures["revision injected into vqueue"]
+	sm := newdepspecSM(fix.ds, nil)
+
+	params := SolveParameters{
+		RootDir:         string(fix.ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		ProjectAnalyzer: naiveAnalyzer{},
+		TraceLogger:     log.New(test.Writer{TB: t}, "", 0),
+		stdLibFn:        func(string) bool { return false },
+		mkBridgeFn:      overrideMkBridge,
+	}
+
+	s, err := Prepare(params, sm)
+	if err != nil {
+		t.Fatalf("Unexpected error while prepping solver: %s", err)
+	}
+
+	res, err := s.Solve(context.Background())
+	fixtureSolveSimpleChecks(fix, res, err, t)
+
+	if _, has := s.(*solver).b.(*depspecBridge).vlists[mkPI("foo")]; has {
+		t.Error("expected version list for foo not to be retrieved when pinned to a revision")
+	}
+}
+
+func TestExactVersionSkipsVersionSort(t *testing.T) {
+	fixtures := map[string]basicFixture{
+		"semver": {
+			ds: []depspec{
+				mkDepspec("root 0.0.0", "foo 1.0.0"),
+				mkDepspec("foo 1.0.0 foorev"),
+				mkDepspec("foo 2.0.0 foorev2"),
+			},
+			r: mksolution(
+				"foo 1.0.0 foorev",
+			),
+		},
+		"plain version": {
+			ds: []depspec{
+				mkDepspec("root 0.0.0", "foo pv1"),
+				mkDepspec("foo pv1 foorev"),
+				mkDepspec("foo pv2 foorev2"),
+			},
+			r: mksolution(
+				"foo pv1 foorev",
+			),
+		},
+	}
+
+	for name, fix := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			sm := newdepspecSM(fix.ds, nil)
+
+			params := SolveParameters{
+				RootDir:         string(fix.ds[0].n),
+				RootPackageTree: fix.rootTree(),
+				Manifest:        fix.rootmanifest(),
+				ProjectAnalyzer: naiveAnalyzer{},
+				TraceLogger:     log.New(test.Writer{TB: t}, "", 0),
+				stdLibFn:        func(string) bool { return false },
+				mkBridgeFn:      overrideMkBridge,
+			}
+
+			s, err := Prepare(params, sm)
+			if err != nil {
+				t.Fatalf("Unexpected error while prepping solver: %s", err)
+			}
+
+			res, err := s.Solve(context.Background())
+			fixtureSolveSimpleChecks(fix, res, err, t)
+
+			if _, has := s.(*solver).b.(*depspecBridge).vlists[mkPI("foo")]; has {
+				t.Error("expected version list for foo not to be sorted when pinned to an exact version")
+			}
+		})
+	}
+}
@@ -97,6 +97,36 @@ type SolveParameters struct {
 	// solving process.
 	TraceLogger *log.Logger
 
+	// MemoryBudget, if non-zero, is the number of bytes of heap the solver
+	// should try to stay within. When the budget is exceeded, the solver first
+	// discards its local caches; if that does not bring heap usage back under
+	// budget, solving fails with an error rather than exhausting memory.
+	MemoryBudget uint64
+
+	// Yanked lists versions that the solver must not select. Versions in the
+	// lock that have been yanked are not preferred, and the solve fails if
+	// only yanked versions satisfy the constraints on a project.
+	Yanked YankedVersions
+
+	// ImportPolicies restrict the projects the solver may select. A project
+	// is only selected if every policy permits it.
+	ImportPolicies []ImportPolicy
+
+	// Hints, if set, holds candidates rejected by earlier solves because
+	// their manifests or packages could not be read. The solver skips those
+	// candidates, and records in Hints any further ones it rejects for the
+	// same reason, so that Hints may be saved and passed to a later solve.
+	// Hints recorded with a different analyzer are discarded.
+	Hints *SolveHints
+
+	// Aliases maps import path roots to the roots of the projects that serve
+	// them. Imports beneath an alias are ignored while solving, and if its
+	// project is selected, the solution also holds the alias, at the same
+	// version and from the same source, with all of the project's packages.
+	// This bridges a project's move to a new import path while some
+	// dependencies still import the old one.
+	Aliases map[ProjectRoot]ProjectRoot
+
 	// stdLibFn is the function to use to recognize standard library import paths.
 	// Only overridden for tests. Defaults to paths.IsStandardImportPath if nil.
 	stdLibFn func(string) bool
@@ -160,6 +190,32 @@ type solver struct {
 	// metrics for the current solve run.
 	mtr *metrics
 
+	// The heap budget for the solve run, in bytes, or 0 for no budget.
+	memBudget uint64
+
+	// Versions that must not be selected.
+	yanked YankedVersions
+
+	// Import policies that every selected project must be permitted by.
+	policies []ImportPolicy
+
+	// Candidates rejected by this or earlier solves because their contents
+	// could not be read.
+	hints *SolveHints
+
+	// Import path roots that are served by the project with another root.
+	aliases map[ProjectRoot]ProjectRoot
+
+	// The number of solving loop iterations since heap usage was last checked.
+	sinceMemCheck int
+
+	// The package reachability of each atom the solver has checked, so that
+	// it is computed once per atom rather than on every check.
+	reaches map[atom]*packageReach
+
+	// Interns the import paths and project roots the solver keeps.
+	strs interner
+
 	// Indicates whether the solver has been run. It is invalid to run this type
 	// of solver more than once.
 	hasrun int32
@@ -198,6 +254,14 @@ func (params SolveParameters) toRootdata() (rootdata, error) {
 		an:      params.ProjectAnalyzer,
 	}
 
+	if len(params.Aliases) > 0 {
+		ir, err := aliasIgnores(params.Aliases, rd.ir, params.Manifest)
+		if err != nil {
+			return rootdata{}, err
+		}
+		rd.ir = ir
+	}
+
 	// Ensure the required and overrides maps are at least initialized
 	if rd.req == nil {
 		rd.req = make(map[string]bool)
@@ -226,7 +290,7 @@ func (params SolveParameters) toRootdata() (rootdata, error) {
 	// Validate no empties in the overrides map
 	var eovr []string
 	for pr, pp := range rd.ovr {
-		if pp.Constraint == nil && pp.Source == "" {
+		if pp.Constraint == nil && pp.Source == "" && pp.RootDir == "" {
 			eovr = append(eovr, string(pr))
 		}
 	}
@@ -285,9 +349,20 @@ func Prepare(params SolveParameters, sm SourceManager) (Solver, error) {
 	}
 
 	s := &solver{
-		tl:       params.TraceLogger,
-		stdLibFn: params.stdLibFn,
-		rd:       rd,
+		tl:        params.TraceLogger,
+		stdLibFn:  params.stdLibFn,
+		rd:        rd,
+		memBudget: params.MemoryBudget,
+		yanked:    params.Yanked,
+		policies:  params.ImportPolicies,
+		hints:     params.Hints,
+		aliases:   params.Aliases,
+		reaches:   make(map[atom]*packageReach),
+		strs:      make(interner),
+	}
+
+	if s.hints != nil && !s.hints.Matches(rd.an.Info()) {
+		*s.hints = *NewSolveHints(rd.an.Info())
 	}
 
 	// Set up the bridge and ensure the root dir is in good, working order
@@ -438,6 +513,10 @@ func (s *solver) Solve(ctx context.Context) (Solution, error) {
 	}
 
 	all, err := s.solve(ctx)
+	var alp []LockedProject
+	if err == nil && len(s.aliases) > 0 {
+		alp, err = s.aliasProjects(all)
+	}
 
 	s.mtr.pop()
 	var soln solution
@@ -461,6 +540,10 @@ func (s *solver) Solve(ctx context.Context) (Solution, error) {
 
 			soln.p = append(soln.p, lp)
 		}
+		soln.p = append(soln.p, alp...)
+		sort.Slice(soln.p, func(i, j int) bool {
+			return soln.p[i].Ident().Less(soln.p[j].Ident())
+		})
 	}
 
 	s.traceFinish(soln, err)
@@ -484,6 +567,10 @@ func (s *solver) solve(ctx context.Context) (map[atom]map[string]struct{}, error
 		default:
 		}
 
+		if err := s.checkMemory(); err != nil {
+			return nil, err
+		}
+
 		bmi, has := s.nextUnselected()
 
 		if !has {
@@ -585,6 +672,11 @@ func (s *solver) solve(ctx context.Context) (map[atom]map[string]struct{}, error
 		}
 	}
 
+	// The version queues are done with; return their slices to the pool.
+	for _, vq := range s.vqs {
+		vq.release()
+	}
+
 	// Getting this far means we successfully found a solution. Combine the
 	// selected projects and packages.
 	projs := make(map[atom]map[string]struct{})
@@ -653,51 +745,62 @@ func (s *solver) getImportsAndConstraintsOf(a atomWithPackages) ([]string, []com
 	// information.
 	m, _, err := s.b.GetManifestAndLock(a.a.id, a.a.v, s.rd.an)
 	if err != nil {
+		s.hintUnreadable(a.a, err)
 		return nil, nil, err
 	}
 
-	ptree, err := s.b.ListPackages(a.a.id, a.a.v)
+	pr, err := s.packageReach(a.a)
 	if err != nil {
+		s.hintUnreadable(a.a, err)
 		return nil, nil, err
 	}
 
-	rm, em := ptree.ToReachMap(true, false, true, s.rd.ir)
-	// Use maps to dedupe the unique internal and external packages.
-	exmap, inmap := make(map[string]struct{}), make(map[string]struct{})
-
+	// Collect the internal packages reached by those explicitly listed in the
+	// atom, as well as the listed packages themselves.
+	in := make(bitset, (len(pr.pkgs)+63)/64)
+	var missing int
 	for _, pkg := range a.pl {
-		inmap[pkg] = struct{}{}
-		for _, ipkg := range rm[pkg].Internal {
-			inmap[ipkg] = struct{}{}
+		if i, has := pr.index(pkg); has {
+			in.union(pr.internal[i])
+		} else {
+			missing++
 		}
 	}
 
 	var pl []string
-	// If lens are the same, then the map must have the same contents as the
-	// slice; no need to build a new one.
-	if len(inmap) == len(a.pl) {
+	// If the counts are the same, then the set must have the same contents as
+	// the slice; no need to build a new one.
+	if in.count()+missing == len(a.pl) {
 		pl = a.pl
 	} else {
-		pl = make([]string, 0, len(inmap))
-		for pkg := range inmap {
-			pl = append(pl, pkg)
+		pl = make([]string, 0, in.count()+missing)
+		for i, pkg := range pr.pkgs {
+			if in.has(i) {
+				pl = append(pl, pkg)
+			}
+		}
+		for _, pkg := range a.pl {
+			if _, has := pr.index(pkg); !has {
+				pl = append(pl, pkg)
+			}
 		}
 		sort.Strings(pl)
 	}
 
 	// Add to the list those packages that are reached by the packages
 	// explicitly listed in the atom
+	var reach []string
 	for _, pkg := range a.pl {
 		// Skip ignored packages
 		if s.rd.ir.IsIgnored(pkg) {
 			continue
 		}
 
-		ie, exists := rm[pkg]
+		i, exists := pr.index(pkg)
 		if !exists {
 			// Missing package here *should* only happen if the target pkg was
 			// poisoned; check the errors map.
-			if importErr, eexists := em[pkg]; eexists {
+			if importErr, eexists := pr.errs[pkg]; eexists {
 				return nil, nil, importErr
 			}
 
@@ -705,16 +808,9 @@ func (s *solver) getImportsAndConstraintsOf(a atomWithPackages) ([]string, []com
 			return nil, nil, fmt.Errorf("package %s does not exist within project %s", pkg, a.a.id)
 		}
 
-		for _, ex := range ie.External {
-			exmap[ex] = struct{}{}
-		}
-	}
-
-	reach := make([]string, 0, len(exmap))
-	for pkg := range exmap {
-		reach = append(reach, pkg)
+		reach = append(reach, pr.external[i]...)
 	}
-	sort.Strings(reach)
+	reach = dedupeSorted(reach)
 
 	deps := s.rd.ovr.overrideAll(m.DependencyConstraints())
 	cd, err := s.intersectConstraintsWithImports(deps, reach)
@@ -766,6 +862,7 @@ func (s *solver) intersectConstraintsWithImports(deps []workingConstraint, reach
 			// Nothing we can do if we can't suss out a root
 			return nil, err
 		}
+		root = ProjectRoot(s.strs.intern(string(root)))
 
 		// Make a new completeDep with an open constraint, respecting overrides
 		pd := s.rd.ovr.override(root, ProjectProperties{Constraint: Any()})
@@ -786,9 +883,46 @@ func (s *solver) intersectConstraintsWithImports(deps []workingConstraint, reach
 		cdeps = append(cdeps, cdep)
 	}
 
+	// Sort them, so that map iteration order cannot leak into solving.
+	sort.Slice(cdeps, func(i, j int) bool {
+		return cdeps[i].Ident.Less(cdeps[j].Ident)
+	})
+
 	return cdeps, nil
 }
 
+// packageReach returns the package reachability of the atom a, computing it
+// on first use.
+func (s *solver) packageReach(a atom) (*packageReach, error) {
+	if pr, has := s.reaches[a]; has {
+		return pr, nil
+	}
+
+	ptree, err := s.b.ListPackages(a.id, a.v)
+	if err != nil {
+		return nil, err
+	}
+
+	pr := newPackageReach(ptree, s.rd.ir, s.strs)
+	s.reaches[a] = pr
+	return pr, nil
+}
+
+// dedupeSorted sorts l and removes its duplicates, in place.
+func dedupeSorted(l []string) []string {
+	if len(l) == 0 {
+		return l
+	}
+	sort.Strings(l)
+	out := l[:1]
+	for _, s := range l[1:] {
+		if s != out[len(out)-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
 func (s *solver) createVersionQueue(bmi bimodalIdentifier) (*versionQueue, error) {
 	id := bmi.id
 	// If on the root package, there's no queue to make
@@ -869,6 +1003,25 @@ func (s *solver) createVersionQueue(bmi bimodalIdentifier) (*versionQueue, error
 		prefv = bmi.prefv
 	}
 
+	// If the project is pinned to exactly one version, nothing else could
+	// possibly be selected, so seed the queue with it rather than paying the
+	// cost of retrieving and sorting the full version list. If it fails, the
+	// queue will fall back to loading the full list, as it would for a lock.
+	//
+	// A bare version still has to be paired with its revision, which takes the
+	// unsorted version list; if it is not in there, the queue is left to load
+	// the list and fail as usual.
+	if lockv == nil && prefv == nil {
+		if ev, has := s.exactVersion(id); has {
+			if uv, ok := ev.(UnpairedVersion); ok {
+				ev, _ = s.b.pairVersion(id, uv)
+			}
+			if ev != nil {
+				prefv = ev
+			}
+		}
+	}
+
 	q, err := newVersionQueue(id, lockv, prefv, s.b)
 	if err != nil {
 		// TODO(sdboyer) this particular err case needs to be improved to be ONLY for cases
@@ -902,6 +1055,34 @@ func (s *solver) createVersionQueue(bmi bimodalIdentifier) (*versionQueue, error
 	return q, s.findValidVersion(q, bmi.pl)
 }
 
+// exactVersion reports whether the current constraint on the given project
+// admits exactly one version - a bare revision, a version paired with one, an
+// exact semver version or a plain version - and returns that version if so.
+func (s *solver) exactVersion(id ProjectIdentifier) (Version, bool) {
+	switch tc := s.sel.getConstraint(id).(type) {
+	case Revision:
+		return tc, true
+	case PairedVersion:
+		return tc, true
+	case semVersion:
+		return tc, true
+	case plainVersion:
+		return tc, true
+	}
+	return nil, false
+}
+
+// pinnedToRevision reports whether the project's exact version, if it has one,
+// comes with its revision, so that no version list is needed to select it.
+func (s *solver) pinnedToRevision(id ProjectIdentifier) bool {
+	ev, has := s.exactVersion(id)
+	if !has {
+		return false
+	}
+	_, unpaired := ev.(UnpairedVersion)
+	return !unpaired
+}
+
 // findValidVersion walks through a versionQueue until it finds a version that
 // satisfies the constraints held in the current state of the solver.
 //
@@ -1030,6 +1211,7 @@ func (s *solver) backtrack(ctx context.Context) (bool, error) {
 				break
 			}
 
+			s.vqs[len(s.vqs)-1].release()
 			s.vqs, s.vqs[len(s.vqs)-1] = s.vqs[:len(s.vqs)-1], nil
 
 			// Pop selections off until we get to a project.
@@ -1098,6 +1280,7 @@ func (s *solver) backtrack(ctx context.Context) (bool, error) {
 		// No solution found; continue backtracking after popping the queue
 		// we just inspected off the list
 		// GC-friendly pop pointer elem in slice
+		q.release()
 		s.vqs, s.vqs[len(s.vqs)-1] = s.vqs[:len(s.vqs)-1], nil
 	}
 
@@ -1158,11 +1341,25 @@ func (s *solver) unselectedComparator(i, j int) bool {
 		return iname.Less(jname)
 	}
 
+	// Projects pinned to a revision also skip retrieving their version list
+	// when their version queue is made, so sort them next, by name, rather
+	// than triggering that retrieval here.
+	iexact, jexact := s.pinnedToRevision(iname), s.pinnedToRevision(jname)
+
+	switch {
+	case iexact && !jexact:
+		return true
+	case !iexact && jexact:
+		return false
+	case iexact && jexact:
+		return iname.Less(jname)
+	}
+
 	// Now, sort by number of available versions. This will trigger network
 	// activity, but at this point we know that the project we're looking at
-	// isn't locked by the root. And, because being locked by root is the only
-	// way avoid that call when making a version queue, we know we're gonna have
-	// to pay that cost anyway.
+	// isn't locked by the root or pinned to an exact version. And, because
+	// those are the only ways to avoid that call when making a version queue,
+	// we know we're gonna have to pay that cost anyway.
 
 	// We can safely ignore an err from listVersions here because, if there is
 	// an actual problem, it'll be noted and handled somewhere else saner in the
@@ -9,7 +9,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/golang/dep/gps/pkgtree"
 	"github.com/pkg/errors"
@@ -68,12 +70,24 @@ type sourceCoordinator struct {
 	protoSrcs  map[string][]chan srcReturn
 	cachedir   string
 	cache      sourceCache
+	backend    CacheBackend
+	readOnly   bool
 	logger     *log.Logger
+	// platforms are those for which packages are listed; every platform if
+	// empty.
+	platforms []pkgtree.Platform
+	// fallbacks maps normalized source names to the sources, in order, that
+	// are tried when theirs cannot be reached, or fails. fellBack holds the gateway
+	// chosen for each, and servedBy the name of its source, when it is one of
+	// the fallbacks; both are guarded by srcmut.
+	fallbacks map[string][]string
+	fellBack  map[string]*sourceGateway
+	servedBy  map[string]string
 }
 
 // newSourceCoordinator returns a new sourceCoordinator.
-// Passing a nil sourceCache defaults to an in-memory cache.
-func newSourceCoordinator(superv *supervisor, deducer deducer, cachedir string, cache sourceCache, logger *log.Logger) *sourceCoordinator {
+// Passing a nil sourceCache defaults to an in-memory cache. backend may be nil.
+func newSourceCoordinator(superv *supervisor, deducer deducer, cachedir string, cache sourceCache, backend CacheBackend, logger *log.Logger) *sourceCoordinator {
 	if cache == nil {
 		cache = memoryCache{}
 	}
@@ -82,10 +96,24 @@ func newSourceCoordinator(superv *supervisor, deducer deducer, cachedir string,
 		deducer:    deducer,
 		cachedir:   cachedir,
 		cache:      cache,
+		backend:    backend,
 		logger:     logger,
 		srcs:       make(map[string]*sourceGateway),
 		nameToURL:  make(map[string]string),
 		protoSrcs:  make(map[string][]chan srcReturn),
+		fellBack:   make(map[string]*sourceGateway),
+		servedBy:   make(map[string]string),
+	}
+}
+
+// setFallbacks records the fallback sources of the sources of the identifiers
+// in fallbacks.
+func (sc *sourceCoordinator) setFallbacks(fallbacks map[ProjectIdentifier][]string) {
+	sc.fallbacks = make(map[string][]string, len(fallbacks))
+	for id, srcs := range fallbacks {
+		if len(srcs) > 0 {
+			sc.fallbacks[id.normalizedSource()] = srcs
+		}
 	}
 }
 
@@ -95,7 +123,126 @@ func (sc *sourceCoordinator) close() {
 	}
 }
 
+// getSourceGatewayFor returns the gateway for the source of id. If that source
+// has fallbacks, it is the gateway for the first of the source and its
+// fallbacks, in order, whose upstream can be reached.
 func (sc *sourceCoordinator) getSourceGatewayFor(ctx context.Context, id ProjectIdentifier) (*sourceGateway, error) {
+	name := id.normalizedSource()
+	fallbacks := sc.fallbacks[name]
+	if len(fallbacks) == 0 {
+		return sc.getSingleSourceGatewayFor(ctx, id)
+	}
+
+	sc.srcmut.RLock()
+	srcGate, has := sc.fellBack[name]
+	sc.srcmut.RUnlock()
+	if has {
+		return srcGate, nil
+	}
+
+	var errs errorSlice
+	for i, src := range append([]string{name}, fallbacks...) {
+		srcGate, err := sc.getSingleSourceGatewayFor(ctx, ProjectIdentifier{ProjectRoot: id.ProjectRoot, Source: src})
+		if err == nil {
+			// A local copy of the source is not enough: what is fetched
+			// must come from an upstream that can be reached.
+			err = srcGate.existsUpstream(ctx)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			errs = append(errs, errors.Wrapf(err, "failed to reach %s", src))
+			continue
+		}
+
+		sc.srcmut.Lock()
+		defer sc.srcmut.Unlock()
+		// Concurrent calls may each have found a source; the first to be
+		// recorded is kept, so that all are served from the same one.
+		if chosen, has := sc.fellBack[name]; has {
+			return chosen, nil
+		}
+		sc.fellBack[name] = srcGate
+		if i > 0 {
+			sc.servedBy[name] = src
+		}
+		return srcGate, nil
+	}
+	return nil, errs
+}
+
+// withSourceGateway runs f with the gateway for the source of id. If that
+// source has fallbacks, and f fails with the gateway chosen for it, f is run
+// again with the gateway of each of the sources after the chosen one, in
+// order, and the first with which f succeeds is chosen in its place. Each
+// retry is logged. Failures to analyze what was retrieved are not retried, as
+// they are the same whichever source serves the revision.
+func (sc *sourceCoordinator) withSourceGateway(ctx context.Context, id ProjectIdentifier, f func(*sourceGateway) error) error {
+	srcGate, err := sc.getSourceGatewayFor(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = f(srcGate)
+	name := id.normalizedSource()
+	fallbacks := sc.fallbacks[name]
+	if err == nil || len(fallbacks) == 0 || ctx.Err() != nil || contextCanceledOrSMReleased(err) || isAnalysisError(err) {
+		return err
+	}
+
+	srcs := append([]string{name}, fallbacks...)
+	sc.srcmut.RLock()
+	from := sc.servedBy[name]
+	sc.srcmut.RUnlock()
+	var i int
+	for j, src := range srcs {
+		if src == from {
+			i = j
+		}
+	}
+
+	errs := errorSlice{errors.Wrapf(err, "failed with %s", srcs[i])}
+	for _, src := range srcs[i+1:] {
+		sc.logger.Printf("failed to use %s for %s, retrying with %s: %v\n", srcs[i], id.ProjectRoot, src, err)
+		srcGate, err = sc.getSingleSourceGatewayFor(ctx, ProjectIdentifier{ProjectRoot: id.ProjectRoot, Source: src})
+		if err == nil {
+			err = f(srcGate)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			errs = append(errs, errors.Wrapf(err, "failed with %s", src))
+			i++
+			continue
+		}
+
+		sc.srcmut.Lock()
+		sc.fellBack[name] = srcGate
+		sc.servedBy[name] = src
+		sc.srcmut.Unlock()
+		return nil
+	}
+	return errs
+}
+
+// servedFrom returns the fallback source from which the source of id is
+// served, if it is served from one. It reports false if the source of id has
+// fallbacks, but none has been chosen yet.
+func (sc *sourceCoordinator) servedFrom(id ProjectIdentifier) (string, bool) {
+	name := id.normalizedSource()
+	if len(sc.fallbacks[name]) == 0 {
+		return "", true
+	}
+	sc.srcmut.RLock()
+	defer sc.srcmut.RUnlock()
+	if _, has := sc.fellBack[name]; !has {
+		return "", false
+	}
+	return sc.servedBy[name], true
+}
+
+func (sc *sourceCoordinator) getSingleSourceGatewayFor(ctx context.Context, id ProjectIdentifier) (*sourceGateway, error) {
 	if err := sc.supervisor.ctx.Err(); err != nil {
 		return nil, err
 	}
@@ -228,8 +375,9 @@ func (sc *sourceCoordinator) getSourceGatewayFor(ctx context.Context, id Project
 		src, err := m.try(ctx, sc.cachedir)
 		if err == nil {
 			cache := sc.cache.newSingleSourceCache(id)
-			srcGate, err = newSourceGateway(ctx, src, sc.supervisor, sc.cachedir, cache)
+			srcGate, err = newSourceGateway(ctx, src, sc.supervisor, sc.cachedir, cache, sc.backend, sc.readOnly)
 			if err == nil {
+				srcGate.platforms = sc.platforms
 				sc.srcs[url] = srcGate
 				break
 			}
@@ -266,13 +414,52 @@ type sourceGateway struct {
 	srcState sourceState
 	src      source
 	cache    singleSourceCache
+	backend  CacheBackend
+	readOnly bool
 	mu       sync.Mutex // global lock, serializes all behaviors
 	suprvsr  *supervisor
+	// platforms are those for which packages are listed. The package trees
+	// in the cache are listed for every platform, so if there are any, the
+	// trees listed for them are kept in localTrees instead.
+	platforms []pkgtree.Platform
+	// localTrees, and localManifests, hold what is listed and analyzed for
+	// this process only: the package trees listed for platforms, and
+	// anything from a subdirectory of the source, as the cache is keyed by
+	// revision alone.
+	localTrees     map[localKey]pkgtree.PackageTree
+	localManifests map[localKey]manifestAndLock
+}
+
+// localKey identifies what is held in a sourceGateway's local maps.
+type localKey struct {
+	r   Revision
+	dir string
+	an  ProjectAnalyzerInfo
+}
+
+type manifestAndLock struct {
+	m Manifest
+	l Lock
 }
 
 // newSourceGateway returns a new gateway for src. If the source exists locally,
-// the local state may be cleaned, otherwise we ping upstream.
-func newSourceGateway(ctx context.Context, src source, superv *supervisor, cachedir string, cache singleSourceCache) (*sourceGateway, error) {
+// the local state may be cleaned, otherwise we ping upstream. If backend is not
+// nil, a missing local copy of the source is first sought there.
+//
+// A readOnly sourceGateway never makes a local copy of the source: it serves
+// what is in cache, and whatever can be read from upstream without a local
+// copy, such as the versions of a git source.
+func newSourceGateway(ctx context.Context, src source, superv *supervisor, cachedir string, cache singleSourceCache, backend CacheBackend, readOnly bool) (*sourceGateway, error) {
+	if readOnly {
+		return &sourceGateway{
+			src:      src,
+			cachedir: cachedir,
+			cache:    cache,
+			suprvsr:  superv,
+			readOnly: true,
+		}, nil
+	}
+
 	var state sourceState
 	local := src.existsLocally(ctx)
 	if local {
@@ -289,6 +476,7 @@ func newSourceGateway(ctx context.Context, src source, superv *supervisor, cache
 		src:      src,
 		cachedir: cachedir,
 		cache:    cache,
+		backend:  backend,
 		suprvsr:  superv,
 	}
 
@@ -373,7 +561,7 @@ func (sg *sourceGateway) exportPrunedVersionTo(ctx context.Context, lp LockedPro
 
 	if fastprune, ok := sg.src.(sourceFastPrune); ok {
 		return sg.suprvsr.do(ctx, sg.src.upstreamURL(), ctExportTree, func(ctx context.Context) error {
-			return fastprune.exportPrunedRevisionTo(ctx, r, lp.Packages(), prune, to)
+			return fastprune.exportPrunedRevisionTo(ctx, r, lp, prune, to)
 		})
 	}
 
@@ -386,7 +574,9 @@ func (sg *sourceGateway) exportPrunedVersionTo(ctx context.Context, lp LockedPro
 	return PruneProject(to, lp, prune)
 }
 
-func (sg *sourceGateway) getManifestAndLock(ctx context.Context, pr ProjectRoot, v Version, an ProjectAnalyzer) (Manifest, Lock, error) {
+// getManifestAndLock analyzes the project at pr, which is in the directory dir
+// of the source, or at its root if dir is empty, at v.
+func (sg *sourceGateway) getManifestAndLock(ctx context.Context, pr ProjectRoot, dir string, v Version, an ProjectAnalyzer) (Manifest, Lock, error) {
 	sg.mu.Lock()
 	defer sg.mu.Unlock()
 
@@ -395,7 +585,17 @@ func (sg *sourceGateway) getManifestAndLock(ctx context.Context, pr ProjectRoot,
 		return nil, nil, err
 	}
 
-	m, l, has := sg.cache.getManifestAndLock(r, an.Info())
+	key := localKey{r: r, dir: dir, an: an.Info()}
+	var m Manifest
+	var l Lock
+	var has bool
+	if dir != "" {
+		var ml manifestAndLock
+		ml, has = sg.localManifests[key]
+		m, l = ml.m, ml.l
+	} else {
+		m, l, has = sg.cache.getManifestAndLock(r, an.Info())
+	}
 	if has {
 		return m, l, nil
 	}
@@ -407,7 +607,7 @@ func (sg *sourceGateway) getManifestAndLock(ctx context.Context, pr ProjectRoot,
 
 	label := fmt.Sprintf("%s:%s", sg.src.upstreamURL(), an.Info())
 	err = sg.suprvsr.do(ctx, label, ctGetManifestAndLock, func(ctx context.Context) error {
-		m, l, err = sg.src.getManifestAndLock(ctx, pr, r, an)
+		m, l, err = sg.src.getManifestAndLock(ctx, pr, dir, r, an)
 		return err
 	})
 
@@ -426,7 +626,7 @@ func (sg *sourceGateway) getManifestAndLock(ctx context.Context, pr ProjectRoot,
 		}
 
 		err = sg.suprvsr.do(ctx, label, ctGetManifestAndLock, func(ctx context.Context) error {
-			m, l, err = sg.src.getManifestAndLock(ctx, pr, r, an)
+			m, l, err = sg.src.getManifestAndLock(ctx, pr, dir, r, an)
 			return err
 		})
 	}
@@ -435,11 +635,20 @@ func (sg *sourceGateway) getManifestAndLock(ctx context.Context, pr ProjectRoot,
 		return nil, nil, err
 	}
 
-	sg.cache.setManifestAndLock(r, an.Info(), m, l)
+	if dir != "" {
+		if sg.localManifests == nil {
+			sg.localManifests = make(map[localKey]manifestAndLock)
+		}
+		sg.localManifests[key] = manifestAndLock{m: m, l: l}
+	} else {
+		sg.cache.setManifestAndLock(r, an.Info(), m, l)
+	}
 	return m, l, nil
 }
 
-func (sg *sourceGateway) listPackages(ctx context.Context, pr ProjectRoot, v Version) (pkgtree.PackageTree, error) {
+// listPackages lists the packages of the project at pr, which is in the
+// directory dir of the source, or at its root if dir is empty, at v.
+func (sg *sourceGateway) listPackages(ctx context.Context, pr ProjectRoot, dir string, v Version) (pkgtree.PackageTree, error) {
 	sg.mu.Lock()
 	defer sg.mu.Unlock()
 
@@ -448,7 +657,15 @@ func (sg *sourceGateway) listPackages(ctx context.Context, pr ProjectRoot, v Ver
 		return pkgtree.PackageTree{}, err
 	}
 
-	ptree, has := sg.cache.getPackageTree(r, pr)
+	key := localKey{r: r, dir: dir}
+	local := len(sg.platforms) > 0 || dir != ""
+	var ptree pkgtree.PackageTree
+	var has bool
+	if local {
+		ptree, has = sg.localTrees[key]
+	} else {
+		ptree, has = sg.cache.getPackageTree(r, pr)
+	}
 	if has {
 		return ptree, nil
 	}
@@ -460,7 +677,7 @@ func (sg *sourceGateway) listPackages(ctx context.Context, pr ProjectRoot, v Ver
 
 	label := fmt.Sprintf("%s:%s", pr, sg.src.upstreamURL())
 	err = sg.suprvsr.do(ctx, label, ctListPackages, func(ctx context.Context) error {
-		ptree, err = sg.src.listPackages(ctx, pr, r)
+		ptree, err = sg.src.listPackages(ctx, pr, dir, r, sg.platforms)
 		return err
 	})
 
@@ -479,7 +696,7 @@ func (sg *sourceGateway) listPackages(ctx context.Context, pr ProjectRoot, v Ver
 		}
 
 		err = sg.suprvsr.do(ctx, label, ctListPackages, func(ctx context.Context) error {
-			ptree, err = sg.src.listPackages(ctx, pr, r)
+			ptree, err = sg.src.listPackages(ctx, pr, dir, r, sg.platforms)
 			return err
 		})
 	}
@@ -488,7 +705,14 @@ func (sg *sourceGateway) listPackages(ctx context.Context, pr ProjectRoot, v Ver
 		return pkgtree.PackageTree{}, err
 	}
 
-	sg.cache.setPackageTree(r, ptree)
+	if local {
+		if sg.localTrees == nil {
+			sg.localTrees = make(map[localKey]pkgtree.PackageTree)
+		}
+		sg.localTrees[key] = ptree
+	} else {
+		sg.cache.setPackageTree(r, ptree)
+	}
 	return ptree, nil
 }
 
@@ -571,6 +795,37 @@ func (sg *sourceGateway) revisionPresentIn(ctx context.Context, r Revision) (boo
 	return present, err
 }
 
+// localPathWith returns the path of the local copy of the source, after
+// updating it from upstream if it does not hold r yet.
+func (sg *sourceGateway) localPathWith(ctx context.Context, r Revision) (string, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	ls, ok := sg.src.(interface {
+		localPath() string
+	})
+	if !ok {
+		return "", errors.Errorf("%s has no local copy", sg.src.upstreamURL())
+	}
+	if err := sg.require(ctx, sourceExistsLocally); err != nil {
+		return "", err
+	}
+
+	present, err := sg.src.revisionPresentIn(r)
+	if err == nil && !present && sg.srcState&sourceHasLatestLocally == 0 {
+		if err = sg.require(ctx, sourceHasLatestLocally); err == nil {
+			present, err = sg.src.revisionPresentIn(r)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if !present {
+		return "", errors.Errorf("revision %s does not exist in %s", r, sg.src.upstreamURL())
+	}
+	return ls.localPath(), nil
+}
+
 func (sg *sourceGateway) disambiguateRevision(ctx context.Context, r Revision) (Revision, error) {
 	sg.mu.Lock()
 	defer sg.mu.Unlock()
@@ -583,6 +838,85 @@ func (sg *sourceGateway) disambiguateRevision(ctx context.Context, r Revision) (
 	return sg.src.disambiguateRevision(ctx, r)
 }
 
+func (sg *sourceGateway) rootRevisions(ctx context.Context) ([]Revision, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	rr, ok := sg.src.(sourceRootRevisions)
+	if !ok {
+		return nil, errors.Errorf("listing root revisions is not supported for %s sources", sg.src.sourceType())
+	}
+
+	err := sg.require(ctx, sourceExistsLocally)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []Revision
+	err = sg.suprvsr.do(ctx, sg.src.upstreamURL(), ctListRootRevisions, func(ctx context.Context) error {
+		roots, err = rr.rootRevisions(ctx)
+		return err
+	})
+	return roots, err
+}
+
+func (sg *sourceGateway) revisionTime(ctx context.Context, v Version) (time.Time, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	sa, ok := sg.src.(sourceActivity)
+	if !ok {
+		return time.Time{}, errors.Errorf("reading revision times is not supported for %s sources", sg.src.sourceType())
+	}
+
+	err := sg.require(ctx, sourceExistsLocally)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	r, err := sg.convertToRevision(ctx, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var t time.Time
+	read := func(ctx context.Context) error {
+		t, err = sa.revisionTime(ctx, r)
+		return err
+	}
+	err = sg.suprvsr.do(ctx, sg.src.upstreamURL(), ctReadActivity, read)
+
+	// As with exporting, the revision may be missing from a stale local cache.
+	if err != nil && sg.srcState&sourceHasLatestLocally == 0 {
+		if err = sg.require(ctx, sourceHasLatestLocally); err == nil {
+			err = sg.suprvsr.do(ctx, sg.src.upstreamURL(), ctReadActivity, read)
+		}
+	}
+	return t, err
+}
+
+func (sg *sourceGateway) latestActivity(ctx context.Context) (time.Time, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	sa, ok := sg.src.(sourceActivity)
+	if !ok {
+		return time.Time{}, errors.Errorf("reading revision times is not supported for %s sources", sg.src.sourceType())
+	}
+
+	err := sg.require(ctx, sourceExistsLocally)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var t time.Time
+	err = sg.suprvsr.do(ctx, sg.src.upstreamURL(), ctReadActivity, func(ctx context.Context) error {
+		t, err = sa.latestActivity(ctx)
+		return err
+	})
+	return t, err
+}
+
 // sourceExistsUpstream verifies that the source exists upstream and that the
 // upstreamURL has not changed and returns any additional sourceState, or an error.
 func (sg *sourceGateway) sourceExistsUpstream(ctx context.Context) (sourceState, error) {
@@ -599,14 +933,52 @@ func (sg *sourceGateway) sourceExistsUpstream(ctx context.Context) (sourceState,
 }
 
 // initLocal initializes the source locally and returns the resulting sourceState.
-func (sg *sourceGateway) initLocal(ctx context.Context) (sourceState, error) {
-	if err := sg.suprvsr.do(ctx, sg.src.sourceType(), ctSourceInit, func(ctx context.Context) error {
-		err := sg.src.initLocal(ctx)
-		return errors.Wrapf(err, "failed to fetch source for %s", sg.src.upstreamURL())
-	}); err != nil {
-		return 0, err
+func (sg *sourceGateway) initLocal(ctx context.Context) (state sourceState, err error) {
+	err = sg.recordingFetched(func() error {
+		if sg.seedLocal(ctx) {
+			// The copy from the backend may lack the latest upstream changes,
+			// which are fetched if they come to be needed.
+			state = sourceExistsLocally
+			return nil
+		}
+		if err := sg.suprvsr.do(ctx, sg.src.sourceType(), ctSourceInit, func(ctx context.Context) error {
+			err := sg.src.initLocal(ctx)
+			return errors.Wrapf(err, "failed to fetch source for %s", sg.src.upstreamURL())
+		}); err != nil {
+			return err
+		}
+		state = sourceExistsUpstream | sourceExistsLocally | sourceHasLatestLocally
+		return nil
+	})
+	return state, err
+}
+
+// seedLocal tries to copy the source from the cache backend, and reports
+// whether a usable copy was found. A backend that fails is treated as not
+// having the source, so that it is cloned from upstream instead.
+func (sg *sourceGateway) seedLocal(ctx context.Context) bool {
+	path, name, ok := sg.localSourceName()
+	if sg.backend == nil || !ok {
+		return false
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		// Leave whatever is there to be dealt with by a regular clone.
+		return false
+	}
+
+	var seeded bool
+	err := sg.suprvsr.do(ctx, sg.src.upstreamURL(), ctSourceSeed, func(ctx context.Context) error {
+		var err error
+		if seeded, err = sg.backend.FetchSource(ctx, name, path); err != nil || !seeded {
+			return err
+		}
+		return sg.src.maybeClean(ctx)
+	})
+	if err != nil || !seeded || !sg.src.existsLocally(ctx) {
+		os.RemoveAll(path)
+		return false
 	}
-	return sourceExistsUpstream | sourceExistsLocally | sourceHasLatestLocally, nil
+	return true
 }
 
 // loadLatestVersionList loads the latest version list, possibly ensuring the source
@@ -637,6 +1009,14 @@ func (sg *sourceGateway) loadLatestVersionList(ctx context.Context) (sourceState
 // caller must hold sg.mu
 func (sg *sourceGateway) require(ctx context.Context, wanted sourceState) (err error) {
 	todo := (^sg.srcState) & wanted
+	if sg.readOnly {
+		if _, ok := sg.cache.getAllVersions(); ok {
+			todo &^= sourceHasLatestVersionList
+		}
+		if todo&(sourceExistsLocally|sourceHasLatestLocally) != 0 || (todo&sourceHasLatestVersionList != 0 && sg.src.listVersionsRequiresLocal()) {
+			return errors.Wrapf(ErrSourceManagerIsReadOnly, "data for %s is not in the cache", sg.src.upstreamURL())
+		}
+	}
 	var flag sourceState = 1
 
 	for todo != 0 {
@@ -657,8 +1037,10 @@ func (sg *sourceGateway) require(ctx context.Context, wanted sourceState) (err e
 					addlState, err = sg.loadLatestVersionList(ctx)
 				}
 			case sourceHasLatestLocally:
-				err = sg.suprvsr.do(ctx, sg.src.sourceType(), ctSourceFetch, func(ctx context.Context) error {
-					return sg.src.updateLocal(ctx)
+				err = sg.recordingFetched(func() error {
+					return sg.suprvsr.do(ctx, sg.src.sourceType(), ctSourceFetch, func(ctx context.Context) error {
+						return sg.src.updateLocal(ctx)
+					})
 				})
 				addlState = sourceExistsUpstream | sourceExistsLocally
 			}
@@ -690,8 +1072,13 @@ type source interface {
 	// maybeClean is a no-op when the underlying source does not support cleaning.
 	maybeClean(context.Context) error
 	listVersions(context.Context) ([]PairedVersion, error)
-	getManifestAndLock(context.Context, ProjectRoot, Revision, ProjectAnalyzer) (Manifest, Lock, error)
-	listPackages(context.Context, ProjectRoot, Revision) (pkgtree.PackageTree, error)
+	// getManifestAndLock analyzes the project in a directory of the source,
+	// or at its root if the directory is empty, at a revision.
+	getManifestAndLock(context.Context, ProjectRoot, string, Revision, ProjectAnalyzer) (Manifest, Lock, error)
+	// listPackages lists the packages of the project in a directory of the
+	// source, or at its root if the directory is empty, at a revision, as
+	// they are built on platforms, or on every platform if it is empty.
+	listPackages(context.Context, ProjectRoot, string, Revision, []pkgtree.Platform) (pkgtree.PackageTree, error)
 	revisionPresentIn(Revision) (bool, error)
 	disambiguateRevision(context.Context, Revision) (Revision, error)
 	exportRevisionTo(context.Context, Revision, string) error
@@ -704,7 +1091,24 @@ type source interface {
 	listVersionsRequiresLocal() bool
 }
 
+// sourceFastPrune is implemented by sources that can write out a pruned tree
+// faster than writing out the whole tree and pruning it.
 type sourceFastPrune interface {
 	source
-	exportPrunedRevisionTo(context.Context, Revision, []string, PruneOptions, string) error
+	exportPrunedRevisionTo(context.Context, Revision, LockedProject, PruneOptions, string) error
+}
+
+// sourceRootRevisions is implemented by sources that are able to report the
+// parentless revisions in their history.
+type sourceRootRevisions interface {
+	source
+	rootRevisions(context.Context) ([]Revision, error)
+}
+
+// sourceActivity is implemented by sources that are able to report when
+// revisions were committed.
+type sourceActivity interface {
+	source
+	revisionTime(context.Context, Revision) (time.Time, error)
+	latestActivity(context.Context) (time.Time, error)
 }
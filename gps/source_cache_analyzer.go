@@ -0,0 +1,58 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+// analyzerCacheFilename is a versioned filename for the bolt cache of what
+// ProjectAnalyzers derive, when the persistent cache is not enabled.
+const analyzerCacheFilename = "analyzers-v1.db"
+
+// analyzerCache creates singleSourceAnalyzerCaches, which persist only what
+// ProjectAnalyzers derive, and keep everything else in memory.
+type analyzerCache struct {
+	mem       sourceCache
+	manifests *multiCache
+}
+
+// newAnalyzerCache returns a new analyzerCache which persists manifests and
+// locks to disk.
+func newAnalyzerCache(disk sourceCache) *analyzerCache {
+	return &analyzerCache{
+		mem:       memoryCache{},
+		manifests: newMultiCache(memoryCache{}, disk),
+	}
+}
+
+// close releases resources after blocking until async writes complete.
+func (c *analyzerCache) close() error {
+	_ = c.mem.close()
+	return c.manifests.close()
+}
+
+// newSingleSourceCache returns a singleSourceAnalyzerCache for id.
+func (c *analyzerCache) newSingleSourceCache(id ProjectIdentifier) singleSourceCache {
+	return &singleSourceAnalyzerCache{
+		singleSourceCache: c.mem.newSingleSourceCache(id),
+		manifests:         c.manifests.newSingleSourceCache(id),
+	}
+}
+
+// singleSourceAnalyzerCache is used when the persistent cache is not enabled.
+// The manifest and lock that a ProjectAnalyzer derives at a revision depend
+// only on the revision and the name and version of the analyzer, so they never
+// go stale, and are cached both in memory and on disk anyway, sparing a solve
+// from reading and parsing them again for the revisions already analyzed.
+// Everything else is only cached in memory.
+type singleSourceAnalyzerCache struct {
+	singleSourceCache
+	manifests singleSourceCache
+}
+
+func (c *singleSourceAnalyzerCache) setManifestAndLock(r Revision, ai ProjectAnalyzerInfo, m Manifest, l Lock) {
+	c.manifests.setManifestAndLock(r, ai, m, l)
+}
+
+func (c *singleSourceAnalyzerCache) getManifestAndLock(r Revision, ai ProjectAnalyzerInfo) (Manifest, Lock, bool) {
+	return c.manifests.getManifestAndLock(r, ai)
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestAnalyzerCache(t *testing.T) {
+	cpath, err := ioutil.TempDir("", "analyzercache")
+	if err != nil {
+		t.Fatalf("Failed to create temp cache dir: %s", err)
+	}
+	defer os.RemoveAll(cpath)
+	logger := log.New(test.Writer{TB: t}, "", 0)
+	path := filepath.Join(cpath, analyzerCacheFilename)
+
+	pi := ProjectIdentifier{ProjectRoot: "example.com/test"}
+	rev := Revision("test")
+	ai := ProjectAnalyzerInfo{Name: "name", Version: 1}
+	manifest := &simpleRootManifest{
+		c: ProjectConstraints{
+			ProjectRoot("foo"): ProjectProperties{Constraint: Any()},
+		},
+	}
+	pvs := []PairedVersion{NewVersion("v1.0.0").Pair(rev)}
+
+	// Each solve uses a cache of its own, over the same file.
+	newCache := func() *analyzerCache {
+		bc, err := openBoltCache(path, time.Now().Unix(), logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newAnalyzerCache(bc)
+	}
+
+	ac := newCache()
+	c := ac.newSingleSourceCache(pi)
+	c.setManifestAndLock(rev, ai, manifest, nil)
+	c.setVersionMap(pvs)
+	if _, ok := c.getAllVersions(); !ok {
+		t.Error("expected versions to be cached in memory")
+	}
+	if err := ac.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ac = newCache()
+	defer ac.close()
+	c = ac.newSingleSourceCache(pi)
+	gotM, _, ok := c.getManifestAndLock(rev, ai)
+	if !ok {
+		t.Fatal("expected the manifest to be persisted")
+	}
+	compareManifests(t, manifest, gotM)
+	if _, ok := c.getAllVersions(); ok {
+		t.Error("expected versions not to be persisted")
+	}
+}
@@ -5,11 +5,13 @@
 package gps
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,7 +36,12 @@ type boltCache struct {
 
 // newBoltCache returns a new boltCache backed by a BoltDB file under the cache directory.
 func newBoltCache(cd string, epoch int64, logger *log.Logger) (*boltCache, error) {
-	path := filepath.Join(cd, boltCacheFilename)
+	return openBoltCache(filepath.Join(cd, boltCacheFilename), epoch, logger)
+}
+
+// openBoltCache returns a new boltCache backed by the BoltDB file at path,
+// which is created if it does not exist.
+func openBoltCache(path string, epoch int64, logger *log.Logger) (*boltCache, error) {
 	dir := filepath.Dir(path)
 	if fi, err := os.Stat(dir); os.IsNotExist(err) {
 		if err := os.MkdirAll(dir, os.ModeDir|os.ModePerm); err != nil {
@@ -56,6 +63,26 @@ func newBoltCache(cd string, epoch int64, logger *log.Logger) (*boltCache, error
 	}, nil
 }
 
+// newReadOnlyBoltCache returns a new boltCache backed by an existing BoltDB
+// file under the cache directory cd, which is opened read-only, so that cd
+// may be shared by users who cannot write to it. Only the getters of the
+// resulting caches may be used.
+func newReadOnlyBoltCache(cd string, epoch int64, logger *log.Logger) (*boltCache, error) {
+	path := filepath.Join(cd, boltCacheFilename)
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.Wrapf(err, "failed to check BoltDB cache file %q", path)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open BoltDB cache file %q", path)
+	}
+	return &boltCache{
+		db:     db,
+		epoch:  epoch,
+		logger: logger,
+	}, nil
+}
+
 // newSingleSourceCache returns a new singleSourceCache for pi.
 func (c *boltCache) newSingleSourceCache(pi ProjectIdentifier) singleSourceCache {
 	return &singleSourceCacheBolt{
@@ -111,17 +138,16 @@ type singleSourceCacheBolt struct {
 
 func (s *singleSourceCacheBolt) setManifestAndLock(rev Revision, ai ProjectAnalyzerInfo, m Manifest, l Lock) {
 	err := s.updateRevBucket(rev, func(b *bolt.Bucket) error {
+		// What other versions of the analyzer derived is no longer of use.
+		if err := deleteAnalyzerBuckets(b, ai); err != nil {
+			return err
+		}
+
 		info := ai.String()
 		name := make([]byte, len(info)+1)
 		copy(name, info)
 		name[len(info)] = 'm'
 
-		if b.Bucket(name) != nil {
-			if err := b.DeleteBucket(name); err != nil {
-				return err
-			}
-		}
-
 		// Manifest
 		mb, err := b.CreateBucket(name)
 		if err != nil {
@@ -136,11 +162,6 @@ func (s *singleSourceCacheBolt) setManifestAndLock(rev Revision, ai ProjectAnaly
 
 		// Lock
 		name[len(info)] = 'l'
-		if b.Bucket(name) != nil {
-			if err := b.DeleteBucket(name); err != nil {
-				return err
-			}
-		}
 		lb, err := b.CreateBucket(name)
 		if err != nil {
 			return err
@@ -152,6 +173,36 @@ func (s *singleSourceCacheBolt) setManifestAndLock(rev Revision, ai ProjectAnaly
 	}
 }
 
+// deleteAnalyzerBuckets deletes the manifest and lock buckets in the revision
+// bucket b of every version of the analyzer ai, including ai itself.
+func deleteAnalyzerBuckets(b *bolt.Bucket, ai ProjectAnalyzerInfo) error {
+	prefix := []byte(ai.Name + ".")
+	var names [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		// Nested buckets have nil values.
+		if v != nil || len(k) <= len(prefix) || !bytes.HasPrefix(k, prefix) {
+			return nil
+		}
+		if kind := k[len(k)-1]; kind != 'm' && kind != 'l' {
+			return nil
+		}
+		if _, err := strconv.Atoi(string(k[len(prefix) : len(k)-1])); err != nil {
+			return nil
+		}
+		names = append(names, append([]byte(nil), k...))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := b.DeleteBucket(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *singleSourceCacheBolt) getManifestAndLock(rev Revision, ai ProjectAnalyzerInfo) (m Manifest, l Lock, ok bool) {
 	err := s.viewRevBucket(rev, func(b *bolt.Bucket) error {
 		info := ai.String()
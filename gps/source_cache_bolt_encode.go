@@ -26,6 +26,7 @@ var (
 	cacheKeyImport       = cacheKeyIgnored
 	cacheKeyLock         = []byte("l")
 	cacheKeyName         = []byte("n")
+	cacheKeyNative       = []byte("x")
 	cacheKeyOverride     = []byte("o")
 	cacheKeyPTree        = []byte("p")
 	cacheKeyRequired     = []byte("r")
@@ -41,6 +42,7 @@ func propertiesFromCache(m *pb.ProjectProperties) (ProjectRoot, ProjectPropertie
 	ip := ProjectRoot(m.Root)
 	var pp ProjectProperties
 	pp.Source = m.Source
+	pp.RootDir = m.RootDir
 
 	if m.Constraint == nil {
 		pp.Constraint = Any()
@@ -65,6 +67,7 @@ type projectPropertiesMsgs struct {
 func (ms *projectPropertiesMsgs) copyFrom(ip ProjectRoot, pp ProjectProperties) {
 	ms.pp.Root = string(ip)
 	ms.pp.Source = pp.Source
+	ms.pp.RootDir = pp.RootDir
 
 	if pp.Constraint != nil && !IsAny(pp.Constraint) {
 		pp.Constraint.copyTo(&ms.c)
@@ -251,6 +254,7 @@ func (lp lockedProject) copyTo(msg *pb.LockedProject, c *pb.Constraint) {
 
 	msg.Root = string(lp.pi.ProjectRoot)
 	msg.Source = lp.pi.Source
+	msg.RootDir = lp.pi.RootDir
 	msg.Revision = string(lp.r)
 	msg.Packages = lp.pkgs
 }
@@ -281,6 +285,7 @@ func copyLockedProjectTo(lp LockedProject, msg *pb.LockedProject, c *pb.Constrai
 	pi := lp.Ident()
 	msg.Root = string(pi.ProjectRoot)
 	msg.Source = pi.Source
+	msg.RootDir = pi.RootDir
 	msg.Packages = lp.Packages()
 }
 
@@ -298,6 +303,7 @@ func lockedProjectFromCache(m *pb.LockedProject) (LockedProject, error) {
 		pi: ProjectIdentifier{
 			ProjectRoot: ProjectRoot(m.Root),
 			Source:      m.Source,
+			RootDir:     m.RootDir,
 		},
 		v:    uv,
 		r:    Revision(m.Revision),
@@ -414,6 +420,21 @@ func cachePutPackageOrErr(b *bolt.Bucket, poe pkgtree.PackageOrErr) error {
 			}
 		}
 	}
+
+	if len(poe.P.Native) > 0 {
+		np, err := b.CreateBucket(cacheKeyNative)
+		if err != nil {
+			return err
+		}
+		key := make(nuts.Key, nuts.KeyLen(uint64(len(poe.P.Native)-1)))
+		for i := range poe.P.Native {
+			v := []byte(poe.P.Native[i])
+			key.Put(uint64(i))
+			if err := np.Put(key, v); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -447,6 +468,15 @@ func cacheGetPackageOrErr(b *bolt.Bucket) (pkgtree.PackageOrErr, error) {
 			return pkgtree.PackageOrErr{}, err
 		}
 	}
+	if np := b.Bucket(cacheKeyNative); np != nil {
+		err := np.ForEach(func(_, v []byte) error {
+			p.Native = append(p.Native, string(v))
+			return nil
+		})
+		if err != nil {
+			return pkgtree.PackageOrErr{}, err
+		}
+	}
 	return pkgtree.PackageOrErr{P: p}, nil
 }
 
@@ -19,15 +19,17 @@ func TestPropertiesFromCache(t *testing.T) {
 		pp   ProjectProperties
 	}{
 		{"defaultBranch",
-			"root", ProjectProperties{"", newDefaultBranch("test")}},
+			"root", ProjectProperties{Constraint: newDefaultBranch("test")}},
 		{"branch",
-			"root", ProjectProperties{"source", NewBranch("test")}},
+			"root", ProjectProperties{Source: "source", Constraint: NewBranch("test")}},
 		{"semver",
-			"root", ProjectProperties{"", testSemverConstraint(t, "^1.0.0")}},
+			"root", ProjectProperties{Constraint: testSemverConstraint(t, "^1.0.0")}},
 		{"rev",
-			"root", ProjectProperties{"source", Revision("test")}},
+			"root", ProjectProperties{Source: "source", Constraint: Revision("test")}},
 		{"any",
-			"root", ProjectProperties{"source", Any()}},
+			"root", ProjectProperties{Source: "source", Constraint: Any()}},
+		{"rootDir",
+			"root", ProjectProperties{Source: "source", Constraint: NewBranch("test"), RootDir: "go"}},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			var buf projectPropertiesMsgs
@@ -7,6 +7,7 @@ package gps
 import (
 	"io/ioutil"
 	"log"
+	"os"
 	"path"
 	"testing"
 	"time"
@@ -310,3 +311,54 @@ func TestBoltCacheTimeout(t *testing.T) {
 		}
 	}
 }
+
+func TestBoltCacheAnalyzerVersions(t *testing.T) {
+	cpath, err := ioutil.TempDir("", "singlesourcecache")
+	if err != nil {
+		t.Fatalf("Failed to create temp cache dir: %s", err)
+	}
+	defer os.RemoveAll(cpath)
+	logger := log.New(test.Writer{TB: t}, "", 0)
+
+	bc, err := newBoltCache(cpath, 0, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.close()
+	c := bc.newSingleSourceCache(ProjectIdentifier{ProjectRoot: "example.com/test"})
+
+	rev := Revision("test")
+	v1 := ProjectAnalyzerInfo{Name: "name", Version: 1}
+	v2 := ProjectAnalyzerInfo{Name: "name", Version: 2}
+	other := ProjectAnalyzerInfo{Name: "name.other", Version: 1}
+
+	manifest := &simpleRootManifest{
+		c: ProjectConstraints{
+			ProjectRoot("foo"): ProjectProperties{Constraint: Any()},
+		},
+	}
+	lock := &safeLock{
+		p: []LockedProject{
+			NewLockedProject(mkPI("foo"), NewVersion("v1.0.0").Pair("zero"), []string{"foo"}),
+		},
+	}
+
+	c.setManifestAndLock(rev, v1, manifest, lock)
+	c.setManifestAndLock(rev, other, manifest, lock)
+	c.setManifestAndLock(rev, v2, manifest, nil)
+
+	if _, _, ok := c.getManifestAndLock(rev, v1); ok {
+		t.Error("expected what the previous version of the analyzer derived to be gone")
+	}
+	gotM, gotL, ok := c.getManifestAndLock(rev, v2)
+	if !ok {
+		t.Fatal("no manifest and lock found for the new version of the analyzer")
+	}
+	compareManifests(t, manifest, gotM)
+	if gotL != nil {
+		t.Errorf("expected no lock, got %s", gotL)
+	}
+	if _, gotL, ok := c.getManifestAndLock(rev, other); !ok || !locksAreEq(lock, gotL) {
+		t.Error("expected what another analyzer derived to be kept")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"github.com/golang/dep/gps/pkgtree"
+)
+
+// overlayCache creates singleSourceOverlayCaches, which layer a writable
+// sourceCache over a read-only one.
+type overlayCache struct {
+	upper, lower sourceCache
+}
+
+// newOverlayCache returns a new overlayCache which writes to upper, and reads
+// from lower whatever upper lacks.
+func newOverlayCache(upper, lower sourceCache) *overlayCache {
+	return &overlayCache{upper: upper, lower: lower}
+}
+
+// close releases the resources of both layers.
+func (c *overlayCache) close() error {
+	uerr := c.upper.close()
+	if lerr := c.lower.close(); uerr == nil {
+		return lerr
+	}
+	return uerr
+}
+
+// newSingleSourceCache returns a singleSourceOverlayCache for id.
+func (c *overlayCache) newSingleSourceCache(id ProjectIdentifier) singleSourceCache {
+	return &singleSourceOverlayCache{
+		upper: c.upper.newSingleSourceCache(id),
+		lower: c.lower.newSingleSourceCache(id),
+	}
+}
+
+// singleSourceOverlayCache manages two cache layers: a writable upper layer,
+// such as a per-user cache, and a read-only lower layer, such as a cache shared
+// by all the users of a machine.
+//
+// The upper layer is always checked first, with the lower used as a fallback.
+// Values associated with a revision are immutable, so those read from the
+// lower layer are copied into the upper. Set values are only cached in the
+// upper layer.
+type singleSourceOverlayCache struct {
+	upper, lower singleSourceCache
+}
+
+func (c *singleSourceOverlayCache) setManifestAndLock(r Revision, ai ProjectAnalyzerInfo, m Manifest, l Lock) {
+	c.upper.setManifestAndLock(r, ai, m, l)
+}
+
+func (c *singleSourceOverlayCache) getManifestAndLock(r Revision, ai ProjectAnalyzerInfo) (Manifest, Lock, bool) {
+	m, l, ok := c.upper.getManifestAndLock(r, ai)
+	if ok {
+		return m, l, true
+	}
+
+	m, l, ok = c.lower.getManifestAndLock(r, ai)
+	if ok {
+		c.upper.setManifestAndLock(r, ai, m, l)
+		return m, l, true
+	}
+
+	return nil, nil, false
+}
+
+func (c *singleSourceOverlayCache) setPackageTree(r Revision, ptree pkgtree.PackageTree) {
+	c.upper.setPackageTree(r, ptree)
+}
+
+func (c *singleSourceOverlayCache) getPackageTree(r Revision, pr ProjectRoot) (pkgtree.PackageTree, bool) {
+	ptree, ok := c.upper.getPackageTree(r, pr)
+	if ok {
+		return ptree, true
+	}
+
+	ptree, ok = c.lower.getPackageTree(r, pr)
+	if ok {
+		c.upper.setPackageTree(r, ptree)
+		return ptree, true
+	}
+
+	return pkgtree.PackageTree{}, false
+}
+
+func (c *singleSourceOverlayCache) markRevisionExists(r Revision) {
+	c.upper.markRevisionExists(r)
+}
+
+func (c *singleSourceOverlayCache) setVersionMap(pvs []PairedVersion) {
+	c.upper.setVersionMap(pvs)
+}
+
+func (c *singleSourceOverlayCache) getVersionsFor(rev Revision) ([]UnpairedVersion, bool) {
+	uvs, ok := c.upper.getVersionsFor(rev)
+	if ok {
+		return uvs, true
+	}
+
+	return c.lower.getVersionsFor(rev)
+}
+
+func (c *singleSourceOverlayCache) getAllVersions() ([]PairedVersion, bool) {
+	pvs, ok := c.upper.getAllVersions()
+	if ok {
+		return pvs, true
+	}
+
+	return c.lower.getAllVersions()
+}
+
+func (c *singleSourceOverlayCache) getRevisionFor(uv UnpairedVersion) (Revision, bool) {
+	rev, ok := c.upper.getRevisionFor(uv)
+	if ok {
+		return rev, true
+	}
+
+	return c.lower.getRevisionFor(uv)
+}
+
+func (c *singleSourceOverlayCache) toRevision(v Version) (Revision, bool) {
+	rev, ok := c.upper.toRevision(v)
+	if ok {
+		return rev, true
+	}
+
+	return c.lower.toRevision(v)
+}
+
+func (c *singleSourceOverlayCache) toUnpaired(v Version) (UnpairedVersion, bool) {
+	uv, ok := c.upper.toUnpaired(v)
+	if ok {
+		return uv, true
+	}
+
+	return c.lower.toUnpaired(v)
+}
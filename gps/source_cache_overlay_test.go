@@ -0,0 +1,82 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestOverlayCache(t *testing.T) {
+	const root = "example.com/test"
+	cpath, err := ioutil.TempDir("", "overlaycache")
+	if err != nil {
+		t.Fatalf("Failed to create temp cache dir: %s", err)
+	}
+	defer os.RemoveAll(cpath)
+	pi := ProjectIdentifier{ProjectRoot: root}
+	logger := log.New(test.Writer{TB: t}, "", 0)
+	epoch := time.Now().Add(-time.Hour).Unix()
+
+	rev := Revision("rev")
+	ptree := pkgtree.PackageTree{
+		ImportRoot: root,
+		Packages: map[string]pkgtree.PackageOrErr{
+			root: {P: pkgtree.Package{ImportPath: root, Name: "test", Imports: []string{"sort"}}},
+		},
+	}
+	pvs := []PairedVersion{NewVersion("v1.0.0").Pair(rev)}
+
+	// Warm the global cache, as an administrator would.
+	gc, err := newBoltCache(cpath, epoch, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gsc := gc.newSingleSourceCache(pi)
+	gsc.setPackageTree(rev, ptree)
+	gsc.setVersionMap(pvs)
+	if err := gc.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lower, err := newReadOnlyBoltCache(cpath, epoch, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upper := memoryCache{}
+	oc := newOverlayCache(upper, lower)
+	defer oc.close()
+	c := oc.newSingleSourceCache(pi).(*singleSourceOverlayCache)
+
+	if _, ok := c.upper.getPackageTree(rev, root); ok {
+		t.Fatal("expected the upper layer to start out empty")
+	}
+	if got, ok := c.getPackageTree(rev, root); !ok || !reflect.DeepEqual(got, ptree) {
+		t.Errorf("expected the package tree to be read from the lower layer, got %#v", got)
+	}
+	if _, ok := c.upper.getPackageTree(rev, root); !ok {
+		t.Error("expected the package tree to be copied into the upper layer")
+	}
+	if got, ok := c.getAllVersions(); !ok || !reflect.DeepEqual(got, pvs) {
+		t.Errorf("expected the versions to be read from the lower layer, got %v", got)
+	}
+
+	// Writes go to the upper layer only, and take precedence there.
+	newPvs := []PairedVersion{NewVersion("v1.1.0").Pair("newrev")}
+	c.setVersionMap(newPvs)
+	if got, ok := c.getAllVersions(); !ok || !reflect.DeepEqual(got, newPvs) {
+		t.Errorf("expected the versions to be read from the upper layer, got %v", got)
+	}
+	if got, ok := c.lower.getAllVersions(); !ok || !reflect.DeepEqual(got, pvs) {
+		t.Errorf("expected the lower layer to be left alone, got %v", got)
+	}
+}
@@ -235,6 +235,7 @@ func (test singleSourceCacheTest) run(t *testing.T) {
 							"github.com/golang/dep/gps",
 							"sort",
 						},
+						Native: []string{pkgtree.NativeCgo, pkgtree.NativeAsm},
 					},
 				},
 				path.Join(root, "m1p"): {
@@ -562,6 +563,15 @@ func packageOrErrEqual(a, b pkgtree.PackageOrErr) bool {
 		}
 	}
 
+	if len(a.P.Native) != len(b.P.Native) {
+		return false
+	}
+	for i := range a.P.Native {
+		if a.P.Native[i] != b.P.Native[i] {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -175,6 +175,9 @@ type SourceMgr struct {
 	qch         chan struct{}         // quit chan for signal handler
 	relonce     sync.Once             // once-er to ensure we only release once
 	releasing   int32                 // flag indicating release of sm has begun
+	scratchdir  string                // scratch dir for sources of a read-only sm
+	proxy       *moduleProxy          // module proxy that exports fail over to, if any
+	proxyFirst  bool                  // true if exports try the module proxy before sources
 }
 
 var _ SourceManager = &SourceMgr{}
@@ -184,12 +187,47 @@ var _ SourceManager = &SourceMgr{}
 // longer safe to call.
 var ErrSourceManagerIsReleased = fmt.Errorf("this SourceManager has been released, its methods can no longer be called")
 
+// ErrSourceManagerIsReadOnly is the cause of the errors returned by a
+// read-only SourceManager for data that it could only get from a local copy of
+// a source.
+var ErrSourceManagerIsReadOnly = fmt.Errorf("this SourceManager is read-only, and cannot make local copies of sources")
+
 // SourceManagerConfig holds configuration information for creating SourceMgrs.
 type SourceManagerConfig struct {
-	CacheAge       time.Duration // Maximum valid age of cached data. <=0: Don't cache.
+	CacheAge       time.Duration // Maximum valid age of cached data. <=0: Only cache what ProjectAnalyzers derive.
 	Cachedir       string        // Where to store local instances of upstream sources.
 	Logger         *log.Logger   // Optional info/warn logger. Discards if nil.
 	DisableLocking bool          // True if the SourceManager should NOT use a lock file to protect the Cachedir from multiple processes.
+	CacheBackend   CacheBackend  // Optional shared store from which sources missing from Cachedir are copied before cloning them.
+	GlobalCachedir string        // Optional read-only cache, shared by all users, that Cachedir is layered over.
+	ReadOnly       bool          // True if the SourceManager must neither modify Cachedir nor lock it, and so serve data from its persistent cache and upstream version lists alone.
+
+	// Platforms are the platforms for which ListPackages lists packages. If
+	// empty, the imports of every file are listed, whatever platforms it is
+	// built on.
+	Platforms []pkgtree.Platform
+
+	// FallbackSources maps identifiers to the sources, in order, from which
+	// their projects are fetched if their own source cannot be reached, or
+	// fails to serve them. Only the ProjectRoot and Source of the identifiers
+	// matter.
+	FallbackSources map[ProjectIdentifier][]string
+
+	// ModuleProxy is the http or https URL of a Go module proxy from which
+	// ExportProject and ExportPrunedProject export projects that cannot be
+	// exported from their sources. If ModuleProxyFirst is set, the proxy is
+	// tried first, and sources only if it fails.
+	ModuleProxy      string
+	ModuleProxyFirst bool
+}
+
+// globalCachedir returns the global cache directory to layer Cachedir over, if
+// there is one.
+func (c SourceManagerConfig) globalCachedir() string {
+	if c.GlobalCachedir == "" || filepath.Clean(c.GlobalCachedir) == filepath.Clean(c.Cachedir) {
+		return ""
+	}
+	return c.GlobalCachedir
 }
 
 // NewSourceManager produces an instance of gps's built-in SourceManager.
@@ -201,8 +239,9 @@ type SourceManagerConfig struct {
 // solver can benefit from any caches that may have already been warmed.
 //
 // A cacheEpoch is calculated from now()-cacheAge, and older persistent cache data
-// is discarded. When cacheAge is <= 0, the persistent cache is
-// not used.
+// is discarded. When cacheAge is <= 0, only the manifests and locks derived by
+// ProjectAnalyzers, which are keyed by revision and by the name and version of
+// the analyzer, and so never go stale, are cached persistently.
 //
 // gps's SourceManager is intended to be threadsafe (if it's not, please file a
 // bug!). It should be safe to reuse across concurrent solving runs, even on
@@ -211,6 +250,16 @@ func NewSourceManager(c SourceManagerConfig) (*SourceMgr, error) {
 	if c.Logger == nil {
 		c.Logger = log.New(ioutil.Discard, "", 0)
 	}
+	var proxy *moduleProxy
+	if c.ModuleProxy != "" {
+		var err error
+		if proxy, err = newModuleProxy(c.ModuleProxy); err != nil {
+			return nil, err
+		}
+	}
+	if c.ReadOnly {
+		return newReadOnlySourceManager(c, proxy)
+	}
 
 	err := fs.EnsureDir(filepath.Join(c.Cachedir, "sources"), 0777)
 	if err != nil {
@@ -294,30 +343,131 @@ func NewSourceManager(c SourceManagerConfig) (*SourceMgr, error) {
 	deducer := newDeductionCoordinator(superv)
 
 	var sc sourceCache
+	// Try to open the BoltDB cache from disk. Without a cacheAge, only what
+	// ProjectAnalyzers derive is persisted, in a file of its own, which leaves
+	// the main one free for read-only SourceManagers.
+	epoch := time.Now().Unix()
+	path := filepath.Join(c.Cachedir, analyzerCacheFilename)
 	if c.CacheAge > 0 {
-		// Try to open the BoltDB cache from disk.
-		epoch := time.Now().Add(-c.CacheAge).Unix()
-		boltCache, err := newBoltCache(c.Cachedir, epoch, c.Logger)
-		if err != nil {
-			c.Logger.Println(errors.Wrapf(err, "failed to open persistent cache %q", c.Cachedir))
+		epoch = time.Now().Add(-c.CacheAge).Unix()
+		path = filepath.Join(c.Cachedir, boltCacheFilename)
+	}
+	boltCache, err := openBoltCache(path, epoch, c.Logger)
+	if err != nil {
+		c.Logger.Println(errors.Wrapf(err, "failed to open persistent cache %q", c.Cachedir))
+	} else {
+		var disk sourceCache = boltCache
+		if global := c.globalCachedir(); global != "" {
+			// The global cache need not hold any persistent data.
+			if globalCache, err := newReadOnlyBoltCache(global, epoch, c.Logger); err == nil {
+				disk = newOverlayCache(boltCache, globalCache)
+			} else if !os.IsNotExist(errors.Cause(err)) {
+				c.Logger.Println(errors.Wrapf(err, "failed to open global persistent cache %q", global))
+			}
+		}
+		if c.CacheAge > 0 {
+			sc = newMultiCache(memoryCache{}, disk)
 		} else {
-			sc = newMultiCache(memoryCache{}, boltCache)
+			sc = newAnalyzerCache(disk)
+		}
+	}
+
+	backend := c.CacheBackend
+	if global := c.globalCachedir(); global != "" {
+		// Sources are copied from the global cache before any other backend.
+		backends := cacheBackends{dirCacheBackend{root: global}}
+		if c.CacheBackend != nil {
+			backends = append(backends, c.CacheBackend)
 		}
+		backend = backends
 	}
 
+	srcCoord := newSourceCoordinator(superv, deducer, c.Cachedir, sc, backend, c.Logger)
+	srcCoord.platforms = c.Platforms
+	srcCoord.setFallbacks(c.FallbackSources)
+
 	sm := &SourceMgr{
 		cachedir:    c.Cachedir,
 		lf:          lockfile,
 		suprvsr:     superv,
 		cancelAll:   cf,
 		deduceCoord: deducer,
-		srcCoord:    newSourceCoordinator(superv, deducer, c.Cachedir, sc, c.Logger),
+		srcCoord:    srcCoord,
 		qch:         make(chan struct{}),
+		proxy:       proxy,
+		proxyFirst:  c.ModuleProxyFirst,
 	}
 
 	return sm, nil
 }
 
+// newReadOnlySourceManager returns a SourceMgr that serves data from the
+// persistent caches in c.Cachedir and c.GlobalCachedir, falling back only on
+// what can be read from upstream without a local copy of the source, such as
+// the versions of a git repository. It takes no lock on the cache, so it can
+// be used while another SourceManager holds it, or where the cache cannot be
+// written; but a persistent cache that is held open for writing by another
+// process cannot be read, and so is treated as empty.
+//
+// Data in the persistent caches is used whatever its age, unless c.CacheAge is
+// set to limit it.
+func newReadOnlySourceManager(c SourceManagerConfig, proxy *moduleProxy) (*SourceMgr, error) {
+	var epoch int64
+	if c.CacheAge > 0 {
+		epoch = time.Now().Add(-c.CacheAge).Unix()
+	}
+
+	// Both persistent caches are read-only lower layers; whatever would be
+	// written to them, including data copied up from the global cache, is
+	// only kept in memory.
+	var sc sourceCache = memoryCache{}
+	for _, cd := range []string{c.Cachedir, c.globalCachedir()} {
+		if cd == "" {
+			continue
+		}
+		bc, err := newReadOnlyBoltCache(cd, epoch, c.Logger)
+		if err != nil {
+			if !os.IsNotExist(errors.Cause(err)) {
+				c.Logger.Println(errors.Wrapf(err, "failed to open persistent cache %q", cd))
+			}
+			continue
+		}
+		sc = newOverlayCache(sc, bc)
+	}
+
+	// Sources are set up in an empty scratch directory rather than in the
+	// cache, so that they never find, nor clean up, the local copies there.
+	scratch, err := ioutil.TempDir("", "dep-readonly")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create scratch directory")
+	}
+	if err := os.Mkdir(filepath.Join(scratch, "sources"), 0777); err != nil {
+		os.RemoveAll(scratch)
+		return nil, errors.Wrap(err, "failed to create scratch directory")
+	}
+
+	ctx, cf := context.WithCancel(context.TODO())
+	superv := newSupervisor(ctx)
+	deducer := newDeductionCoordinator(superv)
+	srcCoord := newSourceCoordinator(superv, deducer, scratch, sc, nil, c.Logger)
+	srcCoord.readOnly = true
+	srcCoord.platforms = c.Platforms
+	srcCoord.setFallbacks(c.FallbackSources)
+
+	return &SourceMgr{
+		cachedir:    c.Cachedir,
+		scratchdir:  scratch,
+		lf:          falseLocker{},
+		suprvsr:     superv,
+		cancelAll:   cf,
+		deduceCoord: deducer,
+		srcCoord:    srcCoord,
+		qch:         make(chan struct{}),
+		proxy:       proxy,
+		proxyFirst:  c.ModuleProxyFirst,
+	}, nil
+}
+
 // Cachedir returns the location of the cache directory.
 func (sm *SourceMgr) Cachedir() string {
 	return sm.cachedir
@@ -419,9 +569,14 @@ func (sm *SourceMgr) Release() {
 		// Close the source coordinator.
 		sm.srcCoord.close()
 
-		// Close the file handle for the lock file and remove it from disk
+		// Close the file handle for the lock file and remove it from disk. A
+		// read-only SourceMgr has no lock of its own, only a scratch directory.
 		sm.lf.Unlock()
-		os.Remove(filepath.Join(sm.cachedir, "sm.lock"))
+		if sm.scratchdir != "" {
+			os.RemoveAll(sm.scratchdir)
+		} else {
+			os.Remove(filepath.Join(sm.cachedir, "sm.lock"))
+		}
 
 		// Close the qch, if non-nil, so the signal handlers run out. This will
 		// also deregister the sig channel, if any has been set up.
@@ -440,12 +595,13 @@ func (sm *SourceMgr) GetManifestAndLock(id ProjectIdentifier, v Version, an Proj
 		return nil, nil, ErrSourceManagerIsReleased
 	}
 
-	srcg, err := sm.srcCoord.getSourceGatewayFor(context.TODO(), id)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return srcg.getManifestAndLock(context.TODO(), id.ProjectRoot, v, an)
+	var m Manifest
+	var l Lock
+	err := sm.srcCoord.withSourceGateway(context.TODO(), id, func(srcg *sourceGateway) (err error) {
+		m, l, err = srcg.getManifestAndLock(context.TODO(), id.ProjectRoot, id.RootDir, v, an)
+		return err
+	})
+	return m, l, err
 }
 
 // ListPackages parses the tree of the Go packages at and below the ProjectRoot
@@ -455,12 +611,12 @@ func (sm *SourceMgr) ListPackages(id ProjectIdentifier, v Version) (pkgtree.Pack
 		return pkgtree.PackageTree{}, ErrSourceManagerIsReleased
 	}
 
-	srcg, err := sm.srcCoord.getSourceGatewayFor(context.TODO(), id)
-	if err != nil {
-		return pkgtree.PackageTree{}, err
-	}
-
-	return srcg.listPackages(context.TODO(), id.ProjectRoot, v)
+	var ptree pkgtree.PackageTree
+	err := sm.srcCoord.withSourceGateway(context.TODO(), id, func(srcg *sourceGateway) (err error) {
+		ptree, err = srcg.listPackages(context.TODO(), id.ProjectRoot, id.RootDir, v)
+		return err
+	})
+	return ptree, err
 }
 
 // ListVersions retrieves a list of the available versions for a given
@@ -480,29 +636,113 @@ func (sm *SourceMgr) ListVersions(id ProjectIdentifier) ([]PairedVersion, error)
 		return nil, ErrSourceManagerIsReleased
 	}
 
+	var vl []PairedVersion
+	err := sm.srcCoord.withSourceGateway(context.TODO(), id, func(srcg *sourceGateway) (err error) {
+		vl, err = srcg.listVersions(context.TODO())
+		return err
+	})
+	// TODO(sdboyer) More-er proper-er errors
+	return vl, err
+}
+
+// RootRevisions returns the parentless revisions in the history of the source
+// for the given ProjectIdentifier. Sources sharing a root revision share
+// history, which typically indicates that one is a fork of the other.
+//
+// The source will be brought into the local cache if it is not already
+// present. An error is returned for sources whose VCS cannot report this
+// information.
+func (sm *SourceMgr) RootRevisions(id ProjectIdentifier) ([]Revision, error) {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return nil, ErrSourceManagerIsReleased
+	}
+
+	var revs []Revision
+	err := sm.srcCoord.withSourceGateway(context.TODO(), id, func(srcg *sourceGateway) (err error) {
+		revs, err = srcg.rootRevisions(context.TODO())
+		return err
+	})
+	return revs, err
+}
+
+// RevisionTime returns the time at which the revision underlying the provided
+// Version was committed to the source for the given ProjectIdentifier.
+//
+// The time is read from the local cache of the source, which will be created
+// if it is not already present, and updated if it does not hold the revision.
+// An error is returned for sources whose VCS cannot report this information.
+func (sm *SourceMgr) RevisionTime(id ProjectIdentifier, v Version) (time.Time, error) {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return time.Time{}, ErrSourceManagerIsReleased
+	}
+
+	var t time.Time
+	err := sm.srcCoord.withSourceGateway(context.TODO(), id, func(srcg *sourceGateway) (err error) {
+		t, err = srcg.revisionTime(context.TODO(), v)
+		return err
+	})
+	return t, err
+}
+
+// LatestActivity returns the time of the most recent commit to any branch or
+// tag of the source for the given ProjectIdentifier.
+//
+// The time is read from the local cache of the source, which will be created
+// if it is not already present, but is not otherwise updated; it reflects the
+// upstream as of the last time the cache was fetched. An error is returned for
+// sources whose VCS cannot report this information.
+func (sm *SourceMgr) LatestActivity(id ProjectIdentifier) (time.Time, error) {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return time.Time{}, ErrSourceManagerIsReleased
+	}
+
 	srcg, err := sm.srcCoord.getSourceGatewayFor(context.TODO(), id)
 	if err != nil {
-		// TODO(sdboyer) More-er proper-er errors
-		return nil, err
+		return time.Time{}, err
 	}
 
-	return srcg.listVersions(context.TODO())
+	return srcg.latestActivity(context.TODO())
 }
 
-// RevisionPresentIn indicates whether the provided Revision is present in the given
-// repository.
-func (sm *SourceMgr) RevisionPresentIn(id ProjectIdentifier, r Revision) (bool, error) {
+// SourceUsage measures the local copy of the source for the given
+// ProjectIdentifier in the cache, which will be created if it is not already
+// present, and reports the bytes recorded as fetched into it.
+func (sm *SourceMgr) SourceUsage(id ProjectIdentifier) (SourceUsage, error) {
 	if atomic.LoadInt32(&sm.releasing) == 1 {
-		return false, ErrSourceManagerIsReleased
+		return SourceUsage{}, ErrSourceManagerIsReleased
 	}
 
 	srcg, err := sm.srcCoord.getSourceGatewayFor(context.TODO(), id)
 	if err != nil {
-		// TODO(sdboyer) More-er proper-er errors
-		return false, err
+		return SourceUsage{}, err
 	}
 
-	return srcg.revisionPresentIn(context.TODO(), r)
+	return srcg.usage(context.TODO())
+}
+
+// FetchedFrom returns the fallback source, of those set in
+// SourceManagerConfig.FallbackSources, from which the project identified by id
+// is fetched. It is empty if the project is fetched from its own source. If
+// the project has fallback sources, but has not been fetched, it reports
+// false, as which source it would be fetched from is not known.
+func (sm *SourceMgr) FetchedFrom(id ProjectIdentifier) (string, bool) {
+	return sm.srcCoord.servedFrom(id)
+}
+
+// RevisionPresentIn indicates whether the provided Revision is present in the given
+// repository.
+func (sm *SourceMgr) RevisionPresentIn(id ProjectIdentifier, r Revision) (bool, error) {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return false, ErrSourceManagerIsReleased
+	}
+
+	var present bool
+	err := sm.srcCoord.withSourceGateway(context.TODO(), id, func(srcg *sourceGateway) (err error) {
+		present, err = srcg.revisionPresentIn(context.TODO(), r)
+		return err
+	})
+	// TODO(sdboyer) More-er proper-er errors
+	return present, err
 }
 
 // SourceExists checks if a repository exists, either upstream or in the cache,
@@ -536,12 +776,25 @@ func (sm *SourceMgr) SyncSourceFor(id ProjectIdentifier) error {
 		return ErrSourceManagerIsReleased
 	}
 
-	srcg, err := sm.srcCoord.getSourceGatewayFor(context.TODO(), id)
-	if err != nil {
-		return err
+	return sm.srcCoord.withSourceGateway(context.TODO(), id, func(srcg *sourceGateway) error {
+		return srcg.syncLocal(context.TODO())
+	})
+}
+
+// SourceDir returns the directory in the cache holding the local copy of the
+// source for the provided ProjectIdentifier, after making sure that it holds
+// revision r.
+func (sm *SourceMgr) SourceDir(id ProjectIdentifier, r Revision) (string, error) {
+	if atomic.LoadInt32(&sm.releasing) == 1 {
+		return "", ErrSourceManagerIsReleased
 	}
 
-	return srcg.syncLocal(context.TODO())
+	var dir string
+	err := sm.srcCoord.withSourceGateway(context.TODO(), id, func(srcg *sourceGateway) (err error) {
+		dir, err = srcg.localPathWith(context.TODO(), r)
+		return err
+	})
+	return dir, err
 }
 
 // ExportProject writes out the tree of the provided ProjectIdentifier's
@@ -551,12 +804,18 @@ func (sm *SourceMgr) ExportProject(ctx context.Context, id ProjectIdentifier, v
 		return ErrSourceManagerIsReleased
 	}
 
-	srcg, err := sm.srcCoord.getSourceGatewayFor(ctx, id)
-	if err != nil {
-		return err
-	}
-
-	return srcg.exportVersionTo(ctx, v, to)
+	return sm.exportWithProxy(ctx, id, v, to, func() error {
+		return sm.srcCoord.withSourceGateway(ctx, id, func(srcg *sourceGateway) error {
+			return removeOnError(to, func() error {
+				if id.RootDir != "" {
+					return exportRootDir(id.RootDir, to, func(tmp string) error {
+						return srcg.exportVersionTo(ctx, v, tmp)
+					})
+				}
+				return srcg.exportVersionTo(ctx, v, to)
+			})
+		})
+	}, nil)
 }
 
 // ExportPrunedProject writes out a tree of the provided LockedProject, applying
@@ -566,12 +825,18 @@ func (sm *SourceMgr) ExportPrunedProject(ctx context.Context, lp LockedProject,
 		return ErrSourceManagerIsReleased
 	}
 
-	srcg, err := sm.srcCoord.getSourceGatewayFor(ctx, lp.Ident())
-	if err != nil {
-		return err
-	}
-
-	return srcg.exportPrunedVersionTo(ctx, lp, prune, to)
+	return sm.exportWithProxy(ctx, lp.Ident(), lp.Version(), to, func() error {
+		return sm.srcCoord.withSourceGateway(ctx, lp.Ident(), func(srcg *sourceGateway) error {
+			return removeOnError(to, func() error {
+				if lp.Ident().RootDir != "" {
+					return exportPrunedRootDir(ctx, srcg, lp, prune, to)
+				}
+				return srcg.exportPrunedVersionTo(ctx, lp, prune, to)
+			})
+		})
+	}, func() error {
+		return PruneProject(to, lp, prune)
+	})
 }
 
 // DeduceProjectRoot takes an import path and deduces the corresponding
@@ -662,11 +927,25 @@ func (sm *SourceMgr) SourceURLsForPath(ip string) ([]*url.URL, error) {
 // abbreviated git commit hash. disambiguateRevision would return the complete
 // hash.
 func (sm *SourceMgr) disambiguateRevision(ctx context.Context, pi ProjectIdentifier, rev Revision) (Revision, error) {
-	srcg, err := sm.srcCoord.getSourceGatewayFor(context.TODO(), pi)
-	if err != nil {
-		return "", err
+	var r Revision
+	err := sm.srcCoord.withSourceGateway(ctx, pi, func(srcg *sourceGateway) (err error) {
+		r, err = srcg.disambiguateRevision(ctx, rev)
+		return err
+	})
+	return r, err
+}
+
+// removeOnError runs export, which writes to to, and removes what it left
+// there if it fails, unless to was there to begin with, so that the export
+// can be tried again.
+func removeOnError(to string, export func() error) error {
+	_, err := os.Lstat(to)
+	existed := err == nil
+	err = export()
+	if err != nil && !existed {
+		os.RemoveAll(to)
 	}
-	return srcg.disambiguateRevision(ctx, rev)
+	return err
 }
 
 type timeCount struct {
@@ -798,6 +1077,9 @@ const (
 	ctSourceFetch
 	ctExportTree
 	ctValidateLocal
+	ctListRootRevisions
+	ctReadActivity
+	ctSourceSeed
 )
 
 func (ct callType) String() string {
@@ -818,6 +1100,12 @@ func (ct callType) String() string {
 		return "Fetching latest data into local source cache"
 	case ctExportTree:
 		return "Writing code tree out to disk"
+	case ctListRootRevisions:
+		return "Listing root revisions"
+	case ctReadActivity:
+		return "Reading revision times"
+	case ctSourceSeed:
+		return "Copying source from the cache backend"
 	default:
 		panic("unknown calltype")
 	}
@@ -6,10 +6,12 @@ package gps
 
 import (
 	"log"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/golang/dep/internal/test"
+	"github.com/pkg/errors"
 )
 
 func TestSourceManager_InferConstraint(t *testing.T) {
@@ -112,3 +114,49 @@ func TestSourceManager_InferConstraint(t *testing.T) {
 		})
 	}
 }
+
+func TestReadOnlySourceManager(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("cache")
+	cachedir := h.Path("cache")
+	logger := log.New(test.Writer{TB: t}, "", 0)
+
+	id := mkPI("github.com/foo/bar")
+	pvs := []PairedVersion{NewVersion("v1.0.0").Pair("abc123")}
+	bc, err := newBoltCache(cachedir, 0, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc.newSingleSourceCache(id).setVersionMap(pvs)
+	if err := bc.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another SourceManager holds the cache.
+	sm, err := NewSourceManager(SourceManagerConfig{Cachedir: cachedir, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Release()
+
+	ro, err := NewSourceManager(SourceManagerConfig{Cachedir: cachedir, Logger: logger, ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ro.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, pvs) {
+		t.Errorf("expected the versions in the persistent cache, got %v", got)
+	}
+	if _, err := ro.ListPackages(id, pvs[0]); errors.Cause(err) != ErrSourceManagerIsReadOnly {
+		t.Errorf("expected data missing from the cache to be unavailable, got %v", err)
+	}
+
+	ro.Release()
+	h.MustExist(filepath.Join(cachedir, "sm.lock"))
+	h.MustNotExist(filepath.Join(cachedir, "sources", "https---github.com-foo-bar"))
+}
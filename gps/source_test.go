@@ -15,6 +15,7 @@ import (
 
 	"github.com/golang/dep/gps/pkgtree"
 	"github.com/golang/dep/internal/test"
+	"github.com/pkg/errors"
 )
 
 // Executed in parallel by TestSlowVcs
@@ -43,7 +44,7 @@ func testSourceGateway(t *testing.T) {
 			superv := newSupervisor(ctx)
 			deducer := newDeductionCoordinator(superv)
 			logger := log.New(test.Writer{TB: t}, "", 0)
-			sc := newSourceCoordinator(superv, deducer, cachedir, nil, logger)
+			sc := newSourceCoordinator(superv, deducer, cachedir, nil, nil, logger)
 			defer sc.close()
 
 			id := mkPI("github.com/sdboyer/deptest")
@@ -137,14 +138,14 @@ func testSourceGateway(t *testing.T) {
 			badver := NewVersion("notexist")
 			wanterr := fmt.Errorf("version %q does not exist in source", badver)
 
-			_, _, err = sg.getManifestAndLock(ctx, ProjectRoot("github.com/sdboyer/deptest"), badver, naiveAnalyzer{})
+			_, _, err = sg.getManifestAndLock(ctx, ProjectRoot("github.com/sdboyer/deptest"), "", badver, naiveAnalyzer{})
 			if err == nil {
 				t.Fatal("wanted err on nonexistent version")
 			} else if err.Error() != wanterr.Error() {
 				t.Fatalf("wanted nonexistent err when passing bad version, got: %s", err)
 			}
 
-			_, err = sg.listPackages(ctx, ProjectRoot("github.com/sdboyer/deptest"), badver)
+			_, err = sg.listPackages(ctx, ProjectRoot("github.com/sdboyer/deptest"), "", badver)
 			if err == nil {
 				t.Fatal("wanted err on nonexistent version")
 			} else if err.Error() != wanterr.Error() {
@@ -171,13 +172,13 @@ func testSourceGateway(t *testing.T) {
 				},
 			}
 
-			ptree, err := sg.listPackages(ctx, ProjectRoot("github.com/sdboyer/deptest"), Revision("ff2948a2ac8f538c4ecd55962e919d1e13e74baf"))
+			ptree, err := sg.listPackages(ctx, ProjectRoot("github.com/sdboyer/deptest"), "", Revision("ff2948a2ac8f538c4ecd55962e919d1e13e74baf"))
 			if err != nil {
 				t.Fatalf("unexpected err when getting package tree with known rev: %s", err)
 			}
 			comparePackageTree(t, wantptree, ptree)
 
-			ptree, err = sg.listPackages(ctx, ProjectRoot("github.com/sdboyer/deptest"), NewVersion("v1.0.0"))
+			ptree, err = sg.listPackages(ctx, ProjectRoot("github.com/sdboyer/deptest"), "", NewVersion("v1.0.0"))
 			if err != nil {
 				t.Fatalf("unexpected err when getting package tree with unpaired good version: %s", err)
 			}
@@ -189,3 +190,176 @@ func testSourceGateway(t *testing.T) {
 	t.Run("empty", do(sourceExistsUpstream|sourceHasLatestVersionList))
 	t.Run("exists", do(sourceExistsLocally))
 }
+
+func TestReadOnlySourceGateway(t *testing.T) {
+	requiresBins(t, "git")
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("upstream")
+	h.TempDir("scratch/sources")
+	up := h.Path("upstream")
+	h.RunGit(up, "init")
+	h.TempFile("upstream/foo.go", "package foo\n")
+	h.RunGit(up, "add", "foo.go")
+	h.RunGit(up, "-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "-m", "foo")
+	h.RunGit(up, "tag", "v1.0.0")
+
+	ctx := context.Background()
+	scratch := h.Path("scratch")
+	src, err := maybeGitSource{url: mkurl("file://" + filepath.ToSlash(up))}.try(ctx, scratch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg, err := newSourceGateway(ctx, src, newSupervisor(ctx), scratch, newMemoryCache(), nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vl, err := sg.listVersions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vl) != 2 {
+		t.Errorf("expected versions to be listed from upstream, got %v", vl)
+	}
+	if _, err := sg.listPackages(ctx, "", "", vl[0].Unpair()); errors.Cause(err) != ErrSourceManagerIsReadOnly {
+		t.Errorf("expected packages to be unavailable without a local copy, got %v", err)
+	}
+	if src.existsLocally(ctx) {
+		t.Error("expected no local copy of the source to be made")
+	}
+}
+
+// fileDeducer deduces the names in it to git sources at the file URLs of the
+// directories they map to.
+type fileDeducer map[string]string
+
+func (fd fileDeducer) deduceRootPath(ctx context.Context, path string) (pathDeduction, error) {
+	dir, has := fd[path]
+	if !has {
+		return pathDeduction{}, errNoKnownPathMatch
+	}
+	return pathDeduction{
+		root: path,
+		mb:   maybeSources{maybeGitSource{url: mkurl("file://" + filepath.ToSlash(dir))}},
+	}, nil
+}
+
+func TestSourceCoordinatorFallbacks(t *testing.T) {
+	requiresBins(t, "git")
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("upstream")
+	h.TempDir("cache/sources")
+	up := h.Path("upstream")
+	h.RunGit(up, "init")
+	h.TempFile("upstream/foo.go", "package foo\n")
+	h.RunGit(up, "add", "foo.go")
+	h.RunGit(up, "-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "-m", "foo")
+
+	ctx := context.Background()
+	superv := newSupervisor(ctx)
+	deducer := fileDeducer{
+		"mirror.example.com/foo":  filepath.Join(up, "missing"),
+		"archive.example.com/foo": filepath.Join(up, "missing-too"),
+		"example.com/foo":         up,
+	}
+	sc := newSourceCoordinator(superv, deducer, h.Path("cache"), nil, nil, log.New(test.Writer{TB: t}, "", 0))
+	defer sc.close()
+	id := ProjectIdentifier{ProjectRoot: "example.com/foo", Source: "mirror.example.com/foo"}
+	sc.setFallbacks(map[ProjectIdentifier][]string{
+		id: {"archive.example.com/foo", "example.com/foo"},
+	})
+
+	if _, known := sc.servedFrom(id); known {
+		t.Error("expected the source of the project not to be known before it is fetched")
+	}
+	sg, err := sc.getSourceGatewayFor(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, known := sc.servedFrom(id); got != "example.com/foo" || !known {
+		t.Errorf("expected the project to be served from the last fallback, got %q", got)
+	}
+	if again, err := sc.getSourceGatewayFor(ctx, id); err != nil || again != sg {
+		t.Errorf("expected the chosen gateway to be kept, got %v, %v", again, err)
+	}
+	if _, err := sg.listVersions(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without fallbacks, an unreachable source is an error.
+	if _, err := sc.getSourceGatewayFor(ctx, ProjectIdentifier{ProjectRoot: "example.com/foo", Source: "archive.example.com/foo"}); err == nil {
+		t.Error("expected an error for a source that cannot be reached")
+	}
+	if got, known := sc.servedFrom(ProjectIdentifier{ProjectRoot: "example.com/foo"}); got != "" || !known {
+		t.Errorf("expected a project without fallbacks to be served from its own source, got %q", got)
+	}
+}
+
+func TestSourceCoordinatorRetriesFallbacks(t *testing.T) {
+	requiresBins(t, "git")
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("cache/sources")
+	for _, dir := range []string{"mirror", "upstream"} {
+		h.TempFile(dir+"/foo.go", "package foo\n")
+		h.RunGit(h.Path(dir), "init")
+		h.RunGit(h.Path(dir), "add", "foo.go")
+		h.RunGit(h.Path(dir), "-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "-m", "foo")
+	}
+
+	ctx := context.Background()
+	superv := newSupervisor(ctx)
+	deducer := fileDeducer{
+		"mirror.example.com/foo": h.Path("mirror"),
+		"example.com/foo":        h.Path("upstream"),
+	}
+	sc := newSourceCoordinator(superv, deducer, h.Path("cache"), nil, nil, log.New(test.Writer{TB: t}, "", 0))
+	defer sc.close()
+	id := ProjectIdentifier{ProjectRoot: "example.com/foo", Source: "mirror.example.com/foo"}
+	sc.setFallbacks(map[ProjectIdentifier][]string{
+		id: {"example.com/foo"},
+	})
+
+	mirror, err := sc.getSourceGatewayFor(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The mirror can be reached, but an operation on it fails, so the
+	// operation is retried with the fallback, which is then kept.
+	var used []*sourceGateway
+	err = sc.withSourceGateway(ctx, id, func(sg *sourceGateway) error {
+		used = append(used, sg)
+		if sg == mirror {
+			return errors.New("fetch failed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(used) != 2 || used[1] == mirror {
+		t.Fatalf("expected the operation to be retried with the fallback, got %v", used)
+	}
+	if got, known := sc.servedFrom(id); got != "example.com/foo" || !known {
+		t.Errorf("expected the project to be served from the fallback, got %q", got)
+	}
+	if sg, err := sc.getSourceGatewayFor(ctx, id); err != nil || sg != used[1] {
+		t.Errorf("expected the fallback to be kept, got %v, %v", sg, err)
+	}
+
+	// Once the last source fails, there is nothing left to retry with; nor
+	// are failures to analyze what was retrieved retried.
+	for _, fail := range []error{errors.New("fetch failed"), analysisError{err: errors.New("bad manifest")}} {
+		used = nil
+		err = sc.withSourceGateway(ctx, id, func(sg *sourceGateway) error {
+			used = append(used, sg)
+			return fail
+		})
+		if err == nil || len(used) != 1 {
+			t.Errorf("expected %v not to be retried, got %v, %v", fail, used, err)
+		}
+	}
+}
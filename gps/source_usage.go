@@ -0,0 +1,167 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
+)
+
+// fetchLedgerName is the name of the file in the cache recording the bytes
+// fetched into each source, keyed by the name of its directory.
+const fetchLedgerName = "fetched.json"
+
+// fetchLedgerMu serializes updates of the fetch ledger within the process;
+// the lock of the SourceMgr does so across processes.
+var fetchLedgerMu sync.Mutex
+
+// SourceUsage describes the local copy of a source in the cache.
+type SourceUsage struct {
+	// Size is the number of bytes that the local copy takes on disk.
+	Size uint64
+	// Files is the number of files in the local copy.
+	Files int
+	// Fetched is the number of bytes fetched into the local copy, from
+	// upstream or from a cache backend, as recorded since the ledger of the
+	// cache was started. It is measured by the growth of the local copy, so
+	// data that replaced other data is not counted.
+	Fetched uint64
+}
+
+// diskUsage returns the number of bytes and of files in the regular files
+// beneath dir, which need not exist.
+func diskUsage(dir string) (size uint64, files int, err error) {
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += uint64(fi.Size())
+			files++
+		}
+		return nil
+	})
+	return size, files, err
+}
+
+// readFetchLedger reads the bytes fetched into each source in the cache at
+// cachedir.
+func readFetchLedger(cachedir string) (map[string]uint64, error) {
+	ledger := make(map[string]uint64)
+	b, err := ioutil.ReadFile(filepath.Join(cachedir, fetchLedgerName))
+	if os.IsNotExist(err) {
+		return ledger, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read the fetch ledger")
+	}
+	if err := json.Unmarshal(b, &ledger); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the fetch ledger")
+	}
+	return ledger, nil
+}
+
+// recordFetched adds n bytes to those fetched into the source whose directory
+// in the cache at cachedir is called name.
+func recordFetched(cachedir, name string, n uint64) error {
+	fetchLedgerMu.Lock()
+	defer fetchLedgerMu.Unlock()
+
+	ledger, err := readFetchLedger(cachedir)
+	if err != nil {
+		return err
+	}
+	ledger[name] += n
+	b, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write the ledger atomically, so that it survives an interrupted write.
+	tmp, err := ioutil.TempFile(cachedir, fetchLedgerName)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return fs.RenameWithFallback(tmp.Name(), filepath.Join(cachedir, fetchLedgerName))
+}
+
+// localSourceName returns the path of the local copy of the source, and the
+// name of its directory in the sources directory of the cache, if it has one.
+func (sg *sourceGateway) localSourceName() (path, name string, ok bool) {
+	ls, ok := sg.src.(interface {
+		localPath() string
+	})
+	if !ok {
+		return "", "", false
+	}
+	path = ls.localPath()
+	name, err := filepath.Rel(filepath.Join(sg.cachedir, "sources"), path)
+	if err != nil || strings.HasPrefix(name, "..") {
+		return "", "", false
+	}
+	return path, name, true
+}
+
+// recordingFetched calls fetch, which fetches data into the local copy of the
+// source, and records the growth of the local copy in the fetch ledger.
+// Failures to record it are ignored, as the ledger is only informational.
+func (sg *sourceGateway) recordingFetched(fetch func() error) error {
+	path, name, ok := sg.localSourceName()
+	if !ok {
+		return fetch()
+	}
+
+	before, _, _ := diskUsage(path)
+	err := fetch()
+	if after, _, derr := diskUsage(path); derr == nil && after > before {
+		recordFetched(sg.cachedir, name, after-before)
+	}
+	return err
+}
+
+// usage measures the local copy of the source, making one if there is none.
+func (sg *sourceGateway) usage(ctx context.Context) (SourceUsage, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	var u SourceUsage
+	path, name, ok := sg.localSourceName()
+	if !ok {
+		return u, errors.Errorf("%s has no local copy", sg.src.upstreamURL())
+	}
+	if err := sg.require(ctx, sourceExistsLocally); err != nil {
+		return u, err
+	}
+
+	var err error
+	if u.Size, u.Files, err = diskUsage(path); err != nil {
+		return u, errors.Wrapf(err, "failed to measure the local copy of %s", sg.src.upstreamURL())
+	}
+	ledger, err := readFetchLedger(sg.cachedir)
+	if err != nil {
+		return u, err
+	}
+	u.Fetched = ledger[name]
+	return u, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchLedger(t *testing.T) {
+	cachedir, err := ioutil.TempDir("", "fetchledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cachedir)
+
+	ledger, err := readFetchLedger(cachedir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledger) != 0 {
+		t.Fatalf("expected an empty ledger without a file, got %v", ledger)
+	}
+
+	for _, r := range []struct {
+		name string
+		n    uint64
+	}{{"github.com-foo-bar", 100}, {"github.com-baz-qux", 7}, {"github.com-foo-bar", 23}} {
+		if err := recordFetched(cachedir, r.name, r.n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ledger, err = readFetchLedger(cachedir); err != nil {
+		t.Fatal(err)
+	}
+	if ledger["github.com-foo-bar"] != 123 || ledger["github.com-baz-qux"] != 7 || len(ledger) != 2 {
+		t.Errorf("unexpected ledger %v", ledger)
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskusage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if size, files, err := diskUsage(filepath.Join(dir, "missing")); err != nil || size != 0 || files != 0 {
+		t.Errorf("expected nothing in a missing dir, got %d bytes in %d files (%v)", size, files, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	for path, size := range map[string]int{"x": 10, "a/y": 20, "a/b/z": 30} {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(path)), make([]byte, size), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if size, files, err := diskUsage(dir); err != nil || size != 60 || files != 3 {
+		t.Errorf("expected 60 bytes in 3 files, got %d in %d (%v)", size, files, err)
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/xml"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -54,27 +56,228 @@ func newVcsLocalErrorOr(err error, args []string, out, msg string) error {
 	return vcs.NewLocalError(msg, errors.Wrapf(err, "command failed: %v", args), out)
 }
 
-func (r *gitRepo) get(ctx context.Context) error {
-	cmd := commandContext(
-		ctx,
-		"git",
-		"clone",
-		"--recursive",
-		"-v",
-		"--progress",
-		r.Remote(),
-		r.LocalPath(),
-	)
+// gitCloneProgress is the file, in the .git directory of a clone that has not
+// been completed, that lists the steps of the clone that have been.
+const gitCloneProgress = "dep-clone"
+
+// gitCloneDepth is the number of commits of the history of the default branch
+// fetched by the first round of a clone. Each further round fetches twice as
+// many as the last.
+const gitCloneDepth = 64
+
+// gitCloneStep is a step of cloning a git repository.
+type gitCloneStep struct {
+	name string
+	run  func(context.Context) error
+}
+
+// cloneSteps returns the steps by which get clones r. Together, they do what
+// git clone --recursive does.
+func (r *gitRepo) cloneSteps() []gitCloneStep {
+	return []gitCloneStep{
+		{"init", func(ctx context.Context) error {
+			if _, err := r.runClone(ctx, false, "init", "--quiet"); err != nil {
+				return err
+			}
+			if _, err := r.runClone(ctx, false, "config", "remote.origin.url", r.Remote()); err != nil {
+				return err
+			}
+			_, err := r.runClone(ctx, false, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*")
+			return err
+		}},
+		// The default branch is fetched on its own first, as it generally
+		// holds most of the objects of the repository, so that they are kept
+		// even if fetching everything else fails, and in rounds, by
+		// fetchHistory, so that those of each round are kept even if a later
+		// one fails. A repository whose HEAD names no branch has no default
+		// branch, as with git clone.
+		{"head", func(ctx context.Context) error {
+			out, err := r.runClone(ctx, true, "ls-remote", "--symref", "origin", "HEAD")
+			if err != nil {
+				return err
+			}
+			var branch string
+			for _, line := range strings.Split(string(out), "\n") {
+				if fields := strings.Fields(line); len(fields) == 3 && fields[0] == "ref:" && fields[2] == "HEAD" {
+					branch = strings.TrimPrefix(fields[1], "refs/heads/")
+				}
+			}
+			if branch == "" {
+				return nil
+			}
+			if err := r.fetchHistory(ctx, branch); err != nil {
+				return err
+			}
+			_, err = r.runClone(ctx, false, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/"+branch)
+			return err
+		}},
+		{"refs", func(ctx context.Context) error {
+			_, err := r.runClone(ctx, true, "fetch", "--tags", "-v", "--progress", "origin")
+			return err
+		}},
+		{"checkout", func(ctx context.Context) error {
+			out, err := r.runClone(ctx, false, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+			if err != nil {
+				// There is no default branch to check out.
+				return nil
+			}
+			remote := strings.TrimSpace(string(out))
+			branch := strings.TrimPrefix(remote, "origin/")
+			_, err = r.runClone(ctx, false, "checkout", "--quiet", "-B", branch, "--track", remote)
+			return err
+		}},
+		{"submodules", func(ctx context.Context) error {
+			_, err := r.runClone(ctx, true, "submodule", "update", "--init", "--recursive")
+			return err
+		}},
+	}
+}
+
+// fetchHistory fetches the history of branch from origin as a step of cloning
+// the repository, in rounds of growing depth: a shallow fetch of gitCloneDepth
+// commits first, then deeper ones, until the history is complete. The objects
+// of each round are kept if a later one fails, so that a fetch interrupted part
+// way, as on a flaky network, resumes from the depth reached rather than
+// fetching the whole history again.
+//
+// Remotes that do not support shallow fetches, as over dumb HTTP, are fetched
+// in a single round, that is started again if interrupted.
+func (r *gitRepo) fetchHistory(ctx context.Context, branch string) error {
+	ref := "refs/remotes/origin/" + branch
+	refspec := "+refs/heads/" + branch + ":" + ref
+	shallow := filepath.Join(r.LocalPath(), ".git", "shallow")
+	fetch := func(args ...string) error {
+		args = append(append([]string{"fetch", "-v", "--progress"}, args...), "origin", refspec)
+		_, err := r.runClone(ctx, true, args...)
+		return err
+	}
+
+	depth := gitCloneDepth
+	if out, err := r.runClone(ctx, false, "rev-list", "--count", ref, "--"); err == nil {
+		if _, err := os.Stat(shallow); os.IsNotExist(err) {
+			// The whole history was fetched by an earlier attempt.
+			return nil
+		}
+		// Resume from the depth reached by an earlier attempt. As many
+		// commits as it fetched are at least as deep as that, and fetching
+		// less deep would make the clone shallower, discarding some.
+		n, err := strconv.Atoi(strings.TrimSpace(string(out)))
+		if err != nil {
+			return vcs.NewLocalError("unable to get repository", err, string(out))
+		}
+		if 2*n > depth {
+			depth = 2 * n
+		}
+	} else if err := fetch("--depth=" + strconv.Itoa(depth)); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		// The remote may not support shallow fetches.
+		return fetch()
+	} else {
+		depth *= 2
+	}
+
+	var last []byte
+	for ; ; depth *= 2 {
+		b, err := ioutil.ReadFile(shallow)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return vcs.NewLocalError("unable to get repository", err, "")
+		}
+		// The commits at the bottom of a history that is complete but only
+		// just as deep as the last round are still listed as shallow, and
+		// stay so however deep the next round. The rest of the history is
+		// then empty, but must still be fetched to clear them.
+		if last != nil && bytes.Equal(b, last) {
+			return fetch("--unshallow")
+		}
+		last = b
+
+		if err := fetch("--depth=" + strconv.Itoa(depth)); err != nil {
+			return err
+		}
+	}
+}
+
+// runClone runs git with args in the repository as a step of cloning it.
+// remote is true if the command talks to the remote.
+func (r *gitRepo) runClone(ctx context.Context, remote bool, args ...string) ([]byte, error) {
+	cmd := commandContext(ctx, "git", args...)
+	cmd.op = OpClone
+	cmd.SetDir(r.LocalPath())
 	// Ensure no prompting for PWs
-	cmd.SetEnv(append([]string{"GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0"}, os.Environ()...))
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return newVcsRemoteErrorOr(err, cmd.Args(), string(out),
-			"unable to get repository")
+	cmd.SetEnv(append([]string{"GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0"}, gitEnv()...))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if remote {
+			return nil, newVcsRemoteErrorOr(err, cmd.Args(), string(out), "unable to get repository")
+		}
+		return nil, newVcsLocalErrorOr(err, cmd.Args(), string(out), "unable to get repository")
 	}
+	return out, nil
+}
 
+// get clones the repository in the steps of cloneSteps, recording each step
+// in gitCloneProgress as it is completed. Unlike git clone, which deletes what
+// it has fetched when it fails, a clone that fails part way, as on a flaky
+// network, is left in place, and the next call resumes it from the first step
+// not completed. That step is run again from its start, but the history of the
+// default branch is fetched in rounds that are each kept, and the fetches of
+// the other steps reuse the objects already fetched, though not the objects of
+// a fetch that was itself interrupted.
+func (r *gitRepo) get(ctx context.Context) error {
+	progress := filepath.Join(r.LocalPath(), ".git", gitCloneProgress)
+	// The progress file is created before anything else, so that a clone is
+	// never taken for a complete one by CheckLocal.
+	if err := os.MkdirAll(filepath.Dir(progress), 0777); err != nil {
+		return vcs.NewLocalError("unable to get repository", err, "")
+	}
+	f, err := os.OpenFile(progress, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return vcs.NewLocalError("unable to get repository", err, "")
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return vcs.NewLocalError("unable to get repository", err, "")
+	}
+	done := make(map[string]bool)
+	for _, name := range strings.Fields(string(b)) {
+		done[name] = true
+	}
+
+	for _, step := range r.cloneSteps() {
+		if done[step.name] {
+			continue
+		}
+		if err := step.run(ctx); err != nil {
+			return err
+		}
+		if _, err := f.WriteString(step.name + "\n"); err != nil {
+			return vcs.NewLocalError("unable to get repository", err, "")
+		}
+	}
+
+	f.Close()
+	if err := os.Remove(progress); err != nil {
+		return vcs.NewLocalError("unable to get repository", err, "")
+	}
 	return nil
 }
 
+// CheckLocal reports whether the repository has been cloned. It shadows the
+// vcs.GitRepo method, so that a clone that has not been completed is not
+// taken for one.
+func (r *gitRepo) CheckLocal() bool {
+	if _, err := os.Stat(filepath.Join(r.LocalPath(), ".git", gitCloneProgress)); err == nil {
+		return false
+	}
+	return r.GitRepo.CheckLocal()
+}
+
 func (r *gitRepo) fetch(ctx context.Context) error {
 	cmd := commandContext(
 		ctx,
@@ -86,7 +289,7 @@ func (r *gitRepo) fetch(ctx context.Context) error {
 	)
 	cmd.SetDir(r.LocalPath())
 	// Ensure no prompting for PWs
-	cmd.SetEnv(append([]string{"GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0"}, os.Environ()...))
+	cmd.SetEnv(append([]string{"GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0"}, gitEnv()...))
 	if out, err := cmd.CombinedOutput(); err != nil {
 		return newVcsRemoteErrorOr(err, cmd.Args(), string(out),
 			"unable to update repository")
@@ -105,6 +308,53 @@ func (r *gitRepo) updateVersion(ctx context.Context, v string) error {
 	return r.defendAgainstSubmodules(ctx)
 }
 
+// IsReference reports whether r names a revision, branch or tag in the local
+// repository. It shadows the vcs.GitRepo method, so that git runs with gitEnv.
+func (r *gitRepo) IsReference(ref string) bool {
+	cmd := commandContext(context.TODO(), "git", "rev-parse", "--verify", ref)
+	cmd.SetDir(r.LocalPath())
+	if _, err := cmd.CombinedOutput(); err == nil {
+		return true
+	}
+
+	// Some refs will fail rev-parse. For example, a remote branch that has
+	// not been checked out yet. This next step should pickup the other
+	// possible references.
+	cmd = commandContext(context.TODO(), "git", "show-ref", ref)
+	cmd.SetDir(r.LocalPath())
+	_, err := cmd.CombinedOutput()
+	return err == nil
+}
+
+// CommitInfo retrieves metadata about a commit. It shadows the vcs.GitRepo
+// method, so that git runs with gitEnv.
+func (r *gitRepo) CommitInfo(id string) (*vcs.CommitInfo, error) {
+	cmd := commandContext(context.TODO(), "git", "log", "-1", "--format=%H%n%an <%ae>%n%at%n%s", id, "--")
+	cmd.SetDir(r.LocalPath())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, vcs.ErrRevisionUnavailable
+	}
+
+	fields := strings.SplitN(strings.TrimRight(string(out), "\n"), "\n", 4)
+	if len(fields) < 3 {
+		return nil, vcs.NewLocalError("Unable to retrieve commit information", errors.Errorf("unexpected output from git log"), string(out))
+	}
+	t, err := parseUnixTime([]byte(fields[2]))
+	if err != nil {
+		return nil, vcs.NewLocalError("Unable to retrieve commit information", err, string(out))
+	}
+	ci := &vcs.CommitInfo{
+		Commit: fields[0],
+		Author: fields[1],
+		Date:   t,
+	}
+	if len(fields) == 4 {
+		ci.Message = fields[3]
+	}
+	return ci, nil
+}
+
 // defendAgainstSubmodules tries to keep repo state sane in the event of
 // submodules. Or nested submodules. What a great idea, submodules.
 func (r *gitRepo) defendAgainstSubmodules(ctx context.Context) error {
@@ -120,7 +370,7 @@ func (r *gitRepo) defendAgainstSubmodules(ctx context.Context) error {
 		)
 		cmd.SetDir(r.LocalPath())
 		// Ensure no prompting for PWs
-		cmd.SetEnv(append([]string{"GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0"}, os.Environ()...))
+		cmd.SetEnv(append([]string{"GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0"}, gitEnv()...))
 		if out, err := cmd.CombinedOutput(); err != nil {
 			return newVcsLocalErrorOr(err, cmd.Args(), string(out),
 				"unexpected error while defensively updating submodules")
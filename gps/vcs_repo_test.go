@@ -9,10 +9,16 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Masterminds/vcs"
+	"github.com/golang/dep/internal/test"
 )
 
 // original implementation of these test files come from
@@ -20,6 +26,21 @@ import (
 
 const gitRemoteTestRepo = "https://github.com/Masterminds/VCSTestRepo"
 
+func TestWithGitConfig(t *testing.T) {
+	got := withGitConfig([]string{"HOME=/home/dep"}, "safe.directory", "*")
+	want := []string{"HOME=/home/dep", "GIT_CONFIG_KEY_0=safe.directory", "GIT_CONFIG_VALUE_0=*", "GIT_CONFIG_COUNT=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected env:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+
+	// Config already set through the environment is kept.
+	got = withGitConfig([]string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=core.autocrlf", "GIT_CONFIG_VALUE_0=false"}, "safe.directory", "*")
+	want = []string{"GIT_CONFIG_KEY_0=core.autocrlf", "GIT_CONFIG_VALUE_0=false", "GIT_CONFIG_KEY_1=safe.directory", "GIT_CONFIG_VALUE_1=*", "GIT_CONFIG_COUNT=2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected env:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+}
+
 func TestErrs(t *testing.T) {
 	err := newVcsLocalErrorOr(context.Canceled, nil, "", "")
 	if err != context.Canceled {
@@ -278,6 +299,118 @@ func testGitRepo(t *testing.T) {
 	}
 }
 
+func TestGitRepoGetResumes(t *testing.T) {
+	requiresBins(t, "git")
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("cache")
+	up := filepath.Join(h.Path("cache"), "upstream")
+
+	ctx := context.Background()
+	rep, err := vcs.NewGitRepo("file://"+filepath.ToSlash(up), filepath.Join(h.Path("cache"), "repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &gitRepo{rep}
+
+	// Upstream does not exist yet, so the clone fails after its first step.
+	if err := repo.get(ctx); err == nil {
+		t.Fatal("expected the clone of a missing repository to fail")
+	}
+	if repo.CheckLocal() {
+		t.Fatal("expected a clone that failed not to be taken for a complete one")
+	}
+	progress, err := ioutil.ReadFile(filepath.Join(repo.LocalPath(), ".git", gitCloneProgress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(progress) != "init\n" {
+		t.Fatalf("unexpected steps recorded as done: %q", progress)
+	}
+
+	h.TempDir("cache/upstream")
+	h.RunGit(up, "init")
+	h.TempFile("cache/upstream/foo.go", "package foo\n")
+	h.RunGit(up, "add", "foo.go")
+	h.RunGit(up, "-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "-m", "foo")
+	h.RunGit(up, "tag", "v1.0.0")
+
+	if err := repo.get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.CheckLocal() {
+		t.Fatal("expected the resumed clone to be complete")
+	}
+	if _, err := os.Stat(filepath.Join(repo.LocalPath(), ".git", gitCloneProgress)); !os.IsNotExist(err) {
+		t.Errorf("expected the progress of the clone to be removed, got %v", err)
+	}
+	if !repo.IsReference("v1.0.0") {
+		t.Error("expected the tags of the repository to be fetched")
+	}
+	if _, err := os.Stat(filepath.Join(repo.LocalPath(), "foo.go")); err != nil {
+		t.Errorf("expected the default branch to be checked out: %v", err)
+	}
+	if err := repo.fetch(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitRepoFetchHistoryResumes(t *testing.T) {
+	requiresBins(t, "git")
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("upstream")
+	up := h.Path("upstream")
+	h.RunGit(up, "init")
+	commits := func(n int) {
+		for i := 0; i < n; i++ {
+			h.RunGit(up, "-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "--allow-empty", "-m", "commit")
+		}
+	}
+	h.RunGit(up, "checkout", "-b", "main")
+	// A history exactly as deep as the first round leaves its root listed as
+	// shallow.
+	commits(gitCloneDepth)
+
+	ctx := context.Background()
+	clone := func(name string) *gitRepo {
+		h.TempDir(name)
+		rep, err := vcs.NewGitRepo("file://"+filepath.ToSlash(up), h.Path(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo := &gitRepo{rep}
+		if err := repo.cloneSteps()[0].run(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	}
+	complete := func(repo *gitRepo, want int) {
+		t.Helper()
+		if err := repo.fetchHistory(ctx, "main"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(repo.LocalPath(), ".git", "shallow")); !os.IsNotExist(err) {
+			t.Errorf("expected the history to be complete, got %v", err)
+		}
+		out, err := repo.runClone(ctx, false, "rev-list", "--count", "refs/remotes/origin/main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(out)); got != strconv.Itoa(want) {
+			t.Errorf("expected %d commits, got %s", want, got)
+		}
+	}
+
+	complete(clone("whole"), gitCloneDepth)
+
+	// An earlier attempt fetched part of the history only.
+	repo := clone("resumed")
+	h.RunGit(repo.LocalPath(), "fetch", "--depth=3", "origin", "+refs/heads/main:refs/remotes/origin/main")
+	commits(gitCloneDepth)
+	complete(repo, 2*gitCloneDepth)
+}
+
 func testBzrRepo(t *testing.T) {
 	t.Parallel()
 
@@ -346,3 +479,54 @@ func testBzrRepo(t *testing.T) {
 		t.Fatalf("Current failed to detect Bzr on rev 2 of branch. Got version: %s", v)
 	}
 }
+
+func TestGitRepoLocalCommitInfo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	dir, err := ioutil.TempDir("", "go-vcs-git-tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	up, local := filepath.Join(dir, "upstream"), filepath.Join(dir, "local")
+	if err := os.Mkdir(up, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init"},
+		{"-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "--allow-empty", "-m", "first\n\nbody"},
+		{"tag", "v1.0.0"},
+		{"clone", up, local},
+	} {
+		c := exec.Command("git", args...)
+		c.Dir = up
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+
+	rep, err := vcs.NewGitRepo(up, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &gitRepo{rep}
+
+	ci, err := repo.CommitInfo("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ci.Commit) != 40 || ci.Author != "dep <dep@example.com>" || ci.Message != "first" || ci.Date.IsZero() {
+		t.Errorf("unexpected commit info: %+v", ci)
+	}
+	if !repo.IsReference("v1.0.0") || !repo.IsReference(ci.Commit) {
+		t.Error("expected the tag and its commit to be references")
+	}
+	if repo.IsReference("v2.0.0") {
+		t.Error("expected a missing tag not to be a reference")
+	}
+	if _, err := repo.CommitInfo("v2.0.0"); err != vcs.ErrRevisionUnavailable {
+		t.Errorf("expected ErrRevisionUnavailable for a missing tag, got %v", err)
+	}
+}
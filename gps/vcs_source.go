@@ -8,21 +8,30 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/golang/dep/gps/pkgtree"
 	"github.com/golang/dep/internal/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
 )
 
 type baseVCSSource struct {
 	repo ctxRepo
 }
 
+func (bs *baseVCSSource) localPath() string {
+	return bs.repo.LocalPath()
+}
+
 func (bs *baseVCSSource) sourceType() string {
 	return string(bs.repo.Vcs())
 }
@@ -55,15 +64,15 @@ func (bs *baseVCSSource) disambiguateRevision(ctx context.Context, r Revision) (
 	return Revision(ci.Commit), nil
 }
 
-func (bs *baseVCSSource) getManifestAndLock(ctx context.Context, pr ProjectRoot, r Revision, an ProjectAnalyzer) (Manifest, Lock, error) {
+func (bs *baseVCSSource) getManifestAndLock(ctx context.Context, pr ProjectRoot, dir string, r Revision, an ProjectAnalyzer) (Manifest, Lock, error) {
 	err := bs.repo.updateVersion(ctx, r.String())
 	if err != nil {
 		return nil, nil, unwrapVcsErr(err)
 	}
 
-	m, l, err := an.DeriveManifestAndLock(bs.repo.LocalPath(), pr)
+	m, l, err := an.DeriveManifestAndLock(filepath.Join(bs.repo.LocalPath(), filepath.FromSlash(dir)), pr)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, analysisError{err: err}
 	}
 
 	if l != nil && l != Lock(nil) {
@@ -123,13 +132,16 @@ func (bs *baseVCSSource) maybeClean(ctx context.Context) error {
 	return nil
 }
 
-func (bs *baseVCSSource) listPackages(ctx context.Context, pr ProjectRoot, r Revision) (ptree pkgtree.PackageTree, err error) {
+func (bs *baseVCSSource) listPackages(ctx context.Context, pr ProjectRoot, dir string, r Revision, platforms []pkgtree.Platform) (ptree pkgtree.PackageTree, err error) {
 	err = bs.repo.updateVersion(ctx, r.String())
 
 	if err != nil {
 		err = unwrapVcsErr(err)
 	} else {
-		ptree, err = pkgtree.ListPackages(bs.repo.LocalPath(), string(pr))
+		ptree, err = pkgtree.ListPackagesForPlatforms(filepath.Join(bs.repo.LocalPath(), filepath.FromSlash(dir)), string(pr), platforms)
+		if err != nil {
+			err = analysisError{err: err}
+		}
 	}
 
 	return
@@ -146,9 +158,43 @@ func (bs *baseVCSSource) exportRevisionTo(ctx context.Context, r Revision, to st
 		return unwrapVcsErr(err)
 	}
 
+	if exportLinks != ExportCopies {
+		paths, err := workingTreeFiles(bs.repo.LocalPath())
+		if err != nil {
+			return err
+		}
+		// Only git is known to replace the files of its working tree rather
+		// than change them in place, so these are never hard linked.
+		return fs.LinkDir(bs.repo.LocalPath(), to, paths, false)
+	}
 	return fs.CopyDir(bs.repo.LocalPath(), to)
 }
 
+// workingTreeFiles lists the slash-separated paths of the files in the
+// working tree at dir, leaving out the metadata of the VCS.
+func workingTreeFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			switch fi.Name() {
+			case ".git", ".hg", ".bzr", ".svn":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	return paths, err
+}
+
 var (
 	gitHashRE = regexp.MustCompile(`^[a-f0-9]{40}$`)
 )
@@ -159,7 +205,126 @@ type gitSource struct {
 	baseVCSSource
 }
 
+// checkoutBatchSize bounds the length of the paths passed to each run of git
+// checkout-index.
+const checkoutBatchSize = 16 << 10
+
 func (s *gitSource) exportRevisionTo(ctx context.Context, rev Revision, to string) error {
+	return s.checkoutRevisionTo(ctx, rev, nil, to)
+}
+
+// exportPrunedRevisionTo writes out only the parts of the tree at rev that
+// pruning may keep, then prunes them as PruneProject would the whole tree.
+//
+// When unused packages are pruned, that is the files of the packages of lp,
+// the preserved legal files and non-Go source files anywhere, and symlinks:
+// for a project that is a large monorepo, of which only a few packages are
+// imported, it is a small fraction of the tree. The result is the same as
+// pruning the whole tree, as the lock records only the packages and prune
+// options either way.
+func (s *gitSource) exportPrunedRevisionTo(ctx context.Context, rev Revision, lp LockedProject, prune PruneOptions, to string) error {
+	var paths []string
+	if prune&PruneUnusedPackages != 0 {
+		var err error
+		if paths, err = s.sparsePaths(ctx, rev, lp.Packages()); err != nil {
+			return err
+		}
+	}
+
+	if err := s.checkoutRevisionTo(ctx, rev, paths, to); err != nil {
+		return err
+	}
+	return PruneProject(to, lp, prune)
+}
+
+// sparsePaths lists the paths in the tree at rev that pruning unused packages
+// other than pkgs may keep.
+func (s *gitSource) sparsePaths(ctx context.Context, rev Revision, pkgs []string) ([]string, error) {
+	imported := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		imported[norm.NFC.String(pkg)] = true
+	}
+
+	files, err := s.treeFiles(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	// Not nil, even if empty, as that would write out the whole tree.
+	paths := []string{}
+	for _, f := range files {
+		dir := path.Dir(f.name)
+		switch {
+		case f.mode == "120000",
+			imported[norm.NFC.String(dir)],
+			isPreservedFile(path.Base(f.name)),
+			isSourceFile(f.name) && fileExt(f.name) != ".go":
+			paths = append(paths, f.name)
+		}
+	}
+	return paths, nil
+}
+
+// gitTreeFile is a file in a git tree, with its mode as git gives it.
+type gitTreeFile struct {
+	mode, name string
+}
+
+// treeFiles lists the files in the tree at rev. Submodules are left out, as
+// checkout-index does not write them out.
+func (s *gitSource) treeFiles(ctx context.Context, rev Revision) ([]gitTreeFile, error) {
+	cmd := commandContext(ctx, "git", "ls-tree", "-r", "-z", "--full-tree", rev.String())
+	cmd.SetDir(s.repo.LocalPath())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrap(err, string(out))
+	}
+
+	var files []gitTreeFile
+	for _, entry := range bytes.Split(out, []byte{0}) {
+		// Each entry is "<mode> <type> <object>\t<path>".
+		i := bytes.IndexByte(entry, '\t')
+		if i < 0 {
+			continue
+		}
+		meta, name := strings.Fields(string(entry[:i])), string(entry[i+1:])
+		if len(meta) != 3 || meta[1] != "blob" {
+			continue
+		}
+		files = append(files, gitTreeFile{mode: meta[0], name: name})
+	}
+	return files, nil
+}
+
+// linkRevisionTo writes out the tree at rev to the directory to, or only the
+// files at paths in it if paths is not nil, as checkoutRevisionTo does, but by
+// checking out rev in the cached repository and linking its files, as set by
+// SetExportLinks.
+func (s *gitSource) linkRevisionTo(ctx context.Context, rev Revision, paths []string, to string) error {
+	if paths == nil {
+		files, err := s.treeFiles(ctx, rev)
+		if err != nil {
+			return err
+		}
+		paths = make([]string, 0, len(files))
+		for _, f := range files {
+			paths = append(paths, f.name)
+		}
+	}
+
+	if err := s.repo.updateVersion(ctx, rev.String()); err != nil {
+		return unwrapVcsErr(err)
+	}
+	return fs.LinkDir(s.repo.LocalPath(), to, paths, exportLinks == ExportHardlinks)
+}
+
+// checkoutRevisionTo writes out the tree at rev to the directory to, or only
+// the files at paths in it if paths is not nil.
+func (s *gitSource) checkoutRevisionTo(ctx context.Context, rev Revision, paths []string, to string) error {
+	if exportLinks != ExportCopies {
+		return s.linkRevisionTo(ctx, rev, paths, to)
+	}
+
 	r := s.repo
 
 	if err := os.MkdirAll(to, 0777); err != nil {
@@ -195,17 +360,74 @@ func (s *gitSource) exportRevisionTo(ctx context.Context, rev Revision, to strin
 	// though we have a bunch of housekeeping to do to set up, then tear
 	// down, the sparse checkout controls, as well as restore the original
 	// index and HEAD.
-	{
+	if paths == nil {
 		cmd := commandContext(ctx, "git", "checkout-index", "-a", "--prefix="+to)
 		cmd.SetDir(r.LocalPath())
 		if out, err := cmd.CombinedOutput(); err != nil {
 			return errors.Wrap(err, string(out))
 		}
+		return nil
+	}
+
+	// Paths are passed in batches, to keep each command line well under the
+	// limits of any platform.
+	for len(paths) > 0 {
+		args := []string{"checkout-index", "--prefix=" + to, "--"}
+		for size := 0; len(paths) > 0 && size < checkoutBatchSize; paths = paths[1:] {
+			args = append(args, paths[0])
+			size += len(paths[0]) + 1
+		}
+		cmd := commandContext(ctx, "git", args...)
+		cmd.SetDir(r.LocalPath())
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errors.Wrap(err, string(out))
+		}
 	}
 
 	return nil
 }
 
+// rootRevisions lists all commits without parents that are reachable from any
+// ref in the local repository. Two repositories that share a root revision
+// share history; typically, one is a fork of the other.
+func (s *gitSource) rootRevisions(ctx context.Context) ([]Revision, error) {
+	cmd := commandContext(ctx, "git", "rev-list", "--max-parents=0", "--all")
+	cmd.SetDir(s.repo.LocalPath())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrap(err, string(out))
+	}
+
+	var roots []Revision
+	for _, line := range bytes.Split(bytes.TrimSpace(out), []byte("\n")) {
+		if s.isValidHash(line) {
+			roots = append(roots, Revision(line))
+		}
+	}
+	return roots, nil
+}
+
+// revisionTime returns the committer date of r in the local repository.
+func (s *gitSource) revisionTime(ctx context.Context, r Revision) (time.Time, error) {
+	return s.commitTime(ctx, string(r))
+}
+
+// latestActivity returns the most recent committer date of any commit that is
+// reachable from a ref in the local repository.
+func (s *gitSource) latestActivity(ctx context.Context) (time.Time, error) {
+	return s.commitTime(ctx, "--all")
+}
+
+func (s *gitSource) commitTime(ctx context.Context, rev string) (time.Time, error) {
+	cmd := commandContext(ctx, "git", "log", "-1", "--format=%ct", rev)
+	cmd.SetDir(s.repo.LocalPath())
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, string(out))
+	}
+	return parseUnixTime(bytes.TrimSpace(out))
+}
+
 func (s *gitSource) isValidHash(hash []byte) bool {
 	return gitHashRE.Match(hash)
 }
@@ -215,24 +437,9 @@ func (*gitSource) existsCallsListVersions() bool {
 }
 
 func (s *gitSource) listVersions(ctx context.Context) (vlist []PairedVersion, err error) {
-	r := s.repo
-
-	cmd := commandContext(ctx, "git", "ls-remote", r.Remote())
-	// We want to invoke from a place where it's not possible for there to be a
-	// .git file instead of a .git directory, as git ls-remote will choke on the
-	// former and erroneously quit. However, we can't be sure that the repo
-	// exists on disk yet at this point; if it doesn't, then instead use the
-	// parent of the local path, as that's still likely a good bet.
-	if r.CheckLocal() {
-		cmd.SetDir(r.LocalPath())
-	} else {
-		cmd.SetDir(filepath.Dir(r.LocalPath()))
-	}
-	// Ensure no prompting for PWs
-	cmd.SetEnv(append([]string{"GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0"}, os.Environ()...))
-	out, err := cmd.CombinedOutput()
+	out, err := s.lsRemote(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, string(out))
+		return nil, err
 	}
 
 	all := bytes.Split(bytes.TrimSpace(out), []byte("\n"))
@@ -351,6 +558,39 @@ func (s *gitSource) listVersions(ctx context.Context) (vlist []PairedVersion, er
 	return
 }
 
+// lsRemote lists the refs of the upstream repository, as git ls-remote does.
+// If the repository is served from a host for which UsePureGit was called,
+// refs are listed without running git where possible.
+func (s *gitSource) lsRemote(ctx context.Context) ([]byte, error) {
+	r := s.repo
+
+	if u, err := url.Parse(r.Remote()); err == nil && usePureGit(u) {
+		out, err := lsRemoteHTTP(ctx, u)
+		if err != errNotSmartHTTP {
+			return out, err
+		}
+	}
+
+	cmd := commandContext(ctx, "git", "ls-remote", r.Remote())
+	// We want to invoke from a place where it's not possible for there to be a
+	// .git file instead of a .git directory, as git ls-remote will choke on the
+	// former and erroneously quit. However, we can't be sure that the repo
+	// exists on disk yet at this point; if it doesn't, then instead use the
+	// parent of the local path, as that's still likely a good bet.
+	if r.CheckLocal() {
+		cmd.SetDir(r.LocalPath())
+	} else {
+		cmd.SetDir(filepath.Dir(r.LocalPath()))
+	}
+	// Ensure no prompting for PWs
+	cmd.SetEnv(append([]string{"GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0"}, gitEnv()...))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrap(err, string(out))
+	}
+	return out, nil
+}
+
 // gopkginSource is a specialized git source that performs additional filtering
 // according to the input URL.
 type gopkginSource struct {
@@ -549,6 +789,49 @@ func (s *hgSource) hgCmd(ctx context.Context, args ...string) cmd {
 	return cmd
 }
 
+// rootRevisions lists all changesets without parents in the local repository.
+func (s *hgSource) rootRevisions(ctx context.Context) ([]Revision, error) {
+	cmd := s.hgCmd(ctx, "log", "-r", "roots(all())", "--template", "{node}\\n")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrap(err, string(out))
+	}
+
+	var roots []Revision
+	for _, line := range bytes.Split(bytes.TrimSpace(out), []byte("\n")) {
+		if len(line) > 0 {
+			roots = append(roots, Revision(line))
+		}
+	}
+	return roots, nil
+}
+
+// revisionTime returns the commit date of the changeset r in the local
+// repository.
+func (s *hgSource) revisionTime(ctx context.Context, r Revision) (time.Time, error) {
+	return s.changesetTime(ctx, string(r))
+}
+
+// latestActivity returns the most recent commit date of any changeset in the
+// local repository.
+func (s *hgSource) latestActivity(ctx context.Context) (time.Time, error) {
+	return s.changesetTime(ctx, "sort(all(), -date)")
+}
+
+func (s *hgSource) changesetTime(ctx context.Context, rev string) (time.Time, error) {
+	cmd := s.hgCmd(ctx, "log", "-l", "1", "-r", rev, "--template", "{date|hgdate}")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, string(out))
+	}
+	// hgdate is the unix time followed by the offset of the committer's zone.
+	fields := bytes.Fields(out)
+	if len(fields) == 0 {
+		return time.Time{}, errors.Errorf("no changeset matching %s", rev)
+	}
+	return parseUnixTime(fields[0])
+}
+
 func (s *hgSource) listVersions(ctx context.Context) ([]PairedVersion, error) {
 	var vlist []PairedVersion
 
@@ -653,3 +936,13 @@ func (s *hgSource) listVersions(ctx context.Context) ([]PairedVersion, error) {
 
 	return vlist, nil
 }
+
+// parseUnixTime parses a number of seconds since the Unix epoch, as printed by
+// a VCS.
+func parseUnixTime(b []byte) (time.Time, error) {
+	secs, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Errorf("unexpected commit time %q", b)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
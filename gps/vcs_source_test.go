@@ -128,6 +128,33 @@ func testGitSourceInteractions(t *testing.T) {
 	} else if !is {
 		t.Errorf("Revision that should exist was not present on re-check")
 	}
+
+	roots, err := src.rootRevisions(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error while listing root revisions: %s", err)
+	} else if len(roots) == 0 {
+		t.Errorf("Expected at least one root revision in git test repo")
+	} else {
+		for _, r := range roots {
+			if !src.isValidHash([]byte(r)) {
+				t.Errorf("Root revision %q is not a valid git hash", r)
+			}
+		}
+	}
+
+	rt, err := src.revisionTime(ctx, Revision("30605f6ac35fcb075ad0bfa9296f90a7d891523e"))
+	if err != nil {
+		t.Errorf("Unexpected error while reading revision time: %s", err)
+	} else if rt.IsZero() {
+		t.Errorf("Expected a non-zero revision time")
+	}
+
+	latest, err := src.latestActivity(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error while reading latest activity: %s", err)
+	} else if latest.Before(rt) {
+		t.Errorf("Latest activity %s is earlier than the time of a revision in the repo, %s", latest, rt)
+	}
 }
 
 func testGopkginSourceInteractions(t *testing.T) {
@@ -867,3 +894,209 @@ func requiresBins(t *testing.T, bins ...string) {
 		}
 	}
 }
+
+func TestGitSourceExportPrunedRevisionTo(t *testing.T) {
+	requiresBins(t, "git")
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("upstream")
+	h.TempDir("scratch/sources")
+	h.TempDir("full")
+	h.TempDir("sparse")
+	up := h.Path("upstream")
+	h.RunGit(up, "init")
+	for name, contents := range map[string]string{
+		"mono.go":                   "package mono\n",
+		"LICENSE":                   "license\n",
+		"client/client.go":          "package client\n",
+		"client/client_test.go":     "package client\n",
+		"client/README.md":          "readme\n",
+		"client/internal/x/x.go":    "package x\n",
+		"client/vendor/dep/dep.go":  "package dep\n",
+		"cgo/cgo.go":                "package cgo\n\nimport \"C\"\n",
+		"cgo/cgo.c":                 "int cgo;\n",
+		"server/server.go":          "package server\n",
+		"server/NOTICE":             "notice\n",
+		"server/helper.h":           "int helper;\n",
+		"server/data/big.dat":       "data\n",
+		"docs/guide.md":             "guide\n",
+		"docs/internal/AUTHORS.txt": "authors\n",
+	} {
+		h.TempFile(filepath.Join("upstream", filepath.FromSlash(name)), contents)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("client", filepath.Join(up, "link")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.RunGit(up, "add", ".")
+	h.RunGit(up, "-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "-m", "mono")
+	h.RunGit(up, "tag", "v1.0.0")
+
+	ctx := context.Background()
+	scratch := h.Path("scratch")
+	src, err := maybeGitSource{url: mkurl("file://" + filepath.ToSlash(up))}.try(ctx, scratch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg, err := newSourceGateway(ctx, src, newSupervisor(ctx), scratch, newMemoryCache(), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vl, err := sg.listVersions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v Version
+	for _, pv := range vl {
+		if pv.String() == "v1.0.0" {
+			v = pv
+		}
+	}
+
+	// tree lists the files, symlinks and directories under dir.
+	tree := func(t *testing.T, dir string) []string {
+		t.Helper()
+		var names []string
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || path == dir {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+
+	cases := []struct {
+		name  string
+		pkgs  []string
+		prune PruneOptions
+	}{
+		{"unused", []string{"client"}, PruneUnusedPackages | PruneNestedVendorDirs},
+		{"all", []string{"client", "server"}, PruneUnusedPackages | PruneNonGoFiles | PruneGoTestFiles | PruneNestedVendorDirs},
+		{"native", []string{"cgo"}, PruneUnusedPackages},
+		{"root", []string{"."}, PruneUnusedPackages | PruneNestedVendorDirs},
+		{"none", []string{}, PruneUnusedPackages},
+		{"whole", []string{"client"}, PruneGoTestFiles},
+	}
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lp := NewLockedProject(mkPI("example.com/mono"), v, c.pkgs)
+
+			full := filepath.Join(h.Path("full"), c.name)
+			if err := sg.exportVersionTo(ctx, v, full); err != nil {
+				t.Fatal(err)
+			}
+			if err := PruneProject(full, lp, c.prune); err != nil {
+				t.Fatal(err)
+			}
+
+			sparse := filepath.Join(h.Path("sparse"), c.name)
+			if err := sg.exportPrunedVersionTo(ctx, lp, c.prune, sparse); err != nil {
+				t.Fatal(err)
+			}
+
+			if want, got := tree(t, full), tree(t, sparse); !reflect.DeepEqual(want, got) {
+				t.Errorf("(%d) expected the pruned export to hold\n\t%v\ngot\n\t%v", i, want, got)
+			}
+		})
+	}
+}
+
+func TestGitSourceExportLinks(t *testing.T) {
+	requiresBins(t, "git")
+	defer SetExportLinks(ExportCopies)
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("upstream")
+	h.TempDir("scratch/sources")
+	h.TempDir("export")
+	up := h.Path("upstream")
+	h.RunGit(up, "init")
+	h.TempFile("upstream/foo.go", "package foo\n")
+	h.TempFile("upstream/sub/sub.go", "package sub\n")
+	h.RunGit(up, "add", ".")
+	h.RunGit(up, "-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "-m", "first")
+	h.RunGit(up, "tag", "v1.0.0")
+	h.TempFile("upstream/foo.go", "package foo // v2\n")
+	h.RunGit(up, "add", ".")
+	h.RunGit(up, "-c", "user.name=dep", "-c", "user.email=dep@example.com", "commit", "-m", "second")
+	h.RunGit(up, "tag", "v2.0.0")
+
+	ctx := context.Background()
+	scratch := h.Path("scratch")
+	src, err := maybeGitSource{url: mkurl("file://" + filepath.ToSlash(up))}.try(ctx, scratch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg, err := newSourceGateway(ctx, src, newSupervisor(ctx), scratch, newMemoryCache(), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vl, err := sg.listVersions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := make(map[string]Version)
+	for _, pv := range vl {
+		versions[pv.String()] = pv
+	}
+
+	read := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(h.Path("export"), filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	cached := filepath.Join(src.(*gitSource).repo.LocalPath(), "foo.go")
+
+	for _, mode := range []ExportLinks{ExportReflinks, ExportHardlinks} {
+		SetExportLinks(mode)
+		dir := filepath.Join(h.Path("export"), mode.String())
+		if err := sg.exportVersionTo(ctx, versions["v1.0.0"], filepath.Join(dir, "v1")); err != nil {
+			t.Fatal(err)
+		}
+		if err := sg.exportVersionTo(ctx, versions["v2.0.0"], filepath.Join(dir, "v2")); err != nil {
+			t.Fatal(err)
+		}
+
+		// Checking out v2 in the cache must leave the files linked for v1 alone.
+		if got := read(mode.String() + "/v1/foo.go"); got != "package foo\n" {
+			t.Errorf("%s: expected foo.go of v1 to be unchanged, got %q", mode, got)
+		}
+		if got := read(mode.String() + "/v2/foo.go"); got != "package foo // v2\n" {
+			t.Errorf("%s: unexpected foo.go of v2: %q", mode, got)
+		}
+		if got := read(mode.String() + "/v2/sub/sub.go"); got != "package sub\n" {
+			t.Errorf("%s: unexpected sub/sub.go of v2: %q", mode, got)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "v2", ".git")); !os.IsNotExist(err) {
+			t.Errorf("%s: did not expect .git to be exported, got %v", mode, err)
+		}
+	}
+
+	// Only hard links share their files with the cache, so that patching an
+	// exported file in place must leave the cache alone otherwise.
+	cfi, err := os.Stat(cached)
+	h.Must(err)
+	hfi, err := os.Stat(filepath.Join(h.Path("export"), "hardlink", "v2", "foo.go"))
+	h.Must(err)
+	if !os.SameFile(cfi, hfi) {
+		t.Error("expected foo.go to be hard linked to the cache")
+	}
+	h.Must(ioutil.WriteFile(filepath.Join(h.Path("export"), "reflink", "v2", "foo.go"), []byte("patched"), 0666))
+	b, err := ioutil.ReadFile(cached)
+	h.Must(err)
+	if string(b) != "package foo // v2\n" {
+		t.Errorf("expected the cached working tree to be unchanged, got %q", b)
+	}
+}
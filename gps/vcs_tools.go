@@ -0,0 +1,157 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// vcsToolNames are the names of the VCS binaries that can be pinned.
+var vcsToolNames = []string{"bzr", "git", "hg", "svn"}
+
+// A VCSTool pins the binary run for a version control system.
+type VCSTool struct {
+	// Name is the name of the tool: "git", "hg", "bzr" or "svn".
+	Name string
+	// Path is the absolute path of the binary, whose base name must be Name.
+	Path string
+	// Constraint, if not nil, must be satisfied by the version the binary
+	// reports.
+	Constraint Constraint
+}
+
+var (
+	// pinnedTools maps the names of pinned tools to their binaries.
+	pinnedTools map[string]string
+	// hermeticTools forbids looking up tools that are not pinned in PATH.
+	hermeticTools bool
+)
+
+// ConfigureTools pins the VCS binaries run by gps to those in tools, after
+// checking that each exists and reports a version satisfying its constraint.
+// The directories holding them are also put at the front of PATH, so that
+// anything else in the process looking the tools up there, such as the
+// libraries gps relies on, finds the same binaries.
+//
+// If hermetic is true, tools are never looked up in PATH, and sources using a
+// VCS whose binary is not pinned cannot be used at all.
+//
+// ConfigureTools must be called before any SourceManager is created.
+func ConfigureTools(tools []VCSTool, hermetic bool) error {
+	pinned := make(map[string]string, len(tools))
+	var dirs []string
+	for _, t := range tools {
+		if _, has := pinned[t.Name]; has {
+			return errors.Errorf("%s is pinned more than once", t.Name)
+		}
+		if err := checkTool(t); err != nil {
+			return err
+		}
+		pinned[t.Name] = t.Path
+		dirs = append(dirs, filepath.Dir(t.Path))
+	}
+
+	if len(dirs) > 0 {
+		if path := os.Getenv("PATH"); path != "" {
+			dirs = append(dirs, path)
+		}
+		os.Setenv("PATH", strings.Join(dirs, string(os.PathListSeparator)))
+	}
+	pinnedTools, hermeticTools = pinned, hermetic
+	return nil
+}
+
+// isVCSTool reports whether name is the name of a VCS tool.
+func isVCSTool(name string) bool {
+	for _, n := range vcsToolNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTool checks that t names a usable binary.
+func checkTool(t VCSTool) error {
+	if !isVCSTool(t.Name) {
+		return errors.Errorf("cannot pin unknown tool %q, expected one of %s", t.Name, strings.Join(vcsToolNames, ", "))
+	}
+	if !filepath.IsAbs(t.Path) {
+		return errors.Errorf("path of %s must be absolute, got %q", t.Name, t.Path)
+	}
+	if toolName(t.Path) != t.Name {
+		return errors.Errorf("path of %s must name a binary called %s, got %q", t.Name, t.Name, t.Path)
+	}
+
+	fi, err := os.Stat(t.Path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to use %s", t.Path)
+	}
+	if fi.IsDir() {
+		return errors.Errorf("unable to use %s: is a directory", t.Path)
+	}
+	if t.Constraint == nil {
+		return nil
+	}
+
+	v, err := toolVersion(t.Path)
+	if err != nil {
+		return err
+	}
+	if !t.Constraint.Matches(v) {
+		return errors.Errorf("%s is version %s of %s, which does not satisfy %s", t.Path, v, t.Name, t.Constraint)
+	}
+	return nil
+}
+
+// toolVersionRE matches the version in the output of --version of each tool,
+// as in "git version 2.17.1", or "Mercurial Distributed SCM (version 4.5.3)".
+var toolVersionRE = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// toolVersion returns the version reported by the binary at path.
+func toolVersion(path string) (Version, error) {
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the version of %s", path)
+	}
+	v := toolVersionRE.Find(out)
+	if v == nil {
+		return nil, errors.Errorf("no version in the output of %s --version", path)
+	}
+	return NewVersion(string(v)), nil
+}
+
+// toolPath returns the binary to run for the tool called name.
+func toolPath(name string) string {
+	if path, has := pinnedTools[name]; has {
+		return path
+	}
+	return name
+}
+
+// useTool returns an error if the tool called name may not be run, because it
+// is not pinned, and tools may not be looked up in PATH.
+func useTool(name string) error {
+	if _, has := pinnedTools[name]; hermeticTools && !has {
+		return errors.Errorf("no %s binary is pinned, and tools may not be looked up in PATH", name)
+	}
+	return nil
+}
+
+// toolInstalled reports whether the tool called name may be run, and its
+// binary can be found.
+func toolInstalled(name string) bool {
+	if useTool(name) != nil {
+		return false
+	}
+	_, err := exec.LookPath(toolPath(name))
+	return err == nil
+}
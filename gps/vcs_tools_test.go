@@ -0,0 +1,78 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestConfigureTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "vcstools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	git := filepath.Join(dir, "git")
+	if err := ioutil.WriteFile(git, []byte("#!/bin/sh\necho git version 2.17.1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(path string) {
+		os.Setenv("PATH", path)
+		pinnedTools, hermeticTools = nil, false
+	}(os.Getenv("PATH"))
+
+	newer, err := NewSemverConstraint(">=2.20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := map[string]VCSTool{
+		"unknown tool":    {Name: "cvs", Path: git},
+		"relative path":   {Name: "git", Path: "bin/git"},
+		"other binary":    {Name: "hg", Path: git},
+		"missing binary":  {Name: "git", Path: filepath.Join(dir, "missing", "git")},
+		"too old version": {Name: "git", Path: git, Constraint: newer},
+	}
+	for name, tool := range bad {
+		if err := ConfigureTools([]VCSTool{tool}, false); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := ConfigureTools([]VCSTool{{Name: "git", Path: git}, {Name: "git", Path: git}}, false); err == nil {
+		t.Error("expected an error for a tool pinned twice")
+	}
+
+	older, err := NewSemverConstraint("^2.17")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ConfigureTools([]VCSTool{{Name: "git", Path: git, Constraint: older}}, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := commandContext(context.Background(), "git", "version").Args()[0]; got != git {
+		t.Errorf("expected git to be run from %s, got %s", git, got)
+	}
+	if !strings.HasPrefix(os.Getenv("PATH"), dir+string(os.PathListSeparator)) {
+		t.Errorf("expected %s at the front of PATH, got %s", dir, os.Getenv("PATH"))
+	}
+	if err := useTool("git"); err != nil {
+		t.Errorf("expected the pinned git to be usable: %v", err)
+	}
+	if err := useTool("hg"); err == nil {
+		t.Error("expected hg not to be usable without being pinned")
+	}
+	if _, err := (maybeHgSource{url: mkurl("https://example.com/foo")}).try(context.Background(), dir); err == nil {
+		t.Error("expected hg sources not to be usable without a pinned hg")
+	}
+}
@@ -18,14 +18,17 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/golang/dep/gps"
 	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
 )
 
 // HashVersion is an arbitrary number that identifies the hash algorithm used by
 // the directory hasher.
 //
 //   1: SHA256, as implemented in crypto/sha256
-const HashVersion = 1
+//   2: as 1, with names hashed and ordered in Unicode Normalization Form C
+const HashVersion = 2
 
 const osPathSeparator = string(filepath.Separator)
 
@@ -158,6 +161,12 @@ type dirWalkClosure struct {
 // Symbolic links are excluded, as they are not considered valid elements in the
 // definition of a Go module.
 func DigestFromDirectory(osDirname string) (VersionedDigest, error) {
+	return digestFromDirectory(osDirname, nil)
+}
+
+// digestFromDirectory is DigestFromDirectory, also recording each regular file
+// in idx, if it is not nil.
+func digestFromDirectory(osDirname string, idx *treeIndex) (VersionedDigest, error) {
 	osDirname = filepath.Clean(osDirname)
 
 	// Create a single hash instance for the entire operation, rather than a new
@@ -170,7 +179,7 @@ func DigestFromDirectory(osDirname string) (VersionedDigest, error) {
 		someHash:      sha256.New(),
 	}
 
-	err := filepath.Walk(osDirname, func(osPathname string, info os.FileInfo, err error) error {
+	err := walkNormalized(osDirname, func(osPathname string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -226,8 +235,10 @@ func DigestFromDirectory(osDirname string) (VersionedDigest, error) {
 		// Write the relative pathname to hash because the hash is a function of
 		// the node names, node types, and node contents. Added benefit is that
 		// empty directories, named pipes, sockets, and devices. Use
-		// `filepath.ToSlash` to ensure relative pathname is os-agnostic.
-		writeBytesWithNull(closure.someHash, []byte(filepath.ToSlash(osRelative)))
+		// `filepath.ToSlash` to ensure relative pathname is os-agnostic, and
+		// NFC to ensure it does not depend on how the filesystem stores
+		// non-ASCII names.
+		writeBytesWithNull(closure.someHash, []byte(norm.NFC.String(filepath.ToSlash(osRelative))))
 
 		binary.LittleEndian.PutUint32(closure.someModeBytes, uint32(mt)) // encode the type of mode
 		writeBytesWithNull(closure.someHash, closure.someModeBytes)      // and write to hash
@@ -242,10 +253,25 @@ func DigestFromDirectory(osDirname string) (VersionedDigest, error) {
 			return errors.Wrap(err, "cannot Open")
 		}
 
+		var w io.Writer = closure.someHash
+		var fileHash hash.Hash
+		if idx != nil {
+			fileHash = sha256.New()
+			w = io.MultiWriter(closure.someHash, fileHash)
+		}
+
 		var bytesWritten int64
-		bytesWritten, err = io.CopyBuffer(closure.someHash, newLineEndingReader(fh), closure.someCopyBufer) // fast copy of file contents to hash
-		err = errors.Wrap(err, "cannot Copy")                                                               // errors.Wrap only wraps non-nil, so skip extra check
-		writeBytesWithNull(closure.someHash, []byte(strconv.FormatInt(bytesWritten, 10)))                   // 10: format file size as base 10 integer
+		bytesWritten, err = io.CopyBuffer(w, newLineEndingReader(fh), closure.someCopyBufer) // fast copy of file contents to hash
+		err = errors.Wrap(err, "cannot Copy")                                                // errors.Wrap only wraps non-nil, so skip extra check
+		writeBytesWithNull(closure.someHash, []byte(strconv.FormatInt(bytesWritten, 10)))    // 10: format file size as base 10 integer
+
+		if idx != nil {
+			*idx = append(*idx, indexedFile{
+				path: filepath.ToSlash(osRelative),
+				size: info.Size(),
+				sum:  fileHash.Sum(nil),
+			})
+		}
 
 		// Close the file handle to the open file without masking
 		// possible previous error value.
@@ -374,6 +400,22 @@ func ParseVersionedDigest(input string) (VersionedDigest, error) {
 // solidus, one particular dependency would be represented as
 // "github.com/alice/alice1".
 func CheckDepTree(osDirname string, wantDigests map[string]VersionedDigest) (map[string]VendorStatus, error) {
+	return checkDepTree(osDirname, wantDigests, digestMatches)
+}
+
+// digestMatches reports whether the digest of the directory at osPathname is
+// want.
+func digestMatches(osPathname string, want VersionedDigest) (bool, error) {
+	got, err := DigestFromDirectory(osPathname)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(got.Digest, want.Digest), nil
+}
+
+// checkDepTree is CheckDepTree, comparing each project that has a digest of
+// the current hash version with matches.
+func checkDepTree(osDirname string, wantDigests map[string]VersionedDigest, matches func(osPathname string, want VersionedDigest) (bool, error)) (map[string]VendorStatus, error) {
 	osDirname = filepath.Clean(osDirname)
 
 	// Create associative array to store the results of calling this function.
@@ -444,8 +486,13 @@ func CheckDepTree(osDirname string, wantDigests map[string]VersionedDigest) (map
 	// project is later found while traversing the vendor root hierarchy, its
 	// status will be updated to reflect whether its digest is empty, or,
 	// whether or not it matches the expected digest.
+	//
+	// Projects are found by their names in Unicode Normalization Form C, as
+	// the filesystem may store them otherwise.
+	wantPathnames := make(map[string]string, len(wantDigests))
 	for slashPathname := range wantDigests {
 		slashStatus[slashPathname] = NotInTree
+		wantPathnames[norm.NFC.String(slashPathname)] = slashPathname
 	}
 
 	for len(queue) > 0 {
@@ -457,18 +504,20 @@ func CheckDepTree(osDirname string, wantDigests map[string]VersionedDigest) (map
 		slashPathname := filepath.ToSlash(currentNode.osRelative)
 		osPathname := filepath.Join(osDirname, currentNode.osRelative)
 
-		if expectedSum, ok := wantDigests[slashPathname]; ok {
+		if wantPathname, ok := wantPathnames[norm.NFC.String(slashPathname)]; ok {
+			slashPathname = wantPathname
+			expectedSum := wantDigests[slashPathname]
 			ls := EmptyDigestInLock
 			if expectedSum.HashVersion != HashVersion {
 				if !expectedSum.IsEmpty() {
 					ls = HashVersionMismatch
 				}
 			} else if len(expectedSum.Digest) > 0 {
-				projectSum, err := DigestFromDirectory(osPathname)
+				match, err := matches(osPathname, expectedSum)
 				if err != nil {
 					return nil, errors.Wrap(err, "cannot compute dependency hash")
 				}
-				if bytes.Equal(projectSum.Digest, expectedSum.Digest) {
+				if match {
 					ls = NoMismatch
 				} else {
 					ls = DigestMismatchInLock
@@ -491,6 +540,11 @@ func CheckDepTree(osDirname string, wantDigests map[string]VersionedDigest) (map
 			return nil, errors.Wrap(err, "cannot get sorted list of directory children")
 		}
 		for _, osChildName := range osChildrenNames {
+			if currentNode.osRelative == "" && (osChildName == gps.ProvenanceFile || osChildName == gps.ModulesFile) {
+				// dep's own records of the vendor tree's contents, rather than
+				// orphaned files.
+				continue
+			}
 			switch osChildName {
 			case ".", "..", "vendor", ".bzr", ".git", ".hg", ".svn":
 				// skip
@@ -532,8 +586,55 @@ func CheckDepTree(osDirname string, wantDigests map[string]VersionedDigest) (map
 	return slashStatus, nil
 }
 
-// sortedChildrenFromDirname returns a lexicographically sorted list of child
-// nodes for the specified directory.
+// walkNormalized is filepath.Walk, but visits the entries of each directory in
+// the order of their names in Unicode Normalization Form C, so that the order
+// does not depend on how the filesystem stores non-ASCII names.
+func walkNormalized(root string, walkFn filepath.WalkFunc) error {
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walkNormalizedDir(root, info, walkFn)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walkNormalizedDir(path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+
+	names, err := sortedChildrenFromDirname(path)
+	err1 := walkFn(path, info, err)
+	// Either the directory cannot be read, or walkFn skips it or stops.
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	for _, name := range names {
+		filename := filepath.Join(path, name)
+		fileInfo, err := os.Lstat(filename)
+		if err != nil {
+			if err := walkFn(filename, fileInfo, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		if err := walkNormalizedDir(filename, fileInfo, walkFn); err != nil {
+			if !fileInfo.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedChildrenFromDirname returns a list of child nodes for the specified
+// directory, sorted lexicographically by their names in Unicode Normalization
+// Form C.
 func sortedChildrenFromDirname(osDirname string) ([]string, error) {
 	fh, err := os.Open(osDirname)
 	if err != nil {
@@ -544,7 +645,13 @@ func sortedChildrenFromDirname(osDirname string) ([]string, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot Readdirnames")
 	}
-	sort.Strings(osChildrenNames)
+	sort.Slice(osChildrenNames, func(i, j int) bool {
+		ni, nj := norm.NFC.String(osChildrenNames[i]), norm.NFC.String(osChildrenNames[j])
+		if ni != nj {
+			return ni < nj
+		}
+		return osChildrenNames[i] < osChildrenNames[j]
+	})
 
 	// Close the file handle to the open directory without masking possible
 	// previous error value.
@@ -7,9 +7,14 @@ package verify
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/golang/dep/gps"
 )
 
 // crossBuffer is a test io.Reader that emits a few canned responses.
@@ -234,6 +239,35 @@ func TestVerifyDepTree(t *testing.T) {
 	})
 }
 
+func TestCheckDepTreeIgnoresProvenance(t *testing.T) {
+	vendorRoot, err := ioutil.TempDir("", "dep-provenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vendorRoot)
+
+	for _, p := range []string{gps.ProvenanceFile, "orphan.txt", filepath.Join("github.com", gps.ProvenanceFile)} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(vendorRoot, p)), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(vendorRoot, p), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, err := CheckDepTree(vendorRoot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]VendorStatus{
+		"orphan.txt": NotInLock,
+		"github.com": NotInLock,
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("unexpected vendor status:\n\t(GOT): %v\n\t(WNT): %v", status, want)
+	}
+}
+
 func BenchmarkDigestFromDirectory(b *testing.B) {
 	b.Skip("Eliding benchmark of user's Go source directory")
 
@@ -259,3 +293,52 @@ func BenchmarkVerifyDepTree(b *testing.B) {
 		}
 	}
 }
+
+func TestDigestIgnoresNormalizationOfNames(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "dep-nfc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// The same project, as written where names are precomposed, and as
+	// HFS+ would store it, decomposed.
+	const composed, decomposed = "github.com/jos\u00e9/lib", "github.com/jose\u0301/lib"
+	write := func(vendor, project string) {
+		for _, name := range []string{"a.go", "caf\u00e9.go", "z.go"} {
+			contents := []byte(name)
+			if project == decomposed {
+				name = strings.Replace(name, "\u00e9", "e\u0301", -1)
+			}
+			path := filepath.Join(tmp, vendor, filepath.FromSlash(project), name)
+			if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, contents, 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write("nfc", composed)
+	write("nfd", decomposed)
+
+	want, err := DigestFromDirectory(filepath.Join(tmp, "nfc", filepath.FromSlash(composed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DigestFromDirectory(filepath.Join(tmp, "nfd", filepath.FromSlash(decomposed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Digest, want.Digest) {
+		t.Errorf("expected the digest of decomposed names to be %s, got %s", want, got)
+	}
+
+	status, err := CheckDepTree(filepath.Join(tmp, "nfd"), map[string]VersionedDigest{composed: want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantStatus := map[string]VendorStatus{composed: NoMismatch}; !reflect.DeepEqual(status, wantStatus) {
+		t.Errorf("unexpected vendor status:\n\t(GOT): %v\n\t(WNT): %v", status, wantStatus)
+	}
+}
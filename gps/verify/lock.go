@@ -15,4 +15,17 @@ type VerifiableProject struct {
 	gps.LockedProject
 	PruneOpts gps.PruneOptions
 	Digest    VersionedDigest
+	// PseudoVersion is the go modules pseudo-version of the locked revision,
+	// recorded for projects locked to a revision or a branch, rather than to
+	// a tag. It is empty if it has not been recorded.
+	PseudoVersion string
+	// TestOnly is set for projects that are reachable only through the
+	// imports of the _test.go files of the current project.
+	TestOnly bool
+	// FetchedFrom is the fallback source from which the locked revision was
+	// fetched, when its own source could not be reached.
+	FetchedFrom string
+	// UpdateReason is the reason given for updating the project to a new
+	// major version, if one was.
+	UpdateReason string
 }
@@ -29,6 +29,7 @@ const (
 	PruneOptsChanged
 	HashVersionChanged
 	HashChanged
+	RootDirChanged
 	AnyChanged = (1 << iota) - 1
 )
 
@@ -57,6 +58,7 @@ type LockedProjectPropertiesDelta struct {
 	VersionBefore, VersionAfter         gps.UnpairedVersion
 	RevisionBefore, RevisionAfter       gps.Revision
 	SourceBefore, SourceAfter           string
+	RootDirBefore, RootDirAfter         string
 	PruneOptsBefore, PruneOptsAfter     gps.PruneOptions
 	HashVersionBefore, HashVersionAfter int
 	HashChanged                         bool
@@ -175,8 +177,10 @@ func findAddedAndRemoved(l1, l2 []string) (add, remove []string) {
 // in properties, not intrinsic identity.
 func DiffLockedProjectProperties(lp1, lp2 gps.LockedProject) LockedProjectPropertiesDelta {
 	ld := LockedProjectPropertiesDelta{
-		SourceBefore: lp1.Ident().Source,
-		SourceAfter:  lp2.Ident().Source,
+		SourceBefore:  lp1.Ident().Source,
+		SourceAfter:   lp2.Ident().Source,
+		RootDirBefore: lp1.Ident().RootDir,
+		RootDirAfter:  lp2.Ident().RootDir,
 	}
 
 	ld.PackagesAdded, ld.PackagesRemoved = findAddedAndRemoved(lp1.Packages(), lp2.Packages())
@@ -317,6 +321,9 @@ func (ld LockedProjectPropertiesDelta) Changed(dims DeltaDimension) bool {
 	if dims&SourceChanged != 0 && ld.SourceChanged() {
 		return true
 	}
+	if dims&RootDirChanged != 0 && ld.RootDirChanged() {
+		return true
+	}
 	if dims&RevisionChanged != 0 && ld.RevisionChanged() {
 		return true
 	}
@@ -346,6 +353,9 @@ func (ld LockedProjectPropertiesDelta) Changes() DeltaDimension {
 	if ld.SourceChanged() {
 		dd |= SourceChanged
 	}
+	if ld.RootDirChanged() {
+		dd |= RootDirChanged
+	}
 	if ld.RevisionChanged() {
 		dd |= RevisionChanged
 	}
@@ -374,6 +384,12 @@ func (ld LockedProjectPropertiesDelta) SourceChanged() bool {
 	return ld.SourceBefore != ld.SourceAfter
 }
 
+// RootDirChanged returns true if the root-dir field differed between the first
+// and second locks.
+func (ld LockedProjectPropertiesDelta) RootDirChanged() bool {
+	return ld.RootDirBefore != ld.RootDirAfter
+}
+
 // VersionChanged returns true if the version property differed between the
 // first and second locks. In addition to simple changes (e.g. 1.0.1 -> 1.0.2),
 // this also includes all possible version type changes either going from a
@@ -5,12 +5,43 @@
 package verify
 
 import (
+	"path"
+	"sort"
+	"strings"
+
 	radix "github.com/armon/go-radix"
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/gps/paths"
 	"github.com/golang/dep/gps/pkgtree"
 )
 
+// InputsVersion identifies the rules by which the input imports recorded in a
+// lock are normalized and compared against the current inputs. It is recorded
+// in locks, so that a lock written under different rules is not reported as
+// out of sync merely because the rules changed.
+//
+// Version 1 compares slash-separated, cleaned import paths. Import paths are
+// case-sensitive, so paths that differ only in case are different imports.
+const InputsVersion = 1
+
+// InputsVersioner is implemented by locks that record the InputsVersion used
+// when writing them. Locks that do not implement it, or that report zero, are
+// assumed to be compatible with the current rules.
+type InputsVersioner interface {
+	InputsVersion() int
+}
+
+// NormalizeImportPath returns the form of ip used when comparing input
+// imports: slash-separated, and without redundant or trailing separators.
+// Backslashes are never valid in import paths, so they are taken to be path
+// separators that leaked in from Windows.
+func NormalizeImportPath(ip string) string {
+	if ip == "" {
+		return ip
+	}
+	return path.Clean(strings.Replace(ip, `\`, "/", -1))
+}
+
 // LockSatisfaction holds the compound result of LockSatisfiesInputs, allowing
 // the caller to inspect each of several orthogonal possible types of failure.
 //
@@ -27,12 +58,27 @@ type LockSatisfaction struct {
 	// ExcessImports is the set of import paths that were present in the Lock
 	// but absent from the inputs.
 	ExcessImports []string
+	// InputsVersionMismatch is set if the Lock records input imports compared
+	// under newer rules than this version of dep knows about. The imports are
+	// not compared in that case, and MissingImports and ExcessImports are
+	// empty, but the Lock is not taken to satisfy the inputs.
+	InputsVersionMismatch bool
 	// UnmatchedConstraints reports any normal, non-override constraint rules that
 	// were not satisfied by the corresponding LockedProject in the Lock.
 	UnmetConstraints map[gps.ProjectRoot]ConstraintMismatch
 	// UnmatchedOverrides reports any override rules that were not satisfied by the
 	// corresponding LockedProject in the Lock.
 	UnmetOverrides map[gps.ProjectRoot]ConstraintMismatch
+	// UnmetRootDirs reports the LockedProjects in the Lock whose root
+	// directory is not the one the manifest sets, by override or constraint,
+	// if it sets one.
+	UnmetRootDirs map[gps.ProjectRoot]RootDirMismatch
+}
+
+// RootDirMismatch is a two-tuple of the root directory of a project set in a
+// manifest, and the differing one recorded in a Lock.
+type RootDirMismatch struct {
+	Want, Locked string
 }
 
 // ConstraintMismatch is a two-tuple of a gps.Version, and a gps.Constraint that
@@ -59,6 +105,7 @@ func LockSatisfiesInputs(l gps.Lock, m gps.RootManifest, ptree pkgtree.PackageTr
 		LockExisted:      true,
 		UnmetOverrides:   make(map[gps.ProjectRoot]ConstraintMismatch),
 		UnmetConstraints: make(map[gps.ProjectRoot]ConstraintMismatch),
+		UnmetRootDirs:    make(map[gps.ProjectRoot]RootDirMismatch),
 	}
 
 	var ig *pkgtree.IgnoredRuleset
@@ -71,56 +118,18 @@ func LockSatisfiesInputs(l gps.Lock, m gps.RootManifest, ptree pkgtree.PackageTr
 	rm, _ := ptree.ToReachMap(true, true, false, ig)
 	reach := rm.FlattenFn(paths.IsStandardImportPath)
 
-	inlock := make(map[string]bool, len(l.InputImports()))
 	ininputs := make(map[string]bool, len(reach)+len(req))
-
-	type lockUnsatisfy uint8
-	const (
-		missingFromLock lockUnsatisfy = iota
-		inAdditionToLock
-	)
-
-	pkgDiff := make(map[string]lockUnsatisfy)
-
 	for _, imp := range reach {
-		ininputs[imp] = true
+		ininputs[NormalizeImportPath(imp)] = true
 	}
-
 	for imp := range req {
-		ininputs[imp] = true
-	}
-
-	for _, imp := range l.InputImports() {
-		inlock[imp] = true
-	}
-
-	for ip := range ininputs {
-		if !inlock[ip] {
-			pkgDiff[ip] = missingFromLock
-		} else {
-			// So we don't have to revisit it below
-			delete(inlock, ip)
-		}
-	}
-
-	// Something in the missing list might already be in the packages list,
-	// because another package in the depgraph imports it. We could make a
-	// special case for that, but it would break the simplicity of the model and
-	// complicate the notion of LockSatisfaction.Passed(), so let's see if we
-	// can get away without it.
-
-	for ip := range inlock {
-		if !ininputs[ip] {
-			pkgDiff[ip] = inAdditionToLock
-		}
+		ininputs[NormalizeImportPath(imp)] = true
 	}
 
-	for ip, typ := range pkgDiff {
-		if typ == missingFromLock {
-			lsat.MissingImports = append(lsat.MissingImports, ip)
-		} else {
-			lsat.ExcessImports = append(lsat.ExcessImports, ip)
-		}
+	if iv, ok := l.(InputsVersioner); ok && iv.InputsVersion() > InputsVersion {
+		lsat.InputsVersionMismatch = true
+	} else {
+		lsat.MissingImports, lsat.ExcessImports = diffInputImports(ininputs, l.InputImports())
 	}
 
 	eff := findEffectualConstraints(m, ininputs)
@@ -129,6 +138,19 @@ func LockSatisfiesInputs(l gps.Lock, m gps.RootManifest, ptree pkgtree.PackageTr
 	for _, lp := range l.Projects() {
 		pr := lp.Ident().ProjectRoot
 
+		dir := constraints[pr].RootDir
+		if pp, has := ovr[pr]; has && pp.RootDir != "" {
+			dir = pp.RootDir
+		}
+		// Without a root directory in the manifest, the one in the Lock may
+		// have been set by a dependency.
+		if dir != "" && dir != lp.Ident().RootDir {
+			lsat.UnmetRootDirs[pr] = RootDirMismatch{
+				Want:   dir,
+				Locked: lp.Ident().RootDir,
+			}
+		}
+
 		if pp, has := ovr[pr]; has {
 			if !pp.Constraint.Matches(lp.Version()) {
 				lsat.UnmetOverrides[pr] = ConstraintMismatch{
@@ -160,6 +182,10 @@ func (ls LockSatisfaction) Satisfied() bool {
 		return false
 	}
 
+	if ls.InputsVersionMismatch {
+		return false
+	}
+
 	if len(ls.MissingImports) > 0 {
 		return false
 	}
@@ -176,9 +202,37 @@ func (ls LockSatisfaction) Satisfied() bool {
 		return false
 	}
 
+	if len(ls.UnmetRootDirs) > 0 {
+		return false
+	}
+
 	return true
 }
 
+// diffInputImports returns the sorted lists of imports that are in inputs but
+// not in locked, and that are in locked but not in inputs.
+func diffInputImports(inputs map[string]bool, locked []string) (missing, excess []string) {
+	inlock := make(map[string]bool, len(locked))
+	for _, imp := range locked {
+		inlock[NormalizeImportPath(imp)] = true
+	}
+
+	for ip := range inputs {
+		if !inlock[ip] {
+			missing = append(missing, ip)
+		}
+	}
+	for ip := range inlock {
+		if !inputs[ip] {
+			excess = append(excess, ip)
+		}
+	}
+
+	sort.Strings(missing)
+	sort.Strings(excess)
+	return missing, excess
+}
+
 func findEffectualConstraints(m gps.Manifest, imports map[string]bool) map[string]bool {
 	eff := make(map[string]bool)
 	xt := radix.New()
@@ -5,6 +5,7 @@
 package verify
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -20,11 +21,12 @@ const (
 	excessImports
 	unmatchedOverrides
 	unmatchedConstraints
+	unmatchedRootDirs
 )
 
 func (lsd lockUnsatisfactionDimension) String() string {
 	var parts []string
-	for i := uint(0); i < 5; i++ {
+	for i := uint(0); i < 6; i++ {
 		if lsd&(1<<i) != 0 {
 			switch lsd {
 			case noLock:
@@ -37,6 +39,8 @@ func (lsd lockUnsatisfactionDimension) String() string {
 				parts = append(parts, "unmatched overrides")
 			case unmatchedConstraints:
 				parts = append(parts, "unmatched constraints")
+			case unmatchedRootDirs:
+				parts = append(parts, "unmatched root directories")
 			}
 		}
 	}
@@ -129,6 +133,16 @@ func TestLockSatisfaction(t *testing.T) {
 				}
 			},
 		},
+		"unmatched root directory": {
+			rmt: dup.setRootDir("baz.com/qux", "go"),
+			sat: unmatchedRootDirs,
+			checkfn: func(t *testing.T, lsat LockSatisfaction) {
+				want := RootDirMismatch{Want: "go"}
+				if got := lsat.UnmetRootDirs["baz.com/qux"]; got != want {
+					t.Errorf("wanted %+v for unmet root directory, got %+v", want, got)
+				}
+			},
+		},
 		"acceptable override": {
 			rmt: dup.setOverride("baz.com/qux", bazversion.Unpair(), ""),
 		},
@@ -202,6 +216,9 @@ func (ls LockSatisfaction) unsatTypes() lockUnsatisfactionDimension {
 	if len(ls.UnmetConstraints) != 0 {
 		dims |= unmatchedConstraints
 	}
+	if len(ls.UnmetRootDirs) != 0 {
+		dims |= unmatchedRootDirs
+	}
 
 	return dims
 }
@@ -251,9 +268,69 @@ func (rmt rootManifestTransformer) setOverride(pr string, c gps.Constraint, sour
 	})
 }
 
+func (rmt rootManifestTransformer) setRootDir(pr string, dir string) rootManifestTransformer {
+	return rmt.compose(func(rm simpleRootManifest) simpleRootManifest {
+		pp, has := rm.c[gps.ProjectRoot(pr)]
+		if !has {
+			pp.Constraint = gps.Any()
+		}
+		pp.RootDir = dir
+		rm.c[gps.ProjectRoot(pr)] = pp
+		return rm
+	})
+}
+
 func (rmt rootManifestTransformer) addIgnore(path string) rootManifestTransformer {
 	return rmt.compose(func(rm simpleRootManifest) simpleRootManifest {
 		rm.ig = pkgtree.NewIgnoredRuleset(append(rm.ig.ToSlice(), path))
 		return rm
 	})
 }
+
+type versionedLock struct {
+	safeLock
+	v int
+}
+
+func (l versionedLock) InputsVersion() int { return l.v }
+
+func TestLockSatisfactionNormalizesImports(t *testing.T) {
+	ptree := pkgtree.PackageTree{
+		ImportRoot: "current",
+		Packages: map[string]pkgtree.PackageOrErr{
+			"current": {
+				P: pkgtree.Package{
+					Name:       "current",
+					ImportPath: "current",
+					Imports:    []string{"foo.com/bar", "baz.com/qux", "new.com/b", "new.com/a", "case.com/Upper"},
+				},
+			},
+		},
+	}
+	rm := simpleRootManifest{}
+
+	l := safeLock{
+		i: []string{`baz.com\qux`, "foo.com/bar/", "old.com/b", "old.com/a", "case.com/upper"},
+	}
+
+	lsat := LockSatisfiesInputs(l, rm, ptree)
+	// Import paths are case-sensitive, so those that differ only in case are
+	// not the same import.
+	if want := []string{"case.com/Upper", "new.com/a", "new.com/b"}; !reflect.DeepEqual(lsat.MissingImports, want) {
+		t.Errorf("unexpected missing imports:\n\t(GOT): %v\n\t(WNT): %v", lsat.MissingImports, want)
+	}
+	if want := []string{"case.com/upper", "old.com/a", "old.com/b"}; !reflect.DeepEqual(lsat.ExcessImports, want) {
+		t.Errorf("unexpected excess imports:\n\t(GOT): %v\n\t(WNT): %v", lsat.ExcessImports, want)
+	}
+	if lsat.InputsVersionMismatch {
+		t.Error("did not expect an inputs version mismatch for an unversioned lock")
+	}
+
+	lsat = LockSatisfiesInputs(versionedLock{safeLock: l, v: InputsVersion + 1}, rm, ptree)
+	if !lsat.InputsVersionMismatch || len(lsat.MissingImports) != 0 || len(lsat.ExcessImports) != 0 {
+		t.Errorf("expected imports not to be compared for a lock from a newer version, got %+v", lsat)
+	}
+	if lsat.Satisfied() {
+		t.Error("expected a lock from a newer version not to satisfy inputs it cannot compare")
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultSamples is the number of files of each project whose contents
+// CheckDepTreeSampled hashes, unless SampleOptions says otherwise.
+const DefaultSamples = 8
+
+// SampleOptions configures CheckDepTreeSampled.
+type SampleOptions struct {
+	// IndexDir is the directory holding an index of the files of each
+	// project that has been verified in full, by digest.
+	IndexDir string
+	// Samples is the number of files of each project whose contents are
+	// hashed. If it is zero, DefaultSamples is used; if it is negative, none
+	// are.
+	Samples int
+	// Rand picks the files to hash. If it is nil, a source seeded from the
+	// time is used.
+	Rand *rand.Rand
+}
+
+// CheckDepTreeSampled is a fast, but weaker, form of CheckDepTree, for vendor
+// trees too large to hash in full routinely.
+//
+// The first time a project is checked against a digest, its digest is computed
+// in full, as by CheckDepTree, and if it matches, the path, size and hash of
+// each of its files is recorded in an index in opts.IndexDir. From then on, the
+// project is taken to match the digest if it holds the same files, of the same
+// sizes, as the index, and the contents of a random sample of them hash as
+// recorded. Changes to other files, or to empty directories, go unnoticed; use
+// CheckDepTree to catch those.
+//
+// Indexes that cannot be written are not an error; the projects concerned are
+// just checked in full again the next time.
+func CheckDepTreeSampled(osDirname string, wantDigests map[string]VersionedDigest, opts SampleOptions) (map[string]VendorStatus, error) {
+	if opts.Samples == 0 {
+		opts.Samples = DefaultSamples
+	}
+	if opts.Rand == nil {
+		opts.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return checkDepTree(osDirname, wantDigests, opts.digestMatches)
+}
+
+func (opts SampleOptions) digestMatches(osPathname string, want VersionedDigest) (bool, error) {
+	idxPath := filepath.Join(opts.IndexDir, fmt.Sprintf("%d-%x", want.HashVersion, want.Digest))
+	if idx, err := readTreeIndex(idxPath); err == nil {
+		return idx.sampleMatches(osPathname, opts.Samples, opts.Rand)
+	}
+
+	var idx treeIndex
+	got, err := digestFromDirectory(osPathname, &idx)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(got.Digest, want.Digest) {
+		return false, nil
+	}
+	_ = idx.write(idxPath)
+	return true, nil
+}
+
+// treeIndex lists the regular files of a directory, as hashed for its digest.
+type treeIndex []indexedFile
+
+type indexedFile struct {
+	path string // slash-separated, relative to the directory
+	size int64  // size on disk
+	sum  []byte // SHA-256 of the contents, with line endings normalized
+}
+
+// readTreeIndex reads an index written by write, each line of which is the
+// hex-encoded hash, size and path of a file, separated by spaces.
+func readTreeIndex(path string) (treeIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var idx treeIndex
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.SplitN(sc.Text(), " ", 3)
+		if len(fields) != 3 {
+			return nil, errors.Errorf("malformed line in %s: %q", path, sc.Text())
+		}
+		sum, err := hex.DecodeString(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "malformed hash in %s", path)
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "malformed size in %s", path)
+		}
+		idx = append(idx, indexedFile{path: fields[2], size: size, sum: sum})
+	}
+	return idx, sc.Err()
+}
+
+// write writes idx to path atomically, creating its directory if needed.
+func (idx treeIndex) write(path string) error {
+	var buf bytes.Buffer
+	for _, f := range idx {
+		fmt.Fprintf(&buf, "%x %d %s\n", f.sum, f.size, f.path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// sampleMatches reports whether the directory at osDirname holds exactly the
+// files of idx, with the same sizes, and whether n of them, picked by rnd,
+// hash as recorded.
+func (idx treeIndex) sampleMatches(osDirname string, n int, rnd *rand.Rand) (bool, error) {
+	sizes, err := regularFileSizes(osDirname)
+	if err != nil {
+		return false, err
+	}
+	if len(sizes) != len(idx) {
+		return false, nil
+	}
+	for _, f := range idx {
+		if size, has := sizes[f.path]; !has || size != f.size {
+			return false, nil
+		}
+	}
+
+	if n > len(idx) {
+		n = len(idx)
+	} else if n < 0 {
+		n = 0
+	}
+	for _, i := range rnd.Perm(len(idx))[:n] {
+		f := idx[i]
+		sum, err := hashFile(filepath.Join(osDirname, filepath.FromSlash(f.path)))
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(sum, f.sum) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// regularFileSizes returns the size of each regular file under osDirname, by
+// slash-separated relative path, skipping what DigestFromDirectory skips.
+func regularFileSizes(osDirname string) (map[string]int64, error) {
+	osDirname = filepath.Clean(osDirname)
+	sizes := make(map[string]int64)
+	err := filepath.Walk(osDirname, func(osPathname string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		if osPathname != osDirname {
+			switch info.Name() {
+			case "vendor", ".bzr", ".git", ".hg", ".svn":
+				return filepath.SkipDir
+			}
+		}
+		if info.Mode()&os.ModeType != 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(osDirname, osPathname)
+		if err != nil {
+			return err
+		}
+		sizes[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	return sizes, err
+}
+
+// hashFile returns the SHA-256 of the contents of the file at osPathname, with
+// line endings normalized as by DigestFromDirectory.
+func hashFile(osPathname string) ([]byte, error) {
+	fh, err := os.Open(osPathname)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot Open")
+	}
+	defer fh.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, newLineEndingReader(fh)); err != nil {
+		return nil, errors.Wrap(err, "cannot Copy")
+	}
+	return h.Sum(nil), nil
+}
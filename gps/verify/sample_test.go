@@ -0,0 +1,87 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package verify
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckDepTreeSampled(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "dep-sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	vendorRoot := filepath.Join(tmp, "vendor")
+	project := "github.com/alice/alice1"
+	write := func(name, contents string) {
+		path := filepath.Join(vendorRoot, filepath.FromSlash(project), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.go", "package alice1\n")
+	write("sub/b.go", "package sub\n")
+
+	digest, err := DigestFromDirectory(filepath.Join(vendorRoot, filepath.FromSlash(project)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]VersionedDigest{project: digest}
+	opts := SampleOptions{
+		IndexDir: filepath.Join(tmp, "index"),
+		Rand:     rand.New(rand.NewSource(1)),
+	}
+
+	check := func(name string, wantStatus VendorStatus) {
+		t.Helper()
+		status, err := CheckDepTreeSampled(vendorRoot, want, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status[project] != wantStatus {
+			t.Errorf("%s: expected %s, got %s", name, wantStatus, status[project])
+		}
+	}
+
+	// The first check hashes in full, and writes the index.
+	check("full", NoMismatch)
+	entries, err := ioutil.ReadDir(opts.IndexDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected an index to be written, got %v (%v)", entries, err)
+	}
+
+	// An edit that keeps the size of a file is only caught by hashing it, and
+	// every file is sampled here.
+	write("a.go", "package alice2\n")
+	check("edited", DigestMismatchInLock)
+	write("a.go", "package alice1\n")
+	check("restored", NoMismatch)
+
+	// Added files and changed sizes are caught without hashing.
+	opts.Samples = -1
+	write("c.go", "package alice1\n")
+	check("added", DigestMismatchInLock)
+	if err := os.Remove(filepath.Join(vendorRoot, filepath.FromSlash(project), "c.go")); err != nil {
+		t.Fatal(err)
+	}
+	write("sub/b.go", "package sub // longer\n")
+	check("resized", DigestMismatchInLock)
+
+	// Without an index, a mismatch is found in full, and no index written.
+	opts.IndexDir = filepath.Join(tmp, "other")
+	check("full mismatch", DigestMismatchInLock)
+	if _, err := os.Stat(opts.IndexDir); !os.IsNotExist(err) {
+		t.Errorf("expected no index to be written for a mismatch, got %v", err)
+	}
+}
@@ -812,10 +812,16 @@ func vLess(l, r Version, down bool) bool {
 		return lpre
 	}
 
-	if down {
-		return lsv.LessThan(rsv)
+	if !lsv.Equal(rsv) {
+		if down {
+			return lsv.LessThan(rsv)
+		}
+		return lsv.GreaterThan(rsv)
 	}
-	return lsv.GreaterThan(rsv)
+
+	// Semantically equal versions, like "v1.0.0" and "1.0.0", fall back to an
+	// alpha sort so that the result never depends on the input order.
+	return l.String() < r.String()
 }
 
 func hidePair(pvl []PairedVersion) []Version {
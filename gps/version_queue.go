@@ -7,8 +7,16 @@ package gps
 import (
 	"fmt"
 	"strings"
+	"sync"
 )
 
+// versionSlices pools the slices that versionQueues copy version lists into,
+// so that the queues repeatedly created and discarded while backtracking
+// through large graphs reuse them.
+var versionSlices = sync.Pool{
+	New: func() interface{} { return new([]Version) },
+}
+
 type failedVersion struct {
 	v Version
 	f error
@@ -23,6 +31,8 @@ type versionQueue struct {
 	failed       bool
 	allLoaded    bool
 	adverr       error
+	// buf is the pooled slice that backs pi, if any.
+	buf *[]Version
 }
 
 func newVersionQueue(id ProjectIdentifier, lockv, prefv Version, b sourceBridge) (*versionQueue, error) {
@@ -58,6 +68,21 @@ func newVersionQueue(id ProjectIdentifier, lockv, prefv Version, b sourceBridge)
 	return vq, nil
 }
 
+// release returns the queue's pooled slice, if it has one, to the pool. The
+// queue must not be used afterwards.
+func (vq *versionQueue) release() {
+	if vq.buf == nil {
+		return
+	}
+	all := (*vq.buf)[:cap(*vq.buf)]
+	for k := range all {
+		all[k] = nil
+	}
+	*vq.buf = all[:0]
+	versionSlices.Put(vq.buf)
+	vq.buf, vq.pi = nil, nil
+}
+
 func (vq *versionQueue) current() Version {
 	if len(vq.pi) > 0 {
 		return vq.pi[0]
@@ -97,8 +122,9 @@ func (vq *versionQueue) advance(fail error) error {
 		}
 		// defensive copy - calling listVersions here means slice contents may
 		// be modified when removing prefv/lockv.
-		vq.pi = make([]Version, len(vltmp))
-		copy(vq.pi, vltmp)
+		vq.buf = versionSlices.Get().(*[]Version)
+		vq.pi = append((*vq.buf)[:0], vltmp...)
+		*vq.buf = vq.pi
 
 		// search for and remove lockv and prefv, in a pointer GC-safe manner
 		//
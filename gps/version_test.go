@@ -187,3 +187,17 @@ func TestVersionSorts(t *testing.T) {
 		t.Errorf("Up-then-downgrade sort positions with wrong versions: %v", wrong)
 	}
 }
+
+func TestVersionSortsEquivalentSemver(t *testing.T) {
+	a := NewVersion("v1.0.0").Pair(Revision("abc"))
+	b := NewVersion("1.0.0").Pair(Revision("def"))
+
+	for _, sortFn := range []func([]Version){SortForUpgrade, SortForDowngrade} {
+		l1, l2 := []Version{a, b}, []Version{b, a}
+		sortFn(l1)
+		sortFn(l2)
+		if l1[0] != l2[0] || l1[1] != l2[1] {
+			t.Errorf("sort of equivalent semver versions depended on input order: %s vs. %s", l1, l2)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import "fmt"
+
+// YankedVersions lists, by project root, versions that must not be selected by
+// the solver, such as releases that are known to be broken.
+type YankedVersions map[ProjectRoot][]YankedVersion
+
+// YankedVersion is a single version that has been yanked, along with the
+// reason given for yanking it.
+type YankedVersion struct {
+	// Version is a version, branch or revision. A version of the project is
+	// yanked if either its unpaired form or its underlying revision is equal
+	// to it.
+	Version string
+	Reason  string
+}
+
+// Yanked reports whether v has been yanked for the project identified by id,
+// returning the matching entry if so.
+func (y YankedVersions) Yanked(id ProjectIdentifier, v Version) (YankedVersion, bool) {
+	entries := y[id.ProjectRoot]
+	if len(entries) == 0 || v == nil {
+		return YankedVersion{}, false
+	}
+
+	var rev Revision
+	switch tv := v.(type) {
+	case PairedVersion:
+		rev = tv.Revision()
+	case Revision:
+		rev = tv
+	}
+
+	for _, yv := range entries {
+		if yv.Version == v.String() || (rev != "" && yv.Version == string(rev)) {
+			return yv, true
+		}
+	}
+	return YankedVersion{}, false
+}
+
+// yankedVersionFailure indicates that an atom could not be selected because
+// its version has been yanked.
+type yankedVersionFailure struct {
+	goal atom
+	yv   YankedVersion
+}
+
+func (e *yankedVersionFailure) Error() string {
+	if e.yv.Reason == "" {
+		return fmt.Sprintf("Could not introduce %s, as that version has been yanked", a2vs(e.goal))
+	}
+	return fmt.Sprintf("Could not introduce %s, as that version has been yanked: %s", a2vs(e.goal), e.yv.Reason)
+}
+
+func (e *yankedVersionFailure) traceString() string {
+	return fmt.Sprintf("%s is yanked", a2vs(e.goal))
+}
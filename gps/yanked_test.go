@@ -0,0 +1,93 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gps
+
+import "testing"
+
+func TestYankedVersions(t *testing.T) {
+	y := YankedVersions{
+		"github.com/foo/bar": {
+			{Version: "v1.0.1", Reason: "panics on start"},
+			{Version: "abc123"},
+		},
+	}
+	id := mkPI("github.com/foo/bar")
+
+	cases := []struct {
+		v      Version
+		yanked bool
+	}{
+		{NewVersion("v1.0.1"), true},
+		{NewVersion("v1.0.1").Pair("def456"), true},
+		{NewVersion("v1.0.0").Pair("abc123"), true},
+		{Revision("abc123"), true},
+		{NewBranch("master").Pair("def456"), false},
+		{NewVersion("v1.0.0"), false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if _, yanked := y.Yanked(id, c.v); yanked != c.yanked {
+			t.Errorf("expected Yanked(%v) to be %v", c.v, c.yanked)
+		}
+	}
+
+	if _, yanked := y.Yanked(mkPI("github.com/other"), NewVersion("v1.0.1")); yanked {
+		t.Error("expected versions of other projects not to be yanked")
+	}
+}
+
+func TestSolveSkipsYankedVersions(t *testing.T) {
+	fix := basicFixture{
+		ds: []depspec{
+			mkDepspec("root 0.0.0", "foo *"),
+			mkDepspec("foo 1.0.0"),
+			mkDepspec("foo 1.0.1"),
+		},
+		r: mksolution(
+			"foo 1.0.0",
+		),
+		// The lock prefers the yanked version, which must not be kept.
+		l: mklock(
+			"foo 1.0.1",
+		),
+	}
+
+	params := SolveParameters{
+		RootDir:         string(fix.ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		Lock:            fix.l,
+		ProjectAnalyzer: naiveAnalyzer{},
+		Yanked: YankedVersions{
+			"foo": {{Version: "1.0.1"}},
+		},
+	}
+
+	res, err := fixSolve(params, newdepspecSM(fix.ds, nil), t)
+	fixtureSolveSimpleChecks(fix, res, err, t)
+}
+
+func TestSolveFailsWithOnlyYankedVersions(t *testing.T) {
+	ds := []depspec{
+		mkDepspec("root 0.0.0", "foo 1.0.1"),
+		mkDepspec("foo 1.0.0"),
+		mkDepspec("foo 1.0.1"),
+	}
+	fix := basicFixture{ds: ds}
+
+	params := SolveParameters{
+		RootDir:         string(ds[0].n),
+		RootPackageTree: fix.rootTree(),
+		Manifest:        fix.rootmanifest(),
+		ProjectAnalyzer: naiveAnalyzer{},
+		Yanked: YankedVersions{
+			"foo": {{Version: "1.0.1", Reason: "broken"}},
+		},
+	}
+
+	if _, err := fixSolve(params, newdepspecSM(ds, nil), t); err == nil {
+		t.Fatal("expected solving to fail when the only allowed version is yanked")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+type rawSolveHints struct {
+	Analyzer rawAnalyzerInfo      `json:"analyzer"`
+	Rejected []rawHintedRejection `json:"rejected"`
+}
+
+type rawAnalyzerInfo struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+type rawHintedRejection struct {
+	ProjectRoot string `json:"projectRoot"`
+	Source      string `json:"source,omitempty"`
+	Version     string `json:"version"`
+	Revision    string `json:"revision"`
+	Reason      string `json:"reason"`
+}
+
+// ReadSolveHints reads solver hints written by WriteSolveHints from r.
+func ReadSolveHints(r io.Reader) (*gps.SolveHints, error) {
+	var raw rawSolveHints
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, errors.Wrap(err, "unable to parse solver hints")
+	}
+
+	h := &gps.SolveHints{
+		AnalyzerName:    raw.Analyzer.Name,
+		AnalyzerVersion: raw.Analyzer.Version,
+	}
+	for _, rr := range raw.Rejected {
+		if rr.ProjectRoot == "" || rr.Revision == "" {
+			return nil, errors.Errorf("solver hint for %q must name a project root and a revision", rr.ProjectRoot)
+		}
+		h.Rejected = append(h.Rejected, gps.HintedRejection{
+			ProjectRoot: gps.ProjectRoot(rr.ProjectRoot),
+			Source:      rr.Source,
+			Version:     rr.Version,
+			Revision:    gps.Revision(rr.Revision),
+			Reason:      rr.Reason,
+		})
+	}
+	return h, nil
+}
+
+// WriteSolveHints writes h to w as JSON.
+func WriteSolveHints(w io.Writer, h *gps.SolveHints) error {
+	raw := rawSolveHints{
+		Analyzer: rawAnalyzerInfo{Name: h.AnalyzerName, Version: h.AnalyzerVersion},
+		Rejected: make([]rawHintedRejection, 0, len(h.Rejected)),
+	}
+	for _, r := range h.Rejected {
+		raw.Rejected = append(raw.Rejected, rawHintedRejection{
+			ProjectRoot: string(r.ProjectRoot),
+			Source:      r.Source,
+			Version:     r.Version,
+			Revision:    string(r.Revision),
+			Reason:      r.Reason,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(raw), "failed to encode solver hints")
+}
+
+// LoadSolveHints reads the solver hints file named by c.HintsFile, for use
+// with the analyzer described by an. Empty hints are returned if the file
+// does not exist yet, or was written with another analyzer. It returns nil if
+// no hints file is configured.
+func (c *Ctx) LoadSolveHints(an gps.ProjectAnalyzerInfo) (*gps.SolveHints, error) {
+	if c.HintsFile == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(c.HintsFile)
+	if os.IsNotExist(err) {
+		return gps.NewSolveHints(an), nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to open solver hints")
+	}
+	defer f.Close()
+
+	h, err := ReadSolveHints(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read solver hints %s", c.HintsFile)
+	}
+	if !h.Matches(an) {
+		return gps.NewSolveHints(an), nil
+	}
+	return h, nil
+}
+
+// SaveSolveHints writes h to the solver hints file named by c.HintsFile.
+func (c *Ctx) SaveSolveHints(h *gps.SolveHints) error {
+	if c.HintsFile == "" || h == nil {
+		return nil
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(c.HintsFile), ".dephints")
+	if err != nil {
+		return errors.Wrap(err, "failed to create solver hints file")
+	}
+	defer os.Remove(f.Name())
+
+	if err := WriteSolveHints(f, h); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed to write solver hints")
+	}
+	return errors.Wrapf(os.Rename(f.Name(), c.HintsFile), "failed to write solver hints %s", c.HintsFile)
+}
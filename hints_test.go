@@ -0,0 +1,98 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestSolveHintsRoundTrip(t *testing.T) {
+	want := &gps.SolveHints{
+		AnalyzerName:    "dep",
+		AnalyzerVersion: 1,
+		Rejected: []gps.HintedRejection{
+			{
+				ProjectRoot: "github.com/sdboyer/deptest",
+				Version:     "v1.0.0",
+				Revision:    "ff2948a2ac8f538c4ecd55962e919d1e13e74baf",
+				Reason:      "unable to parse Gopkg.toml",
+			},
+			{
+				ProjectRoot: "github.com/sdboyer/deptestdos",
+				Source:      "https://example.com/deptestdos.git",
+				Version:     "a0196baa11ea047dd65037287451d36b861b00ea",
+				Revision:    "a0196baa11ea047dd65037287451d36b861b00ea",
+				Reason:      "no Go files",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSolveHints(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSolveHints(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected solver hints:\n\t(GOT): %#v\n\t(WNT): %#v", got, want)
+	}
+}
+
+func TestReadSolveHintsInvalid(t *testing.T) {
+	_, err := ReadSolveHints(strings.NewReader(`{"rejected": [{"projectRoot": "github.com/sdboyer/deptest"}]}`))
+	if err == nil {
+		t.Fatal("expected an error for a hint without a revision")
+	}
+}
+
+func TestLoadSaveSolveHints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dephints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	an := gps.ProjectAnalyzerInfo{Name: "dep", Version: 1}
+	ctx := &Ctx{HintsFile: filepath.Join(dir, "hints.json")}
+
+	h, err := ctx.LoadSolveHints(an)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h == nil || len(h.Rejected) != 0 || !h.Matches(an) {
+		t.Fatalf("expected empty hints before the file exists, got %#v", h)
+	}
+
+	h.Rejected = append(h.Rejected, gps.HintedRejection{ProjectRoot: "github.com/sdboyer/deptest", Version: "v1.0.0", Revision: "ff2948a2ac8f538c4ecd55962e919d1e13e74baf"})
+	if err := ctx.SaveSolveHints(h); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ctx.LoadSolveHints(an); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, h) {
+		t.Errorf("unexpected solver hints:\n\t(GOT): %#v\n\t(WNT): %#v", got, h)
+	}
+
+	// Hints recorded with another version of the analyzer do not apply.
+	if got, err := ctx.LoadSolveHints(gps.ProjectAnalyzerInfo{Name: "dep", Version: 2}); err != nil {
+		t.Fatal(err)
+	} else if len(got.Rejected) != 0 {
+		t.Errorf("expected hints from another analyzer version to be discarded, got %#v", got)
+	}
+
+	if h, err := (&Ctx{}).LoadSolveHints(an); h != nil || err != nil {
+		t.Errorf("expected no hints when no file is configured, got %#v, %v", h, err)
+	}
+}
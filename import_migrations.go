@@ -0,0 +1,229 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ImportMigration is a move of the packages beneath an import path to another,
+// as when a project changes hosts or its owner is renamed, so that the old
+// path can no longer be fetched, or names a copy that is no longer
+// maintained.
+type ImportMigration struct {
+	// From is the import path that the packages moved from, and To the one
+	// they moved to.
+	From string
+	To   string
+	// Reason explains the move.
+	Reason string
+}
+
+// Migrate returns path with its prefix From replaced by To, and whether path
+// is beneath From.
+func (m ImportMigration) Migrate(path string) (string, bool) {
+	if path == m.From {
+		return m.To, true
+	}
+	if strings.HasPrefix(path, m.From+"/") {
+		return m.To + path[len(m.From):], true
+	}
+	return "", false
+}
+
+// KnownImportMigrations are the well-known moves of import paths that dep
+// offers to rewrite the imports of. A manifest adds to them with [[migration]].
+var KnownImportMigrations = []ImportMigration{
+	{"code.google.com/p/go.crypto", "golang.org/x/crypto", "Google Code was shut down"},
+	{"code.google.com/p/go.exp", "golang.org/x/exp", "Google Code was shut down"},
+	{"code.google.com/p/go.image", "golang.org/x/image", "Google Code was shut down"},
+	{"code.google.com/p/go.net", "golang.org/x/net", "Google Code was shut down"},
+	{"code.google.com/p/go.text", "golang.org/x/text", "Google Code was shut down"},
+	{"code.google.com/p/go.tools", "golang.org/x/tools", "Google Code was shut down"},
+	{"code.google.com/p/goprotobuf", "github.com/golang/protobuf", "Google Code was shut down"},
+	{"code.google.com/p/gogoprotobuf", "github.com/gogo/protobuf", "Google Code was shut down"},
+	{"code.google.com/p/go-uuid/uuid", "github.com/pborman/uuid", "Google Code was shut down"},
+	{"code.google.com/p/snappy-go/snappy", "github.com/golang/snappy", "Google Code was shut down"},
+	{"code.google.com/p/google-api-go-client", "google.golang.org/api", "Google Code was shut down"},
+	{"code.google.com/p/log4go", "github.com/alecthomas/log4go", "Google Code was shut down"},
+	{"code.google.com/p/gcfg", "gopkg.in/gcfg.v1", "Google Code was shut down"},
+	{"github.com/Sirupsen/logrus", "github.com/sirupsen/logrus", "the owner was renamed in lower case"},
+	{"github.com/codegangsta/cli", "github.com/urfave/cli", "the project moved to a new owner"},
+	{"github.com/go-fsnotify/fsnotify", "github.com/fsnotify/fsnotify", "the project moved to a new owner"},
+	{"github.com/golang/lint", "golang.org/x/lint", "the project moved to golang.org/x"},
+	{"camlistore.org", "perkeep.org", "Camlistore was renamed Perkeep"},
+}
+
+// ImportMigrations returns the migrations set with [[migration]] in m, if m is
+// not nil, followed by KnownImportMigrations.
+func (m *Manifest) ImportMigrations() []ImportMigration {
+	var migrations []ImportMigration
+	if m != nil {
+		migrations = append(migrations, m.Migrations...)
+	}
+	return append(migrations, KnownImportMigrations...)
+}
+
+// FindImportMigration returns the migration among migrations with the longest
+// From beneath which path is, preferring the first of those that are equally
+// long, and whether there is one.
+func FindImportMigration(migrations []ImportMigration, path string) (ImportMigration, bool) {
+	var found ImportMigration
+	var ok bool
+	for _, m := range migrations {
+		if _, has := m.Migrate(path); has && (!ok || len(m.From) > len(found.From)) {
+			found, ok = m, true
+		}
+	}
+	return found, ok
+}
+
+// MovedImport is an import, in the packages of a project, of a path that has
+// moved.
+type MovedImport struct {
+	Path      string
+	NewPath   string
+	Migration ImportMigration
+	// Files are the files that import Path, relative to the project root, in
+	// order.
+	Files []string
+}
+
+// FindMovedImports returns the imports, in the Go files beneath root, of paths
+// that have moved according to migrations, sorted by path. The vendor and
+// testdata directories, and those whose names start with "." or "_", are
+// skipped, as the go tool skips them; so are files that cannot be parsed.
+func FindMovedImports(root string, migrations []ImportMigration) ([]MovedImport, error) {
+	moved := make(map[string]*MovedImport)
+	err := walkGoFiles(root, func(path, rel string) error {
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		seen := make(map[string]bool)
+		for _, spec := range f.Imports {
+			ip, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || seen[ip] {
+				continue
+			}
+			seen[ip] = true
+			m, ok := FindImportMigration(migrations, ip)
+			if !ok {
+				continue
+			}
+			if moved[ip] == nil {
+				np, _ := m.Migrate(ip)
+				moved[ip] = &MovedImport{Path: ip, NewPath: np, Migration: m}
+			}
+			moved[ip].Files = append(moved[ip].Files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var mis []MovedImport
+	for _, mi := range moved {
+		sort.Strings(mi.Files)
+		mis = append(mis, *mi)
+	}
+	sort.Slice(mis, func(i, j int) bool { return mis[i].Path < mis[j].Path })
+	return mis, nil
+}
+
+// RewriteMovedImports rewrites the imports in moved, as returned by
+// FindMovedImports for root, to their new paths. Only the paths of the
+// imports are changed; the rest of each file is left as it was.
+func RewriteMovedImports(root string, moved []MovedImport) error {
+	byFile := make(map[string]map[string]string)
+	for _, mi := range moved {
+		for _, rel := range mi.Files {
+			if byFile[rel] == nil {
+				byFile[rel] = make(map[string]string)
+			}
+			byFile[rel][mi.Path] = mi.NewPath
+		}
+	}
+
+	for rel, paths := range byFile {
+		path := filepath.Join(root, rel)
+		if err := rewriteImports(path, paths); err != nil {
+			return errors.Wrapf(err, "failed to rewrite the imports of %s", rel)
+		}
+	}
+	return nil
+}
+
+// rewriteImports rewrites the imports in the Go file at path of the keys of
+// paths to their values.
+func rewriteImports(path string, paths map[string]string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ImportsOnly)
+	if err != nil {
+		return err
+	}
+
+	// Replace the literals from the end, so that the offsets of those before
+	// them still hold.
+	out := src
+	for i := len(f.Imports) - 1; i >= 0; i-- {
+		lit := f.Imports[i].Path
+		ip, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			continue
+		}
+		np, has := paths[ip]
+		if !has {
+			continue
+		}
+		start := fset.Position(lit.Pos()).Offset
+		end := start + len(lit.Value)
+		out = append(append(append([]byte(nil), out[:start]...), strconv.Quote(np)...), out[end:]...)
+	}
+	return ioutil.WriteFile(path, out, fi.Mode())
+}
+
+// walkGoFiles calls f with the path of each Go file beneath root, and that
+// path relative to root, skipping the directories the go tool skips.
+func walkGoFiles(root string, f func(path, rel string) error) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return f(path, filepath.ToSlash(rel))
+	})
+}
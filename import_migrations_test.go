@@ -0,0 +1,116 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestFindImportMigration(t *testing.T) {
+	m := &Manifest{Migrations: []ImportMigration{
+		{From: "github.com/Sirupsen/logrus", To: "git.example.com/mirror/logrus"},
+		{From: "code.google.com/p/go.net/websocket", To: "github.com/gorilla/websocket"},
+	}}
+	migrations := m.ImportMigrations()
+
+	cases := []struct {
+		path, want string
+	}{
+		{"github.com/Sirupsen/logrus", "git.example.com/mirror/logrus"},
+		{"github.com/Sirupsen/logrus/hooks/syslog", "git.example.com/mirror/logrus/hooks/syslog"},
+		{"code.google.com/p/go.net/context", "golang.org/x/net/context"},
+		{"code.google.com/p/go.net/websocket", "github.com/gorilla/websocket"},
+		{"code.google.com/p/go.netx", ""},
+		{"github.com/sirupsen/logrus", ""},
+	}
+	for _, c := range cases {
+		mg, ok := FindImportMigration(migrations, c.path)
+		var got string
+		if ok {
+			got, _ = mg.Migrate(c.path)
+		}
+		if got != c.want {
+			t.Errorf("migration of %s: got %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestRewriteMovedImports(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("proj/main.go", `package main
+
+import (
+	"fmt"
+
+	"code.google.com/p/go.net/context"
+	log "github.com/Sirupsen/logrus" // logging
+)
+
+func main() { fmt.Println(log.New(), context.Background()) }
+`)
+	h.TempFile("proj/sub/sub.go", `package sub
+
+import _ "github.com/Sirupsen/logrus/hooks/syslog"
+`)
+	h.TempFile("proj/vendor/github.com/Sirupsen/logrus/logrus.go", `package logrus
+
+import _ "code.google.com/p/go.net/context"
+`)
+	root := h.Path("proj")
+
+	moved, err := FindMovedImports(root, KnownImportMigrations)
+	h.Must(err)
+	var got []string
+	for _, mi := range moved {
+		got = append(got, mi.Path+" -> "+mi.NewPath)
+	}
+	want := []string{
+		"code.google.com/p/go.net/context -> golang.org/x/net/context",
+		"github.com/Sirupsen/logrus -> github.com/sirupsen/logrus",
+		"github.com/Sirupsen/logrus/hooks/syslog -> github.com/sirupsen/logrus/hooks/syslog",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected moved imports:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+	if !reflect.DeepEqual(moved[2].Files, []string{"sub/sub.go"}) {
+		t.Errorf("unexpected files importing %s: %v", moved[2].Path, moved[2].Files)
+	}
+
+	h.Must(RewriteMovedImports(root, moved))
+	mustHaveContents(t, h, "proj/main.go", `package main
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	log "github.com/sirupsen/logrus" // logging
+)
+
+func main() { fmt.Println(log.New(), context.Background()) }
+`)
+	mustHaveContents(t, h, "proj/sub/sub.go", `package sub
+
+import _ "github.com/sirupsen/logrus/hooks/syslog"
+`)
+	mustHaveContents(t, h, "proj/vendor/github.com/Sirupsen/logrus/logrus.go", `package logrus
+
+import _ "code.google.com/p/go.net/context"
+`)
+}
+
+func mustHaveContents(t *testing.T, h *test.Helper, path, want string) {
+	t.Helper()
+	got, err := ioutil.ReadFile(h.Path(path))
+	h.Must(err)
+	if string(got) != want {
+		t.Errorf("unexpected contents of %s:\n%s", path, got)
+	}
+}
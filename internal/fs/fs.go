@@ -509,6 +509,21 @@ func IsDir(name string) (bool, error) {
 	return true, nil
 }
 
+// DirSize returns the total size of the regular files under dir.
+func DirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += uint64(fi.Size())
+		}
+		return nil
+	})
+	return size, err
+}
+
 // IsNonEmptyDir determines if the path given is a non-empty directory or not.
 func IsNonEmptyDir(name string) (bool, error) {
 	isDir, err := IsDir(name)
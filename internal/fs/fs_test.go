@@ -5,6 +5,7 @@
 package fs
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -1054,6 +1055,97 @@ func TestIsNonEmptyDir(t *testing.T) {
 	}
 }
 
+func TestDirSize(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir("dir")
+	h.TempFile("dir/a", "hello")
+	h.TempFile("dir/sub/b", "world!")
+	if runtime.GOOS != "windows" {
+		h.Must(os.Symlink("a", filepath.Join(h.Path("dir"), "link")))
+	}
+
+	size, err := DirSize(h.Path("dir"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 11 {
+		t.Fatalf("expected the size of the regular files to be 11, got %d", size)
+	}
+
+	if _, err := DirSize(filepath.Join(h.Path("dir"), "nonexistent")); err == nil {
+		t.Fatal("expected an error for a directory that does not exist")
+	}
+}
+
+func TestLinkFile(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("src/file", "hello")
+	src := filepath.Join(h.Path("src"), "file")
+	h.TempDir("dst")
+
+	for _, hard := range []bool{false, true} {
+		dst := filepath.Join(h.Path("dst"), fmt.Sprintf("file-%v", hard))
+		if err := LinkFile(src, dst, hard); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "hello" {
+			t.Fatalf("expected %s to hold %q, got %q", dst, "hello", b)
+		}
+
+		sfi, err := os.Stat(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dfi, err := os.Stat(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sfi.Mode() != dfi.Mode() {
+			t.Errorf("expected the mode of %s to be %v, got %v", dst, sfi.Mode(), dfi.Mode())
+		}
+		if !hard && os.SameFile(sfi, dfi) {
+			t.Errorf("did not expect %s to be hard linked", dst)
+		}
+	}
+
+	// A change in place to a copy that is not hard linked leaves the original
+	// alone.
+	if err := ioutil.WriteFile(filepath.Join(h.Path("dst"), "file-false"), []byte("patched"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(src); err != nil || string(b) != "hello" {
+		t.Errorf("expected %s to be unchanged, got %q, %v", src, b, err)
+	}
+}
+
+func TestLinkDir(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("src/a", "a")
+	h.TempFile("src/sub/b", "b")
+	h.TempFile("src/sub/c", "c")
+	dst := filepath.Join(h.Path("."), "dst")
+
+	if err := LinkDir(h.Path("src"), dst, []string{"a", "sub/b"}, true); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"a": true, "sub/b": true, "sub/c": false} {
+		_, err := os.Stat(filepath.Join(dst, filepath.FromSlash(name)))
+		if got := err == nil; got != want {
+			t.Errorf("expected %s to exist in the linked directory to be %v", name, want)
+		}
+	}
+}
+
 func TestIsSymlink(t *testing.T) {
 	dir, err := ioutil.TempDir("", "dep")
 	if err != nil {
@@ -0,0 +1,58 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// LinkFile makes dst, which must not exist, a copy of the file named src, by
+// the cheapest means available. A regular file is cloned copy-on-write, where
+// the filesystem supports it; failing that, if hard is set, it is hard linked;
+// failing that, as across devices, it is copied. Symlinks are cloned.
+//
+// A hard link shares its contents with src, so that a change to either in
+// place changes both; hard should only be set if neither is changed in place.
+func LinkFile(src, dst string, hard bool) error {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return copyFile(src, dst)
+	}
+
+	if err := reflink(src, dst, fi.Mode()); err == nil {
+		return nil
+	}
+	if hard {
+		if err := os.Link(src, dst); err == nil {
+			return nil
+		}
+	}
+	return copyFile(src, dst)
+}
+
+// LinkDir populates dst with the files named by paths, slash-separated and
+// relative to src, linking each with LinkFile. dst is created if need be, but
+// none of the files may exist in it.
+func LinkDir(src, dst string, paths []string, hard bool) error {
+	if err := os.MkdirAll(dst, 0777); err != nil {
+		return errors.Wrapf(err, "cannot mkdir %s", dst)
+	}
+	for _, p := range paths {
+		to := filepath.Join(dst, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+			return errors.Wrapf(err, "cannot mkdir %s", filepath.Dir(to))
+		}
+		if err := LinkFile(filepath.Join(src, filepath.FromSlash(p)), to, hard); err != nil {
+			return errors.Wrapf(err, "linking %s failed", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package fs
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// reflink is only supported on Linux.
+func reflink(src, dst string, mode os.FileMode) error {
+	return errors.New("copy-on-write clones are not supported on this platform")
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// SyncFile commits the contents of the file at path to stable storage.
+func SyncFile(path string) error {
+	// Windows can only flush files opened for writing.
+	flag := os.O_RDONLY
+	if runtime.GOOS == "windows" {
+		flag = os.O_RDWR
+	}
+	f, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open %s to sync it", path)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "cannot sync %s", path)
+	}
+	return f.Close()
+}
+
+// SyncDir commits the entries of the directory at path to stable storage, so
+// that files created in, renamed into or removed from it stay so after a
+// crash. It does nothing on Windows, which cannot sync directories.
+func SyncDir(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open %s to sync it", path)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "cannot sync %s", path)
+	}
+	return f.Close()
+}
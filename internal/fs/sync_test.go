@@ -0,0 +1,35 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte("contents"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := SyncFile(file); err != nil {
+		t.Error(err)
+	}
+	if err := SyncDir(dir); err != nil {
+		t.Error(err)
+	}
+
+	if err := SyncFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error syncing a missing file")
+	}
+}
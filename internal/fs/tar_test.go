@@ -0,0 +1,95 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestTarGzRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "a", "empty"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "a", "file"), []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink(filepath.Join("a", "file"), filepath.Join(src, "link")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTarGz(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst")
+	if err := ExtractTarGz(&buf, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := ioutil.ReadFile(filepath.Join(dst, "a", "file")); err != nil || string(b) != "contents" {
+		t.Errorf("expected the file to be unpacked, got %q, %v", b, err)
+	}
+	if ok, err := IsDir(filepath.Join(dst, "a", "empty")); err != nil || !ok {
+		t.Errorf("expected the empty directory to be unpacked, got %v, %v", ok, err)
+	}
+	if runtime.GOOS != "windows" {
+		if l, err := os.Readlink(filepath.Join(dst, "link")); err != nil || l != filepath.Join("a", "file") {
+			t.Errorf("expected the symlink to be unpacked, got %q, %v", l, err)
+		}
+	}
+}
+
+func TestExtractTarGzRejectsEscapes(t *testing.T) {
+	cases := map[string][]*tar.Header{
+		"parent": {{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644}},
+		"symlink": {
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: os.TempDir()},
+			{Name: "link/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+	}
+	for name, hdrs := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			for _, hdr := range hdrs {
+				if err := tw.WriteHeader(hdr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tw.Close()
+			gz.Close()
+
+			root, err := ioutil.TempDir("", "dep")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(root)
+
+			if err := ExtractTarGz(&buf, filepath.Join(root, "dst")); err == nil {
+				t.Error("expected an error for an entry outside of the destination")
+			}
+			if _, err := os.Stat(filepath.Join(root, "evil")); !os.IsNotExist(err) {
+				t.Error("expected nothing to be written outside of the destination")
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gomod
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/importers/base"
+	"github.com/pkg/errors"
+)
+
+func modulesFile(dir string) string {
+	return filepath.Join(dir, "vendor", "modules.txt")
+}
+
+// pseudoVersionRevision matches the timestamp and abbreviated revision at the
+// end of a go modules pseudo-version, such as
+// v0.0.0-20180613153352-e1c0ee2d9a6c or v1.2.4-0.20180613153352-e1c0ee2d9a6c.
+var pseudoVersionRevision = regexp.MustCompile(`[-.][0-9]{14}-([0-9a-f]{12})$`)
+
+// Importer imports the go modules vendor/modules.txt file into the dep
+// configuration format.
+type Importer struct {
+	*base.Importer
+	modules []vendoredModule
+}
+
+// NewImporter for go modules vendor trees.
+func NewImporter(log *log.Logger, verbose bool, sm gps.SourceManager) *Importer {
+	return &Importer{Importer: base.NewImporter(log, verbose, sm)}
+}
+
+// Name of the importer.
+func (m *Importer) Name() string { return "go modules" }
+
+// HasDepMetadata checks if a directory contains config that the importer can handle.
+func (m *Importer) HasDepMetadata(dir string) bool {
+	_, err := os.Stat(modulesFile(dir))
+	return err == nil
+}
+
+// Import the config found in the directory.
+func (m *Importer) Import(dir string, pr gps.ProjectRoot) (*dep.Manifest, *dep.Lock, error) {
+	m.Logger.Println("Detected go modules vendor/modules.txt file...")
+
+	err := m.loadModulesFile(dir)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to load vendor/modules.txt")
+	}
+
+	man, l := m.convert(pr)
+	return man, l, nil
+}
+
+func (m *Importer) loadModulesFile(dir string) error {
+	m.Logger.Printf("Converting from vendor/modules.txt...")
+
+	path := modulesFile(dir)
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open %s", path)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		mod, err := parseModuleLine(scanner.Text())
+		if err != nil {
+			m.Logger.Printf("  Warning: Skipping line. Unable to parse: %s\n", err)
+			continue
+		}
+		if mod == nil {
+			// A package line, an annotation such as "## explicit", or an
+			// empty line.
+			continue
+		}
+		m.modules = append(m.modules, *mod)
+	}
+
+	if err := scanner.Err(); err != nil {
+		m.Logger.Printf("  Warning: Ignoring errors found while parsing %s: %s\n", path, err)
+	}
+
+	return nil
+}
+
+func (m *Importer) convert(pr gps.ProjectRoot) (*dep.Manifest, *dep.Lock) {
+	packages := make([]base.ImportedPackage, 0, len(m.modules))
+	for _, mod := range m.modules {
+		if mod.replacement != "" && isLocalPath(mod.replacement) {
+			m.Logger.Printf(
+				"  Warning: Skipping project. %s is replaced by the local directory %s, which dep cannot use as a source\n",
+				mod.path, mod.replacement,
+			)
+			continue
+		}
+
+		ip := base.ImportedPackage{
+			Name:   mod.path,
+			Source: mod.replacement,
+		}
+
+		version := mod.version
+		if mod.replacement != "" {
+			version = mod.replacementVersion
+		}
+		if version == "" {
+			m.Logger.Printf(
+				"  Warning: Invalid vendor/modules.txt, version not found for module %q\n",
+				mod.path,
+			)
+		} else {
+			ip.LockHint = m.lockHint(mod, version)
+		}
+
+		packages = append(packages, ip)
+	}
+	m.ImportPackages(packages, true)
+	return m.Manifest, m.Lock
+}
+
+// lockHint converts a module version into a tag or revision. Pseudo-versions
+// only record an abbreviated revision, which is expanded by looking for a
+// matching revision among the versions of the project; if none is found, the
+// project is not locked.
+func (m *Importer) lockHint(mod vendoredModule, version string) string {
+	version = strings.TrimSuffix(version, "+incompatible")
+
+	match := pseudoVersionRevision.FindStringSubmatch(version)
+	if match == nil {
+		return version
+	}
+	abbrev := match[1]
+
+	pr, err := m.SourceManager.DeduceProjectRoot(mod.path)
+	if err == nil {
+		pi := gps.ProjectIdentifier{ProjectRoot: pr, Source: mod.replacement}
+		var versions []gps.PairedVersion
+		if versions, err = m.SourceManager.ListVersions(pi); err == nil {
+			for _, v := range versions {
+				if strings.HasPrefix(string(v.Revision()), abbrev) {
+					return string(v.Revision())
+				}
+			}
+		}
+	}
+
+	m.Logger.Printf(
+		"  Warning: Unable to find the revision %s of pseudo-version %s for %s, it will not be locked\n",
+		abbrev, version, mod.path,
+	)
+	return ""
+}
+
+// vendoredModule is a module listed in vendor/modules.txt, with any
+// replacement that was applied to it.
+type vendoredModule struct {
+	path               string
+	version            string
+	replacement        string
+	replacementVersion string
+}
+
+// parseModuleLine parses a line of vendor/modules.txt, returning nil for any
+// line that does not introduce a module:
+//
+//	# github.com/foo/bar v1.0.0
+//	# github.com/foo/bar v1.0.0 => github.com/fork/bar v1.0.1
+//	# github.com/foo/bar => ../bar
+func parseModuleLine(line string) (*vendoredModule, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "# ") {
+		return nil, nil
+	}
+
+	fields := strings.Fields(line[2:])
+	var mod vendoredModule
+	switch {
+	case len(fields) == 2 && fields[1] != "=>":
+		mod.path, mod.version = fields[0], fields[1]
+	case len(fields) == 3 && fields[1] == "=>":
+		mod.path, mod.replacement = fields[0], fields[2]
+	case len(fields) == 4 && fields[1] == "=>":
+		mod.path, mod.replacement, mod.replacementVersion = fields[0], fields[2], fields[3]
+	case len(fields) == 4 && fields[2] == "=>":
+		mod.path, mod.version, mod.replacement = fields[0], fields[1], fields[3]
+	case len(fields) == 5 && fields[2] == "=>":
+		mod.path, mod.version, mod.replacement, mod.replacementVersion = fields[0], fields[1], fields[3], fields[4]
+	default:
+		return nil, errors.Errorf("invalid module line: %q", line)
+	}
+
+	return &mod, nil
+}
+
+// isLocalPath reports whether a module replacement is a directory on disk,
+// rather than another module.
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") || filepath.IsAbs(p) || p == "." || p == ".."
+}
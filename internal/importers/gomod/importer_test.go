@@ -0,0 +1,245 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gomod
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/importers/importertest"
+	"github.com/golang/dep/internal/test"
+	"github.com/pkg/errors"
+)
+
+func TestGoModules_Convert(t *testing.T) {
+	testCases := map[string]struct {
+		modules []vendoredModule
+		importertest.TestCase
+	}{
+		"tagged module": {
+			[]vendoredModule{{
+				path:    importertest.Project,
+				version: importertest.V1Tag,
+			}},
+			importertest.TestCase{
+				WantConstraint: importertest.V1Constraint,
+				WantRevision:   importertest.V1Rev,
+				WantVersion:    importertest.V1Tag,
+			},
+		},
+		"replaced module": {
+			[]vendoredModule{{
+				path:               importertest.Project,
+				version:            "v0.1.0",
+				replacement:        importertest.ProjectSrc,
+				replacementVersion: importertest.V1Tag,
+			}},
+			importertest.TestCase{
+				WantSourceRepo: importertest.ProjectSrc,
+				WantConstraint: importertest.V1Constraint,
+				WantRevision:   importertest.V1Rev,
+				WantVersion:    importertest.V1Tag,
+			},
+		},
+		"local replacement": {
+			[]vendoredModule{{
+				path:        importertest.Project,
+				replacement: "../deptest-importers",
+			}},
+			importertest.TestCase{
+				WantWarning: fmt.Sprintf(
+					"Warning: Skipping project. %s is replaced by the local directory ../deptest-importers",
+					importertest.Project,
+				),
+			},
+		},
+		"unknown pseudo-version revision": {
+			[]vendoredModule{{
+				path:    importertest.Project,
+				version: "v0.0.0-20180101000000-000000000000",
+			}},
+			importertest.TestCase{
+				WantWarning: fmt.Sprintf(
+					"Warning: Unable to find the revision 000000000000 of pseudo-version v0.0.0-20180101000000-000000000000 for %s",
+					importertest.Project,
+				),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		name := name
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			err := testCase.Execute(t, func(logger *log.Logger, sm gps.SourceManager) (*dep.Manifest, *dep.Lock) {
+				g := NewImporter(logger, true, sm)
+				g.modules = testCase.modules
+				return g.convert(importertest.RootProject)
+			})
+			if err != nil {
+				t.Fatalf("%#v", err)
+			}
+		})
+	}
+}
+
+func TestGoModules_Import(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	ctx := importertest.NewTestContext(h)
+	sm, err := ctx.SourceManager()
+	h.Must(err)
+	defer sm.Release()
+
+	h.TempDir(filepath.Join("src", importertest.RootProject, "vendor"))
+	h.TempCopy(modulesFile(importertest.RootProject), "modules.txt")
+	projectRoot := h.Path(importertest.RootProject)
+
+	logOutput := bytes.NewBuffer(nil)
+	ctx.Err = log.New(logOutput, "", 0)
+
+	m := NewImporter(ctx.Err, false, sm)
+	if !m.HasDepMetadata(projectRoot) {
+		t.Fatal("Expected the importer to detect vendor/modules.txt")
+	}
+
+	man, l, err := m.Import(projectRoot, importertest.RootProject)
+	h.Must(err)
+
+	wantM := dep.NewManifest()
+	c1, _ := gps.NewSemverConstraint("^0.8.1")
+	wantM.Constraints["github.com/sdboyer/deptest"] = gps.ProjectProperties{
+		Constraint: c1,
+	}
+	c2, _ := gps.NewSemverConstraint("^2.0.0")
+	wantM.Constraints["github.com/sdboyer/deptestdos"] = gps.ProjectProperties{
+		Constraint: c2,
+	}
+	if !reflect.DeepEqual(wantM, man) {
+		t.Errorf("unexpected manifest\nhave=%+v\nwant=%+v", man, wantM)
+	}
+
+	wantL := &dep.Lock{
+		P: []gps.LockedProject{
+			gps.NewLockedProject(
+				gps.ProjectIdentifier{ProjectRoot: "github.com/sdboyer/deptest"},
+				gps.NewVersion("v0.8.1").Pair("3f4c3bea144e112a69bbe5d8d01c1b09a544253f"),
+				nil,
+			),
+			gps.NewLockedProject(
+				gps.ProjectIdentifier{ProjectRoot: "github.com/sdboyer/deptestdos"},
+				gps.NewVersion("v2.0.0").Pair("5c607206be5decd28e6263ffffdcee067266015e"),
+				nil,
+			),
+		},
+	}
+	if !reflect.DeepEqual(wantL, l) {
+		t.Errorf("unexpected lock\nhave=%+v\nwant=%+v", l, wantL)
+	}
+
+	goldenFile := "golden.txt"
+	got := logOutput.String()
+	want := h.GetTestFileString(goldenFile)
+	if want != got {
+		if *test.UpdateGolden {
+			if err := h.WriteTestFile(goldenFile, got); err != nil {
+				t.Fatalf("%+v", errors.Wrapf(err, "Unable to write updated golden file %s", goldenFile))
+			}
+		} else {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestParseModuleLine(t *testing.T) {
+	testcase := func(in string, wantMod *vendoredModule, wantErr error) func(*testing.T) {
+		return func(t *testing.T) {
+			haveMod, haveErr := parseModuleLine(in)
+			if !reflect.DeepEqual(haveMod, wantMod) {
+				t.Errorf("unexpected module, have=%+v, want=%+v", haveMod, wantMod)
+			}
+
+			switch {
+			case wantErr == nil:
+				if haveErr != nil {
+					t.Errorf("expected nil err, have %v", haveErr)
+				}
+			case haveErr == nil:
+				t.Errorf("expected non-nil err %v, have nil", wantErr)
+			default:
+				if haveErr.Error() != wantErr.Error() {
+					t.Errorf("expected err=%q, have err=%q", wantErr.Error(), haveErr.Error())
+				}
+			}
+		}
+	}
+
+	t.Run("module",
+		testcase("# github.com/golang/notreal v1.0.0",
+			&vendoredModule{
+				path:    "github.com/golang/notreal",
+				version: "v1.0.0",
+			}, nil))
+
+	t.Run("replaced version",
+		testcase("# github.com/golang/notreal v1.0.0 => github.com/fork/notreal v1.0.1",
+			&vendoredModule{
+				path:               "github.com/golang/notreal",
+				version:            "v1.0.0",
+				replacement:        "github.com/fork/notreal",
+				replacementVersion: "v1.0.1",
+			}, nil))
+
+	t.Run("replaced module",
+		testcase("# github.com/golang/notreal => github.com/fork/notreal v1.0.1",
+			&vendoredModule{
+				path:               "github.com/golang/notreal",
+				replacement:        "github.com/fork/notreal",
+				replacementVersion: "v1.0.1",
+			}, nil))
+
+	t.Run("local replacement",
+		testcase("# github.com/golang/notreal => ../notreal",
+			&vendoredModule{
+				path:        "github.com/golang/notreal",
+				replacement: "../notreal",
+			}, nil))
+
+	t.Run("package line", testcase("github.com/golang/notreal/pkg", nil, nil))
+	t.Run("annotation", testcase("## explicit", nil, nil))
+	t.Run("empty line", testcase("", nil, nil))
+
+	t.Run("missing version",
+		testcase("# github.com/golang/notreal", nil,
+			errors.New("invalid module line: \"# github.com/golang/notreal\""),
+		))
+}
+
+func TestPseudoVersionRevision(t *testing.T) {
+	cases := map[string]string{
+		"v0.0.0-20180613153352-e1c0ee2d9a6c":       "e1c0ee2d9a6c",
+		"v1.2.4-0.20180613153352-e1c0ee2d9a6c":     "e1c0ee2d9a6c",
+		"v1.2.3-pre.0.20180613153352-e1c0ee2d9a6c": "e1c0ee2d9a6c",
+		"v1.2.3":     "",
+		"v1.2.3-rc1": "",
+	}
+
+	for in, want := range cases {
+		var have string
+		if match := pseudoVersionRevision.FindStringSubmatch(in); match != nil {
+			have = match[1]
+		}
+		if have != want {
+			t.Errorf("unexpected revision for %s: have %q, want %q", in, have, want)
+		}
+	}
+}
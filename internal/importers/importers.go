@@ -12,6 +12,7 @@ import (
 	"github.com/golang/dep/internal/importers/glide"
 	"github.com/golang/dep/internal/importers/glock"
 	"github.com/golang/dep/internal/importers/godep"
+	"github.com/golang/dep/internal/importers/gomod"
 	"github.com/golang/dep/internal/importers/govend"
 	"github.com/golang/dep/internal/importers/govendor"
 	"github.com/golang/dep/internal/importers/gvt"
@@ -41,5 +42,6 @@ func BuildAll(logger *log.Logger, verbose bool, sm gps.SourceManager) []Importer
 		gvt.NewImporter(logger, verbose, sm),
 		govendor.NewImporter(logger, verbose, sm),
 		glock.NewImporter(logger, verbose, sm),
+		gomod.NewImporter(logger, verbose, sm),
 	}
 }
@@ -0,0 +1,111 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package watch tracks modifications to the files of a project that dep check
+// compares: Gopkg.toml, Gopkg.lock and everything under vendor.
+package watch
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupported is returned by New where the platform offers no way to be
+// notified of modifications to files.
+var ErrUnsupported = errors.New("watching files is not supported on this platform")
+
+// Tracker records whether a project has been modified since it was last reset,
+// so that the answer costs nothing, rather than a hash of vendor.
+//
+// A Tracker starts out dirty, as nothing is known of the project until it has
+// been checked.
+type Tracker struct {
+	dirty   int32 // accessed atomically
+	changed chan struct{}
+	close   func() error
+
+	mu  sync.Mutex
+	err error
+}
+
+// New starts tracking modifications to the project at root.
+func New(root string) (*Tracker, error) {
+	t := &Tracker{
+		dirty:   1,
+		changed: make(chan struct{}, 1),
+	}
+	closer, err := watchProject(root, t)
+	if err != nil {
+		return nil, err
+	}
+	t.close = closer
+	return t, nil
+}
+
+// Dirty reports whether anything has been modified since the last Reset, or
+// whether tracking has failed, in which case nothing can be ruled out.
+func (t *Tracker) Dirty() bool {
+	return atomic.LoadInt32(&t.dirty) != 0 || t.Err() != nil
+}
+
+// Reset marks the project clean, reporting whether it was dirty. To not miss
+// modifications, reset before checking the project, not after.
+func (t *Tracker) Reset() bool {
+	was := atomic.SwapInt32(&t.dirty, 0) != 0
+	select {
+	case <-t.changed:
+	default:
+	}
+	return was || t.Err() != nil
+}
+
+// Changed returns a channel that receives when the project is first modified
+// after a Reset.
+func (t *Tracker) Changed() <-chan struct{} {
+	return t.changed
+}
+
+// Err returns the error that made tracking fail, if it has.
+func (t *Tracker) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Close stops tracking modifications.
+func (t *Tracker) Close() error {
+	return t.close()
+}
+
+// touch marks the project dirty, signalling Changed if it was clean.
+func (t *Tracker) touch() {
+	if atomic.SwapInt32(&t.dirty, 1) == 0 {
+		select {
+		case t.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// fail records that modifications can no longer be tracked, which leaves the
+// project dirty for good.
+func (t *Tracker) fail(err error) {
+	t.mu.Lock()
+	if t.err == nil {
+		t.err = err
+	}
+	t.mu.Unlock()
+	t.touch()
+}
+
+// isProjectFile reports whether name, in the root of a project, is tracked.
+func isProjectFile(name string) bool {
+	switch name {
+	case "Gopkg.toml", "Gopkg.lock", "vendor":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin freebsd openbsd netbsd dragonfly windows
+
+package watch
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// watcher watches the root of a project, and every directory under vendor, as
+// fsnotify does not watch directories recursively.
+type watcher struct {
+	fw   *fsnotify.Watcher
+	root string
+	t    *Tracker
+}
+
+func watchProject(root string, t *Tracker) (func() error, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot start watching files")
+	}
+	w := &watcher{
+		fw:   fw,
+		root: filepath.Clean(root),
+		t:    t,
+	}
+
+	if err := w.add(w.root); err != nil {
+		fw.Close()
+		return nil, err
+	}
+	if err := w.addTree(filepath.Join(w.root, "vendor")); err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	go w.run()
+	return fw.Close, nil
+}
+
+func (w *watcher) add(dir string) error {
+	return errors.Wrapf(w.fw.Add(dir), "cannot watch %s", dir)
+}
+
+// addTree watches dir and every directory under it, if it still exists.
+func (w *watcher) addTree(dir string) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		return w.add(path)
+	})
+	// A directory removed while it is walked will be reported as removed.
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil
+	}
+	return err
+}
+
+// run handles the events of fw until it is closed.
+func (w *watcher) run() {
+	for {
+		select {
+		case ev, ok := <-w.fw.Events:
+			if !ok {
+				return
+			}
+			if err := w.handle(ev); err != nil {
+				w.t.fail(err)
+			}
+		case err, ok := <-w.fw.Errors:
+			if !ok {
+				return
+			}
+			if err != fsnotify.ErrEventOverflow {
+				w.t.fail(errors.Wrap(err, "cannot read file events"))
+				continue
+			}
+			// Events were lost, possibly for new directories, so watch them
+			// all again. Watching a directory twice keeps a single watch.
+			w.t.touch()
+			if err := w.addTree(filepath.Join(w.root, "vendor")); err != nil {
+				w.t.fail(err)
+			}
+		}
+	}
+}
+
+func (w *watcher) handle(ev fsnotify.Event) error {
+	// Changes of permissions are not compared by dep check.
+	if ev.Op&^fsnotify.Chmod == 0 {
+		return nil
+	}
+	name := filepath.Clean(ev.Name)
+	if filepath.Dir(name) == w.root && !isProjectFile(filepath.Base(name)) {
+		return nil
+	}
+
+	// Watch new directories before reporting them, so that by the time the
+	// project is reported dirty, their contents are tracked too.
+	if ev.Op&fsnotify.Create != 0 {
+		if fi, err := os.Lstat(name); err == nil && fi.IsDir() {
+			if err := w.addTree(name); err != nil {
+				return err
+			}
+		}
+	}
+	w.t.touch()
+	return nil
+}
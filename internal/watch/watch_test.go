@@ -0,0 +1,81 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	root, err := ioutil.TempDir("", "dep-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(name string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Gopkg.toml")
+	write("vendor/github.com/foo/bar/bar.go")
+
+	tr, err := New(root)
+	if err == ErrUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	if !tr.Reset() {
+		t.Fatal("expected a new tracker to be dirty")
+	}
+
+	// Modifications are reported asynchronously.
+	expectChange := func(what string, modify func()) {
+		t.Helper()
+		tr.Reset()
+		modify()
+		select {
+		case <-tr.Changed():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: expected a change to be reported", what)
+		}
+		if !tr.Dirty() {
+			t.Fatalf("%s: expected the tracker to be dirty", what)
+		}
+	}
+
+	expectChange("vendored file", func() { write("vendor/github.com/foo/bar/bar.go") })
+	expectChange("manifest", func() { write("Gopkg.toml") })
+	expectChange("new directory", func() {
+		if err := os.Mkdir(filepath.Join(root, "vendor", "github.com", "baz"), 0777); err != nil {
+			t.Fatal(err)
+		}
+	})
+	expectChange("file in new directory", func() { write("vendor/github.com/baz/baz.go") })
+	expectChange("lock", func() { write("Gopkg.lock") })
+
+	tr.Reset()
+	write("main.go")
+	time.Sleep(100 * time.Millisecond)
+	if tr.Dirty() {
+		t.Error("expected other files in the root to be ignored")
+	}
+	if tr.Err() != nil {
+		t.Fatal(tr.Err())
+	}
+}
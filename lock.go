@@ -32,6 +32,10 @@ type SolveMeta struct {
 	SolverName      string
 	SolverVersion   int
 	InputImports    []string
+	// InputsVersion is the verify.InputsVersion under which InputImports
+	// were recorded. It is zero for locks that do not record it, which are
+	// compared under version 1.
+	InputsVersion int
 }
 
 type rawLock struct {
@@ -45,17 +49,28 @@ type solveMeta struct {
 	SolverName      string   `toml:"solver-name"`
 	SolverVersion   int      `toml:"solver-version"`
 	InputImports    []string `toml:"input-imports"`
+	InputsVersion   int      `toml:"inputs-version,omitempty"`
 }
 
 type rawLockedProject struct {
-	Name      string   `toml:"name"`
-	Branch    string   `toml:"branch,omitempty"`
-	Revision  string   `toml:"revision"`
-	Version   string   `toml:"version,omitempty"`
-	Source    string   `toml:"source,omitempty"`
-	Packages  []string `toml:"packages"`
-	PruneOpts string   `toml:"pruneopts"`
-	Digest    string   `toml:"digest"`
+	Name          string   `toml:"name"`
+	Branch        string   `toml:"branch,omitempty"`
+	Revision      string   `toml:"revision"`
+	PseudoVersion string   `toml:"pseudo-version,omitempty"`
+	Version       string   `toml:"version,omitempty"`
+	Source        string   `toml:"source,omitempty"`
+	FetchedFrom   string   `toml:"fetched-from,omitempty"`
+	UpdateReason  string   `toml:"update-reason,omitempty"`
+	RootDir       string   `toml:"root-dir,omitempty"`
+	TestOnly      bool     `toml:"test-only,omitempty"`
+	Packages      []string `toml:"packages"`
+	PruneOpts     string   `toml:"pruneopts"`
+	Digest        string   `toml:"digest"`
+}
+
+// ReadLock reads a Lock in the Gopkg.lock format from r.
+func ReadLock(r io.Reader) (*Lock, error) {
+	return readLock(r)
 }
 
 func readLock(r io.Reader) (*Lock, error) {
@@ -71,7 +86,11 @@ func readLock(r io.Reader) (*Lock, error) {
 		return nil, errors.Wrap(err, "Unable to parse the lock as TOML")
 	}
 
-	return fromRawLock(raw)
+	l, err := fromRawLock(raw)
+	if err != nil {
+		return nil, locateTOMLError(err, buf.Bytes())
+	}
+	return l, nil
 }
 
 func fromRawLock(raw rawLock) (*Lock, error) {
@@ -84,41 +103,50 @@ func fromRawLock(raw rawLock) (*Lock, error) {
 	l.SolveMeta.SolverName = raw.SolveMeta.SolverName
 	l.SolveMeta.SolverVersion = raw.SolveMeta.SolverVersion
 	l.SolveMeta.InputImports = raw.SolveMeta.InputImports
+	l.SolveMeta.InputsVersion = raw.SolveMeta.InputsVersion
 
-	for _, ld := range raw.Projects {
+	for i, ld := range raw.Projects {
 		r := gps.Revision(ld.Revision)
 
 		var v gps.Version = r
 		if ld.Version != "" {
 			if ld.Branch != "" {
-				return nil, errors.Errorf("lock file specified both a branch (%s) and version (%s) for %s", ld.Branch, ld.Version, ld.Name)
+				return nil, newTOMLPathError(errors.Errorf("lock file specified both a branch (%s) and version (%s) for %s", ld.Branch, ld.Version, ld.Name), "projects", i)
 			}
 			v = gps.NewVersion(ld.Version).Pair(r)
 		} else if ld.Branch != "" {
 			v = gps.NewBranch(ld.Branch).Pair(r)
 		} else if r == "" {
-			return nil, errors.Errorf("lock file has entry for %s, but specifies no branch or version", ld.Name)
+			return nil, newTOMLPathError(errors.Errorf("lock file has entry for %s, but specifies no branch or version", ld.Name), "projects", i)
 		}
 
+		rootDir, err := gps.CleanRootDir(ld.RootDir)
+		if err != nil {
+			return nil, newTOMLPathError(errors.Wrapf(err, "root-dir of %s", ld.Name), "projects", i, "root-dir")
+		}
 		id := gps.ProjectIdentifier{
 			ProjectRoot: gps.ProjectRoot(ld.Name),
 			Source:      ld.Source,
+			RootDir:     rootDir,
 		}
 
-		var err error
 		vp := verify.VerifiableProject{
 			LockedProject: gps.NewLockedProject(id, v, ld.Packages),
+			PseudoVersion: ld.PseudoVersion,
+			TestOnly:      ld.TestOnly,
+			FetchedFrom:   ld.FetchedFrom,
+			UpdateReason:  ld.UpdateReason,
 		}
 		if ld.Digest != "" {
 			vp.Digest, err = verify.ParseVersionedDigest(ld.Digest)
 			if err != nil {
-				return nil, err
+				return nil, newTOMLPathError(err, "projects", i, "digest")
 			}
 		}
 
 		po, err := gps.ParsePruneOptions(ld.PruneOpts)
 		if err != nil {
-			return nil, errors.Errorf("%s in prune options for %s", err.Error(), ld.Name)
+			return nil, newTOMLPathError(errors.Errorf("%s in prune options for %s", err.Error(), ld.Name), "projects", i, "pruneopts")
 		}
 		// Add the vendor pruning bit so that gps doesn't get confused
 		vp.PruneOpts = po | gps.PruneNestedVendorDirs
@@ -146,6 +174,15 @@ func (l *Lock) InputImports() []string {
 	return l.SolveMeta.InputImports
 }
 
+// InputsVersion reports the version of the rules under which the input imports
+// of this Lock were recorded.
+func (l *Lock) InputsVersion() int {
+	if l == nil {
+		return 0
+	}
+	return l.SolveMeta.InputsVersion
+}
+
 // HasProjectWithRoot checks if the lock contains a project with the provided
 // ProjectRoot.
 //
@@ -173,6 +210,124 @@ func (l *Lock) dup() *Lock {
 	return l2
 }
 
+// hasPseudoVersion reports whether a project locked to v is locked to a
+// revision or a branch, rather than to a tag, so that the pseudo-version of its
+// revision is recorded in the lock.
+func hasPseudoVersion(v gps.Version) bool {
+	switch tv := v.(type) {
+	case gps.Revision:
+		return true
+	case gps.PairedVersion:
+		return tv.Type() == gps.IsBranch
+	}
+	return false
+}
+
+// carryPseudoVersions copies into to the pseudo-versions recorded in from for
+// the projects that both lock to the same revision.
+func carryPseudoVersions(from, to *Lock) {
+	recorded := make(map[gps.ProjectRoot]verify.VerifiableProject)
+	for _, lp := range from.Projects() {
+		if vp, ok := lp.(verify.VerifiableProject); ok && vp.PseudoVersion != "" {
+			recorded[lp.Ident().ProjectRoot] = vp
+		}
+	}
+
+	for k, lp := range to.Projects() {
+		vp, ok := lp.(verify.VerifiableProject)
+		if !ok || vp.PseudoVersion != "" {
+			continue
+		}
+		if old, has := recorded[lp.Ident().ProjectRoot]; has && revisionOf(old.Version()) == revisionOf(vp.Version()) {
+			vp.PseudoVersion = old.PseudoVersion
+			to.P[k] = vp
+		}
+	}
+}
+
+// recordPseudoVersions records the pseudo-version of each project in l that
+// is locked to a revision or a branch, and has none recorded yet. The commit
+// times of the revisions are read from sm; if it cannot report them, no
+// pseudo-versions are recorded.
+func (l *Lock) recordPseudoVersions(sm gps.SourceManager) {
+	rt, ok := sm.(revisionTimer)
+	if !ok {
+		return
+	}
+	for k, lp := range l.Projects() {
+		vp, ok := lp.(verify.VerifiableProject)
+		if !ok || vp.PseudoVersion != "" || !hasPseudoVersion(vp.Version()) {
+			continue
+		}
+		t, err := rt.RevisionTime(vp.Ident(), vp.Version())
+		if err != nil {
+			// Sources whose VCS cannot report commit times go without.
+			continue
+		}
+		vp.PseudoVersion = gps.PseudoVersion(revisionOf(vp.Version()), t)
+		l.P[k] = vp
+	}
+}
+
+// fetchRecorder is implemented by SourceManagers that can report which of the
+// fallback sources of a project it was fetched from.
+type fetchRecorder interface {
+	FetchedFrom(gps.ProjectIdentifier) (string, bool)
+}
+
+// carryFetchedFrom copies into to the fallback sources recorded in from for
+// the projects that both lock to the same revision from the same source.
+func carryFetchedFrom(from, to *Lock) {
+	recorded := make(map[gps.ProjectIdentifier]verify.VerifiableProject)
+	for _, lp := range from.Projects() {
+		if vp, ok := lp.(verify.VerifiableProject); ok && vp.FetchedFrom != "" {
+			recorded[lp.Ident()] = vp
+		}
+	}
+
+	for k, lp := range to.Projects() {
+		vp, ok := lp.(verify.VerifiableProject)
+		if !ok || vp.FetchedFrom != "" {
+			continue
+		}
+		if old, has := recorded[lp.Ident()]; has && revisionOf(old.Version()) == revisionOf(vp.Version()) {
+			vp.FetchedFrom = old.FetchedFrom
+			to.P[k] = vp
+		}
+	}
+}
+
+// recordFetchedFrom records, for each project in l, the fallback source it was
+// fetched from by sm, if any. Projects that sm has not fetched, and so cannot
+// tell about, keep what is recorded.
+func (l *Lock) recordFetchedFrom(sm gps.SourceManager) {
+	fr, ok := sm.(fetchRecorder)
+	if !ok {
+		return
+	}
+	for k, lp := range l.Projects() {
+		vp, ok := lp.(verify.VerifiableProject)
+		if !ok {
+			continue
+		}
+		if src, known := fr.FetchedFrom(vp.Ident()); known {
+			vp.FetchedFrom = src
+			l.P[k] = vp
+		}
+	}
+}
+
+// revisionOf returns the revision of v, if it has one.
+func revisionOf(v gps.Version) gps.Revision {
+	switch tv := v.(type) {
+	case gps.Revision:
+		return tv
+	case gps.PairedVersion:
+		return tv.Revision()
+	}
+	return ""
+}
+
 // toRaw converts the manifest into a representation suitable to write to the lock file
 func (l *Lock) toRaw() rawLock {
 	raw := rawLock{
@@ -185,6 +340,11 @@ func (l *Lock) toRaw() rawLock {
 		},
 		Projects: make([]rawLockedProject, 0, len(l.P)),
 	}
+	// Version 1 of the rules is implied by the absence of the field, so that
+	// locks only change once the rules do.
+	if l.SolveMeta.InputsVersion > 1 {
+		raw.SolveMeta.InputsVersion = l.SolveMeta.InputsVersion
+	}
 
 	sort.Slice(l.P, func(i, j int) bool {
 		return l.P[i].Ident().Less(l.P[j].Ident())
@@ -195,6 +355,7 @@ func (l *Lock) toRaw() rawLock {
 		ld := rawLockedProject{
 			Name:     string(id.ProjectRoot),
 			Source:   id.Source,
+			RootDir:  id.RootDir,
 			Packages: lp.Packages(),
 		}
 
@@ -208,6 +369,12 @@ func (l *Lock) toRaw() rawLock {
 		vp := lp.(verify.VerifiableProject)
 		ld.Digest = vp.Digest.String()
 		ld.PruneOpts = (vp.PruneOpts & ^gps.PruneNestedVendorDirs).String()
+		ld.TestOnly = vp.TestOnly
+		ld.FetchedFrom = vp.FetchedFrom
+		ld.UpdateReason = vp.UpdateReason
+		if hasPseudoVersion(v) {
+			ld.PseudoVersion = vp.PseudoVersion
+		}
 
 		raw.Projects = append(raw.Projects, ld)
 	}
@@ -238,6 +405,7 @@ func LockFromSolution(in gps.Solution, prune gps.CascadingPruneOptions) *Lock {
 			AnalyzerName:    in.AnalyzerName(),
 			AnalyzerVersion: in.AnalyzerVersion(),
 			InputImports:    in.InputImports(),
+			InputsVersion:   verify.InputsVersion,
 			SolverName:      in.SolverName(),
 			SolverVersion:   in.SolverVersion(),
 		},
@@ -0,0 +1,239 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+)
+
+// LockChanges is what changes between two locks, typically Gopkg.lock and the
+// lock that a solve would write in its place.
+//
+// Its JSON form is stable, and is the one emitted by dep ensure -dry-run
+// -json, dep check -json, dep lock diff -json and the daemon's /changes
+// endpoint. Fields may be added, but not renamed or removed.
+type LockChanges struct {
+	AddedImports   []string        `json:"addedImports,omitempty"`
+	RemovedImports []string        `json:"removedImports,omitempty"`
+	Projects       []ProjectChange `json:"projects"`
+
+	delta verify.LockDelta
+}
+
+// ChangeKind is how a project changes between two locks.
+type ChangeKind string
+
+// The kinds of ProjectChange.
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// ProjectChange is a change to a single project between two locks.
+type ProjectChange struct {
+	Name string     `json:"name"`
+	Kind ChangeKind `json:"kind"`
+	// Changed lists what changed about a modified project, as any of
+	// "source", "rootDir", "version", "revision", "packages", "pruneOpts",
+	// "digestVersion" and "digest", in that order.
+	Changed []string `json:"changed,omitempty"`
+	// Before and After are the project as it is in each lock, if it is.
+	Before          *LockedProjectState `json:"before,omitempty"`
+	After           *LockedProjectState `json:"after,omitempty"`
+	PackagesAdded   []string            `json:"packagesAdded,omitempty"`
+	PackagesRemoved []string            `json:"packagesRemoved,omitempty"`
+
+	delta verify.LockedProjectDelta
+}
+
+// LockedProjectState is a project as it is recorded in a lock.
+type LockedProjectState struct {
+	Source    string `json:"source,omitempty"`
+	RootDir   string `json:"rootDir,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	PruneOpts string `json:"pruneOpts,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+}
+
+// changedDimensions names the dimensions listed in ProjectChange.Changed.
+var changedDimensions = []struct {
+	dim  verify.DeltaDimension
+	name string
+}{
+	{verify.SourceChanged, "source"},
+	{verify.RootDirChanged, "rootDir"},
+	{verify.VersionChanged, "version"},
+	{verify.RevisionChanged, "revision"},
+	{verify.PackagesChanged, "packages"},
+	{verify.PruneOptsChanged, "pruneOpts"},
+	{verify.HashVersionChanged, "digestVersion"},
+	{verify.HashChanged, "digest"},
+}
+
+// DiffLocks computes the LockChanges from l1 to l2. Either may be nil, which is
+// treated as an empty lock.
+func DiffLocks(l1, l2 gps.Lock) LockChanges {
+	if l, ok := l1.(*Lock); ok && l == nil {
+		l1 = nil
+	}
+	if l, ok := l2.(*Lock); ok && l == nil {
+		l2 = nil
+	}
+
+	lc := LockChanges{
+		delta:    verify.DiffLocks(l1, l2),
+		Projects: []ProjectChange{},
+	}
+	lc.AddedImports = sortedStrings(lc.delta.AddedImportInputs)
+	lc.RemovedImports = sortedStrings(lc.delta.RemovedImportInputs)
+
+	before, after := lockedProjectsByRoot(l1), lockedProjectsByRoot(l2)
+	roots := make([]string, 0, len(lc.delta.ProjectDeltas))
+	for pr, lpd := range lc.delta.ProjectDeltas {
+		if lpd.Changes() != 0 {
+			roots = append(roots, string(pr))
+		}
+	}
+	sort.Strings(roots)
+
+	for _, name := range roots {
+		pr := gps.ProjectRoot(name)
+		lpd := lc.delta.ProjectDeltas[pr]
+		pc := ProjectChange{
+			Name:  name,
+			Kind:  ChangeModified,
+			delta: lpd,
+		}
+		if lp, has := before[pr]; has {
+			pc.Before = newLockedProjectState(lp)
+		}
+		if lp, has := after[pr]; has {
+			pc.After = newLockedProjectState(lp)
+		}
+
+		switch {
+		case lpd.WasAdded():
+			pc.Kind = ChangeAdded
+		case lpd.WasRemoved():
+			pc.Kind = ChangeRemoved
+		default:
+			dims := lpd.Changes()
+			for _, cd := range changedDimensions {
+				if dims&cd.dim != 0 {
+					pc.Changed = append(pc.Changed, cd.name)
+				}
+			}
+			pc.PackagesAdded = sortedStrings(lpd.PackagesAdded)
+			pc.PackagesRemoved = sortedStrings(lpd.PackagesRemoved)
+		}
+		lc.Projects = append(lc.Projects, pc)
+	}
+
+	return lc
+}
+
+// Empty reports whether there are no changes at all.
+func (lc LockChanges) Empty() bool {
+	return len(lc.AddedImports) == 0 && len(lc.RemovedImports) == 0 && len(lc.Projects) == 0
+}
+
+// Changed reports whether there are changes along any of dims.
+func (lc LockChanges) Changed(dims verify.DeltaDimension) bool {
+	return lc.delta.Changed(dims)
+}
+
+// Delta returns the underlying delta, for the details that LockChanges does
+// not carry.
+func (lc LockChanges) Delta() verify.LockDelta {
+	return lc.delta
+}
+
+// Delta returns the underlying delta of the project.
+func (pc ProjectChange) Delta() verify.LockedProjectDelta {
+	return pc.delta
+}
+
+// String returns the changes one per line, marking added projects and imports
+// with +, removed ones with -, and modified projects with ~.
+func (lc LockChanges) String() string {
+	var buf bytes.Buffer
+	for _, imp := range lc.AddedImports {
+		fmt.Fprintf(&buf, "+ import %s\n", imp)
+	}
+	for _, imp := range lc.RemovedImports {
+		fmt.Fprintf(&buf, "- import %s\n", imp)
+	}
+	for _, pc := range lc.Projects {
+		switch pc.Kind {
+		case ChangeAdded:
+			fmt.Fprintf(&buf, "+ %s %s\n", pc.Name, pc.After)
+		case ChangeRemoved:
+			fmt.Fprintf(&buf, "- %s %s\n", pc.Name, pc.Before)
+		default:
+			fmt.Fprintf(&buf, "~ %s %s -> %s (%s)\n", pc.Name, pc.Before, pc.After, strings.Join(pc.Changed, ", "))
+		}
+	}
+	return buf.String()
+}
+
+func (s *LockedProjectState) String() string {
+	if s == nil {
+		return ""
+	}
+	rev := trimSHA(gps.Revision(s.Revision))
+	if s.Version == "" {
+		return rev
+	}
+	return fmt.Sprintf("%s (%s)", s.Version, rev)
+}
+
+func newLockedProjectState(lp gps.LockedProject) *LockedProjectState {
+	rev, branch, version := gps.VersionComponentStrings(lp.Version())
+	if version == "" {
+		version = branch
+	}
+	s := &LockedProjectState{
+		Source:   lp.Ident().Source,
+		RootDir:  lp.Ident().RootDir,
+		Version:  version,
+		Revision: rev,
+	}
+	if vp, ok := lp.(verify.VerifiableProject); ok {
+		s.PruneOpts = vp.PruneOpts.String()
+		if vp.Digest.HashVersion != 0 {
+			s.Digest = vp.Digest.String()
+		}
+	}
+	return s
+}
+
+func lockedProjectsByRoot(l gps.Lock) map[gps.ProjectRoot]gps.LockedProject {
+	m := make(map[gps.ProjectRoot]gps.LockedProject)
+	if l == nil {
+		return m
+	}
+	for _, lp := range l.Projects() {
+		m[lp.Ident().ProjectRoot] = lp
+	}
+	return m
+}
+
+// sortedStrings returns a sorted copy of s.
+func sortedStrings(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	s = append([]string(nil), s...)
+	sort.Strings(s)
+	return s
+}
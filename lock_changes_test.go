@@ -0,0 +1,121 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+)
+
+func TestDiffLocks(t *testing.T) {
+	lp := func(name string, v gps.Version, prune gps.PruneOptions, pkgs ...string) gps.LockedProject {
+		return verify.VerifiableProject{
+			LockedProject: gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(name)}, v, pkgs),
+			PruneOpts:     prune,
+		}
+	}
+
+	l1 := &Lock{
+		SolveMeta: SolveMeta{InputImports: []string{"github.com/foo/bar", "github.com/old/dep"}},
+		P: []gps.LockedProject{
+			lp("github.com/foo/bar", gps.NewVersion("v1.0.0").Pair("rev1"), gps.PruneNestedVendorDirs, "."),
+			lp("github.com/old/dep", gps.NewBranch("master").Pair("rev2"), gps.PruneNestedVendorDirs, "."),
+			lp("github.com/same/dep", gps.Revision("rev3"), gps.PruneNestedVendorDirs, "."),
+		},
+	}
+	l2 := &Lock{
+		SolveMeta: SolveMeta{InputImports: []string{"github.com/foo/bar", "github.com/new/dep"}},
+		P: []gps.LockedProject{
+			lp("github.com/foo/bar", gps.NewVersion("v1.1.0").Pair("rev4"), gps.PruneNestedVendorDirs|gps.PruneGoTestFiles, ".", "sub"),
+			lp("github.com/new/dep", gps.NewVersion("v0.1.0").Pair("rev5"), gps.PruneNestedVendorDirs, "."),
+			lp("github.com/same/dep", gps.Revision("rev3"), gps.PruneNestedVendorDirs, "."),
+		},
+	}
+
+	lc := DiffLocks(l1, l2)
+	if lc.Empty() || !lc.Changed(verify.VersionChanged) || lc.Changed(verify.SourceChanged) {
+		t.Errorf("unexpected dimensions of change: %v", lc.Delta().Changes())
+	}
+
+	got, err := json.MarshalIndent(lc, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "addedImports": [
+    "github.com/new/dep"
+  ],
+  "removedImports": [
+    "github.com/old/dep"
+  ],
+  "projects": [
+    {
+      "name": "github.com/foo/bar",
+      "kind": "modified",
+      "changed": [
+        "version",
+        "revision",
+        "packages",
+        "pruneOpts"
+      ],
+      "before": {
+        "version": "v1.0.0",
+        "revision": "rev1",
+        "pruneOpts": "V"
+      },
+      "after": {
+        "version": "v1.1.0",
+        "revision": "rev4",
+        "pruneOpts": "TV"
+      },
+      "packagesAdded": [
+        "sub"
+      ]
+    },
+    {
+      "name": "github.com/new/dep",
+      "kind": "added",
+      "after": {
+        "version": "v0.1.0",
+        "revision": "rev5",
+        "pruneOpts": "V"
+      }
+    },
+    {
+      "name": "github.com/old/dep",
+      "kind": "removed",
+      "before": {
+        "version": "master",
+        "revision": "rev2",
+        "pruneOpts": "V"
+      }
+    }
+  ]
+}`
+	if string(got) != want {
+		t.Errorf("unexpected JSON:\n%s\nwant:\n%s", got, want)
+	}
+
+	wantText := `+ import github.com/new/dep
+- import github.com/old/dep
+~ github.com/foo/bar v1.0.0 (rev1) -> v1.1.0 (rev4) (version, revision, packages, pruneOpts)
++ github.com/new/dep v0.1.0 (rev5)
+- github.com/old/dep master (rev2)
+`
+	if lc.String() != wantText {
+		t.Errorf("unexpected text:\n%s\nwant:\n%s", lc.String(), wantText)
+	}
+
+	var nilLock *Lock
+	if lc := DiffLocks(nilLock, nil); !lc.Empty() {
+		t.Errorf("expected no changes between nil locks, got %v", lc)
+	}
+	if lc := DiffLocks(nil, l2); len(lc.Projects) != 3 || lc.Projects[0].Kind != ChangeAdded {
+		t.Errorf("expected every project to be added to a nil lock, got %v", lc.Projects)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LockRegistryFile is the name of the file in the cache directory that lists
+// the lock files registered with the cache, one absolute path per line.
+const LockRegistryFile = "locks.txt"
+
+// ReadLockRegistry returns the paths of the lock files registered with the
+// cache in cachedir, in lexical order. The registered files need not exist.
+func ReadLockRegistry(cachedir string) ([]string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(cachedir, LockRegistryFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read the lock registry")
+	}
+
+	var paths []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			paths = append(paths, line)
+		}
+	}
+	sort.Strings(paths)
+	return paths, errors.Wrap(sc.Err(), "failed to read the lock registry")
+}
+
+// RegisterLocks adds the lock files at paths to the registry of the cache in
+// cachedir. Paths already registered are not added again.
+func RegisterLocks(cachedir string, paths ...string) error {
+	registered, err := ReadLockRegistry(cachedir)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(registered))
+	for _, p := range registered {
+		seen[p] = true
+	}
+	changed := false
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return errors.Wrapf(err, "invalid lock path %s", p)
+		}
+		if !seen[abs] {
+			seen[abs] = true
+			registered = append(registered, abs)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	sort.Strings(registered)
+
+	if err := os.MkdirAll(cachedir, 0777); err != nil {
+		return errors.Wrap(err, "failed to create the cache directory")
+	}
+	f, err := ioutil.TempFile(cachedir, ".locks")
+	if err != nil {
+		return errors.Wrap(err, "failed to create the lock registry")
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(strings.Join(registered, "\n") + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write the lock registry")
+	}
+	return errors.Wrap(os.Rename(f.Name(), filepath.Join(cachedir, LockRegistryFile)), "failed to write the lock registry")
+}
@@ -0,0 +1,42 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLockRegistry(t *testing.T) {
+	cachedir, err := ioutil.TempDir("", "dep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cachedir)
+
+	if paths, err := ReadLockRegistry(cachedir); err != nil || len(paths) != 0 {
+		t.Fatalf("expected an empty registry, got %v, %v", paths, err)
+	}
+
+	b := filepath.Join(cachedir, "b", "Gopkg.lock")
+	a := filepath.Join(cachedir, "a", "Gopkg.lock")
+	if err := RegisterLocks(cachedir, b, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterLocks(cachedir, a); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := ReadLockRegistry(cachedir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{a, b}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected %v to be registered, got %v", want, paths)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/gps/verify"
@@ -177,3 +178,166 @@ func TestReadLockErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestLockInputsVersionRoundTrip(t *testing.T) {
+	in := `[solve-meta]
+  analyzer-name = ""
+  analyzer-version = 0
+  input-imports = []
+  inputs-version = 2
+  solver-name = ""
+  solver-version = 0
+`
+	l, err := readLock(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.InputsVersion() != 2 {
+		t.Fatalf("expected inputs version 2, got %d", l.InputsVersion())
+	}
+
+	out, err := l.MarshalTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(out)) != strings.TrimSpace(in) {
+		t.Errorf("lock did not round-trip:\n\t(GOT): %s\n\t(WNT): %s", out, in)
+	}
+
+	// Version 1 is implied, and not written.
+	l.SolveMeta.InputsVersion = 1
+	out, err = l.MarshalTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "inputs-version") {
+		t.Errorf("expected inputs version 1 not to be written:\n%s", out)
+	}
+}
+
+// fakeRevisionTimer is a SourceManager that reports the same commit time for
+// every revision.
+type fakeRevisionTimer struct {
+	gps.SourceManager
+	t time.Time
+}
+
+func (f fakeRevisionTimer) RevisionTime(gps.ProjectIdentifier, gps.Version) (time.Time, error) {
+	return f.t, nil
+}
+
+func TestLockPseudoVersions(t *testing.T) {
+	rev := gps.Revision("d05d5aca9f895d19e9265839bffeadd74a2d2ecb")
+	vp := func(root string, v gps.Version, pseudo string) verify.VerifiableProject {
+		return verify.VerifiableProject{
+			LockedProject: gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(root)}, v, []string{"."}),
+			PseudoVersion: pseudo,
+		}
+	}
+
+	old := &Lock{P: []gps.LockedProject{
+		vp("github.com/carried/lib", rev, "v0.0.0-20170101000000-d05d5aca9f89"),
+		vp("github.com/moved/lib", gps.Revision("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"), "v0.0.0-20170101000000-aaaaaaaaaaaa"),
+	}}
+	l := &Lock{P: []gps.LockedProject{
+		vp("github.com/carried/lib", rev, ""),
+		vp("github.com/moved/lib", rev, ""),
+		vp("github.com/branch/lib", gps.NewBranch("master").Pair(rev), ""),
+		vp("github.com/tagged/lib", gps.NewVersion("v1.0.0").Pair(rev), ""),
+	}}
+
+	carryPseudoVersions(old, l)
+	l.recordPseudoVersions(fakeRevisionTimer{t: time.Date(2018, time.June, 13, 15, 33, 52, 0, time.UTC)})
+
+	want := map[string]string{
+		"github.com/carried/lib": "v0.0.0-20170101000000-d05d5aca9f89",
+		"github.com/moved/lib":   "v0.0.0-20180613153352-d05d5aca9f89",
+		"github.com/branch/lib":  "v0.0.0-20180613153352-d05d5aca9f89",
+		"github.com/tagged/lib":  "",
+	}
+	for _, lp := range l.P {
+		root := string(lp.Ident().ProjectRoot)
+		if got := lp.(verify.VerifiableProject).PseudoVersion; got != want[root] {
+			t.Errorf("%s: expected pseudo-version %q, got %q", root, want[root], got)
+		}
+	}
+
+	b, err := l.MarshalTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "pseudo-version = "); n != 3 {
+		t.Errorf("expected 3 pseudo-versions in the lock, got %d:\n%s", n, b)
+	}
+	l2, err := readLock(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lp := range l2.P {
+		root := string(lp.Ident().ProjectRoot)
+		if got := lp.(verify.VerifiableProject).PseudoVersion; got != want[root] {
+			t.Errorf("%s: expected pseudo-version %q after reading the lock back, got %q", root, want[root], got)
+		}
+	}
+}
+
+// fakeFetchRecorder is a SourceManager that reports the fallback sources that
+// it fetched projects from, and that it has not fetched any others.
+type fakeFetchRecorder struct {
+	gps.SourceManager
+	fetched map[gps.ProjectRoot]string
+}
+
+func (f fakeFetchRecorder) FetchedFrom(id gps.ProjectIdentifier) (string, bool) {
+	src, has := f.fetched[id.ProjectRoot]
+	return src, has
+}
+
+func TestLockFetchedFrom(t *testing.T) {
+	rev := gps.Revision("d05d5aca9f895d19e9265839bffeadd74a2d2ecb")
+	vp := func(root string, v gps.Version, from string) verify.VerifiableProject {
+		return verify.VerifiableProject{
+			LockedProject: gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(root)}, v, []string{"."}),
+			FetchedFrom:   from,
+		}
+	}
+
+	old := &Lock{P: []gps.LockedProject{
+		vp("github.com/carried/lib", rev, "git.example.com/carried/lib"),
+		vp("github.com/moved/lib", gps.Revision("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"), "git.example.com/moved/lib"),
+		vp("github.com/recovered/lib", rev, "git.example.com/recovered/lib"),
+	}}
+	l := &Lock{P: []gps.LockedProject{
+		vp("github.com/carried/lib", rev, ""),
+		vp("github.com/moved/lib", rev, ""),
+		vp("github.com/recovered/lib", rev, ""),
+		vp("github.com/fallen/lib", rev, ""),
+	}}
+
+	carryFetchedFrom(old, l)
+	l.recordFetchedFrom(fakeFetchRecorder{fetched: map[gps.ProjectRoot]string{
+		"github.com/recovered/lib": "",
+		"github.com/fallen/lib":    "git.example.com/fallen/lib",
+	}})
+
+	want := map[string]string{
+		"github.com/carried/lib":   "git.example.com/carried/lib",
+		"github.com/moved/lib":     "",
+		"github.com/recovered/lib": "",
+		"github.com/fallen/lib":    "git.example.com/fallen/lib",
+	}
+	b, err := l.MarshalTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2, err := readLock(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lp := range l2.P {
+		root := string(lp.Ident().ProjectRoot)
+		if got := lp.(verify.VerifiableProject).FetchedFrom; got != want[root] {
+			t.Errorf("%s: expected to have been fetched from %q, got %q", root, want[root], got)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+)
+
+// MajorUpdates returns the projects that newLock updates to a new major
+// version from the one they are locked to in oldLock. An update to a semver
+// version outside the caret range of the old one counts, so that updates from
+// 0.3.x to 0.4.0 do as well as those from 1.x to 2.0.0.
+func MajorUpdates(oldLock, newLock gps.Lock) []gps.ProjectRoot {
+	if oldLock == nil || newLock == nil {
+		return nil
+	}
+
+	old := make(map[gps.ProjectRoot]gps.Version)
+	for _, lp := range oldLock.Projects() {
+		old[lp.Ident().ProjectRoot] = lp.Version()
+	}
+
+	var majors []gps.ProjectRoot
+	for _, lp := range newLock.Projects() {
+		pr := lp.Ident().ProjectRoot
+		if from, has := old[pr]; has && isMajorUpdate(from, lp.Version()) {
+			majors = append(majors, pr)
+		}
+	}
+	return majors
+}
+
+// isMajorUpdate reports whether to is a later semver version than from, and
+// outside its caret range.
+func isMajorUpdate(from, to gps.Version) bool {
+	if from == nil || to == nil || from.Type() != gps.IsSemver || to.Type() != gps.IsSemver {
+		return false
+	}
+	c, err := gps.NewSemverConstraintIC(from.String())
+	if err != nil || c.Matches(to) {
+		return false
+	}
+	vl := []gps.Version{from, to}
+	gps.SortForUpgrade(vl)
+	return vl[0].String() == to.String()
+}
+
+// RecordUpdateReason records reason as the reason for each of the updates to
+// a new major version that l makes to oldLock.
+func (l *Lock) RecordUpdateReason(oldLock gps.Lock, reason string) {
+	if reason == "" {
+		return
+	}
+	majors := make(map[gps.ProjectRoot]bool)
+	for _, pr := range MajorUpdates(oldLock, l) {
+		majors[pr] = true
+	}
+	for k, lp := range l.P {
+		vp, ok := lp.(verify.VerifiableProject)
+		if ok && majors[vp.Ident().ProjectRoot] {
+			vp.UpdateReason = reason
+			l.P[k] = vp
+		}
+	}
+}
+
+// carryUpdateReasons copies into to the reasons recorded in from for the
+// projects that both lock to the same revision, so that the reason for an
+// update is kept for as long as the project stays at the version it was
+// updated to.
+func carryUpdateReasons(from, to *Lock) {
+	recorded := make(map[gps.ProjectRoot]verify.VerifiableProject)
+	for _, lp := range from.Projects() {
+		if vp, ok := lp.(verify.VerifiableProject); ok && vp.UpdateReason != "" {
+			recorded[lp.Ident().ProjectRoot] = vp
+		}
+	}
+
+	for k, lp := range to.Projects() {
+		vp, ok := lp.(verify.VerifiableProject)
+		if !ok || vp.UpdateReason != "" {
+			continue
+		}
+		if old, has := recorded[lp.Ident().ProjectRoot]; has && revisionOf(old.Version()) == revisionOf(vp.Version()) {
+			vp.UpdateReason = old.UpdateReason
+			to.P[k] = vp
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+)
+
+func TestMajorUpdates(t *testing.T) {
+	lp := func(name string, v gps.Version) gps.LockedProject {
+		return verify.VerifiableProject{
+			LockedProject: gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(name)}, v, []string{"."}),
+		}
+	}
+	semver := func(v, rev string) gps.Version {
+		return gps.NewVersion(v).Pair(gps.Revision(rev))
+	}
+
+	old := &Lock{P: []gps.LockedProject{
+		lp("github.com/foo/major", semver("v1.8.0", "a")),
+		lp("github.com/foo/minor", semver("v1.8.0", "b")),
+		lp("github.com/foo/zero", semver("v0.3.2", "c")),
+		lp("github.com/foo/down", semver("v2.1.0", "d")),
+		lp("github.com/foo/branch", gps.NewBranch("master").Pair("e")),
+	}}
+	updated := &Lock{P: []gps.LockedProject{
+		lp("github.com/foo/major", semver("v2.0.0", "f")),
+		lp("github.com/foo/minor", semver("v1.9.0", "g")),
+		lp("github.com/foo/zero", semver("v0.4.0", "h")),
+		lp("github.com/foo/down", semver("v1.5.0", "i")),
+		lp("github.com/foo/branch", semver("v3.0.0", "e")),
+		lp("github.com/foo/new", semver("v5.0.0", "j")),
+	}}
+
+	want := []gps.ProjectRoot{"github.com/foo/major", "github.com/foo/zero"}
+	if got := MajorUpdates(old, updated); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected major updates %v, got %v", want, got)
+	}
+
+	updated.RecordUpdateReason(old, "v2 fixes a vulnerability")
+	for _, p := range updated.P {
+		vp := p.(verify.VerifiableProject)
+		isMajor := vp.Ident().ProjectRoot == want[0] || vp.Ident().ProjectRoot == want[1]
+		if isMajor && vp.UpdateReason != "v2 fixes a vulnerability" {
+			t.Errorf("expected the reason to be recorded for %s", vp.Ident())
+		} else if !isMajor && vp.UpdateReason != "" {
+			t.Errorf("did not expect a reason to be recorded for %s, got %q", vp.Ident(), vp.UpdateReason)
+		}
+	}
+
+	// The reason is carried over as long as the project is not updated again.
+	next := &Lock{P: []gps.LockedProject{
+		lp("github.com/foo/major", semver("v2.0.0", "f")),
+		lp("github.com/foo/zero", semver("v0.4.1", "k")),
+	}}
+	carryUpdateReasons(updated, next)
+	if reason := next.P[0].(verify.VerifiableProject).UpdateReason; reason != "v2 fixes a vulnerability" {
+		t.Errorf("expected the reason to be carried over for an unchanged project, got %q", reason)
+	}
+	if reason := next.P[1].(verify.VerifiableProject).UpdateReason; reason != "" {
+		t.Errorf("did not expect the reason to be carried over for an updated project, got %q", reason)
+	}
+}
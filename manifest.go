@@ -8,10 +8,13 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"path"
 	"reflect"
 	"regexp"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/gps/pkgtree"
@@ -29,9 +32,28 @@ var (
 	errInvalidRequired     = errors.Errorf("%q must be a TOML list of strings", "required")
 	errInvalidIgnored      = errors.Errorf("%q must be a TOML list of strings", "ignored")
 	errInvalidNoVerify     = errors.Errorf("%q must be a TOML list of strings", "noverify")
+	errInvalidAllowed      = errors.Errorf("%q must be a TOML list of strings", "allowed")
+	errInvalidDenied       = errors.Errorf("%q must be a TOML list of strings", "denied")
+	errInvalidAnalyzers    = errors.Errorf("%q must be a TOML list of strings", "analyzers")
 	errInvalidPrune        = errors.Errorf("%q must be a TOML table of booleans", "prune")
 	errInvalidPruneProject = errors.Errorf("%q must be a TOML array of tables", "prune.project")
 	errInvalidMetadata     = errors.New("metadata should be a TOML table")
+	errInvalidCheck        = errors.Errorf("%q must be a TOML table of booleans", "check")
+	errInvalidQuarantine   = errors.Errorf("%q must be a boolean", "quarantine")
+	errInvalidJustify      = errors.Errorf("%q must be a boolean", "justify-major-updates")
+	errInvalidBudget       = errors.Errorf("%q must be a TOML table of limits", "budget")
+	errInvalidApproved     = errors.Errorf("%q must be a TOML array of tables", "approved")
+	errInvalidHealth       = errors.Errorf("%q must be a TOML table of limits", "health")
+	errInvalidGroup        = errors.Errorf("%q must be a TOML array of tables", "group")
+	errInvalidSmokeTest    = errors.Errorf("%q must be a string", "smoke-test")
+	errInvalidVendorLayout = errors.Errorf("%q must be %q or %q", "vendor-layout", VendorLayoutDep, VendorLayoutModules)
+	errInvalidKindPolicy   = errors.Errorf("%q must be a TOML table of lists of dependency kinds", "kind-policy")
+	errInvalidTestDeps     = errors.Errorf("%q must be a boolean", "exclude-test-deps")
+	errInvalidPlatform     = errors.Errorf("%q must be a TOML array of tables, each with a %q and a %q", "platform", "goos", "goarch")
+	errInvalidForbid       = errors.Errorf("%q must be a TOML array of tables, each with %q and a list of %q", "forbid", "packages", "imports")
+	errInvalidMigration    = errors.Errorf("%q must be a TOML array of tables, each with %q and %q", "migration", "from", "to")
+	errInvalidFreeze       = errors.Errorf("%q must be a TOML table with a %q and an array of %q tables, each with %q and %q dates given as YYYY-MM-DD", "freeze", "file", "window", "from", "until")
+	errInvalidAlias        = errors.Errorf("%q must be a TOML array of tables, each with %q and %q", "alias", "name", "target")
 
 	errInvalidProjectRoot = errors.New("ProjectRoot name validation failed")
 
@@ -41,9 +63,49 @@ var (
 	errRootPruneContainsName   = errors.Errorf("%q should not include a name", "prune")
 	errInvalidRootPruneValue   = errors.New("root prune options must be omitted instead of being set to false")
 	errInvalidPruneProjectName = errors.Errorf("%q in %q must be a string", "name", "prune.project")
+	errInvalidPruneKeep        = errors.Errorf("%q in %q must be a TOML list of path patterns", "keep", "prune")
+	errDuplicateGroup          = errors.Errorf("each %q must have a unique name", "group")
 	errNoName                  = errors.New("no name provided")
 )
 
+// manifestErrKeys maps manifest validation errors to the top-level key whose
+// value they describe.
+var manifestErrKeys = map[error]string{
+	errInvalidConstraint:       "constraint",
+	errInvalidOverride:         "override",
+	errInvalidRequired:         "required",
+	errInvalidIgnored:          "ignored",
+	errInvalidNoVerify:         "noverify",
+	errInvalidAllowed:          "allowed",
+	errInvalidDenied:           "denied",
+	errInvalidAnalyzers:        "analyzers",
+	errInvalidPrune:            "prune",
+	errInvalidPruneProject:     "prune",
+	errInvalidPruneValue:       "prune",
+	errPruneSubProject:         "prune",
+	errRootPruneContainsName:   "prune",
+	errInvalidRootPruneValue:   "prune",
+	errInvalidPruneProjectName: "prune",
+	errInvalidPruneKeep:        "prune",
+	errInvalidCheck:            "check",
+	errInvalidQuarantine:       "quarantine",
+	errInvalidJustify:          "justify-major-updates",
+	errInvalidBudget:           "budget",
+	errInvalidApproved:         "approved",
+	errInvalidHealth:           "health",
+	errInvalidGroup:            "group",
+	errDuplicateGroup:          "group",
+	errInvalidSmokeTest:        "smoke-test",
+	errInvalidVendorLayout:     "vendor-layout",
+	errInvalidKindPolicy:       "kind-policy",
+	errInvalidTestDeps:         "exclude-test-deps",
+	errInvalidPlatform:         "platform",
+	errInvalidForbid:           "forbid",
+	errInvalidMigration:        "migration",
+	errInvalidAlias:            "alias",
+	errInvalidFreeze:           "freeze",
+}
+
 // Manifest holds manifest file data and implements gps.RootManifest.
 type Manifest struct {
 	Constraints gps.ProjectConstraints
@@ -54,16 +116,255 @@ type Manifest struct {
 
 	NoVerify []string
 
+	// Allowed and Denied are the import path prefixes of the projects that
+	// may, and may not, be selected when solving.
+	Allowed []string
+	Denied  []string
+
+	// Analyzers are the names of the analyzers that derive the constraints
+	// of dependencies, in order of precedence: the first that finds its
+	// metadata in a dependency is used. dep's own is named AnalyzerNameDep.
+	// If empty, only dep's own is used.
+	Analyzers []string
+
 	PruneOptions gps.CascadingPruneOptions
+
+	// KeepVendor lists patterns, as for path.Match, of the slash-separated
+	// paths in vendor of files that are kept as they are whenever vendor is
+	// rewritten, such as patched generated files. A pattern that matches a
+	// directory keeps all the files under it.
+	KeepVendor []string
+
+	Check CheckOptions
+
+	// Quarantine requires each project that is added to the lock to first be
+	// listed in Approved.
+	Quarantine bool
+	Approved   []string
+
+	// JustifyMajor requires a reason to be given for each update of a
+	// project in the lock to a new major version, which is recorded in the
+	// lock.
+	JustifyMajor bool
+
+	Budget BudgetOptions
+
+	Health HealthOptions
+
+	// Freeze declares when changes to the lock are refused.
+	Freeze FreezeOptions
+
+	// Groups are named sets of projects that dep ensure -update -group
+	// updates together.
+	Groups []UpdateGroup
+
+	// SmokeTest is a shell command that dep ensure -update runs after
+	// writing the lock and vendor, rolling both back if it fails.
+	SmokeTest string
+
+	// VendorLayout is the layout in which vendor is written, one of
+	// VendorLayoutDep and VendorLayoutModules. It is empty if not set, which
+	// is the same as VendorLayoutDep.
+	VendorLayout string
+
+	// ExcludeTestDeps leaves the projects that are marked test-only in the
+	// lock out of vendor, while still locking them.
+	ExcludeTestDeps bool
+
+	// Platforms are the platforms for which packages are analyzed. If empty,
+	// the imports of every file count, whatever platforms it is built on.
+	Platforms []pkgtree.Platform
+
+	// Refresh maps the roots of projects constrained to a branch to the
+	// policy, one of RefreshWeekly and RefreshNever, by which their locked
+	// revisions may advance. Projects without an entry follow RefreshUpdate.
+	Refresh map[gps.ProjectRoot]string
+
+	// FallbackSources maps the roots of projects to the sources from which
+	// they are fetched, in order, when the source in their constraint or
+	// override cannot be reached.
+	FallbackSources map[gps.ProjectRoot][]string
+
+	KindPolicy KindPolicy
+
+	// Forbidden are the rules that forbid packages of the current project to
+	// import certain packages, which dep check enforces.
+	Forbidden []ImportRule
+
+	// Migrations are moves of import paths that dep offers to rewrite the
+	// imports of, in addition to KnownImportMigrations.
+	Migrations []ImportMigration
+
+	// Aliases maps the roots of import paths to the roots of the projects
+	// that serve them, which are vendored under both.
+	Aliases map[gps.ProjectRoot]gps.ProjectRoot
+}
+
+// UpdateGroup is a named set of projects that must be updated together, as
+// declared by a [[group]] table in the manifest.
+type UpdateGroup struct {
+	Name string
+	// Projects holds project roots, or patterns in which "..." matches any
+	// string.
+	Projects []string
+}
+
+// UpdateGroup returns the update group with the given name.
+func (m *Manifest) UpdateGroup(name string) (UpdateGroup, bool) {
+	for _, g := range m.Groups {
+		if g.Name == name {
+			return g, true
+		}
+	}
+	return UpdateGroup{}, false
+}
+
+// HealthOptions sets the limits past which locked projects are reported as
+// unhealthy by dep status -health, as set in the [health] table of the
+// manifest. A zero limit is not checked.
+type HealthOptions struct {
+	// MaxAgeYears is the largest number of years since the locked revision of
+	// a project was committed.
+	MaxAgeYears int
+	// MaxInactiveMonths is the largest number of months since the last commit
+	// to any branch or tag of a project.
+	MaxInactiveMonths int
+	// Enforce makes dep check fail on unhealthy projects.
+	Enforce bool
+}
+
+// BudgetOptions sets limits on the dependencies of the project, which dep
+// ensure checks, as set in the [budget] table of the manifest. A zero limit is
+// not checked.
+type BudgetOptions struct {
+	// MaxProjects is the largest number of projects the lock may hold.
+	MaxProjects int
+	// MaxDepth is the largest number of projects that may lie on the shortest
+	// import chain from the root project to any dependency; direct
+	// dependencies have a depth of one.
+	MaxDepth int
+	// MaxVendorSize is the largest size, in bytes, of the files in vendor.
+	MaxVendorSize uint64
+	// Enforce makes exceeding a limit an error, rather than a warning.
+	Enforce bool
+}
+
+// IsZero reports whether no limits are set.
+func (b BudgetOptions) IsZero() bool {
+	return b.MaxProjects == 0 && b.MaxDepth == 0 && b.MaxVendorSize == 0
+}
+
+// CheckOptions selects the checks that dep check skips, or runs in addition to
+// its defaults, as set in the [check] table of the manifest.
+type CheckOptions struct {
+	// SkipLock skips checking that the lock is in sync with the manifest and
+	// imports.
+	SkipLock bool
+	// SkipVendor skips checking that vendor is in sync with the lock.
+	SkipVendor bool
+	// SkipDigest skips comparing the digests of vendored projects with those in
+	// the lock, while still checking that the right projects are vendored.
+	SkipDigest bool
+	// Upstream enables checking that the source of every locked project can
+	// be reached.
+	Upstream bool
+	// Generated enables checking that vendored protobuf definitions and the
+	// Go code generated from them are both present.
+	Generated bool
+	// Thorough compares vendored projects with their digests in full, rather
+	// than by sampling their files.
+	Thorough bool
 }
 
 type rawManifest struct {
-	Constraints  []rawProject    `toml:"constraint,omitempty"`
-	Overrides    []rawProject    `toml:"override,omitempty"`
-	Ignored      []string        `toml:"ignored,omitempty"`
-	Required     []string        `toml:"required,omitempty"`
-	NoVerify     []string        `toml:"noverify,omitempty"`
-	PruneOptions rawPruneOptions `toml:"prune,omitempty"`
+	Constraints     []rawProject    `toml:"constraint,omitempty"`
+	Overrides       []rawProject    `toml:"override,omitempty"`
+	Ignored         []string        `toml:"ignored,omitempty"`
+	Required        []string        `toml:"required,omitempty"`
+	NoVerify        []string        `toml:"noverify,omitempty"`
+	Allowed         []string        `toml:"allowed,omitempty"`
+	Denied          []string        `toml:"denied,omitempty"`
+	Analyzers       []string        `toml:"analyzers,omitempty"`
+	PruneOptions    rawPruneOptions `toml:"prune,omitempty"`
+	Check           rawCheckOptions `toml:"check,omitempty"`
+	Quarantine      bool            `toml:"quarantine,omitempty"`
+	Approved        []rawApproval   `toml:"approved,omitempty"`
+	JustifyMajor    bool            `toml:"justify-major-updates,omitempty"`
+	Budget          rawBudget       `toml:"budget,omitempty"`
+	Health          rawHealth       `toml:"health,omitempty"`
+	Freeze          rawFreeze       `toml:"freeze,omitempty"`
+	Groups          []rawGroup      `toml:"group,omitempty"`
+	SmokeTest       string          `toml:"smoke-test,omitempty"`
+	VendorLayout    string          `toml:"vendor-layout,omitempty"`
+	ExcludeTestDeps bool            `toml:"exclude-test-deps,omitempty"`
+	Platforms       []rawPlatform   `toml:"platform,omitempty"`
+	KindPolicy      rawKindPolicy   `toml:"kind-policy,omitempty"`
+	Forbidden       []rawImportRule `toml:"forbid,omitempty"`
+	Migrations      []rawMigration  `toml:"migration,omitempty"`
+	Aliases         []rawAlias      `toml:"alias,omitempty"`
+}
+
+type rawKindPolicy struct {
+	Constraints []string `toml:"constraints,omitempty"`
+	Overrides   []string `toml:"overrides,omitempty"`
+	Enforce     bool     `toml:"enforce,omitempty"`
+}
+
+type rawPlatform struct {
+	GOOS   string   `toml:"goos"`
+	GOARCH string   `toml:"goarch"`
+	Tags   []string `toml:"tags,omitempty"`
+}
+
+type rawImportRule struct {
+	Packages   string   `toml:"packages"`
+	Imports    []string `toml:"imports"`
+	Transitive bool     `toml:"transitive,omitempty"`
+	Reason     string   `toml:"reason,omitempty"`
+}
+
+type rawMigration struct {
+	From   string `toml:"from"`
+	To     string `toml:"to"`
+	Reason string `toml:"reason,omitempty"`
+}
+
+type rawAlias struct {
+	Name   string `toml:"name"`
+	Target string `toml:"target"`
+}
+
+type rawGroup struct {
+	Name     string   `toml:"name"`
+	Projects []string `toml:"projects"`
+}
+
+type rawHealth struct {
+	MaxAgeYears       int  `toml:"max-age-years,omitempty"`
+	MaxInactiveMonths int  `toml:"max-inactive-months,omitempty"`
+	Enforce           bool `toml:"enforce,omitempty"`
+}
+
+type rawFreeze struct {
+	File    string            `toml:"file,omitempty"`
+	Windows []rawFreezeWindow `toml:"window,omitempty"`
+}
+
+type rawFreezeWindow struct {
+	From   string `toml:"from"`
+	Until  string `toml:"until"`
+	Reason string `toml:"reason,omitempty"`
+}
+
+type rawBudget struct {
+	MaxProjects   int    `toml:"max-projects,omitempty"`
+	MaxDepth      int    `toml:"max-depth,omitempty"`
+	MaxVendorSize string `toml:"max-vendor-size,omitempty"`
+	Enforce       bool   `toml:"enforce,omitempty"`
+}
+
+type rawApproval struct {
+	Name string `toml:"name"`
 }
 
 type rawProject struct {
@@ -72,6 +373,19 @@ type rawProject struct {
 	Revision string `toml:"revision,omitempty"`
 	Version  string `toml:"version,omitempty"`
 	Source   string `toml:"source,omitempty"`
+	RootDir  string `toml:"root-dir,omitempty"`
+	Refresh  string `toml:"refresh,omitempty"`
+
+	FallbackSources []string `toml:"fallback-sources,omitempty"`
+}
+
+type rawCheckOptions struct {
+	SkipLock   bool `toml:"skip-lock,omitempty"`
+	SkipVendor bool `toml:"skip-vendor,omitempty"`
+	SkipDigest bool `toml:"skip-digest,omitempty"`
+	Upstream   bool `toml:"upstream,omitempty"`
+	Generated  bool `toml:"generated,omitempty"`
+	Thorough   bool `toml:"thorough,omitempty"`
 }
 
 type rawPruneOptions struct {
@@ -79,6 +393,8 @@ type rawPruneOptions struct {
 	NonGoFiles     bool `toml:"non-go,omitempty"`
 	GoTests        bool `toml:"go-tests,omitempty"`
 
+	Keep []string `toml:"keep,omitempty"`
+
 	//Projects []map[string]interface{} `toml:"project,omitempty"`
 	Projects []map[string]interface{}
 }
@@ -133,8 +449,9 @@ func validateManifest(s string) ([]error, error) {
 			// Invalid if type assertion fails. Not a TOML array of tables.
 			if rawProj, ok := val.([]interface{}); ok {
 				// Check element type. Must be a map. Checking one element would be
-				// enough because TOML doesn't allow mixing of types.
-				if reflect.TypeOf(rawProj[0]).Kind() != reflect.Map {
+				// enough because TOML doesn't allow mixing of types. Empty array is
+				// valid.
+				if len(rawProj) > 0 && reflect.TypeOf(rawProj[0]).Kind() != reflect.Map {
 					valid = false
 				}
 
@@ -148,8 +465,23 @@ func validateManifest(s string) ([]error, error) {
 							// Check if the key is valid
 							switch key {
 							case "name":
-							case "branch", "version", "source":
+							case "branch", "source":
+								ruleProvided = true
+							case "fallback-sources":
 								ruleProvided = true
+								if list, ok := value.([]interface{}); !ok || (len(list) > 0 && reflect.TypeOf(list[0]).Kind() != reflect.String) {
+									warns = append(warns, fmt.Errorf("fallback-sources in %q should be a list of strings", prop))
+								}
+							case "root-dir":
+								ruleProvided = true
+								if _, ok := value.(string); !ok {
+									warns = append(warns, fmt.Errorf("root-dir in %q should be a string", prop))
+								}
+							case "version":
+								ruleProvided = true
+								if valueStr, ok := value.(string); ok && isMalformedSemverRange(valueStr) {
+									warns = append(warns, fmt.Errorf("version %q in %q is not a valid semver range and will be treated as a literal tag", valueStr, prop))
+								}
 							case "revision":
 								ruleProvided = true
 								if valueStr, ok := value.(string); ok {
@@ -162,6 +494,12 @@ func validateManifest(s string) ([]error, error) {
 								if reflect.TypeOf(value).Kind() != reflect.Map {
 									warns = append(warns, fmt.Errorf("metadata in %q should be a TOML table", prop))
 								}
+							case "refresh":
+								if prop != "constraint" {
+									warns = append(warns, fmt.Errorf("invalid key %q in %q", key, prop))
+								} else if _, ok := props["branch"]; !ok {
+									warns = append(warns, fmt.Errorf("refresh for %q only applies to constraints on a branch", props["name"]))
+								}
 							default:
 								// unknown/invalid key
 								warns = append(warns, fmt.Errorf("invalid key %q in %q", key, prop))
@@ -186,7 +524,7 @@ func validateManifest(s string) ([]error, error) {
 					return warns, errInvalidOverride
 				}
 			}
-		case "ignored", "required", "noverify":
+		case "ignored", "required", "noverify", "allowed", "denied", "analyzers":
 			valid := true
 			if rawList, ok := val.([]interface{}); ok {
 				// Check element type of the array. TOML doesn't let mixing of types in
@@ -208,6 +546,15 @@ func validateManifest(s string) ([]error, error) {
 				if prop == "noverify" {
 					return warns, errInvalidNoVerify
 				}
+				if prop == "allowed" {
+					return warns, errInvalidAllowed
+				}
+				if prop == "denied" {
+					return warns, errInvalidDenied
+				}
+				if prop == "analyzers" {
+					return warns, errInvalidAnalyzers
+				}
 			}
 		case "prune":
 			pruneWarns, err := validatePruneOptions(val, true)
@@ -215,6 +562,92 @@ func validateManifest(s string) ([]error, error) {
 			if err != nil {
 				return warns, err
 			}
+		case "check":
+			checkWarns, err := validateCheckOptions(val)
+			warns = append(warns, checkWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "quarantine":
+			if _, ok := val.(bool); !ok {
+				return warns, errInvalidQuarantine
+			}
+		case "justify-major-updates":
+			if _, ok := val.(bool); !ok {
+				return warns, errInvalidJustify
+			}
+		case "kind-policy":
+			policyWarns, err := validateKindPolicy(val)
+			warns = append(warns, policyWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "budget":
+			budgetWarns, err := validateBudget(val)
+			warns = append(warns, budgetWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "freeze":
+			freezeWarns, err := validateFreeze(val)
+			warns = append(warns, freezeWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "health":
+			healthWarns, err := validateHealth(val)
+			warns = append(warns, healthWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "smoke-test":
+			if _, ok := val.(string); !ok {
+				return warns, errInvalidSmokeTest
+			}
+		case "vendor-layout":
+			if layout, ok := val.(string); !ok || (layout != VendorLayoutDep && layout != VendorLayoutModules) {
+				return warns, errInvalidVendorLayout
+			}
+		case "exclude-test-deps":
+			if _, ok := val.(bool); !ok {
+				return warns, errInvalidTestDeps
+			}
+		case "platform":
+			platformWarns, err := validatePlatforms(val)
+			warns = append(warns, platformWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "forbid":
+			forbidWarns, err := validateForbid(val)
+			warns = append(warns, forbidWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "migration":
+			migrationWarns, err := validateMigrations(val)
+			warns = append(warns, migrationWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "alias":
+			aliasWarns, err := validateAliases(val)
+			warns = append(warns, aliasWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "group":
+			groupWarns, err := validateGroups(val)
+			warns = append(warns, groupWarns...)
+			if err != nil {
+				return warns, err
+			}
+		case "approved":
+			approvedWarns, err := validateApproved(val)
+			warns = append(warns, approvedWarns...)
+			if err != nil {
+				return warns, err
+			}
 		default:
 			warns = append(warns, fmt.Errorf("unknown field in manifest: %v", prop))
 		}
@@ -223,6 +656,408 @@ func validateManifest(s string) ([]error, error) {
 	return warns, nil
 }
 
+// isMalformedSemverRange reports whether v uses semver range syntax, but does
+// not parse as a semver constraint. Such versions are otherwise silently
+// treated as plain tags, which is rarely what was intended.
+func isMalformedSemverRange(v string) bool {
+	if !strings.ContainsAny(v, "<>=!^~*,|") {
+		return false
+	}
+	_, err := gps.NewSemverConstraintIC(v)
+	return err != nil
+}
+
+func validateCheckOptions(val interface{}) (warns []error, err error) {
+	checkmap, ok := val.(map[string]interface{})
+	if !ok {
+		return warns, errInvalidCheck
+	}
+
+	for key, value := range checkmap {
+		switch key {
+		case "skip-lock", "skip-vendor", "skip-digest", "upstream", "generated", "thorough":
+			if _, ok := value.(bool); !ok {
+				return warns, errInvalidCheck
+			}
+		default:
+			warns = append(warns, errors.Errorf("unknown field %q in %q", key, "check"))
+		}
+	}
+
+	return warns, nil
+}
+
+func validateBudget(val interface{}) (warns []error, err error) {
+	budgetmap, ok := val.(map[string]interface{})
+	if !ok {
+		return warns, errInvalidBudget
+	}
+
+	for key, value := range budgetmap {
+		switch key {
+		case "max-projects", "max-depth":
+			if n, ok := value.(int64); !ok || n < 0 {
+				return warns, errInvalidBudget
+			}
+		case "max-vendor-size":
+			size, ok := value.(string)
+			if !ok {
+				return warns, errInvalidBudget
+			}
+			if _, err := ParseByteSize(size); err != nil {
+				return warns, errInvalidBudget
+			}
+		case "enforce":
+			if _, ok := value.(bool); !ok {
+				return warns, errInvalidBudget
+			}
+		default:
+			warns = append(warns, errors.Errorf("unknown field %q in %q", key, "budget"))
+		}
+	}
+
+	return warns, nil
+}
+
+func validateHealth(val interface{}) (warns []error, err error) {
+	healthmap, ok := val.(map[string]interface{})
+	if !ok {
+		return warns, errInvalidHealth
+	}
+
+	for key, value := range healthmap {
+		switch key {
+		case "max-age-years", "max-inactive-months":
+			if n, ok := value.(int64); !ok || n < 0 {
+				return warns, errInvalidHealth
+			}
+		case "enforce":
+			if _, ok := value.(bool); !ok {
+				return warns, errInvalidHealth
+			}
+		default:
+			warns = append(warns, errors.Errorf("unknown field %q in %q", key, "health"))
+		}
+	}
+
+	return warns, nil
+}
+
+func validateFreeze(val interface{}) (warns []error, err error) {
+	freezemap, ok := val.(map[string]interface{})
+	if !ok {
+		return warns, errInvalidFreeze
+	}
+
+	for key, value := range freezemap {
+		switch key {
+		case "file":
+			if v, ok := value.(string); !ok || v == "" {
+				return warns, errInvalidFreeze
+			}
+		case "window":
+			windows, ok := value.([]interface{})
+			if !ok {
+				return warns, errInvalidFreeze
+			}
+			for _, window := range windows {
+				wmap, ok := window.(map[string]interface{})
+				if !ok {
+					return warns, errInvalidFreeze
+				}
+				for wkey, wvalue := range wmap {
+					switch wkey {
+					case "from", "until":
+						v, ok := wvalue.(string)
+						if !ok {
+							return warns, errInvalidFreeze
+						}
+						if _, err := time.Parse(freezeDate, v); err != nil {
+							return warns, errInvalidFreeze
+						}
+					case "reason":
+						if _, ok := wvalue.(string); !ok {
+							return warns, errInvalidFreeze
+						}
+					default:
+						warns = append(warns, errors.Errorf("invalid key %q in %q", wkey, "freeze.window"))
+					}
+				}
+				from, hasFrom := wmap["from"]
+				until, hasUntil := wmap["until"]
+				if !hasFrom || !hasUntil || from.(string) > until.(string) {
+					return warns, errInvalidFreeze
+				}
+			}
+		default:
+			warns = append(warns, errors.Errorf("unknown field %q in %q", key, "freeze"))
+		}
+	}
+
+	return warns, nil
+}
+
+func validateKindPolicy(val interface{}) (warns []error, err error) {
+	policymap, ok := val.(map[string]interface{})
+	if !ok {
+		return warns, errInvalidKindPolicy
+	}
+
+	for key, value := range policymap {
+		switch key {
+		case "constraints", "overrides":
+			kinds, ok := value.([]interface{})
+			if !ok {
+				return warns, errInvalidKindPolicy
+			}
+			for _, kind := range kinds {
+				if k, ok := kind.(string); !ok || !isDependencyKind(k) {
+					return warns, errInvalidKindPolicy
+				}
+			}
+		case "enforce":
+			if _, ok := value.(bool); !ok {
+				return warns, errInvalidKindPolicy
+			}
+		default:
+			warns = append(warns, errors.Errorf("unknown field %q in %q", key, "kind-policy"))
+		}
+	}
+
+	return warns, nil
+}
+
+func validateApproved(val interface{}) (warns []error, err error) {
+	approvals, ok := val.([]interface{})
+	if !ok {
+		return warns, errInvalidApproved
+	}
+
+	for _, approval := range approvals {
+		approvalmap, ok := approval.(map[string]interface{})
+		if !ok {
+			return warns, errInvalidApproved
+		}
+		for key, value := range approvalmap {
+			switch key {
+			case "name":
+				if _, ok := value.(string); !ok {
+					return warns, errInvalidApproved
+				}
+			default:
+				warns = append(warns, errors.Errorf("invalid key %q in %q", key, "approved"))
+			}
+		}
+	}
+
+	return warns, nil
+}
+
+func validateGroups(val interface{}) (warns []error, err error) {
+	groups, ok := val.([]interface{})
+	if !ok {
+		return warns, errInvalidGroup
+	}
+
+	names := make(map[string]bool)
+	for _, group := range groups {
+		groupmap, ok := group.(map[string]interface{})
+		if !ok {
+			return warns, errInvalidGroup
+		}
+		for key, value := range groupmap {
+			switch key {
+			case "name":
+				name, ok := value.(string)
+				if !ok || name == "" {
+					return warns, errInvalidGroup
+				}
+				if names[name] {
+					return warns, errDuplicateGroup
+				}
+				names[name] = true
+			case "projects":
+				projects, ok := value.([]interface{})
+				if !ok {
+					return warns, errInvalidGroup
+				}
+				for _, project := range projects {
+					if _, ok := project.(string); !ok {
+						return warns, errInvalidGroup
+					}
+				}
+			default:
+				warns = append(warns, errors.Errorf("invalid key %q in %q", key, "group"))
+			}
+		}
+		if _, has := groupmap["name"]; !has {
+			return warns, errInvalidGroup
+		}
+	}
+
+	return warns, nil
+}
+
+func validatePlatforms(val interface{}) (warns []error, err error) {
+	platforms, ok := val.([]interface{})
+	if !ok {
+		return warns, errInvalidPlatform
+	}
+
+	for _, platform := range platforms {
+		platformmap, ok := platform.(map[string]interface{})
+		if !ok {
+			return warns, errInvalidPlatform
+		}
+		for key, value := range platformmap {
+			switch key {
+			case "goos", "goarch":
+				if v, ok := value.(string); !ok || v == "" {
+					return warns, errInvalidPlatform
+				}
+			case "tags":
+				tags, ok := value.([]interface{})
+				if !ok {
+					return warns, errInvalidPlatform
+				}
+				for _, tag := range tags {
+					if _, ok := tag.(string); !ok {
+						return warns, errInvalidPlatform
+					}
+				}
+			default:
+				warns = append(warns, errors.Errorf("invalid key %q in %q", key, "platform"))
+			}
+		}
+		if _, has := platformmap["goos"]; !has {
+			return warns, errInvalidPlatform
+		}
+		if _, has := platformmap["goarch"]; !has {
+			return warns, errInvalidPlatform
+		}
+	}
+
+	return warns, nil
+}
+
+func validateForbid(val interface{}) (warns []error, err error) {
+	rules, ok := val.([]interface{})
+	if !ok {
+		return warns, errInvalidForbid
+	}
+
+	for _, rule := range rules {
+		rulemap, ok := rule.(map[string]interface{})
+		if !ok {
+			return warns, errInvalidForbid
+		}
+		for key, value := range rulemap {
+			switch key {
+			case "packages":
+				if v, ok := value.(string); !ok || v == "" {
+					return warns, errInvalidForbid
+				}
+			case "imports":
+				imports, ok := value.([]interface{})
+				if !ok || len(imports) == 0 {
+					return warns, errInvalidForbid
+				}
+				for _, imp := range imports {
+					if v, ok := imp.(string); !ok || v == "" {
+						return warns, errInvalidForbid
+					}
+				}
+			case "transitive":
+				if _, ok := value.(bool); !ok {
+					return warns, errInvalidForbid
+				}
+			case "reason":
+				if _, ok := value.(string); !ok {
+					return warns, errInvalidForbid
+				}
+			default:
+				warns = append(warns, errors.Errorf("invalid key %q in %q", key, "forbid"))
+			}
+		}
+		if _, has := rulemap["packages"]; !has {
+			return warns, errInvalidForbid
+		}
+		if _, has := rulemap["imports"]; !has {
+			return warns, errInvalidForbid
+		}
+	}
+
+	return warns, nil
+}
+
+func validateMigrations(val interface{}) (warns []error, err error) {
+	migrations, ok := val.([]interface{})
+	if !ok {
+		return warns, errInvalidMigration
+	}
+
+	for _, migration := range migrations {
+		mmap, ok := migration.(map[string]interface{})
+		if !ok {
+			return warns, errInvalidMigration
+		}
+		for key, value := range mmap {
+			switch key {
+			case "from", "to":
+				if v, ok := value.(string); !ok || v == "" {
+					return warns, errInvalidMigration
+				}
+			case "reason":
+				if _, ok := value.(string); !ok {
+					return warns, errInvalidMigration
+				}
+			default:
+				warns = append(warns, errors.Errorf("invalid key %q in %q", key, "migration"))
+			}
+		}
+		if _, has := mmap["from"]; !has {
+			return warns, errInvalidMigration
+		}
+		if _, has := mmap["to"]; !has {
+			return warns, errInvalidMigration
+		}
+	}
+
+	return warns, nil
+}
+
+func validateAliases(val interface{}) (warns []error, err error) {
+	aliases, ok := val.([]interface{})
+	if !ok {
+		return warns, errInvalidAlias
+	}
+
+	for _, alias := range aliases {
+		amap, ok := alias.(map[string]interface{})
+		if !ok {
+			return warns, errInvalidAlias
+		}
+		for key, value := range amap {
+			switch key {
+			case "name", "target":
+				if v, ok := value.(string); !ok || v == "" {
+					return warns, errInvalidAlias
+				}
+			default:
+				warns = append(warns, errors.Errorf("invalid key %q in %q", key, "alias"))
+			}
+		}
+		if _, has := amap["name"]; !has {
+			return warns, errInvalidAlias
+		}
+		if _, has := amap["target"]; !has {
+			return warns, errInvalidAlias
+		}
+	}
+
+	return warns, nil
+}
+
 func validatePruneOptions(val interface{}, root bool) (warns []error, err error) {
 	if reflect.TypeOf(val).Kind() != reflect.Map {
 		return warns, errInvalidPrune
@@ -236,6 +1071,24 @@ func validatePruneOptions(val interface{}, root bool) (warns []error, err error)
 			} else if root && !option {
 				return warns, errInvalidRootPruneValue
 			}
+		case "keep":
+			if !root {
+				warns = append(warns, errors.Errorf("unknown field %q in %q", key, "prune.project"))
+				continue
+			}
+			patterns, ok := value.([]interface{})
+			if !ok {
+				return warns, errInvalidPruneKeep
+			}
+			for _, pattern := range patterns {
+				p, ok := pattern.(string)
+				if !ok || p == "" {
+					return warns, errInvalidPruneKeep
+				}
+				if _, err := path.Match(p, ""); err != nil {
+					return warns, errInvalidPruneKeep
+				}
+			}
 		case "name":
 			if root {
 				warns = append(warns, errRootPruneContainsName)
@@ -340,6 +1193,11 @@ func ValidateProjectRoots(c *Ctx, m *Manifest, sm gps.SourceManager) error {
 	return valErr
 }
 
+// ReadManifest returns a Manifest read from r and a slice of validation warnings.
+func ReadManifest(r io.Reader) (*Manifest, []error, error) {
+	return readManifest(r)
+}
+
 // readManifest returns a Manifest read from r and a slice of validation warnings.
 func readManifest(r io.Reader) (*Manifest, []error, error) {
 	buf := &bytes.Buffer{}
@@ -350,6 +1208,9 @@ func readManifest(r io.Reader) (*Manifest, []error, error) {
 
 	warns, err := validateManifest(buf.String())
 	if err != nil {
+		if key, has := manifestErrKeys[err]; has {
+			err = locateTOMLError(newTOMLPathError(err, key), buf.Bytes())
+		}
 		return nil, warns, errors.Wrap(err, "manifest validation failed")
 	}
 
@@ -361,10 +1222,11 @@ func readManifest(r io.Reader) (*Manifest, []error, error) {
 
 	m, err := fromRawManifest(raw, buf)
 	if err != nil {
-		return nil, warns, err
+		return nil, warns, locateTOMLError(err, buf.Bytes())
 	}
 
 	warns = append(warns, checkRedundantPruneOptions(m.PruneOptions)...)
+	warns = append(warns, checkAnalyzers(m.Analyzers)...)
 	return m, warns, nil
 }
 
@@ -376,27 +1238,96 @@ func fromRawManifest(raw rawManifest, buf *bytes.Buffer) (*Manifest, error) {
 	m.Ignored = raw.Ignored
 	m.Required = raw.Required
 	m.NoVerify = raw.NoVerify
+	m.Allowed = raw.Allowed
+	m.Denied = raw.Denied
+	m.Analyzers = raw.Analyzers
+	m.Check = CheckOptions(raw.Check)
+	m.Quarantine = raw.Quarantine
+	m.JustifyMajor = raw.JustifyMajor
+	m.Budget = BudgetOptions{
+		MaxProjects: raw.Budget.MaxProjects,
+		MaxDepth:    raw.Budget.MaxDepth,
+		Enforce:     raw.Budget.Enforce,
+	}
+	if raw.Budget.MaxVendorSize != "" {
+		size, err := ParseByteSize(raw.Budget.MaxVendorSize)
+		if err != nil {
+			return nil, newTOMLPathError(err, "budget", "max-vendor-size")
+		}
+		m.Budget.MaxVendorSize = size
+	}
+	m.Health = HealthOptions(raw.Health)
+	m.Freeze.File = raw.Freeze.File
+	for _, w := range raw.Freeze.Windows {
+		m.Freeze.Windows = append(m.Freeze.Windows, FreezeWindow(w))
+	}
+	m.SmokeTest = raw.SmokeTest
+	m.VendorLayout = raw.VendorLayout
+	m.ExcludeTestDeps = raw.ExcludeTestDeps
+	m.KindPolicy = KindPolicy(raw.KindPolicy)
+	for _, p := range raw.Platforms {
+		m.Platforms = append(m.Platforms, pkgtree.Platform(p))
+	}
+	for _, r := range raw.Forbidden {
+		m.Forbidden = append(m.Forbidden, ImportRule(r))
+	}
+	for _, mg := range raw.Migrations {
+		m.Migrations = append(m.Migrations, ImportMigration(mg))
+	}
+	for _, g := range raw.Groups {
+		m.Groups = append(m.Groups, UpdateGroup(g))
+	}
+	for _, approval := range raw.Approved {
+		m.Approved = append(m.Approved, approval.Name)
+	}
+
+	for i, a := range raw.Aliases {
+		name := gps.ProjectRoot(a.Name)
+		if _, exists := m.Aliases[name]; exists {
+			return nil, newTOMLPathError(errors.Errorf("multiple aliases specified for %s, can only specify one", name), "alias", i)
+		}
+		if m.Aliases == nil {
+			m.Aliases = make(map[gps.ProjectRoot]gps.ProjectRoot)
+		}
+		m.Aliases[name] = gps.ProjectRoot(a.Target)
+	}
 
 	for i := 0; i < len(raw.Constraints); i++ {
 		name, prj, err := toProject(raw.Constraints[i])
 		if err != nil {
-			return nil, err
+			return nil, newTOMLPathError(err, "constraint", i)
 		}
 		if _, exists := m.Constraints[name]; exists {
-			return nil, errors.Errorf("multiple dependencies specified for %s, can only specify one", name)
+			return nil, newTOMLPathError(errors.Errorf("multiple dependencies specified for %s, can only specify one", name), "constraint", i)
 		}
 		m.Constraints[name] = prj
+		if err := m.addFallbackSources(name, raw.Constraints[i].FallbackSources); err != nil {
+			return nil, newTOMLPathError(err, "constraint", i, "fallback-sources")
+		}
+
+		if policy := raw.Constraints[i].Refresh; policy != "" && policy != RefreshUpdate {
+			if !isRefreshPolicy(policy) {
+				return nil, newTOMLPathError(errors.Errorf("refresh for %s must be %q, %q or %q", name, RefreshUpdate, RefreshWeekly, RefreshNever), "constraint", i)
+			}
+			if m.Refresh == nil {
+				m.Refresh = make(map[gps.ProjectRoot]string)
+			}
+			m.Refresh[name] = policy
+		}
 	}
 
 	for i := 0; i < len(raw.Overrides); i++ {
 		name, prj, err := toProject(raw.Overrides[i])
 		if err != nil {
-			return nil, err
+			return nil, newTOMLPathError(err, "override", i)
 		}
 		if _, exists := m.Ovr[name]; exists {
-			return nil, errors.Errorf("multiple overrides specified for %s, can only specify one", name)
+			return nil, newTOMLPathError(errors.Errorf("multiple overrides specified for %s, can only specify one", name), "override", i)
 		}
 		m.Ovr[name] = prj
+		if err := m.addFallbackSources(name, raw.Overrides[i].FallbackSources); err != nil {
+			return nil, newTOMLPathError(err, "override", i, "fallback-sources")
+		}
 	}
 
 	// TODO(sdboyer) it is awful that we have to do this manual extraction
@@ -411,7 +1342,13 @@ func fromRawManifest(raw rawManifest, buf *bytes.Buffer) (*Manifest, error) {
 	}
 	// Previous validation already guaranteed that, if it exists, it's this map
 	// type.
-	m.PruneOptions = fromRawPruneOptions(iprunemap.(*toml.Tree).ToMap())
+	prunemap := iprunemap.(*toml.Tree).ToMap()
+	m.PruneOptions = fromRawPruneOptions(prunemap)
+	if keep, has := prunemap["keep"]; has {
+		for _, pattern := range keep.([]interface{}) {
+			m.KeepVendor = append(m.KeepVendor, pattern.(string))
+		}
+	}
 
 	return m, nil
 }
@@ -521,9 +1458,54 @@ func toProject(raw rawProject) (n gps.ProjectRoot, pp gps.ProjectProperties, err
 
 	pp.Source = raw.Source
 
+	rootDir, err := gps.CleanRootDir(raw.RootDir)
+	if err != nil {
+		return n, pp, errors.Wrapf(err, "root-dir of %s", n)
+	}
+	pp.RootDir = rootDir
+
 	return n, pp, nil
 }
 
+// addFallbackSources records the fallback sources of the project named by pr,
+// which may be given in its constraint or its override, but not both.
+func (m *Manifest) addFallbackSources(pr gps.ProjectRoot, srcs []string) error {
+	if len(srcs) == 0 {
+		return nil
+	}
+	if _, has := m.FallbackSources[pr]; has {
+		return errors.Errorf("fallback-sources for %s can only be given once, in its constraint or its override", pr)
+	}
+	for _, src := range srcs {
+		if src == "" {
+			return errors.Errorf("fallback-sources for %s cannot be empty", pr)
+		}
+	}
+	if m.FallbackSources == nil {
+		m.FallbackSources = make(map[gps.ProjectRoot][]string)
+	}
+	m.FallbackSources[pr] = srcs
+	return nil
+}
+
+// fallbackSourcesByIdentifier returns the fallback sources of m keyed by the
+// identifiers whose sources they fall back from: those with the source of the
+// override of each project, if it has one, or else of its constraint.
+func (m *Manifest) fallbackSourcesByIdentifier() map[gps.ProjectIdentifier][]string {
+	if len(m.FallbackSources) == 0 {
+		return nil
+	}
+	fallbacks := make(map[gps.ProjectIdentifier][]string, len(m.FallbackSources))
+	for pr, srcs := range m.FallbackSources {
+		pp, has := m.Ovr[pr]
+		if !has || pp.Source == "" {
+			pp = m.Constraints[pr]
+		}
+		fallbacks[gps.ProjectIdentifier{ProjectRoot: pr, Source: pp.Source}] = srcs
+	}
+	return fallbacks
+}
+
 // MarshalTOML serializes this manifest into TOML via an intermediate raw form.
 func (m *Manifest) MarshalTOML() ([]byte, error) {
 	raw := m.toRaw()
@@ -541,19 +1523,69 @@ func (m *Manifest) toRaw() rawManifest {
 		Ignored:     m.Ignored,
 		Required:    m.Required,
 		NoVerify:    m.NoVerify,
+		Allowed:     m.Allowed,
+		Denied:      m.Denied,
+		Analyzers:   m.Analyzers,
 	}
 
 	for n, prj := range m.Constraints {
-		raw.Constraints = append(raw.Constraints, toRawProject(n, prj))
+		rp := toRawProject(n, prj)
+		rp.Refresh = m.Refresh[n]
+		if _, has := m.Ovr[n]; !has {
+			rp.FallbackSources = m.FallbackSources[n]
+		}
+		raw.Constraints = append(raw.Constraints, rp)
 	}
 	sort.Sort(sortedRawProjects(raw.Constraints))
 
 	for n, prj := range m.Ovr {
-		raw.Overrides = append(raw.Overrides, toRawProject(n, prj))
+		rp := toRawProject(n, prj)
+		rp.FallbackSources = m.FallbackSources[n]
+		raw.Overrides = append(raw.Overrides, rp)
 	}
 	sort.Sort(sortedRawProjects(raw.Overrides))
 
 	raw.PruneOptions = toRawPruneOptions(m.PruneOptions)
+	raw.PruneOptions.Keep = m.KeepVendor
+	raw.Check = rawCheckOptions(m.Check)
+	raw.Quarantine = m.Quarantine
+	raw.JustifyMajor = m.JustifyMajor
+	raw.Budget = rawBudget{
+		MaxProjects: m.Budget.MaxProjects,
+		MaxDepth:    m.Budget.MaxDepth,
+		Enforce:     m.Budget.Enforce,
+	}
+	if m.Budget.MaxVendorSize != 0 {
+		raw.Budget.MaxVendorSize = FormatByteSize(m.Budget.MaxVendorSize)
+	}
+	raw.Health = rawHealth(m.Health)
+	raw.Freeze.File = m.Freeze.File
+	for _, w := range m.Freeze.Windows {
+		raw.Freeze.Windows = append(raw.Freeze.Windows, rawFreezeWindow(w))
+	}
+	raw.SmokeTest = m.SmokeTest
+	raw.VendorLayout = m.VendorLayout
+	raw.ExcludeTestDeps = m.ExcludeTestDeps
+	raw.KindPolicy = rawKindPolicy(m.KindPolicy)
+	for _, p := range m.Platforms {
+		raw.Platforms = append(raw.Platforms, rawPlatform(p))
+	}
+	for _, r := range m.Forbidden {
+		raw.Forbidden = append(raw.Forbidden, rawImportRule(r))
+	}
+	for _, mg := range m.Migrations {
+		raw.Migrations = append(raw.Migrations, rawMigration(mg))
+	}
+	for name, target := range m.Aliases {
+		raw.Aliases = append(raw.Aliases, rawAlias{Name: string(name), Target: string(target)})
+	}
+	sort.Slice(raw.Aliases, func(i, j int) bool { return raw.Aliases[i].Name < raw.Aliases[j].Name })
+	for _, g := range m.Groups {
+		raw.Groups = append(raw.Groups, rawGroup(g))
+	}
+	for _, name := range m.Approved {
+		raw.Approved = append(raw.Approved, rawApproval{Name: name})
+	}
 
 	return raw
 }
@@ -572,13 +1604,17 @@ func (s sortedRawProjects) Less(i, j int) bool {
 		return false
 	}
 
-	return l.Source < r.Source
+	if l.Source != r.Source {
+		return l.Source < r.Source
+	}
+	return l.RootDir < r.RootDir
 }
 
 func toRawProject(name gps.ProjectRoot, project gps.ProjectProperties) rawProject {
 	raw := rawProject{
-		Name:   string(name),
-		Source: project.Source,
+		Name:    string(name),
+		Source:  project.Source,
+		RootDir: project.RootDir,
 	}
 
 	if v, ok := project.Constraint.(gps.Version); ok {
@@ -621,7 +1657,17 @@ func (m *Manifest) IgnoredPackages() *pkgtree.IgnoredRuleset {
 	if m == nil {
 		return pkgtree.NewIgnoredRuleset(nil)
 	}
-	return pkgtree.NewIgnoredRuleset(m.Ignored)
+	if len(m.Aliases) == 0 {
+		return pkgtree.NewIgnoredRuleset(m.Ignored)
+	}
+
+	// Imports beneath aliases are served by their targets, and so are never
+	// solved for on their own.
+	ig := append([]string(nil), m.Ignored...)
+	for alias := range m.Aliases {
+		ig = append(ig, string(alias), string(alias)+"/*")
+	}
+	return pkgtree.NewIgnoredRuleset(ig)
 }
 
 // HasConstraintsOn checks if the manifest contains either constraints or
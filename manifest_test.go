@@ -50,6 +50,7 @@ func TestReadManifest(t *testing.T) {
 			DefaultOptions:    gps.PruneNestedVendorDirs | gps.PruneNonGoFiles,
 			PerProjectOptions: make(map[gps.ProjectRoot]gps.PruneOptionSet),
 		},
+		Check: CheckOptions{SkipDigest: true},
 	}
 
 	if !reflect.DeepEqual(got.Constraints, want.Constraints) {
@@ -65,6 +66,9 @@ func TestReadManifest(t *testing.T) {
 		t.Error("Valid manifest's prune options did not parse as expected")
 		t.Error(got.PruneOptions, want.PruneOptions)
 	}
+	if !reflect.DeepEqual(got.Check, want.Check) {
+		t.Errorf("Valid manifest's check options did not parse as expected: %+v", got.Check)
+	}
 }
 
 func TestWriteManifest(t *testing.T) {
@@ -90,6 +94,7 @@ func TestWriteManifest(t *testing.T) {
 		DefaultOptions:    gps.PruneNestedVendorDirs | gps.PruneNonGoFiles,
 		PerProjectOptions: make(map[gps.ProjectRoot]gps.PruneOptionSet),
 	}
+	m.Check = CheckOptions{SkipDigest: true}
 
 	got, err := m.MarshalTOML()
 	if err != nil {
@@ -107,6 +112,27 @@ func TestWriteManifest(t *testing.T) {
 	}
 }
 
+func TestReadManifestCleansRootDir(t *testing.T) {
+	m, _, err := readManifest(strings.NewReader(`
+[[constraint]]
+  name = "example.com/client"
+  root-dir = "go/"
+
+[[override]]
+  name = "example.com/other"
+  root-dir = "./clients/go"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Constraints["example.com/client"].RootDir; got != "go" {
+		t.Errorf("expected root-dir \"go/\" to be read as \"go\", got %q", got)
+	}
+	if got := m.Ovr["example.com/other"].RootDir; got != "clients/go" {
+		t.Errorf("expected root-dir \"./clients/go\" to be read as \"clients/go\", got %q", got)
+	}
+}
+
 func TestReadManifestErrors(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
@@ -119,6 +145,8 @@ func TestReadManifestErrors(t *testing.T) {
 		{"multiple constraints", "manifest/error1.toml"},
 		{"multiple dependencies", "manifest/error2.toml"},
 		{"multiple overrides", "manifest/error3.toml"},
+		{"root-dir", "manifest/error4.toml"},
+		{"fallback-sources", "manifest/error5.toml"},
 	}
 
 	for _, tst := range tests {
@@ -252,6 +280,22 @@ func TestValidateManifest(t *testing.T) {
 			},
 			wantError: nil,
 		},
+		{
+			name: "malformed semver range",
+			tomlString: `
+			[[constraint]]
+			  name = "github.com/foo/bar"
+			  version = "^1.2..3"
+
+			[[constraint]]
+			  name = "github.com/foo/baz"
+			  version = "release-1"
+			`,
+			wantWarn: []error{
+				errors.New(`version "^1.2..3" in "constraint" is not a valid semver range and will be treated as a literal tag`),
+			},
+			wantError: nil,
+		},
 		{
 			name: "invalid metadata format",
 			tomlString: `
@@ -396,6 +440,477 @@ func TestValidateManifest(t *testing.T) {
 			wantWarn:  []error{errors.New("revision \"8d43f8c0b836\" should not be in abbreviated form")},
 			wantError: nil,
 		},
+		{
+			name: "valid check options",
+			tomlString: `
+			[check]
+			  skip-vendor = true
+			  upstream = true
+			  generated = true
+			  thorough = true
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid check option value",
+			tomlString: `
+			[check]
+			  skip-digest = "yes"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidCheck,
+		},
+		{
+			name: "unknown check option",
+			tomlString: `
+			[check]
+			  skip-everything = true
+			`,
+			wantWarn:  []error{errors.New("unknown field \"skip-everything\" in \"check\"")},
+			wantError: nil,
+		},
+		{
+			name: "valid quarantine",
+			tomlString: `
+			quarantine = true
+
+			[[approved]]
+			  name = "github.com/foo/bar"
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "valid justify-major-updates",
+			tomlString: `
+			justify-major-updates = true
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "valid allowed and denied",
+			tomlString: `
+			allowed = ["github.com/our-org", "gitlab.example.com"]
+			denied = ["github.com/our-org/legacy"]
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid denied",
+			tomlString: `
+			denied = "github.com/our-org/legacy"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidDenied,
+		},
+		{
+			name: "valid analyzers",
+			tomlString: `
+			analyzers = ["dep", "gomod"]
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid analyzers",
+			tomlString: `
+			analyzers = "dep"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidAnalyzers,
+		},
+		{
+			name: "valid budget",
+			tomlString: `
+			[budget]
+			  max-projects = 40
+			  max-depth = 3
+			  max-vendor-size = "50MB"
+			  enforce = true
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid budget size",
+			tomlString: `
+			[budget]
+			  max-vendor-size = "lots"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidBudget,
+		},
+		{
+			name: "valid health",
+			tomlString: `
+			[health]
+			  max-age-years = 3
+			  max-inactive-months = 18
+			  enforce = true
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid health limit",
+			tomlString: `
+			[health]
+			  max-age-years = "three"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidHealth,
+		},
+		{
+			name: "invalid smoke test",
+			tomlString: `
+			smoke-test = ["go", "test"]
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidSmokeTest,
+		},
+		{
+			name: "valid vendor layout",
+			tomlString: `
+			vendor-layout = "modules"
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid vendor layout",
+			tomlString: `
+			vendor-layout = "gopath"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidVendorLayout,
+		},
+		{
+			name: "valid test dependency exclusion",
+			tomlString: `
+			exclude-test-deps = true
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid test dependency exclusion",
+			tomlString: `
+			exclude-test-deps = "yes"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidTestDeps,
+		},
+		{
+			name: "valid platforms",
+			tomlString: `
+			[[platform]]
+			  goos = "linux"
+			  goarch = "amd64"
+
+			[[platform]]
+			  goos = "windows"
+			  goarch = "386"
+			  tags = ["netgo"]
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "platform with invalid key",
+			tomlString: `
+			[[platform]]
+			  goos = "linux"
+			  goarch = "amd64"
+			  cgo = true
+			`,
+			wantWarn:  []error{errors.New(`invalid key "cgo" in "platform"`)},
+			wantError: nil,
+		},
+		{
+			name: "platform without goarch",
+			tomlString: `
+			[[platform]]
+			  goos = "linux"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidPlatform,
+		},
+		{
+			name: "valid forbid rules",
+			tomlString: `
+			[[forbid]]
+			  packages = "./pkg/api"
+			  imports = ["github.com/lib/pq"]
+			  reason = "go through the storage package"
+
+			[[forbid]]
+			  packages = "github.com/golang/notexist/pkg/core"
+			  imports = ["net/http"]
+			  transitive = true
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "forbid rule without imports",
+			tomlString: `
+			[[forbid]]
+			  packages = "./pkg/api"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidForbid,
+		},
+		{
+			name: "forbid rule with empty imports",
+			tomlString: `
+			[[forbid]]
+			  packages = "./pkg/api"
+			  imports = []
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidForbid,
+		},
+		{
+			name: "valid migrations",
+			tomlString: `
+			[[migration]]
+			  from = "git.example.com/old/tool"
+			  to = "git.example.com/new/tool"
+			  reason = "the team was renamed"
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "migration without to",
+			tomlString: `
+			[[migration]]
+			  from = "git.example.com/old/tool"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidMigration,
+		},
+		{
+			name: "valid freeze",
+			tomlString: `
+			[freeze]
+			  file = "RELEASE_FREEZE"
+			  [[freeze.window]]
+			    from = "2018-12-17"
+			    until = "2019-01-04"
+			    reason = "the end-of-year release"
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "freeze window ending before it starts",
+			tomlString: `
+			[freeze]
+			  [[freeze.window]]
+			    from = "2019-01-04"
+			    until = "2018-12-17"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidFreeze,
+		},
+		{
+			name: "freeze window with a malformed date",
+			tomlString: `
+			[freeze]
+			  [[freeze.window]]
+			    from = "17/12/2018"
+			    until = "2019-01-04"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidFreeze,
+		},
+		{
+			name: "valid alias",
+			tomlString: `
+			[[alias]]
+			  name = "github.com/codegangsta/cli"
+			  target = "github.com/urfave/cli"
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "alias without target",
+			tomlString: `
+			[[alias]]
+			  name = "github.com/codegangsta/cli"
+			  version = "1.0.0"
+			`,
+			wantWarn: []error{
+				errors.New(`invalid key "version" in "alias"`),
+			},
+			wantError: errInvalidAlias,
+		},
+		{
+			name: "valid branch refresh",
+			tomlString: `
+			[[constraint]]
+			  name = "github.com/foo/bar"
+			  branch = "master"
+			  refresh = "weekly"
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "refresh without branch",
+			tomlString: `
+			[[constraint]]
+			  name = "github.com/foo/bar"
+			  version = "1.0.0"
+			  refresh = "never"
+			`,
+			wantWarn: []error{
+				errors.New("refresh for \"github.com/foo/bar\" only applies to constraints on a branch"),
+			},
+			wantError: nil,
+		},
+		{
+			name: "refresh in override",
+			tomlString: `
+			[[override]]
+			  name = "github.com/foo/bar"
+			  branch = "master"
+			  refresh = "never"
+			`,
+			wantWarn: []error{
+				errors.New("invalid key \"refresh\" in \"override\""),
+			},
+			wantError: nil,
+		},
+		{
+			name: "valid root-dir",
+			tomlString: `
+			[[constraint]]
+			  name = "example.com/client"
+			  source = "github.com/foo/mono"
+			  root-dir = "go"
+
+			[[override]]
+			  name = "example.com/other"
+			  root-dir = "clients/go"
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "valid fallback-sources",
+			tomlString: `
+			[[constraint]]
+			  name = "github.com/foo/bar"
+			  source = "git.example.com/mirror/bar"
+			  fallback-sources = ["github.com/foo/bar", "https://archive.example.com/bar.git"]
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "fallback-sources not a list of strings",
+			tomlString: `
+			[[override]]
+			  name = "github.com/foo/bar"
+			  fallback-sources = "github.com/foo/bar"
+			`,
+			wantWarn: []error{
+				errors.New("fallback-sources in \"override\" should be a list of strings"),
+			},
+			wantError: nil,
+		},
+		{
+			name: "root-dir not a string",
+			tomlString: `
+			[[constraint]]
+			  name = "example.com/client"
+			  root-dir = 1
+			`,
+			wantWarn: []error{
+				errors.New("root-dir in \"constraint\" should be a string"),
+			},
+			wantError: nil,
+		},
+		{
+			name: "valid kind policy",
+			tomlString: `
+			[kind-policy]
+			  constraints = ["direct", "required"]
+			  overrides = ["transitive"]
+			  enforce = true
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid kind in kind policy",
+			tomlString: `
+			[kind-policy]
+			  constraints = ["indirect"]
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidKindPolicy,
+		},
+		{
+			name: "valid groups",
+			tomlString: `
+			[[group]]
+			  name = "kubernetes"
+			  projects = ["k8s.io/...", "github.com/googleapis/gnostic"]
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "group without name",
+			tomlString: `
+			[[group]]
+			  projects = ["k8s.io/..."]
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidGroup,
+		},
+		{
+			name: "duplicate group",
+			tomlString: `
+			[[group]]
+			  name = "grpc"
+			[[group]]
+			  name = "grpc"
+			`,
+			wantWarn:  []error{},
+			wantError: errDuplicateGroup,
+		},
+		{
+			name: "invalid quarantine",
+			tomlString: `
+			quarantine = "yes"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidQuarantine,
+		},
+		{
+			name: "invalid justify-major-updates",
+			tomlString: `
+			justify-major-updates = "yes"
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidJustify,
+		},
+		{
+			name: "invalid approved",
+			tomlString: `
+			approved = ["github.com/foo/bar"]
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidApproved,
+		},
 		{
 			name: "valid prune options",
 			tomlString: `
@@ -405,6 +920,24 @@ func TestValidateManifest(t *testing.T) {
 			wantWarn:  []error{},
 			wantError: nil,
 		},
+		{
+			name: "valid prune keep",
+			tomlString: `
+			[prune]
+			  keep = ["github.com/foo/bar/*.pb.go", "github.com/foo/baz/gen"]
+			`,
+			wantWarn:  []error{},
+			wantError: nil,
+		},
+		{
+			name: "invalid prune keep",
+			tomlString: `
+			[prune]
+			  keep = ["github.com/foo/bar/[.pb.go"]
+			`,
+			wantWarn:  []error{},
+			wantError: errInvalidPruneKeep,
+		},
 		{
 			name: "invalid root prune options",
 			tomlString: `
@@ -0,0 +1,156 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/dep/gps"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+)
+
+// NotesName is the name of the file that holds the notes recorded about the
+// dependencies of a project, next to its manifest and lock.
+const NotesName = "Gopkg.notes.toml"
+
+// Note is a note recorded about a dependency: a remark from its review, a
+// link to further reading, or the record of its approval.
+type Note struct {
+	Project gps.ProjectRoot
+	// Date is the day the note was recorded, as YYYY-MM-DD.
+	Date string
+	// By is who recorded the note.
+	By string
+	// Version is the version the project was locked to when the note was
+	// recorded, if it was in the lock.
+	Version  string
+	Text     string
+	Link     string
+	Approval bool
+}
+
+// Notes are the notes recorded about the dependencies of a project, in the
+// order they were recorded.
+type Notes struct {
+	N []Note
+}
+
+type rawNotes struct {
+	Notes []rawNote `toml:"note,omitempty"`
+}
+
+type rawNote struct {
+	Name     string `toml:"name"`
+	Date     string `toml:"date,omitempty"`
+	By       string `toml:"by,omitempty"`
+	Version  string `toml:"version,omitempty"`
+	Text     string `toml:"text,omitempty"`
+	Link     string `toml:"link,omitempty"`
+	Approval bool   `toml:"approval,omitempty"`
+}
+
+// ReadNotes reads the notes file of the project at root. It returns no notes,
+// rather than an error, if the file does not exist.
+func ReadNotes(root string) (*Notes, error) {
+	f, err := os.Open(filepath.Join(root, NotesName))
+	if os.IsNotExist(err) {
+		return &Notes{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open %s", NotesName)
+	}
+	defer f.Close()
+
+	n, err := readNotes(f)
+	return n, errors.Wrapf(err, "error while parsing %s", NotesName)
+}
+
+func readNotes(r io.Reader) (*Notes, error) {
+	buf := &bytes.Buffer{}
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, errors.Wrap(err, "Unable to read byte stream")
+	}
+
+	raw := rawNotes{}
+	if err := toml.Unmarshal(buf.Bytes(), &raw); err != nil {
+		return nil, errors.Wrap(err, "Unable to parse the notes as TOML")
+	}
+
+	n := &Notes{N: make([]Note, 0, len(raw.Notes))}
+	for _, rn := range raw.Notes {
+		if rn.Name == "" {
+			return nil, errors.New("note without a project name")
+		}
+		n.N = append(n.N, Note{
+			Project:  gps.ProjectRoot(rn.Name),
+			Date:     rn.Date,
+			By:       rn.By,
+			Version:  rn.Version,
+			Text:     rn.Text,
+			Link:     rn.Link,
+			Approval: rn.Approval,
+		})
+	}
+	return n, nil
+}
+
+// For returns the notes recorded about the project at pr, oldest first.
+func (n *Notes) For(pr gps.ProjectRoot) []Note {
+	var notes []Note
+	for _, note := range n.N {
+		if note.Project == pr {
+			notes = append(notes, note)
+		}
+	}
+	return notes
+}
+
+// MarshalTOML serializes the notes into TOML via an intermediate raw form.
+func (n *Notes) MarshalTOML() ([]byte, error) {
+	raw := rawNotes{Notes: make([]rawNote, 0, len(n.N))}
+	for _, note := range n.N {
+		raw.Notes = append(raw.Notes, rawNote{
+			Name:     string(note.Project),
+			Date:     note.Date,
+			By:       note.By,
+			Version:  note.Version,
+			Text:     note.Text,
+			Link:     note.Link,
+			Approval: note.Approval,
+		})
+	}
+
+	var buf bytes.Buffer
+	err := toml.NewEncoder(&buf).Encode(raw)
+	return buf.Bytes(), errors.Wrap(err, "Unable to marshal notes to TOML string")
+}
+
+// WriteNotes writes n as the notes file of the project at root. The file is
+// moved in by a write transaction, so the caller must hold the lock of the
+// project.
+func WriteNotes(root string, n *Notes) error {
+	b, err := n.MarshalTOML()
+	if err != nil {
+		return err
+	}
+
+	txn, err := newWriteTxn(root)
+	if err != nil {
+		return errors.Wrap(err, "error while creating scratch directory")
+	}
+	defer txn.close()
+	if err := ioutil.WriteFile(txn.path(NotesName), b, 0666); err != nil {
+		return errors.Wrapf(err, "writing %s failed", NotesName)
+	}
+	if err := txn.commit(NotesName); err != nil {
+		return errors.Wrapf(err, "failed to commit %s to disk", NotesName)
+	}
+	return txn.moveIn(NotesName)
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestNotesRoundTrip(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	h.TempDir("proj")
+	root := h.Path("proj")
+
+	n, err := ReadNotes(root)
+	h.Must(err)
+	if len(n.N) != 0 {
+		t.Fatalf("expected no notes without %s, got %v", NotesName, n.N)
+	}
+
+	n.N = []Note{
+		{Project: "github.com/foo/bar", Date: "2018-10-01", By: "alice", Version: "v1.2.0", Text: "Reviewed the diff from v1.1.0"},
+		{Project: "github.com/foo/baz", Date: "2018-10-02", Link: "https://example.com/review/42"},
+		{Project: "github.com/foo/bar", Date: "2018-10-03", By: "bob", Version: "v1.2.0", Approval: true},
+	}
+	h.Must(WriteNotes(root, n))
+	if dirs, _ := filepath.Glob(filepath.Join(root, txnDirPrefix+"*")); len(dirs) > 0 {
+		t.Errorf("expected the write to be cleaned up, found %v", dirs)
+	}
+
+	got, err := ReadNotes(root)
+	h.Must(err)
+	if !reflect.DeepEqual(got, n) {
+		t.Fatalf("notes did not round-trip:\n\t(GOT): %+v\n\t(WNT): %+v", got, n)
+	}
+
+	bar := got.For("github.com/foo/bar")
+	if len(bar) != 2 || bar[0].By != "alice" || !bar[1].Approval {
+		t.Errorf("unexpected notes for github.com/foo/bar: %+v", bar)
+	}
+	if none := got.For("github.com/foo/qux"); len(none) != 0 {
+		t.Errorf("expected no notes for github.com/foo/qux, got %+v", none)
+	}
+}
+
+func TestReadNotesWithoutName(t *testing.T) {
+	_, err := readNotes(strings.NewReader("[[note]]\n  text = \"orphan\"\n"))
+	if err == nil || !strings.Contains(err.Error(), "without a project name") {
+		t.Errorf("expected a note without a name to be rejected, got %v", err)
+	}
+}
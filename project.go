@@ -122,6 +122,9 @@ type Project struct {
 	VendorStatus map[string]verify.VendorStatus
 	// The error, if any, from checking vendor.
 	CheckVendorErr error
+	// SampleVendor, if set before vendor is first checked, makes the check
+	// use verify.CheckDepTreeSampled rather than verify.CheckDepTree.
+	SampleVendor *verify.SampleOptions
 }
 
 // VerifyVendor checks the vendor directory against the hash digests in
@@ -140,12 +143,22 @@ func (p *Project) VerifyVendor() (map[string]verify.VendorStatus, error) {
 			lps = p.Lock.Projects()
 		}
 
+		// Test-only projects that are excluded from vendor are expected to be
+		// absent from it.
+		exclude := p.Manifest != nil && p.Manifest.ExcludeTestDeps
 		sums := make(map[string]verify.VersionedDigest)
 		for _, lp := range lps {
+			if exclude && isTestOnly(lp) {
+				continue
+			}
 			sums[string(lp.Ident().ProjectRoot)] = lp.(verify.VerifiableProject).Digest
 		}
 
-		p.VendorStatus, p.CheckVendorErr = verify.CheckDepTree(vendorDir, sums)
+		if p.SampleVendor != nil {
+			p.VendorStatus, p.CheckVendorErr = verify.CheckDepTreeSampled(vendorDir, sums, *p.SampleVendor)
+		} else {
+			p.VendorStatus, p.CheckVendorErr = verify.CheckDepTree(vendorDir, sums)
+		}
 	})
 
 	return p.VendorStatus, p.CheckVendorErr
@@ -173,6 +186,15 @@ func (p *Project) MakeParams() gps.SolveParameters {
 
 	if p.Manifest != nil {
 		params.Manifest = p.Manifest
+		params.ProjectAnalyzer = Analyzer{Order: p.Manifest.Analyzers}
+		if len(p.Manifest.Allowed) > 0 || len(p.Manifest.Denied) > 0 {
+			params.ImportPolicies = append(params.ImportPolicies, gps.ImportPolicy{
+				Source: ManifestName,
+				Allow:  p.Manifest.Allowed,
+				Deny:   p.Manifest.Denied,
+			})
+		}
+		params.Aliases = p.Manifest.Aliases
 	}
 
 	// It should be impossible for p.ChangedLock to be nil if p.Lock is non-nil;
@@ -191,16 +213,18 @@ func (p *Project) MakeParams() gps.SolveParameters {
 // The resulting tree is cached internally at p.RootPackageTree.
 func (p *Project) parseRootPackageTree() (pkgtree.PackageTree, error) {
 	if p.RootPackageTree.Packages == nil {
-		ptree, err := pkgtree.ListPackages(p.ResolvedAbsRoot, string(p.ImportRoot))
+		var ig *pkgtree.IgnoredRuleset
+		var platforms []pkgtree.Platform
+		if p.Manifest != nil {
+			ig = p.Manifest.IgnoredPackages()
+			platforms = p.Manifest.Platforms
+		}
+		ptree, err := pkgtree.ListPackagesForPlatforms(p.ResolvedAbsRoot, string(p.ImportRoot), platforms)
 		if err != nil {
 			return pkgtree.PackageTree{}, errors.Wrap(err, "analysis of current project's packages failed")
 		}
 		// We don't care about (unreachable) hidden packages for the root project,
 		// so drop all of those.
-		var ig *pkgtree.IgnoredRuleset
-		if p.Manifest != nil {
-			ig = p.Manifest.IgnoredPackages()
-		}
 		p.RootPackageTree = ptree.TrimHiddenPackages(true, true, ig)
 	}
 	return p.RootPackageTree, nil
@@ -0,0 +1,195 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nightlyone/lockfile"
+	"github.com/pkg/errors"
+)
+
+// projectLockName is the advisory lock held in the root of a project by the
+// dep command writing to it, so that commands run from different terminals, or
+// by CI jobs sharing a workspace, never interleave their writes.
+const projectLockName = ".dep.lock"
+
+// ensureRecordDir is the directory in the cache that holds a record of the
+// last dep ensure to finish on each project.
+const ensureRecordDir = "ensure"
+
+// projectLockPoll is how often a command queued behind another checks whether
+// it has finished.
+var projectLockPoll = time.Second
+
+// ProjectLock is held by a dep command while it writes to a project.
+type ProjectLock struct {
+	lock   *lockfile.Lockfile // nil if locking is disabled
+	record string             // "" if there is no cache to keep it in
+	root   string
+	// waited is when LockProject started to wait for another command; zero
+	// if it did not have to.
+	waited time.Time
+}
+
+// ProjectLockedError is returned by LockProject when another dep command holds
+// the lock of a project, and it was not asked to wait for it.
+type ProjectLockedError struct {
+	Root string
+	Pid  int
+}
+
+func (e ProjectLockedError) Error() string {
+	return fmt.Sprintf("another dep command (pid %d) is writing to %s", e.Pid, e.Root)
+}
+
+// ensureRecord is the record of the last dep ensure to finish on a project.
+type ensureRecord struct {
+	Request  string    `json:"request"`
+	Manifest string    `json:"manifest"`
+	Lock     string    `json:"lock"`
+	Finished time.Time `json:"finished"`
+}
+
+// LockProject takes the lock of the project that WorkingDir is in, before it
+// is loaded, so that it is not loaded half-written. If another dep command
+// holds the lock, LockProject returns a ProjectLockedError, or with wait,
+// waits until the lock is free. Locks left behind by processes that no longer
+// exist are taken over.
+//
+// Once it holds the lock, LockProject finishes any write to the project that
+// was interrupted, so that the command goes on from what was last written in
+// full. Only commands that write to the project take the lock, so those that
+// only read it never write to it.
+func (c *Ctx) LockProject(wait bool) (*ProjectLock, error) {
+	root, err := findProjectRoot(c.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return nil, err
+	}
+	if c.DisableLocking {
+		l := &ProjectLock{root: root}
+		return l, completeWrites(root)
+	}
+
+	lpath := filepath.Join(root, projectLockName)
+	lock, err := lockfile.New(lpath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create lock %s", lpath)
+	}
+	l := &ProjectLock{lock: &lock, root: root}
+	if c.Cachedir != "" {
+		sum := sha256.Sum256([]byte(root))
+		l.record = filepath.Join(c.Cachedir, ensureRecordDir, hex.EncodeToString(sum[:8])+".json")
+	}
+
+	err = lock.TryLock()
+	for err != nil {
+		if t, ok := err.(interface {
+			Temporary() bool
+		}); !ok || !t.Temporary() {
+			return nil, errors.Wrapf(err, "unable to lock %s", lpath)
+		}
+
+		if l.waited.IsZero() {
+			pid := 0
+			if p, perr := lock.GetOwner(); perr == nil {
+				pid = p.Pid
+			}
+			if !wait {
+				return nil, ProjectLockedError{Root: root, Pid: pid}
+			}
+			l.waited = time.Now()
+			c.Err.Printf("Waiting for dep (pid %d) to finish writing to %s...\n", pid, root)
+		}
+		time.Sleep(projectLockPoll)
+		err = lock.TryLock()
+	}
+
+	if err := completeWrites(root); err != nil {
+		l.Release()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Waited reports whether LockProject had to wait for another command to
+// finish.
+func (l *ProjectLock) Waited() bool {
+	return !l.waited.IsZero()
+}
+
+// Coalesced reports whether a dep ensure finished while LockProject waited,
+// after having been asked to make the same request, and Gopkg.toml and
+// Gopkg.lock have not changed since. If so, there is nothing left to do.
+//
+// request identifies what dep ensure was asked to do, such as its flags and
+// arguments; it is only compared with those passed to Done.
+func (l *ProjectLock) Coalesced(request string) bool {
+	if l.record == "" || !l.Waited() {
+		return false
+	}
+	b, err := ioutil.ReadFile(l.record)
+	if err != nil {
+		return false
+	}
+	var rec ensureRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return false
+	}
+	if rec.Request != request || rec.Finished.Before(l.waited) {
+		return false
+	}
+	manifest, lock := l.digests()
+	return rec.Manifest == manifest && rec.Lock == lock
+}
+
+// Done records that a dep ensure asked to make request has finished, for
+// Coalesced.
+func (l *ProjectLock) Done(request string) error {
+	if l.lock == nil || l.record == "" {
+		return nil
+	}
+	rec := ensureRecord{Request: request, Finished: time.Now()}
+	rec.Manifest, rec.Lock = l.digests()
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.record), 0777); err != nil {
+		return errors.Wrap(err, "failed to create the cache directory")
+	}
+	return errors.Wrap(ioutil.WriteFile(l.record, b, 0666), "failed to record the end of dep ensure")
+}
+
+// Release lets go of the lock.
+func (l *ProjectLock) Release() {
+	if l.lock != nil {
+		l.lock.Unlock()
+	}
+}
+
+// digests returns digests of the contents of Gopkg.toml and Gopkg.lock, empty
+// for those that cannot be read.
+func (l *ProjectLock) digests() (manifest, lock string) {
+	digest := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(l.root, name))
+		if err != nil {
+			return ""
+		}
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:])
+	}
+	return digest(ManifestName), digest(LockName)
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dep-project-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(poll time.Duration) { projectLockPoll = poll }(projectLockPoll)
+	projectLockPoll = 10 * time.Millisecond
+
+	root := filepath.Join(dir, "project")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{ManifestName, LockName} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte("v1"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := &Ctx{WorkingDir: root, Cachedir: filepath.Join(dir, "cache"), Err: discardLogger()}
+
+	// Take the lock, then hand it to the parent process, which is alive but
+	// is not this one, as if another dep command held it.
+	lock, err := ctx.LockProject(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.Waited() {
+		t.Fatal("expected a free lock to be taken without waiting")
+	}
+	lpath := string(*lock.lock)
+	if lpath != filepath.Join(root, projectLockName) {
+		t.Fatalf("expected the lock to be held in the root of the project, got %s", lpath)
+	}
+	if err := ioutil.WriteFile(lpath, []byte(fmt.Sprintf("%d\n", os.Getppid())), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctx.LockProject(false)
+	if e, ok := err.(ProjectLockedError); !ok || e.Pid != os.Getppid() {
+		t.Fatalf("expected a ProjectLockedError for the parent process, got %v", err)
+	}
+
+	// Finish the other command, a dep ensure, a little later.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lock.Done("update=true")
+		os.Remove(lpath)
+	}()
+	waiter, err := ctx.LockProject(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer waiter.Release()
+	if !waiter.Waited() {
+		t.Fatal("expected LockProject to wait")
+	}
+	if !waiter.Coalesced("update=true") {
+		t.Error("expected the same request to be coalesced")
+	}
+	if waiter.Coalesced("add=true") {
+		t.Error("expected a different request not to be coalesced")
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, LockName), []byte("v2"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if waiter.Coalesced("update=true") {
+		t.Error("expected the request not to be coalesced once Gopkg.lock changed")
+	}
+}
+
+func TestLockProjectCompletesWrites(t *testing.T) {
+	root, err := ioutil.TempDir("", "dep-project-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := ioutil.WriteFile(filepath.Join(root, ManifestName), []byte("v1"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// Commit a write of the manifest, then abandon it, as if dep had been
+	// interrupted.
+	txn, err := newWriteTxn(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(txn.path(ManifestName), []byte("v2"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.commit(ManifestName); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(txn.path(txnLockName), []byte("not a pid\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &Ctx{WorkingDir: root, Err: discardLogger()}
+	lock, err := ctx.LockProject(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	b, err := ioutil.ReadFile(filepath.Join(root, ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "v2" {
+		t.Errorf("expected the interrupted write to be completed, got %q", b)
+	}
+}
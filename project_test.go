@@ -160,64 +160,3 @@ func TestProjectMakeParams(t *testing.T) {
 		t.Error("makeParams() returned gps.SolveParameters with incorrect Lock")
 	}
 }
-
-func TestBackupVendor(t *testing.T) {
-	h := test.NewHelper(t)
-	defer h.Cleanup()
-
-	pc := NewTestProjectContext(h, "vendorbackupproject")
-	defer pc.Release()
-
-	dummyFile := filepath.Join("vendor", "badinput_fileroot")
-	pc.CopyFile(dummyFile, "txn_writer/badinput_fileroot")
-	pc.Load()
-
-	if err := pc.VendorShouldExist(); err != nil {
-		t.Fatal(err)
-	}
-
-	// Create a backup
-	wantName := "_vendor-sfx"
-	vendorbak, err := BackupVendor("vendor", "sfx")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if vendorbak != wantName {
-		t.Fatalf("Vendor backup name is not as expected: \n\t(GOT) %v\n\t(WNT) %v", vendorbak, wantName)
-	}
-
-	if err = pc.h.ShouldExist(vendorbak); err != nil {
-		t.Fatal(err)
-	}
-
-	if err = pc.h.ShouldExist(vendorbak + string(filepath.Separator) + "badinput_fileroot"); err != nil {
-		t.Fatal(err)
-	}
-
-	// Should return error on creating backup with existing filename
-	vendorbak, err = BackupVendor("vendor", "sfx")
-
-	if err != errVendorBackupFailed {
-		t.Fatalf("Vendor backup error is not as expected: \n\t(GOT) %v\n\t(WNT) %v", err, errVendorBackupFailed)
-	}
-
-	if vendorbak != "" {
-		t.Fatalf("Vendor backup name is not as expected: \n\t(GOT) %v\n\t(WNT) %v", vendorbak, "")
-	}
-
-	// Delete vendor
-	if err = os.RemoveAll("vendor"); err != nil {
-		t.Fatal(err)
-	}
-
-	// Should return empty backup file name when no vendor exists
-	vendorbak, err = BackupVendor("vendor", "sfx")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if vendorbak != "" {
-		t.Fatalf("Vendor backup name is not as expected: \n\t(GOT) %v\n\t(WNT) %v", vendorbak, "")
-	}
-}
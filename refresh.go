@@ -0,0 +1,83 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"time"
+
+	"github.com/golang/dep/gps"
+)
+
+// The policies that may be set with refresh in a [[constraint]] on a branch,
+// which govern when the locked revision of the project may advance to a newer
+// commit on the branch.
+const (
+	// RefreshUpdate is the default policy: the revision advances only when
+	// dep ensure -update is run.
+	RefreshUpdate = "update"
+	// RefreshWeekly also lets dep ensure advance the revision once it was
+	// committed more than a week ago.
+	RefreshWeekly = "weekly"
+	// RefreshNever holds the revision back, unless the project is named to
+	// dep ensure -update.
+	RefreshNever = "never"
+)
+
+// refreshWeeklyAge is the age after which the locked revision of a project
+// following RefreshWeekly is due to advance.
+const refreshWeeklyAge = 7 * 24 * time.Hour
+
+func isRefreshPolicy(s string) bool {
+	return s == RefreshUpdate || s == RefreshWeekly || s == RefreshNever
+}
+
+// RefreshPolicy returns the refresh policy of the project at pr: the one set
+// in its constraint, if that is on a branch, and RefreshUpdate otherwise.
+func (m *Manifest) RefreshPolicy(pr gps.ProjectRoot) string {
+	if policy, has := m.Refresh[pr]; has && isBranchConstraint(m.Constraints[pr].Constraint) {
+		return policy
+	}
+	return RefreshUpdate
+}
+
+func isBranchConstraint(c gps.Constraint) bool {
+	v, ok := c.(gps.Version)
+	return ok && v.Type() == gps.IsBranch
+}
+
+// RefreshHeld returns the roots of the projects in l that follow
+// RefreshNever, which dep ensure -update leaves alone unless they are named.
+func RefreshHeld(m *Manifest, l gps.Lock) []gps.ProjectRoot {
+	var held []gps.ProjectRoot
+	for _, lp := range l.Projects() {
+		if pr := lp.Ident().ProjectRoot; m.RefreshPolicy(pr) == RefreshNever {
+			held = append(held, pr)
+		}
+	}
+	return held
+}
+
+// RefreshDue returns the roots of the projects in l that follow RefreshWeekly
+// and whose locked revisions were committed more than a week before now. The
+// revisions of projects whose commit times sm cannot report are not due.
+func RefreshDue(m *Manifest, l gps.Lock, sm gps.SourceManager, now time.Time) []gps.ProjectRoot {
+	rt, ok := sm.(revisionTimer)
+	if !ok {
+		return nil
+	}
+
+	var due []gps.ProjectRoot
+	for _, lp := range l.Projects() {
+		id := lp.Ident()
+		if m.RefreshPolicy(id.ProjectRoot) != RefreshWeekly {
+			continue
+		}
+		t, err := rt.RevisionTime(id, lp.Version())
+		if err == nil && now.Sub(t) > refreshWeeklyAge {
+			due = append(due, id.ProjectRoot)
+		}
+	}
+	return due
+}
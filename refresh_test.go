@@ -0,0 +1,50 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestRefreshPolicies(t *testing.T) {
+	m := NewManifest()
+	m.Constraints["github.com/weekly/lib"] = gps.ProjectProperties{Constraint: gps.NewBranch("master")}
+	m.Constraints["github.com/never/lib"] = gps.ProjectProperties{Constraint: gps.NewBranch("release")}
+	m.Constraints["github.com/default/lib"] = gps.ProjectProperties{Constraint: gps.NewBranch("master")}
+	m.Constraints["github.com/tagged/lib"] = gps.ProjectProperties{Constraint: gps.NewVersion("v1.0.0")}
+	m.Refresh = map[gps.ProjectRoot]string{
+		"github.com/weekly/lib": RefreshWeekly,
+		"github.com/never/lib":  RefreshNever,
+		"github.com/tagged/lib": RefreshNever,
+	}
+
+	rev := gps.Revision("d05d5aca9f895d19e9265839bffeadd74a2d2ecb")
+	l := &Lock{}
+	for _, root := range []string{"github.com/default/lib", "github.com/never/lib", "github.com/tagged/lib", "github.com/weekly/lib"} {
+		id := gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(root)}
+		l.P = append(l.P, gps.NewLockedProject(id, gps.NewBranch("master").Pair(rev), []string{"."}))
+	}
+
+	if got := m.RefreshPolicy("github.com/tagged/lib"); got != RefreshUpdate {
+		t.Errorf("expected the policy of a project not on a branch to be %q, got %q", RefreshUpdate, got)
+	}
+
+	if got, want := RefreshHeld(m, l), []gps.ProjectRoot{"github.com/never/lib"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v to be held back, got %v", want, got)
+	}
+
+	committed := time.Date(2018, time.June, 13, 15, 33, 52, 0, time.UTC)
+	sm := fakeRevisionTimer{t: committed}
+	if got := RefreshDue(m, l, sm, committed.AddDate(0, 0, 6)); len(got) != 0 {
+		t.Errorf("expected no project to be due within a week of its commit, got %v", got)
+	}
+	if got, want := RefreshDue(m, l, sm, committed.AddDate(0, 0, 8)), []gps.ProjectRoot{"github.com/weekly/lib"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v to be due, got %v", want, got)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+	"github.com/pkg/errors"
+)
+
+// ReproduceVendor writes to dir the vendor tree that dep ensure -vendor-only
+// would write for the lock of p, exporting every project afresh from sm. As
+// in ensure, projects are pruned with the options recorded in the lock,
+// test-only projects are left out if the manifest excludes them, and files in
+// vendor that match the manifest's [prune] keep patterns are carried over.
+func (p *Project) ReproduceVendor(ctx context.Context, sm gps.SourceManager, dir string) error {
+	if p.Lock == nil {
+		return errors.Errorf("%s does not exist, cannot reproduce vendor", LockName)
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return errors.Wrapf(err, "error while creating %s", dir)
+	}
+
+	var exclude bool
+	var keep []string
+	if p.Manifest != nil {
+		exclude, keep = p.Manifest.ExcludeTestDeps, p.Manifest.KeepVendor
+	}
+	projs := make(map[gps.ProjectRoot]gps.LockedProject)
+	var prs []gps.ProjectRoot
+	for _, lp := range p.Lock.Projects() {
+		if exclude && isTestOnly(lp) {
+			continue
+		}
+		projs[lp.Ident().ProjectRoot] = lp
+		prs = append(prs, lp.Ident().ProjectRoot)
+	}
+
+	vendor := filepath.Join(p.AbsRoot, "vendor")
+	return forEachProject(ctx, prs, func(ctx context.Context, pr gps.ProjectRoot) error {
+		lp := projs[pr]
+		var po gps.PruneOptions
+		if vp, ok := lp.(verify.VerifiableProject); ok {
+			po = vp.PruneOpts
+		} else if p.Manifest != nil {
+			po = p.Manifest.PruneOptions.PruneOptionsFor(pr)
+		}
+
+		to := filepath.Join(dir, filepath.FromSlash(string(pr)))
+		if err := sm.ExportPrunedProject(ctx, lp, po, to); err != nil {
+			return errors.Wrapf(err, "failed to export %s", pr)
+		}
+		_, err := keepVendorFiles(vendor, dir, pr, keep)
+		return err
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"strings"
+)
+
+// jsonSchemaDraft is the JSON Schema dialect emitted by ManifestSchema and
+// LockSchema.
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// ManifestSchema returns a JSON Schema describing the Gopkg.toml format. It is
+// generated from the types dep uses to read and write manifests, so that it
+// cannot drift out of sync with them.
+func ManifestSchema() map[string]interface{} {
+	s := typeSchema(reflect.TypeOf(rawManifest{}))
+	s["$schema"] = jsonSchemaDraft
+	s["title"] = ManifestName
+
+	props := s["properties"].(map[string]interface{})
+	// Metadata is never decoded into the raw types, as dep ignores it.
+	props["metadata"] = map[string]interface{}{"type": "object"}
+	for _, key := range []string{"constraint", "override"} {
+		pprops := props[key].(map[string]interface{})["items"].(map[string]interface{})["properties"].(map[string]interface{})
+		pprops["metadata"] = map[string]interface{}{"type": "object"}
+	}
+
+	// Per-project prune options are extracted from the TOML tree by hand,
+	// rather than being decoded into rawPruneOptions.
+	prune := props["prune"].(map[string]interface{})
+	project := typeSchema(reflect.TypeOf(rawPruneOptions{}))
+	project["properties"].(map[string]interface{})["name"] = map[string]interface{}{"type": "string"}
+	project["required"] = []string{"name"}
+	prune["properties"].(map[string]interface{})["project"] = map[string]interface{}{
+		"type":  "array",
+		"items": project,
+	}
+
+	return s
+}
+
+// LockSchema returns a JSON Schema describing the Gopkg.lock format. It is
+// generated from the types dep uses to read and write locks.
+func LockSchema() map[string]interface{} {
+	s := typeSchema(reflect.TypeOf(rawLock{}))
+	s["$schema"] = jsonSchemaDraft
+	s["title"] = LockName
+	return s
+}
+
+// typeSchema builds a JSON Schema for values of type t, as encoded by go-toml.
+// Struct fields without a toml tag are omitted.
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(t.Elem()),
+		}
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("toml")
+			if tag == "" || tag == "-" {
+				continue
+			}
+
+			name := strings.Split(tag, ",")[0]
+			props[name] = typeSchema(f.Type)
+			if name == "name" {
+				required = append(required, name)
+			}
+		}
+
+		s := map[string]interface{}{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+
+	// Anything else (maps, interfaces) is left unconstrained.
+	return map[string]interface{}{}
+}
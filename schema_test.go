@@ -0,0 +1,73 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pelletier/go-toml"
+)
+
+// checkSchemaKeys reports every key in v that is not permitted by schema.
+func checkSchemaKeys(t *testing.T, path string, schema map[string]interface{}, v interface{}) {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for k, sub := range tv {
+			ps, ok := props[k].(map[string]interface{})
+			if !ok {
+				t.Errorf("key %s.%s is not described by the schema", path, k)
+				continue
+			}
+			checkSchemaKeys(t, path+"."+k, ps, sub)
+		}
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		for _, sub := range tv {
+			checkSchemaKeys(t, path+"[]", items, sub)
+		}
+	}
+}
+
+func TestSchemasDescribeGoldenFiles(t *testing.T) {
+	cases := []struct {
+		file   string
+		schema map[string]interface{}
+	}{
+		{"manifest/golden.toml", ManifestSchema()},
+		{"lock/golden0.toml", LockSchema()},
+		{"lock/golden1.toml", LockSchema()},
+	}
+
+	for _, c := range cases {
+		b, err := ioutil.ReadFile(filepath.Join("testdata", c.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := toml.LoadBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSchemaKeys(t, c.file, c.schema, tree.ToMap())
+	}
+}
+
+func TestManifestSchemaPruneProject(t *testing.T) {
+	s := ManifestSchema()
+	prune := s["properties"].(map[string]interface{})["prune"].(map[string]interface{})
+	project, ok := prune["properties"].(map[string]interface{})["project"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected prune.project to be described by the schema")
+	}
+
+	props := project["items"].(map[string]interface{})["properties"].(map[string]interface{})
+	for _, key := range []string{"name", pruneOptionUnusedPackages, pruneOptionNonGo, pruneOptionGoTests} {
+		if _, has := props[key]; !has {
+			t.Errorf("expected prune.project to have property %q", key)
+		}
+	}
+}
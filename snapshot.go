@@ -0,0 +1,99 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/golang/dep/internal/fs"
+	"github.com/pkg/errors"
+)
+
+// Snapshot holds copies of the lock and vendor directory of a project, so that
+// they can be put back if an update turns out to break the project.
+//
+// The copies are staged in a write transaction in the root of the project, so
+// that putting them back is done as any other write: it is moved in with
+// renames, and completed by the next dep run if interrupted once started.
+type Snapshot struct {
+	txn *writeTxn
+	// staged are the names of what is held in the transaction.
+	staged []string
+	// absent are the names of what did not exist when the snapshot was
+	// taken, and so are removed by Restore.
+	absent []string
+}
+
+// NewSnapshot copies the lock of the project at root, along with its vendor
+// directory if withVendor is set. Discard must be called once the snapshot is
+// no longer needed.
+func NewSnapshot(root string, withVendor bool) (*Snapshot, error) {
+	txn, err := newWriteTxn(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create directory for snapshot")
+	}
+	s := &Snapshot{txn: txn}
+
+	names := []string{LockName}
+	if withVendor {
+		names = append(names, "vendor")
+	}
+	for _, name := range names {
+		src := filepath.Join(txn.root, name)
+		fi, err := os.Lstat(src)
+		switch {
+		case os.IsNotExist(err):
+			s.absent = append(s.absent, name)
+			continue
+		case err != nil:
+			s.Discard()
+			return nil, errors.Wrapf(err, "failed to read %s", name)
+		case fi.IsDir():
+			err = fs.CopyDir(src, txn.path(name))
+		default:
+			err = copyRegularFile(src, txn.path(name))
+		}
+		if err != nil {
+			s.Discard()
+			return nil, errors.Wrapf(err, "failed to copy %s", name)
+		}
+		s.staged = append(s.staged, name)
+	}
+	return s, nil
+}
+
+// Restore puts the lock and vendor directory back as they were when the
+// snapshot was taken. It can only be called once.
+func (s *Snapshot) Restore() error {
+	t := s.txn
+	if len(s.staged) > 0 {
+		if err := t.commit(s.staged...); err != nil {
+			return errors.Wrap(err, "failed to commit snapshot")
+		}
+		if err := t.moveIn(s.staged...); err != nil {
+			t.undo()
+			return errors.Wrap(err, "failed to restore snapshot")
+		}
+	}
+
+	// What did not exist is moved out of the way, into the transaction, to be
+	// removed with it.
+	for _, name := range s.absent {
+		dst := filepath.Join(t.root, name)
+		if _, err := os.Lstat(dst); os.IsNotExist(err) {
+			continue
+		}
+		if err := fs.RenameWithFallback(dst, t.path(name+txnBackupSuffix)); err != nil {
+			return errors.Wrapf(err, "failed to remove %s", name)
+		}
+	}
+	return fs.SyncDir(t.root)
+}
+
+// Discard removes the copies held by the snapshot.
+func (s *Snapshot) Discard() error {
+	return s.txn.close()
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("Gopkg.lock", "old lock")
+	h.TempFile("vendor/github.com/foo/bar/bar.go", "package bar")
+	root := h.Path(".")
+
+	snap, err := NewSnapshot(root, true)
+	h.Must(err)
+	defer snap.Discard()
+
+	// Simulate an update rewriting the lock and vendor.
+	h.Must(ioutil.WriteFile(h.Path("Gopkg.lock"), []byte("new lock"), 0666))
+	h.Must(os.RemoveAll(h.Path("vendor/github.com/foo")))
+	h.TempFile("vendor/github.com/baz/qux/qux.go", "package qux")
+
+	h.Must(snap.Restore())
+	h.MustExist(filepath.Join(root, "vendor/github.com/foo/bar/bar.go"))
+	h.MustNotExist(filepath.Join(root, "vendor/github.com/baz"))
+	lock, err := ioutil.ReadFile(filepath.Join(root, "Gopkg.lock"))
+	h.Must(err)
+	if string(lock) != "old lock" {
+		t.Errorf("expected the lock to be restored, got %q", lock)
+	}
+
+	h.Must(snap.Discard())
+	if dirs, _ := filepath.Glob(filepath.Join(root, txnDirPrefix+"*")); len(dirs) > 0 {
+		t.Errorf("expected the snapshot to be removed, found %v", dirs)
+	}
+}
+
+func TestSnapshotWithoutLock(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir(".")
+	root := h.Path(".")
+	snap, err := NewSnapshot(root, true)
+	h.Must(err)
+	defer snap.Discard()
+
+	h.TempFile("Gopkg.lock", "new lock")
+	h.TempFile("vendor/github.com/foo/bar/bar.go", "package bar")
+	h.Must(snap.Restore())
+	h.MustNotExist(filepath.Join(root, "Gopkg.lock"))
+	h.MustNotExist(filepath.Join(root, "vendor"))
+}
+
+func TestSnapshotInterruptedRestore(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("Gopkg.lock", "old lock")
+	root := h.Path(".")
+	snap, err := NewSnapshot(root, false)
+	h.Must(err)
+	defer snap.Discard()
+
+	// An interrupted restore is completed by the next run, as any other
+	// committed write.
+	h.Must(ioutil.WriteFile(h.Path("Gopkg.lock"), []byte("new lock"), 0666))
+	h.Must(snap.txn.commit(snap.staged...))
+	h.Must(ioutil.WriteFile(filepath.Join(snap.txn.dir, txnLockName), []byte("not a pid\n"), 0666))
+	h.Must(completeWrites(root))
+
+	lock, err := ioutil.ReadFile(filepath.Join(root, "Gopkg.lock"))
+	h.Must(err)
+	if string(lock) != "old lock" {
+		t.Errorf("expected the lock to be restored, got %q", lock)
+	}
+}
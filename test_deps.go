@@ -0,0 +1,118 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"strings"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/paths"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/gps/verify"
+	"github.com/pkg/errors"
+)
+
+// RecordTestOnly marks each project in l that is reachable only through the
+// imports of the _test.go files of p as test-only, and clears the mark from
+// every other project. The packages of the locked projects are listed by sm
+// at their locked versions.
+func (l *Lock) RecordTestOnly(p *Project, sm gps.SourceManager) error {
+	testOnly, err := testOnlyProjects(p.RootPackageTree, p.Manifest, l, sm)
+	if err != nil {
+		return err
+	}
+
+	for k, lp := range l.P {
+		vp, ok := lp.(verify.VerifiableProject)
+		if !ok {
+			vp = verify.VerifiableProject{LockedProject: lp}
+		}
+		vp.TestOnly = testOnly[lp.Ident().ProjectRoot]
+		l.P[k] = vp
+	}
+	return nil
+}
+
+// testOnlyProjects returns the roots of the projects in l that no package of
+// ptree, the current project, reaches without passing through a _test.go file.
+// The packages m requires are reached by production code.
+func testOnlyProjects(ptree pkgtree.PackageTree, m *Manifest, l *Lock, sm gps.SourceManager) (map[gps.ProjectRoot]bool, error) {
+	lps := make(map[gps.ProjectRoot]gps.LockedProject)
+	for _, lp := range l.Projects() {
+		lps[lp.Ident().ProjectRoot] = lp
+	}
+
+	// Each package is provided by the project with the longest matching root.
+	provider := func(pkg string) (gps.LockedProject, bool) {
+		var root gps.ProjectRoot
+		for pr := range lps {
+			if (pkg == string(pr) || strings.HasPrefix(pkg, string(pr)+"/")) && len(pr) > len(root) {
+				root = pr
+			}
+		}
+		lp, has := lps[root]
+		return lp, has
+	}
+
+	rm, _ := ptree.ToReachMap(true, false, false, m.IgnoredPackages())
+	queue := rm.FlattenFn(paths.IsStandardImportPath)
+	queue = append(queue, m.Required...)
+
+	reached := make(map[gps.ProjectRoot]pkgtree.ReachMap)
+	seen := make(map[string]bool)
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		if seen[pkg] {
+			continue
+		}
+		seen[pkg] = true
+
+		lp, has := provider(pkg)
+		if !has {
+			continue
+		}
+		pr := lp.Ident().ProjectRoot
+		prm, has := reached[pr]
+		if !has {
+			dtree, err := sm.ListPackages(lp.Ident(), lp.Version())
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list packages of %s", pr)
+			}
+			prm, _ = dtree.ToReachMap(true, false, false, nil)
+			reached[pr] = prm
+		}
+		queue = append(queue, prm[pkg].External...)
+	}
+
+	testOnly := make(map[gps.ProjectRoot]bool)
+	for pr := range lps {
+		if _, has := reached[pr]; !has {
+			testOnly[pr] = true
+		}
+	}
+	return testOnly, nil
+}
+
+// vendoredLock returns the lock of the projects in l that are written to
+// vendor: all of them, unless exclude is set, in which case the test-only
+// projects are left out.
+func vendoredLock(l *Lock, exclude bool) *Lock {
+	if !exclude {
+		return l
+	}
+	vl := &Lock{SolveMeta: l.SolveMeta}
+	for _, lp := range l.P {
+		if !isTestOnly(lp) {
+			vl.P = append(vl.P, lp)
+		}
+	}
+	return vl
+}
+
+func isTestOnly(lp gps.LockedProject) bool {
+	vp, ok := lp.(verify.VerifiableProject)
+	return ok && vp.TestOnly
+}
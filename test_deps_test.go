@@ -0,0 +1,110 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/pkgtree"
+	"github.com/golang/dep/gps/verify"
+)
+
+// fakePackageLister is a SourceManager that lists the packages of projects
+// from fixed package trees.
+type fakePackageLister struct {
+	gps.SourceManager
+	trees map[gps.ProjectRoot]pkgtree.PackageTree
+}
+
+func (f fakePackageLister) ListPackages(id gps.ProjectIdentifier, v gps.Version) (pkgtree.PackageTree, error) {
+	return f.trees[id.ProjectRoot], nil
+}
+
+// packageTree returns a tree of a single package, at root, with imports and
+// test imports.
+func packageTree(root string, imports, testImports []string) pkgtree.PackageTree {
+	return pkgtree.PackageTree{
+		ImportRoot: root,
+		Packages: map[string]pkgtree.PackageOrErr{
+			root: {P: pkgtree.Package{
+				ImportPath:  root,
+				Name:        "pkg",
+				Imports:     imports,
+				TestImports: testImports,
+			}},
+		},
+	}
+}
+
+func TestRecordTestOnly(t *testing.T) {
+	sm := fakePackageLister{trees: map[gps.ProjectRoot]pkgtree.PackageTree{
+		"github.com/prod/lib":     packageTree("github.com/prod/lib", []string{"github.com/shared/lib"}, []string{"github.com/prodtest/lib"}),
+		"github.com/shared/lib":   packageTree("github.com/shared/lib", nil, nil),
+		"github.com/assert/lib":   packageTree("github.com/assert/lib", []string{"github.com/shared/lib", "github.com/diff/lib"}, nil),
+		"github.com/diff/lib":     packageTree("github.com/diff/lib", nil, nil),
+		"github.com/prodtest/lib": packageTree("github.com/prodtest/lib", nil, nil),
+		"github.com/required/lib": packageTree("github.com/required/lib", nil, nil),
+	}}
+
+	m := NewManifest()
+	m.Required = []string{"github.com/required/lib"}
+	p := &Project{
+		Manifest:        m,
+		RootPackageTree: packageTree("github.com/root/project", []string{"fmt", "github.com/prod/lib"}, []string{"testing", "github.com/assert/lib"}),
+	}
+
+	rev := gps.Revision("d05d5aca9f895d19e9265839bffeadd74a2d2ecb")
+	l := &Lock{}
+	for root := range sm.trees {
+		id := gps.ProjectIdentifier{ProjectRoot: root}
+		l.P = append(l.P, verify.VerifiableProject{
+			LockedProject: gps.NewLockedProject(id, rev, []string{"."}),
+			// Stale marks are cleared.
+			TestOnly: root == "github.com/prod/lib",
+		})
+	}
+
+	if err := l.RecordTestOnly(p, sm); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[gps.ProjectRoot]bool{
+		"github.com/prod/lib":     false,
+		"github.com/shared/lib":   false,
+		"github.com/assert/lib":   true,
+		"github.com/diff/lib":     true,
+		"github.com/prodtest/lib": true,
+		"github.com/required/lib": false,
+	}
+	for _, lp := range l.P {
+		pr := lp.Ident().ProjectRoot
+		if got := isTestOnly(lp); got != want[pr] {
+			t.Errorf("%s: expected test-only to be %v, got %v", pr, want[pr], got)
+		}
+	}
+
+	data, err := l.MarshalTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl, err := readLock(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lp := range rl.P {
+		if pr := lp.Ident().ProjectRoot; isTestOnly(lp) != want[pr] {
+			t.Errorf("%s: test-only was not read back from the lock", pr)
+		}
+	}
+
+	if got := len(vendoredLock(l, true).P); got != 3 {
+		t.Errorf("expected 3 projects to be vendored, got %d", got)
+	}
+	if vendoredLock(l, false) != l {
+		t.Error("expected the whole lock to be vendored without exclusion")
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pelletier/go-toml"
+)
+
+// tomlPathError is an error attributed to a particular element of a TOML
+// document, such as a single [[constraint]] table in a manifest.
+type tomlPathError struct {
+	// Path to the offending element. Each element is either a string key, or
+	// an int index into an array of tables.
+	path []interface{}
+	// Position of the element in the document. Zero if it could not be
+	// located.
+	pos toml.Position
+	err error
+}
+
+// newTOMLPathError attributes err to the TOML element at the given path. The
+// element's position is filled in later, by locateTOMLError.
+func newTOMLPathError(err error, path ...interface{}) error {
+	return &tomlPathError{path: path, err: err}
+}
+
+func (e *tomlPathError) Error() string {
+	if e.pos.Invalid() {
+		return fmt.Sprintf("%s: %s", e.pathString(), e.err)
+	}
+	return fmt.Sprintf("%s (line %d, column %d): %s", e.pathString(), e.pos.Line, e.pos.Col, e.err)
+}
+
+// Cause returns the underlying error, for use with errors.Cause.
+func (e *tomlPathError) Cause() error {
+	return e.err
+}
+
+func (e *tomlPathError) pathString() string {
+	var buf bytes.Buffer
+	for _, p := range e.path {
+		switch tp := p.(type) {
+		case int:
+			fmt.Fprintf(&buf, "[%d]", tp)
+		default:
+			if buf.Len() > 0 {
+				buf.WriteByte('.')
+			}
+			fmt.Fprint(&buf, tp)
+		}
+	}
+	return buf.String()
+}
+
+// locateTOMLError fills in the position of err in doc, if err is a
+// tomlPathError. All other errors, and errors whose path cannot be found in
+// doc, are returned unchanged.
+func locateTOMLError(err error, doc []byte) error {
+	perr, ok := err.(*tomlPathError)
+	if !ok {
+		return err
+	}
+
+	tree, lerr := toml.LoadBytes(doc)
+	if lerr != nil {
+		return err
+	}
+
+	var cur interface{} = tree
+	var pos toml.Position
+	for _, p := range perr.path {
+		switch tp := p.(type) {
+		case string:
+			t, ok := cur.(*toml.Tree)
+			if !ok || !t.Has(tp) {
+				return err
+			}
+			cur, pos = t.Get(tp), t.GetPosition(tp)
+		case int:
+			ts, ok := cur.([]*toml.Tree)
+			if !ok || tp < 0 || tp >= len(ts) {
+				return err
+			}
+			cur, pos = ts[tp], ts[tp].Position()
+		}
+	}
+
+	perr.pos = pos
+	return perr
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadErrorPositions(t *testing.T) {
+	manifests := []struct {
+		name, toml, want string
+	}{
+		{
+			name: "invalid top-level value",
+			toml: "\n\nrequired = 1\n",
+			want: `required (line 3, column 1): "required" must be a TOML list of strings`,
+		},
+		{
+			name: "duplicate constraint",
+			toml: `
+[[constraint]]
+  name = "github.com/foo/bar"
+  version = "1.0.0"
+
+[[constraint]]
+  name = "github.com/foo/bar"
+  branch = "master"
+`,
+			want: "constraint[1] (line 6, column 1): multiple dependencies specified for github.com/foo/bar",
+		},
+		{
+			name: "empty constraint array",
+			toml: "constraint = []\n",
+			want: "unable to parse the manifest as TOML",
+		},
+	}
+
+	for _, c := range manifests {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := readManifest(strings.NewReader(c.toml))
+			checkErrContains(t, err, c.want)
+		})
+	}
+
+	locks := []struct {
+		name, toml, want string
+	}{
+		{
+			name: "bad digest",
+			toml: `
+[[projects]]
+  name = "github.com/foo/bar"
+  revision = "d4a1a8e2a4f50f8b4e610b7ab3e8b3a4a4b6c0de"
+
+[[projects]]
+  name = "github.com/foo/baz"
+  revision = "d4a1a8e2a4f50f8b4e610b7ab3e8b3a4a4b6c0de"
+  digest = "zzz"
+`,
+			want: "projects[1].digest (line 9, column 3): expected two colon-separated components",
+		},
+		{
+			name: "no version",
+			toml: "[[projects]]\n  name = \"github.com/foo/bar\"\n",
+			want: "projects[0] (line 1, column 1): lock file has entry for github.com/foo/bar",
+		},
+	}
+
+	for _, c := range locks {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := readLock(strings.NewReader(c.toml))
+			checkErrContains(t, err, c.want)
+		})
+	}
+}
+
+func checkErrContains(t *testing.T, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Fatalf("unexpected error: %s", err)
+	case want != "" && err == nil:
+		t.Fatalf("expected error containing %q", want)
+	case want != "" && !strings.Contains(err.Error(), want):
+		t.Fatalf("unexpected error:\n\t(GOT): %s\n\t(WNT): %s", err, want)
+	}
+}
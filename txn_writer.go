@@ -12,11 +12,13 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/gps/verify"
 	"github.com/golang/dep/internal/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -69,10 +71,22 @@ var lockFileComment = []byte(`# This file is autogenerated, do not edit; changes
 type SafeWriter struct {
 	Manifest     *Manifest
 	lock         *Lock
-	lockDiff     verify.LockDelta
+	changes      LockChanges
 	writeVendor  bool
 	writeLock    bool
 	pruneOptions gps.CascadingPruneOptions
+	// vendorIfMissing defers the choice of writeVendor to Write, which
+	// writes vendor only if it is absent or empty.
+	vendorIfMissing bool
+	// modulePath is set when vendor is written in VendorLayoutModules.
+	modulePath string
+	// excludeTestOnly leaves test-only projects out of vendor.
+	excludeTestOnly bool
+	// onProgress, if set, is called as projects are written to vendor.
+	onProgress func(gps.WriteProgress)
+	// keepVendor are the patterns of the paths in vendor that are kept as
+	// they are when vendor is rewritten.
+	keepVendor []string
 }
 
 // NewSafeWriter sets up a SafeWriter to write a set of manifest, lock, and
@@ -87,9 +101,12 @@ type SafeWriter struct {
 // - If vendor is VendorAlways, or is VendorOnChanged and the locks are different,
 // the vendor directory will be written beneath root based on newLock.
 //
+// - If vendor is VendorIfMissing, the vendor directory will be written beneath
+// root based on newLock if, when Write is called, it is absent or empty.
+//
 // - If oldLock is provided without newLock, error.
 //
-// - If vendor is VendorAlways without a newLock, error.
+// - If vendor is VendorAlways or VendorIfMissing without a newLock, error.
 func NewSafeWriter(manifest *Manifest, oldLock, newLock *Lock, vendor VendorBehavior, prune gps.CascadingPruneOptions, status map[string]verify.VendorStatus) (*SafeWriter, error) {
 	sw := &SafeWriter{
 		Manifest:     manifest,
@@ -102,11 +119,15 @@ func NewSafeWriter(manifest *Manifest, oldLock, newLock *Lock, vendor VendorBeha
 			return nil, errors.New("must provide newLock when oldLock is specified")
 		}
 
-		sw.lockDiff = verify.DiffLocks(oldLock, newLock)
-		if sw.lockDiff.Changed(anyExceptHash) {
+		sw.changes = DiffLocks(oldLock, newLock)
+		if sw.changes.Changed(anyExceptHash) {
 			sw.writeLock = true
 		}
+		carryPseudoVersions(oldLock, newLock)
+		carryFetchedFrom(oldLock, newLock)
+		carryUpdateReasons(oldLock, newLock)
 	} else if newLock != nil {
+		sw.changes = DiffLocks(nil, newLock)
 		sw.writeLock = true
 	}
 
@@ -116,7 +137,7 @@ func NewSafeWriter(manifest *Manifest, oldLock, newLock *Lock, vendor VendorBeha
 	case VendorOnChanged:
 		if newLock != nil && oldLock == nil {
 			sw.writeVendor = true
-		} else if sw.lockDiff.Changed(anyExceptHash & ^verify.InputImportsChanged) {
+		} else if sw.changes.Changed(anyExceptHash & ^verify.InputImportsChanged) {
 			sw.writeVendor = true
 		} else {
 			for _, stat := range status {
@@ -126,15 +147,50 @@ func NewSafeWriter(manifest *Manifest, oldLock, newLock *Lock, vendor VendorBeha
 				}
 			}
 		}
+	case VendorIfMissing:
+		sw.vendorIfMissing = true
 	}
 
-	if sw.writeVendor && newLock == nil {
+	if (sw.writeVendor || sw.vendorIfMissing) && newLock == nil {
 		return nil, errors.New("must provide newLock in order to write out vendor")
 	}
 
 	return sw, nil
 }
 
+// UseModulesLayout makes the writer write vendor in VendorLayoutModules:
+// whenever it writes vendor, it also writes vendor/modules.txt, and the
+// require directives of go.mod to match. If there is no go.mod, one is written
+// for the module at modulePath.
+func (sw *SafeWriter) UseModulesLayout(modulePath string) {
+	sw.modulePath = modulePath
+}
+
+// ExcludeTestOnly makes the writer leave the projects that are marked
+// test-only in the lock out of vendor. They are still written to the lock.
+func (sw *SafeWriter) ExcludeTestOnly() {
+	sw.excludeTestOnly = true
+}
+
+// KeepVendor makes the writer keep the files in vendor whose paths match any of
+// patterns, such as patched generated files, as they are, rather than replace
+// them with those of their projects. See Manifest.KeepVendor.
+func (sw *SafeWriter) KeepVendor(patterns []string) {
+	sw.keepVendor = patterns
+}
+
+// OnProgress makes the writer call f as each project written to vendor goes
+// through the stages of being written, for tools that render the progress.
+// Calls are ordered and atomic.
+func (sw *SafeWriter) OnProgress(f func(gps.WriteProgress)) {
+	sw.onProgress = f
+}
+
+// Changes returns the changes that writing the lock would make to the old one.
+func (sw *SafeWriter) Changes() LockChanges {
+	return sw.changes
+}
+
 // HasLock checks if a Lock is present in the SafeWriter
 func (sw *SafeWriter) HasLock() bool {
 	return sw.lock != nil
@@ -157,6 +213,10 @@ const (
 	VendorAlways
 	// VendorNever indicates the vendor directory should never be written.
 	VendorNever
+	// VendorIfMissing indicates that the vendor directory should be written
+	// only when it is absent or empty, whether or not the lock has changed,
+	// as in fresh checkouts of projects that do not commit vendor.
+	VendorIfMissing
 )
 
 func (sw SafeWriter) validate(root string, sm gps.SourceManager) error {
@@ -187,26 +247,43 @@ func (sw SafeWriter) validate(root string, sm gps.SourceManager) error {
 // that would leave an undefined state on disk.
 //
 // If logger is not nil, progress will be logged after each project write.
-func (sw *SafeWriter) Write(root string, sm gps.SourceManager, examples bool, logger *log.Logger) error {
+//
+// If ctx is cancelled before everything is staged, Write stops, removes what
+// it staged and returns the error of ctx, leaving the project as it was.
+func (sw *SafeWriter) Write(ctx context.Context, root string, sm gps.SourceManager, examples bool, logger *log.Logger) error {
+	if sw.vendorIfMissing {
+		populated, err := fs.IsNonEmptyDir(filepath.Join(root, "vendor"))
+		if err != nil {
+			return errors.Wrap(err, "failed to check for an existing vendor dir")
+		}
+		sw.writeVendor = !populated
+	}
+
 	err := sw.validate(root, sm)
 	if err != nil {
 		return err
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	if !sw.HasManifest() && !sw.writeLock && !sw.writeVendor {
 		// nothing to do
 		return nil
 	}
 
-	mpath := filepath.Join(root, ManifestName)
-	lpath := filepath.Join(root, LockName)
 	vpath := filepath.Join(root, "vendor")
+	gpath := filepath.Join(root, GoModName)
+	writeGoMod := sw.writeVendor && sw.modulePath != ""
 
-	td, err := ioutil.TempDir(os.TempDir(), "dep")
+	// Everything is staged in the project, so that it can be moved into
+	// place together, once it is all on stable storage.
+	txn, err := newWriteTxn(root)
 	if err != nil {
 		return errors.Wrap(err, "error while creating temp dir for writing manifest/lock/vendor")
 	}
-	defer os.RemoveAll(td)
+	defer txn.close()
+	var staged []string
 
 	if sw.HasManifest() {
 		// Always write the example text to the bottom of the TOML file.
@@ -222,140 +299,144 @@ func (sw *SafeWriter) Write(root string, sm gps.SourceManager, examples bool, lo
 			initOutput = exampleTOML
 		}
 
-		if err = ioutil.WriteFile(filepath.Join(td, ManifestName), append(initOutput, tb...), 0666); err != nil {
+		if err = ioutil.WriteFile(txn.path(ManifestName), append(initOutput, tb...), 0666); err != nil {
 			return errors.Wrap(err, "failed to write manifest file to temp dir")
 		}
+		staged = append(staged, ManifestName)
 	}
 
 	if sw.writeVendor {
 		var onWrite func(gps.WriteProgress)
-		if logger != nil {
+		if logger != nil || sw.onProgress != nil {
 			onWrite = func(progress gps.WriteProgress) {
-				logger.Println(progress)
+				if logger != nil && progress.Stage == gps.WriteExported {
+					logger.Println(progress)
+				}
+				if sw.onProgress != nil {
+					sw.onProgress(progress)
+				}
 			}
 		}
-		err = gps.WriteDepTree(filepath.Join(td, "vendor"), sw.lock, sm, sw.pruneOptions, onWrite)
+		vlock := vendoredLock(sw.lock, sw.excludeTestOnly)
+		err = gps.WriteDepTreeContext(ctx, txn.path("vendor"), vlock, sm, sw.pruneOptions, onWrite)
 		if err != nil {
 			return errors.Wrap(err, "error while writing out vendor tree")
 		}
-
-		for k, lp := range sw.lock.Projects() {
-			vp := lp.(verify.VerifiableProject)
-			vp.Digest, err = verify.DigestFromDirectory(filepath.Join(td, "vendor", string(lp.Ident().ProjectRoot)))
+		for _, lp := range vlock.Projects() {
+			kept, err := keepVendorFiles(vpath, txn.path("vendor"), lp.Ident().ProjectRoot, sw.keepVendor)
 			if err != nil {
-				return errors.Wrapf(err, "error while hashing tree of %s in vendor", lp.Ident().ProjectRoot)
+				return err
+			}
+			if logger != nil {
+				for _, k := range kept {
+					logger.Printf("Kept vendor/%s", k)
+				}
 			}
-			sw.lock.P[k] = vp
 		}
-	}
 
-	if sw.writeLock {
-		l, err := sw.lock.MarshalTOML()
+		// Hash the projects written, several at a time.
+		var mu sync.Mutex
+		hashed := 0
+		digests := make(map[gps.ProjectRoot]verify.VersionedDigest)
+		lps := make(map[gps.ProjectRoot]gps.LockedProject)
+		var roots []gps.ProjectRoot
+		for _, lp := range vlock.Projects() {
+			roots = append(roots, lp.Ident().ProjectRoot)
+			lps[lp.Ident().ProjectRoot] = lp
+		}
+		err = forEachProject(ctx, roots, func(ctx context.Context, pr gps.ProjectRoot) error {
+			digest, err := verify.DigestFromDirectory(filepath.Join(txn.path("vendor"), string(pr)))
+			if err != nil {
+				return errors.Wrapf(err, "error while hashing tree of %s in vendor", pr)
+			}
+			mu.Lock()
+			digests[pr] = digest
+			if sw.onProgress != nil {
+				hashed++
+				sw.onProgress(gps.WriteProgress{
+					Count: hashed,
+					Total: len(roots),
+					LP:    lps[pr],
+					Stage: gps.WriteHashed,
+				})
+			}
+			mu.Unlock()
+			return nil
+		})
 		if err != nil {
-			return errors.Wrap(err, "failed to marshal lock to TOML")
+			return err
 		}
-
-		if err = ioutil.WriteFile(filepath.Join(td, LockName), append(lockFileComment, l...), 0666); err != nil {
-			return errors.Wrap(err, "failed to write lock file to temp dir")
+		for k, lp := range sw.lock.Projects() {
+			if digest, has := digests[lp.Ident().ProjectRoot]; has {
+				vp := lp.(verify.VerifiableProject)
+				vp.Digest = digest
+				sw.lock.P[k] = vp
+			}
 		}
-	}
 
-	// Ensure vendor/.git is preserved if present
-	if hasDotGit(vpath) {
-		err = fs.RenameWithFallback(filepath.Join(vpath, ".git"), filepath.Join(td, "vendor/.git"))
-		if _, ok := err.(*os.LinkError); ok {
-			return errors.Wrap(err, "failed to preserve vendor/.git")
+		if err := gps.WriteProvenance(txn.path("vendor"), vlock); err != nil {
+			return errors.Wrap(err, "error while writing vendor provenance")
 		}
-	}
-
-	// Move the existing files and dirs to the temp dir while we put the new
-	// ones in, to provide insurance against errors for as long as possible.
-	type pathpair struct {
-		from, to string
-	}
-	var restore []pathpair
-	var failerr error
-	var vendorbak string
 
-	if sw.HasManifest() {
-		if _, err := os.Stat(mpath); err == nil {
-			// Move out the old one.
-			tmploc := filepath.Join(td, ManifestName+".orig")
-			failerr = fs.RenameWithFallback(mpath, tmploc)
-			if failerr != nil {
-				goto fail
+		if writeGoMod {
+			gomod, err := writeModulesLayout(txn.path("vendor"), gpath, sw.modulePath, vlock, sm)
+			if err != nil {
+				return err
+			}
+			if err = ioutil.WriteFile(txn.path(GoModName), gomod, 0666); err != nil {
+				return errors.Wrapf(err, "failed to write %s to temp dir", GoModName)
 			}
-			restore = append(restore, pathpair{from: tmploc, to: mpath})
-		}
-
-		// Move in the new one.
-		failerr = fs.RenameWithFallback(filepath.Join(td, ManifestName), mpath)
-		if failerr != nil {
-			goto fail
 		}
 	}
 
 	if sw.writeLock {
-		if _, err := os.Stat(lpath); err == nil {
-			// Move out the old one.
-			tmploc := filepath.Join(td, LockName+".orig")
-
-			failerr = fs.RenameWithFallback(lpath, tmploc)
-			if failerr != nil {
-				goto fail
-			}
-			restore = append(restore, pathpair{from: tmploc, to: lpath})
+		sw.lock.recordPseudoVersions(sm)
+		sw.lock.recordFetchedFrom(sm)
+		l, err := sw.lock.MarshalTOML()
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal lock to TOML")
 		}
 
-		// Move in the new one.
-		failerr = fs.RenameWithFallback(filepath.Join(td, LockName), lpath)
-		if failerr != nil {
-			goto fail
+		if err = ioutil.WriteFile(txn.path(LockName), append(lockFileComment, l...), 0666); err != nil {
+			return errors.Wrap(err, "failed to write lock file to temp dir")
 		}
+		staged = append(staged, LockName)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if sw.writeVendor {
-		if _, err := os.Stat(vpath); err == nil {
-			// Move out the old vendor dir. just do it into an adjacent dir, to
-			// try to mitigate the possibility of a pointless cross-filesystem
-			// move with a temp directory.
-			vendorbak = vpath + ".orig"
-			if _, err := os.Stat(vendorbak); err == nil {
-				// If the adjacent dir already exists, bite the bullet and move
-				// to a proper tempdir.
-				vendorbak = filepath.Join(td, ".vendor.orig")
-			}
+		if writeGoMod {
+			staged = append(staged, GoModName)
+		}
+		staged = append(staged, "vendor")
 
-			failerr = fs.RenameWithFallback(vpath, vendorbak)
-			if failerr != nil {
-				goto fail
+		// Ensure vendor/.git is preserved if present
+		if hasDotGit(vpath) {
+			err = fs.RenameWithFallback(filepath.Join(vpath, ".git"), filepath.Join(txn.path("vendor"), ".git"))
+			if _, ok := err.(*os.LinkError); ok {
+				return errors.Wrap(err, "failed to preserve vendor/.git")
 			}
-			restore = append(restore, pathpair{from: vendorbak, to: vpath})
 		}
+	}
 
-		// Move in the new one.
-		failerr = fs.RenameWithFallback(filepath.Join(td, "vendor"), vpath)
-		if failerr != nil {
-			goto fail
+	// From here on, an interrupted write is completed the next time the
+	// project is locked, so a new lock never goes with an old manifest.
+	if err := txn.commit(staged...); err != nil {
+		if hasDotGit(txn.path("vendor")) {
+			fs.RenameWithFallback(filepath.Join(txn.path("vendor"), ".git"), filepath.Join(vpath, ".git"))
 		}
+		return errors.Wrap(err, "failed to commit manifest/lock/vendor to disk")
 	}
-
-	// Renames all went smoothly. The deferred os.RemoveAll will get the temp
-	// dir, but if we wrote vendor, we have to clean that up directly
-	if sw.writeVendor {
-		// Nothing we can really do about an error at this point, so ignore it
-		os.RemoveAll(vendorbak)
+	if err := txn.moveIn(staged...); err != nil {
+		// If we failed at any point, move all the things back into place,
+		// then bail.
+		txn.undo()
+		return err
 	}
 
 	return nil
-
-fail:
-	// If we failed at any point, move all the things back into place, then bail.
-	for _, pair := range restore {
-		// Nothing we can do on err here, as we're already in recovery mode.
-		fs.RenameWithFallback(pair.from, pair.to)
-	}
-	return failerr
 }
 
 // PrintPreparedActions logs the actions a call to Write would perform.
@@ -385,17 +466,23 @@ func (sw *SafeWriter) PrintPreparedActions(output *log.Logger, verbose bool) err
 		} else {
 			output.Printf("Would have written %s.\n", LockName)
 		}
+		if !sw.changes.Empty() {
+			output.Printf("With the following changes:\n%s", sw.changes)
+		}
 	}
 
 	if sw.writeVendor {
+		lps := vendoredLock(sw.lock, sw.excludeTestOnly).Projects()
 		if verbose {
-			output.Printf("Would have written the following %d projects to the vendor directory:\n", len(sw.lock.Projects()))
-			lps := sw.lock.Projects()
+			output.Printf("Would have written the following %d projects to the vendor directory:\n", len(lps))
 			for i, p := range lps {
 				output.Printf("(%d/%d) %s@%s\n", i+1, len(lps), p.Ident(), p.Version())
 			}
 		} else {
-			output.Printf("Would have written %d projects to the vendor directory.\n", len(sw.lock.Projects()))
+			output.Printf("Would have written %d projects to the vendor directory.\n", len(lps))
+		}
+		if sw.modulePath != "" {
+			output.Printf("Would have written vendor/%s and %s.\n", gps.ModulesFile, GoModName)
 		}
 	}
 
@@ -414,10 +501,20 @@ func hasDotGit(path string) bool {
 // have changed.
 type DeltaWriter struct {
 	lock      *Lock
+	changes   LockChanges
 	lockDiff  verify.LockDelta
 	vendorDir string
 	changed   map[gps.ProjectRoot]changeType
 	behavior  VendorBehavior
+	// modulePath is set when vendor is written in VendorLayoutModules.
+	modulePath string
+	// excludeTestOnly leaves test-only projects out of vendor.
+	excludeTestOnly bool
+	// onProgress, if set, is called as projects are written to vendor.
+	onProgress func(gps.WriteProgress)
+	// keepVendor are the patterns of the paths in vendor that are kept as
+	// they are when vendor is rewritten.
+	keepVendor []string
 }
 
 type changeType uint8
@@ -446,10 +543,18 @@ func NewDeltaWriter(p *Project, newLock *Lock, behavior VendorBehavior) (TreeWri
 		changed:   make(map[gps.ProjectRoot]changeType),
 		behavior:  behavior,
 	}
+	if p.Manifest.VendorLayout == VendorLayoutModules {
+		dw.modulePath = string(p.ImportRoot)
+	}
+	dw.excludeTestOnly = p.Manifest.ExcludeTestDeps
+	dw.keepVendor = p.Manifest.KeepVendor
 
 	if newLock == nil {
 		return nil, errors.New("must provide a non-nil newlock")
 	}
+	carryPseudoVersions(p.Lock, newLock)
+	carryFetchedFrom(p.Lock, newLock)
+	carryUpdateReasons(p.Lock, newLock)
 
 	status, err := p.VerifyVendor()
 	if err != nil {
@@ -461,12 +566,35 @@ func NewDeltaWriter(p *Project, newLock *Lock, behavior VendorBehavior) (TreeWri
 		if os.IsNotExist(err) {
 			// Provided dir does not exist, so there's no disk contents to compare
 			// against. Fall back to the old SafeWriter.
-			return NewSafeWriter(nil, p.Lock, newLock, behavior, p.Manifest.PruneOptions, status)
+			sw, err := NewSafeWriter(nil, p.Lock, newLock, behavior, p.Manifest.PruneOptions, status)
+			if err == nil && dw.modulePath != "" {
+				sw.UseModulesLayout(dw.modulePath)
+			}
+			if err == nil && dw.excludeTestOnly {
+				sw.ExcludeTestOnly()
+			}
+			if err == nil {
+				sw.KeepVendor(dw.keepVendor)
+			}
+			return sw, err
 		}
 		return nil, err
 	}
 
-	dw.lockDiff = verify.DiffLocks(p.Lock, newLock)
+	if behavior == VendorIfMissing {
+		// Every project in an empty vendor is missing from it, and so written;
+		// a populated vendor is left alone.
+		populated, err := fs.IsNonEmptyDir(dw.vendorDir)
+		if err != nil {
+			return nil, err
+		}
+		if populated {
+			dw.behavior = VendorNever
+		}
+	}
+
+	dw.changes = DiffLocks(p.Lock, newLock)
+	dw.lockDiff = dw.changes.Delta()
 
 	for pr, lpd := range dw.lockDiff.ProjectDeltas {
 		// Hash changes aren't relevant at this point, as they could be empty
@@ -504,6 +632,26 @@ func NewDeltaWriter(p *Project, newLock *Lock, behavior VendorBehavior) (TreeWri
 		}
 	}
 
+	if dw.excludeTestOnly {
+		// Test-only projects are removed from vendor if they are there, and
+		// otherwise left alone. Projects that were left out of vendor while
+		// they were test-only have to be written once they no longer are.
+		for _, lp := range newLock.Projects() {
+			pr := lp.Ident().ProjectRoot
+			_, err := os.Stat(filepath.Join(dw.vendorDir, string(pr)))
+			vendored := err == nil
+			if isTestOnly(lp) {
+				if vendored {
+					dw.changed[pr] = projectRemoved
+				} else {
+					delete(dw.changed, pr)
+				}
+			} else if _, has := dw.changed[pr]; !has && !vendored {
+				dw.changed[pr] = missingFromTree
+			}
+		}
+	}
+
 	// Apply noverify last, as it should only supersede changeTypes with lower
 	// values. It is NOT applied if no existing change is registered.
 	for _, spr := range p.Manifest.NoVerify {
@@ -542,7 +690,10 @@ func NewDeltaWriter(p *Project, newLock *Lock, behavior VendorBehavior) (TreeWri
 // This writes recreated projects to a new directory, then moves in existing,
 // unchanged projects from the original vendor directory. If any failures occur,
 // reasonable attempts are made to roll back the changes.
-func (dw *DeltaWriter) Write(path string, sm gps.SourceManager, examples bool, logger *log.Logger) error {
+//
+// If ctx is cancelled before everything is staged, Write stops, moves the
+// unchanged projects back and returns the error of ctx.
+func (dw *DeltaWriter) Write(ctx context.Context, path string, sm gps.SourceManager, examples bool, logger *log.Logger) error {
 	// TODO(sdboyer) remove path from the signature for this
 	if path != filepath.Dir(dw.vendorDir) {
 		return errors.Errorf("target path (%q) must be the parent of the original vendor path (%q)", path, dw.vendorDir)
@@ -552,18 +703,22 @@ func (dw *DeltaWriter) Write(path string, sm gps.SourceManager, examples bool, l
 		logger = log.New(ioutil.Discard, "", 0)
 	}
 
-	lpath := filepath.Join(path, LockName)
 	vpath := dw.vendorDir
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	// Write the modified projects to a new adjacent directory. We use an
-	// adjacent directory to minimize the possibility of cross-filesystem renames
-	// becoming expensive copies, and to make removal of unneeded projects implicit
-	// and automatic.
-	vnewpath := filepath.Join(filepath.Dir(vpath), ".vendor-new")
-	if _, err := os.Stat(vnewpath); err == nil {
-		return errors.Errorf("scratch directory %s already exists, please remove it", vnewpath)
+	// Write the modified projects to a new directory staged in the project. We
+	// stage in the project to minimize the possibility of cross-filesystem
+	// renames becoming expensive copies, and to make removal of unneeded
+	// projects implicit and automatic.
+	txn, err := newWriteTxn(path)
+	if err != nil {
+		return errors.Wrap(err, "error while creating scratch directory")
 	}
-	err := os.MkdirAll(vnewpath, os.FileMode(0777))
+	defer txn.close()
+	vnewpath := txn.path("vendor")
+	err = os.MkdirAll(vnewpath, os.FileMode(0777))
 	if err != nil {
 		return errors.Wrapf(err, "error while creating scratch directory at %s", vnewpath)
 	}
@@ -574,78 +729,148 @@ func (dw *DeltaWriter) Write(path string, sm gps.SourceManager, examples bool, l
 		projs[lp.Ident().ProjectRoot] = lp
 	}
 
-	var dropped, preserved []gps.ProjectRoot
-	i := 0
+	var dropped, preserved, exported []gps.ProjectRoot
 	tot := len(dw.changed)
-	for _, reason := range dw.changed {
-		if reason != pathPreserved {
-			logger.Println("# Bringing vendor into sync")
-			break
-		}
-	}
-
 	for pr, reason := range dw.changed {
 		switch reason {
 		case projectRemoved:
 			dropped = append(dropped, pr)
-			continue
 		case pathPreserved:
 			preserved = append(preserved, pr)
-			continue
+		default:
+			exported = append(exported, pr)
 		}
+	}
+	if len(exported) > 0 {
+		logger.Println("# Bringing vendor into sync")
+	}
+
+	// Export and hash the changed projects, several at a time.
+	var mu sync.Mutex
+	var started, hashed int
+	i := 0
+	// progress must be called with mu held.
+	progress := func(count int, pr gps.ProjectRoot, stage gps.WriteStage, size uint64) {
+		if dw.onProgress != nil {
+			dw.onProgress(gps.WriteProgress{
+				Count:        count,
+				Total:        len(exported),
+				LP:           projs[pr],
+				Stage:        stage,
+				BytesWritten: size,
+			})
+		}
+	}
+	digests := make(map[gps.ProjectRoot]verify.VersionedDigest, len(exported))
+	err = forEachProject(ctx, exported, func(ctx context.Context, pr gps.ProjectRoot) error {
+		mu.Lock()
+		started++
+		progress(started, pr, gps.WriteStarted, 0)
+		mu.Unlock()
 
 		to := filepath.FromSlash(filepath.Join(vnewpath, string(pr)))
 		po := projs[pr].(verify.VerifiableProject).PruneOpts
-		if err := sm.ExportPrunedProject(context.TODO(), projs[pr], po, to); err != nil {
+		if err := sm.ExportPrunedProject(ctx, projs[pr], po, to); err != nil {
 			return errors.Wrapf(err, "failed to export %s", pr)
 		}
+		kept, err := keepVendorFiles(vpath, vnewpath, pr, dw.keepVendor)
+		if err != nil {
+			return err
+		}
+		for _, k := range kept {
+			logger.Printf("Kept vendor/%s", k)
+		}
+		var size uint64
+		if dw.onProgress != nil {
+			if size, err = fs.DirSize(to); err != nil {
+				return errors.Wrapf(err, "failed to measure %s", pr)
+			}
+		}
 
+		mu.Lock()
 		i++
+		progress(i, pr, gps.WriteExported, size)
 		lpd := dw.lockDiff.ProjectDeltas[pr]
 		v, id := projs[pr].Version(), projs[pr].Ident()
 
 		// Only print things if we're actually going to leave behind a new
 		// vendor dir.
 		if dw.behavior != VendorNever {
-			logger.Printf("(%d/%d) Wrote %s@%s: %s", i, tot, id, v, changeExplanation(reason, lpd))
+			logger.Printf("(%d/%d) Wrote %s@%s: %s", i, tot, id, v, changeExplanation(dw.changed[pr], lpd))
 		}
+		mu.Unlock()
 
 		digest, err := verify.DigestFromDirectory(to)
 		if err != nil {
 			return errors.Wrapf(err, "failed to hash %s", pr)
 		}
+		mu.Lock()
+		digests[pr] = digest
+		hashed++
+		progress(hashed, pr, gps.WriteHashed, 0)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		// Update the new Lock with verification information.
-		for k, lp := range dw.lock.P {
-			if lp.Ident().ProjectRoot == pr {
-				vp := lp.(verify.VerifiableProject)
-				vp.Digest = digest
-				dw.lock.P[k] = verify.VerifiableProject{
-					LockedProject: lp,
-					PruneOpts:     po,
-					Digest:        digest,
-				}
+	// Update the new Lock with verification information.
+	for k, lp := range dw.lock.P {
+		pr := lp.Ident().ProjectRoot
+		if digest, has := digests[pr]; has {
+			vp := lp.(verify.VerifiableProject)
+			dw.lock.P[k] = verify.VerifiableProject{
+				LockedProject: lp,
+				PruneOpts:     projs[pr].(verify.VerifiableProject).PruneOpts,
+				Digest:        digest,
+				PseudoVersion: vp.PseudoVersion,
+				TestOnly:      vp.TestOnly,
+				FetchedFrom:   vp.FetchedFrom,
+				UpdateReason:  vp.UpdateReason,
 			}
 		}
 	}
 
 	// Write out the lock, now that it's fully updated with digests.
+	dw.lock.recordPseudoVersions(sm)
+	dw.lock.recordFetchedFrom(sm)
 	l, err := dw.lock.MarshalTOML()
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal lock to TOML")
 	}
 
-	if err = ioutil.WriteFile(lpath, append(lockFileComment, l...), 0666); err != nil {
+	if err = ioutil.WriteFile(txn.path(LockName), append(lockFileComment, l...), 0666); err != nil {
 		return errors.Wrap(err, "failed to write new lock file")
 	}
 
 	if dw.behavior == VendorNever {
-		return os.RemoveAll(vnewpath)
+		if err := txn.commit(LockName); err != nil {
+			return errors.Wrap(err, "failed to commit lock to disk")
+		}
+		return txn.moveIn(LockName)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
 	// Changed projects are fully populated. Now, iterate over the lock's
 	// projects and move any remaining ones not in the changed list to vnewpath.
-	for _, lp := range dw.lock.Projects() {
+	// Until the transaction is committed, they are moved back if anything
+	// goes wrong.
+	var taken []string
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		for _, name := range taken {
+			fs.RenameWithFallback(filepath.Join(vnewpath, name), filepath.Join(vpath, name))
+		}
+	}()
+	vlock := vendoredLock(dw.lock, dw.excludeTestOnly)
+	for _, lp := range vlock.Projects() {
 		pr := lp.Ident().ProjectRoot
 		tgt := filepath.Join(vnewpath, string(pr))
 		err := os.MkdirAll(filepath.Dir(tgt), os.FileMode(0777))
@@ -658,6 +883,7 @@ func (dw *DeltaWriter) Write(path string, sm gps.SourceManager, examples bool, l
 			if err != nil {
 				return errors.Wrapf(err, "error moving unchanged project %s into scratch vendor dir", pr)
 			}
+			taken = append(taken, string(pr))
 		}
 	}
 
@@ -675,6 +901,23 @@ func (dw *DeltaWriter) Write(path string, sm gps.SourceManager, examples bool, l
 		}
 	}
 
+	if err := gps.WriteProvenance(vnewpath, vlock); err != nil {
+		return errors.Wrap(err, "failed to write vendor provenance")
+	}
+
+	staged := []string{LockName}
+	if dw.modulePath != "" {
+		gomod, err := writeModulesLayout(vnewpath, filepath.Join(path, GoModName), dw.modulePath, vlock, sm)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(txn.path(GoModName), gomod, 0666); err != nil {
+			return errors.Wrapf(err, "failed to write %s", GoModName)
+		}
+		staged = append(staged, GoModName)
+	}
+	staged = append(staged, "vendor")
+
 	// Special case: ensure vendor/.git is preserved if present
 	if hasDotGit(vpath) {
 		preserved = append(preserved, ".git")
@@ -685,15 +928,21 @@ func (dw *DeltaWriter) Write(path string, sm gps.SourceManager, examples bool, l
 		if err != nil {
 			return errors.Wrapf(err, "failed to preserve vendor/%s", path)
 		}
+		taken = append(taken, string(path))
 	}
 
-	err = os.RemoveAll(vpath)
-	if err != nil {
-		return errors.Wrap(err, "failed to remove original vendor directory")
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	err = fs.RenameWithFallback(vnewpath, vpath)
-	if err != nil {
-		return errors.Wrap(err, "failed to put new vendor directory into place")
+	// From here on, an interrupted write is completed the next time the
+	// project is locked.
+	if err := txn.commit(staged...); err != nil {
+		return errors.Wrap(err, "failed to commit lock/vendor to disk")
+	}
+	committed = true
+	if err := txn.moveIn(staged...); err != nil {
+		txn.undo()
+		return errors.Wrap(err, "failed to put new lock and vendor directory into place")
 	}
 
 	return nil
@@ -708,6 +957,8 @@ func changeExplanation(c changeType, lpd verify.LockedProjectDelta) string {
 	case solveChanged:
 		if lpd.SourceChanged() {
 			return fmt.Sprintf("source changed (%s -> %s)", lpd.SourceBefore, lpd.SourceAfter)
+		} else if lpd.RootDirChanged() {
+			return fmt.Sprintf("root directory changed (%q -> %q)", lpd.RootDirBefore, lpd.RootDirAfter)
 		} else if lpd.VersionChanged() {
 			if lpd.VersionBefore == nil {
 				return fmt.Sprintf("version changed (was a bare revision)")
@@ -748,6 +999,17 @@ func changeExplanation(c changeType, lpd verify.LockedProjectDelta) string {
 	return ""
 }
 
+// OnProgress makes the writer call f as each changed project goes through the
+// stages of being written to vendor. Calls are ordered and atomic.
+func (dw *DeltaWriter) OnProgress(f func(gps.WriteProgress)) {
+	dw.onProgress = f
+}
+
+// Changes returns the changes that writing the lock would make to the old one.
+func (dw *DeltaWriter) Changes() LockChanges {
+	return dw.changes
+}
+
 // PrintPreparedActions indicates what changes the DeltaWriter plans to make.
 func (dw *DeltaWriter) PrintPreparedActions(output *log.Logger, verbose bool) error {
 	if verbose {
@@ -759,6 +1021,9 @@ func (dw *DeltaWriter) PrintPreparedActions(output *log.Logger, verbose bool) er
 	} else {
 		output.Printf("Would have written %s.\n", LockName)
 	}
+	if !dw.changes.Empty() {
+		output.Printf("With the following changes:\n%s", dw.changes)
+	}
 
 	projs := make(map[gps.ProjectRoot]gps.LockedProject)
 	for _, lp := range dw.lock.Projects() {
@@ -779,14 +1044,49 @@ func (dw *DeltaWriter) PrintPreparedActions(output *log.Logger, verbose bool) er
 		}
 	}
 
+	if dw.modulePath != "" {
+		output.Printf("Would have written vendor/%s and %s.\n", gps.ModulesFile, GoModName)
+	}
+
 	return nil
 }
 
+// forEachProject calls f for each of prs, making up to
+// gps.MaxConcurrentWriters calls at the same time. It stops at the first error
+// that f returns, or once ctx is cancelled, and returns that error.
+func forEachProject(ctx context.Context, prs []gps.ProjectRoot, f func(context.Context, gps.ProjectRoot) error) error {
+	g, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, gps.MaxConcurrentWriters())
+loop:
+	for _, pr := range prs {
+		pr := pr // per-iteration copy
+		select {
+		case sem <- struct{}{}:
+		case <-gctx.Done():
+			break loop
+		}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			return f(gctx, pr)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
 // A TreeWriter is responsible for writing important dep states to disk -
 // Gopkg.lock, vendor, and possibly Gopkg.toml.
 type TreeWriter interface {
+	Changes() LockChanges
+	Plan() WritePlan
+	OnProgress(f func(gps.WriteProgress))
 	PrintPreparedActions(output *log.Logger, verbose bool) error
-	Write(path string, sm gps.SourceManager, examples bool, logger *log.Logger) error
+	Write(ctx context.Context, path string, sm gps.SourceManager, examples bool, logger *log.Logger) error
 }
 
 // trimSHA checks if revision is a valid SHA1 digest and trims to 10 characters.
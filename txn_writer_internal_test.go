@@ -0,0 +1,92 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+func TestForEachProject(t *testing.T) {
+	defer gps.SetConcurrentWriters(0)
+	gps.SetConcurrentWriters(3)
+
+	var prs []gps.ProjectRoot
+	for i := 0; i < 20; i++ {
+		prs = append(prs, gps.ProjectRoot(fmt.Sprintf("github.com/p/%d", i)))
+	}
+
+	var mu sync.Mutex
+	running, most := 0, 0
+	seen := make(map[gps.ProjectRoot]bool)
+	err := forEachProject(context.Background(), prs, func(ctx context.Context, pr gps.ProjectRoot) error {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		seen[pr] = true
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != len(prs) {
+		t.Errorf("expected each of the %d projects to be visited, got %d", len(prs), len(seen))
+	}
+	if most > 3 {
+		t.Errorf("expected at most 3 projects at a time, got %d", most)
+	}
+
+	failed := errors.New("failed")
+	err = forEachProject(context.Background(), prs, func(ctx context.Context, pr gps.ProjectRoot) error {
+		if pr == prs[0] {
+			return failed
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != failed {
+		t.Errorf("expected the first error to be returned, got %v", err)
+	}
+
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = forEachProject(c, prs, func(ctx context.Context, pr gps.ProjectRoot) error {
+		t.Errorf("expected nothing to be done once cancelled, got %s", pr)
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("expected the write to be cancelled, got %v", err)
+	}
+}
+
+func TestHasDotGit(t *testing.T) {
+	// Create a tempdir with .git file
+	td, err := ioutil.TempDir(os.TempDir(), "dotGitFile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	os.OpenFile(td+string(filepath.Separator)+".git", os.O_CREATE, 0777)
+	if !hasDotGit(td) {
+		t.Fatal("Expected hasDotGit to find .git")
+	}
+}
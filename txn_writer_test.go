@@ -2,15 +2,17 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package dep
+package dep_test
 
 import (
-	"io/ioutil"
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/golang/dep"
+	"github.com/golang/dep/deptest"
 	"github.com/golang/dep/gps"
 	"github.com/golang/dep/internal/test"
 	"github.com/pkg/errors"
@@ -20,21 +22,14 @@ const safeWriterProject = "safewritertest"
 const safeWriterGoldenManifest = "txn_writer/expected_manifest.toml"
 const safeWriterGoldenLock = "txn_writer/expected_lock.toml"
 
-func defaultCascadingPruneOptions() gps.CascadingPruneOptions {
-	return gps.CascadingPruneOptions{
-		DefaultOptions:    gps.PruneNestedVendorDirs,
-		PerProjectOptions: map[gps.ProjectRoot]gps.PruneOptionSet{},
-	}
-}
-
 func TestSafeWriter_BadInput_MissingRoot(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
 
-	sw, _ := NewSafeWriter(nil, nil, nil, VendorOnChanged, defaultCascadingPruneOptions(), nil)
-	err := sw.Write("", pc.SourceManager, true, nil)
+	sw, _ := dep.NewSafeWriter(nil, nil, nil, dep.VendorOnChanged, dep.DefaultCascadingPruneOptions(), nil)
+	err := sw.Write(context.Background(), "", pc.SourceManager, true, nil)
 
 	if err == nil {
 		t.Fatal("should have errored without a root path, but did not")
@@ -46,13 +41,13 @@ func TestSafeWriter_BadInput_MissingRoot(t *testing.T) {
 func TestSafeWriter_BadInput_MissingSourceManager(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
-	pc.CopyFile(LockName, safeWriterGoldenLock)
+	pc.CopyFile(dep.LockName, safeWriterGoldenLock)
 	pc.Load()
 
-	sw, _ := NewSafeWriter(nil, nil, pc.Project.Lock, VendorAlways, defaultCascadingPruneOptions(), nil)
-	err := sw.Write(pc.Project.AbsRoot, nil, true, nil)
+	sw, _ := dep.NewSafeWriter(nil, nil, pc.Project.Lock, dep.VendorAlways, dep.DefaultCascadingPruneOptions(), nil)
+	err := sw.Write(context.Background(), pc.Project.AbsRoot, nil, true, nil)
 
 	if err == nil {
 		t.Fatal("should have errored without a source manager when forceVendor is true, but did not")
@@ -61,13 +56,106 @@ func TestSafeWriter_BadInput_MissingSourceManager(t *testing.T) {
 	}
 }
 
+func TestSafeWriter_VendorIfMissing(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	pc := deptest.NewProjectContext(h, safeWriterProject)
+	defer pc.Release()
+	l := &dep.Lock{}
+
+	// A populated vendor is left alone, even though the lock is new.
+	pc.CopyFile(filepath.Join("vendor", "badinput_fileroot"), "txn_writer/badinput_fileroot")
+	sw, err := dep.NewSafeWriter(nil, nil, l, dep.VendorIfMissing, dep.DefaultCascadingPruneOptions(), nil)
+	h.Must(err)
+	h.Must(sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, false, nil))
+	if sw.WritesVendor() {
+		t.Fatal("Did not expect the writer to write an existing vendor directory")
+	}
+	if err := pc.VendorFileShouldExist("badinput_fileroot"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing vendor is written, even though the lock is unchanged.
+	h.Must(os.RemoveAll(filepath.Join(pc.Project.AbsRoot, "vendor")))
+	sw, err = dep.NewSafeWriter(nil, l, l, dep.VendorIfMissing, dep.DefaultCascadingPruneOptions(), nil)
+	h.Must(err)
+	h.Must(sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, false, nil))
+	if !sw.WritesVendor() {
+		t.Fatal("Expected the writer to write the missing vendor directory")
+	}
+	if err := pc.VendorShouldExist(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSafeWriter_OnProgress(t *testing.T) {
+	test.NeedsExternalNetwork(t)
+	test.NeedsGit(t)
+
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	pc := deptest.NewProjectContext(h, safeWriterProject)
+	defer pc.Release()
+	pc.CopyFile(dep.LockName, safeWriterGoldenLock)
+	pc.Load()
+
+	sw, err := dep.NewSafeWriter(nil, nil, pc.Project.Lock, dep.VendorAlways, dep.DefaultCascadingPruneOptions(), nil)
+	h.Must(err)
+	var progress []gps.WriteProgress
+	sw.OnProgress(func(p gps.WriteProgress) {
+		progress = append(progress, p)
+	})
+	h.Must(sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, false, nil))
+
+	stages := []gps.WriteStage{gps.WriteStarted, gps.WriteExported, gps.WriteHashed}
+	if len(progress) != len(stages) {
+		t.Fatalf("expected %d progress events, got %v", len(stages), progress)
+	}
+	for i, p := range progress {
+		if p.Stage != stages[i] || p.Count != 1 || p.Total != 1 || p.Failure {
+			t.Errorf("unexpected progress event %d: %+v", i, p)
+		}
+		if p.LP.Ident().ProjectRoot != "github.com/sdboyer/dep-test" {
+			t.Errorf("unexpected project in progress event %d: %s", i, p.LP.Ident())
+		}
+	}
+	if progress[1].BytesWritten == 0 {
+		t.Error("expected the size of the exported project to be reported")
+	}
+}
+
+func TestSafeWriter_Cancelled(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+	pc := deptest.NewProjectContext(h, safeWriterProject)
+	defer pc.Release()
+	pc.CopyFile(dep.LockName, safeWriterGoldenLock)
+	pc.Load()
+
+	sw, _ := dep.NewSafeWriter(nil, nil, pc.Project.Lock, dep.VendorAlways, dep.DefaultCascadingPruneOptions(), nil)
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := sw.Write(c, pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	if errors.Cause(err) != context.Canceled {
+		t.Fatalf("expected the write to be cancelled, got %v", err)
+	}
+
+	if err := pc.VendorShouldNotExist(); err != nil {
+		t.Fatal(err)
+	}
+	staged, _ := filepath.Glob(filepath.Join(pc.Project.AbsRoot, dep.TxnDirPrefix+"*"))
+	if len(staged) != 0 {
+		t.Fatalf("expected nothing to be left staged, got %v", staged)
+	}
+}
+
 func TestSafeWriter_BadInput_ForceVendorMissingLock(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
 
-	_, err := NewSafeWriter(nil, nil, nil, VendorAlways, defaultCascadingPruneOptions(), nil)
+	_, err := dep.NewSafeWriter(nil, nil, nil, dep.VendorAlways, dep.DefaultCascadingPruneOptions(), nil)
 	if err == nil {
 		t.Fatal("should have errored without a lock when forceVendor is true, but did not")
 	} else if !strings.Contains(err.Error(), "newLock") {
@@ -78,12 +166,12 @@ func TestSafeWriter_BadInput_ForceVendorMissingLock(t *testing.T) {
 func TestSafeWriter_BadInput_OldLockOnly(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
-	pc.CopyFile(LockName, safeWriterGoldenLock)
+	pc.CopyFile(dep.LockName, safeWriterGoldenLock)
 	pc.Load()
 
-	_, err := NewSafeWriter(nil, pc.Project.Lock, nil, VendorAlways, defaultCascadingPruneOptions(), nil)
+	_, err := dep.NewSafeWriter(nil, pc.Project.Lock, nil, dep.VendorAlways, dep.DefaultCascadingPruneOptions(), nil)
 	if err == nil {
 		t.Fatal("should have errored with only an old lock, but did not")
 	} else if !strings.Contains(err.Error(), "oldLock") {
@@ -94,13 +182,13 @@ func TestSafeWriter_BadInput_OldLockOnly(t *testing.T) {
 func TestSafeWriter_BadInput_NonexistentRoot(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
 
-	sw, _ := NewSafeWriter(nil, nil, nil, VendorOnChanged, defaultCascadingPruneOptions(), nil)
+	sw, _ := dep.NewSafeWriter(nil, nil, nil, dep.VendorOnChanged, dep.DefaultCascadingPruneOptions(), nil)
 
 	missingroot := filepath.Join(pc.Project.AbsRoot, "nonexistent")
-	err := sw.Write(missingroot, pc.SourceManager, true, nil)
+	err := sw.Write(context.Background(), missingroot, pc.SourceManager, true, nil)
 
 	if err == nil {
 		t.Fatal("should have errored with nonexistent dir for root path, but did not")
@@ -112,13 +200,13 @@ func TestSafeWriter_BadInput_NonexistentRoot(t *testing.T) {
 func TestSafeWriter_BadInput_RootIsFile(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
 
-	sw, _ := NewSafeWriter(nil, nil, nil, VendorOnChanged, defaultCascadingPruneOptions(), nil)
+	sw, _ := dep.NewSafeWriter(nil, nil, nil, dep.VendorOnChanged, dep.DefaultCascadingPruneOptions(), nil)
 
 	fileroot := pc.CopyFile("fileroot", "txn_writer/badinput_fileroot")
-	err := sw.Write(fileroot, pc.SourceManager, true, nil)
+	err := sw.Write(context.Background(), fileroot, pc.SourceManager, true, nil)
 
 	if err == nil {
 		t.Fatal("should have errored when root path is a file, but did not")
@@ -134,12 +222,12 @@ func TestSafeWriter_Manifest(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
 
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
-	pc.CopyFile(ManifestName, safeWriterGoldenManifest)
+	pc.CopyFile(dep.ManifestName, safeWriterGoldenManifest)
 	pc.Load()
 
-	sw, _ := NewSafeWriter(pc.Project.Manifest, nil, nil, VendorOnChanged, defaultCascadingPruneOptions(), nil)
+	sw, _ := dep.NewSafeWriter(pc.Project.Manifest, nil, nil, dep.VendorOnChanged, dep.DefaultCascadingPruneOptions(), nil)
 
 	// Verify prepared actions
 	if !sw.HasManifest() {
@@ -148,12 +236,12 @@ func TestSafeWriter_Manifest(t *testing.T) {
 	if sw.HasLock() {
 		t.Fatal("Did not expect the payload to contain the lock")
 	}
-	if sw.writeVendor {
+	if sw.WritesVendor() {
 		t.Fatal("Did not expect the payload to contain the vendor directory")
 	}
 
 	// Write changes
-	err := sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	err := sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, true, nil)
 	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
 
 	// Verify file system changes
@@ -175,13 +263,13 @@ func TestSafeWriter_ManifestAndUnmodifiedLock(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
 
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
-	pc.CopyFile(ManifestName, safeWriterGoldenManifest)
-	pc.CopyFile(LockName, safeWriterGoldenLock)
+	pc.CopyFile(dep.ManifestName, safeWriterGoldenManifest)
+	pc.CopyFile(dep.LockName, safeWriterGoldenLock)
 	pc.Load()
 
-	sw, _ := NewSafeWriter(pc.Project.Manifest, pc.Project.Lock, pc.Project.Lock, VendorOnChanged, defaultCascadingPruneOptions(), nil)
+	sw, _ := dep.NewSafeWriter(pc.Project.Manifest, pc.Project.Lock, pc.Project.Lock, dep.VendorOnChanged, dep.DefaultCascadingPruneOptions(), nil)
 
 	// Verify prepared actions
 	if !sw.HasManifest() {
@@ -190,15 +278,15 @@ func TestSafeWriter_ManifestAndUnmodifiedLock(t *testing.T) {
 	if !sw.HasLock() {
 		t.Fatal("Expected the payload to contain the lock.")
 	}
-	if sw.writeLock {
+	if sw.WritesLock() {
 		t.Fatal("Did not expect that the writer should plan to write the lock")
 	}
-	if sw.writeVendor {
+	if sw.WritesVendor() {
 		t.Fatal("Did not expect the payload to contain the vendor directory")
 	}
 
 	// Write changes
-	err := sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	err := sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, true, nil)
 	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
 
 	// Verify file system changes
@@ -220,13 +308,13 @@ func TestSafeWriter_ManifestAndUnmodifiedLockWithForceVendor(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
 
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
-	pc.CopyFile(ManifestName, safeWriterGoldenManifest)
-	pc.CopyFile(LockName, safeWriterGoldenLock)
+	pc.CopyFile(dep.ManifestName, safeWriterGoldenManifest)
+	pc.CopyFile(dep.LockName, safeWriterGoldenLock)
 	pc.Load()
 
-	sw, _ := NewSafeWriter(pc.Project.Manifest, pc.Project.Lock, pc.Project.Lock, VendorAlways, defaultCascadingPruneOptions(), nil)
+	sw, _ := dep.NewSafeWriter(pc.Project.Manifest, pc.Project.Lock, pc.Project.Lock, dep.VendorAlways, dep.DefaultCascadingPruneOptions(), nil)
 
 	// Verify prepared actions
 	if !sw.HasManifest() {
@@ -235,15 +323,15 @@ func TestSafeWriter_ManifestAndUnmodifiedLockWithForceVendor(t *testing.T) {
 	if !sw.HasLock() {
 		t.Fatal("Expected the payload to contain the lock")
 	}
-	if sw.writeLock {
+	if sw.WritesLock() {
 		t.Fatal("Did not expect that the writer should plan to write the lock")
 	}
-	if !sw.writeVendor {
+	if !sw.WritesVendor() {
 		t.Fatal("Expected the payload to contain the vendor directory")
 	}
 
 	// Write changes
-	err := sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	err := sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, true, nil)
 	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
 
 	// Verify file system changes
@@ -268,31 +356,31 @@ func TestSafeWriter_ForceVendorWhenVendorAlreadyExists(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
 
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
-	pc.CopyFile(LockName, safeWriterGoldenLock)
+	pc.CopyFile(dep.LockName, safeWriterGoldenLock)
 	pc.Load()
 
-	sw, _ := NewSafeWriter(nil, pc.Project.Lock, pc.Project.Lock, VendorAlways, defaultCascadingPruneOptions(), nil)
-	err := sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	sw, _ := dep.NewSafeWriter(nil, pc.Project.Lock, pc.Project.Lock, dep.VendorAlways, dep.DefaultCascadingPruneOptions(), nil)
+	err := sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, true, nil)
 	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
 
 	// Verify prepared actions
-	sw, _ = NewSafeWriter(nil, nil, pc.Project.Lock, VendorAlways, defaultCascadingPruneOptions(), nil)
+	sw, _ = dep.NewSafeWriter(nil, nil, pc.Project.Lock, dep.VendorAlways, dep.DefaultCascadingPruneOptions(), nil)
 	if sw.HasManifest() {
 		t.Fatal("Did not expect the payload to contain the manifest")
 	}
 	if !sw.HasLock() {
 		t.Fatal("Expected the payload to contain the lock")
 	}
-	if !sw.writeLock {
+	if !sw.WritesLock() {
 		t.Fatal("Expected that the writer should plan to write the lock")
 	}
-	if !sw.writeVendor {
+	if !sw.WritesVendor() {
 		t.Fatal("Expected the payload to contain the vendor directory ")
 	}
 
-	err = sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	err = sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, true, nil)
 	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
 
 	// Verify file system changes
@@ -317,15 +405,15 @@ func TestSafeWriter_NewLock(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
 
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
 	pc.Load()
 
 	lf := h.GetTestFile(safeWriterGoldenLock)
 	defer lf.Close()
-	newLock, err := readLock(lf)
+	newLock, err := dep.ReadLock(lf)
 	h.Must(err)
-	sw, _ := NewSafeWriter(nil, nil, newLock, VendorOnChanged, defaultCascadingPruneOptions(), nil)
+	sw, _ := dep.NewSafeWriter(nil, nil, newLock, dep.VendorOnChanged, dep.DefaultCascadingPruneOptions(), nil)
 
 	// Verify prepared actions
 	if sw.HasManifest() {
@@ -334,15 +422,15 @@ func TestSafeWriter_NewLock(t *testing.T) {
 	if !sw.HasLock() {
 		t.Fatal("Expected the payload to contain the lock")
 	}
-	if !sw.writeLock {
+	if !sw.WritesLock() {
 		t.Fatal("Expected that the writer should plan to write the lock")
 	}
-	if !sw.writeVendor {
+	if !sw.WritesVendor() {
 		t.Fatal("Expected the payload to contain the vendor directory")
 	}
 
 	// Write changes
-	err = sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	err = sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, true, nil)
 	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
 
 	// Verify file system changes
@@ -364,15 +452,15 @@ func TestSafeWriter_NewLockSkipVendor(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
 
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
 	pc.Load()
 
 	lf := h.GetTestFile(safeWriterGoldenLock)
 	defer lf.Close()
-	newLock, err := readLock(lf)
+	newLock, err := dep.ReadLock(lf)
 	h.Must(err)
-	sw, _ := NewSafeWriter(nil, nil, newLock, VendorNever, defaultCascadingPruneOptions(), nil)
+	sw, _ := dep.NewSafeWriter(nil, nil, newLock, dep.VendorNever, dep.DefaultCascadingPruneOptions(), nil)
 
 	// Verify prepared actions
 	if sw.HasManifest() {
@@ -381,15 +469,15 @@ func TestSafeWriter_NewLockSkipVendor(t *testing.T) {
 	if !sw.HasLock() {
 		t.Fatal("Expected the payload to contain the lock")
 	}
-	if !sw.writeLock {
+	if !sw.WritesLock() {
 		t.Fatal("Expected that the writer should plan to write the lock")
 	}
-	if sw.writeVendor {
+	if sw.WritesVendor() {
 		t.Fatal("Did not expect the payload to contain the vendor directory")
 	}
 
 	// Write changes
-	err = sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	err = sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, true, nil)
 	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
 
 	// Verify file system changes
@@ -404,36 +492,22 @@ func TestSafeWriter_NewLockSkipVendor(t *testing.T) {
 	}
 }
 
-func TestHasDotGit(t *testing.T) {
-	// Create a tempdir with .git file
-	td, err := ioutil.TempDir(os.TempDir(), "dotGitFile")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer os.RemoveAll(td)
-
-	os.OpenFile(td+string(filepath.Separator)+".git", os.O_CREATE, 0777)
-	if !hasDotGit(td) {
-		t.Fatal("Expected hasDotGit to find .git")
-	}
-}
-
 func TestSafeWriter_VendorDotGitPreservedWithForceVendor(t *testing.T) {
 	h := test.NewHelper(t)
 	defer h.Cleanup()
 
-	pc := NewTestProjectContext(h, safeWriterProject)
+	pc := deptest.NewProjectContext(h, safeWriterProject)
 	defer pc.Release()
 
 	gitDirPath := filepath.Join(pc.Project.AbsRoot, "vendor", ".git")
 	os.MkdirAll(gitDirPath, 0777)
 	dummyFile := filepath.Join("vendor", ".git", "badinput_fileroot")
 	pc.CopyFile(dummyFile, "txn_writer/badinput_fileroot")
-	pc.CopyFile(ManifestName, safeWriterGoldenManifest)
-	pc.CopyFile(LockName, safeWriterGoldenLock)
+	pc.CopyFile(dep.ManifestName, safeWriterGoldenManifest)
+	pc.CopyFile(dep.LockName, safeWriterGoldenLock)
 	pc.Load()
 
-	sw, _ := NewSafeWriter(pc.Project.Manifest, pc.Project.Lock, pc.Project.Lock, VendorAlways, defaultCascadingPruneOptions(), nil)
+	sw, _ := dep.NewSafeWriter(pc.Project.Manifest, pc.Project.Lock, pc.Project.Lock, dep.VendorAlways, dep.DefaultCascadingPruneOptions(), nil)
 
 	// Verify prepared actions
 	if !sw.HasManifest() {
@@ -442,14 +516,14 @@ func TestSafeWriter_VendorDotGitPreservedWithForceVendor(t *testing.T) {
 	if !sw.HasLock() {
 		t.Fatal("Expected the payload to contain the lock")
 	}
-	if sw.writeLock {
+	if sw.WritesLock() {
 		t.Fatal("Did not expect that the writer should plan to write the lock")
 	}
-	if !sw.writeVendor {
+	if !sw.WritesVendor() {
 		t.Fatal("Expected the payload to contain the vendor directory")
 	}
 
-	err := sw.Write(pc.Project.AbsRoot, pc.SourceManager, true, nil)
+	err := sw.Write(context.Background(), pc.Project.AbsRoot, pc.SourceManager, true, nil)
 	h.Must(errors.Wrap(err, "SafeWriter.Write failed"))
 
 	// Verify file system changes
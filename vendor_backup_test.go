@@ -0,0 +1,76 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/dep"
+	"github.com/golang/dep/deptest"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestBackupVendor(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	pc := deptest.NewProjectContext(h, "vendorbackupproject")
+	defer pc.Release()
+
+	dummyFile := filepath.Join("vendor", "badinput_fileroot")
+	pc.CopyFile(dummyFile, "txn_writer/badinput_fileroot")
+	pc.Load()
+
+	if err := pc.VendorShouldExist(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a backup
+	wantName := "_vendor-sfx"
+	vendorbak, err := dep.BackupVendor("vendor", "sfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vendorbak != wantName {
+		t.Fatalf("Vendor backup name is not as expected: \n\t(GOT) %v\n\t(WNT) %v", vendorbak, wantName)
+	}
+
+	if err = h.ShouldExist(vendorbak); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = h.ShouldExist(vendorbak + string(filepath.Separator) + "badinput_fileroot"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Should return error on creating backup with existing filename
+	vendorbak, err = dep.BackupVendor("vendor", "sfx")
+
+	if err != dep.ErrVendorBackupFailed {
+		t.Fatalf("Vendor backup error is not as expected: \n\t(GOT) %v\n\t(WNT) %v", err, dep.ErrVendorBackupFailed)
+	}
+
+	if vendorbak != "" {
+		t.Fatalf("Vendor backup name is not as expected: \n\t(GOT) %v\n\t(WNT) %v", vendorbak, "")
+	}
+
+	// Delete vendor
+	if err = os.RemoveAll("vendor"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Should return empty backup file name when no vendor exists
+	vendorbak, err = dep.BackupVendor("vendor", "sfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vendorbak != "" {
+		t.Fatalf("Vendor backup name is not as expected: \n\t(GOT) %v\n\t(WNT) %v", vendorbak, "")
+	}
+}
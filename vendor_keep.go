@@ -0,0 +1,99 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// matchKeep reports whether any of patterns matches p, a slash-separated path
+// in vendor, or one of the directories that contain it. The patterns are those
+// of path.Match, so that "github.com/foo/bar/*.pb.go" matches the generated
+// files at the root of github.com/foo/bar, and "github.com/foo/bar/gen" all
+// the files under its gen directory.
+func matchKeep(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		for dir := p; dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if ok, _ := path.Match(pattern, dir); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mayKeepUnder reports whether any of patterns could match a path in the
+// project at pr, so that projects without kept files need not be walked.
+func mayKeepUnder(patterns []string, pr gps.ProjectRoot) bool {
+	prElems := strings.Split(string(pr), "/")
+	for _, pattern := range patterns {
+		elems := strings.Split(pattern, "/")
+		n := len(elems)
+		if len(prElems) < n {
+			n = len(prElems)
+		}
+		if ok, _ := path.Match(strings.Join(elems[:n], "/"), strings.Join(prElems[:n], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// keepVendorFiles copies the files of the project at pr in the vendor
+// directory oldVendor that match any of patterns over those newly written to
+// newVendor, and returns their slash-separated paths in vendor. Nothing is
+// kept for a project that was not in oldVendor.
+func keepVendorFiles(oldVendor, newVendor string, pr gps.ProjectRoot, patterns []string) ([]string, error) {
+	if len(patterns) == 0 || !mayKeepUnder(patterns, pr) {
+		return nil, nil
+	}
+	root := filepath.Join(oldVendor, filepath.FromSlash(string(pr)))
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var kept []string
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(oldVendor, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !matchKeep(patterns, rel) {
+			return nil
+		}
+
+		to := filepath.Join(newVendor, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+			return err
+		}
+		// The file written may be linked to the source cache, so it is
+		// replaced rather than written over.
+		if err := os.Remove(to); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := copyRegularFile(p, to); err != nil {
+			return err
+		}
+		kept = append(kept, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to keep files of %s in vendor", pr)
+	}
+	return kept, nil
+}
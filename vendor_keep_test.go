@@ -0,0 +1,105 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/internal/test"
+)
+
+func TestMatchKeep(t *testing.T) {
+	patterns := []string{"github.com/foo/bar/*.pb.go", "github.com/foo/baz/gen"}
+	cases := map[string]bool{
+		"github.com/foo/bar/api.pb.go":     true,
+		"github.com/foo/bar/api.go":        false,
+		"github.com/foo/bar/sub/api.pb.go": false,
+		"github.com/foo/baz/gen/a/b.go":    true,
+		"github.com/foo/baz/generate.go":   false,
+	}
+	for p, want := range cases {
+		if got := matchKeep(patterns, p); got != want {
+			t.Errorf("expected matchKeep for %s to be %v", p, want)
+		}
+	}
+}
+
+func TestKeepVendorFiles(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempFile("old/github.com/foo/bar/api.pb.go", "package bar // patched")
+	h.TempFile("old/github.com/foo/bar/api.go", "package bar // patched")
+	h.TempFile("new/github.com/foo/bar/api.pb.go", "package bar")
+	h.TempFile("new/github.com/foo/bar/api.go", "package bar")
+	h.TempFile("old/github.com/foo/baz/gen/a.go", "package gen // patched")
+	h.TempDir("new/github.com/foo/baz")
+
+	readFile := func(name string) string {
+		b, err := ioutil.ReadFile(h.Path(name))
+		h.Must(err)
+		return string(b)
+	}
+
+	patterns := []string{"github.com/foo/bar/*.pb.go", "github.com/foo/baz/gen"}
+	for _, c := range []struct {
+		pr   string
+		kept []string
+	}{
+		{"github.com/foo/bar", []string{"github.com/foo/bar/api.pb.go"}},
+		{"github.com/foo/baz", []string{"github.com/foo/baz/gen/a.go"}},
+		{"github.com/foo/qux", nil},
+	} {
+		kept, err := keepVendorFiles(h.Path("old"), h.Path("new"), gps.ProjectRoot(c.pr), patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(kept, c.kept) {
+			t.Errorf("expected %v to be kept for %s, got %v", c.kept, c.pr, kept)
+		}
+	}
+
+	if got := readFile("new/github.com/foo/bar/api.pb.go"); !strings.Contains(got, "patched") {
+		t.Errorf("expected the patched file to be kept, got %q", got)
+	}
+	if got := readFile("new/github.com/foo/bar/api.go"); strings.Contains(got, "patched") {
+		t.Errorf("did not expect a file that is not kept to be replaced, got %q", got)
+	}
+	if got := readFile("new/github.com/foo/baz/gen/a.go"); !strings.Contains(got, "patched") {
+		t.Errorf("expected the files under a kept directory to be kept, got %q", got)
+	}
+}
+
+func TestReadManifestKeepVendor(t *testing.T) {
+	m, _, err := readManifest(strings.NewReader(`
+[prune]
+  go-tests = true
+  keep = ["github.com/foo/bar/*.pb.go"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"github.com/foo/bar/*.pb.go"}
+	if !reflect.DeepEqual(m.KeepVendor, want) {
+		t.Fatalf("expected keep patterns %v, got %v", want, m.KeepVendor)
+	}
+
+	b, err := m.MarshalTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _, err = readManifest(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.KeepVendor, want) {
+		t.Fatalf("expected keep patterns %v to be written, got %v", want, m.KeepVendor)
+	}
+}
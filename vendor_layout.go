@@ -0,0 +1,138 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// The vendor layouts that may be set with vendor-layout in the manifest.
+const (
+	// VendorLayoutDep is the default layout, which only holds the projects in
+	// the lock.
+	VendorLayoutDep = "dep"
+	// VendorLayoutModules also holds vendor/modules.txt, and keeps the require
+	// directives of go.mod in step with it, so that the go command can build
+	// the project in module mode with -mod=vendor.
+	VendorLayoutModules = "modules"
+)
+
+// GoModName is the name of the file in which the go command looks for the
+// module path and requirements of a project.
+const GoModName = "go.mod"
+
+// goModGoVersion is the version in the go directive of the go.mod files that
+// dep creates.
+const goModGoVersion = "1.16"
+
+// revisionTimer is implemented by SourceManagers that can report when the
+// revisions of a source were committed.
+type revisionTimer interface {
+	RevisionTime(gps.ProjectIdentifier, gps.Version) (time.Time, error)
+}
+
+// writeModulesLayout writes vendor/modules.txt into the vendor tree at
+// vendorDir, which was populated from l, and returns the contents of the
+// go.mod file at goModPath with its require directives replaced by the
+// modules in vendor. If there is no such file, a go.mod for the module at
+// modulePath is returned.
+//
+// The pseudo-versions of projects that are not locked to semver versions are
+// timestamped with the commit times of their revisions, if sm can report
+// them, and with the zero time otherwise; the go command does not check them
+// when it builds from vendor.
+func writeModulesLayout(vendorDir, goModPath, modulePath string, l *Lock, sm gps.SourceManager) ([]byte, error) {
+	rt, _ := sm.(revisionTimer)
+	mods, err := gps.VendoredModules(vendorDir, l, func(lp gps.LockedProject) time.Time {
+		if rt == nil {
+			return time.Time{}
+		}
+		t, err := rt.RevisionTime(lp.Ident(), lp.Version())
+		if err != nil {
+			return time.Time{}
+		}
+		return t
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to infer the modules in vendor")
+	}
+	if err := gps.WriteModulesTxt(vendorDir, mods); err != nil {
+		return nil, errors.Wrapf(err, "failed to write vendor/%s", gps.ModulesFile)
+	}
+
+	existing, err := ioutil.ReadFile(goModPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to read %s", GoModName)
+	}
+	return goModWithRequires(existing, modulePath, mods, l.InputImports()), nil
+}
+
+// goModWithRequires returns the go.mod file goMod with its require directives
+// replaced by a single one requiring mods. Modules that provide none of
+// inputImports, the imports of the project, are marked as indirect. If goMod
+// is empty, a new file declaring the module at modulePath is returned.
+func goModWithRequires(goMod []byte, modulePath string, mods []gps.VendoredModule, inputImports []string) []byte {
+	var kept bytes.Buffer
+	if len(bytes.TrimSpace(goMod)) == 0 {
+		fmt.Fprintf(&kept, "module %s\n\ngo %s\n", modulePath, goModGoVersion)
+	} else {
+		var inRequire bool
+		s := bufio.NewScanner(bytes.NewReader(goMod))
+		for s.Scan() {
+			line := s.Text()
+			fields := strings.Fields(line)
+			switch {
+			case inRequire:
+				inRequire = len(fields) == 0 || fields[0] != ")"
+			case len(fields) > 1 && fields[0] == "require" && fields[1] == "(":
+				inRequire = true
+			case len(fields) > 0 && fields[0] == "require":
+			case len(fields) == 0 && bytes.HasSuffix(kept.Bytes(), []byte("\n\n")):
+				// Collapse the blank lines left around removed directives.
+			default:
+				kept.WriteString(line)
+				kept.WriteByte('\n')
+			}
+		}
+	}
+
+	if len(mods) == 0 {
+		return kept.Bytes()
+	}
+
+	// Each import is provided by the module with the longest matching path.
+	direct := make(map[string]bool)
+	for _, imp := range inputImports {
+		var provider string
+		for _, mod := range mods {
+			if (imp == mod.Path || strings.HasPrefix(imp, mod.Path+"/")) && len(mod.Path) > len(provider) {
+				provider = mod.Path
+			}
+		}
+		direct[provider] = true
+	}
+
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimRight(kept.Bytes(), "\n"))
+	buf.WriteString("\n\nrequire (\n")
+	for _, mod := range mods {
+		fmt.Fprintf(&buf, "\t%s %s", mod.Path, mod.Version)
+		if !direct[mod.Path] {
+			buf.WriteString(" // indirect")
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(")\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"testing"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestGoModWithRequires(t *testing.T) {
+	mods := []gps.VendoredModule{
+		{Path: "github.com/foo/bar", Version: "v1.2.0"},
+		{Path: "github.com/foo/bar/sub", Version: "v0.0.0-20180613153352-abcdef012345"},
+		{Path: "github.com/qux/quux", Version: "v0.0.0-20180613153352-0123456789ab"},
+	}
+	imports := []string{"github.com/foo/bar/sub/pkg", "github.com/qux/quux", "fmt"}
+
+	cases := []struct {
+		name, goMod, want string
+	}{
+		{
+			name: "new",
+			want: `module github.com/me/proj
+
+go 1.16
+
+require (
+	github.com/foo/bar v1.2.0 // indirect
+	github.com/foo/bar/sub v0.0.0-20180613153352-abcdef012345
+	github.com/qux/quux v0.0.0-20180613153352-0123456789ab
+)
+`,
+		},
+		{
+			name: "existing",
+			goMod: `module example.com/proj
+
+go 1.13
+
+require github.com/old/lib v1.0.0
+
+require (
+	github.com/other/lib v0.1.0
+)
+
+replace github.com/qux/quux => github.com/fork/quux v0.0.0-20180613153352-0123456789ab
+`,
+			want: `module example.com/proj
+
+go 1.13
+
+replace github.com/qux/quux => github.com/fork/quux v0.0.0-20180613153352-0123456789ab
+
+require (
+	github.com/foo/bar v1.2.0 // indirect
+	github.com/foo/bar/sub v0.0.0-20180613153352-abcdef012345
+	github.com/qux/quux v0.0.0-20180613153352-0123456789ab
+)
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := string(goModWithRequires([]byte(c.goMod), "github.com/me/proj", mods, imports))
+			if got != c.want {
+				t.Errorf("unexpected go.mod:\n\t(GOT):\n%s\n\t(WNT):\n%s", got, c.want)
+			}
+		})
+	}
+}
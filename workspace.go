@@ -0,0 +1,82 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A Workspace is a directory tree containing several dep projects, such as the
+// services in a monorepo, that are inspected together.
+type Workspace struct {
+	// Root is the absolute path of the directory containing all members.
+	Root string
+	// Members are the projects found beneath Root, ordered by path.
+	Members []*Project
+}
+
+// MemberName returns the path of p relative to the workspace root, which
+// identifies it within the workspace.
+func (w *Workspace) MemberName(p *Project) string {
+	rel, err := filepath.Rel(w.Root, p.AbsRoot)
+	if err != nil {
+		return p.AbsRoot
+	}
+	return filepath.ToSlash(rel)
+}
+
+// LoadWorkspace treats the working directory as the root of a workspace, and
+// loads every project beneath it. A project is any directory containing a
+// manifest; vendor, testdata and directories beginning with "." or "_" are not
+// searched.
+func (c *Ctx) LoadWorkspace() (*Workspace, error) {
+	w := &Workspace{Root: c.WorkingDir}
+
+	err := filepath.Walk(w.Root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+
+		name := fi.Name()
+		if path != w.Root && (name == "vendor" || name == "testdata" ||
+			strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+
+		if _, err := os.Stat(filepath.Join(path, ManifestName)); err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		// Each member may live in a different GOPATH, so load it with its
+		// own copy of the context.
+		mc := *c
+		mc.WorkingDir = path
+		mc.GOPATH = ""
+		p, err := mc.LoadProject()
+		if err != nil {
+			return errors.Wrapf(err, "failed to load workspace member %s", path)
+		}
+		w.Members = append(w.Members, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(w.Members) == 0 {
+		return nil, errors.Errorf("no projects with a %s found in workspace %s", ManifestName, w.Root)
+	}
+	return w, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"testing"
+
+	"github.com/golang/dep/internal/test"
+)
+
+func TestLoadWorkspace(t *testing.T) {
+	h := test.NewHelper(t)
+	defer h.Cleanup()
+
+	h.TempDir("src/example.com/mono")
+	for _, member := range []string{"svc/b", "svc/a", "lib", "svc/a/vendor/x", ".hidden", "_old"} {
+		h.TempFile("src/example.com/mono/"+member+"/"+ManifestName, "")
+	}
+	h.TempFile("src/example.com/mono/svc/a/"+LockName, "")
+
+	ctx := &Ctx{Out: discardLogger(), Err: discardLogger()}
+	h.Must(ctx.SetPaths(h.Path("src/example.com/mono"), h.Path(".")))
+
+	w, err := ctx.LoadWorkspace()
+	h.Must(err)
+
+	var got []string
+	for _, p := range w.Members {
+		got = append(got, w.MemberName(p))
+	}
+	want := []string{"lib", "svc/a", "svc/b"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected members:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected members:\n\t(GOT): %v\n\t(WNT): %v", got, want)
+		}
+	}
+	if w.Members[1].Lock == nil {
+		t.Error("expected the lock of svc/a to be loaded")
+	}
+	if w.Members[1].ImportRoot != "example.com/mono/svc/a" {
+		t.Errorf("unexpected import root %q", w.Members[1].ImportRoot)
+	}
+
+	h.TempDir("src/empty")
+	ctx.WorkingDir = h.Path("src/empty")
+	if _, err := ctx.LoadWorkspace(); err == nil {
+		t.Error("expected an error for a workspace without projects")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"sort"
+
+	"github.com/golang/dep/gps"
+)
+
+// WritePlan describes everything that a call to Write on a TreeWriter would
+// do, so that it can be reviewed before anything is written.
+type WritePlan struct {
+	// Manifest is true if Gopkg.toml would be written.
+	Manifest bool `json:"manifest"`
+	// Lock is true if Gopkg.lock would be written, and Changes are the
+	// changes that would be made to it.
+	Lock    bool        `json:"lock"`
+	Changes LockChanges `json:"changes"`
+	// Vendor is true if vendor would be written, and VendorProjects lists
+	// what would be done to each project in it, sorted by name.
+	Vendor         bool           `json:"vendor"`
+	VendorProjects []VendorChange `json:"vendorProjects,omitempty"`
+	// GoMod is true if go.mod and vendor/modules.txt would be written.
+	GoMod bool `json:"goMod,omitempty"`
+}
+
+// VendorAction is what writing vendor would do to a project.
+type VendorAction string
+
+// The kinds of VendorAction.
+const (
+	VendorAdd    VendorAction = "add"
+	VendorRemove VendorAction = "remove"
+	VendorUpdate VendorAction = "update"
+	// VendorRewrite is a project that is written again as it was, as
+	// SafeWriter writes the whole of vendor at once.
+	VendorRewrite VendorAction = "rewrite"
+	// VendorPreserve is a path in vendor that is kept as it is, as noverify
+	// asks.
+	VendorPreserve VendorAction = "preserve"
+)
+
+// VendorChange is what writing vendor would do to a single project.
+type VendorChange struct {
+	Name   string       `json:"name"`
+	Action VendorAction `json:"action"`
+	// Version and Revision are those the project would be written at. They
+	// are not set for projects that would be removed.
+	Version  string `json:"version,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	// Reason explains why the project would be written.
+	Reason string `json:"reason,omitempty"`
+}
+
+// newVendorChange returns a VendorChange for the project lp.
+func newVendorChange(lp gps.LockedProject, action VendorAction, reason string) VendorChange {
+	vc := VendorChange{Name: string(lp.Ident().ProjectRoot), Action: action, Reason: reason}
+	vc.Revision, _, _ = gps.VersionComponentStrings(lp.Version())
+	if _, isRev := lp.Version().(gps.Revision); !isRev {
+		vc.Version = lp.Version().String()
+	}
+	return vc
+}
+
+func sortVendorChanges(vcs []VendorChange) {
+	sort.Slice(vcs, func(i, j int) bool { return vcs[i].Name < vcs[j].Name })
+}
+
+// Plan returns a description of what a call to Write would do, without
+// writing anything. Digests in the lock are not yet known, and so are not
+// accounted for. Nor, with VendorIfMissing, is whether vendor is missing, and
+// so vendor is planned to be left alone.
+func (sw *SafeWriter) Plan() WritePlan {
+	plan := WritePlan{
+		Manifest: sw.HasManifest(),
+		Lock:     sw.writeLock,
+		Changes:  sw.changes,
+		Vendor:   sw.writeVendor,
+		GoMod:    sw.writeVendor && sw.modulePath != "",
+	}
+	if !sw.writeVendor {
+		return plan
+	}
+
+	deltas := sw.changes.Delta().ProjectDeltas
+	for _, lp := range vendoredLock(sw.lock, sw.excludeTestOnly).Projects() {
+		pr := lp.Ident().ProjectRoot
+		lpd, has := deltas[pr]
+		var vc VendorChange
+		switch {
+		case has && lpd.WasAdded():
+			vc = newVendorChange(lp, VendorAdd, changeExplanation(projectAdded, lpd))
+		case has && lpd.Changed(anyExceptHash) && lpd.PruneOptsChanged():
+			vc = newVendorChange(lp, VendorUpdate, changeExplanation(pruneOptsChanged, lpd))
+		case has && lpd.Changed(anyExceptHash):
+			vc = newVendorChange(lp, VendorUpdate, changeExplanation(solveChanged, lpd))
+		default:
+			vc = newVendorChange(lp, VendorRewrite, "")
+		}
+		plan.VendorProjects = append(plan.VendorProjects, vc)
+	}
+	for pr, lpd := range deltas {
+		if lpd.WasRemoved() {
+			plan.VendorProjects = append(plan.VendorProjects, VendorChange{Name: string(pr), Action: VendorRemove})
+		}
+	}
+	sortVendorChanges(plan.VendorProjects)
+	return plan
+}
+
+// Plan returns a description of what a call to Write would do, without
+// writing anything. The lock is always written; digests in it are not yet
+// known, and so are not accounted for.
+func (dw *DeltaWriter) Plan() WritePlan {
+	plan := WritePlan{
+		Lock:    true,
+		Changes: dw.changes,
+		Vendor:  dw.behavior != VendorNever,
+		GoMod:   dw.behavior != VendorNever && dw.modulePath != "",
+	}
+	if !plan.Vendor {
+		return plan
+	}
+
+	projs := make(map[gps.ProjectRoot]gps.LockedProject)
+	for _, lp := range dw.lock.Projects() {
+		projs[lp.Ident().ProjectRoot] = lp
+	}
+	for pr, reason := range dw.changed {
+		lpd := dw.lockDiff.ProjectDeltas[pr]
+		var vc VendorChange
+		switch reason {
+		case projectRemoved:
+			vc = VendorChange{Name: string(pr), Action: VendorRemove}
+		case pathPreserved:
+			vc = VendorChange{Name: string(pr), Action: VendorPreserve, Reason: "noverify"}
+		case projectAdded:
+			vc = newVendorChange(projs[pr], VendorAdd, changeExplanation(reason, lpd))
+		default:
+			vc = newVendorChange(projs[pr], VendorUpdate, changeExplanation(reason, lpd))
+		}
+		plan.VendorProjects = append(plan.VendorProjects, vc)
+	}
+	sortVendorChanges(plan.VendorProjects)
+	return plan
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/dep/gps"
+	"github.com/golang/dep/gps/verify"
+)
+
+func defaultCascadingPruneOptions() gps.CascadingPruneOptions {
+	return gps.CascadingPruneOptions{
+		DefaultOptions:    gps.PruneNestedVendorDirs,
+		PerProjectOptions: map[gps.ProjectRoot]gps.PruneOptionSet{},
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	lp := func(name string, v gps.Version, prune gps.PruneOptions) gps.LockedProject {
+		return verify.VerifiableProject{
+			LockedProject: gps.NewLockedProject(gps.ProjectIdentifier{ProjectRoot: gps.ProjectRoot(name)}, v, []string{"."}),
+			PruneOpts:     prune,
+		}
+	}
+	l1 := &Lock{P: []gps.LockedProject{
+		lp("github.com/foo/bar", gps.NewVersion("v1.0.0").Pair("rev1"), gps.PruneNestedVendorDirs),
+		lp("github.com/old/dep", gps.NewBranch("master").Pair("rev2"), gps.PruneNestedVendorDirs),
+		lp("github.com/same/dep", gps.Revision("rev3"), gps.PruneNestedVendorDirs),
+	}}
+	l2 := &Lock{P: []gps.LockedProject{
+		lp("github.com/foo/bar", gps.NewVersion("v1.1.0").Pair("rev4"), gps.PruneNestedVendorDirs),
+		lp("github.com/new/dep", gps.NewVersion("v0.1.0").Pair("rev5"), gps.PruneNestedVendorDirs),
+		lp("github.com/same/dep", gps.Revision("rev3"), gps.PruneNestedVendorDirs),
+	}}
+
+	sw, err := NewSafeWriter(nil, l1, l2, VendorOnChanged, defaultCascadingPruneOptions(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := sw.Plan()
+	if plan.Manifest || !plan.Lock || !plan.Vendor || plan.GoMod {
+		t.Errorf("expected the lock and vendor, and only them, to be written, got %+v", plan)
+	}
+	if len(plan.Changes.Projects) != 3 {
+		t.Errorf("expected the changes to the lock to be planned, got %+v", plan.Changes)
+	}
+	want := []VendorChange{
+		{Name: "github.com/foo/bar", Action: VendorUpdate, Version: "v1.1.0", Revision: "rev4", Reason: "version changed (was v1.0.0)"},
+		{Name: "github.com/new/dep", Action: VendorAdd, Version: "v0.1.0", Revision: "rev5", Reason: "new project"},
+		{Name: "github.com/old/dep", Action: VendorRemove},
+		{Name: "github.com/same/dep", Action: VendorRewrite, Revision: "rev3"},
+	}
+	if !reflect.DeepEqual(want, plan.VendorProjects) {
+		t.Errorf("expected the vendor projects\n\t%+v\ngot\n\t%+v", want, plan.VendorProjects)
+	}
+
+	sw, err = NewSafeWriter(nil, l1, l2, VendorNever, defaultCascadingPruneOptions(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan := sw.Plan(); plan.Vendor || len(plan.VendorProjects) != 0 {
+		t.Errorf("expected vendor not to be written, got %+v", plan)
+	}
+
+	changes := DiffLocks(l1, l2)
+	dw := &DeltaWriter{
+		lock:     l2,
+		changes:  changes,
+		lockDiff: changes.Delta(),
+		changed: map[gps.ProjectRoot]changeType{
+			"github.com/foo/bar":  solveChanged,
+			"github.com/new/dep":  projectAdded,
+			"github.com/old/dep":  projectRemoved,
+			"github.com/same/dep": hashMismatch,
+			"github.com/kept/dir": pathPreserved,
+		},
+		behavior:   VendorOnChanged,
+		modulePath: "example.com/me",
+	}
+	plan = dw.Plan()
+	if !plan.Lock || !plan.Vendor || !plan.GoMod {
+		t.Errorf("expected the lock, vendor and go.mod to be written, got %+v", plan)
+	}
+	want = []VendorChange{
+		{Name: "github.com/foo/bar", Action: VendorUpdate, Version: "v1.1.0", Revision: "rev4", Reason: "version changed (was v1.0.0)"},
+		{Name: "github.com/kept/dir", Action: VendorPreserve, Reason: "noverify"},
+		{Name: "github.com/new/dep", Action: VendorAdd, Version: "v0.1.0", Revision: "rev5", Reason: "new project"},
+		{Name: "github.com/old/dep", Action: VendorRemove},
+		{Name: "github.com/same/dep", Action: VendorUpdate, Revision: "rev3", Reason: "hash of vendored tree didn't match digest in Gopkg.lock"},
+	}
+	if !reflect.DeepEqual(want, plan.VendorProjects) {
+		t.Errorf("expected the vendor projects\n\t%+v\ngot\n\t%+v", want, plan.VendorProjects)
+	}
+}
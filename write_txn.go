@@ -0,0 +1,248 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/dep/internal/fs"
+	"github.com/nightlyone/lockfile"
+	"github.com/pkg/errors"
+)
+
+const (
+	// txnDirPrefix starts the name of the directories in the root of a
+	// project in which writes to it are staged.
+	txnDirPrefix = ".dep-txn-"
+	// txnLockName is held in a staging directory by the process writing it.
+	txnLockName = "txn.lock"
+	// txnCommitName lists what is to be moved out of a staging directory,
+	// once all of it is on stable storage.
+	txnCommitName = "committed"
+	// txnBackupSuffix marks what was replaced in the root of the project, kept
+	// until the transaction is closed.
+	txnBackupSuffix = ".orig"
+)
+
+// writeTxn moves files and directories into the root of a project together,
+// such as Gopkg.toml, Gopkg.lock and vendor: they are staged in a directory in
+// the root, so that moving them in is a rename on the same filesystem, and
+// once the transaction is committed, they are moved in even if dep is
+// interrupted, by completeWrites the next time a dep command takes the lock of
+// the project to write to it. Nothing is moved in if dep is interrupted before
+// then.
+type writeTxn struct {
+	root, dir string
+	moved     []string
+}
+
+// newWriteTxn creates a staging directory in the root of a project.
+func newWriteTxn(root string) (*writeTxn, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir(root, txnDirPrefix)
+	if err != nil {
+		return nil, err
+	}
+	// The lock tells completeWrites that the transaction is still being
+	// written, rather than interrupted, so the write cannot go ahead without
+	// it.
+	lock, err := lockfile.New(filepath.Join(dir, txnLockName))
+	if err == nil {
+		err = lock.TryLock()
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, errors.Wrap(err, "cannot lock write")
+	}
+	return &writeTxn{root: root, dir: dir}, nil
+}
+
+// path returns where to stage name.
+func (t *writeTxn) path(name string) string {
+	return filepath.Join(t.dir, name)
+}
+
+// commit commits what is staged under names to stable storage, then records
+// that they are to be moved in, in that order.
+func (t *writeTxn) commit(names ...string) error {
+	for _, name := range names {
+		if err := syncStaged(t.path(name)); err != nil {
+			return err
+		}
+	}
+
+	cpath := t.path(txnCommitName)
+	if err := ioutil.WriteFile(cpath, []byte(strings.Join(names, "\n")+"\n"), 0666); err != nil {
+		return errors.Wrap(err, "cannot commit write")
+	}
+	if err := fs.SyncFile(cpath); err != nil {
+		return err
+	}
+	if err := fs.SyncDir(t.dir); err != nil {
+		return err
+	}
+	return fs.SyncDir(t.root)
+}
+
+// moveIn moves what is staged under each of names into the root, in order,
+// keeping what it replaces until the transaction is closed, for undo.
+//
+// Files are replaced in a single rename, so they are never missing from the
+// root, even for a moment. Directories have to be moved out of the way first.
+func (t *writeTxn) moveIn(names ...string) error {
+	for _, name := range names {
+		dst := filepath.Join(t.root, name)
+		fi, err := os.Lstat(dst)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return err
+		case fi.IsDir():
+			if err := fs.RenameWithFallback(dst, t.path(name+txnBackupSuffix)); err != nil {
+				return err
+			}
+		default:
+			if err := copyRegularFile(dst, t.path(name+txnBackupSuffix)); err != nil {
+				return err
+			}
+		}
+
+		t.moved = append(t.moved, name)
+		if err := fs.RenameWithFallback(t.path(name), dst); err != nil {
+			return err
+		}
+	}
+	return fs.SyncDir(t.root)
+}
+
+// undo puts back what moveIn replaced, and removes what it added. It is only
+// for failures during moveIn; it cannot stop completeWrites from finishing a
+// committed transaction if dep is interrupted.
+func (t *writeTxn) undo() {
+	// Nothing we can do on err here, as we're already in recovery mode.
+	for i := len(t.moved) - 1; i >= 0; i-- {
+		name := t.moved[i]
+		dst := filepath.Join(t.root, name)
+		os.RemoveAll(dst)
+		if _, err := os.Lstat(t.path(name + txnBackupSuffix)); err == nil {
+			fs.RenameWithFallback(t.path(name+txnBackupSuffix), dst)
+		}
+	}
+	t.moved = nil
+}
+
+// close removes the staging directory, and whatever is left in it.
+func (t *writeTxn) close() error {
+	// The lock lives in the directory, so there is no need to release it.
+	return os.RemoveAll(t.dir)
+}
+
+// syncStaged commits the file or tree at path to stable storage, skipping the
+// contents of any vendor/.git, which is only ever moved into a staged tree.
+func syncStaged(path string) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.IsDir():
+			if err := fs.SyncDir(p); err != nil {
+				return err
+			}
+			if info.Name() == ".git" && filepath.Base(filepath.Dir(p)) == "vendor" {
+				return filepath.SkipDir
+			}
+		case info.Mode().IsRegular():
+			return fs.SyncFile(p)
+		}
+		return nil
+	})
+}
+
+// completeWrites finishes the writes to the project at root that were
+// committed, but interrupted before everything was moved into place, and
+// removes the staging directories of those interrupted before they were
+// committed. It leaves alone the writes of running processes.
+func completeWrites(root string) error {
+	dirs, err := filepath.Glob(filepath.Join(root, txnDirPrefix+"*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		dir, err = filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		lock, err := lockfile.New(filepath.Join(dir, txnLockName))
+		if err != nil {
+			return err
+		}
+		// A missing lock is a write that has only just started.
+		owner, err := lock.GetOwner()
+		if os.IsNotExist(err) || (err == nil && owner.Pid == os.Getpid()) {
+			continue
+		}
+		if err := lock.TryLock(); err != nil {
+			if _, ok := err.(interface{ Temporary() bool }); ok {
+				continue
+			}
+			return errors.Wrapf(err, "cannot lock %s", dir)
+		}
+
+		if b, err := ioutil.ReadFile(filepath.Join(dir, txnCommitName)); err == nil {
+			t := &writeTxn{root: filepath.Dir(dir), dir: dir}
+			if err := t.rollForward(strings.Fields(string(b))); err != nil {
+				lock.Unlock()
+				return errors.Wrapf(err, "cannot complete interrupted write in %s", dir)
+			}
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollForward moves in what is still staged under names, as moveIn would
+// have.
+func (t *writeTxn) rollForward(names []string) error {
+	for _, name := range names {
+		src := t.path(name)
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			// Already moved in.
+			continue
+		}
+		dst := filepath.Join(t.root, name)
+		if fi, err := os.Lstat(dst); err == nil && fi.IsDir() {
+			if err := os.RemoveAll(dst); err != nil {
+				return err
+			}
+		}
+		if err := fs.RenameWithFallback(src, dst); err != nil {
+			return err
+		}
+	}
+	return fs.SyncDir(t.root)
+}
+
+// copyRegularFile copies the contents and mode of the file at src to dst.
+func copyRegularFile(src, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, b, fi.Mode())
+}
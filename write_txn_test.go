@@ -0,0 +1,132 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTxn(t *testing.T) {
+	root, err := ioutil.TempDir("", "dep-txn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(path, contents string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(name, want string) {
+		t.Helper()
+		got, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("expected %s to hold %q, got %q", name, want, got)
+		}
+	}
+	stage := func(version string) *writeTxn {
+		t.Helper()
+		txn, err := newWriteTxn(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(txn.path(ManifestName), "manifest "+version)
+		write(txn.path(LockName), "lock "+version)
+		write(filepath.Join(txn.path("vendor"), "foo", "foo.go"), "foo "+version)
+		if err := txn.commit(ManifestName, LockName, "vendor"); err != nil {
+			t.Fatal(err)
+		}
+		return txn
+	}
+	dirs := func() []string {
+		t.Helper()
+		dirs, err := filepath.Glob(filepath.Join(root, txnDirPrefix+"*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dirs
+	}
+
+	write(filepath.Join(root, ManifestName), "manifest 1")
+	write(filepath.Join(root, "vendor", "bar", "bar.go"), "bar 1")
+
+	// Moving everything in replaces it.
+	txn := stage("2")
+	if err := txn.moveIn(ManifestName, LockName, "vendor"); err != nil {
+		t.Fatal(err)
+	}
+	txn.close()
+	expect(ManifestName, "manifest 2")
+	expect(LockName, "lock 2")
+	expect("vendor/foo/foo.go", "foo 2")
+	if _, err := os.Stat(filepath.Join(root, "vendor", "bar")); !os.IsNotExist(err) {
+		t.Errorf("expected the old vendor to be removed, got %v", err)
+	}
+	if len(dirs()) != 0 {
+		t.Errorf("expected no staging directories to be left, got %v", dirs())
+	}
+
+	// Undoing puts it all back.
+	txn = stage("3")
+	if err := txn.moveIn(ManifestName, LockName, "vendor"); err != nil {
+		t.Fatal(err)
+	}
+	txn.undo()
+	txn.close()
+	expect(ManifestName, "manifest 2")
+	expect(LockName, "lock 2")
+	expect("vendor/foo/foo.go", "foo 2")
+
+	// The writes of running processes are left alone.
+	txn = stage("4")
+	if err := completeWrites(root); err != nil {
+		t.Fatal(err)
+	}
+	expect(ManifestName, "manifest 2")
+	if len(dirs()) != 1 {
+		t.Fatalf("expected a running write to be left alone, got %v", dirs())
+	}
+
+	// A committed write interrupted after moving the manifest in is completed.
+	if err := txn.moveIn(ManifestName); err != nil {
+		t.Fatal(err)
+	}
+	write(txn.path(txnLockName), "not a pid\n")
+	if err := completeWrites(root); err != nil {
+		t.Fatal(err)
+	}
+	expect(ManifestName, "manifest 4")
+	expect(LockName, "lock 4")
+	expect("vendor/foo/foo.go", "foo 4")
+	if len(dirs()) != 0 {
+		t.Errorf("expected completed writes to be removed, got %v", dirs())
+	}
+
+	// An uncommitted one is discarded.
+	txn, err = newWriteTxn(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(txn.path(ManifestName), "manifest 5")
+	write(txn.path(txnLockName), "not a pid\n")
+	if err := completeWrites(root); err != nil {
+		t.Fatal(err)
+	}
+	expect(ManifestName, "manifest 4")
+	if len(dirs()) != 0 {
+		t.Errorf("expected uncommitted writes to be removed, got %v", dirs())
+	}
+}
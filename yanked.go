@@ -0,0 +1,97 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/dep/gps"
+	"github.com/pkg/errors"
+)
+
+// yankedFeedTimeout bounds the time taken to fetch a yanked versions feed
+// over HTTP.
+const yankedFeedTimeout = 30 * time.Second
+
+// ReadYankedVersions reads a feed of yanked versions from r. Each line of the
+// feed names a project root and a version, branch or revision of it, followed
+// by an optional reason:
+//
+//	github.com/foo/bar v1.2.3 deadlocks under load
+//
+// Blank lines, and lines beginning with #, are ignored.
+func ReadYankedVersions(r io.Reader) (gps.YankedVersions, error) {
+	yanked := make(gps.YankedVersions)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, errors.Errorf("line %d: expected a project root and a version, got %q", n, line)
+		}
+
+		pr := gps.ProjectRoot(fields[0])
+		yanked[pr] = append(yanked[pr], gps.YankedVersion{
+			Version: fields[1],
+			Reason:  strings.Join(fields[2:], " "),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return yanked, nil
+}
+
+// LoadYankedVersions reads the yanked versions feed named by c.YankedFeed,
+// which is either a local file or an http or https URL. If no feed is named,
+// the feed in the metadata bundle named by c.Bundle is used instead, if there
+// is one. It returns nil if no feed is configured.
+func (c *Ctx) LoadYankedVersions() (gps.YankedVersions, error) {
+	if c.YankedFeed == "" {
+		b, err := c.LoadBundle()
+		if b == nil || err != nil {
+			return nil, err
+		}
+		return b.Yanked, nil
+	}
+
+	var r io.ReadCloser
+	if strings.HasPrefix(c.YankedFeed, "http://") || strings.HasPrefix(c.YankedFeed, "https://") {
+		client := http.Client{Timeout: yankedFeedTimeout}
+		resp, err := client.Get(c.YankedFeed)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch yanked versions feed %s", c.YankedFeed)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, errors.Errorf("failed to fetch yanked versions feed %s: %s", c.YankedFeed, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(c.YankedFeed)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open yanked versions feed")
+		}
+		r = f
+	}
+	defer r.Close()
+
+	yanked, err := ReadYankedVersions(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read yanked versions feed %s", c.YankedFeed)
+	}
+	return yanked, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dep
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/dep/gps"
+)
+
+func TestReadYankedVersions(t *testing.T) {
+	feed := `# yanked versions
+
+github.com/sdboyer/deptest v1.0.0 deadlocks under load
+github.com/sdboyer/deptest ff2948a2ac8f538c4ecd55962e919d1e13e74baf
+  github.com/sdboyer/deptestdos  v2.0.0   bad   release
+`
+	got, err := ReadYankedVersions(strings.NewReader(feed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := gps.YankedVersions{
+		"github.com/sdboyer/deptest": {
+			{Version: "v1.0.0", Reason: "deadlocks under load"},
+			{Version: "ff2948a2ac8f538c4ecd55962e919d1e13e74baf"},
+		},
+		"github.com/sdboyer/deptestdos": {
+			{Version: "v2.0.0", Reason: "bad release"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected yanked versions:\n\t(GOT): %#v\n\t(WNT): %#v", got, want)
+	}
+}
+
+func TestReadYankedVersionsInvalid(t *testing.T) {
+	_, err := ReadYankedVersions(strings.NewReader("github.com/sdboyer/deptest v1.0.0\ngithub.com/sdboyer/deptestdos\n"))
+	if err == nil {
+		t.Fatal("expected an error for a line without a version")
+	}
+	if want := "line 2: expected a project root and a version"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("unexpected error: %s", err)
+	}
+}